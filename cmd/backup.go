@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupDatabasePath string
+	backupStorage      string
+)
+
+// backupCmd backs up the catalog of a database the server is not running on
+var backupCmd = &cobra.Command{
+	Use:   "backup <directory>",
+	Short: "Back up the catalog to a directory of Parquet files",
+	Long: `Export every table of every tenant (the ingested catalog and its history, the curated
+documentation, owners, declared schemas, reviews and ingest tokens, the audit log and the state
+of policies, budgets and retention) to a Parquet file each, plus a manifest.json recording the row counts,
+checksums and the migration version of the database. The directory must be empty or missing.
+The files can be read by any Parquet reader for offline analysis.
+
+The database must not be in use. While the server runs, take backups with
+POST /api/v1/backups instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStorage(backupStorage, backupDatabasePath, nil, slog.Default())
+		if err != nil {
+			return err
+		}
+		defer store.close()
+		if store.backupRepo == nil {
+			return fmt.Errorf("the %s storage backend does not support backups", backupStorage)
+		}
+
+		manifest, err := store.backupRepo.Backup(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to back up catalog: %w", err)
+		}
+
+		var rows int64
+		for _, table := range manifest.Tables {
+			rows += table.Rows
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Backed up %d rows of %d tables to %s (migration version %d)\n",
+			rows, len(manifest.Tables), args[0], manifest.MigrationVersion)
+		return nil
+	},
+}
+
+// restoreCmd restores a backup into a database the server is not running on
+var restoreCmd = &cobra.Command{
+	Use:   "restore <directory>",
+	Short: "Restore a catalog backup into an empty database",
+	Long: `Load a directory written by "tallycat backup" or POST /api/v1/backups into the database,
+which is created and migrated if needed. The backup must have been taken at the migration
+version of this release, every file must match the checksum and row count of the manifest, and
+the catalog must be empty. Nothing is restored unless every table is.
+
+The database must not be in use. While the server runs, restore backups with
+POST /api/v1/backups/{name}/restore instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := openStorage(backupStorage, backupDatabasePath, nil, slog.Default())
+		if err != nil {
+			return err
+		}
+		defer store.close()
+		if store.backupRepo == nil {
+			return fmt.Errorf("the %s storage backend does not support backups", backupStorage)
+		}
+		if err := store.migrate(); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
+
+		manifest, err := store.backupRepo.Restore(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		var rows int64
+		for _, table := range manifest.Tables {
+			rows += table.Rows
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Restored %d rows of %d tables from the backup of %s\n",
+			rows, len(manifest.Tables), manifest.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		return nil
+	},
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{backupCmd, restoreCmd} {
+		cmd.Flags().StringVarP(&backupDatabasePath, "database-path", "d", "tallycat.db", "Path to the database file")
+		cmd.Flags().StringVar(&backupStorage, "storage", storageDuckDB, "Storage backend of the database, only duckdb supports backups")
+		rootCmd.AddCommand(cmd)
+	}
+}
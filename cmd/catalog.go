@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// dumpPageSize is the page size the catalog is read with
+const dumpPageSize = 500
+
+// catalogDump is the catalog written on shutdown, for CI jobs to archive what was ingested
+type catalogDump struct {
+	GeneratedAt time.Time       `json:"generatedAt"`
+	Tenants     []tenantCatalog `json:"tenants"`
+}
+
+type tenantCatalog struct {
+	Name        string             `json:"name"`
+	Telemetries []catalogTelemetry `json:"telemetries"`
+	Entities    []schema.Entity    `json:"entities"`
+	Scopes      []schema.Scope     `json:"scopes"`
+}
+
+// catalogTelemetry is the latest variant of a telemetry with every variant seen
+type catalogTelemetry struct {
+	schema.Telemetry
+	Schemas []schema.TelemetrySchema `json:"schemas"`
+}
+
+// dumpCatalog writes the catalog of every tenant as JSON. The file is replaced at once, so a
+// job reading it never sees a partial catalog.
+func dumpCatalog(ctx context.Context, store *storage, path string) error {
+	tenants, err := store.tenantRepo.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list tenants: %w", err)
+	}
+
+	dump := catalogDump{GeneratedAt: time.Now().UTC(), Tenants: []tenantCatalog{}}
+	for _, summary := range tenants {
+		catalog, err := readTenantCatalog(tenant.WithTenant(ctx, summary.Name), store)
+		if err != nil {
+			return fmt.Errorf("failed to read the catalog of tenant %s: %w", summary.Name, err)
+		}
+		catalog.Name = summary.Name
+		dump.Tenants = append(dump.Tenants, *catalog)
+	}
+
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode catalog: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create catalog file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write catalog file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write catalog file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace catalog file: %w", err)
+	}
+	return nil
+}
+
+func readTenantCatalog(ctx context.Context, store *storage) (*tenantCatalog, error) {
+	catalog := &tenantCatalog{
+		Telemetries: []catalogTelemetry{},
+		Entities:    []schema.Entity{},
+		Scopes:      []schema.Scope{},
+	}
+
+	var latest []schema.Telemetry
+	for page := 1; ; page++ {
+		telemetries, total, err := store.schemaRepo.ListTelemetries(ctx, query.ListQueryParams{Page: page, PageSize: dumpPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list telemetries: %w", err)
+		}
+		latest = append(latest, telemetries...)
+		if len(telemetries) == 0 || len(latest) >= total {
+			break
+		}
+	}
+
+	for _, listed := range latest {
+		// Listed telemetries come without their attributes
+		telemetry, err := store.schemaRepo.GetTelemetry(ctx, listed.SchemaKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get telemetry %s: %w", listed.SchemaKey, err)
+		}
+		if telemetry == nil {
+			continue
+		}
+
+		var schemas []schema.TelemetrySchema
+		for page := 1; ; page++ {
+			rows, total, err := store.schemaRepo.ListTelemetrySchemas(ctx, listed.SchemaKey, query.ListQueryParams{Page: page, PageSize: dumpPageSize})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list schemas of %s: %w", listed.SchemaKey, err)
+			}
+			schemas = append(schemas, rows...)
+			if len(rows) == 0 || len(schemas) >= total {
+				break
+			}
+		}
+		catalog.Telemetries = append(catalog.Telemetries, catalogTelemetry{Telemetry: *telemetry, Schemas: schemas})
+	}
+
+	for page := 1; ; page++ {
+		entities, total, err := store.schemaRepo.ListEntities(ctx, query.ListQueryParams{Page: page, PageSize: dumpPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list entities: %w", err)
+		}
+		catalog.Entities = append(catalog.Entities, entities...)
+		if len(entities) == 0 || len(catalog.Entities) >= total {
+			break
+		}
+	}
+
+	for page := 1; ; page++ {
+		scopes, total, err := store.schemaRepo.ListScopes(ctx, query.ListQueryParams{Page: page, PageSize: dumpPageSize})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list scopes: %w", err)
+		}
+		catalog.Scopes = append(catalog.Scopes, scopes...)
+		if len(scopes) == 0 || len(catalog.Scopes) >= total {
+			break
+		}
+	}
+	return catalog, nil
+}
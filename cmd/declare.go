@@ -0,0 +1,131 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	declareServer     string
+	declareRegistry   string
+	declareEntityType string
+	declareScope      string
+)
+
+// declareCmd imports declared schemas from Weaver registry files
+var declareCmd = &cobra.Command{
+	Use:   "declare <file or directory>...",
+	Short: "Declare the telemetry of a Weaver registry ahead of it being emitted",
+	Long: `Import the metric, event and span groups of Weaver registry YAML files into a running
+TallyCat server as declared schemas. Directories are read for .yaml and .yml files.
+Importing a registry again replaces its declared schemas. Coverage reports comparing declared
+and observed telemetry are served at /api/v1/entities/{entityType}/coverage and
+/api/v1/scopes/{scope}/coverage.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var documents [][]byte
+		for _, arg := range args {
+			files, err := registryFiles(arg)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read registry file %s: %w", file, err)
+				}
+				documents = append(documents, data)
+			}
+		}
+
+		params := url.Values{"registry": {declareRegistry}}
+		if declareEntityType != "" {
+			params.Set("entityType", declareEntityType)
+		}
+		if declareScope != "" {
+			params.Set("scope", declareScope)
+		}
+		endpoint := strings.TrimSuffix(declareServer, "/") + "/api/v1/declared-schemas?" + params.Encode()
+
+		body := bytes.Join(documents, []byte("\n---\n"))
+		req, err := http.NewRequestWithContext(cmd.Context(), http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/yaml")
+		if token := os.Getenv("TALLYCAT_API_TOKEN"); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		client := &http.Client{Timeout: 30 * time.Second}
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to import registry: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			message, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("failed to import registry: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+		}
+
+		var result struct {
+			Registry string `json:"registry"`
+			Imported int    `json:"imported"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Declared %d schemas in registry %s\n", result.Imported, result.Registry)
+		return nil
+	},
+}
+
+// registryFiles returns the file itself, or the YAML files of a directory tree
+func registryFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ext := filepath.Ext(file); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry directory %s: %w", path, err)
+	}
+	return files, nil
+}
+
+func init() {
+	rootCmd.AddCommand(declareCmd)
+
+	declareCmd.Flags().StringVar(&declareServer, "server", "http://localhost:8080", "URL of the TallyCat HTTP server, authenticated with $TALLYCAT_API_TOKEN")
+	declareCmd.Flags().StringVar(&declareRegistry, "registry", "", "Name of the registry, importing it again replaces its declared schemas")
+	declareCmd.Flags().StringVar(&declareEntityType, "entity-type", "", "Entity type expected to emit the declared telemetry")
+	declareCmd.Flags().StringVar(&declareScope, "scope", "", "Instrumentation scope expected to emit the declared telemetry")
+	declareCmd.MarkFlagRequired("registry")
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/tallycat/tallycat/internal/repository/migrator"
+)
+
+var (
+	migrateDatabasePath string
+	migrateStorage      string
+)
+
+// migrateCmd groups the commands managing the schema of the database
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply, roll back and inspect database migrations",
+	Long: `Manage the migrations of the database schema. The server applies pending migrations on
+start and refuses to start when a migration fails, when an applied migration was edited since,
+or when the database was migrated by a newer release.
+
+Migrations run under a lock kept in the database, so a second migrator fails instead of running
+at the same time. The lock is refreshed while migrations run, and a lock left by a crashed
+migrator is broken once it went 15 minutes without a refresh.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending migration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMigration(cmd, func(m *migrator.Migrator, migrations []migrator.Migration) error {
+			return m.Migrate(migrations)
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the last applied migration",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMigration(cmd, func(m *migrator.Migrator, migrations []migrator.Migration) error {
+			return m.Rollback(migrations)
+		})
+	},
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version>",
+	Short: "Apply or roll back migrations until the database is at a version, 0 rolling back all",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		version, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", args[0])
+		}
+		return runMigration(cmd, func(m *migrator.Migrator, migrations []migrator.Migration) error {
+			return m.MigrateTo(migrations, version)
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the migrations of this release and whether they are applied",
+	Long: `List every migration of this release and every migration applied to the database, as
+pending, applied, modified (edited since it was applied) or unknown (applied by a newer
+release).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *migrator.Migrator, migrations []migrator.Migration) error {
+			statuses, err := m.Status(migrations)
+			if err != nil {
+				return fmt.Errorf("failed to read migration status: %w", err)
+			}
+			version, err := m.Version()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+			for _, status := range statuses {
+				appliedAt := "-"
+				if status.AppliedAt != nil {
+					appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			latest := 0
+			if len(migrations) > 0 {
+				latest = migrations[len(migrations)-1].Version
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "\nDatabase at version %d, this release at version %d\n", version, latest)
+			return nil
+		})
+	},
+}
+
+// withMigrator runs fn with the migrator of the database the migrate flags name
+func withMigrator(fn func(m *migrator.Migrator, migrations []migrator.Migration) error) error {
+	store, err := openStorage(migrateStorage, migrateDatabasePath, nil, slog.Default())
+	if err != nil {
+		return err
+	}
+	defer store.close()
+
+	m, migrations, err := store.migrator()
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return fmt.Errorf("the %s storage backend has no migrations", migrateStorage)
+	}
+	return fn(m, migrations)
+}
+
+// runMigration runs a migration and reports the version the database ends at
+func runMigration(cmd *cobra.Command, fn func(m *migrator.Migrator, migrations []migrator.Migration) error) error {
+	return withMigrator(func(m *migrator.Migrator, migrations []migrator.Migration) error {
+		if err := fn(m, migrations); err != nil {
+			return fmt.Errorf("failed to migrate: %w", err)
+		}
+		version, err := m.Version()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Database at version %d\n", version)
+		return nil
+	})
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringVarP(&migrateDatabasePath, "database-path", "d", "tallycat.db", "Path to the database file")
+	migrateCmd.PersistentFlags().StringVar(&migrateStorage, "storage", storageDuckDB, "Storage backend of the database, duckdb or sqlite")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateToCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -15,16 +16,18 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tallycat/tallycat/internal/auth"
 	"github.com/tallycat/tallycat/internal/grpcserver"
 	"github.com/tallycat/tallycat/internal/httpserver"
-	"github.com/tallycat/tallycat/internal/repository/duckdb"
-	"github.com/tallycat/tallycat/internal/repository/duckdb/migrator"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tlsconfig"
 	logspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
 	metricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	profilespb "go.opentelemetry.io/proto/otlp/collector/profiles/v1development"
 	tracespb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -34,6 +37,22 @@ var (
 	shutdownTimeout      time.Duration
 	httpAddr             string
 	databasePath         string
+	storageBackend       string
+	entityModelPath      string
+	policiesPath         string
+	budgetsPath          string
+	usageHourlyRetention time.Duration
+	usageDailyRetention  time.Duration
+	retentionPath        string
+	ownershipPath        string
+	apiToken             string
+	authConfigPath       string
+	corsAllowedOrigins   []string
+	tlsConfigPath        string
+	requireIngestToken   bool
+	allowTenantHeader    bool
+	dumpCatalogPath      string
+	backupDir            string
 )
 
 // serverCmd represents the server command
@@ -59,47 +78,209 @@ and processes log data according to the OpenTelemetry protocol.`,
 			"shutdownTimeout", shutdownTimeout,
 		)
 
+		entityModel := schema.DefaultEntityModel()
+		if entityModelPath != "" {
+			var err error
+			entityModel, err = schema.LoadEntityModel(entityModelPath)
+			if err != nil {
+				return fmt.Errorf("failed to load entity model: %w", err)
+			}
+			slog.Info("Loaded entity model", "path", entityModelPath, "entityTypes", len(entityModel.Entities))
+		}
+
+		var authConfig *auth.Config
+		if authConfigPath != "" {
+			var err error
+			authConfig, err = auth.LoadConfig(authConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to load auth config: %w", err)
+			}
+			slog.Info("Loaded auth config", "path", authConfigPath, "tokens", len(authConfig.Tokens), "jwt", authConfig.JWT != nil)
+		}
+		authenticator, err := auth.NewAuthenticator(authConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create authenticator: %w", err)
+		}
+		if apiToken == "" {
+			apiToken = os.Getenv("TALLYCAT_API_TOKEN")
+		}
+		if apiToken != "" {
+			authenticator.AddToken("api-token", auth.RoleEditor, apiToken)
+		}
+
+		var ownershipModel *schema.OwnershipModel
+		if ownershipPath != "" {
+			ownershipModel, err = schema.LoadOwnershipModel(ownershipPath)
+			if err != nil {
+				return fmt.Errorf("failed to load ownership model: %w", err)
+			}
+			slog.Info("Loaded ownership model", "path", ownershipPath, "teams", len(ownershipModel.Teams), "rules", len(ownershipModel.Rules))
+		}
+
 		opts := []grpc.ServerOption{
 			grpc.MaxConcurrentStreams(maxConcurrentStreams),
 			grpc.ConnectionTimeout(connectionTimeout),
 		}
 
+		var interceptors []grpc.UnaryServerInterceptor
+		var httpTLS *tls.Config
+		if tlsConfigPath != "" {
+			tlsCfg, err := tlsconfig.LoadConfig(tlsConfigPath)
+			if err != nil {
+				return fmt.Errorf("failed to load TLS config: %w", err)
+			}
+			if tlsCfg.GRPC != nil {
+				reloader, err := tlsconfig.NewReloader(tlsCfg.GRPC, tlsCfg.ReloadInterval)
+				if err != nil {
+					return fmt.Errorf("failed to load gRPC TLS certificate: %w", err)
+				}
+				opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+				interceptors = append(interceptors, grpcserver.SenderInterceptor(tlsCfg))
+			}
+			if tlsCfg.HTTP != nil {
+				reloader, err := tlsconfig.NewReloader(tlsCfg.HTTP, tlsCfg.ReloadInterval)
+				if err != nil {
+					return fmt.Errorf("failed to load HTTP TLS certificate: %w", err)
+				}
+				httpTLS = reloader.ServerConfig()
+			}
+			slog.Info("Loaded TLS config", "path", tlsConfigPath, "grpc", tlsCfg.GRPC != nil, "http", tlsCfg.HTTP != nil, "identityRules", len(tlsCfg.Identities))
+		}
+
+		store, err := openStorage(storageBackend, databasePath, ownershipModel, logger)
+		if err != nil {
+			return err
+		}
+		defer store.close()
+		if err := checkFeatures(storageBackend, []feature{
+			{name: "ingest tokens (--require-ingest-token)", configured: requireIngestToken, supported: store.ingestTokenRepo != nil},
+			{name: "budgets (--budgets)", configured: budgetsPath != "", supported: store.budgetRepo != nil},
+			{name: "retention policies (--retention)", configured: retentionPath != "", supported: store.retentionRepo != nil},
+			{name: "ownership models (--ownership)", configured: ownershipPath != "", supported: store.ownershipRepo != nil},
+			{name: "usage rollups (--usage-hourly-retention, --usage-daily-retention)",
+				configured: cmd.Flags().Changed("usage-hourly-retention") || cmd.Flags().Changed("usage-daily-retention"),
+				supported:  store.usageRepo != nil},
+			{name: "policy violations (--policies)", configured: policiesPath != "", supported: store.violationRepo != nil, degraded: true},
+			{name: "the audit log of API changes (--auth, --api-token)", configured: authConfigPath != "" || apiToken != "", supported: store.auditRepo != nil, degraded: true},
+		}); err != nil {
+			return err
+		}
+		slog.Info("Opened storage", "backend", storageBackend, "path", databasePath)
+
+		// Authenticate and rate limit OTLP clients by their ingest token
+		var ingestGate *grpcserver.IngestGate
+		if store.ingestTokenRepo != nil {
+			ingestGate = grpcserver.NewIngestGate(store.ingestTokenRepo, requireIngestToken)
+			interceptors = append(interceptors, ingestGate.Interceptor())
+		}
+		// The tenant of an export may come from its certificate or ingest token, so it is resolved last
+		interceptors = append(interceptors, grpcserver.TenantInterceptor(allowTenantHeader))
+		opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
 		srv := grpcserver.NewServer(grpcAddr, opts...)
 
-		pool, err := duckdb.NewConnectionPool(&duckdb.Config{
-			DatabasePath:    databasePath,
-			MaxOpenConns:    10,
-			MaxIdleConns:    5,
-			ConnMaxLifetime: time.Hour,
-			ConnMaxIdleTime: time.Minute * 5,
-		}, logger)
+		// Serving a half-migrated database, or one migrated by a newer release, would corrupt it
+		if err := store.migrate(); err != nil {
+			return fmt.Errorf("failed to run migrations: %w", err)
+		}
 
-		if err != nil {
-			return fmt.Errorf("failed to create connection pool: %w", err)
+		// Entities stored by a release or an entity model identifying them differently are moved
+		// to their ID, so ingesting them again does not split them in two
+		if store.entityRekeyRepo != nil {
+			moved, err := store.entityRekeyRepo.RekeyEntities(ctx, entityModel)
+			if err != nil {
+				return fmt.Errorf("failed to move entities to the entity model: %w", err)
+			}
+			if moved > 0 {
+				slog.Info("Moved entities to the entity model", "entities", moved)
+			}
 		}
 
-		schemaRepo := duckdb.NewTelemetrySchemaRepository(pool.(*duckdb.ConnectionPool))
-		historyRepo := duckdb.NewTelemetryHistoryRepository(pool.(*duckdb.ConnectionPool))
+		// Producers stored per instance by earlier releases are moved to their ID
+		if store.producerRekeyRepo != nil {
+			moved, err := store.producerRekeyRepo.RekeyProducers(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to move producers to their IDs: %w", err)
+			}
+			if moved > 0 {
+				slog.Info("Moved producers to their IDs", "producers", moved)
+			}
+		}
 
-		// Run migrations using the pool connection
-		db := pool.GetConnection()
-		if err := migrator.ApplyMigrations(db); err != nil {
-			slog.Error("failed to run migrations", "error", err)
+		// Resolve ownership again in case the ownership model changed since the last run
+		if store.ownershipRepo != nil {
+			if err := store.ownershipRepo.RefreshOwnership(ctx); err != nil {
+				slog.Error("failed to refresh ownership", "error", err)
+			}
 		}
 
-		logsService := grpcserver.NewLogsServiceServer(schemaRepo)
+		// Policies created by rejecting telemetry in review are enforced even without a policy file
+		var policies *schema.PolicySet
+		if policiesPath != "" {
+			policies, err = schema.LoadPolicies(policiesPath)
+			if err != nil {
+				return fmt.Errorf("failed to load policies: %w", err)
+			}
+			slog.Info("Loaded policies", "path", policiesPath, "policies", len(policies.Policies))
+		}
+		policyEnforcer := grpcserver.NewPolicyEnforcer(policies, store.violationRepo, store.reviewRepo)
+
+		var budgets *schema.BudgetSet
+		var budgetEnforcer *grpcserver.BudgetEnforcer
+		if budgetsPath != "" {
+			budgets, err = schema.LoadBudgets(budgetsPath)
+			if err != nil {
+				return fmt.Errorf("failed to load budgets: %w", err)
+			}
+			budgetEnforcer = grpcserver.NewBudgetEnforcer(budgets, store.budgetRepo)
+			slog.Info("Loaded budgets", "path", budgetsPath, "budgets", len(budgets.Budgets))
+		}
+
+		retention := schema.DefaultRetentionPolicy()
+		if retentionPath != "" {
+			retention, err = schema.LoadRetentionPolicy(retentionPath)
+			if err != nil {
+				return fmt.Errorf("failed to load retention policy: %w", err)
+			}
+			slog.Info("Loaded retention policy", "path", retentionPath, "action", retention.Action)
+		}
+
+		logsService := grpcserver.NewLogsServiceServer(entityModel, store.schemaRepo, policyEnforcer, budgetEnforcer)
 		srv.RegisterService(&logspb.LogsService_ServiceDesc, logsService)
 
-		metricsService := grpcserver.NewMetricsServiceServer(schemaRepo)
+		metricsService := grpcserver.NewMetricsServiceServer(entityModel, store.schemaRepo, policyEnforcer, budgetEnforcer)
 		srv.RegisterService(&metricspb.MetricsService_ServiceDesc, metricsService)
 
-		tracesService := grpcserver.NewTracesServiceServer(schemaRepo)
+		tracesService := grpcserver.NewTracesServiceServer(entityModel, store.schemaRepo, store.dependencyRepo, policyEnforcer)
 		srv.RegisterService(&tracespb.TraceService_ServiceDesc, tracesService)
 
-		profilesService := grpcserver.NewProfilesServiceServer(schemaRepo)
+		profilesService := grpcserver.NewProfilesServiceServer(entityModel, store.schemaRepo, policyEnforcer)
 		srv.RegisterService(&profilespb.ProfilesService_ServiceDesc, profilesService)
 
-		httpSrv := httpserver.New(httpAddr, schemaRepo, historyRepo)
+		httpSrv := httpserver.New(&httpserver.Config{
+			Addr:               httpAddr,
+			TLSConfig:          httpTLS,
+			CORSAllowedOrigins: corsAllowedOrigins,
+			Authenticator:      authenticator,
+			SchemaRepo:         store.schemaRepo,
+			HistoryRepo:        store.historyRepo,
+			EntityHistoryRepo:  store.entityHistoryRepo,
+			DependencyRepo:     store.dependencyRepo,
+			ViolationRepo:      store.violationRepo,
+			BudgetRepo:         store.budgetRepo,
+			UsageRepo:          store.usageRepo,
+			RetentionRepo:      store.retentionRepo,
+			OwnershipRepo:      store.ownershipRepo,
+			DocRepo:            store.docRepo,
+			DeclaredRepo:       store.declaredRepo,
+			ReviewRepo:         store.reviewRepo,
+			AuditRepo:          store.auditRepo,
+			IngestTokenRepo:    store.ingestTokenRepo,
+			TenantRepo:         store.tenantRepo,
+			BackupRepo:         store.backupRepo,
+			Budgets:            budgets,
+			Retention:          retention,
+			BackupDir:          backupDir,
+		})
 
 		g, _ := errgroup.WithContext(ctx)
 
@@ -117,6 +298,63 @@ and processes log data according to the OpenTelemetry protocol.`,
 			return nil
 		})
 
+		// Downsample and expire the usage rollups every hour
+		usageRetention := schema.UsageRetention{Hourly: usageHourlyRetention, Daily: usageDailyRetention}
+		if store.usageRepo != nil {
+			g.Go(func() error {
+				ticker := time.NewTicker(time.Hour)
+				defer ticker.Stop()
+				for {
+					if err := store.usageRepo.CompactUsage(ctx, time.Now(), usageRetention); err != nil {
+						slog.Error("failed to compact usage rollups", "error", err)
+					}
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-ticker.C:
+					}
+				}
+			})
+
+		}
+
+		// Write the usage of the ingest tokens counted in memory
+		if ingestGate != nil {
+			g.Go(func() error {
+				ticker := time.NewTicker(grpcserver.IngestUsageFlushInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-ticker.C:
+					}
+					if err := ingestGate.FlushUsage(ctx); err != nil {
+						slog.Error("failed to record ingest usage", "error", err)
+					}
+				}
+			})
+		}
+
+		// Mark stale schemas and remove the expired ones
+		if store.retentionRepo != nil {
+			g.Go(func() error {
+				ticker := time.NewTicker(retention.Interval)
+				defer ticker.Stop()
+				for {
+					if _, err := store.retentionRepo.RunRetention(ctx, retention, time.Now()); err != nil {
+						slog.Error("failed to run retention", "error", err)
+					}
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-ticker.C:
+					}
+				}
+			})
+
+		}
+
 		func() {
 			sigChan := make(chan os.Signal, 1)
 			signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
@@ -125,7 +363,6 @@ and processes log data according to the OpenTelemetry protocol.`,
 				switch sig {
 				case syscall.SIGTERM, syscall.SIGINT:
 					slog.Info("Received shutdown signal", "signal", sig)
-					pool.Close()
 					cancel()
 					return
 				case syscall.SIGHUP:
@@ -157,6 +394,21 @@ and processes log data according to the OpenTelemetry protocol.`,
 			srv.ForceStop()
 		}
 
+		// Usage counted since the last flush is written once ingest has stopped
+		if ingestGate != nil {
+			if err := ingestGate.FlushUsage(context.Background()); err != nil {
+				slog.Error("failed to record ingest usage", "error", err)
+			}
+		}
+
+		// The catalog is final once ingest has stopped, the storage is closed after it is written
+		if dumpCatalogPath != "" {
+			if err := dumpCatalog(context.Background(), store, dumpCatalogPath); err != nil {
+				return fmt.Errorf("failed to dump catalog: %w", err)
+			}
+			slog.Info("Dumped catalog", "path", dumpCatalogPath)
+		}
+
 		return nil
 	},
 }
@@ -171,6 +423,22 @@ func init() {
 	serverCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "Graceful shutdown timeout duration")
 	serverCmd.Flags().StringVarP(&httpAddr, "http-addr", "H", ":8080", "Address to listen on for HTTP server (default: :8080)")
 	serverCmd.Flags().StringVarP(&databasePath, "database-path", "d", "tallycat.db", "Path to the database file")
+	serverCmd.Flags().StringVar(&storageBackend, "storage", storageDuckDB, "Storage backend, duckdb, sqlite or memory, see docs/storage-backends.md")
+	serverCmd.Flags().StringVar(&backupDir, "backup-dir", "backups", "Directory the backups taken and restored through /api/v1/backups are kept in")
+	serverCmd.Flags().StringVar(&dumpCatalogPath, "dump-catalog", "", "Path to write the catalog to as JSON when the server stops, e.g. to archive what a CI run emitted (default: none)")
+	serverCmd.Flags().StringVar(&policiesPath, "policies", "", "Path to a YAML file defining governance policies enforced on ingest (default: none)")
+	serverCmd.Flags().StringVar(&budgetsPath, "budgets", "", "Path to a YAML file defining cardinality and volume budgets (default: none)")
+	serverCmd.Flags().DurationVar(&usageHourlyRetention, "usage-hourly-retention", schema.DefaultUsageRetention.Hourly, "How long hourly usage rollups are kept before being downsampled to daily rollups")
+	serverCmd.Flags().DurationVar(&usageDailyRetention, "usage-daily-retention", schema.DefaultUsageRetention.Daily, "How long daily usage rollups are kept")
+	serverCmd.Flags().StringVar(&retentionPath, "retention", "", "Path to a YAML file defining when unseen schemas become stale and are removed (default: stale after 7 days, never removed)")
+	serverCmd.Flags().StringVar(&ownershipPath, "ownership", "", "Path to a YAML file defining teams and the rules assigning them telemetry (default: explicit assignments only)")
+	serverCmd.Flags().StringVar(&apiToken, "api-token", "", "Bearer token granted the editor role, in addition to the tokens of the auth config (default: $TALLYCAT_API_TOKEN)")
+	serverCmd.Flags().StringVar(&authConfigPath, "auth", "", "Path to a YAML file defining API tokens, JWT validation and roles (default: anonymous viewers, editing with --api-token only)")
+	serverCmd.Flags().BoolVar(&requireIngestToken, "require-ingest-token", false, "Refuse OTLP exports without an ingest token, managed at /api/v1/ingest-tokens (default: tokens are optional)")
+	serverCmd.Flags().BoolVar(&allowTenantHeader, "allow-tenant-header", false, "Let OTLP clients without a tenant-bound ingest token or client certificate pick their tenant with the x-tallycat-tenant metadata (default: default tenant only)")
+	serverCmd.Flags().StringVar(&tlsConfigPath, "tls", "", "Path to a YAML file enabling TLS and client certificate verification on the gRPC and HTTP listeners (default: plaintext)")
+	serverCmd.Flags().StringSliceVar(&corsAllowedOrigins, "cors-allowed-origins", nil, "Origins allowed to call the HTTP API from a browser, e.g. https://*.example.com (default: same origin only)")
+	serverCmd.Flags().StringVar(&entityModelPath, "entity-model", "", "Path to a YAML file defining entity types, stored entities are moved to its IDs on start (default: built-in service, host, container and k8s entities)")
 
 	// Cobra supports Persistent Flags which will work for this command
 	// and all subcommands, e.g.:
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/repository/duckdb"
+	"github.com/tallycat/tallycat/internal/repository/memory"
+	"github.com/tallycat/tallycat/internal/repository/migrator"
+	"github.com/tallycat/tallycat/internal/repository/sqlite"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+const (
+	storageDuckDB = "duckdb"
+	storageSQLite = "sqlite"
+	storageMemory = "memory"
+)
+
+// storage holds the repositories of a storage backend. Repositories the backend does not
+// implement are nil, and the features built on them are disabled. The memory backend has no
+// database, so its pool and migrations are nil too.
+type storage struct {
+	pool       repository.ConnectionProvider
+	migrations fs.FS
+
+	schemaRepo        repository.TelemetrySchemaRepository
+	historyRepo       repository.TelemetryHistoryRepository
+	entityRekeyRepo   repository.EntityRekeyRepository
+	producerRekeyRepo repository.ProducerRekeyRepository
+	entityHistoryRepo repository.EntityHistoryRepository
+	dependencyRepo    repository.DependencyGraphRepository
+	violationRepo     repository.PolicyViolationRepository
+	budgetRepo        repository.BudgetRepository
+	usageRepo         repository.UsageRollupRepository
+	retentionRepo     repository.RetentionRepository
+	ownershipRepo     repository.OwnershipRepository
+	docRepo           repository.DocumentationRepository
+	declaredRepo      repository.DeclaredSchemaRepository
+	reviewRepo        repository.ReviewRepository
+	auditRepo         repository.AuditLogRepository
+	ingestTokenRepo   repository.IngestTokenRepository
+	tenantRepo        repository.TenantRepository
+	backupRepo        repository.BackupRepository
+}
+
+func openStorage(kind, databasePath string, ownershipModel *schema.OwnershipModel, logger *slog.Logger) (*storage, error) {
+	switch kind {
+	case storageDuckDB:
+		provider, err := duckdb.NewConnectionPool(&duckdb.Config{
+			DatabasePath:    databasePath,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: time.Hour,
+			ConnMaxIdleTime: time.Minute * 5,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create connection pool: %w", err)
+		}
+		pool := provider.(*duckdb.ConnectionPool)
+		schemaRepo := duckdb.NewTelemetrySchemaRepository(pool, ownershipModel)
+
+		return &storage{
+			pool:              provider,
+			migrations:        duckdb.EmbeddedMigrations,
+			schemaRepo:        schemaRepo,
+			historyRepo:       duckdb.NewTelemetryHistoryRepository(pool),
+			entityRekeyRepo:   schemaRepo,
+			producerRekeyRepo: schemaRepo,
+			entityHistoryRepo: duckdb.NewEntityHistoryRepository(pool),
+			dependencyRepo:    duckdb.NewDependencyGraphRepository(pool),
+			violationRepo:     duckdb.NewPolicyViolationRepository(pool),
+			budgetRepo:        duckdb.NewBudgetRepository(pool),
+			usageRepo:         duckdb.NewUsageRollupRepository(pool),
+			retentionRepo:     duckdb.NewRetentionRepository(pool),
+			ownershipRepo:     duckdb.NewOwnershipRepository(pool, ownershipModel),
+			docRepo:           duckdb.NewDocumentationRepository(pool),
+			declaredRepo:      duckdb.NewDeclaredSchemaRepository(pool),
+			reviewRepo:        duckdb.NewReviewRepository(pool),
+			auditRepo:         duckdb.NewAuditLogRepository(pool),
+			ingestTokenRepo:   duckdb.NewIngestTokenRepository(pool),
+			tenantRepo:        duckdb.NewTenantRepository(pool),
+			backupRepo:        duckdb.NewBackupRepository(pool),
+		}, nil
+
+	case storageSQLite:
+		// Readers share the database with the single writer WAL mode allows
+		provider, err := sqlite.NewConnectionPool(&sqlite.Config{
+			DatabasePath:    databasePath,
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: time.Hour,
+			ConnMaxIdleTime: time.Minute * 5,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create connection pool: %w", err)
+		}
+		pool := provider.(*sqlite.ConnectionPool)
+
+		schemaRepo := sqlite.NewTelemetrySchemaRepository(pool)
+
+		// The SQLite backend keeps the catalog only
+		return &storage{
+			pool:              provider,
+			migrations:        sqlite.EmbeddedMigrations,
+			schemaRepo:        schemaRepo,
+			historyRepo:       sqlite.NewTelemetryHistoryRepository(pool),
+			entityRekeyRepo:   schemaRepo,
+			producerRekeyRepo: schemaRepo,
+			tenantRepo:        sqlite.NewTenantRepository(pool),
+		}, nil
+
+	case storageMemory:
+		// The memory backend keeps the catalog only, until the server stops
+		store := memory.NewStore()
+		return &storage{
+			schemaRepo:  memory.NewTelemetrySchemaRepository(store),
+			historyRepo: memory.NewTelemetryHistoryRepository(store),
+			tenantRepo:  memory.NewTenantRepository(store),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q, expected %s, %s or %s", kind, storageDuckDB, storageSQLite, storageMemory)
+}
+
+// feature is a server feature built on a repository the storage backend may not implement
+type feature struct {
+	name       string
+	configured bool
+	supported  bool
+	// degraded features still run without the repository, missing only what it records
+	degraded bool
+}
+
+// checkFeatures refuses to start when a configured feature needs a repository the backend does
+// not implement, as it would otherwise be disabled without notice, and warns about configured
+// features running without the records of a missing repository
+func checkFeatures(kind string, features []feature) error {
+	var unsupported []string
+	for _, f := range features {
+		if !f.configured || f.supported {
+			continue
+		}
+		if f.degraded {
+			slog.Warn("The storage backend does not record a configured feature", "backend", kind, "feature", f.name)
+			continue
+		}
+		unsupported = append(unsupported, f.name)
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("the %s storage backend does not support %s", kind, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// migrator returns the migrator of the backend database, with its tables created, and the
+// migrations of this release. The migrator is nil for backends without a database.
+func (s *storage) migrator() (*migrator.Migrator, []migrator.Migration, error) {
+	if s.migrations == nil {
+		return nil, nil, nil
+	}
+
+	m := migrator.New(s.pool.GetConnection(), slog.Default())
+	if err := m.Initialize(); err != nil {
+		return nil, nil, err
+	}
+	migrations, err := m.LoadMigrations(s.migrations)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	return m, migrations, nil
+}
+
+// migrate applies the pending migrations, refusing databases with edited or unknown migrations
+func (s *storage) migrate() error {
+	m, migrations, err := s.migrator()
+	if err != nil || m == nil {
+		return err
+	}
+	return m.Migrate(migrations)
+}
+
+// close closes the database of the backend, if it has one
+func (s *storage) close() error {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Close()
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var b64 = base64.RawURLEncoding.EncodeToString
+
+type testSigner struct {
+	kid string
+	alg string
+	key crypto.Signer
+}
+
+func (s testSigner) jwk() map[string]string {
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kid": s.kid, "kty": "RSA", "alg": s.alg, "n": b64(pub.N.Bytes()), "e": b64(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kid": s.kid, "kty": "EC", "crv": pub.Curve.Params().Name, "x": b64(pub.X.Bytes()), "y": b64(pub.Y.Bytes())}
+	case ed25519.PublicKey:
+		return map[string]string{"kid": s.kid, "kty": "OKP", "crv": "Ed25519", "x": b64(pub)}
+	}
+	return nil
+}
+
+func (s testSigner) sign(t *testing.T, claims map[string]any) string {
+	header, err := json.Marshal(map[string]string{"alg": s.alg, "kid": s.kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := b64(header) + "." + b64(payload)
+
+	var signature []byte
+	switch key := s.key.(type) {
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		r, sig, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	}
+	return signed + "." + b64(signature)
+}
+
+func newSigners(t *testing.T) []testSigner {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return []testSigner{
+		{kid: "rsa", alg: "RS256", key: rsaKey},
+		{kid: "ec", alg: "ES256", key: ecKey},
+		{kid: "ed", alg: "EdDSA", key: edKey},
+	}
+}
+
+func writeJWKS(t *testing.T, dir string, signers []testSigner) {
+	keys := make([]map[string]string, 0, len(signers))
+	for _, s := range signers {
+		keys = append(keys, s.jwk())
+	}
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwks.json"), data, 0o600))
+}
+
+func tokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func authenticate(t *testing.T, a *Authenticator, header string) (*Identity, error) {
+	t.Helper()
+	r := httptest.NewRequest("GET", "/api/v1/telemetries", nil)
+	if header != "" {
+		r.Header.Set("Authorization", header)
+	}
+	return a.Authenticate(r)
+}
+
+func TestAuthenticator_Tokens(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`
+tokens:
+  - name: ci
+    role: editor
+    sha256: ` + tokenHash("s3cret") + `
+`))
+	require.NoError(t, err)
+	a, err := NewAuthenticator(cfg)
+	require.NoError(t, err)
+	a.AddToken("api-token", RoleAdmin, "legacy")
+
+	identity, err := authenticate(t, a, "Bearer s3cret")
+	require.NoError(t, err)
+	require.Equal(t, &Identity{Subject: "ci", Role: RoleEditor, Method: MethodToken}, identity)
+
+	identity, err = authenticate(t, a, "Bearer legacy")
+	require.NoError(t, err)
+	require.Equal(t, RoleAdmin, identity.Role)
+
+	_, err = authenticate(t, a, "Bearer wrong")
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = authenticate(t, a, "Basic czNjcmV0")
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	// Anonymous access is refused unless a role is configured
+	identity, err = authenticate(t, a, "")
+	require.NoError(t, err)
+	require.Nil(t, identity)
+
+	a, err = NewAuthenticator(nil)
+	require.NoError(t, err)
+	identity, err = authenticate(t, a, "")
+	require.NoError(t, err)
+	require.Equal(t, RoleViewer, identity.Role)
+	require.Equal(t, MethodAnonymous, identity.Method)
+}
+
+func TestAuthenticator_JWT(t *testing.T) {
+	dir := t.TempDir()
+	signers := newSigners(t)
+	writeJWKS(t, dir, signers)
+
+	configPath := filepath.Join(dir, "auth.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+jwt:
+  jwks_file: jwks.json
+  issuer: https://idp.example.com
+  audience: tallycat
+  role_claim: groups
+  role_mapping:
+    platform-team: admin
+`), 0o600))
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "jwks.json"), cfg.JWT.JWKSFile)
+
+	a, err := NewAuthenticator(cfg)
+	require.NoError(t, err)
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":    "alice",
+			"iss":    "https://idp.example.com",
+			"aud":    []string{"tallycat", "other"},
+			"exp":    now.Add(time.Hour).Unix(),
+			"groups": []string{"viewer", "platform-team"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	for _, s := range signers {
+		t.Run(s.alg, func(t *testing.T) {
+			identity, err := authenticate(t, a, "Bearer "+s.sign(t, claims(nil)))
+			require.NoError(t, err)
+			require.Equal(t, &Identity{Subject: "alice", Role: RoleAdmin, Method: MethodJWT}, identity)
+		})
+	}
+
+	rsaSigner := signers[0]
+	for name, c := range map[string]map[string]any{
+		"expired":        {"exp": now.Add(-time.Hour).Unix()},
+		"no expiry":      {"exp": nil},
+		"not valid yet":  {"nbf": now.Add(time.Hour).Unix()},
+		"wrong issuer":   {"iss": "https://other.example.com"},
+		"wrong audience": {"aud": "other"},
+		"no subject":     {"sub": nil},
+		"no role":        {"groups": []string{"unknown"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := authenticate(t, a, "Bearer "+rsaSigner.sign(t, claims(c)))
+			require.ErrorIs(t, err, ErrUnauthenticated)
+		})
+	}
+
+	t.Run("tampered", func(t *testing.T) {
+		token := rsaSigner.sign(t, claims(nil))
+		forged := rsaSigner.sign(t, claims(map[string]any{"sub": "mallory"}))
+		parts, forgedParts := strings.Split(token, "."), strings.Split(forged, ".")
+		_, err := authenticate(t, a, "Bearer "+parts[0]+"."+forgedParts[1]+"."+parts[2])
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("algorithm not allowed by the key", func(t *testing.T) {
+		s := rsaSigner
+		s.alg = "RS384"
+		_, err := authenticate(t, a, "Bearer "+s.sign(t, claims(nil)))
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		s := signers[1]
+		s.kid = "rotated"
+		_, err := authenticate(t, a, "Bearer "+s.sign(t, claims(nil)))
+		require.ErrorIs(t, err, ErrUnauthenticated)
+	})
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown role":    "tokens: [{name: ci, role: owner, sha256: " + tokenHash("x") + "}]",
+		"plain token":     "tokens: [{name: ci, role: viewer, sha256: s3cret}]",
+		"duplicate token": "tokens: [{name: ci, role: viewer, sha256: " + tokenHash("x") + "}, {name: ci, role: admin, sha256: " + tokenHash("y") + "}]",
+		"no jwks file":    "jwt: {issuer: https://idp.example.com}",
+		"role mapping":    "jwt: {jwks_file: jwks.json, role_mapping: {devs: root}}",
+		"anonymous role":  "anonymous_role: guest",
+		"invalid tenant":  "tokens: [{name: ci, role: viewer, sha256: " + tokenHash("x") + ", tenants: ['a b']}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(content))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRole_Allows(t *testing.T) {
+	require.True(t, RoleAdmin.Allows(RoleEditor))
+	require.True(t, RoleEditor.Allows(RoleEditor))
+	require.False(t, RoleViewer.Allows(RoleEditor))
+	require.False(t, Role("").Allows(RoleViewer))
+}
+
+func TestIdentity_CanAccessTenant(t *testing.T) {
+	viewer := &Identity{Subject: "ci", Role: RoleViewer}
+	require.True(t, viewer.CanAccessTenant("default"))
+	require.False(t, viewer.CanAccessTenant("payments"))
+
+	scoped := &Identity{Subject: "ci", Role: RoleEditor, Tenants: []string{"payments"}}
+	require.True(t, scoped.CanAccessTenant("payments"))
+	require.False(t, scoped.CanAccessTenant("default"))
+	require.False(t, scoped.CanAccessTenant("*"))
+
+	every := &Identity{Subject: "ci", Role: RoleEditor, Tenants: []string{"*"}}
+	require.True(t, every.CanAccessTenant("search"))
+	require.False(t, every.CanAccessTenant("*"), "cross-tenant views are for admins")
+
+	admin := &Identity{Subject: "root", Role: RoleAdmin}
+	require.True(t, admin.CanAccessTenant("payments"))
+	require.True(t, admin.CanAccessTenant("*"))
+}
+
+func TestAuthenticator_Tenants(t *testing.T) {
+	dir := t.TempDir()
+	signers := newSigners(t)
+	writeJWKS(t, dir, signers)
+
+	cfg, err := ParseConfig([]byte(`
+tokens:
+  - name: payments-ci
+    role: editor
+    sha256: ` + tokenHash("s3cret") + `
+    tenants: [payments]
+jwt:
+  jwks_file: ` + filepath.Join(dir, "jwks.json") + `
+  tenant_claim: business_units
+`))
+	require.NoError(t, err)
+	a, err := NewAuthenticator(cfg)
+	require.NoError(t, err)
+
+	identity, err := authenticate(t, a, "Bearer s3cret")
+	require.NoError(t, err)
+	require.Equal(t, []string{"payments"}, identity.Tenants)
+
+	now := time.Now()
+	identity, err = authenticate(t, a, "Bearer "+signers[0].sign(t, map[string]any{
+		"sub":            "alice",
+		"exp":            now.Add(time.Hour).Unix(),
+		"role":           "viewer",
+		"business_units": []string{"payments", "search"},
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"payments", "search"}, identity.Tenants)
+	require.True(t, identity.CanAccessTenant("search"))
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnauthenticated is returned for requests with missing or invalid credentials
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authenticator resolves the identity of HTTP requests from static API tokens or signed JWTs
+type Authenticator struct {
+	tokens        []token
+	jwt           *JWTConfig
+	keys          *KeySet
+	anonymousRole Role
+	now           func() time.Time
+}
+
+type token struct {
+	name    string
+	role    Role
+	hash    []byte
+	tenants []string
+}
+
+// NewAuthenticator loads the JWKS file of the config. Without a config, requests without
+// credentials are granted the viewer role.
+func NewAuthenticator(cfg *Config) (*Authenticator, error) {
+	if cfg == nil {
+		return &Authenticator{anonymousRole: RoleViewer, now: time.Now}, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	a := &Authenticator{jwt: cfg.JWT, anonymousRole: cfg.AnonymousRole, now: time.Now}
+	for _, t := range cfg.Tokens {
+		hash, _ := hex.DecodeString(t.SHA256)
+		a.tokens = append(a.tokens, token{name: t.Name, role: t.Role, hash: hash, tenants: t.Tenants})
+	}
+	if cfg.JWT != nil {
+		keys, err := LoadKeySet(cfg.JWT.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	return a, nil
+}
+
+// AddToken grants a role to a plain text token, such as the token given on the command line
+func (a *Authenticator) AddToken(name string, role Role, value string) {
+	hash := sha256.Sum256([]byte(value))
+	a.tokens = append(a.tokens, token{name: name, role: role, hash: hash[:]})
+}
+
+// Authenticate returns the identity of the bearer credentials of a request. Requests without
+// credentials get an anonymous identity, or nil when anonymous access is not allowed.
+func (a *Authenticator) Authenticate(r *http.Request) (*Identity, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		if a.anonymousRole == "" {
+			return nil, nil
+		}
+		return &Identity{Subject: "anonymous", Role: a.anonymousRole, Method: MethodAnonymous}, nil
+	}
+
+	credential, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || credential == "" {
+		return nil, fmt.Errorf("%w: expected a bearer token", ErrUnauthenticated)
+	}
+	if a.keys != nil && strings.Count(credential, ".") == 2 {
+		return a.authenticateJWT(credential)
+	}
+
+	hash := sha256.Sum256([]byte(credential))
+	var match *token
+	for i := range a.tokens {
+		// Compare with every token so the time taken does not reveal which one matched
+		if subtle.ConstantTimeCompare(hash[:], a.tokens[i].hash) == 1 {
+			match = &a.tokens[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("%w: unknown token", ErrUnauthenticated)
+	}
+	return &Identity{Subject: match.name, Role: match.role, Method: MethodToken, Tenants: match.tenants}, nil
+}
+
+func (a *Authenticator) authenticateJWT(credential string) (*Identity, error) {
+	claims, err := a.keys.Verify(credential, a.jwt.Issuer, a.jwt.Audience, a.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	subject := claims.String(a.jwt.SubjectClaim)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrUnauthenticated, a.jwt.SubjectClaim)
+	}
+
+	var granted Role
+	for _, value := range claims.Strings(a.jwt.RoleClaim) {
+		role, ok := a.jwt.RoleMapping[value]
+		if !ok {
+			role = Role(value)
+		}
+		if _, known := roleRanks[role]; known && roleRanks[role] > roleRanks[granted] {
+			granted = role
+		}
+	}
+	if granted == "" {
+		return nil, fmt.Errorf("%w: no role granted by the %s claim", ErrUnauthenticated, a.jwt.RoleClaim)
+	}
+	var tenants []string
+	if a.jwt.TenantClaim != "" {
+		tenants = claims.Strings(a.jwt.TenantClaim)
+	}
+	return &Identity{Subject: subject, Role: granted, Method: MethodJWT, Tenants: tenants}, nil
+}
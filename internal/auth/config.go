@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// Config declares the API tokens and the JWT issuer allowed to call the HTTP API
+type Config struct {
+	Tokens []TokenConfig `yaml:"tokens"`
+	JWT    *JWTConfig    `yaml:"jwt,omitempty"`
+	// AnonymousRole is granted to requests without credentials, which are refused when empty
+	AnonymousRole Role `yaml:"anonymous_role,omitempty"`
+}
+
+// TokenConfig is a static API token. Only the SHA-256 hash of the token is configured,
+// e.g. echo -n "$TOKEN" | sha256sum
+type TokenConfig struct {
+	Name   string `yaml:"name"`
+	Role   Role   `yaml:"role"`
+	SHA256 string `yaml:"sha256"`
+	// Tenants the token may read and edit, the default tenant only when empty. "*" grants every tenant.
+	Tenants []string `yaml:"tenants,omitempty"`
+}
+
+// JWTConfig validates signed JWTs against the keys of a local JWKS file
+type JWTConfig struct {
+	JWKSFile string `yaml:"jwks_file"`
+	// Issuer and Audience must match the iss and aud claims when set
+	Issuer   string `yaml:"issuer,omitempty"`
+	Audience string `yaml:"audience,omitempty"`
+	// SubjectClaim names the caller, sub by default
+	SubjectClaim string `yaml:"subject_claim,omitempty"`
+	// RoleClaim holds a role or a list of roles, role by default. The highest role is granted.
+	RoleClaim string `yaml:"role_claim,omitempty"`
+	// RoleMapping maps claim values, e.g. groups, to roles. Unmapped values are read as role names.
+	RoleMapping map[string]Role `yaml:"role_mapping,omitempty"`
+	// TenantClaim holds a tenant or a list of tenants the caller may access. Without it, callers only
+	// access the default tenant.
+	TenantClaim string `yaml:"tenant_claim,omitempty"`
+}
+
+// LoadConfig reads an authentication config from a YAML file. A relative JWKS file is resolved
+// against the directory of the config.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth config %s: %w", path, err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.JWT != nil && !filepath.IsAbs(cfg.JWT.JWKSFile) {
+		cfg.JWT.JWKSFile = filepath.Join(filepath.Dir(path), cfg.JWT.JWKSFile)
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses and validates an authentication config from YAML content
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse auth config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that tokens are uniquely named, hashed and granted a known role, and that the
+// JWT settings name a JWKS file
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	for i := range c.Tokens {
+		t := &c.Tokens[i]
+		if t.Name == "" {
+			return fmt.Errorf("token name cannot be empty")
+		}
+		if names[t.Name] {
+			return fmt.Errorf("token %q is defined more than once", t.Name)
+		}
+		names[t.Name] = true
+
+		if _, err := ParseRole(string(t.Role)); err != nil {
+			return fmt.Errorf("token %q: %w", t.Name, err)
+		}
+		t.SHA256 = strings.ToLower(strings.TrimSpace(t.SHA256))
+		if hash, err := hex.DecodeString(t.SHA256); err != nil || len(hash) != 32 {
+			return fmt.Errorf("token %q: sha256 must be the hex encoded SHA-256 hash of the token", t.Name)
+		}
+		for _, name := range t.Tenants {
+			if name == tenant.All {
+				continue
+			}
+			if err := tenant.Validate(name); err != nil {
+				return fmt.Errorf("token %q: %w", t.Name, err)
+			}
+		}
+	}
+
+	if c.JWT != nil {
+		if c.JWT.JWKSFile == "" {
+			return fmt.Errorf("jwt: jwks_file is required")
+		}
+		if c.JWT.SubjectClaim == "" {
+			c.JWT.SubjectClaim = "sub"
+		}
+		if c.JWT.RoleClaim == "" {
+			c.JWT.RoleClaim = "role"
+		}
+		for value, role := range c.JWT.RoleMapping {
+			if _, err := ParseRole(string(role)); err != nil {
+				return fmt.Errorf("jwt: role mapping %q: %w", value, err)
+			}
+		}
+	}
+
+	if c.AnonymousRole != "" {
+		if _, err := ParseRole(string(c.AnonymousRole)); err != nil {
+			return fmt.Errorf("anonymous_role: %w", err)
+		}
+	}
+	return nil
+}
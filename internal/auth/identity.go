@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// Role grants access to the HTTP API. Every role includes the permissions of the roles before it.
+type Role string
+
+const (
+	// RoleViewer reads the catalog
+	RoleViewer Role = "viewer"
+	// RoleEditor also edits documentation, versions, ownership and reviews
+	RoleEditor Role = "editor"
+	// RoleAdmin also removes telemetry, reads the audit log and profiles the server
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// ParseRole parses a role name
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q, expected viewer, editor or admin", s)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role includes the permissions of the required role
+func (r Role) Allows(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Method is how an identity was authenticated
+type Method string
+
+const (
+	MethodToken     Method = "token"
+	MethodJWT       Method = "jwt"
+	MethodAnonymous Method = "anonymous"
+)
+
+// Identity is the caller of a request
+type Identity struct {
+	// Subject names the caller: the token name or the subject claim of the JWT
+	Subject string `json:"subject"`
+	Role    Role   `json:"role"`
+	Method  Method `json:"method"`
+	// Tenants the caller may access besides the default one when empty, "*" for every tenant
+	Tenants []string `json:"tenants,omitempty"`
+}
+
+// CanAccessTenant reports whether the identity may read and edit the catalog of a tenant. Admins
+// access every tenant and are the only ones allowed the cross-tenant views.
+func (i *Identity) CanAccessTenant(name string) bool {
+	if i.Role.Allows(RoleAdmin) {
+		return true
+	}
+	if name == tenant.All {
+		return false
+	}
+	if len(i.Tenants) == 0 {
+		return name == tenant.Default
+	}
+	return slices.Contains(i.Tenants, name) || slices.Contains(i.Tenants, tenant.All)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context carrying the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the identity of the request, nil when the caller is not authenticated
+func IdentityFromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityKey{}).(*Identity)
+	return identity
+}
+
+// Author returns the subject of the identity of the request, nil when the caller is not authenticated
+func Author(ctx context.Context) *string {
+	identity := IdentityFromContext(ctx)
+	if identity == nil || identity.Method == MethodAnonymous {
+		return nil
+	}
+	return &identity.Subject
+}
@@ -0,0 +1,216 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// BudgetEnforcer evaluates cardinality and volume budgets against the usage coming from ingest
+type BudgetEnforcer struct {
+	budgets    *schema.BudgetSet
+	budgetRepo repository.BudgetRepository
+
+	// mu guards the evaluation state only, repositories are never called holding it
+	mu sync.Mutex
+	// breached holds the budget limits exceeded on the last evaluation of each tenant, so a breach
+	// is recorded once
+	breached map[string]bool
+	// evaluatedAt holds when the usage of each budget of each tenant was last evaluated
+	evaluatedAt map[string]time.Time
+}
+
+func NewBudgetEnforcer(budgets *schema.BudgetSet, budgetRepo repository.BudgetRepository) *BudgetEnforcer {
+	return &BudgetEnforcer{
+		budgets:     budgets,
+		budgetRepo:  budgetRepo,
+		breached:    make(map[string]bool),
+		evaluatedAt: make(map[string]time.Time),
+	}
+}
+
+// Admit drops the schemas new to the scope of an enforced budget that would exceed its metric or log
+// schema limit, and returns the message to report through OTLP partial success.
+// Usage lookups that fail are logged and the schemas admitted. A nil enforcer admits everything.
+// Concurrent exports are admitted against the same known schemas, so together they may exceed a
+// limit by the new schemas of all but one of them.
+func (e *BudgetEnforcer) Admit(ctx context.Context, schemas []schema.Telemetry) ([]schema.Telemetry, string) {
+	if e == nil || e.budgets == nil {
+		return schemas, ""
+	}
+
+	now := time.Now()
+	rejected := make(map[string]bool)
+	var messages []string
+	for _, budget := range e.budgets.Budgets {
+		if !budget.Enforce {
+			continue
+		}
+
+		limits := []struct {
+			name          string
+			telemetryType schema.TelemetryType
+			max           int
+			since         time.Time
+		}{
+			{schema.BudgetLimitMetricSchemas, schema.TelemetryTypeMetric, budget.MaxMetricSchemas, time.Time{}},
+			{schema.BudgetLimitLogSchemasPerDay, schema.TelemetryTypeLog, budget.MaxLogSchemasPerDay, schema.StartOfDay(now)},
+		}
+		for _, limit := range limits {
+			if limit.max <= 0 {
+				continue
+			}
+
+			var candidates []string
+			for i := range schemas {
+				telemetry := &schemas[i]
+				if telemetry.TelemetryType == limit.telemetryType && !rejected[telemetry.SchemaID] && budget.Scope.Covers(telemetry) {
+					candidates = append(candidates, telemetry.SchemaID)
+				}
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+
+			known, err := e.budgetRepo.ListScopeSchemaIDs(ctx, budget.Scope, limit.telemetryType, limit.since)
+			if err != nil {
+				slog.Error("failed to get budget usage", "budget", budget.Name, "error", err)
+				continue
+			}
+			knownSet := make(map[string]bool, len(known))
+			for _, schemaID := range known {
+				knownSet[schemaID] = true
+			}
+			var newSchemaIDs []string
+			for _, schemaID := range candidates {
+				if !knownSet[schemaID] {
+					newSchemaIDs = append(newSchemaIDs, schemaID)
+				}
+			}
+
+			_, over := schema.AdmitNewSchemas(newSchemaIDs, len(known), limit.max)
+			if len(over) == 0 {
+				continue
+			}
+			for _, schemaID := range over {
+				rejected[schemaID] = true
+			}
+			messages = append(messages, fmt.Sprintf("budget %s: rejected %d new %s %s beyond %s %d",
+				budget.Name, len(over), strings.ToLower(string(limit.telemetryType)), pluralSchemas(len(over)), limit.name, limit.max))
+		}
+	}
+
+	if len(rejected) == 0 {
+		return schemas, ""
+	}
+
+	admitted := make([]schema.Telemetry, 0, len(schemas)-len(rejected))
+	for _, telemetry := range schemas {
+		if !rejected[telemetry.SchemaID] {
+			admitted = append(admitted, telemetry)
+		}
+	}
+	return admitted, strings.Join(messages, "; ")
+}
+
+func pluralSchemas(n int) string {
+	if n == 1 {
+		return "schema"
+	}
+	return "schemas"
+}
+
+// Evaluate computes the usage of the budgets covering the registered schemas and records the limits
+// they newly exceed. The usage of a budget is evaluated at most once per evaluation interval and
+// tenant, other exports skip it. A nil enforcer does nothing.
+func (e *BudgetEnforcer) Evaluate(ctx context.Context, schemas []schema.Telemetry) {
+	if e == nil || e.budgets == nil {
+		return
+	}
+
+	now := time.Now()
+	tenantName := tenant.FromContext(ctx)
+	var breaches []schema.BudgetBreach
+	for _, budget := range e.budgets.Budgets {
+		covered := false
+		for i := range schemas {
+			if budget.Scope.Covers(&schemas[i]) {
+				covered = true
+				break
+			}
+		}
+		if !covered || !e.due(tenantName+"|"+budget.Name, now) {
+			continue
+		}
+
+		usage, err := e.budgetRepo.GetBudgetUsage(ctx, budget, e.budgets.ActiveSeriesWindow, now)
+		if err != nil {
+			slog.Error("failed to get budget usage", "budget", budget.Name, "error", err)
+			continue
+		}
+		breaches = append(breaches, e.newBreaches(tenantName, budget, budget.Check(*usage))...)
+	}
+
+	if len(breaches) == 0 {
+		return
+	}
+	for _, breach := range breaches {
+		slog.Warn("budget exceeded", "budget", breach.Budget, "scope", breach.Scope, "limit", breach.Limit, "value", breach.Value, "max", breach.Max)
+	}
+	if err := e.budgetRepo.RecordBudgetBreaches(ctx, breaches); err != nil {
+		slog.Error("failed to record budget breaches", "error", err)
+	}
+}
+
+// due reports whether the budget usage of the key is to be evaluated now, and if so claims the
+// evaluation until the next interval
+func (e *BudgetEnforcer) due(key string, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if last, ok := e.evaluatedAt[key]; ok && now.Sub(last) < e.budgets.EvaluationInterval {
+		return false
+	}
+	e.evaluatedAt[key] = now
+	return true
+}
+
+// newBreaches remembers the limits of the budget exceeded in the tenant and returns the breaches
+// of the limits that were not exceeded on the previous evaluation
+func (e *BudgetEnforcer) newBreaches(tenantName string, budget schema.Budget, exceeded []schema.BudgetBreach) []schema.BudgetBreach {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var breaches []schema.BudgetBreach
+	keys := make(map[string]bool)
+	for _, breach := range exceeded {
+		key := tenantName + "|" + budget.Name + "|" + breach.Limit
+		keys[key] = true
+		if !e.breached[key] {
+			breaches = append(breaches, breach)
+		}
+	}
+	for _, limit := range []string{schema.BudgetLimitMetricSchemas, schema.BudgetLimitActiveSeries, schema.BudgetLimitLogSchemasPerDay} {
+		key := tenantName + "|" + budget.Name + "|" + limit
+		e.breached[key] = keys[key]
+	}
+	return breaches
+}
+
+// joinWarnings combines the partial success messages of an export
+func joinWarnings(warnings ...string) string {
+	var nonEmpty []string
+	for _, w := range warnings {
+		if w != "" {
+			nonEmpty = append(nonEmpty, w)
+		}
+	}
+	return strings.Join(nonEmpty, "; ")
+}
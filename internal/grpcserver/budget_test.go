@@ -0,0 +1,148 @@
+package grpcserver_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tallycat/tallycat/internal/grpcserver"
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+func TestExportMetrics_Budgets(t *testing.T) {
+	tests := []struct {
+		name         string
+		budgets      string
+		wantWarning  string
+		wantSchemas  int
+		wantBreaches []string
+	}{
+		{
+			name: "within budget",
+			budgets: `
+budgets:
+  - {name: test-service, max_metric_schemas: 2, max_active_series: 2, enforce: true, scope: {entity: service.name=test-service}}
+`,
+			wantSchemas: 2,
+		},
+		{
+			name: "breach is recorded without enforcement",
+			budgets: `
+budgets:
+  - {name: tenant, max_metric_schemas: 1, max_active_series: 1}
+`,
+			wantSchemas:  2,
+			wantBreaches: []string{schema.BudgetLimitActiveSeries, schema.BudgetLimitMetricSchemas},
+		},
+		{
+			name: "enforced budget rejects new schemas",
+			budgets: `
+budgets:
+  - {name: tenant, max_metric_schemas: 1, enforce: true}
+`,
+			wantWarning: "budget tenant: rejected 1 new metric schema beyond max_metric_schemas 1",
+			wantSchemas: 1,
+		},
+		{
+			name: "budget of another entity is not counted",
+			budgets: `
+budgets:
+  - {name: other, max_metric_schemas: 1, enforce: true, scope: {entity: service.name=other}}
+`,
+			wantSchemas: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+			db.SetupTestDB(t)
+
+			budgets, err := schema.ParseBudgets([]byte(tt.budgets))
+			require.NoError(t, err)
+
+			server := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{Budgets: budgets})
+			defer server.Close()
+
+			ctx := context.Background()
+			md, err := golden.ReadMetrics(filepath.Join("testdata", "two_metrics_two_schemas.yaml"))
+			require.NoError(t, err)
+
+			// Exporting twice must neither reject known schemas nor record a breach again
+			for range 2 {
+				resp, err := server.MetricsClient.Export(ctx, testutil.ConvertPmetricToRequest(md))
+				require.NoError(t, err)
+				require.Equal(t, tt.wantWarning, resp.GetPartialSuccess().GetErrorMessage())
+			}
+
+			_, total, err := db.Repo().ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSchemas, total)
+
+			breaches, _, err := db.BudgetRepo().ListBudgetBreaches(ctx, query.ListQueryParams{Page: 1, PageSize: 10}, query.BudgetBreachFilter{})
+			require.NoError(t, err)
+			limits := []string{}
+			for _, breach := range breaches {
+				limits = append(limits, breach.Limit)
+			}
+			require.ElementsMatch(t, tt.wantBreaches, limits)
+		})
+	}
+}
+
+// countingBudgetRepo reports a usage over every limit and counts the usage evaluations
+type countingBudgetRepo struct {
+	repository.BudgetRepository
+	evaluations atomic.Int32
+	breaches    atomic.Int32
+}
+
+func (r *countingBudgetRepo) GetBudgetUsage(ctx context.Context, budget schema.Budget, activeSeriesWindow time.Duration, now time.Time) (*schema.BudgetUsage, error) {
+	r.evaluations.Add(1)
+	return &schema.BudgetUsage{MetricSchemas: 10, EvaluatedAt: now}, nil
+}
+
+func (r *countingBudgetRepo) RecordBudgetBreaches(ctx context.Context, breaches []schema.BudgetBreach) error {
+	r.breaches.Add(int32(len(breaches)))
+	return nil
+}
+
+func TestBudgetEnforcer_EvaluatesOncePerInterval(t *testing.T) {
+	budgets, err := schema.ParseBudgets([]byte(`
+evaluation_interval: 1h
+budgets:
+  - {name: tenant, max_metric_schemas: 1}
+`))
+	require.NoError(t, err)
+	repo := &countingBudgetRepo{}
+	enforcer := grpcserver.NewBudgetEnforcer(budgets, repo)
+
+	schemas := []schema.Telemetry{{SchemaID: "s1", TelemetryType: schema.TelemetryTypeMetric}}
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			enforcer.Evaluate(ctx, schemas)
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), repo.evaluations.Load())
+	require.Equal(t, int32(1), repo.breaches.Load())
+
+	// Tenants are evaluated apart
+	enforcer.Evaluate(tenant.WithTenant(ctx, "payments"), schemas)
+	require.Equal(t, int32(2), repo.evaluations.Load())
+}
@@ -0,0 +1,293 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// ingestTokenRefresh is how long the active ingest tokens are cached, so a revoked token is refused
+// within that delay
+const ingestTokenRefresh = 10 * time.Second
+
+// IngestUsageFlushInterval is how often the ingest usage counted in memory is written to storage
+const IngestUsageFlushInterval = 10 * time.Second
+
+// IngestGate authenticates OTLP clients by the bearer ingest token of their request metadata and
+// throttles each token to its rate limits. The usage of each token is counted in memory and written
+// by FlushUsage, so throttled clients cost no storage write per export.
+type IngestGate struct {
+	tokenRepo repository.IngestTokenRepository
+	// required refuses clients without an ingest token
+	required bool
+	now      func() time.Time
+
+	mu       sync.Mutex
+	tokens   map[string]schema.IngestToken
+	loadedAt time.Time
+	limiters map[int64]*ingestLimiter
+	usage    map[ingestUsageKey]schema.IngestUsage
+}
+
+// ingestUsageKey is the hourly counter of a token the usage of an export is added to
+type ingestUsageKey struct {
+	tokenID int64
+	bucket  time.Time
+}
+
+func NewIngestGate(tokenRepo repository.IngestTokenRepository, required bool) *IngestGate {
+	return &IngestGate{
+		tokenRepo: tokenRepo,
+		required:  required,
+		now:       time.Now,
+		limiters:  make(map[int64]*ingestLimiter),
+		usage:     make(map[ingestUsageKey]schema.IngestUsage),
+	}
+}
+
+type ingestClientKey struct{}
+
+// ingestClient is the token a call was authenticated with
+type ingestClient struct {
+	gate  *IngestGate
+	token schema.IngestToken
+}
+
+// Interceptor refuses calls with an unknown or revoked ingest token, and calls without one when
+// tokens are required
+func (g *IngestGate) Interceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		// The health service stays reachable for probes
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.") {
+			return handler(ctx, req)
+		}
+
+		credential := bearerToken(ctx)
+		if credential == "" {
+			if g.required {
+				return nil, status.Error(codes.Unauthenticated, "an ingest token is required")
+			}
+			return handler(ctx, req)
+		}
+
+		token, ok, err := g.lookup(ctx, schema.HashIngestToken(credential))
+		if err != nil {
+			slog.Error("failed to load ingest tokens", "error", err)
+			return nil, status.Error(codes.Unavailable, "failed to authenticate ingest token")
+		}
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "invalid ingest token")
+		}
+		return handler(context.WithValue(ctx, ingestClientKey{}, &ingestClient{gate: g, token: token}), req)
+	}
+}
+
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
+
+// lookup returns the active token with the hash, reloading the tokens once the cache expired
+func (g *IngestGate) lookup(ctx context.Context, hash string) (schema.IngestToken, bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.tokens == nil || g.now().Sub(g.loadedAt) >= ingestTokenRefresh {
+		tokens, err := g.tokenRepo.ListActiveIngestTokens(ctx)
+		if err != nil {
+			return schema.IngestToken{}, false, err
+		}
+		g.tokens = make(map[string]schema.IngestToken, len(tokens))
+		for _, token := range tokens {
+			g.tokens[token.Hash] = token
+		}
+		g.loadedAt = g.now()
+	}
+	token, ok := g.tokens[hash]
+	return token, ok, nil
+}
+
+// admitIngest takes one request and the schemas of an export from the rate limits of the ingest
+// token of the call, and counts its usage. Exports over a limit are refused with a
+// ResourceExhausted error carrying the delay to retry after.
+func admitIngest(ctx context.Context, schemas int) error {
+	client, _ := ctx.Value(ingestClientKey{}).(*ingestClient)
+	if client == nil {
+		return nil
+	}
+	return client.gate.admit(ctx, client.token, schemas)
+}
+
+func (g *IngestGate) admit(ctx context.Context, token schema.IngestToken, schemas int) error {
+	now := g.now()
+
+	g.mu.Lock()
+	limiter, ok := g.limiters[token.ID]
+	if !ok || limiter.requests.rate != token.RequestsPerSecond || limiter.schemas.rate != token.SchemasPerSecond {
+		limiter = newIngestLimiter(token, now)
+		g.limiters[token.ID] = limiter
+	}
+	wait := limiter.take(float64(schemas), now)
+
+	key := ingestUsageKey{tokenID: token.ID, bucket: now.UTC().Truncate(time.Hour)}
+	usage := g.usage[key]
+	usage.TokenID = token.ID
+	usage.Timestamp = now
+	if wait > 0 {
+		usage.Throttled++
+	} else {
+		usage.Requests++
+		usage.Schemas += int64(schemas)
+	}
+	g.usage[key] = usage
+	g.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	return rateLimited(ctx, token.Name, wait)
+}
+
+// FlushUsage writes the usage counted since the last flush to the hourly counters of the tokens.
+// Usage failing to be written is kept for the next flush.
+func (g *IngestGate) FlushUsage(ctx context.Context) error {
+	g.mu.Lock()
+	pending := g.usage
+	g.usage = make(map[ingestUsageKey]schema.IngestUsage)
+	g.mu.Unlock()
+
+	var failed error
+	for key, usage := range pending {
+		if err := g.tokenRepo.RecordIngestUsage(ctx, usage); err != nil {
+			failed = err
+			g.restoreUsage(key, usage)
+		}
+	}
+	return failed
+}
+
+// restoreUsage adds back the usage of a failed flush to the usage counted since
+func (g *IngestGate) restoreUsage(key ingestUsageKey, usage schema.IngestUsage) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	current, ok := g.usage[key]
+	if !ok {
+		g.usage[key] = usage
+		return
+	}
+	current.Requests += usage.Requests
+	current.Schemas += usage.Schemas
+	current.Throttled += usage.Throttled
+	g.usage[key] = current
+}
+
+// rateLimited returns a ResourceExhausted status with a RetryInfo detail, and sets the retry-after
+// header for clients ignoring error details
+func rateLimited(ctx context.Context, name string, wait time.Duration) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds))); err != nil {
+		slog.Debug("failed to set retry-after header", "error", err)
+	}
+
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf("rate limit of ingest token %s exceeded, retry in %s", name, wait.Round(time.Millisecond)))
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// ingestLimiter holds the request and schema token buckets of an ingest token
+type ingestLimiter struct {
+	requests tokenBucket
+	schemas  tokenBucket
+}
+
+func newIngestLimiter(token schema.IngestToken, now time.Time) *ingestLimiter {
+	return &ingestLimiter{
+		requests: newTokenBucket(token.RequestsPerSecond, now),
+		schemas:  newTokenBucket(token.SchemasPerSecond, now),
+	}
+}
+
+// take admits one request with the schemas when both buckets can afford them, else it takes
+// nothing and returns how long to wait until they can
+func (l *ingestLimiter) take(schemas float64, now time.Time) time.Duration {
+	l.requests.refill(now)
+	l.schemas.refill(now)
+
+	wait := max(l.requests.wait(1), l.schemas.wait(schemas))
+	if wait > 0 {
+		return wait
+	}
+	l.requests.spend(1)
+	l.schemas.spend(schemas)
+	return 0
+}
+
+// tokenBucket refills at rate tokens per second up to a burst of one second, a zero rate is unlimited
+type tokenBucket struct {
+	rate     float64
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(rate float64, now time.Time) tokenBucket {
+	capacity := math.Max(rate, 1)
+	return tokenBucket{rate: rate, capacity: capacity, tokens: capacity, last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if b.rate == 0 {
+		return
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+}
+
+// wait returns how long until n tokens are available. Costs above the burst are capped to it, so
+// a large export is admitted once the bucket is full.
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if b.rate == 0 {
+		return 0
+	}
+	n = math.Min(n, b.capacity)
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) spend(n float64) {
+	if b.rate == 0 {
+		return
+	}
+	b.tokens = math.Max(0, b.tokens-math.Min(n, b.capacity))
+}
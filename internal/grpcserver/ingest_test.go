@@ -0,0 +1,129 @@
+package grpcserver_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/grpcserver"
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func createIngestToken(t *testing.T, db *testutil.TestDB, name string, rps, sps float64) string {
+	token, hash, err := schema.GenerateIngestToken()
+	require.NoError(t, err)
+	_, err = db.IngestTokenRepo().CreateIngestToken(context.Background(), schema.IngestToken{
+		Name:              name,
+		RequestsPerSecond: rps,
+		SchemasPerSecond:  sps,
+		CreatedAt:         time.Now(),
+		Hash:              hash,
+	})
+	require.NoError(t, err)
+	return token
+}
+
+func withIngestToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestExportMetrics_IngestToken(t *testing.T) {
+	md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+	require.NoError(t, err)
+	req := testutil.ConvertPmetricToRequest(md)
+	ctx := context.Background()
+
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	limited := createIngestToken(t, db, "limited", 1, 0)
+	unlimited := createIngestToken(t, db, "unlimited", 0, 0)
+
+	gate := grpcserver.NewIngestGate(db.IngestTokenRepo(), true)
+	server := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{IngestGate: gate})
+	defer server.Close()
+
+	// Exports without a token or with an unknown one are refused
+	_, err = server.MetricsClient.Export(ctx, req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, "tcit_unknown"), req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, unlimited), req)
+	require.NoError(t, err)
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, unlimited), req)
+	require.NoError(t, err)
+
+	// The second export within a second is over the limit of one request per second
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, limited), req)
+	require.NoError(t, err)
+	var header metadata.MD
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, limited), req, grpc.Header(&header))
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	require.Len(t, st.Details(), 1)
+	retry, ok := st.Details()[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Positive(t, retry.RetryDelay.AsDuration())
+	require.LessOrEqual(t, retry.RetryDelay.AsDuration(), time.Second)
+	require.Equal(t, []string{"1"}, header.Get("retry-after"))
+
+	// Usage is counted in memory until flushed
+	stats, err := db.IngestTokenRepo().ListIngestTokenStats(ctx, query.TimeWindow{})
+	require.NoError(t, err)
+	for _, s := range stats {
+		require.Zero(t, s.Requests+s.Throttled, s.Name)
+	}
+	require.NoError(t, gate.FlushUsage(ctx))
+	require.NoError(t, gate.FlushUsage(ctx))
+
+	stats, err = db.IngestTokenRepo().ListIngestTokenStats(ctx, query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, "limited", stats[0].Name)
+	require.Equal(t, int64(1), stats[0].Requests)
+	require.Equal(t, int64(1), stats[0].Schemas)
+	require.Equal(t, int64(1), stats[0].Throttled)
+	require.Equal(t, "unlimited", stats[1].Name)
+	require.Equal(t, int64(2), stats[1].Requests)
+	require.Equal(t, int64(0), stats[1].Throttled)
+	require.NotNil(t, stats[1].LastSeen)
+}
+
+func TestExportMetrics_IngestTokenOptional(t *testing.T) {
+	md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+	require.NoError(t, err)
+	req := testutil.ConvertPmetricToRequest(md)
+	ctx := context.Background()
+
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	token := createIngestToken(t, db, "collector", 0, 0)
+	require.NoError(t, db.IngestTokenRepo().RevokeIngestToken(ctx, "collector", time.Now()))
+
+	server := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{
+		IngestGate: grpcserver.NewIngestGate(db.IngestTokenRepo(), false),
+	})
+	defer server.Close()
+
+	_, err = server.MetricsClient.Export(ctx, req)
+	require.NoError(t, err)
+
+	// A revoked token is refused even when tokens are optional
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, token), req)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
@@ -14,13 +14,19 @@ import (
 
 type LogsServiceServer struct {
 	logspb.UnimplementedLogsServiceServer
-	schemaRepo repository.TelemetrySchemaRepository
-	logger     *slog.Logger
+	entityModel    *schema.EntityModel
+	schemaRepo     repository.TelemetrySchemaRepository
+	policyEnforcer *PolicyEnforcer
+	budgetEnforcer *BudgetEnforcer
+	logger         *slog.Logger
 }
 
-func NewLogsServiceServer(schemaRepo repository.TelemetrySchemaRepository) *LogsServiceServer {
+func NewLogsServiceServer(entityModel *schema.EntityModel, schemaRepo repository.TelemetrySchemaRepository, policyEnforcer *PolicyEnforcer, budgetEnforcer *BudgetEnforcer) *LogsServiceServer {
 	return &LogsServiceServer{
-		schemaRepo: schemaRepo,
+		entityModel:    entityModel,
+		schemaRepo:     schemaRepo,
+		policyEnforcer: policyEnforcer,
+		budgetEnforcer: budgetEnforcer,
 	}
 }
 
@@ -87,12 +93,37 @@ func (s *LogsServiceServer) Export(ctx context.Context, req *logspb.ExportLogsSe
 	}
 
 	// Extract schemas from the converted logs
-	schemas := schema.ExtractFromLogs(logs)
+	schemas := schema.ExtractFromLogs(logs, s.entityModel)
+	tagSender(ctx, schemas)
+	scopeTenant(ctx, schemas)
+
+	// Throttle the client to the rate limits of its ingest token
+	if err := admitIngest(ctx, len(schemas)); err != nil {
+		slog.Warn("export rate limited", "error", err, "signal", "logs")
+		return nil, err
+	}
+
+	// Evaluate governance policies before the schemas are registered
+	schemas, warning, err := s.policyEnforcer.Enforce(ctx, schemas)
+	if err != nil {
+		slog.Warn("export rejected by policy", "error", err, "signal", "logs")
+		return nil, err
+	}
+
+	// Drop the new schemas beyond an enforced budget
+	schemas, budgetWarning := s.budgetEnforcer.Admit(ctx, schemas)
+	warning = joinWarnings(warning, budgetWarning)
 
 	if err := s.schemaRepo.RegisterTelemetrySchemas(ctx, schemas); err != nil {
 		slog.Error("failed to register schemas", "error", err, "signal", "logs")
 		return nil, err
 	}
 
-	return &logspb.ExportLogsServiceResponse{}, nil
+	s.budgetEnforcer.Evaluate(ctx, schemas)
+
+	response := &logspb.ExportLogsServiceResponse{}
+	if warning != "" {
+		response.PartialSuccess = &logspb.ExportLogsPartialSuccess{ErrorMessage: warning}
+	}
+	return response, nil
 }
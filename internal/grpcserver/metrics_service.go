@@ -15,12 +15,18 @@ import (
 
 type MetricsServiceServer struct {
 	metricspb.UnimplementedMetricsServiceServer
-	schemaRepo repository.TelemetrySchemaRepository
+	entityModel    *schema.EntityModel
+	schemaRepo     repository.TelemetrySchemaRepository
+	policyEnforcer *PolicyEnforcer
+	budgetEnforcer *BudgetEnforcer
 }
 
-func NewMetricsServiceServer(schemaRepo repository.TelemetrySchemaRepository) *MetricsServiceServer {
+func NewMetricsServiceServer(entityModel *schema.EntityModel, schemaRepo repository.TelemetrySchemaRepository, policyEnforcer *PolicyEnforcer, budgetEnforcer *BudgetEnforcer) *MetricsServiceServer {
 	return &MetricsServiceServer{
-		schemaRepo: schemaRepo,
+		entityModel:    entityModel,
+		schemaRepo:     schemaRepo,
+		policyEnforcer: policyEnforcer,
+		budgetEnforcer: budgetEnforcer,
 	}
 }
 
@@ -174,12 +180,37 @@ func (s *MetricsServiceServer) Export(ctx context.Context, req *metricspb.Export
 	}
 
 	// Extract schemas from the converted metrics
-	schemas := schema.ExtractFromMetrics(metrics)
+	schemas := schema.ExtractFromMetrics(metrics, s.entityModel)
+	tagSender(ctx, schemas)
+	scopeTenant(ctx, schemas)
+
+	// Throttle the client to the rate limits of its ingest token
+	if err := admitIngest(ctx, len(schemas)); err != nil {
+		slog.Warn("export rate limited", "error", err, "signal", "metrics")
+		return nil, err
+	}
+
+	// Evaluate governance policies before the schemas are registered
+	schemas, warning, err := s.policyEnforcer.Enforce(ctx, schemas)
+	if err != nil {
+		slog.Warn("export rejected by policy", "error", err, "signal", "metrics")
+		return nil, err
+	}
+
+	// Drop the new schemas beyond an enforced budget
+	schemas, budgetWarning := s.budgetEnforcer.Admit(ctx, schemas)
+	warning = joinWarnings(warning, budgetWarning)
 
 	if err := s.schemaRepo.RegisterTelemetrySchemas(ctx, schemas); err != nil {
 		slog.Error("failed to register schemas", "error", err, "signal", "metrics")
 		return nil, err
 	}
 
-	return &metricspb.ExportMetricsServiceResponse{}, nil
+	s.budgetEnforcer.Evaluate(ctx, schemas)
+
+	response := &metricspb.ExportMetricsServiceResponse{}
+	if warning != "" {
+		response.PartialSuccess = &metricspb.ExportMetricsPartialSuccess{ErrorMessage: warning}
+	}
+	return response, nil
 }
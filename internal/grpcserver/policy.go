@@ -0,0 +1,129 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// reviewPolicyRefresh is how long the policies created by rejecting telemetry in review are cached
+const reviewPolicyRefresh = 10 * time.Second
+
+// PolicyEnforcer evaluates governance policies against the schemas of an export and records the violations.
+// The policies loaded from disk are combined with the ones created by rejecting telemetry in review.
+type PolicyEnforcer struct {
+	policies      *schema.PolicySet
+	violationRepo repository.PolicyViolationRepository
+	reviewRepo    repository.ReviewRepository
+
+	mu             sync.Mutex
+	reviewPolicies map[string]cachedPolicies
+}
+
+// cachedPolicies are the review policies of a tenant and when they were loaded
+type cachedPolicies struct {
+	policies []schema.Policy
+	loadedAt time.Time
+}
+
+func NewPolicyEnforcer(policies *schema.PolicySet, violationRepo repository.PolicyViolationRepository, reviewRepo repository.ReviewRepository) *PolicyEnforcer {
+	return &PolicyEnforcer{
+		policies:      policies,
+		violationRepo: violationRepo,
+		reviewRepo:    reviewRepo,
+
+		reviewPolicies: make(map[string]cachedPolicies),
+	}
+}
+
+// Enforce records every violation and returns the schemas to register, without the ones violating
+// a drop policy, and the message to report through OTLP partial success for warn and drop policies.
+// It returns an InvalidArgument error when a reject policy is violated.
+// A nil enforcer accepts everything.
+func (e *PolicyEnforcer) Enforce(ctx context.Context, schemas []schema.Telemetry) ([]schema.Telemetry, string, error) {
+	if e == nil {
+		return schemas, "", nil
+	}
+
+	set := &schema.PolicySet{}
+	if e.policies != nil {
+		set.Policies = append(set.Policies, e.policies.Policies...)
+	}
+	set.Policies = append(set.Policies, e.loadReviewPolicies(ctx)...)
+
+	violations := set.Evaluate(schemas)
+	if len(violations) == 0 {
+		return schemas, "", nil
+	}
+
+	if e.violationRepo != nil {
+		if err := e.violationRepo.RecordPolicyViolations(ctx, violations); err != nil {
+			slog.Error("failed to record policy violations", "error", err)
+		}
+	}
+
+	var warnings, rejections []string
+	dropped := make(map[string]bool)
+	for _, v := range violations {
+		message := fmt.Sprintf("policy %s: %s %s", v.Policy, v.SchemaKey, v.Message)
+		switch v.Mode {
+		case schema.PolicyModeWarn:
+			warnings = append(warnings, message)
+		case schema.PolicyModeReject:
+			rejections = append(rejections, message)
+		case schema.PolicyModeDrop:
+			warnings = append(warnings, message+", dropped")
+			dropped[v.SchemaID] = true
+		}
+	}
+
+	if len(rejections) > 0 {
+		return nil, "", status.Error(codes.InvalidArgument, "rejected by policy: "+strings.Join(rejections, "; "))
+	}
+
+	if len(dropped) > 0 {
+		admitted := make([]schema.Telemetry, 0, len(schemas))
+		for _, s := range schemas {
+			if !dropped[s.SchemaID] {
+				admitted = append(admitted, s)
+			}
+		}
+		schemas = admitted
+	}
+
+	return schemas, strings.Join(warnings, "; "), nil
+}
+
+// loadReviewPolicies returns the cached review policies of the tenant of the context, reloaded once
+// older than reviewPolicyRefresh. A failed reload is logged and the previous policies kept.
+func (e *PolicyEnforcer) loadReviewPolicies(ctx context.Context) []schema.Policy {
+	if e.reviewRepo == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	name := tenant.FromContext(ctx)
+	cached := e.reviewPolicies[name]
+	if time.Since(cached.loadedAt) < reviewPolicyRefresh {
+		return cached.policies
+	}
+	policies, err := e.reviewRepo.ListReviewPolicies(ctx)
+	if err != nil {
+		slog.Error("failed to load review policies", "tenant", name, "error", err)
+		return cached.policies
+	}
+	e.reviewPolicies[name] = cachedPolicies{policies: policies, loadedAt: time.Now()}
+	return policies
+}
@@ -0,0 +1,147 @@
+package grpcserver_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestExportMetrics_Policies(t *testing.T) {
+	tests := []struct {
+		name        string
+		policies    string
+		wantCode    codes.Code
+		wantWarning string
+		wantSchemas int
+	}{
+		{
+			name: "audit records the violation only",
+			policies: `
+policies:
+  - name: cardinality
+    mode: audit
+    max_attributes: 2
+`,
+			wantSchemas: 1,
+		},
+		{
+			name: "warn reports partial success",
+			policies: `
+policies:
+  - name: environment
+    mode: warn
+    required_resource_attributes: [k8s.cluster.name]
+`,
+			wantWarning: "policy environment: system.cpu.usage missing required resource attributes k8s.cluster.name",
+			wantSchemas: 1,
+		},
+		{
+			name: "reject refuses the export",
+			policies: `
+policies:
+  - name: no-cpu-mode
+    mode: reject
+    forbidden_attributes: [mode]
+`,
+			wantCode:    codes.InvalidArgument,
+			wantSchemas: 0,
+		},
+		{
+			name: "drop leaves the schema out of the catalog",
+			policies: `
+policies:
+  - name: no-cpu-mode
+    mode: drop
+    forbidden_attributes: [mode]
+`,
+			wantWarning: "policy no-cpu-mode: system.cpu.usage datapoint attribute mode is forbidden, dropped",
+			wantSchemas: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+			defer db.Close()
+			db.SetupTestDB(t)
+
+			policies, err := schema.ParsePolicies([]byte(tt.policies))
+			require.NoError(t, err)
+
+			server := testutil.NewTestServerWithPolicies(t, db, policies)
+			defer server.Close()
+
+			ctx := context.Background()
+			md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+			require.NoError(t, err)
+
+			resp, err := server.MetricsClient.Export(ctx, testutil.ConvertPmetricToRequest(md))
+			if tt.wantCode != codes.OK {
+				require.Equal(t, tt.wantCode, status.Code(err))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantWarning, resp.GetPartialSuccess().GetErrorMessage())
+				require.Zero(t, resp.GetPartialSuccess().GetRejectedDataPoints())
+			}
+
+			_, total, err := db.Repo().ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSchemas, total)
+
+			violations, _, err := db.ViolationRepo().ListPolicyViolations(ctx, query.ListQueryParams{Page: 1, PageSize: 10}, query.PolicyViolationFilter{})
+			require.NoError(t, err)
+			require.Len(t, violations, 1)
+			require.Equal(t, policies.Policies[0].Name, violations[0].Policy)
+			require.Equal(t, "system.cpu.usage", violations[0].SchemaKey)
+		})
+	}
+}
+
+func TestExportMetrics_ReviewRejection(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	ctx := context.Background()
+	md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+	require.NoError(t, err)
+
+	server := testutil.NewTestServer(t, db)
+	_, err = server.MetricsClient.Export(ctx, testutil.ConvertPmetricToRequest(md))
+	require.NoError(t, err)
+	server.Close()
+
+	reviews, _, err := db.ReviewRepo().ListReviews(ctx, query.ListQueryParams{Page: 1, PageSize: 10}, query.ReviewFilter{State: string(schema.ReviewStatePending)})
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	require.Equal(t, "system.cpu.usage", reviews[0].SchemaKey)
+
+	_, err = db.ReviewRepo().DecideReview(ctx, reviews[0].ID, schema.ReviewDecision{
+		Action:       schema.ReviewActionReject,
+		Author:       "alice",
+		RejectAction: schema.RejectActionDrop,
+	})
+	require.NoError(t, err)
+
+	// The rejected metric is dropped from then on
+	server = testutil.NewTestServer(t, db)
+	defer server.Close()
+	resp, err := server.MetricsClient.Export(ctx, testutil.ConvertPmetricToRequest(md))
+	require.NoError(t, err)
+	require.Contains(t, resp.GetPartialSuccess().GetErrorMessage(), "system.cpu.usage is denied, dropped")
+
+	violations, _, err := db.ViolationRepo().ListPolicyViolations(ctx, query.ListQueryParams{Page: 1, PageSize: 10}, query.PolicyViolationFilter{})
+	require.NoError(t, err)
+	require.Len(t, violations, 1)
+	require.Equal(t, schema.PolicyModeDrop, violations[0].Mode)
+	require.Equal(t, schema.PolicyRuleDeny, violations[0].Rule)
+}
@@ -13,12 +13,16 @@ import (
 
 type ProfilesServiceServer struct {
 	profilespb.UnimplementedProfilesServiceServer
-	schemaRepo repository.TelemetrySchemaRepository
+	entityModel    *schema.EntityModel
+	schemaRepo     repository.TelemetrySchemaRepository
+	policyEnforcer *PolicyEnforcer
 }
 
-func NewProfilesServiceServer(schemaRepo repository.TelemetrySchemaRepository) *ProfilesServiceServer {
+func NewProfilesServiceServer(entityModel *schema.EntityModel, schemaRepo repository.TelemetrySchemaRepository, policyEnforcer *PolicyEnforcer) *ProfilesServiceServer {
 	return &ProfilesServiceServer{
-		schemaRepo: schemaRepo,
+		entityModel:    entityModel,
+		schemaRepo:     schemaRepo,
+		policyEnforcer: policyEnforcer,
 	}
 }
 
@@ -87,9 +91,17 @@ func (s *ProfilesServiceServer) Export(ctx context.Context, req *profilespb.Expo
 
 	// Extract schemas from the converted profiles
 	slog.Info("Extracting schemas from profiles")
-	schemas := schema.ExtractFromProfiles(profiles, req.Dictionary)
+	schemas := schema.ExtractFromProfiles(profiles, req.Dictionary, s.entityModel)
+	tagSender(ctx, schemas)
+	scopeTenant(ctx, schemas)
 	slog.Info("Schema extraction completed", "schemas_count", len(schemas))
 
+	// Throttle the client to the rate limits of its ingest token
+	if err := admitIngest(ctx, len(schemas)); err != nil {
+		slog.Warn("export rate limited", "error", err, "signal", "profiles")
+		return nil, err
+	}
+
 	for i, schema := range schemas {
 		slog.Info("Extracted schema",
 			"index", i,
@@ -98,11 +110,22 @@ func (s *ProfilesServiceServer) Export(ctx context.Context, req *profilespb.Expo
 			"attributes_count", len(schema.Attributes))
 	}
 
+	// Evaluate governance policies before the schemas are registered
+	schemas, warning, err := s.policyEnforcer.Enforce(ctx, schemas)
+	if err != nil {
+		slog.Warn("export rejected by policy", "error", err, "signal", "profiles")
+		return nil, err
+	}
+
 	if err := s.schemaRepo.RegisterTelemetrySchemas(ctx, schemas); err != nil {
 		slog.Error("failed to register schemas", "error", err, "signal", "profiles")
 		return nil, err
 	}
 
 	slog.Info("Successfully registered telemetry schemas", "count", len(schemas))
-	return &profilespb.ExportProfilesServiceResponse{}, nil
+	response := &profilespb.ExportProfilesServiceResponse{}
+	if warning != "" {
+		response.PartialSuccess = &profilespb.ExportProfilesPartialSuccess{ErrorMessage: warning}
+	}
+	return response, nil
 }
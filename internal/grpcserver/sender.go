@@ -0,0 +1,40 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tlsconfig"
+)
+
+type senderKey struct{}
+
+// SenderInterceptor identifies the client of each call from its verified TLS certificate, mapped
+// by the identity rules of the TLS config
+func SenderInterceptor(cfg *tlsconfig.Config) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if p, ok := peer.FromContext(ctx); ok {
+			if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+				if identity := cfg.ClientIdentity(&tlsInfo.State); identity != "" {
+					ctx = context.WithValue(ctx, senderKey{}, identity)
+				}
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// tagSender records the identity of the client on the schemas it sent
+func tagSender(ctx context.Context, schemas []schema.Telemetry) {
+	identity, _ := ctx.Value(senderKey{}).(string)
+	if identity == "" {
+		return
+	}
+	for i := range schemas {
+		schemas[i].Sender = identity
+	}
+}
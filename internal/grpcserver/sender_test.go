@@ -0,0 +1,84 @@
+package grpcserver_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/tenant"
+	"github.com/tallycat/tallycat/internal/tlsconfig"
+)
+
+func TestExportMetrics_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := testutil.NewTestCA(t, "collectors-ca")
+	certFile, keyFile := ca.IssueFiles(t, dir, "server", "tallycat")
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.CertPEM, 0o600))
+
+	cfg, err := tlsconfig.ParseConfig([]byte(`
+grpc:
+  cert_file: ` + certFile + `
+  key_file: ` + keyFile + `
+  client_ca_file: ` + caFile + `
+identities:
+  - match: "*.prod.example.com"
+    identity: prod-collectors
+`))
+	require.NoError(t, err)
+	require.Equal(t, tlsconfig.ClientAuthRequire, cfg.GRPC.ClientAuth)
+
+	md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+	require.NoError(t, err)
+	req := testutil.ConvertPmetricToRequest(md)
+	ctx := context.Background()
+
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	clientCert, clientKey := ca.Issue(t, "gateway-1", "gateway-1.prod.example.com")
+	server := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{TLS: cfg, ClientTLS: ca.ClientTLS(t, clientCert, clientKey)})
+	defer server.Close()
+
+	_, err = server.MetricsClient.Export(ctx, req)
+	require.NoError(t, err)
+	// The client sends to the tenant of its identity, and to no other
+	_, err = server.MetricsClient.Export(withTenant(ctx, "search"), req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+	_, total, err := db.Repo().ListTelemetries(ctx, params)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	tenantCtx := tenant.WithTenant(ctx, "prod-collectors")
+	telemetries, _, err := db.Repo().ListTelemetries(tenantCtx, params)
+	require.NoError(t, err)
+	require.Len(t, telemetries, 1)
+	telemetry, err := db.Repo().GetTelemetry(tenantCtx, telemetries[0].SchemaKey)
+	require.NoError(t, err)
+	require.Len(t, telemetry.Senders, 1)
+	require.Equal(t, "prod-collectors", telemetry.Senders[0].Identity)
+
+	// Clients without a certificate signed by the client CA are refused
+	anonymous := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{TLS: cfg, ClientTLS: ca.ClientTLS(t, nil, nil)})
+	defer anonymous.Close()
+	_, err = anonymous.MetricsClient.Export(ctx, req)
+	require.Error(t, err)
+
+	other := testutil.NewTestCA(t, "other-ca")
+	otherCert, otherKey := other.Issue(t, "gateway-2")
+	untrusted := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{TLS: cfg, ClientTLS: ca.ClientTLS(t, otherCert, otherKey)})
+	defer untrusted.Close()
+	_, err = untrusted.MetricsClient.Export(ctx, req)
+	require.Error(t, err)
+}
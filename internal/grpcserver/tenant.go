@@ -0,0 +1,152 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// TenantMetadataKey is the request metadata naming the tenant an export is sent to
+const TenantMetadataKey = "x-tallycat-tenant"
+
+// TenantInterceptor scopes each call to the tenant of its client: the identity of its verified
+// certificate, else the tenant its ingest token is bound to. A call naming another tenant in its
+// metadata is refused, as is a token bound to a tenant other than the identity of its certificate.
+// Calls bound to no tenant send to the default tenant, and may only name another one when
+// allowHeader is set. It must run after the sender interceptor and the ingest gate.
+func TenantInterceptor(allowHeader bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.") {
+			return handler(ctx, req)
+		}
+
+		name := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(TenantMetadataKey); len(values) > 0 {
+				name = strings.TrimSpace(values[0])
+				if err := tenant.Validate(name); err != nil {
+					return nil, status.Error(codes.InvalidArgument, err.Error())
+				}
+			}
+		}
+
+		bound, client := "", ""
+		if c, _ := ctx.Value(ingestClientKey{}).(*ingestClient); c != nil && c.token.Tenant != "" {
+			bound, client = c.token.Tenant, "ingest token "+c.token.Name
+		}
+		if identity, _ := ctx.Value(senderKey{}).(string); identity != "" {
+			if err := tenant.Validate(identity); err != nil {
+				return nil, status.Errorf(codes.PermissionDenied, "client certificate identity %s is not a tenant: %v", identity, err)
+			}
+			if bound != "" && bound != identity {
+				return nil, status.Errorf(codes.PermissionDenied, "%s is bound to tenant %s, not to the client certificate identity %s", client, bound, identity)
+			}
+			bound, client = identity, "client certificate "+identity
+		}
+
+		switch {
+		case bound != "":
+			if name != "" && name != bound {
+				return nil, status.Errorf(codes.PermissionDenied, "%s cannot send to tenant %s", client, name)
+			}
+			name = bound
+		case name != "" && name != tenant.Default && !allowHeader:
+			return nil, status.Errorf(codes.PermissionDenied, "sending to tenant %s requires an ingest token bound to it or a client certificate identifying it", name)
+		case name == "":
+			name = tenant.Default
+		}
+		return handler(tenant.WithTenant(ctx, name), req)
+	}
+}
+
+// scopeTenant derives the IDs of the schemas, entities, scopes and producers extracted from an
+// export within the tenant of the call, so equal telemetry sent to two tenants never shares an ID.
+// Entities, scopes and producers may be shared between schemas, so they are copied before their
+// ID changes.
+func scopeTenant(ctx context.Context, schemas []schema.Telemetry) {
+	name := tenant.FromContext(ctx)
+	if name == tenant.Default {
+		return
+	}
+
+	entities := make(map[*schema.Entity]*schema.Entity)
+	scopes := make(map[*schema.Scope]*schema.Scope)
+	producers := make(map[*schema.Producer]*schema.Producer)
+
+	for i := range schemas {
+		s := &schemas[i]
+		s.SchemaID = tenant.ScopeID(name, s.SchemaID)
+
+		if s.Entities != nil {
+			scoped := make(map[string]*schema.Entity, len(s.Entities))
+			for _, entity := range s.Entities {
+				copied, ok := entities[entity]
+				if !ok {
+					e := *entity
+					e.ID = tenant.ScopeID(name, entity.ID)
+					copied = &e
+					entities[entity] = copied
+				}
+				scoped[copied.ID] = copied
+			}
+			s.Entities = scoped
+		}
+
+		if s.Scope != nil {
+			copied, ok := scopes[s.Scope]
+			if !ok {
+				sc := *s.Scope
+				sc.ID = tenant.ScopeID(name, s.Scope.ID)
+				copied = &sc
+				scopes[s.Scope] = copied
+			}
+			s.Scope = copied
+		}
+
+		if s.Producers != nil {
+			scoped := make(map[string]*schema.Producer, len(s.Producers))
+			for _, producer := range s.Producers {
+				copied, ok := producers[producer]
+				if !ok {
+					p := *producer
+					p.ID = tenant.ScopeID(name, producer.ID)
+					copied = &p
+					producers[producer] = copied
+				}
+				scoped[copied.ID] = copied
+			}
+			s.Producers = scoped
+		}
+
+		for j := range s.Series {
+			entityIDs := make([]string, len(s.Series[j].EntityIDs))
+			for k, id := range s.Series[j].EntityIDs {
+				entityIDs[k] = tenant.ScopeID(name, id)
+			}
+			s.Series[j].EntityIDs = entityIDs
+		}
+	}
+}
+
+// scopeDependencies points the nodes of a dependency graph to the entities of the tenant of the
+// call. Node IDs are the unscoped IDs of service entities, kept apart by the tenant stored with them.
+func scopeDependencies(ctx context.Context, graph schema.DependencyGraph) schema.DependencyGraph {
+	name := tenant.FromContext(ctx)
+	if name == tenant.Default {
+		return graph
+	}
+	nodes := make([]schema.DependencyNode, len(graph.Nodes))
+	for i, node := range graph.Nodes {
+		node.EntityID = tenant.ScopeID(name, node.EntityID)
+		nodes[i] = node
+	}
+	graph.Nodes = nodes
+	return graph
+}
@@ -0,0 +1,90 @@
+package grpcserver_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/tallycat/tallycat/internal/grpcserver"
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+func withTenant(ctx context.Context, name string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, grpcserver.TenantMetadataKey, name)
+}
+
+func TestExportMetrics_Tenants(t *testing.T) {
+	md, err := golden.ReadMetrics(filepath.Join("testdata", "single_metric_single_schema.yaml"))
+	require.NoError(t, err)
+	req := testutil.ConvertPmetricToRequest(md)
+	ctx := context.Background()
+
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	token, hash, err := schema.GenerateIngestToken()
+	require.NoError(t, err)
+	_, err = db.IngestTokenRepo().CreateIngestToken(ctx, schema.IngestToken{
+		Name:      "payments-collector",
+		Tenant:    "payments",
+		CreatedAt: time.Now(),
+		Hash:      hash,
+	})
+	require.NoError(t, err)
+
+	server := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{
+		IngestGate:        grpcserver.NewIngestGate(db.IngestTokenRepo(), false),
+		AllowTenantHeader: true,
+	})
+	defer server.Close()
+
+	_, err = server.MetricsClient.Export(ctx, req)
+	require.NoError(t, err)
+	_, err = server.MetricsClient.Export(withTenant(ctx, "search"), req)
+	require.NoError(t, err)
+	// A token bound to a tenant sends to it without naming it, and to no other tenant
+	_, err = server.MetricsClient.Export(withIngestToken(ctx, token), req)
+	require.NoError(t, err)
+	_, err = server.MetricsClient.Export(withTenant(withIngestToken(ctx, token), "search"), req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = server.MetricsClient.Export(withTenant(ctx, "Not a tenant"), req)
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+	schemaIDs := make(map[string]bool)
+	for _, name := range []string{tenant.Default, "payments", "search"} {
+		telemetries, total, err := db.Repo().ListTelemetries(tenant.WithTenant(ctx, name), params)
+		require.NoError(t, err)
+		require.Equal(t, 1, total, name)
+
+		schemas, _, err := db.Repo().ListTelemetrySchemas(tenant.WithTenant(ctx, name), telemetries[0].SchemaKey, params)
+		require.NoError(t, err)
+		require.Len(t, schemas, 1)
+		schemaIDs[schemas[0].SchemaId] = true
+	}
+	// Equal telemetry gets a distinct schema ID in each tenant
+	require.Len(t, schemaIDs, 3)
+
+	// Unless allowed, clients bound to no tenant cannot pick one
+	strict := testutil.NewTestServerWithOptions(t, db, testutil.ServerOptions{
+		IngestGate: grpcserver.NewIngestGate(db.IngestTokenRepo(), false),
+	})
+	defer strict.Close()
+	_, err = strict.MetricsClient.Export(withTenant(ctx, "search"), req)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = strict.MetricsClient.Export(withTenant(ctx, tenant.Default), req)
+	require.NoError(t, err)
+	_, err = strict.MetricsClient.Export(withIngestToken(ctx, token), req)
+	require.NoError(t, err)
+}
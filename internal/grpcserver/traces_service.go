@@ -11,17 +11,24 @@ import (
 
 	"github.com/tallycat/tallycat/internal/repository"
 	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
 )
 
 type TracesServiceServer struct {
 	tracespb.UnimplementedTraceServiceServer
-	schemaRepo repository.TelemetrySchemaRepository
-	logger     *slog.Logger
+	entityModel    *schema.EntityModel
+	schemaRepo     repository.TelemetrySchemaRepository
+	policyEnforcer *PolicyEnforcer
+	dependencyRepo repository.DependencyGraphRepository
+	logger         *slog.Logger
 }
 
-func NewTracesServiceServer(schemaRepo repository.TelemetrySchemaRepository) *TracesServiceServer {
+func NewTracesServiceServer(entityModel *schema.EntityModel, schemaRepo repository.TelemetrySchemaRepository, dependencyRepo repository.DependencyGraphRepository, policyEnforcer *PolicyEnforcer) *TracesServiceServer {
 	return &TracesServiceServer{
-		schemaRepo: schemaRepo,
+		entityModel:    entityModel,
+		schemaRepo:     schemaRepo,
+		dependencyRepo: dependencyRepo,
+		policyEnforcer: policyEnforcer,
 	}
 }
 
@@ -164,12 +171,61 @@ func (s *TracesServiceServer) Export(ctx context.Context, req *tracespb.ExportTr
 	}
 
 	// Extract schemas from the converted traces
-	schemas := schema.ExtractFromTraces(traces)
+	schemas := schema.ExtractFromTraces(traces, s.entityModel)
+	tagSender(ctx, schemas)
+	scopeTenant(ctx, schemas)
+
+	// Throttle the client to the rate limits of its ingest token
+	if err := admitIngest(ctx, len(schemas)); err != nil {
+		slog.Warn("export rate limited", "error", err, "signal", "traces")
+		return nil, err
+	}
+
+	// Evaluate governance policies before the schemas are registered
+	schemas, warning, err := s.policyEnforcer.Enforce(ctx, schemas)
+	if err != nil {
+		slog.Warn("export rejected by policy", "error", err, "signal", "traces")
+		return nil, err
+	}
 
 	if err := s.schemaRepo.RegisterTelemetrySchemas(ctx, schemas); err != nil {
 		slog.Error("failed to register schemas", "error", err, "signal", "traces")
 		return nil, err
 	}
 
-	return &tracespb.ExportTraceServiceResponse{}, nil
+	// Extract the service dependencies observed in the spans the policies admitted
+	if s.dependencyRepo != nil {
+		admittedSpans(ctx, traces, schemas)
+		graph := scopeDependencies(ctx, schema.ExtractDependencies(traces, s.entityModel))
+		if err := s.dependencyRepo.RegisterDependencies(ctx, graph); err != nil {
+			slog.Error("failed to register dependencies", "error", err, "signal", "traces")
+			return nil, err
+		}
+	}
+
+	response := &tracespb.ExportTraceServiceResponse{}
+	if warning != "" {
+		response.PartialSuccess = &tracespb.ExportTracePartialSuccess{ErrorMessage: warning}
+	}
+	return response, nil
+}
+
+// admittedSpans removes from the traces the spans whose schema is not among the admitted schemas,
+// as dropped by a policy
+func admittedSpans(ctx context.Context, traces ptrace.Traces, admitted []schema.Telemetry) {
+	name := tenant.FromContext(ctx)
+	schemaIDs := make(map[string]bool, len(admitted))
+	for _, s := range admitted {
+		schemaIDs[s.SchemaID] = true
+	}
+
+	traces.ResourceSpans().RemoveIf(func(resourceSpan ptrace.ResourceSpans) bool {
+		resourceSpan.ScopeSpans().RemoveIf(func(scopeSpan ptrace.ScopeSpans) bool {
+			scopeSpan.Spans().RemoveIf(func(span ptrace.Span) bool {
+				return !schemaIDs[tenant.ScopeID(name, schema.SpanSchemaID(span))]
+			})
+			return scopeSpan.Spans().Len() == 0
+		})
+		return resourceSpan.ScopeSpans().Len() == 0
+	})
 }
@@ -7,8 +7,12 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
 
 	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
 )
 
 func TestExportTraces(t *testing.T) {
@@ -64,3 +68,49 @@ func TestExportTraces(t *testing.T) {
 		require.NotNil(t, resp)
 	}
 }
+
+func TestExportTraces_DependenciesOfAdmittedSpans(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	defer db.Close()
+	db.SetupTestDB(t)
+
+	policies, err := schema.ParsePolicies([]byte(`
+policies:
+  - name: no-card-numbers
+    mode: drop
+    forbidden_attributes: [card.number]
+`))
+	require.NoError(t, err)
+	server := testutil.NewTestServerWithPolicies(t, db, policies)
+	defer server.Close()
+
+	// checkout calls payments with a span the policy drops, and inventory with one it admits
+	traces := ptrace.NewTraces()
+	resourceSpan := traces.ResourceSpans().AppendEmpty()
+	resourceSpan.Resource().Attributes().PutStr("service.name", "checkout")
+	spans := resourceSpan.ScopeSpans().AppendEmpty().Spans()
+	call := func(id byte, name, peer string) ptrace.Span {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		span.SetKind(ptrace.SpanKindClient)
+		span.SetTraceID(pcommon.TraceID([16]byte{id}))
+		span.SetSpanID(pcommon.SpanID([8]byte{id}))
+		span.Attributes().PutStr("http.request.method", "POST")
+		span.Attributes().PutStr("peer.service", peer)
+		return span
+	}
+	call(1, "POST /charge", "payments").Attributes().PutStr("card.number", "4111111111111111")
+	call(2, "POST /reserve", "inventory")
+
+	ctx := context.Background()
+	_, err = server.TracesClient.Export(ctx, testutil.ConvertPtraceToRequest(traces))
+	require.NoError(t, err)
+
+	graph, err := db.DependencyRepo().GetDependencyGraph(ctx, query.TimeWindow{}, "")
+	require.NoError(t, err)
+	require.Len(t, graph.Edges, 1)
+	require.Equal(t, "POST /reserve", graph.Edges[0].SchemaKey)
+	for _, node := range graph.Nodes {
+		require.NotEqual(t, "payments", node.Name)
+	}
+}
@@ -1,8 +1,10 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/tallycat/tallycat/internal/repository/query"
 )
@@ -20,7 +22,38 @@ func ParseListQueryParams(r *http.Request) query.ListQueryParams {
 	return query.ListQueryParams{
 		FilterType: q.Get("type"),
 		Search:     q.Get("search"),
+		Owner:      q.Get("owner"),
 		Page:       page,
 		PageSize:   pageSize,
 	}
 }
+
+// ParseTimeWindow parses the since and until query parameters. Each accepts an RFC 3339
+// timestamp or a duration relative to now (e.g. since=24h).
+func ParseTimeWindow(r *http.Request) (query.TimeWindow, error) {
+	q := r.URL.Query()
+	now := time.Now()
+
+	var window query.TimeWindow
+	var err error
+	if window.Since, err = parseTimeBound(q.Get("since"), now); err != nil {
+		return query.TimeWindow{}, fmt.Errorf("invalid since parameter: %w", err)
+	}
+	if window.Until, err = parseTimeBound(q.Get("until"), now); err != nil {
+		return query.TimeWindow{}, fmt.Errorf("invalid until parameter: %w", err)
+	}
+	if !window.Since.IsZero() && !window.Until.IsZero() && window.Until.Before(window.Since) {
+		return query.TimeWindow{}, fmt.Errorf("until must not be before since")
+	}
+	return window, nil
+}
+
+func parseTimeBound(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
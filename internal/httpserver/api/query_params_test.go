@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTimeWindow(t *testing.T) {
+	req := httptest.NewRequest("GET", "/?since=2025-06-01T10:00:00Z&until=2025-06-02T10:00:00Z", nil)
+	window, err := ParseTimeWindow(req)
+	require.NoError(t, err)
+	require.True(t, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC).Equal(window.Since))
+	require.True(t, time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC).Equal(window.Until))
+
+	req = httptest.NewRequest("GET", "/?since=24h", nil)
+	window, err = ParseTimeWindow(req)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(-24*time.Hour), window.Since, time.Minute)
+	require.True(t, window.Until.IsZero())
+
+	req = httptest.NewRequest("GET", "/", nil)
+	window, err = ParseTimeWindow(req)
+	require.NoError(t, err)
+	require.True(t, window.IsZero())
+
+	req = httptest.NewRequest("GET", "/?since=yesterday", nil)
+	_, err = ParseTimeWindow(req)
+	require.Error(t, err)
+
+	req = httptest.NewRequest("GET", "/?since=2025-06-02T10:00:00Z&until=2025-06-01T10:00:00Z", nil)
+	_, err = ParseTimeWindow(req)
+	require.Error(t, err)
+}
@@ -4,15 +4,22 @@ import (
 	"archive/zip"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/tallycat/tallycat/internal/auth"
 	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/repository/query"
 	"github.com/tallycat/tallycat/internal/schema"
 	"github.com/tallycat/tallycat/internal/weaver"
 )
@@ -102,20 +109,43 @@ func HandleTelemetrySchemaVersionAssignment(
 			return
 		}
 
+		if assignment.SchemaId == "" {
+			assignment.SchemaId = chi.URLParam(r, "schemaId")
+		}
+
 		err = schemaRepo.AssignTelemetrySchemaVersion(ctx, assignment)
-		if err != nil {
+		switch {
+		case errors.Is(err, schema.ErrSchemaNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, schema.ErrInvalidVersion),
+			errors.Is(err, schema.ErrInvalidStatus),
+			errors.Is(err, schema.ErrInvalidStatusTransition):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, schema.ErrVersionConflict),
+			errors.Is(err, schema.ErrIncompatibleVersion):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			slog.Error("failed to assign schema version", "error", err)
 			http.Error(w, "failed to assign schema version", http.StatusInternalServerError)
 			return
 		}
 
+		summary := fmt.Sprintf("Assigned schema version %s to schema %s", assignment.Version, assignment.SchemaId)
+		if assignment.Status != "" {
+			summary += fmt.Sprintf(" as %s", assignment.Status)
+		}
+
 		// Record history entry after successful version assignment
 		history := &schema.TelemetryHistory{
 			SchemaKey: schemaKey,
 			Version:   assignment.Version,
 			Timestamp: time.Now(),
-			Author:    nil,
-			Summary:   fmt.Sprintf("Assigned schema version %s to schema %s", assignment.Version, assignment.SchemaId),
-			Status:    "",
+			Author:    auth.Author(ctx),
+			Summary:   summary,
+			Status:    string(assignment.Status),
 			Snapshot:  nil,
 		}
 
@@ -144,6 +174,95 @@ func HandleGetTelemetrySchema(schemaRepo repository.TelemetrySchemaRepository) h
 	}
 }
 
+// HandleTelemetrySchemaDiff compares two variants of a telemetry, given either by schema ID
+// (from and to) or by the points in time they were current at (two at parameters).
+// The diff is rendered as Markdown when format=markdown or the client accepts text/markdown.
+func HandleTelemetrySchemaDiff(schemaRepo repository.TelemetrySchemaRepository, historyRepo repository.TelemetryHistoryRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		key := chi.URLParam(r, "key")
+		q := r.URL.Query()
+
+		format := q.Get("format")
+		switch format {
+		case "", "json", "markdown":
+		default:
+			http.Error(w, "invalid format, expected json or markdown", http.StatusBadRequest)
+			return
+		}
+		if format == "" && strings.Contains(r.Header.Get("Accept"), "text/markdown") {
+			format = "markdown"
+		}
+
+		fromID, toID := q.Get("from"), q.Get("to")
+		at := q["at"]
+		switch {
+		case len(at) > 0 && (fromID != "" || toID != ""):
+			http.Error(w, "use either from and to or at, not both", http.StatusBadRequest)
+			return
+		case len(at) > 0:
+			if len(at) != 2 {
+				http.Error(w, "at must be given exactly twice", http.StatusBadRequest)
+				return
+			}
+			now := time.Now()
+			times := make([]time.Time, 0, 2)
+			for _, value := range at {
+				t, err := parseTimeBound(value, now)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("invalid at parameter: %v", err), http.StatusBadRequest)
+					return
+				}
+				times = append(times, t)
+			}
+			ids := make([]string, 0, 2)
+			for _, t := range times {
+				schemaID, err := historyRepo.GetSchemaIDAt(ctx, key, t)
+				if err != nil {
+					slog.Error("failed to resolve schema at time", "error", err)
+					http.Error(w, "failed to resolve schema", http.StatusInternalServerError)
+					return
+				}
+				if schemaID == "" {
+					http.Error(w, fmt.Sprintf("no schema seen for %s at %s", key, t.Format(time.RFC3339)), http.StatusNotFound)
+					return
+				}
+				ids = append(ids, schemaID)
+			}
+			fromID, toID = ids[0], ids[1]
+		case fromID == "" || toID == "":
+			http.Error(w, "from and to schema IDs are required", http.StatusBadRequest)
+			return
+		}
+
+		schemas := make([]*schema.TelemetrySchema, 0, 2)
+		for _, schemaID := range []string{fromID, toID} {
+			telemetrySchema, err := schemaRepo.GetTelemetrySchema(ctx, schemaID)
+			if err != nil {
+				slog.Error("failed to get schema", "error", err)
+				http.Error(w, "failed to get schema", http.StatusInternalServerError)
+				return
+			}
+			if telemetrySchema == nil || telemetrySchema.SchemaKey != key {
+				http.Error(w, fmt.Sprintf("schema %s not found for %s", schemaID, key), http.StatusNotFound)
+				return
+			}
+			schemas = append(schemas, telemetrySchema)
+		}
+
+		diff := schema.DiffTelemetrySchemas(schemas[0], schemas[1])
+
+		if format == "markdown" {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write([]byte(diff.Markdown()))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(diff)
+	}
+}
+
 func HandleWeaverSchemaExport(schemaRepo repository.TelemetrySchemaRepository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -214,8 +333,14 @@ func HandleEntityWeaverSchemaExport(schemaRepo repository.TelemetrySchemaReposit
 		ctx := r.Context()
 		entityType := chi.URLParam(r, "entityType")
 
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Get all telemetries for this entity type
-		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType)
+		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType, window)
 		if err != nil {
 			slog.Error("failed to get telemetries for entity", "entityType", entityType, "error", err)
 			http.Error(w, "failed to get telemetries for entity", http.StatusInternalServerError)
@@ -359,8 +484,14 @@ func HandleEntitySchemaExport(schemaRepo repository.TelemetrySchemaRepository) h
 		entityType := chi.URLParam(r, "entityType")
 		telemetryType := chi.URLParam(r, "type")
 
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Get all telemetries for this entity type
-		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType)
+		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType, window)
 		if err != nil {
 			slog.Error("failed to get telemetries for entity", "entityType", entityType, "error", err)
 			http.Error(w, "failed to get telemetries for entity", http.StatusInternalServerError)
@@ -402,8 +533,14 @@ func HandleEntityDashboardExport(schemaRepo repository.TelemetrySchemaRepository
 		ctx := r.Context()
 		entityType := chi.URLParam(r, "entityType")
 
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Get all telemetries for this entity type
-		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType)
+		telemetries, err := schemaRepo.ListTelemetriesByEntity(ctx, entityType, window)
 		if err != nil {
 			slog.Error("failed to get telemetries for entity", "entityType", entityType, "error", err)
 			http.Error(w, "failed to get telemetries for entity", http.StatusInternalServerError)
@@ -687,6 +824,100 @@ func HandleScopeSchemaExport(schemaRepo repository.TelemetrySchemaRepository) ht
 	}
 }
 
+// HandleProducerList returns a paginated and searched list of producers as JSON.
+func HandleProducerList(schemaRepo repository.TelemetrySchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+		producers, total, err := schemaRepo.ListProducers(ctx, params)
+		if err != nil {
+			slog.Error("failed to list producers", "error", err)
+			http.Error(w, "failed to list producers", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.Producer]{
+			Items:    producers,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleProducerWeaverSchemaExport returns a ZIP with the Weaver definitions of every metric
+// emitted by a producer, together with its registry manifest.
+func HandleProducerWeaverSchemaExport(schemaRepo repository.TelemetrySchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		producerNameVersion := chi.URLParam(r, "producerNameVersion")
+
+		producerName, producerVersion, err := parseProducerNameVersion(producerNameVersion)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		telemetries, err := schemaRepo.ListTelemetriesByProducer(ctx, producerName, producerVersion)
+		if err != nil {
+			slog.Error("failed to get telemetries for producer", "producer", producerNameVersion, "error", err)
+			http.Error(w, "failed to get telemetries for producer", http.StatusInternalServerError)
+			return
+		}
+
+		metricsYAML, err := weaver.GenerateMultiMetricYAML(telemetries, nil)
+		if err != nil {
+			slog.Error("failed to generate metrics YAML", "producer", producerNameVersion, "error", err)
+			http.Error(w, "failed to generate metrics YAML", http.StatusInternalServerError)
+			return
+		}
+
+		// Unknown producers and producers without metrics are both reported as no content
+		if metricsYAML == "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		manifest := weaver.GenerateRegistryManifest(producerName, producerVersion)
+
+		var buf bytes.Buffer
+		zipWriter := zip.NewWriter(&buf)
+
+		metricsFile, err := zipWriter.Create(producerName + "---" + producerVersion + ".yaml")
+		if err != nil {
+			http.Error(w, "failed to create metrics file in zip", http.StatusInternalServerError)
+			return
+		}
+		if _, err := metricsFile.Write([]byte(metricsYAML)); err != nil {
+			http.Error(w, "failed to write metrics yaml to zip", http.StatusInternalServerError)
+			return
+		}
+
+		manifestFile, err := zipWriter.Create("registry_manifest.yaml")
+		if err != nil {
+			http.Error(w, "failed to create manifest file in zip", http.StatusInternalServerError)
+			return
+		}
+		if _, err := manifestFile.Write([]byte(manifest)); err != nil {
+			http.Error(w, "failed to write manifest to zip", http.StatusInternalServerError)
+			return
+		}
+
+		if err := zipWriter.Close(); err != nil {
+			http.Error(w, "failed to close zip file", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", "attachment; filename="+producerName+"-"+producerVersion+".zip")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", buf.Len()))
+		w.Write(buf.Bytes())
+	}
+}
+
 // parseProducerNameVersion parses the producer name---version format
 // Supports empty versions (e.g., "node-exporter---" for producers without version)
 func parseProducerNameVersion(nameVersion string) (string, string, error) {
@@ -765,7 +996,13 @@ func HandleTelemetryEntityList(schemaRepo repository.TelemetrySchemaRepository)
 		ctx := r.Context()
 		telemetryKey := chi.URLParam(r, "key")
 
-		entities, err := schemaRepo.ListEntitiesByTelemetry(ctx, telemetryKey)
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entities, err := schemaRepo.ListEntitiesByTelemetry(ctx, telemetryKey, window)
 		if err != nil {
 			slog.Error("failed to list entities for telemetry", "error", err, "telemetry_key", telemetryKey)
 			http.Error(w, "failed to list entities for telemetry", http.StatusInternalServerError)
@@ -832,3 +1069,1018 @@ func HandleTelemetryScopeList(schemaRepo repository.TelemetrySchemaRepository) h
 		json.NewEncoder(w).Encode(resp)
 	}
 }
+
+// HandleEntityAttributeChanges returns the attribute change log of an entity
+func HandleEntityAttributeChanges(entityHistoryRepo repository.EntityHistoryRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		entityID := chi.URLParam(r, "entityId")
+
+		changes, err := entityHistoryRepo.ListEntityAttributeChanges(ctx, entityID)
+		if err != nil {
+			slog.Error("failed to list entity attribute changes", "error", err, "entity_id", entityID)
+			http.Error(w, "failed to list entity attribute changes", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(changes)
+	}
+}
+
+// HandleEntityRollouts returns the rollouts of an entity, correlating attribute changes with
+// the schemas that appeared or disappeared. When the attribute query parameter is set only
+// the rollouts that introduced that attribute are returned.
+func HandleEntityRollouts(entityHistoryRepo repository.EntityHistoryRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		entityID := chi.URLParam(r, "entityId")
+		attributeName := r.URL.Query().Get("attribute")
+
+		attributeChanges, err := entityHistoryRepo.ListEntityAttributeChanges(ctx, entityID)
+		if err != nil {
+			slog.Error("failed to list entity attribute changes", "error", err, "entity_id", entityID)
+			http.Error(w, "failed to list entity rollouts", http.StatusInternalServerError)
+			return
+		}
+
+		schemaChanges, err := entityHistoryRepo.ListEntitySchemaChanges(ctx, entityID, attributeName)
+		if err != nil {
+			slog.Error("failed to list entity schema changes", "error", err, "entity_id", entityID)
+			http.Error(w, "failed to list entity rollouts", http.StatusInternalServerError)
+			return
+		}
+
+		rollouts := schema.CorrelateRollouts(attributeChanges, schemaChanges)
+		if attributeName != "" {
+			filtered := []schema.EntityRollout{}
+			for _, rollout := range rollouts {
+				if len(rollout.SchemaChanges) > 0 {
+					filtered = append(filtered, rollout)
+				}
+			}
+			rollouts = filtered
+		}
+		if rollouts == nil {
+			rollouts = []schema.EntityRollout{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rollouts)
+	}
+}
+
+// HandleDependencyGraph returns the service dependency graph as JSON, DOT or Mermaid depending on the format query parameter.
+// The graph can be restricted to a time window and to the edges of a single node.
+func HandleDependencyGraph(dependencyRepo repository.DependencyGraphRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		switch format {
+		case "", "json", "dot", "mermaid":
+		default:
+			http.Error(w, "invalid format, expected json, dot or mermaid", http.StatusBadRequest)
+			return
+		}
+
+		graph, err := dependencyRepo.GetDependencyGraph(ctx, window, r.URL.Query().Get("node"))
+		if err != nil {
+			slog.Error("failed to get dependency graph", "error", err)
+			http.Error(w, "failed to get dependency graph", http.StatusInternalServerError)
+			return
+		}
+
+		switch format {
+		case "dot":
+			w.Header().Set("Content-Type", "text/vnd.graphviz")
+			w.Write([]byte(graph.DOT()))
+		case "mermaid":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(graph.Mermaid()))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(graph)
+		}
+	}
+}
+
+// HandlePolicyViolationList returns the recorded policy violations, most recent first.
+// Violations can be filtered by policy, schemaKey, mode and a since/until time window.
+func HandlePolicyViolationList(violationRepo repository.PolicyViolationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		q := r.URL.Query()
+		filter := query.PolicyViolationFilter{
+			Policy:    q.Get("policy"),
+			SchemaKey: q.Get("schemaKey"),
+			Mode:      q.Get("mode"),
+			Window:    window,
+		}
+		switch schema.PolicyMode(filter.Mode) {
+		case "", schema.PolicyModeAudit, schema.PolicyModeWarn, schema.PolicyModeReject, schema.PolicyModeDrop:
+		default:
+			http.Error(w, "invalid mode, expected audit, warn, reject or drop", http.StatusBadRequest)
+			return
+		}
+
+		violations, total, err := violationRepo.ListPolicyViolations(ctx, params, filter)
+		if err != nil {
+			slog.Error("failed to list policy violations", "error", err)
+			http.Error(w, "failed to list policy violations", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.PolicyViolation]{
+			Items:    violations,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleBudgetList returns the configured budgets with the current usage of their scope and the limits it exceeds
+func HandleBudgetList(budgets *schema.BudgetSet, budgetRepo repository.BudgetRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+
+		var matching []schema.Budget
+		if budgets != nil {
+			for _, budget := range budgets.Budgets {
+				if params.Search == "" || strings.Contains(strings.ToLower(budget.Name), strings.ToLower(params.Search)) {
+					matching = append(matching, budget)
+				}
+			}
+		}
+
+		start := min((params.Page-1)*params.PageSize, len(matching))
+		end := min(start+params.PageSize, len(matching))
+
+		now := time.Now()
+		statuses := []schema.BudgetStatus{}
+		for _, budget := range matching[start:end] {
+			usage, err := budgetRepo.GetBudgetUsage(ctx, budget, budgets.ActiveSeriesWindow, now)
+			if err != nil {
+				slog.Error("failed to get budget usage", "budget", budget.Name, "error", err)
+				http.Error(w, "failed to get budget usage", http.StatusInternalServerError)
+				return
+			}
+			statuses = append(statuses, schema.BudgetStatus{
+				Budget:   budget,
+				Usage:    *usage,
+				Breaches: budget.Check(*usage),
+			})
+		}
+
+		resp := ListResponse[schema.BudgetStatus]{
+			Items:    statuses,
+			Total:    len(matching),
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleBudgetBreachList returns the recorded budget breaches, most recent first.
+// Breaches can be filtered by budget, limit and a since/until time window.
+func HandleBudgetBreachList(budgetRepo repository.BudgetRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		q := r.URL.Query()
+		filter := query.BudgetBreachFilter{
+			Budget: q.Get("budget"),
+			Limit:  q.Get("limit"),
+			Window: window,
+		}
+		switch filter.Limit {
+		case "", schema.BudgetLimitMetricSchemas, schema.BudgetLimitActiveSeries, schema.BudgetLimitLogSchemasPerDay:
+		default:
+			http.Error(w, "invalid limit, expected max_metric_schemas, max_active_series or max_log_schemas_per_day", http.StatusBadRequest)
+			return
+		}
+
+		breaches, total, err := budgetRepo.ListBudgetBreaches(ctx, params, filter)
+		if err != nil {
+			slog.Error("failed to list budget breaches", "error", err)
+			http.Error(w, "failed to list budget breaches", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.BudgetBreach]{
+			Items:    breaches,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleTelemetryUsage returns the usage of a telemetry key over time, across all its schemas.
+// The resolution query parameter selects hour (default) or day buckets within a since/until window.
+func HandleTelemetryUsage(usageRepo repository.UsageRollupRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleUsage(w, r, usageRepo, query.UsageFilter{SchemaKey: chi.URLParam(r, "key")})
+	}
+}
+
+// HandleEntityUsage returns the usage of the schemas emitted by an entity over time.
+// The resolution query parameter selects hour (default) or day buckets within a since/until window.
+func HandleEntityUsage(usageRepo repository.UsageRollupRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleUsage(w, r, usageRepo, query.UsageFilter{EntityID: chi.URLParam(r, "entityId")})
+	}
+}
+
+func handleUsage(w http.ResponseWriter, r *http.Request, usageRepo repository.UsageRollupRepository, filter query.UsageFilter) {
+	resolution, err := schema.ParseUsageResolution(r.URL.Query().Get("resolution"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	window, err := ParseTimeWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter.Resolution = string(resolution)
+	filter.Window = window
+
+	usage, err := usageRepo.GetUsage(r.Context(), filter)
+	if err != nil {
+		slog.Error("failed to get usage", "error", err, "schema_key", filter.SchemaKey, "entity_id", filter.EntityID)
+		http.Error(w, "failed to get usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(usage)
+}
+
+// HandleStaleTelemetryList returns the schemas marked stale by the retention job, longest unseen first,
+// so owners can confirm their removal. The type query parameter narrows them down to a signal.
+func HandleStaleTelemetryList(retentionRepo repository.RetentionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+
+		telemetries, total, err := retentionRepo.ListStaleTelemetries(ctx, params)
+		if err != nil {
+			slog.Error("failed to list stale telemetries", "error", err)
+			http.Error(w, "failed to list stale telemetries", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.StaleTelemetry]{
+			Items:    telemetries,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleRemoveStaleTelemetry removes a stale schema right away, archiving it first when the retention
+// policy archives expired schemas, and returns what was removed
+func HandleRemoveStaleTelemetry(retention *schema.RetentionPolicy, retentionRepo repository.RetentionRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schemaID := chi.URLParam(r, "schemaId")
+
+		action := schema.RetentionActionDelete
+		if retention != nil {
+			action = retention.Action
+		}
+
+		result, err := retentionRepo.RemoveStaleTelemetry(r.Context(), schemaID, action)
+		switch {
+		case errors.Is(err, schema.ErrSchemaNotStale):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			slog.Error("failed to remove stale telemetry", "error", err, "schema_id", schemaID)
+			http.Error(w, "failed to remove stale telemetry", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// HandleTeamList returns the teams owning telemetry with the number of telemetries, entities and
+// scopes each owns
+func HandleTeamList(ownershipRepo repository.OwnershipRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		teams, err := ownershipRepo.ListTeams(r.Context())
+		if err != nil {
+			slog.Error("failed to list teams", "error", err)
+			http.Error(w, "failed to list teams", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(teams)
+	}
+}
+
+// HandleTeamInventory returns the telemetries, entities and scopes owned by a team
+func HandleTeamInventory(ownershipRepo repository.OwnershipRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		team := chi.URLParam(r, "team")
+
+		inventory, err := ownershipRepo.GetTeamInventory(r.Context(), team)
+		if err != nil {
+			slog.Error("failed to get team inventory", "error", err, "team", team)
+			http.Error(w, "failed to get team inventory", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(inventory)
+	}
+}
+
+// OwnerAssignmentRequest lists the teams explicitly owning an asset
+type OwnerAssignmentRequest struct {
+	Teams []string `json:"teams"`
+}
+
+// HandleGetOwnership returns the owners resolved for a telemetry, entity or scope
+func HandleGetOwnership(ownershipRepo repository.OwnershipRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleOwnership(w, r, func(kind schema.OwnedKind, id string) (*schema.Ownership, error) {
+			return ownershipRepo.GetOwnership(r.Context(), kind, id)
+		})
+	}
+}
+
+// HandleAssignOwners replaces the teams explicitly owning a telemetry, entity or scope, which take
+// precedence over the ownership rules
+func HandleAssignOwners(ownershipRepo repository.OwnershipRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req OwnerAssignmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		if len(req.Teams) == 0 {
+			http.Error(w, "teams cannot be empty, delete the assignment instead", http.StatusBadRequest)
+			return
+		}
+
+		handleOwnership(w, r, func(kind schema.OwnedKind, id string) (*schema.Ownership, error) {
+			return ownershipRepo.AssignOwners(r.Context(), kind, id, req.Teams)
+		})
+	}
+}
+
+// HandleDeleteOwnerAssignment removes the explicit owners of a telemetry, entity or scope, which
+// falls back to the owners matched by the ownership rules
+func HandleDeleteOwnerAssignment(ownershipRepo repository.OwnershipRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleOwnership(w, r, func(kind schema.OwnedKind, id string) (*schema.Ownership, error) {
+			return ownershipRepo.AssignOwners(r.Context(), kind, id, nil)
+		})
+	}
+}
+
+func handleOwnership(w http.ResponseWriter, r *http.Request, resolve func(kind schema.OwnedKind, id string) (*schema.Ownership, error)) {
+	kind, err := schema.ParseOwnedKind(chi.URLParam(r, "kind"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id := chi.URLParam(r, "id")
+
+	ownership, err := resolve(kind, id)
+	switch {
+	case errors.Is(err, schema.ErrAssetNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, schema.ErrUnknownTeam):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		slog.Error("failed to resolve ownership", "error", err, "kind", kind, "id", id)
+		http.Error(w, "failed to resolve ownership", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ownership)
+}
+
+// HandleGetDocumentation returns the documentation curated for a telemetry
+func HandleGetDocumentation(docRepo repository.DocumentationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, "key")
+		doc, err := docRepo.GetDocumentation(r.Context(), key)
+		writeDocumentation(w, key, doc, err)
+	}
+}
+
+// HandleUpdateDocumentation merges the brief, note, stability, tags and attribute documentation of
+// the request into the documentation curated for a telemetry. Fields left out are unchanged.
+func HandleUpdateDocumentation(docRepo repository.DocumentationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, "key")
+
+		var patch schema.DocumentationPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			http.Error(w, "failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		updateDocumentation(w, r, docRepo, key, patch)
+	}
+}
+
+// HandleUpdateAttributeDocumentation updates the brief, requirement level and stability curated
+// for an attribute of a telemetry
+func HandleUpdateAttributeDocumentation(docRepo repository.DocumentationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := chi.URLParam(r, "key")
+
+		var attrPatch schema.AttributeDocumentationPatch
+		if err := json.NewDecoder(r.Body).Decode(&attrPatch); err != nil {
+			http.Error(w, "failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		patch := schema.DocumentationPatch{
+			Attributes: map[string]schema.AttributeDocumentationPatch{chi.URLParam(r, "attribute"): attrPatch},
+		}
+		updateDocumentation(w, r, docRepo, key, patch)
+	}
+}
+
+func updateDocumentation(w http.ResponseWriter, r *http.Request, docRepo repository.DocumentationRepository, key string, patch schema.DocumentationPatch) {
+	if err := patch.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	doc, err := docRepo.UpdateDocumentation(r.Context(), key, patch)
+	writeDocumentation(w, key, doc, err)
+}
+
+func writeDocumentation(w http.ResponseWriter, key string, doc *schema.TelemetryDocumentation, err error) {
+	switch {
+	case errors.Is(err, schema.ErrAssetNotFound):
+		http.Error(w, "telemetry not found", http.StatusNotFound)
+		return
+	case err != nil:
+		slog.Error("failed to access documentation", "error", err, "key", key)
+		http.Error(w, "failed to access documentation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// maxRegistrySize bounds the size of imported Weaver registries
+const maxRegistrySize = 10 << 20
+
+// DeclaredSchemaImportResponse reports the number of schemas declared by an imported registry
+type DeclaredSchemaImportResponse struct {
+	Registry string `json:"registry"`
+	Imported int    `json:"imported"`
+}
+
+// HandleImportDeclaredSchemas imports the Weaver registry YAML of the request body as the declared
+// schemas of the registry query parameter, replacing its previous import. The entityType and scope
+// query parameters set the entity type and scope expected to emit them.
+func HandleImportDeclaredSchemas(declaredRepo repository.DeclaredSchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		registry := q.Get("registry")
+		if registry == "" {
+			http.Error(w, "registry parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRegistrySize))
+		if err != nil {
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		declared, err := weaver.ParseYAML(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		for i := range declared {
+			declared[i].Registry = registry
+			declared[i].EntityType = q.Get("entityType")
+			declared[i].ScopeName = q.Get("scope")
+			declared[i].ImportedAt = now
+		}
+
+		if err := declaredRepo.ImportDeclaredSchemas(r.Context(), registry, declared); err != nil {
+			slog.Error("failed to import declared schemas", "error", err, "registry", registry)
+			http.Error(w, "failed to import declared schemas", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(DeclaredSchemaImportResponse{Registry: registry, Imported: len(declared)})
+	}
+}
+
+// HandleDeclaredSchemaList returns the declared schemas, filtered by the registry, entityType and
+// scope query parameters
+func HandleDeclaredSchemaList(declaredRepo repository.DeclaredSchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		declared, err := declaredRepo.ListDeclaredSchemas(r.Context(), query.DeclaredSchemaFilter{
+			Registry:   q.Get("registry"),
+			EntityType: q.Get("entityType"),
+			ScopeName:  q.Get("scope"),
+		})
+		if err != nil {
+			slog.Error("failed to list declared schemas", "error", err)
+			http.Error(w, "failed to list declared schemas", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(declared)
+	}
+}
+
+// HandleEntityCoverage compares the telemetry declared for an entity type with the telemetry its
+// entities emitted within the since/until window
+func HandleEntityCoverage(schemaRepo repository.TelemetrySchemaRepository, declaredRepo repository.DeclaredSchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entityType := chi.URLParam(r, "entityType")
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		observed, err := schemaRepo.ListTelemetriesByEntity(r.Context(), entityType, window)
+		if err != nil {
+			slog.Error("failed to get telemetries for entity", "entityType", entityType, "error", err)
+			http.Error(w, "failed to get telemetries for entity", http.StatusInternalServerError)
+			return
+		}
+		writeCoverage(w, r, declaredRepo, query.DeclaredSchemaFilter{EntityType: entityType}, observed)
+	}
+}
+
+// HandleScopeCoverage compares the telemetry declared for a scope with the telemetry it emitted
+func HandleScopeCoverage(schemaRepo repository.TelemetrySchemaRepository, declaredRepo repository.DeclaredSchemaRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scopeName := chi.URLParam(r, "scope")
+
+		observed, err := schemaRepo.ListTelemetriesByScope(r.Context(), scopeName)
+		if err != nil {
+			slog.Error("failed to get telemetries for scope", "scopeName", scopeName, "error", err)
+			http.Error(w, "failed to get telemetries for scope", http.StatusInternalServerError)
+			return
+		}
+		writeCoverage(w, r, declaredRepo, query.DeclaredSchemaFilter{ScopeName: scopeName}, observed)
+	}
+}
+
+func writeCoverage(w http.ResponseWriter, r *http.Request, declaredRepo repository.DeclaredSchemaRepository, filter query.DeclaredSchemaFilter, observed []schema.Telemetry) {
+	declared, err := declaredRepo.ListDeclaredSchemas(r.Context(), filter)
+	if err != nil {
+		slog.Error("failed to list declared schemas", "error", err)
+		http.Error(w, "failed to list declared schemas", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schema.ComputeCoverage(declared, observed))
+}
+
+// HandleReviewList returns the review queue, most recently detected first. Reviews can be filtered
+// by state, schemaKey and reason.
+func HandleReviewList(reviewRepo repository.ReviewRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := ParseListQueryParams(r)
+
+		q := r.URL.Query()
+		filter := query.ReviewFilter{
+			State:     q.Get("state"),
+			SchemaKey: q.Get("schemaKey"),
+			Reason:    q.Get("reason"),
+		}
+		if filter.State != "" {
+			if _, err := schema.ParseReviewState(filter.State); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		switch schema.ReviewReason(filter.Reason) {
+		case "", schema.ReviewReasonNewKey, schema.ReviewReasonNewVariant:
+		default:
+			http.Error(w, "invalid reason, expected new_key or new_variant", http.StatusBadRequest)
+			return
+		}
+
+		reviews, total, err := reviewRepo.ListReviews(r.Context(), params, filter)
+		if err != nil {
+			slog.Error("failed to list reviews", "error", err)
+			http.Error(w, "failed to list reviews", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.TelemetryReview]{
+			Items:    reviews,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleDecideReview approves, rejects or merges a pending review
+func HandleDecideReview(reviewRepo repository.ReviewRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid review id", http.StatusBadRequest)
+			return
+		}
+
+		var decision schema.ReviewDecision
+		if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+			http.Error(w, "failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		// Authenticated reviewers cannot decide on behalf of someone else
+		if author := auth.Author(r.Context()); author != nil {
+			decision.Author = *author
+		}
+
+		review, err := reviewRepo.DecideReview(r.Context(), id, decision)
+		switch {
+		case errors.Is(err, schema.ErrReviewNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, schema.ErrReviewDecided):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, schema.ErrInvalidReviewDecision):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			slog.Error("failed to decide review", "error", err, "id", id)
+			http.Error(w, "failed to decide review", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(review)
+	}
+}
+
+// HandleReviewNotificationList returns the notifications of telemetry entering the review queue,
+// most recent first, within an optional since/until time window
+func HandleReviewNotificationList(reviewRepo repository.ReviewRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := ParseListQueryParams(r)
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		notifications, total, err := reviewRepo.ListReviewNotifications(r.Context(), params, window)
+		if err != nil {
+			slog.Error("failed to list review notifications", "error", err)
+			http.Error(w, "failed to list review notifications", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.ReviewNotification]{
+			Items:    notifications,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleAuditLogList returns the audit log of mutating requests, most recent first, filtered by
+// subject and method within an optional since/until time window
+func HandleAuditLogList(auditRepo repository.AuditLogRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		params := ParseListQueryParams(r)
+
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		q := r.URL.Query()
+		filter := query.AuditFilter{
+			Subject: q.Get("subject"),
+			Method:  strings.ToUpper(q.Get("method")),
+			Window:  window,
+		}
+
+		entries, total, err := auditRepo.ListAuditEntries(ctx, params, filter)
+		if err != nil {
+			slog.Error("failed to list audit entries", "error", err)
+			http.Error(w, "failed to list audit entries", http.StatusInternalServerError)
+			return
+		}
+
+		resp := ListResponse[schema.AuditEntry]{
+			Items:    entries,
+			Total:    total,
+			Page:     params.Page,
+			PageSize: params.PageSize,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// HandleIngestTokenList returns the ingest tokens, revoked ones included, without their secret
+func HandleIngestTokenList(tokenRepo repository.IngestTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := tokenRepo.ListIngestTokens(r.Context())
+		if err != nil {
+			slog.Error("failed to list ingest tokens", "error", err)
+			http.Error(w, "failed to list ingest tokens", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+	}
+}
+
+// HandleCreateIngestToken generates an ingest token with its rate limits. The token is only
+// returned in this response, TallyCat keeps its hash.
+func HandleCreateIngestToken(tokenRepo repository.IngestTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req schema.IngestTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "failed to decode request body", http.StatusBadRequest)
+			return
+		}
+		if err := req.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		secret, hash, err := schema.GenerateIngestToken()
+		if err != nil {
+			slog.Error("failed to generate ingest token", "error", err)
+			http.Error(w, "failed to create ingest token", http.StatusInternalServerError)
+			return
+		}
+
+		token, err := tokenRepo.CreateIngestToken(r.Context(), schema.IngestToken{
+			Name:              req.Name,
+			RequestsPerSecond: req.RequestsPerSecond,
+			SchemasPerSecond:  req.SchemasPerSecond,
+			Tenant:            req.Tenant,
+			CreatedAt:         time.Now(),
+			CreatedBy:         auth.Author(r.Context()),
+			Hash:              hash,
+		})
+		switch {
+		case errors.Is(err, schema.ErrIngestTokenExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			slog.Error("failed to create ingest token", "error", err, "name", req.Name)
+			http.Error(w, "failed to create ingest token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(schema.CreatedIngestToken{IngestToken: *token, Token: secret})
+	}
+}
+
+// HandleRevokeIngestToken revokes an ingest token, refused on ingest from then on
+func HandleRevokeIngestToken(tokenRepo repository.IngestTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+
+		err := tokenRepo.RevokeIngestToken(r.Context(), name, time.Now())
+		switch {
+		case errors.Is(err, schema.ErrIngestTokenNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			slog.Error("failed to revoke ingest token", "error", err, "name", name)
+			http.Error(w, "failed to revoke ingest token", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleIngestTokenStats returns the requests, schemas and throttled requests of every ingest
+// token within an optional since/until time window, counted by hour
+func HandleIngestTokenStats(tokenRepo repository.IngestTokenRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window, err := ParseTimeWindow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stats, err := tokenRepo.ListIngestTokenStats(r.Context(), window)
+		if err != nil {
+			slog.Error("failed to list ingest token stats", "error", err)
+			http.Error(w, "failed to list ingest token stats", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
+
+// HandleTenantList returns every tenant with the size of its catalog and when it last received
+// telemetry
+func HandleTenantList(tenantRepo repository.TenantRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenants, err := tenantRepo.ListTenants(r.Context())
+		if err != nil {
+			slog.Error("failed to list tenants", "error", err)
+			http.Error(w, "failed to list tenants", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tenants)
+	}
+}
+
+// backupNameLayout names the backups taken through the API after the time they were taken
+const backupNameLayout = "20060102T150405Z"
+
+// HandleBackupList lists the backups of the backup directory, newest first. Directories without
+// a readable manifest, such as backups still being written, are left out.
+func HandleBackupList(backupDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := os.ReadDir(backupDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.Error("failed to read backup directory", "error", err, "dir", backupDir)
+			http.Error(w, "failed to list backups", http.StatusInternalServerError)
+			return
+		}
+
+		backups := []schema.Backup{}
+		for _, entry := range entries {
+			if !entry.IsDir() || !schema.ValidBackupName(entry.Name()) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(backupDir, entry.Name(), schema.BackupManifestFile))
+			if err != nil {
+				continue
+			}
+			backup := schema.Backup{Name: entry.Name()}
+			if err := json.Unmarshal(data, &backup.Manifest); err != nil {
+				continue
+			}
+			backups = append(backups, backup)
+		}
+		sort.Slice(backups, func(i, j int) bool {
+			return backups[i].Manifest.CreatedAt.After(backups[j].Manifest.CreatedAt)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(backups)
+	}
+}
+
+// HandleCreateBackup backs up the catalog of every tenant to a new directory of the backup
+// directory, named after the time it was taken
+func HandleCreateBackup(backupRepo repository.BackupRepository, backupDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := time.Now().UTC().Format(backupNameLayout)
+
+		manifest, err := backupRepo.Backup(r.Context(), filepath.Join(backupDir, name))
+		switch {
+		case errors.Is(err, schema.ErrBackupExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			slog.Error("failed to back up catalog", "error", err, "backup", name)
+			http.Error(w, "failed to back up catalog", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(schema.Backup{Name: name, Manifest: *manifest})
+	}
+}
+
+// HandleBackupFile downloads the manifest or a Parquet file of a backup, e.g. for offline analysis
+func HandleBackupFile(backupDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		file := chi.URLParam(r, "file")
+		if !schema.ValidBackupName(name) || !schema.ValidBackupName(file) {
+			http.Error(w, "invalid backup or file name", http.StatusBadRequest)
+			return
+		}
+
+		path := filepath.Join(backupDir, name, file)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			http.Error(w, "backup file not found", http.StatusNotFound)
+			return
+		}
+		if strings.HasSuffix(file, ".parquet") {
+			w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+		}
+		http.ServeFile(w, r, path)
+	}
+}
+
+// HandleRestoreBackup restores a backup of the backup directory into the catalog, refused
+// unless the catalog is empty
+func HandleRestoreBackup(backupRepo repository.BackupRepository, backupDir string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "name")
+		if !schema.ValidBackupName(name) {
+			http.Error(w, "invalid backup name", http.StatusBadRequest)
+			return
+		}
+		dir := filepath.Join(backupDir, name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			http.Error(w, "backup not found", http.StatusNotFound)
+			return
+		}
+
+		manifest, err := backupRepo.Restore(r.Context(), dir)
+		switch {
+		case errors.Is(err, schema.ErrInvalidBackup):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case errors.Is(err, schema.ErrBackupVersionMismatch), errors.Is(err, schema.ErrCatalogNotEmpty):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			slog.Error("failed to restore backup", "error", err, "backup", name)
+			http.Error(w, "failed to restore backup", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schema.Backup{Name: name, Manifest: *manifest})
+	}
+}
@@ -1,17 +1,23 @@
 package api
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/auth"
 	"github.com/tallycat/tallycat/internal/repository/query"
 	"github.com/tallycat/tallycat/internal/schema"
 )
@@ -57,8 +63,8 @@ func (m *MockTelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context,
 	return args.Get(0).(*schema.TelemetrySchema), args.Error(1)
 }
 
-func (m *MockTelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string) ([]schema.Telemetry, error) {
-	args := m.Called(ctx, entityType)
+func (m *MockTelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string, window query.TimeWindow) ([]schema.Telemetry, error) {
+	args := m.Called(ctx, entityType, window)
 	return args.Get(0).([]schema.Telemetry), args.Error(1)
 }
 
@@ -67,6 +73,16 @@ func (m *MockTelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Conte
 	return args.Get(0).([]schema.Telemetry), args.Error(1)
 }
 
+func (m *MockTelemetrySchemaRepository) ListProducers(ctx context.Context, params query.ListQueryParams) ([]schema.Producer, int, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]schema.Producer), args.Int(1), args.Error(2)
+}
+
+func (m *MockTelemetrySchemaRepository) ListTelemetriesByProducer(ctx context.Context, name, version string) ([]schema.Telemetry, error) {
+	args := m.Called(ctx, name, version)
+	return args.Get(0).([]schema.Telemetry), args.Error(1)
+}
+
 func (m *MockTelemetrySchemaRepository) ListScopes(ctx context.Context, params query.ListQueryParams) ([]schema.Scope, int, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).([]schema.Scope), args.Int(1), args.Error(2)
@@ -77,8 +93,8 @@ func (m *MockTelemetrySchemaRepository) ListEntities(ctx context.Context, params
 	return args.Get(0).([]schema.Entity), args.Int(1), args.Error(2)
 }
 
-func (m *MockTelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Entity, error) {
-	args := m.Called(ctx, telemetryKey)
+func (m *MockTelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string, window query.TimeWindow) ([]schema.Entity, error) {
+	args := m.Called(ctx, telemetryKey, window)
 	return args.Get(0).([]schema.Entity), args.Error(1)
 }
 
@@ -89,7 +105,7 @@ func (m *MockTelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Contex
 
 func TestHandleEntityWeaverSchemaExport_EntityNotFound(t *testing.T) {
 	mockRepo := new(MockTelemetrySchemaRepository)
-	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service").
+	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).
 		Return([]schema.Telemetry{}, nil)
 
 	handler := HandleEntityWeaverSchemaExport(mockRepo)
@@ -111,7 +127,7 @@ func TestHandleEntityWeaverSchemaExport_EntityNotFound(t *testing.T) {
 
 func TestHandleEntityWeaverSchemaExport_EntityWithNoMetrics(t *testing.T) {
 	mockRepo := new(MockTelemetrySchemaRepository)
-	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service").
+	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).
 		Return([]schema.Telemetry{}, nil)
 
 	handler := HandleEntityWeaverSchemaExport(mockRepo)
@@ -170,7 +186,7 @@ func TestHandleEntityWeaverSchemaExport_EntityWithMetrics(t *testing.T) {
 	}
 
 	mockRepo := new(MockTelemetrySchemaRepository)
-	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service").
+	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).
 		Return(mockTelemetries, nil)
 
 	handler := HandleEntityWeaverSchemaExport(mockRepo)
@@ -195,7 +211,7 @@ func TestHandleEntityWeaverSchemaExport_EntityWithMetrics(t *testing.T) {
 
 func TestHandleEntityWeaverSchemaExport_InvalidEntityFormat(t *testing.T) {
 	mockRepo := new(MockTelemetrySchemaRepository)
-	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service").
+	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).
 		Return([]schema.Telemetry{}, nil)
 
 	handler := HandleEntityWeaverSchemaExport(mockRepo)
@@ -217,7 +233,7 @@ func TestHandleEntityWeaverSchemaExport_InvalidEntityFormat(t *testing.T) {
 
 func TestHandleEntityWeaverSchemaExport_RepositoryError(t *testing.T) {
 	mockRepo := new(MockTelemetrySchemaRepository)
-	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service").
+	mockRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).
 		Return([]schema.Telemetry{}, fmt.Errorf("database error"))
 
 	handler := HandleEntityWeaverSchemaExport(mockRepo)
@@ -300,3 +316,900 @@ func TestHandleScopeList_WithScopes(t *testing.T) {
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestHandleProducerWeaverSchemaExport_WithMetrics(t *testing.T) {
+	mockRepo := new(MockTelemetrySchemaRepository)
+	mockRepo.On("ListTelemetriesByProducer", mock.Anything, "my-service", "1.0.0").
+		Return([]schema.Telemetry{
+			{
+				SchemaKey:     "http.server.duration",
+				TelemetryType: schema.TelemetryTypeMetric,
+				MetricType:    schema.MetricTypeHistogram,
+				MetricUnit:    "ms",
+			},
+		}, nil)
+
+	handler := HandleProducerWeaverSchemaExport(mockRepo)
+
+	req := httptest.NewRequest("GET", "/api/v1/producers/my-service---1.0.0/weaver-schema.zip", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("producerNameVersion", "my-service---1.0.0")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	require.Equal(t, "attachment; filename=my-service-1.0.0.zip", w.Header().Get("Content-Disposition"))
+
+	zipReader, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	require.NoError(t, err)
+	var names []string
+	for _, f := range zipReader.File {
+		names = append(names, f.Name)
+	}
+	require.ElementsMatch(t, []string{"my-service---1.0.0.yaml", "registry_manifest.yaml"}, names)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleProducerWeaverSchemaExport_NoMetrics(t *testing.T) {
+	mockRepo := new(MockTelemetrySchemaRepository)
+	mockRepo.On("ListTelemetriesByProducer", mock.Anything, "node-exporter", "").
+		Return([]schema.Telemetry{}, nil)
+
+	handler := HandleProducerWeaverSchemaExport(mockRepo)
+
+	req := httptest.NewRequest("GET", "/api/v1/producers/node-exporter---/weaver-schema.zip", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("producerNameVersion", "node-exporter---")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleProducerWeaverSchemaExport_InvalidFormat(t *testing.T) {
+	mockRepo := new(MockTelemetrySchemaRepository)
+
+	handler := HandleProducerWeaverSchemaExport(mockRepo)
+
+	req := httptest.NewRequest("GET", "/api/v1/producers/my-service/weaver-schema.zip", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("producerNameVersion", "my-service")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	mockRepo.AssertNotCalled(t, "ListTelemetriesByProducer", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// MockTelemetryHistoryRepository is a mock implementation of the history repository interface
+type MockTelemetryHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockTelemetryHistoryRepository) InsertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error {
+	args := m.Called(ctx, h)
+	return args.Error(0)
+}
+
+func (m *MockTelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, telemetryID string, page, pageSize int) ([]schema.TelemetryHistory, int, error) {
+	args := m.Called(ctx, telemetryID, page, pageSize)
+	return args.Get(0).([]schema.TelemetryHistory), args.Int(1), args.Error(2)
+}
+
+func (m *MockTelemetryHistoryRepository) GetSchemaIDAt(ctx context.Context, schemaKey string, at time.Time) (string, error) {
+	args := m.Called(ctx, schemaKey, at)
+	return args.String(0), args.Error(1)
+}
+
+func diffTestSchemas() (*schema.TelemetrySchema, *schema.TelemetrySchema) {
+	from := &schema.TelemetrySchema{
+		SchemaId:   "v1",
+		SchemaKey:  "http.server.duration",
+		MetricUnit: "ms",
+		MetricType: schema.MetricTypeHistogram,
+		Attributes: []schema.Attribute{
+			{Name: "http.method", Type: schema.AttributeTypeStr, Source: schema.AttributeSourceDataPoint},
+		},
+		Entities: map[string]*schema.Entity{"checkout": {ID: "checkout"}},
+		Scopes:   []schema.Scope{{Name: "otelhttp", Version: "0.50.0"}},
+	}
+	to := &schema.TelemetrySchema{
+		SchemaId:   "v2",
+		SchemaKey:  "http.server.duration",
+		MetricUnit: "s",
+		MetricType: schema.MetricTypeHistogram,
+		Attributes: []schema.Attribute{
+			{Name: "http.request.method", Type: schema.AttributeTypeStr, Source: schema.AttributeSourceDataPoint},
+		},
+		Entities: map[string]*schema.Entity{"checkout": {ID: "checkout"}, "payments": {ID: "payments"}},
+		Scopes:   []schema.Scope{{Name: "otelhttp", Version: "0.51.0"}},
+	}
+	return from, to
+}
+
+func newDiffRequest(target string) *http.Request {
+	req := httptest.NewRequest("GET", target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("key", "http.server.duration")
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestHandleTelemetrySchemaDiff_BySchemaID(t *testing.T) {
+	from, to := diffTestSchemas()
+	mockRepo := new(MockTelemetrySchemaRepository)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v1").Return(from, nil)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v2").Return(to, nil)
+	historyRepo := new(MockTelemetryHistoryRepository)
+
+	handler := HandleTelemetrySchemaDiff(mockRepo, historyRepo)
+
+	w := httptest.NewRecorder()
+	handler(w, newDiffRequest("/api/v1/telemetries/http.server.duration/schemas/diff?from=v1&to=v2"))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var diff schema.SchemaDiff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &diff))
+	require.Equal(t, "v1", diff.FromSchemaID)
+	require.Equal(t, "v2", diff.ToSchemaID)
+	require.Len(t, diff.AddedAttributes, 1)
+	require.Equal(t, "http.request.method", diff.AddedAttributes[0].Name)
+	require.Len(t, diff.RemovedAttributes, 1)
+	require.Equal(t, []schema.FieldChange{{Field: "unit", OldValue: "ms", NewValue: "s"}}, diff.FieldChanges)
+	require.Equal(t, []string{"payments"}, diff.AddedEntities)
+	require.Empty(t, diff.RemovedEntities)
+	require.Equal(t, []string{"otelhttp@0.51.0"}, diff.AddedScopes)
+	require.Equal(t, []string{"otelhttp@0.50.0"}, diff.RemovedScopes)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleTelemetrySchemaDiff_AtTimesAsMarkdown(t *testing.T) {
+	from, to := diffTestSchemas()
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(24 * time.Hour)
+
+	mockRepo := new(MockTelemetrySchemaRepository)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v1").Return(from, nil)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v2").Return(to, nil)
+	historyRepo := new(MockTelemetryHistoryRepository)
+	historyRepo.On("GetSchemaIDAt", mock.Anything, "http.server.duration", t0).Return("v1", nil)
+	historyRepo.On("GetSchemaIDAt", mock.Anything, "http.server.duration", t1).Return("v2", nil)
+
+	handler := HandleTelemetrySchemaDiff(mockRepo, historyRepo)
+
+	req := newDiffRequest(fmt.Sprintf("/api/v1/telemetries/http.server.duration/schemas/diff?at=%s&at=%s",
+		t0.Format(time.RFC3339), t1.Format(time.RFC3339)))
+	req.Header.Set("Accept", "text/markdown")
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/markdown; charset=utf-8", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	require.Contains(t, body, "## Schema diff `v1` → `v2`")
+	require.Contains(t, body, "| unit | `ms` | `s` |")
+	require.Contains(t, body, "| added | `http.request.method` | DataPoint | Str |")
+	require.Contains(t, body, "- added `payments`")
+
+	historyRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleTelemetrySchemaDiff_SchemaFromOtherKey(t *testing.T) {
+	from, to := diffTestSchemas()
+	to.SchemaKey = "http.client.duration"
+	mockRepo := new(MockTelemetrySchemaRepository)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v1").Return(from, nil)
+	mockRepo.On("GetTelemetrySchema", mock.Anything, "v2").Return(to, nil)
+
+	handler := HandleTelemetrySchemaDiff(mockRepo, new(MockTelemetryHistoryRepository))
+
+	w := httptest.NewRecorder()
+	handler(w, newDiffRequest("/api/v1/telemetries/http.server.duration/schemas/diff?from=v1&to=v2"))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleTelemetrySchemaDiff_InvalidParameters(t *testing.T) {
+	tests := []string{
+		"/api/v1/telemetries/http.server.duration/schemas/diff",
+		"/api/v1/telemetries/http.server.duration/schemas/diff?from=v1",
+		"/api/v1/telemetries/http.server.duration/schemas/diff?at=24h",
+		"/api/v1/telemetries/http.server.duration/schemas/diff?at=24h&at=soon",
+		"/api/v1/telemetries/http.server.duration/schemas/diff?from=v1&to=v2&at=24h&at=1h",
+		"/api/v1/telemetries/http.server.duration/schemas/diff?from=v1&to=v2&format=html",
+	}
+	for _, target := range tests {
+		t.Run(target, func(t *testing.T) {
+			mockRepo := new(MockTelemetrySchemaRepository)
+			handler := HandleTelemetrySchemaDiff(mockRepo, new(MockTelemetryHistoryRepository))
+
+			w := httptest.NewRecorder()
+			handler(w, newDiffRequest(target))
+
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			mockRepo.AssertNotCalled(t, "GetTelemetrySchema", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleTelemetrySchemaVersionAssignment_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w \"one\"", schema.ErrInvalidVersion), http.StatusBadRequest},
+		{fmt.Errorf("%w: active to draft", schema.ErrInvalidStatusTransition), http.StatusBadRequest},
+		{fmt.Errorf("%w: breaking", schema.ErrIncompatibleVersion), http.StatusConflict},
+		{fmt.Errorf("%w: v9", schema.ErrSchemaNotFound), http.StatusNotFound},
+		{fmt.Errorf("database is locked"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			mockRepo := new(MockTelemetrySchemaRepository)
+			mockRepo.On("AssignTelemetrySchemaVersion", mock.Anything, schema.SchemaAssignment{SchemaId: "v1", Version: "1.1.0"}).
+				Return(tt.err)
+			historyRepo := new(MockTelemetryHistoryRepository)
+
+			handler := HandleTelemetrySchemaVersionAssignment(mockRepo, historyRepo)
+
+			req := httptest.NewRequest("POST", "/api/v1/telemetries/http.server.duration/schemas/v1", bytes.NewBufferString(`{"version":"1.1.0"}`))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("key", "http.server.duration")
+			rctx.URLParams.Add("schemaId", "v1")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			require.Equal(t, tt.status, w.Code)
+			historyRepo.AssertNotCalled(t, "InsertTelemetryHistory", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+type MockBudgetRepository struct {
+	mock.Mock
+}
+
+func (m *MockBudgetRepository) ListScopeSchemaIDs(ctx context.Context, scope schema.BudgetScope, telemetryType schema.TelemetryType, since time.Time) ([]string, error) {
+	args := m.Called(ctx, scope, telemetryType, since)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockBudgetRepository) CountActiveSeries(ctx context.Context, scope schema.BudgetScope, since time.Time) (int, error) {
+	args := m.Called(ctx, scope, since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockBudgetRepository) GetBudgetUsage(ctx context.Context, budget schema.Budget, activeSeriesWindow time.Duration, now time.Time) (*schema.BudgetUsage, error) {
+	args := m.Called(ctx, budget, activeSeriesWindow, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.BudgetUsage), args.Error(1)
+}
+
+func (m *MockBudgetRepository) RecordBudgetBreaches(ctx context.Context, breaches []schema.BudgetBreach) error {
+	args := m.Called(ctx, breaches)
+	return args.Error(0)
+}
+
+func (m *MockBudgetRepository) ListBudgetBreaches(ctx context.Context, params query.ListQueryParams, filter query.BudgetBreachFilter) ([]schema.BudgetBreach, int, error) {
+	args := m.Called(ctx, params, filter)
+	return args.Get(0).([]schema.BudgetBreach), args.Int(1), args.Error(2)
+}
+
+func TestHandleBudgetList(t *testing.T) {
+	budgets, err := schema.ParseBudgets([]byte(`
+budgets:
+  - {name: checkout, max_metric_schemas: 2, scope: {entity: service.name=checkout}}
+  - {name: tenant, max_active_series: 100}
+`))
+	require.NoError(t, err)
+
+	budgetRepo := new(MockBudgetRepository)
+	budgetRepo.On("GetBudgetUsage", mock.Anything, budgets.Budgets[0], time.Hour, mock.Anything).
+		Return(&schema.BudgetUsage{MetricSchemas: 3}, nil)
+	budgetRepo.On("GetBudgetUsage", mock.Anything, budgets.Budgets[1], time.Hour, mock.Anything).
+		Return(&schema.BudgetUsage{ActiveSeries: 10}, nil)
+
+	w := httptest.NewRecorder()
+	HandleBudgetList(budgets, budgetRepo)(w, httptest.NewRequest(http.MethodGet, "/api/v1/budgets", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ListResponse[schema.BudgetStatus]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 2, resp.Total)
+	require.Len(t, resp.Items, 2)
+	require.Equal(t, "checkout", resp.Items[0].Budget.Name)
+	require.Len(t, resp.Items[0].Breaches, 1)
+	require.Equal(t, schema.BudgetLimitMetricSchemas, resp.Items[0].Breaches[0].Limit)
+	require.Empty(t, resp.Items[1].Breaches)
+
+	w = httptest.NewRecorder()
+	HandleBudgetList(budgets, budgetRepo)(w, httptest.NewRequest(http.MethodGet, "/api/v1/budgets?search=tenant", nil))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Total)
+	require.Equal(t, "tenant", resp.Items[0].Budget.Name)
+
+	budgetRepo.AssertExpectations(t)
+}
+
+func TestHandleBudgetList_NoBudgets(t *testing.T) {
+	w := httptest.NewRecorder()
+	HandleBudgetList(nil, new(MockBudgetRepository))(w, httptest.NewRequest(http.MethodGet, "/api/v1/budgets", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ListResponse[schema.BudgetStatus]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Zero(t, resp.Total)
+	require.Empty(t, resp.Items)
+}
+
+type MockUsageRollupRepository struct {
+	mock.Mock
+}
+
+func (m *MockUsageRollupRepository) GetUsage(ctx context.Context, filter query.UsageFilter) (*schema.UsageSeries, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.UsageSeries), args.Error(1)
+}
+
+func (m *MockUsageRollupRepository) CompactUsage(ctx context.Context, now time.Time, retention schema.UsageRetention) error {
+	args := m.Called(ctx, now, retention)
+	return args.Error(0)
+}
+
+func TestHandleTelemetryUsage(t *testing.T) {
+	since := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	usageRepo := new(MockUsageRollupRepository)
+	usageRepo.On("GetUsage", mock.Anything, query.UsageFilter{
+		SchemaKey:  "http.server.duration",
+		Resolution: "day",
+		Window:     query.TimeWindow{Since: since},
+	}).Return(&schema.UsageSeries{
+		SchemaKey:  "http.server.duration",
+		Resolution: schema.UsageResolutionDay,
+		Points:     []schema.UsagePoint{{Bucket: since, SeenCount: 3, DataPointCount: 30, EstimatedBytes: 900}},
+	}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/telemetries/{key}/usage", HandleTelemetryUsage(usageRepo))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/telemetries/http.server.duration/usage?resolution=day&since=2025-06-01T00:00:00Z", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var usage schema.UsageSeries
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &usage))
+	require.Len(t, usage.Points, 1)
+	require.Equal(t, int64(30), usage.Points[0].DataPointCount)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/telemetries/http.server.duration/usage?resolution=minute", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	usageRepo.AssertExpectations(t)
+}
+
+type MockRetentionRepository struct {
+	mock.Mock
+}
+
+func (m *MockRetentionRepository) RunRetention(ctx context.Context, policy *schema.RetentionPolicy, now time.Time) (*schema.RetentionResult, error) {
+	args := m.Called(ctx, policy, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.RetentionResult), args.Error(1)
+}
+
+func (m *MockRetentionRepository) ListStaleTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.StaleTelemetry, int, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).([]schema.StaleTelemetry), args.Int(1), args.Error(2)
+}
+
+func (m *MockRetentionRepository) RemoveStaleTelemetry(ctx context.Context, schemaID string, action schema.RetentionAction) (*schema.RetentionResult, error) {
+	args := m.Called(ctx, schemaID, action)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.RetentionResult), args.Error(1)
+}
+
+func TestHandleStaleTelemetries(t *testing.T) {
+	lastSeen := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	retentionRepo := new(MockRetentionRepository)
+	retentionRepo.On("ListStaleTelemetries", mock.Anything, query.ListQueryParams{FilterType: "metric", Page: 1, PageSize: 10}).
+		Return([]schema.StaleTelemetry{{
+			SchemaID:      "v1",
+			SchemaKey:     "checkout.requests",
+			TelemetryType: schema.TelemetryTypeMetric,
+			LastSeen:      lastSeen,
+			StaleSince:    lastSeen.Add(24 * time.Hour),
+		}}, 1, nil)
+	retentionRepo.On("RemoveStaleTelemetry", mock.Anything, "v1", schema.RetentionActionArchive).
+		Return(&schema.RetentionResult{Archived: 1, DeletedSchemas: 1}, nil)
+	retentionRepo.On("RemoveStaleTelemetry", mock.Anything, "v2", schema.RetentionActionArchive).
+		Return(nil, fmt.Errorf("%w: v2", schema.ErrSchemaNotStale))
+
+	retention := schema.DefaultRetentionPolicy()
+	retention.Action = schema.RetentionActionArchive
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/stale-telemetries", HandleStaleTelemetryList(retentionRepo))
+	r.Delete("/api/v1/stale-telemetries/{schemaId}", HandleRemoveStaleTelemetry(retention, retentionRepo))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/stale-telemetries?type=metric&page_size=10", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ListResponse[schema.StaleTelemetry]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, 1, resp.Total)
+	require.Equal(t, "v1", resp.Items[0].SchemaID)
+	require.Nil(t, resp.Items[0].ExpiresAt)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/stale-telemetries/v1", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result schema.RetentionResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	require.Equal(t, 1, result.Archived)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/stale-telemetries/v2", nil))
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	retentionRepo.AssertExpectations(t)
+}
+
+type MockOwnershipRepository struct {
+	mock.Mock
+}
+
+func (m *MockOwnershipRepository) RefreshOwnership(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockOwnershipRepository) AssignOwners(ctx context.Context, kind schema.OwnedKind, id string, teams []string) (*schema.Ownership, error) {
+	args := m.Called(ctx, kind, id, teams)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.Ownership), args.Error(1)
+}
+
+func (m *MockOwnershipRepository) GetOwnership(ctx context.Context, kind schema.OwnedKind, id string) (*schema.Ownership, error) {
+	args := m.Called(ctx, kind, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.Ownership), args.Error(1)
+}
+
+func (m *MockOwnershipRepository) ListTeams(ctx context.Context) ([]schema.TeamSummary, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]schema.TeamSummary), args.Error(1)
+}
+
+func (m *MockOwnershipRepository) GetTeamInventory(ctx context.Context, team string) (*schema.TeamInventory, error) {
+	args := m.Called(ctx, team)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.TeamInventory), args.Error(1)
+}
+
+func TestHandleOwnership(t *testing.T) {
+	ownershipRepo := new(MockOwnershipRepository)
+	ownershipRepo.On("AssignOwners", mock.Anything, schema.OwnedKindTelemetry, "http.server.duration", []string{"payments"}).
+		Return(&schema.Ownership{
+			Kind:   schema.OwnedKindTelemetry,
+			ID:     "http.server.duration",
+			Owners: []schema.Owner{{Team: "payments", Source: schema.OwnershipSourceExplicit}},
+		}, nil)
+	ownershipRepo.On("AssignOwners", mock.Anything, schema.OwnedKindTelemetry, "http.server.duration", []string{"nobody"}).
+		Return(nil, fmt.Errorf("%w %q", schema.ErrUnknownTeam, "nobody"))
+	ownershipRepo.On("GetOwnership", mock.Anything, schema.OwnedKindEntity, "missing").
+		Return(nil, fmt.Errorf("%w: entity missing", schema.ErrAssetNotFound))
+	ownershipRepo.On("AssignOwners", mock.Anything, schema.OwnedKindScope, "otelhttp", []string(nil)).
+		Return(&schema.Ownership{Kind: schema.OwnedKindScope, ID: "otelhttp", Owners: []schema.Owner{}}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/ownership/{kind}/{id}", HandleGetOwnership(ownershipRepo))
+	r.Put("/api/v1/ownership/{kind}/{id}", HandleAssignOwners(ownershipRepo))
+	r.Delete("/api/v1/ownership/{kind}/{id}", HandleDeleteOwnerAssignment(ownershipRepo))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"assign", http.MethodPut, "/api/v1/ownership/telemetry/http.server.duration", `{"teams":["payments"]}`, http.StatusOK},
+		{"unknown team", http.MethodPut, "/api/v1/ownership/telemetry/http.server.duration", `{"teams":["nobody"]}`, http.StatusBadRequest},
+		{"no teams", http.MethodPut, "/api/v1/ownership/telemetry/http.server.duration", `{"teams":[]}`, http.StatusBadRequest},
+		{"invalid kind", http.MethodGet, "/api/v1/ownership/producer/checkout", "", http.StatusBadRequest},
+		{"unknown asset", http.MethodGet, "/api/v1/ownership/entity/missing", "", http.StatusNotFound},
+		{"delete", http.MethodDelete, "/api/v1/ownership/scope/otelhttp", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+
+	ownershipRepo.AssertExpectations(t)
+}
+
+func TestHandleTeamInventory(t *testing.T) {
+	ownershipRepo := new(MockOwnershipRepository)
+	ownershipRepo.On("GetTeamInventory", mock.Anything, "payments").Return(&schema.TeamInventory{
+		Team:        schema.Team{Name: "payments", Contacts: []string{"payments@example.com"}},
+		Telemetries: []schema.OwnedAsset{{ID: "checkout.requests", Type: "Metric", Source: schema.OwnershipSourceRule}},
+		Entities:    []schema.OwnedAsset{},
+		Scopes:      []schema.OwnedAsset{},
+	}, nil)
+
+	r := chi.NewRouter()
+	r.Get("/api/v1/teams/{team}/inventory", HandleTeamInventory(ownershipRepo))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/teams/payments/inventory", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var inventory schema.TeamInventory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &inventory))
+	require.Equal(t, "checkout.requests", inventory.Telemetries[0].ID)
+
+	ownershipRepo.AssertExpectations(t)
+}
+
+type MockDocumentationRepository struct {
+	mock.Mock
+}
+
+func (m *MockDocumentationRepository) GetDocumentation(ctx context.Context, schemaKey string) (*schema.TelemetryDocumentation, error) {
+	args := m.Called(ctx, schemaKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.TelemetryDocumentation), args.Error(1)
+}
+
+func (m *MockDocumentationRepository) UpdateDocumentation(ctx context.Context, schemaKey string, patch schema.DocumentationPatch) (*schema.TelemetryDocumentation, error) {
+	args := m.Called(ctx, schemaKey, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.TelemetryDocumentation), args.Error(1)
+}
+
+func TestHandleUpdateDocumentation(t *testing.T) {
+	docRepo := new(MockDocumentationRepository)
+	docRepo.On("UpdateDocumentation", mock.Anything, "checkout.requests", mock.MatchedBy(func(patch schema.DocumentationPatch) bool {
+		return patch.Brief != nil && *patch.Brief == "Requests handled by checkout" && patch.Note == nil
+	})).Return(&schema.TelemetryDocumentation{SchemaKey: "checkout.requests", Brief: "Requests handled by checkout"}, nil)
+	docRepo.On("UpdateDocumentation", mock.Anything, "checkout.requests", mock.MatchedBy(func(patch schema.DocumentationPatch) bool {
+		level := patch.Attributes["http.method"].RequirementLevel
+		return level != nil && *level == "required"
+	})).Return(&schema.TelemetryDocumentation{SchemaKey: "checkout.requests"}, nil)
+	docRepo.On("UpdateDocumentation", mock.Anything, "unknown", mock.Anything).
+		Return(nil, fmt.Errorf("%w: telemetry unknown", schema.ErrAssetNotFound))
+
+	r := chi.NewRouter()
+	r.Patch("/api/v1/telemetries/{key}/documentation", HandleUpdateDocumentation(docRepo))
+	r.Patch("/api/v1/telemetries/{key}/attributes/{attribute}/documentation", HandleUpdateAttributeDocumentation(docRepo))
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"brief", "/api/v1/telemetries/checkout.requests/documentation", `{"brief":"Requests handled by checkout"}`, http.StatusOK},
+		{"attribute", "/api/v1/telemetries/checkout.requests/attributes/http.method/documentation", `{"requirementLevel":"required"}`, http.StatusOK},
+		{"invalid stability", "/api/v1/telemetries/checkout.requests/documentation", `{"stability":"beta"}`, http.StatusBadRequest},
+		{"invalid body", "/api/v1/telemetries/checkout.requests/documentation", `{`, http.StatusBadRequest},
+		{"unknown telemetry", "/api/v1/telemetries/unknown/documentation", `{"note":"n"}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+		})
+	}
+
+	docRepo.AssertExpectations(t)
+}
+
+type MockDeclaredSchemaRepository struct {
+	mock.Mock
+}
+
+func (m *MockDeclaredSchemaRepository) ImportDeclaredSchemas(ctx context.Context, registry string, declared []schema.DeclaredTelemetry) error {
+	args := m.Called(ctx, registry, declared)
+	return args.Error(0)
+}
+
+func (m *MockDeclaredSchemaRepository) ListDeclaredSchemas(ctx context.Context, filter query.DeclaredSchemaFilter) ([]schema.DeclaredTelemetry, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]schema.DeclaredTelemetry), args.Error(1)
+}
+
+func TestHandleImportDeclaredSchemas(t *testing.T) {
+	declaredRepo := new(MockDeclaredSchemaRepository)
+	declaredRepo.On("ImportDeclaredSchemas", mock.Anything, "checkout", mock.MatchedBy(func(declared []schema.DeclaredTelemetry) bool {
+		return len(declared) == 1 &&
+			declared[0].SchemaKey == "checkout.requests" &&
+			declared[0].Registry == "checkout" &&
+			declared[0].EntityType == "service"
+	})).Return(nil)
+
+	registry := `
+groups:
+  - id: metric.otelhttp.checkout.requests
+    type: metric
+    metric_name: checkout.requests
+    brief: "Requests handled by checkout"
+    instrument: counter
+    unit: "1"
+`
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/declared-schemas?registry=checkout&entityType=service", strings.NewReader(registry))
+	HandleImportDeclaredSchemas(declaredRepo).ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var resp DeclaredSchemaImportResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, DeclaredSchemaImportResponse{Registry: "checkout", Imported: 1}, resp)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/declared-schemas", strings.NewReader(registry))
+	HandleImportDeclaredSchemas(declaredRepo).ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/api/v1/declared-schemas?registry=checkout", strings.NewReader("groups: [\n"))
+	HandleImportDeclaredSchemas(declaredRepo).ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	declaredRepo.AssertExpectations(t)
+}
+
+func TestHandleEntityCoverage(t *testing.T) {
+	schemaRepo := new(MockTelemetrySchemaRepository)
+	schemaRepo.On("ListTelemetriesByEntity", mock.Anything, "service", query.TimeWindow{}).Return([]schema.Telemetry{
+		{SchemaKey: "checkout.requests", TelemetryType: schema.TelemetryTypeMetric},
+		{SchemaKey: "checkout.queue.size", TelemetryType: schema.TelemetryTypeMetric},
+	}, nil)
+	declaredRepo := new(MockDeclaredSchemaRepository)
+	declaredRepo.On("ListDeclaredSchemas", mock.Anything, query.DeclaredSchemaFilter{EntityType: "service"}).Return([]schema.DeclaredTelemetry{
+		{Registry: "checkout", SchemaKey: "checkout.requests", TelemetryType: schema.TelemetryTypeMetric},
+		{Registry: "checkout", SchemaKey: "checkout.refunds", TelemetryType: schema.TelemetryTypeMetric},
+	}, nil)
+
+	router := chi.NewRouter()
+	router.Get("/api/v1/entities/{entityType}/coverage", HandleEntityCoverage(schemaRepo, declaredRepo))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/entities/service/coverage", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var report schema.CoverageReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	require.Equal(t, 1, report.DeclaredAndObserved)
+	require.Equal(t, 1, report.DeclaredButMissing)
+	require.Equal(t, 1, report.ObservedButUndeclared)
+
+	schemaRepo.AssertExpectations(t)
+	declaredRepo.AssertExpectations(t)
+}
+
+type MockReviewRepository struct {
+	mock.Mock
+}
+
+func (m *MockReviewRepository) ListReviews(ctx context.Context, params query.ListQueryParams, filter query.ReviewFilter) ([]schema.TelemetryReview, int, error) {
+	args := m.Called(ctx, params, filter)
+	return args.Get(0).([]schema.TelemetryReview), args.Int(1), args.Error(2)
+}
+
+func (m *MockReviewRepository) DecideReview(ctx context.Context, id int64, decision schema.ReviewDecision) (*schema.TelemetryReview, error) {
+	args := m.Called(ctx, id, decision)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.TelemetryReview), args.Error(1)
+}
+
+func (m *MockReviewRepository) ListReviewNotifications(ctx context.Context, params query.ListQueryParams, window query.TimeWindow) ([]schema.ReviewNotification, int, error) {
+	args := m.Called(ctx, params, window)
+	return args.Get(0).([]schema.ReviewNotification), args.Int(1), args.Error(2)
+}
+
+func (m *MockReviewRepository) ListReviewPolicies(ctx context.Context) ([]schema.Policy, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]schema.Policy), args.Error(1)
+}
+
+func TestHandleDecideReview(t *testing.T) {
+	reviewRepo := new(MockReviewRepository)
+	merge := schema.ReviewDecision{Action: schema.ReviewActionMerge, Author: "alice", MergeInto: "checkout.requests"}
+	reviewRepo.On("DecideReview", mock.Anything, int64(3), merge).Return(&schema.TelemetryReview{
+		ID:         3,
+		SchemaKey:  "checkout_requests",
+		State:      schema.ReviewStateMerged,
+		Reviewer:   "alice",
+		MergedInto: "checkout.requests",
+	}, nil)
+	reviewRepo.On("DecideReview", mock.Anything, int64(4), mock.Anything).Return(nil, fmt.Errorf("%w: review 4 is approved", schema.ErrReviewDecided))
+	reviewRepo.On("DecideReview", mock.Anything, int64(5), mock.Anything).Return(nil, schema.ErrReviewNotFound)
+
+	router := chi.NewRouter()
+	router.Post("/api/v1/reviews/{id}/decision", HandleDecideReview(reviewRepo))
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+	}{
+		{name: "merge", path: "/api/v1/reviews/3/decision", body: `{"action":"merge","author":"alice","mergeInto":"checkout.requests"}`, wantCode: http.StatusOK},
+		{name: "already decided", path: "/api/v1/reviews/4/decision", body: `{"action":"approve","author":"alice"}`, wantCode: http.StatusConflict},
+		{name: "unknown review", path: "/api/v1/reviews/5/decision", body: `{"action":"approve","author":"alice"}`, wantCode: http.StatusNotFound},
+		{name: "invalid id", path: "/api/v1/reviews/abc/decision", body: `{}`, wantCode: http.StatusBadRequest},
+		{name: "invalid body", path: "/api/v1/reviews/3/decision", body: `{`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body)))
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/reviews/3/decision", strings.NewReader(`{"action":"merge","author":"alice","mergeInto":"checkout.requests"}`)))
+	var review schema.TelemetryReview
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &review))
+	require.Equal(t, schema.ReviewStateMerged, review.State)
+	require.Equal(t, "checkout.requests", review.MergedInto)
+
+	// The authenticated reviewer is recorded, whatever the body claims
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/reviews/3/decision", strings.NewReader(`{"action":"merge","author":"mallory","mergeInto":"checkout.requests"}`))
+	r = r.WithContext(auth.WithIdentity(r.Context(), &auth.Identity{Subject: "alice", Role: auth.RoleEditor, Method: auth.MethodJWT}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+func TestHandleReviewList_InvalidState(t *testing.T) {
+	reviewRepo := new(MockReviewRepository)
+
+	w := httptest.NewRecorder()
+	HandleReviewList(reviewRepo).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/reviews?state=ignored", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	reviewRepo.AssertNotCalled(t, "ListReviews", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type MockBackupRepository struct {
+	mock.Mock
+}
+
+func (m *MockBackupRepository) Backup(ctx context.Context, dir string) (*schema.BackupManifest, error) {
+	args := m.Called(ctx, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.BackupManifest), args.Error(1)
+}
+
+func (m *MockBackupRepository) Restore(ctx context.Context, dir string) (*schema.BackupManifest, error) {
+	args := m.Called(ctx, dir)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*schema.BackupManifest), args.Error(1)
+}
+
+func TestHandleBackups(t *testing.T) {
+	backupDir := t.TempDir()
+	manifest := &schema.BackupManifest{
+		FormatVersion:    schema.BackupFormatVersion,
+		MigrationVersion: 23,
+		CreatedAt:        time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+		Tables:           []schema.BackupTable{{Name: "telemetry_schemas", File: "telemetry_schemas.parquet", Rows: 2, SHA256: strings.Repeat("a", 64)}},
+	}
+
+	backupRepo := new(MockBackupRepository)
+	backupRepo.On("Backup", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		dir := args.String(1)
+		data, _ := json.Marshal(manifest)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, schema.BackupManifestFile), data, 0o644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "telemetry_schemas.parquet"), []byte("PAR1"), 0o644))
+	}).Return(manifest, nil)
+
+	router := chi.NewRouter()
+	router.Get("/api/v1/backups", HandleBackupList(backupDir))
+	router.Post("/api/v1/backups", HandleCreateBackup(backupRepo, backupDir))
+	router.Get("/api/v1/backups/{name}/{file}", HandleBackupFile(backupDir))
+	router.Post("/api/v1/backups/{name}/restore", HandleRestoreBackup(backupRepo, backupDir))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/backups", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/backups", nil))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created schema.Backup
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	require.True(t, schema.ValidBackupName(created.Name))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/backups", nil))
+	var backups []schema.Backup
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &backups))
+	require.Len(t, backups, 1)
+	require.Equal(t, created.Name, backups[0].Name)
+	require.Equal(t, 23, backups[0].Manifest.MigrationVersion)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/backups/"+created.Name+"/telemetry_schemas.parquet", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "PAR1", w.Body.String())
+
+	backupDirOf := filepath.Join(backupDir, created.Name)
+	backupRepo.On("Restore", mock.Anything, backupDirOf).Return(nil, fmt.Errorf("%w: table telemetry_schemas has rows", schema.ErrCatalogNotEmpty)).Once()
+	backupRepo.On("Restore", mock.Anything, backupDirOf).Return(nil, fmt.Errorf("%w: file telemetry_schemas.parquet does not match its checksum", schema.ErrInvalidBackup)).Once()
+	backupRepo.On("Restore", mock.Anything, backupDirOf).Return(manifest, nil).Once()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "catalog not empty", method: http.MethodPost, path: "/api/v1/backups/" + created.Name + "/restore", wantCode: http.StatusConflict},
+		{name: "altered backup", method: http.MethodPost, path: "/api/v1/backups/" + created.Name + "/restore", wantCode: http.StatusUnprocessableEntity},
+		{name: "restored", method: http.MethodPost, path: "/api/v1/backups/" + created.Name + "/restore", wantCode: http.StatusOK},
+		{name: "unknown backup", method: http.MethodPost, path: "/api/v1/backups/20200101T000000Z/restore", wantCode: http.StatusNotFound},
+		{name: "unknown file", method: http.MethodGet, path: "/api/v1/backups/" + created.Name + "/audit_log.parquet", wantCode: http.StatusNotFound},
+		{name: "file outside the backups", method: http.MethodGet, path: "/api/v1/backups/" + created.Name + "/..%2F..%2Fpasswd", wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			require.Equal(t, tt.wantCode, w.Code, w.Body.String())
+		})
+	}
+	backupRepo.AssertExpectations(t)
+}
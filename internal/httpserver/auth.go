@@ -0,0 +1,107 @@
+package httpserver
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/tallycat/tallycat/internal/auth"
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// authenticate resolves the identity of every request and records mutating requests, including
+// refused ones, in the audit log. Requests with invalid credentials are refused.
+func authenticate(authenticator *auth.Authenticator, auditRepo repository.AuditLogRepository) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, err := authenticator.Authenticate(r)
+
+			if isMutating(r.Method) && auditRepo != nil {
+				ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+				w = ww
+				defer func() {
+					recordAuditEntry(r, identity, ww.Status(), auditRepo)
+				}()
+			}
+
+			if err != nil {
+				slog.Debug("refused request with invalid credentials", "error", err, "path", r.URL.Path)
+				unauthorized(w)
+				return
+			}
+			if identity != nil {
+				r = r.WithContext(auth.WithIdentity(r.Context(), identity))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireRole only lets through callers granted the role. Anonymous callers are asked to
+// authenticate, authenticated callers without the role are forbidden.
+func requireRole(role auth.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity := auth.IdentityFromContext(r.Context())
+			switch {
+			case identity != nil && identity.Role.Allows(role):
+			case identity == nil || identity.Method == auth.MethodAnonymous:
+				unauthorized(w)
+				return
+			default:
+				http.Error(w, "forbidden, the "+string(role)+" role is required", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="tallycat"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func recordAuditEntry(r *http.Request, identity *auth.Identity, status int, auditRepo repository.AuditLogRepository) {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	entry := schema.AuditEntry{
+		Timestamp:  time.Now().UTC(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     status,
+		RemoteAddr: r.RemoteAddr,
+	}
+	// Requests naming no valid tenant are filed under the default tenant
+	if name := requestedTenant(r); tenant.Validate(name) == nil {
+		entry.Tenant = name
+	}
+	if identity != nil {
+		entry.Subject = identity.Subject
+		entry.Role = string(identity.Role)
+		entry.AuthMethod = string(identity.Method)
+	}
+	// The route context is shared with the router, so the pattern is known once the handler ran
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		entry.Route = rctx.RoutePattern()
+	}
+
+	// The entry is recorded even when the client went away
+	if err := auditRepo.RecordAuditEntry(context.WithoutCancel(r.Context()), entry); err != nil {
+		slog.Error("failed to record audit entry", "error", err, "method", entry.Method, "path", entry.Path)
+	}
+}
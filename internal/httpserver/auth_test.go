@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/auth"
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type recordingAuditRepo struct {
+	mu      sync.Mutex
+	entries []schema.AuditEntry
+}
+
+func (r *recordingAuditRepo) RecordAuditEntry(ctx context.Context, entry schema.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *recordingAuditRepo) ListAuditEntries(ctx context.Context, params query.ListQueryParams, filter query.AuditFilter) ([]schema.AuditEntry, int, error) {
+	return r.entries, len(r.entries), nil
+}
+
+// reviewRepo stands for a backend keeping reviews, for routes refused before the repository is used
+type reviewRepo struct {
+	repository.ReviewRepository
+}
+
+func newTestServer(t *testing.T, corsAllowedOrigins []string) (*Server, *recordingAuditRepo) {
+	authenticator, err := auth.NewAuthenticator(nil)
+	require.NoError(t, err)
+	authenticator.AddToken("ci", auth.RoleEditor, "editor-token")
+	authenticator.AddToken("ops", auth.RoleAdmin, "admin-token")
+
+	auditRepo := &recordingAuditRepo{}
+	srv := New(&Config{
+		Addr:               ":0",
+		CORSAllowedOrigins: corsAllowedOrigins,
+		Authenticator:      authenticator,
+		ReviewRepo:         reviewRepo{},
+		AuditRepo:          auditRepo,
+	})
+	return srv, auditRepo
+}
+
+func serve(srv *Server, method, path, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader("{}"))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestServer_Roles(t *testing.T) {
+	srv, auditRepo := newTestServer(t, nil)
+
+	require.Equal(t, http.StatusOK, serve(srv, "GET", "/healthz", "").Code)
+
+	// The audit log is restricted to admins
+	require.Equal(t, http.StatusOK, serve(srv, "GET", "/api/v1/audit-log", "admin-token").Code)
+	// Routes of repositories the storage backend does not implement are refused after the role check
+	require.Equal(t, http.StatusNotImplemented, serve(srv, "GET", "/api/v1/teams", "").Code)
+	require.Equal(t, http.StatusForbidden, serve(srv, "GET", "/api/v1/ingest-tokens", "editor-token").Code)
+	require.Equal(t, http.StatusNotImplemented, serve(srv, "GET", "/api/v1/ingest-tokens", "admin-token").Code)
+	require.Equal(t, http.StatusUnauthorized, serve(srv, "GET", "/api/v1/audit-log", "").Code)
+	require.Equal(t, http.StatusForbidden, serve(srv, "GET", "/api/v1/audit-log", "editor-token").Code)
+	require.Equal(t, http.StatusUnauthorized, serve(srv, "GET", "/api/v1/audit-log", "wrong").Code)
+
+	// The profiler is restricted to admins
+	require.Equal(t, http.StatusUnauthorized, serve(srv, "GET", "/debug/pprof/cmdline", "").Code)
+	require.Equal(t, http.StatusForbidden, serve(srv, "GET", "/debug/pprof/cmdline", "editor-token").Code)
+	require.Equal(t, http.StatusOK, serve(srv, "GET", "/debug/pprof/cmdline", "admin-token").Code)
+
+	// Mutations are audited, including refused ones
+	w := serve(srv, "POST", "/api/v1/reviews/1/decision", "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, `Bearer realm="tallycat"`, w.Header().Get("WWW-Authenticate"))
+	require.Equal(t, http.StatusBadRequest, serve(srv, "POST", "/api/v1/reviews/abc/decision", "editor-token").Code)
+	require.Equal(t, http.StatusForbidden, serve(srv, "DELETE", "/api/v1/stale-telemetries/s1", "editor-token").Code)
+
+	require.Len(t, auditRepo.entries, 3)
+	require.Equal(t, schema.AuditEntry{
+		Timestamp:  auditRepo.entries[0].Timestamp,
+		Subject:    "anonymous",
+		Role:       "viewer",
+		AuthMethod: "anonymous",
+		Method:     "POST",
+		Path:       "/api/v1/reviews/1/decision",
+		Route:      "/api/v1/reviews/{id}/decision",
+		Status:     http.StatusUnauthorized,
+		RemoteAddr: "192.0.2.1:1234",
+		Tenant:     "default",
+	}, auditRepo.entries[0])
+	require.Equal(t, "ci", auditRepo.entries[1].Subject)
+	require.Equal(t, http.StatusBadRequest, auditRepo.entries[1].Status)
+	require.Equal(t, "/api/v1/stale-telemetries/{schemaId}", auditRepo.entries[2].Route)
+	require.Equal(t, http.StatusForbidden, auditRepo.entries[2].Status)
+}
+
+func TestServer_Tenants(t *testing.T) {
+	hash := sha256.Sum256([]byte("payments-token"))
+	authenticator, err := auth.NewAuthenticator(&auth.Config{Tokens: []auth.TokenConfig{
+		{Name: "payments-ci", Role: auth.RoleEditor, SHA256: hex.EncodeToString(hash[:]), Tenants: []string{"payments"}},
+	}})
+	require.NoError(t, err)
+	authenticator.AddToken("ops", auth.RoleAdmin, "admin-token")
+	auditRepo := &recordingAuditRepo{}
+	srv := New(&Config{
+		Addr:          ":0",
+		Authenticator: authenticator,
+		ReviewRepo:    reviewRepo{},
+		AuditRepo:     auditRepo,
+	})
+
+	serveTenant := func(method, path, token, name string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader("{}"))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set(TenantHeader, name)
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	// The request reaches the handler, which refuses the invalid review ID
+	require.Equal(t, http.StatusBadRequest, serveTenant("POST", "/api/v1/reviews/abc/decision", "payments-token", "payments"))
+	require.Equal(t, http.StatusForbidden, serveTenant("POST", "/api/v1/reviews/abc/decision", "payments-token", "search"))
+	require.Equal(t, http.StatusForbidden, serveTenant("POST", "/api/v1/reviews/abc/decision", "payments-token", ""))
+	require.Equal(t, http.StatusBadRequest, serveTenant("POST", "/api/v1/reviews/abc/decision", "payments-token", "Not a tenant"))
+
+	// Only admins see every tenant at once, and only to read
+	require.Equal(t, http.StatusOK, serveTenant("GET", "/api/v1/audit-log", "admin-token", "*"))
+	require.Equal(t, http.StatusBadRequest, serveTenant("POST", "/api/v1/reviews/abc/decision", "admin-token", "*"))
+
+	require.Len(t, auditRepo.entries, 5)
+	require.Equal(t, "payments", auditRepo.entries[0].Tenant)
+	require.Equal(t, "search", auditRepo.entries[1].Tenant)
+	require.Equal(t, "default", auditRepo.entries[2].Tenant)
+	require.Empty(t, auditRepo.entries[3].Tenant)
+	require.Empty(t, auditRepo.entries[4].Tenant)
+}
+
+func TestServer_CORS(t *testing.T) {
+	preflight := func(srv *Server) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("OPTIONS", "/api/v1/telemetries", nil)
+		r.Header.Set("Origin", "https://catalog.example.com")
+		r.Header.Set("Access-Control-Request-Method", "GET")
+		w := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(w, r)
+		return w
+	}
+
+	srv, _ := newTestServer(t, nil)
+	require.Empty(t, preflight(srv).Header().Get("Access-Control-Allow-Origin"))
+
+	srv, _ = newTestServer(t, []string{"https://*.example.com"})
+	require.Equal(t, "https://catalog.example.com", preflight(srv).Header().Get("Access-Control-Allow-Origin"))
+}
@@ -2,6 +2,7 @@ package httpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io/fs"
 	"log/slog"
@@ -14,63 +15,93 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/tallycat/tallycat/internal/auth"
 	"github.com/tallycat/tallycat/internal/httpserver/api"
 	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/schema"
 	"github.com/tallycat/tallycat/ui"
 )
 
+// Config holds the listener, security settings and repositories of the HTTP server. Repositories
+// left nil are not implemented by the storage backend, and their routes answer 501.
+type Config struct {
+	Addr string
+	// TLSConfig serves the API over TLS when set
+	TLSConfig *tls.Config
+	// CORSAllowedOrigins are the origins browsers may call the API from besides the UI
+	CORSAllowedOrigins []string
+	Authenticator      *auth.Authenticator
+
+	SchemaRepo        repository.TelemetrySchemaRepository
+	HistoryRepo       repository.TelemetryHistoryRepository
+	EntityHistoryRepo repository.EntityHistoryRepository
+	DependencyRepo    repository.DependencyGraphRepository
+	ViolationRepo     repository.PolicyViolationRepository
+	BudgetRepo        repository.BudgetRepository
+	UsageRepo         repository.UsageRollupRepository
+	RetentionRepo     repository.RetentionRepository
+	OwnershipRepo     repository.OwnershipRepository
+	DocRepo           repository.DocumentationRepository
+	DeclaredRepo      repository.DeclaredSchemaRepository
+	ReviewRepo        repository.ReviewRepository
+	AuditRepo         repository.AuditLogRepository
+	IngestTokenRepo   repository.IngestTokenRepository
+	TenantRepo        repository.TenantRepository
+	BackupRepo        repository.BackupRepository
+
+	Budgets   *schema.BudgetSet
+	Retention *schema.RetentionPolicy
+	// BackupDir is the directory backups are written to and restored from
+	BackupDir string
+}
+
 type Server struct {
-	httpServer  *http.Server
-	schemaRepo  repository.TelemetrySchemaRepository
-	historyRepo repository.TelemetryHistoryRepository
+	httpServer *http.Server
 }
 
-func New(
-	addr string,
-	schemaRepo repository.TelemetrySchemaRepository,
-	historyRepo repository.TelemetryHistoryRepository,
-) *Server {
+func New(config *Config) *Server {
 	r := chi.NewRouter()
 
 	// Register middlewares
-	registerMiddlewares(r)
+	registerMiddlewares(r, config.CORSAllowedOrigins, config.Authenticator, config.AuditRepo)
 
 	// Register routes
 	registerHealthCheck(r)
 
 	srv := &Server{
 		httpServer: &http.Server{
-			Addr:    addr,
-			Handler: r,
+			Addr:      config.Addr,
+			Handler:   r,
+			TLSConfig: config.TLSConfig,
 		},
-		schemaRepo:  schemaRepo,
-		historyRepo: historyRepo,
 	}
 
 	// Register API routes
-	registerAPIRoutes(r, srv)
+	registerAPIRoutes(r, config)
 
 	return srv
 }
 
-func registerMiddlewares(r chi.Router) {
+func registerMiddlewares(r chi.Router, corsAllowedOrigins []string, authenticator *auth.Authenticator, auditRepo repository.AuditLogRepository) {
 	// Middlewares must be registered before routes or mounts
 	r.Use(middleware.RealIP)
 	r.Use(middleware.CleanPath)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(time.Second * 60))
-	r.Use(cors.Handler(cors.Options{
-		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
-		AllowedOrigins: []string{"https://*", "http://*"},
-		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: false,
-		MaxAge:           300, // Maximum value not ignored by any of major browsers
-	}))
+	// Without allowed origins, browsers only call the API from the UI it serves
+	if len(corsAllowedOrigins) > 0 {
+		r.Use(cors.Handler(cors.Options{
+			AllowedOrigins:   corsAllowedOrigins,
+			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", TenantHeader},
+			ExposedHeaders:   []string{"Link"},
+			AllowCredentials: false,
+			MaxAge:           300, // Maximum value not ignored by any of major browsers
+		}))
+	}
+	r.Use(authenticate(authenticator, auditRepo))
 	// Now mount profiler and add routes
-	r.Mount("/debug", middleware.Profiler())
+	r.With(requireRole(auth.RoleAdmin)).Mount("/debug", middleware.Profiler())
 }
 
 func registerHealthCheck(r chi.Router) {
@@ -80,41 +111,119 @@ func registerHealthCheck(r chi.Router) {
 	})
 }
 
-func registerAPIRoutes(r chi.Router, srv *Server) {
+func registerAPIRoutes(r chi.Router, config *Config) {
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(requireRole(auth.RoleViewer))
+		r.Use(scopeTenant)
+		editor := requireRole(auth.RoleEditor)
+		admin := requireRole(auth.RoleAdmin)
+
 		r.Route("/telemetries", func(r chi.Router) {
-			r.Get("/", api.HandleTelemetryList(srv.schemaRepo))
-			r.Get("/{key}", api.HandleGetTelemetry(srv.schemaRepo))
-			r.Get("/{key}/history", api.HandleTelemetryHistory(srv.historyRepo))
-			r.Get("/{key}/entities", api.HandleTelemetryEntityList(srv.schemaRepo))
-			r.Get("/{key}/scopes", api.HandleTelemetryScopeList(srv.schemaRepo))
+			r.Get("/", api.HandleTelemetryList(config.SchemaRepo))
+			r.Get("/{key}", api.HandleGetTelemetry(config.SchemaRepo))
+			r.Get("/{key}/history", api.HandleTelemetryHistory(config.HistoryRepo))
+			r.Get("/{key}/entities", api.HandleTelemetryEntityList(config.SchemaRepo))
+			r.Get("/{key}/scopes", api.HandleTelemetryScopeList(config.SchemaRepo))
+			r.With(supported(config.UsageRepo)).Get("/{key}/usage", api.HandleTelemetryUsage(config.UsageRepo))
+			r.With(supported(config.DocRepo)).Get("/{key}/documentation", api.HandleGetDocumentation(config.DocRepo))
+			r.With(editor, supported(config.DocRepo)).Patch("/{key}/documentation", api.HandleUpdateDocumentation(config.DocRepo))
+			r.With(editor, supported(config.DocRepo)).Patch("/{key}/attributes/{attribute}/documentation", api.HandleUpdateAttributeDocumentation(config.DocRepo))
 			r.Route("/{key}/schemas", func(r chi.Router) {
-				r.Get("/", api.HandleTelemetrySchemas(srv.schemaRepo))
-				r.Get("/{schemaId}/weaver-schema.zip", api.HandleWeaverSchemaExport(srv.schemaRepo))
-				r.Post("/{schemaId}", api.HandleTelemetrySchemaVersionAssignment(srv.schemaRepo, srv.historyRepo))
-				r.Get("/{schemaId}", api.HandleGetTelemetrySchema(srv.schemaRepo))
+				r.Get("/", api.HandleTelemetrySchemas(config.SchemaRepo))
+				r.Get("/diff", api.HandleTelemetrySchemaDiff(config.SchemaRepo, config.HistoryRepo))
+				r.Get("/{schemaId}/weaver-schema.zip", api.HandleWeaverSchemaExport(config.SchemaRepo))
+				r.With(editor).Post("/{schemaId}", api.HandleTelemetrySchemaVersionAssignment(config.SchemaRepo, config.HistoryRepo))
+				r.Get("/{schemaId}", api.HandleGetTelemetrySchema(config.SchemaRepo))
 			})
 		})
 		r.Route("/entities", func(r chi.Router) {
-			r.Get("/", api.HandleEntityList(srv.schemaRepo))
-			r.Get("/{entityType}/weaver-schema.zip", api.HandleEntityWeaverSchemaExport(srv.schemaRepo))
-			r.Get("/{entityType}/dashboards", api.HandleEntityDashboardExport(srv.schemaRepo))
-			r.Get("/{entityType}/{type}", api.HandleEntitySchemaExport(srv.schemaRepo))
+			r.Get("/", api.HandleEntityList(config.SchemaRepo))
+			r.With(supported(config.EntityHistoryRepo)).Get("/{entityId}/attribute-changes", api.HandleEntityAttributeChanges(config.EntityHistoryRepo))
+			r.With(supported(config.EntityHistoryRepo)).Get("/{entityId}/rollouts", api.HandleEntityRollouts(config.EntityHistoryRepo))
+			r.With(supported(config.UsageRepo)).Get("/{entityId}/usage", api.HandleEntityUsage(config.UsageRepo))
+			r.With(supported(config.DeclaredRepo)).Get("/{entityType}/coverage", api.HandleEntityCoverage(config.SchemaRepo, config.DeclaredRepo))
+			r.Get("/{entityType}/weaver-schema.zip", api.HandleEntityWeaverSchemaExport(config.SchemaRepo))
+			r.Get("/{entityType}/dashboards", api.HandleEntityDashboardExport(config.SchemaRepo))
+			r.Get("/{entityType}/{type}", api.HandleEntitySchemaExport(config.SchemaRepo))
+		})
+		r.Route("/producers", func(r chi.Router) {
+			r.Get("/", api.HandleProducerList(config.SchemaRepo))
+			r.Get("/{producerNameVersion}/weaver-schema.zip", api.HandleProducerWeaverSchemaExport(config.SchemaRepo))
+		})
+		r.Route("/declared-schemas", func(r chi.Router) {
+			r.With(supported(config.DeclaredRepo)).Get("/", api.HandleDeclaredSchemaList(config.DeclaredRepo))
+			r.With(editor, supported(config.DeclaredRepo)).Post("/", api.HandleImportDeclaredSchemas(config.DeclaredRepo))
+		})
+		r.With(supported(config.DependencyRepo)).Get("/dependencies", api.HandleDependencyGraph(config.DependencyRepo))
+		r.With(supported(config.ViolationRepo)).Get("/policy-violations", api.HandlePolicyViolationList(config.ViolationRepo))
+		r.Route("/reviews", func(r chi.Router) {
+			r.With(supported(config.ReviewRepo)).Get("/", api.HandleReviewList(config.ReviewRepo))
+			r.With(supported(config.ReviewRepo)).Get("/notifications", api.HandleReviewNotificationList(config.ReviewRepo))
+			r.With(editor, supported(config.ReviewRepo)).Post("/{id}/decision", api.HandleDecideReview(config.ReviewRepo))
+		})
+		r.Route("/budgets", func(r chi.Router) {
+			r.With(supported(config.BudgetRepo)).Get("/", api.HandleBudgetList(config.Budgets, config.BudgetRepo))
+			r.With(supported(config.BudgetRepo)).Get("/breaches", api.HandleBudgetBreachList(config.BudgetRepo))
+		})
+		r.Route("/stale-telemetries", func(r chi.Router) {
+			r.With(supported(config.RetentionRepo)).Get("/", api.HandleStaleTelemetryList(config.RetentionRepo))
+			r.With(admin, supported(config.RetentionRepo)).Delete("/{schemaId}", api.HandleRemoveStaleTelemetry(config.Retention, config.RetentionRepo))
+		})
+		r.Route("/teams", func(r chi.Router) {
+			r.With(supported(config.OwnershipRepo)).Get("/", api.HandleTeamList(config.OwnershipRepo))
+			r.With(supported(config.OwnershipRepo)).Get("/{team}/inventory", api.HandleTeamInventory(config.OwnershipRepo))
+		})
+		r.Route("/ownership/{kind}/{id}", func(r chi.Router) {
+			r.With(supported(config.OwnershipRepo)).Get("/", api.HandleGetOwnership(config.OwnershipRepo))
+			r.With(editor, supported(config.OwnershipRepo)).Put("/", api.HandleAssignOwners(config.OwnershipRepo))
+			r.With(editor, supported(config.OwnershipRepo)).Delete("/", api.HandleDeleteOwnerAssignment(config.OwnershipRepo))
 		})
 		r.Route("/scopes", func(r chi.Router) {
-			r.Get("/", api.HandleScopeList(srv.schemaRepo))
-			r.Get("/{scope}/weaver-schema.zip", api.HandleScopeWeaverSchemaExport(srv.schemaRepo))
-			r.Get("/{scope}/dashboards", api.HandleScopeDashboardExport(srv.schemaRepo))
-			r.Get("/{scope}/{type}", api.HandleScopeSchemaExport(srv.schemaRepo))
+			r.Get("/", api.HandleScopeList(config.SchemaRepo))
+			r.Get("/{scope}/weaver-schema.zip", api.HandleScopeWeaverSchemaExport(config.SchemaRepo))
+			r.Get("/{scope}/dashboards", api.HandleScopeDashboardExport(config.SchemaRepo))
+			r.With(supported(config.DeclaredRepo)).Get("/{scope}/coverage", api.HandleScopeCoverage(config.SchemaRepo, config.DeclaredRepo))
+			r.Get("/{scope}/{type}", api.HandleScopeSchemaExport(config.SchemaRepo))
+		})
+		r.With(admin, supported(config.AuditRepo)).Get("/audit-log", api.HandleAuditLogList(config.AuditRepo))
+		r.With(admin, supported(config.IngestTokenRepo)).Route("/ingest-tokens", func(r chi.Router) {
+			r.Get("/", api.HandleIngestTokenList(config.IngestTokenRepo))
+			r.Post("/", api.HandleCreateIngestToken(config.IngestTokenRepo))
+			r.Get("/stats", api.HandleIngestTokenStats(config.IngestTokenRepo))
+			r.Delete("/{name}", api.HandleRevokeIngestToken(config.IngestTokenRepo))
+		})
+		r.With(admin, supported(config.TenantRepo)).Get("/tenants", api.HandleTenantList(config.TenantRepo))
+		// Backups hold every tenant, so they are reserved to admins, who are granted them all
+		r.With(admin, supported(config.BackupRepo)).Route("/backups", func(r chi.Router) {
+			r.Get("/", api.HandleBackupList(config.BackupDir))
+			r.Post("/", api.HandleCreateBackup(config.BackupRepo, config.BackupDir))
+			r.Get("/{name}/{file}", api.HandleBackupFile(config.BackupDir))
+			r.Post("/{name}/restore", api.HandleRestoreBackup(config.BackupRepo, config.BackupDir))
 		})
 	})
 	r.Handle("/*", SPAHandler())
 }
 
+// supported answers 501 on the routes of a repository the storage backend does not implement
+func supported(repo any) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if repo != nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "not supported by the storage backend", http.StatusNotImplemented)
+		})
+	}
+}
+
 func (s *Server) Start() error {
 	hostname, _ := os.Hostname()
-	slog.Info("Starting HTTP server", "addr", s.httpServer.Addr, "hostname", hostname)
+	slog.Info("Starting HTTP server", "addr", s.httpServer.Addr, "hostname", hostname, "tls", s.httpServer.TLSConfig != nil)
+	if s.httpServer.TLSConfig != nil {
+		// The certificate is served by the TLS config
+		return s.httpServer.ListenAndServeTLS("", "")
+	}
 	return s.httpServer.ListenAndServe()
 }
 
@@ -0,0 +1,49 @@
+package httpserver
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/tallycat/tallycat/internal/auth"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// TenantHeader names the tenant a request reads or edits, the tenant query parameter being the
+// fallback for links opened in a browser
+const TenantHeader = "X-TallyCat-Tenant"
+
+// requestedTenant returns the tenant named by a request, the default tenant when none is
+func requestedTenant(r *http.Request) string {
+	name := strings.TrimSpace(r.Header.Get(TenantHeader))
+	if name == "" {
+		name = strings.TrimSpace(r.URL.Query().Get("tenant"))
+	}
+	if name == "" {
+		return tenant.Default
+	}
+	return name
+}
+
+// scopeTenant scopes every request to the tenant it names. Callers are forbidden the tenants they
+// were not granted, and the cross-tenant view "*" is read only and reserved to admins.
+func scopeTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := requestedTenant(r)
+		if name == tenant.All {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(w, "the cross-tenant view is read only", http.StatusBadRequest)
+				return
+			}
+		} else if err := tenant.Validate(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		identity := auth.IdentityFromContext(r.Context())
+		if identity == nil || !identity.CanAccessTenant(name) {
+			http.Error(w, "forbidden, no access to tenant "+name, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), name)))
+	})
+}
@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"crypto/tls"
 	"database/sql"
 	"log/slog"
 	"net"
@@ -11,6 +12,8 @@ import (
 	"github.com/tallycat/tallycat/internal/grpcserver"
 	"github.com/tallycat/tallycat/internal/repository/duckdb"
 	"github.com/tallycat/tallycat/internal/repository/duckdb/migrator"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tlsconfig"
 	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	collectorlogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
@@ -21,14 +24,20 @@ import (
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 // TestDB represents a test database instance
 type TestDB struct {
-	conn *sql.DB
-	pool *duckdb.ConnectionPool
-	repo *duckdb.TelemetrySchemaRepository
+	conn           *sql.DB
+	pool           *duckdb.ConnectionPool
+	repo           *duckdb.TelemetrySchemaRepository
+	dependencyRepo *duckdb.DependencyGraphRepository
+	violationRepo  *duckdb.PolicyViolationRepository
+	budgetRepo     *duckdb.BudgetRepository
+	reviewRepo     *duckdb.ReviewRepository
+	ingestRepo     *duckdb.IngestTokenRepository
 }
 
 // NewTestDB creates a new test database instance
@@ -43,12 +52,17 @@ func NewTestDB(t *testing.T) *TestDB {
 	require.NoError(t, err)
 
 	conn := pool.GetConnection()
-	repo := duckdb.NewTelemetrySchemaRepository(pool.(*duckdb.ConnectionPool))
+	repo := duckdb.NewTelemetrySchemaRepository(pool.(*duckdb.ConnectionPool), nil)
 
 	return &TestDB{
-		conn: conn,
-		pool: pool.(*duckdb.ConnectionPool),
-		repo: repo,
+		conn:           conn,
+		pool:           pool.(*duckdb.ConnectionPool),
+		repo:           repo,
+		dependencyRepo: duckdb.NewDependencyGraphRepository(pool.(*duckdb.ConnectionPool)),
+		violationRepo:  duckdb.NewPolicyViolationRepository(pool.(*duckdb.ConnectionPool)),
+		budgetRepo:     duckdb.NewBudgetRepository(pool.(*duckdb.ConnectionPool)),
+		reviewRepo:     duckdb.NewReviewRepository(pool.(*duckdb.ConnectionPool)),
+		ingestRepo:     duckdb.NewIngestTokenRepository(pool.(*duckdb.ConnectionPool)),
 	}
 }
 
@@ -62,6 +76,31 @@ func (db *TestDB) Repo() *duckdb.TelemetrySchemaRepository {
 	return db.repo
 }
 
+// DependencyRepo returns the dependency graph repository
+func (db *TestDB) DependencyRepo() *duckdb.DependencyGraphRepository {
+	return db.dependencyRepo
+}
+
+// ViolationRepo returns the policy violation repository
+func (db *TestDB) ViolationRepo() *duckdb.PolicyViolationRepository {
+	return db.violationRepo
+}
+
+// BudgetRepo returns the budget repository
+func (db *TestDB) BudgetRepo() *duckdb.BudgetRepository {
+	return db.budgetRepo
+}
+
+// ReviewRepo returns the review repository
+func (db *TestDB) ReviewRepo() *duckdb.ReviewRepository {
+	return db.reviewRepo
+}
+
+// IngestTokenRepo returns the ingest token repository
+func (db *TestDB) IngestTokenRepo() *duckdb.IngestTokenRepository {
+	return db.ingestRepo
+}
+
 // SetupTestDB sets up the test database with the required schema
 func (db *TestDB) SetupTestDB(t *testing.T) {
 	// Apply migrations instead of direct schema creation
@@ -87,11 +126,64 @@ type TestServer struct {
 
 // NewTestServer creates a new test gRPC server
 func NewTestServer(t *testing.T, db *TestDB) *TestServer {
-	server := grpc.NewServer()
-	logsServer := grpcserver.NewLogsServiceServer(db.repo)
-	metricsServer := grpcserver.NewMetricsServiceServer(db.repo)
-	profilesServer := grpcserver.NewProfilesServiceServer(db.repo)
-	tracesServer := grpcserver.NewTracesServiceServer(db.repo)
+	return NewTestServerWithOptions(t, db, ServerOptions{})
+}
+
+// NewTestServerWithPolicies creates a new test gRPC server enforcing the given policies
+func NewTestServerWithPolicies(t *testing.T, db *TestDB, policies *schema.PolicySet) *TestServer {
+	return NewTestServerWithOptions(t, db, ServerOptions{Policies: policies})
+}
+
+// ServerOptions configures the governance enforced by a test gRPC server
+type ServerOptions struct {
+	Policies *schema.PolicySet
+	Budgets  *schema.BudgetSet
+	// TLS serves the listener of the TLS config, identifying clients with its identity rules.
+	// ClientTLS is the config the clients connect with.
+	TLS       *tlsconfig.Config
+	ClientTLS *tls.Config
+	// IngestGate authenticates and rate limits the clients by their ingest token
+	IngestGate *grpcserver.IngestGate
+	// AllowTenantHeader lets clients bound to no tenant pick theirs with the tenant metadata
+	AllowTenantHeader bool
+	// EntityModel detects the entities of the resources, the default entity model when unset
+	EntityModel *schema.EntityModel
+}
+
+// NewTestServerWithOptions creates a new test gRPC server enforcing the given policies and budgets
+func NewTestServerWithOptions(t *testing.T, db *TestDB, options ServerOptions) *TestServer {
+	policyEnforcer := grpcserver.NewPolicyEnforcer(options.Policies, db.violationRepo, db.reviewRepo)
+	var budgetEnforcer *grpcserver.BudgetEnforcer
+	if options.Budgets != nil {
+		budgetEnforcer = grpcserver.NewBudgetEnforcer(options.Budgets, db.budgetRepo)
+	}
+
+	entityModel := options.EntityModel
+	if entityModel == nil {
+		entityModel = schema.DefaultEntityModel()
+	}
+
+	var opts []grpc.ServerOption
+	var interceptors []grpc.UnaryServerInterceptor
+	clientCreds := insecure.NewCredentials()
+	if options.TLS != nil {
+		reloader, err := tlsconfig.NewReloader(options.TLS.GRPC, options.TLS.ReloadInterval)
+		require.NoError(t, err)
+		opts = append(opts, grpc.Creds(credentials.NewTLS(reloader.ServerConfig())))
+		interceptors = append(interceptors, grpcserver.SenderInterceptor(options.TLS))
+		clientCreds = credentials.NewTLS(options.ClientTLS)
+	}
+	if options.IngestGate != nil {
+		interceptors = append(interceptors, options.IngestGate.Interceptor())
+	}
+	interceptors = append(interceptors, grpcserver.TenantInterceptor(options.AllowTenantHeader))
+	opts = append(opts, grpc.ChainUnaryInterceptor(interceptors...))
+
+	server := grpc.NewServer(opts...)
+	logsServer := grpcserver.NewLogsServiceServer(entityModel, db.repo, policyEnforcer, budgetEnforcer)
+	metricsServer := grpcserver.NewMetricsServiceServer(entityModel, db.repo, policyEnforcer, budgetEnforcer)
+	profilesServer := grpcserver.NewProfilesServiceServer(entityModel, db.repo, policyEnforcer)
+	tracesServer := grpcserver.NewTracesServiceServer(entityModel, db.repo, db.dependencyRepo, policyEnforcer)
 	collectorlogspb.RegisterLogsServiceServer(server, logsServer)
 	metricspb.RegisterMetricsServiceServer(server, metricsServer)
 	profilespb.RegisterProfilesServiceServer(server, profilesServer)
@@ -105,7 +197,7 @@ func NewTestServer(t *testing.T, db *TestDB) *TestServer {
 		require.NoError(t, err)
 	}()
 
-	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(clientCreds))
 	require.NoError(t, err)
 
 	logsClient := collectorlogspb.NewLogsServiceClient(conn)
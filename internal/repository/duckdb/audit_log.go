@@ -0,0 +1,132 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+type AuditLogRepository struct {
+	pool *ConnectionPool
+}
+
+func NewAuditLogRepository(pool *ConnectionPool) *AuditLogRepository {
+	return &AuditLogRepository{
+		pool: pool,
+	}
+}
+
+// RecordAuditEntry stores a request, under the default tenant unless the entry names the tenant it was made to
+func (r *AuditLogRepository) RecordAuditEntry(ctx context.Context, entry schema.AuditEntry) error {
+	tenantName := entry.Tenant
+	if tenantName == "" {
+		tenantName = tenant.Default
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.pool.GetConnection().ExecContext(ctx, `
+		INSERT INTO audit_log (timestamp, subject, role, auth_method, method, path, route, status, remote_addr, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		entry.Timestamp,
+		entry.Subject,
+		entry.Role,
+		entry.AuthMethod,
+		entry.Method,
+		entry.Path,
+		entry.Route,
+		entry.Status,
+		entry.RemoteAddr,
+		tenantName,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the most recent entries of the requests made to the tenant of the context first
+func (r *AuditLogRepository) ListAuditEntries(ctx context.Context, params query.ListQueryParams, filter query.AuditFilter) ([]schema.AuditEntry, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if filter.Subject != "" {
+		where += " AND subject = ?"
+		args = append(args, filter.Subject)
+	}
+	if filter.Method != "" {
+		where += " AND method = ?"
+		args = append(args, filter.Method)
+	}
+	if !filter.Window.Since.IsZero() {
+		where += " AND timestamp >= ?"
+		args = append(args, filter.Window.Since)
+	}
+	if !filter.Window.Until.IsZero() {
+		where += " AND timestamp <= ?"
+		args = append(args, filter.Window.Until)
+	}
+	if params.Search != "" {
+		where += " AND path LIKE ?"
+		args = append(args, "%"+params.Search+"%")
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.AuditEntry{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, timestamp, subject, role, auth_method, method, path, route, status, remote_addr, tenant
+		FROM audit_log
+		WHERE 1=1`+where+`
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []schema.AuditEntry{}
+	for rows.Next() {
+		var e schema.AuditEntry
+		if err := rows.Scan(
+			&e.ID,
+			&e.Timestamp,
+			&e.Subject,
+			&e.Role,
+			&e.AuthMethod,
+			&e.Method,
+			&e.Path,
+			&e.Route,
+			&e.Status,
+			&e.RemoteAddr,
+			&e.Tenant,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit entry rows: %w", err)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,57 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestAuditLog_RecordAndList(t *testing.T) {
+	repo := setupTestDB(t)
+	auditRepo := NewAuditLogRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, auditRepo.RecordAuditEntry(ctx, schema.AuditEntry{
+		Timestamp:  t0,
+		Subject:    "alice",
+		Role:       "editor",
+		AuthMethod: "jwt",
+		Method:     "PATCH",
+		Path:       "/api/v1/telemetries/checkout.requests/documentation",
+		Route:      "/api/v1/telemetries/{key}/documentation",
+		Status:     200,
+		RemoteAddr: "10.0.0.1",
+	}))
+	require.NoError(t, auditRepo.RecordAuditEntry(ctx, schema.AuditEntry{
+		Timestamp:  t0.Add(time.Hour),
+		Method:     "DELETE",
+		Path:       "/api/v1/stale-telemetries/s1",
+		Route:      "/api/v1/stale-telemetries/{schemaId}",
+		Status:     401,
+		RemoteAddr: "10.0.0.2",
+	}))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+
+	entries, total, err := auditRepo.ListAuditEntries(ctx, params, query.AuditFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Equal(t, "DELETE", entries[0].Method)
+	require.Equal(t, 401, entries[0].Status)
+	require.NotZero(t, entries[0].ID)
+
+	entries, total, err = auditRepo.ListAuditEntries(ctx, params, query.AuditFilter{Subject: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "/api/v1/telemetries/{key}/documentation", entries[0].Route)
+	require.True(t, t0.Equal(entries[0].Timestamp))
+
+	_, total, err = auditRepo.ListAuditEntries(ctx, params, query.AuditFilter{Window: query.TimeWindow{Until: t0.Add(time.Minute)}})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+}
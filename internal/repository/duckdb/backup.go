@@ -0,0 +1,303 @@
+package duckdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// backupTables are the tables a backup holds, every table the migrations create, in the order
+// they are restored: tables referenced by foreign keys come before the tables referencing them.
+// Only the bookkeeping of the migrator is left out, as backups restore into migrated databases.
+var backupTables = []string{
+	// Catalog filled by ingestion
+	"telemetry_schemas",
+	"schema_attributes",
+	"telemetry_entities",
+	"entity_attributes",
+	"schema_entities",
+	"telemetry_scopes",
+	"scope_attributes",
+	"schema_scopes",
+	"telemetry_producers",
+	"producer_instances",
+	"schema_producers",
+	"schema_senders",
+	"schema_versions",
+	"telemetry_history",
+	"entity_attribute_changes",
+	"entity_schema_changes",
+	"dependency_nodes",
+	"dependency_edges",
+	"metric_series",
+	"usage_rollups",
+	// Curated by hand
+	"telemetry_docs",
+	"attribute_docs",
+	"owner_assignments",
+	"resolved_owners",
+	"declared_schemas",
+	"telemetry_reviews",
+	"review_notifications",
+	"ingest_tokens",
+	"ingest_usage",
+	"audit_log",
+	// State of policies, budgets and retention
+	"policy_violations",
+	"budget_breaches",
+	"stale_schemas",
+	"telemetry_archive",
+}
+
+// backupSequences are the sequences generating the ids of backup tables, advanced past the
+// restored ids
+var backupSequences = map[string]string{
+	"telemetry_history":        "telemetry_history_id_seq",
+	"entity_attribute_changes": "entity_attribute_changes_id_seq",
+	"entity_schema_changes":    "entity_schema_changes_id_seq",
+	"policy_violations":        "policy_violations_id_seq",
+	"budget_breaches":          "budget_breaches_id_seq",
+	"telemetry_reviews":        "telemetry_reviews_id_seq",
+	"review_notifications":     "review_notifications_id_seq",
+	"audit_log":                "audit_log_id_seq",
+	"ingest_tokens":            "ingest_tokens_id_seq",
+}
+
+type BackupRepository struct {
+	pool *ConnectionPool
+}
+
+func NewBackupRepository(pool *ConnectionPool) *BackupRepository {
+	return &BackupRepository{
+		pool: pool,
+	}
+}
+
+// Backup copies the catalog tables to Parquet files in dir, created if missing, and writes the
+// manifest last. The tables are read in one transaction, so the backup is consistent while
+// telemetry keeps being ingested. Backups are not bounded in time as they grow with the catalog.
+func (r *BackupRepository) Backup(ctx context.Context, dir string) (_ *schema.BackupManifest, err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	if len(entries) > 0 {
+		return nil, fmt.Errorf("%w: %s is not empty", schema.ErrBackupExists, dir)
+	}
+
+	// A failed backup leaves no files behind, so it can be retried in the same directory
+	var written []string
+	defer func() {
+		if err != nil {
+			for _, path := range written {
+				os.Remove(path)
+			}
+		}
+	}()
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	migrationVersion, err := currentMigrationVersion(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &schema.BackupManifest{
+		FormatVersion:    schema.BackupFormatVersion,
+		MigrationVersion: migrationVersion,
+		CreatedAt:        time.Now().UTC(),
+	}
+	for _, table := range backupTables {
+		file := table + ".parquet"
+		path := filepath.Join(dir, file)
+		written = append(written, path)
+
+		var rows int64
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+table).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", table, err)
+		}
+		// COPY takes no parameters, so the path is quoted as a string literal
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`COPY %s TO %s (FORMAT PARQUET)`, table, quoteLiteral(path))); err != nil {
+			return nil, fmt.Errorf("failed to copy %s to parquet: %w", table, err)
+		}
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest.Tables = append(manifest.Tables, schema.BackupTable{
+			Name:   table,
+			File:   file,
+			Rows:   rows,
+			SHA256: checksum,
+		})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal backup manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, schema.BackupManifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// Restore loads a backup into the catalog, which must be empty and migrated to the version the
+// backup was taken at. The files are checked against the manifest before anything is loaded, and
+// the tables are loaded in one transaction, so a failed restore leaves the catalog empty.
+func (r *BackupRepository) Restore(ctx context.Context, dir string) (*schema.BackupManifest, error) {
+	manifest, err := readBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]schema.BackupTable, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		files[table.Name] = table
+	}
+	for _, table := range backupTables {
+		if _, ok := files[table]; !ok {
+			return nil, fmt.Errorf("%w: table %s is missing", schema.ErrInvalidBackup, table)
+		}
+	}
+	if len(files) != len(backupTables) {
+		return nil, fmt.Errorf("%w: unexpected tables, expected %s", schema.ErrInvalidBackup, strings.Join(backupTables, ", "))
+	}
+	for _, table := range manifest.Tables {
+		checksum, err := fileChecksum(filepath.Join(dir, table.File))
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: file %s of table %s is missing", schema.ErrInvalidBackup, table.File, table.Name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if checksum != table.SHA256 {
+			return nil, fmt.Errorf("%w: file %s of table %s does not match its checksum", schema.ErrInvalidBackup, table.File, table.Name)
+		}
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	migrationVersion, err := currentMigrationVersion(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	if migrationVersion != manifest.MigrationVersion {
+		return nil, fmt.Errorf("%w: backup taken at version %d, database at version %d",
+			schema.ErrBackupVersionMismatch, manifest.MigrationVersion, migrationVersion)
+	}
+
+	for _, table := range backupTables {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+table+`)`).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check rows of %s: %w", table, err)
+		}
+		if exists {
+			return nil, fmt.Errorf("%w: table %s has rows", schema.ErrCatalogNotEmpty, table)
+		}
+	}
+
+	for _, name := range backupTables {
+		table := files[name]
+		path := filepath.Join(dir, table.File)
+		// Both databases are at the same migration version, so the columns are in the same order
+		result, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM read_parquet(%s)`, name, quoteLiteral(path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count restored rows of %s: %w", name, err)
+		}
+		if rows != table.Rows {
+			return nil, fmt.Errorf("%w: restored %d rows of %s, the manifest lists %d", schema.ErrInvalidBackup, rows, name, table.Rows)
+		}
+
+		if sequence, ok := backupSequences[name]; ok {
+			// DuckDB cannot set a sequence, so it is advanced once per id up to the highest restored one
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(
+				`SELECT MAX(nextval('%s')) FROM range((SELECT COALESCE(MAX(id), 0) FROM %s))`, sequence, name)); err != nil {
+				return nil, fmt.Errorf("failed to advance sequence %s: %w", sequence, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return manifest, nil
+}
+
+// readBackupManifest reads and validates the manifest of a backup directory
+func readBackupManifest(dir string) (*schema.BackupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, schema.BackupManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: no %s in %s", schema.ErrInvalidBackup, schema.BackupManifestFile, dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	var manifest schema.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode manifest: %v", schema.ErrInvalidBackup, err)
+	}
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// currentMigrationVersion returns the last migration applied to the database
+func currentMigrationVersion(ctx context.Context, tx *sql.Tx) (int, error) {
+	var version int
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("failed to read migration version: no migration applied")
+	}
+	return version, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// quoteLiteral quotes a string as an SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
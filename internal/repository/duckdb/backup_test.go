@@ -0,0 +1,212 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/migrator"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// setupMigratedDB returns a database migrated as the server migrates it, with its migration
+// versions recorded
+func setupMigratedDB(t *testing.T) *ConnectionPool {
+	db, err := sql.Open("duckdb", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m := migrator.New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, m.Initialize())
+	migrations, err := m.LoadMigrations(EmbeddedMigrations)
+	require.NoError(t, err)
+	require.NoError(t, m.Migrate(migrations))
+
+	return &ConnectionPool{db: db, config: &Config{}, logger: slog.Default()}
+}
+
+func TestBackupRepository_BackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source := setupMigratedDB(t)
+	schemaRepo := NewTelemetrySchemaRepository(source, nil)
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, schemaRepo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+	require.NoError(t, schemaRepo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.0.0", []string{"http.method", "http.route"}, t0.Add(time.Hour)),
+	}))
+	require.NoError(t, schemaRepo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v2", Version: "1.1.0"}))
+	brief := "Requests handled by checkout"
+	_, err := NewDocumentationRepository(source).UpdateDocumentation(ctx, "checkout.requests", schema.DocumentationPatch{Brief: &brief})
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "backup")
+	manifest, err := NewBackupRepository(source).Backup(ctx, dir)
+	require.NoError(t, err)
+	require.Equal(t, schema.BackupFormatVersion, manifest.FormatVersion)
+	require.Len(t, manifest.Tables, len(backupTables))
+	require.NoError(t, manifest.Validate())
+
+	var migrationVersion int
+	require.NoError(t, source.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&migrationVersion))
+	require.Equal(t, migrationVersion, manifest.MigrationVersion)
+	rows := make(map[string]int64)
+	for _, table := range manifest.Tables {
+		rows[table.Name] = table.Rows
+		require.FileExists(t, filepath.Join(dir, table.File))
+	}
+	require.Equal(t, int64(2), rows["telemetry_schemas"])
+	require.Equal(t, int64(1), rows["schema_versions"])
+	require.Equal(t, int64(1), rows["telemetry_history"])
+
+	// Backups never overwrite another one
+	_, err = NewBackupRepository(source).Backup(ctx, dir)
+	require.ErrorIs(t, err, schema.ErrBackupExists)
+
+	target := setupMigratedDB(t)
+	restored, err := NewBackupRepository(target).Restore(ctx, dir)
+	require.NoError(t, err)
+	require.Equal(t, manifest.CreatedAt, restored.CreatedAt.UTC())
+
+	restoredRepo := NewTelemetrySchemaRepository(target, nil)
+	telemetry, err := restoredRepo.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, "v2", telemetry.SchemaID)
+	require.Len(t, telemetry.Attributes, 2)
+	require.Len(t, telemetry.Entities, 1)
+
+	telemetrySchema, err := restoredRepo.GetTelemetrySchema(ctx, "v2")
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", telemetrySchema.Version)
+
+	// Curated rows are restored with the catalog
+	documentation, err := NewDocumentationRepository(target).GetDocumentation(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, brief, documentation.Brief)
+
+	// History written after a restore gets ids past the restored ones
+	historyRepo := NewTelemetryHistoryRepository(target)
+	require.NoError(t, historyRepo.InsertTelemetryHistory(ctx, &schema.TelemetryHistory{
+		SchemaKey: "checkout.requests",
+		Version:   "1.1.0",
+		Timestamp: t0.Add(2 * time.Hour),
+		Summary:   "Assigned version 1.1.0",
+	}))
+	histories, total, err := historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.NotEqual(t, histories[0].Id, histories[1].Id)
+
+	// Backups restore into empty catalogs only
+	_, err = NewBackupRepository(target).Restore(ctx, dir)
+	require.ErrorIs(t, err, schema.ErrCatalogNotEmpty)
+}
+
+func TestBackupTables_CoverMigratedTables(t *testing.T) {
+	pool := setupMigratedDB(t)
+
+	rows, err := pool.db.Query(`
+		SELECT table_name
+		FROM duckdb_tables()
+		WHERE schema_name = 'main' AND table_name NOT IN ('schema_migrations', 'schema_migrations_lock')
+		ORDER BY table_name`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var migrated []string
+	for rows.Next() {
+		var table string
+		require.NoError(t, rows.Scan(&table))
+		migrated = append(migrated, table)
+	}
+	require.NoError(t, rows.Err())
+	require.ElementsMatch(t, migrated, backupTables, "every migrated table must be backed up")
+
+	rows, err = pool.db.Query(`SELECT sequence_name FROM duckdb_sequences() WHERE schema_name = 'main'`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var sequences []string
+	for rows.Next() {
+		var sequence string
+		require.NoError(t, rows.Scan(&sequence))
+		sequences = append(sequences, sequence)
+	}
+	require.NoError(t, rows.Err())
+	var backedUp []string
+	for _, sequence := range backupSequences {
+		backedUp = append(backedUp, sequence)
+	}
+	require.ElementsMatch(t, sequences, backedUp, "every sequence of a backed up table must be advanced on restore")
+}
+
+func TestBackupRepository_RestoreValidatesBackup(t *testing.T) {
+	ctx := context.Background()
+	source := setupMigratedDB(t)
+	require.NoError(t, NewTelemetrySchemaRepository(source, nil).RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)),
+	}))
+
+	backup := func(t *testing.T) (string, *schema.BackupManifest) {
+		dir := t.TempDir()
+		manifest, err := NewBackupRepository(source).Backup(ctx, dir)
+		require.NoError(t, err)
+		return dir, manifest
+	}
+	writeManifest := func(t *testing.T, dir string, manifest *schema.BackupManifest) {
+		data, err := json.Marshal(manifest)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, schema.BackupManifestFile), data, 0o644))
+	}
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := NewBackupRepository(setupMigratedDB(t)).Restore(ctx, t.TempDir())
+		require.ErrorIs(t, err, schema.ErrInvalidBackup)
+	})
+
+	t.Run("altered file", func(t *testing.T) {
+		dir, _ := backup(t)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "telemetry_schemas.parquet"), []byte("not parquet"), 0o644))
+		_, err := NewBackupRepository(setupMigratedDB(t)).Restore(ctx, dir)
+		require.ErrorIs(t, err, schema.ErrInvalidBackup)
+	})
+
+	t.Run("missing table", func(t *testing.T) {
+		dir, manifest := backup(t)
+		manifest.Tables = manifest.Tables[1:]
+		writeManifest(t, dir, manifest)
+		_, err := NewBackupRepository(setupMigratedDB(t)).Restore(ctx, dir)
+		require.ErrorIs(t, err, schema.ErrInvalidBackup)
+	})
+
+	t.Run("row count mismatch", func(t *testing.T) {
+		dir, manifest := backup(t)
+		manifest.Tables[0].Rows++
+		writeManifest(t, dir, manifest)
+		target := setupMigratedDB(t)
+		_, err := NewBackupRepository(target).Restore(ctx, dir)
+		require.ErrorIs(t, err, schema.ErrInvalidBackup)
+
+		// Nothing is left of a failed restore
+		var count int
+		require.NoError(t, target.db.QueryRow(`SELECT COUNT(*) FROM telemetry_schemas`).Scan(&count))
+		require.Zero(t, count)
+	})
+
+	t.Run("other migration version", func(t *testing.T) {
+		dir, manifest := backup(t)
+		manifest.MigrationVersion--
+		writeManifest(t, dir, manifest)
+		_, err := NewBackupRepository(setupMigratedDB(t)).Restore(ctx, dir)
+		require.ErrorIs(t, err, schema.ErrBackupVersionMismatch)
+	})
+}
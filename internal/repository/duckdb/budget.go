@@ -0,0 +1,270 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type BudgetRepository struct {
+	pool *ConnectionPool
+}
+
+func NewBudgetRepository(pool *ConnectionPool) *BudgetRepository {
+	return &BudgetRepository{
+		pool: pool,
+	}
+}
+
+// scopeEntityClause restricts an entity ID column to the entities of a budget scope.
+// A tenant scope is not restricted.
+func scopeEntityClause(column string, scope *schema.BudgetScope) (string, []any) {
+	selectors := scope.Selectors()
+	if len(selectors) == 0 {
+		return "", nil
+	}
+
+	var args []any
+	subqueries := make([]string, 0, len(selectors))
+	for _, selector := range selectors {
+		conditions := make([]string, 0, len(selector))
+		for name, value := range selector {
+			conditions = append(conditions, "(name = ? AND value = ?)")
+			args = append(args, name, value)
+		}
+		subqueries = append(subqueries, `
+			SELECT entity_id FROM entity_attributes
+			WHERE `+strings.Join(conditions, " OR ")+`
+			GROUP BY entity_id
+			HAVING COUNT(DISTINCT name) = ?`)
+		args = append(args, len(selector))
+	}
+
+	return " AND " + column + " IN (" + strings.Join(subqueries, " UNION ") + ")", args
+}
+
+// ListScopeSchemaIDs returns the schemas of a telemetry type emitted by the scope within the tenant of
+// the context, last seen at or after since. A zero since returns every schema.
+func (r *BudgetRepository) ListScopeSchemaIDs(ctx context.Context, scope schema.BudgetScope, telemetryType schema.TelemetryType, since time.Time) ([]string, error) {
+	var q string
+	args := []any{telemetryType}
+
+	if scope.Kind() == schema.BudgetScopeTenant {
+		q = `SELECT schema_id FROM telemetry_schemas WHERE signal_type = ?`
+		tenantFilter, tenantArgs := tenantClause(ctx, "")
+		q += tenantFilter
+		args = append(args, tenantArgs...)
+		if !since.IsZero() {
+			q += " AND COALESCE(updated_at, created_at) >= ?"
+			args = append(args, since)
+		}
+	} else {
+		q = `
+			SELECT DISTINCT se.schema_id
+			FROM schema_entities se
+			JOIN telemetry_schemas ts ON ts.schema_id = se.schema_id
+			WHERE ts.signal_type = ?`
+		tenantFilter, tenantArgs := tenantClause(ctx, "ts")
+		q += tenantFilter
+		args = append(args, tenantArgs...)
+		if !since.IsZero() {
+			q += " AND se.last_seen >= ?"
+			args = append(args, since)
+		}
+		clause, clauseArgs := scopeEntityClause("se.entity_id", &scope)
+		q += clause
+		args = append(args, clauseArgs...)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, q+" ORDER BY 1", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scope schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemaIDs := []string{}
+	for rows.Next() {
+		var schemaID string
+		if err := rows.Scan(&schemaID); err != nil {
+			return nil, fmt.Errorf("failed to scan scope schema row: %w", err)
+		}
+		schemaIDs = append(schemaIDs, schemaID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scope schema rows: %w", err)
+	}
+
+	return schemaIDs, nil
+}
+
+// CountActiveSeries estimates the metric series of the scope within the tenant of the context seen at or after since
+func (r *BudgetRepository) CountActiveSeries(ctx context.Context, scope schema.BudgetScope, since time.Time) (int, error) {
+	q := `SELECT COUNT(DISTINCT series_id) FROM metric_series WHERE last_seen >= ?`
+	args := []any{since}
+
+	if tenantFilter, tenantArgs := tenantClause(ctx, ""); tenantFilter != "" {
+		q += ` AND schema_id IN (SELECT schema_id FROM telemetry_schemas WHERE 1=1` + tenantFilter + `)`
+		args = append(args, tenantArgs...)
+	}
+
+	clause, clauseArgs := scopeEntityClause("entity_id", &scope)
+	q += clause
+	args = append(args, clauseArgs...)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	count := 0
+	if err := r.pool.GetConnection().QueryRowContext(ctx, q, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count active series: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetBudgetUsage computes the usage of the scope of a budget at now
+func (r *BudgetRepository) GetBudgetUsage(ctx context.Context, budget schema.Budget, activeSeriesWindow time.Duration, now time.Time) (*schema.BudgetUsage, error) {
+	usage := &schema.BudgetUsage{EvaluatedAt: now}
+
+	if budget.MaxMetricSchemas > 0 {
+		metricSchemas, err := r.ListScopeSchemaIDs(ctx, budget.Scope, schema.TelemetryTypeMetric, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		usage.MetricSchemas = len(metricSchemas)
+	}
+
+	if budget.MaxActiveSeries > 0 {
+		activeSeries, err := r.CountActiveSeries(ctx, budget.Scope, now.Add(-activeSeriesWindow))
+		if err != nil {
+			return nil, err
+		}
+		usage.ActiveSeries = activeSeries
+	}
+
+	if budget.MaxLogSchemasPerDay > 0 {
+		logSchemas, err := r.ListScopeSchemaIDs(ctx, budget.Scope, schema.TelemetryTypeLog, schema.StartOfDay(now))
+		if err != nil {
+			return nil, err
+		}
+		usage.LogSchemasToday = len(logSchemas)
+	}
+
+	return usage, nil
+}
+
+func (r *BudgetRepository) RecordBudgetBreaches(ctx context.Context, breaches []schema.BudgetBreach) error {
+	if len(breaches) == 0 {
+		return nil
+	}
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO budget_breaches (budget, scope, limit_name, value, max_value, observed_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare budget breach insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, b := range breaches {
+		_, err = stmt.ExecContext(ctx, b.Budget, b.Scope, b.Limit, b.Value, b.Max, b.ObservedAt, tenantName)
+		if err != nil {
+			return fmt.Errorf("failed to insert budget breach: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListBudgetBreaches returns the most recent breaches first
+func (r *BudgetRepository) ListBudgetBreaches(ctx context.Context, params query.ListQueryParams, filter query.BudgetBreachFilter) ([]schema.BudgetBreach, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if filter.Budget != "" {
+		where += " AND budget = ?"
+		args = append(args, filter.Budget)
+	}
+	if filter.Limit != "" {
+		where += " AND limit_name = ?"
+		args = append(args, filter.Limit)
+	}
+	if !filter.Window.Since.IsZero() {
+		where += " AND observed_at >= ?"
+		args = append(args, filter.Window.Since)
+	}
+	if !filter.Window.Until.IsZero() {
+		where += " AND observed_at <= ?"
+		args = append(args, filter.Window.Until)
+	}
+	if params.Search != "" {
+		where += " AND (budget LIKE ? OR scope LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM budget_breaches WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count budget breaches: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.BudgetBreach{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, budget, scope, limit_name, value, max_value, observed_at
+		FROM budget_breaches
+		WHERE 1=1`+where+`
+		ORDER BY observed_at DESC, id DESC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query budget breaches: %w", err)
+	}
+	defer rows.Close()
+
+	breaches := []schema.BudgetBreach{}
+	for rows.Next() {
+		var b schema.BudgetBreach
+		if err := rows.Scan(&b.ID, &b.Budget, &b.Scope, &b.Limit, &b.Value, &b.Max, &b.ObservedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan budget breach row: %w", err)
+		}
+		breaches = append(breaches, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating budget breach rows: %w", err)
+	}
+
+	return breaches, total, nil
+}
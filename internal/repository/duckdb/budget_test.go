@@ -0,0 +1,117 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func budgetTelemetry(schemaID string, telemetryType schema.TelemetryType, service string, series []string, seenAt time.Time) schema.Telemetry {
+	telemetry := schema.Telemetry{
+		SchemaID:      schemaID,
+		SchemaKey:     schemaID,
+		TelemetryType: telemetryType,
+		Protocol:      schema.TelemetryProtocolOTLP,
+		SeenCount:     1,
+		CreatedAt:     seenAt,
+		UpdatedAt:     seenAt,
+		Entities: map[string]*schema.Entity{
+			service: {
+				ID:         service,
+				Type:       "service",
+				Attributes: map[string]interface{}{"service.name": service},
+				FirstSeen:  seenAt,
+				LastSeen:   seenAt,
+			},
+		},
+	}
+	for _, id := range series {
+		telemetry.Series = append(telemetry.Series, schema.Series{ID: id, EntityIDs: []string{service}})
+	}
+	return telemetry
+}
+
+func TestBudgetRepository_Usage(t *testing.T) {
+	repo := setupTestDB(t)
+	budgetRepo := NewBudgetRepository(repo.pool)
+	ctx := context.Background()
+
+	now := time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-24 * time.Hour)
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		budgetTelemetry("m1", schema.TelemetryTypeMetric, "checkout", []string{"a", "b"}, now.Add(-10*time.Minute)),
+		budgetTelemetry("m2", schema.TelemetryTypeMetric, "checkout", []string{"c"}, now.Add(-2*time.Hour)),
+		budgetTelemetry("m3", schema.TelemetryTypeMetric, "cart", []string{"d"}, now),
+		budgetTelemetry("l1", schema.TelemetryTypeLog, "checkout", nil, now),
+		budgetTelemetry("l2", schema.TelemetryTypeLog, "checkout", nil, yesterday),
+	}))
+
+	budgets, err := schema.ParseBudgets([]byte(`
+budgets:
+  - {name: checkout, max_metric_schemas: 1, max_active_series: 10, max_log_schemas_per_day: 5, scope: {entity: service.name=checkout}}
+  - {name: team, max_metric_schemas: 10, scope: {group: team, entities: [service.name=checkout, service.name=cart]}}
+  - {name: tenant, max_metric_schemas: 10, max_active_series: 10, max_log_schemas_per_day: 10}
+`))
+	require.NoError(t, err)
+
+	usage, err := budgetRepo.GetBudgetUsage(ctx, budgets.Budgets[0], time.Hour, now)
+	require.NoError(t, err)
+	require.Equal(t, 2, usage.MetricSchemas)
+	require.Equal(t, 2, usage.ActiveSeries, "series c was last seen outside the window")
+	require.Equal(t, 1, usage.LogSchemasToday)
+	require.True(t, now.Equal(usage.EvaluatedAt))
+
+	usage, err = budgetRepo.GetBudgetUsage(ctx, budgets.Budgets[1], time.Hour, now)
+	require.NoError(t, err)
+	require.Equal(t, 3, usage.MetricSchemas)
+	require.Zero(t, usage.ActiveSeries, "limit not set")
+
+	usage, err = budgetRepo.GetBudgetUsage(ctx, budgets.Budgets[2], 3*time.Hour, now)
+	require.NoError(t, err)
+	require.Equal(t, 3, usage.MetricSchemas)
+	require.Equal(t, 4, usage.ActiveSeries)
+	require.Equal(t, 1, usage.LogSchemasToday)
+
+	schemaIDs, err := budgetRepo.ListScopeSchemaIDs(ctx, budgets.Budgets[0].Scope, schema.TelemetryTypeLog, time.Time{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"l1", "l2"}, schemaIDs)
+}
+
+func TestBudgetBreaches_RecordAndList(t *testing.T) {
+	repo := setupTestDB(t)
+	budgetRepo := NewBudgetRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, budgetRepo.RecordBudgetBreaches(ctx, nil))
+	require.NoError(t, budgetRepo.RecordBudgetBreaches(ctx, []schema.BudgetBreach{
+		{Budget: "checkout", Scope: "entity service.name=checkout", Limit: schema.BudgetLimitMetricSchemas, Value: 51, Max: 50, ObservedAt: t0},
+		{Budget: "tenant", Scope: "tenant", Limit: schema.BudgetLimitActiveSeries, Value: 1001, Max: 1000, ObservedAt: t0.Add(time.Hour)},
+	}))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+
+	breaches, total, err := budgetRepo.ListBudgetBreaches(ctx, params, query.BudgetBreachFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Equal(t, "tenant", breaches[0].Budget)
+	require.NotZero(t, breaches[0].ID)
+
+	breaches, total, err = budgetRepo.ListBudgetBreaches(ctx, params, query.BudgetBreachFilter{Limit: schema.BudgetLimitMetricSchemas})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, 51, breaches[0].Value)
+	require.Equal(t, 50, breaches[0].Max)
+	require.True(t, t0.Equal(breaches[0].ObservedAt))
+
+	_, total, err = budgetRepo.ListBudgetBreaches(ctx, params, query.BudgetBreachFilter{
+		Window: query.TimeWindow{Until: t0.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+}
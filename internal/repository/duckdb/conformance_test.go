@@ -0,0 +1,20 @@
+package duckdb
+
+import (
+	"testing"
+
+	"github.com/tallycat/tallycat/internal/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		repo := setupTestDB(t)
+		return repositorytest.Backend{
+			Schemas:       repo,
+			History:       NewTelemetryHistoryRepository(repo.pool),
+			Tenants:       NewTenantRepository(repo.pool),
+			Rekey:         repo,
+			ProducerRekey: repo,
+		}
+	})
+}
@@ -0,0 +1,160 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type DeclaredSchemaRepository struct {
+	pool *ConnectionPool
+}
+
+func NewDeclaredSchemaRepository(pool *ConnectionPool) *DeclaredSchemaRepository {
+	return &DeclaredSchemaRepository{
+		pool: pool,
+	}
+}
+
+// ImportDeclaredSchemas replaces the declared schemas of a registry within the tenant of the context
+func (r *DeclaredSchemaRepository) ImportDeclaredSchemas(ctx context.Context, registry string, declared []schema.DeclaredTelemetry) error {
+	if registry == "" {
+		return fmt.Errorf("registry name is required")
+	}
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM declared_schemas WHERE registry = ? AND tenant = ?`, registry, tenantName); err != nil {
+		return fmt.Errorf("failed to clear declared schemas of registry %s: %w", registry, err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO declared_schemas (
+			registry, group_id, schema_key, signal_type, brief, note, stability, unit, metric_type,
+			span_kind, attributes, entity_type, scope_name, imported_at, tenant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare declared schema insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, d := range declared {
+		attributes, err := json.Marshal(d.Attributes)
+		if err != nil {
+			return fmt.Errorf("failed to marshal attributes of %s: %w", d.SchemaKey, err)
+		}
+		_, err = stmt.ExecContext(ctx,
+			registry,
+			d.GroupID,
+			d.SchemaKey,
+			d.TelemetryType,
+			d.Brief,
+			d.Note,
+			d.Stability,
+			d.MetricUnit,
+			d.MetricType,
+			d.SpanKind,
+			string(attributes),
+			d.EntityType,
+			d.ScopeName,
+			d.ImportedAt,
+			tenantName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert declared schema %s: %w", d.SchemaKey, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListDeclaredSchemas returns the declared schemas matching the filter, sorted by schema key
+func (r *DeclaredSchemaRepository) ListDeclaredSchemas(ctx context.Context, filter query.DeclaredSchemaFilter) ([]schema.DeclaredTelemetry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, args := tenantClause(ctx, "")
+	where := "WHERE 1=1" + tenantFilter
+	if filter.Registry != "" {
+		where += " AND registry = ?"
+		args = append(args, filter.Registry)
+	}
+	if filter.EntityType != "" {
+		where += " AND entity_type = ?"
+		args = append(args, filter.EntityType)
+	}
+	if filter.ScopeName != "" {
+		where += " AND scope_name = ?"
+		args = append(args, filter.ScopeName)
+	}
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT registry, group_id, schema_key, signal_type, brief, note, stability, unit, metric_type,
+			span_kind, attributes, entity_type, scope_name, imported_at
+		FROM declared_schemas
+		`+where+`
+		ORDER BY schema_key, signal_type, registry
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query declared schemas: %w", err)
+	}
+	defer rows.Close()
+
+	declared := []schema.DeclaredTelemetry{}
+	for rows.Next() {
+		var d schema.DeclaredTelemetry
+		var brief, note, stability, unit, metricType, spanKind, entityType, scopeName sql.NullString
+		var attributes string
+		if err := rows.Scan(
+			&d.Registry,
+			&d.GroupID,
+			&d.SchemaKey,
+			&d.TelemetryType,
+			&brief,
+			&note,
+			&stability,
+			&unit,
+			&metricType,
+			&spanKind,
+			&attributes,
+			&entityType,
+			&scopeName,
+			&d.ImportedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan declared schema row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(attributes), &d.Attributes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal attributes of %s: %w", d.SchemaKey, err)
+		}
+		d.Brief = brief.String
+		d.Note = note.String
+		d.Stability = schema.Stability(stability.String)
+		d.MetricUnit = unit.String
+		d.MetricType = schema.MetricType(metricType.String)
+		d.SpanKind = schema.SpanKind(spanKind.String)
+		d.EntityType = entityType.String
+		d.ScopeName = scopeName.String
+		declared = append(declared, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating declared schema rows: %w", err)
+	}
+	return declared, nil
+}
@@ -0,0 +1,66 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestDeclaredSchemas_ImportReplacesRegistry(t *testing.T) {
+	repo := setupTestDB(t)
+	declaredRepo := NewDeclaredSchemaRepository(repo.pool)
+	ctx := context.Background()
+
+	importedAt := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	requests := schema.DeclaredTelemetry{
+		Registry:      "checkout",
+		GroupID:       "metric.otelhttp.checkout.requests",
+		SchemaKey:     "checkout.requests",
+		TelemetryType: schema.TelemetryTypeMetric,
+		Brief:         "Requests handled by checkout",
+		Stability:     schema.StabilityStable,
+		MetricUnit:    "1",
+		MetricType:    schema.MetricTypeSum,
+		Attributes: []schema.Attribute{
+			{Name: "http.method", Type: schema.AttributeTypeStr, RequirementLevel: schema.RequirementLevelRequired},
+		},
+		EntityType: "service",
+		ImportedAt: importedAt,
+	}
+	refunds := requests
+	refunds.SchemaKey = "checkout.refunds"
+	refunds.GroupID = "metric.otelhttp.checkout.refunds"
+	audit := schema.DeclaredTelemetry{
+		Registry:      "payments",
+		SchemaKey:     "payments.audit",
+		TelemetryType: schema.TelemetryTypeLog,
+		Attributes:    []schema.Attribute{},
+		ScopeName:     "payments-logger",
+		ImportedAt:    importedAt,
+	}
+
+	require.NoError(t, declaredRepo.ImportDeclaredSchemas(ctx, "checkout", []schema.DeclaredTelemetry{requests, refunds}))
+	require.NoError(t, declaredRepo.ImportDeclaredSchemas(ctx, "payments", []schema.DeclaredTelemetry{audit}))
+
+	declared, err := declaredRepo.ListDeclaredSchemas(ctx, query.DeclaredSchemaFilter{EntityType: "service"})
+	require.NoError(t, err)
+	require.Len(t, declared, 2)
+	require.Equal(t, requests, declared[1])
+
+	// Importing the registry again replaces its declarations
+	require.NoError(t, declaredRepo.ImportDeclaredSchemas(ctx, "checkout", []schema.DeclaredTelemetry{requests}))
+
+	declared, err = declaredRepo.ListDeclaredSchemas(ctx, query.DeclaredSchemaFilter{})
+	require.NoError(t, err)
+	require.Len(t, declared, 2)
+	require.Equal(t, "checkout.requests", declared[0].SchemaKey)
+	require.Equal(t, "payments.audit", declared[1].SchemaKey)
+
+	declared, err = declaredRepo.ListDeclaredSchemas(ctx, query.DeclaredSchemaFilter{ScopeName: "payments-logger"})
+	require.NoError(t, err)
+	require.Equal(t, []schema.DeclaredTelemetry{audit}, declared)
+}
@@ -0,0 +1,165 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type DependencyGraphRepository struct {
+	pool *ConnectionPool
+}
+
+func NewDependencyGraphRepository(pool *ConnectionPool) *DependencyGraphRepository {
+	return &DependencyGraphRepository{
+		pool: pool,
+	}
+}
+
+func (r *DependencyGraphRepository) RegisterDependencies(ctx context.Context, graph schema.DependencyGraph) error {
+	if len(graph.Nodes) == 0 && len(graph.Edges) == 0 {
+		return nil
+	}
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, node := range graph.Nodes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO dependency_nodes (tenant, node_id, name, entity_id, first_seen, last_seen)
+			VALUES (?, ?, ?, NULLIF(?, ''), ?, ?)
+			ON CONFLICT (tenant, node_id) DO UPDATE SET
+				entity_id = COALESCE(excluded.entity_id, dependency_nodes.entity_id),
+				last_seen = GREATEST(dependency_nodes.last_seen, excluded.last_seen)
+		`, tenantName, node.ID, node.Name, node.EntityID, node.FirstSeen, node.LastSeen)
+		if err != nil {
+			return fmt.Errorf("failed to insert dependency node: %w", err)
+		}
+	}
+
+	for _, edge := range graph.Edges {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO dependency_edges (tenant, caller_id, callee_id, protocol, schema_key, first_seen, last_seen, seen_count)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (tenant, caller_id, callee_id, protocol, schema_key) DO UPDATE SET
+				last_seen = GREATEST(dependency_edges.last_seen, excluded.last_seen),
+				seen_count = dependency_edges.seen_count + excluded.seen_count
+		`, tenantName, edge.CallerID, edge.CalleeID, edge.Protocol, edge.SchemaKey, edge.FirstSeen, edge.LastSeen, edge.SeenCount)
+		if err != nil {
+			return fmt.Errorf("failed to insert dependency edge: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetDependencyGraph returns the edges active within the window and the nodes they connect.
+// When node is set to a node ID only the edges calling or called by that node are returned.
+func (r *DependencyGraphRepository) GetDependencyGraph(ctx context.Context, window query.TimeWindow, node string) (*schema.DependencyGraph, error) {
+	db := r.pool.GetConnection()
+
+	windowClause, args := timeWindowClause("de", window)
+	tenantFilter, tenantArgs := tenantClause(ctx, "de")
+	windowClause += tenantFilter
+	args = append(args, tenantArgs...)
+	if node != "" {
+		windowClause += " AND (de.caller_id = ? OR de.callee_id = ?)"
+		args = append(args, node, node)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT de.caller_id, de.callee_id, de.protocol, de.schema_key, de.first_seen, de.last_seen, de.seen_count
+		FROM dependency_edges de
+		WHERE 1=1`+windowClause+`
+		ORDER BY de.caller_id, de.callee_id, de.protocol, de.schema_key`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependency edges: %w", err)
+	}
+	defer rows.Close()
+
+	graph := &schema.DependencyGraph{
+		Nodes: []schema.DependencyNode{},
+		Edges: []schema.DependencyEdge{},
+	}
+	nodeIDs := make(map[string]bool)
+	for rows.Next() {
+		var edge schema.DependencyEdge
+		if err := rows.Scan(
+			&edge.CallerID,
+			&edge.CalleeID,
+			&edge.Protocol,
+			&edge.SchemaKey,
+			&edge.FirstSeen,
+			&edge.LastSeen,
+			&edge.SeenCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency edge row: %w", err)
+		}
+		nodeIDs[edge.CallerID] = true
+		nodeIDs[edge.CalleeID] = true
+		graph.Edges = append(graph.Edges, edge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dependency edge rows: %w", err)
+	}
+
+	if len(nodeIDs) == 0 {
+		return graph, nil
+	}
+
+	nodeFilter, nodeArgs := tenantClause(ctx, "")
+	nodeRows, err := db.QueryContext(ctx, `
+		SELECT node_id, name, entity_id, first_seen, last_seen
+		FROM dependency_nodes
+		WHERE 1=1`+nodeFilter+`
+		ORDER BY node_id`, nodeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dependency nodes: %w", err)
+	}
+	defer nodeRows.Close()
+
+	for nodeRows.Next() {
+		var node schema.DependencyNode
+		var entityID sql.NullString
+		if err := nodeRows.Scan(
+			&node.ID,
+			&node.Name,
+			&entityID,
+			&node.FirstSeen,
+			&node.LastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency node row: %w", err)
+		}
+		if !nodeIDs[node.ID] {
+			continue
+		}
+		node.EntityID = entityID.String
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	if err := nodeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dependency node rows: %w", err)
+	}
+
+	return graph, nil
+}
@@ -0,0 +1,54 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestDependencyGraphRepository(t *testing.T) {
+	repo := NewDependencyGraphRepository(setupTestDB(t).pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	graphAt := func(seenAt time.Time, callee string) schema.DependencyGraph {
+		return schema.DependencyGraph{
+			Nodes: []schema.DependencyNode{
+				{ID: "checkout", Name: "checkout", EntityID: "e1", FirstSeen: seenAt, LastSeen: seenAt},
+				{ID: callee, Name: callee, FirstSeen: seenAt, LastSeen: seenAt},
+			},
+			Edges: []schema.DependencyEdge{
+				{CallerID: "checkout", CalleeID: callee, Protocol: "grpc", SchemaKey: "Charge", FirstSeen: seenAt, LastSeen: seenAt, SeenCount: 1},
+			},
+		}
+	}
+
+	require.NoError(t, repo.RegisterDependencies(ctx, graphAt(t0, "payments")))
+	require.NoError(t, repo.RegisterDependencies(ctx, graphAt(t0.Add(time.Hour), "payments")))
+	require.NoError(t, repo.RegisterDependencies(ctx, graphAt(t0.Add(2*time.Hour), "inventory")))
+
+	graph, err := repo.GetDependencyGraph(ctx, query.TimeWindow{}, "")
+	require.NoError(t, err)
+	require.Len(t, graph.Nodes, 3)
+	require.Len(t, graph.Edges, 2)
+	require.Equal(t, "e1", graph.Nodes[0].EntityID)
+	require.Equal(t, "payments", graph.Edges[1].CalleeID)
+	require.Equal(t, int64(2), graph.Edges[1].SeenCount)
+	require.True(t, t0.Equal(graph.Edges[1].FirstSeen))
+	require.True(t, t0.Add(time.Hour).Equal(graph.Edges[1].LastSeen))
+
+	graph, err = repo.GetDependencyGraph(ctx, query.TimeWindow{Since: t0.Add(90 * time.Minute)}, "")
+	require.NoError(t, err)
+	require.Len(t, graph.Edges, 1)
+	require.Equal(t, "inventory", graph.Edges[0].CalleeID)
+	require.Len(t, graph.Nodes, 2)
+
+	graph, err = repo.GetDependencyGraph(ctx, query.TimeWindow{}, "payments")
+	require.NoError(t, err)
+	require.Len(t, graph.Edges, 1)
+	require.Equal(t, "payments", graph.Edges[0].CalleeID)
+}
@@ -0,0 +1,204 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type DocumentationRepository struct {
+	pool *ConnectionPool
+}
+
+func NewDocumentationRepository(pool *ConnectionPool) *DocumentationRepository {
+	return &DocumentationRepository{
+		pool: pool,
+	}
+}
+
+// loadDocumentation returns the curated documentation of schema keys, by key. Keys without
+// documentation are absent.
+func loadDocumentation(ctx context.Context, q execQuerier, keys []string) (map[string]*schema.TelemetryDocumentation, error) {
+	docs := make(map[string]*schema.TelemetryDocumentation)
+	if len(keys) == 0 {
+		return docs, nil
+	}
+
+	args := make([]any, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	placeholders := "?" + strings.Repeat(", ?", len(keys)-1)
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	args = append(args, tenantArgs...)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT schema_key, brief, note, stability, tags, updated_at
+		FROM telemetry_docs
+		WHERE schema_key IN (`+placeholders+`)`+tenantFilter+`
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry documentation: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var brief, note, stability, tags sql.NullString
+		doc := &schema.TelemetryDocumentation{Tags: []string{}, Attributes: map[string]schema.AttributeDocumentation{}}
+		if err := rows.Scan(&doc.SchemaKey, &brief, &note, &stability, &tags, &doc.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry documentation row: %w", err)
+		}
+		doc.Brief = brief.String
+		doc.Note = note.String
+		doc.Stability = schema.Stability(stability.String)
+		if tags.Valid {
+			if err := json.Unmarshal([]byte(tags.String), &doc.Tags); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal tags of %s: %w", doc.SchemaKey, err)
+			}
+		}
+		docs[doc.SchemaKey] = doc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating telemetry documentation rows: %w", err)
+	}
+
+	attrRows, err := q.QueryContext(ctx, `
+		SELECT schema_key, attribute_name, brief, requirement_level, stability
+		FROM attribute_docs
+		WHERE schema_key IN (`+placeholders+`)`+tenantFilter+`
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attribute documentation: %w", err)
+	}
+	defer attrRows.Close()
+
+	for attrRows.Next() {
+		var key, name string
+		var brief, requirementLevel, stability sql.NullString
+		if err := attrRows.Scan(&key, &name, &brief, &requirementLevel, &stability); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute documentation row: %w", err)
+		}
+		doc, ok := docs[key]
+		if !ok {
+			continue
+		}
+		doc.Attributes[name] = schema.AttributeDocumentation{
+			Brief:            brief.String,
+			RequirementLevel: schema.RequirementLevel(requirementLevel.String),
+			Stability:        schema.Stability(stability.String),
+		}
+	}
+	return docs, attrRows.Err()
+}
+
+// documentTelemetries overrides the inferred documentation of telemetries with the curated one
+func documentTelemetries(ctx context.Context, q execQuerier, telemetries []schema.Telemetry) error {
+	return forEachTenant(ctx, telemetries, func(ctx context.Context, group []schema.Telemetry) error {
+		keys := make([]string, 0, len(group))
+		for _, t := range group {
+			keys = append(keys, t.SchemaKey)
+		}
+		docs, err := loadDocumentation(ctx, q, keys)
+		if err != nil {
+			return err
+		}
+		for i := range group {
+			docs[group[i].SchemaKey].Document(&group[i])
+		}
+		return nil
+	})
+}
+
+// GetDocumentation returns the curated documentation of a schema key, empty when none was written,
+// or schema.ErrAssetNotFound for unknown keys
+func (r *DocumentationRepository) GetDocumentation(ctx context.Context, schemaKey string) (*schema.TelemetryDocumentation, error) {
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkOwnedAsset(ctx, db, schema.OwnedKindTelemetry, schemaKey); err != nil {
+		return nil, err
+	}
+
+	docs, err := loadDocumentation(ctx, db, []string{schemaKey})
+	if err != nil {
+		return nil, err
+	}
+	if doc, ok := docs[schemaKey]; ok {
+		return doc, nil
+	}
+	return &schema.TelemetryDocumentation{
+		SchemaKey:  schemaKey,
+		Tags:       []string{},
+		Attributes: map[string]schema.AttributeDocumentation{},
+	}, nil
+}
+
+// UpdateDocumentation merges a patch into the curated documentation of a schema key and returns
+// the result. It returns schema.ErrAssetNotFound for unknown keys.
+func (r *DocumentationRepository) UpdateDocumentation(ctx context.Context, schemaKey string, patch schema.DocumentationPatch) (*schema.TelemetryDocumentation, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
+	doc, err := r.GetDocumentation(ctx, schemaKey)
+	if err != nil {
+		return nil, err
+	}
+	doc.Apply(patch)
+	doc.UpdatedAt = time.Now()
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := json.Marshal(doc.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM telemetry_docs WHERE schema_key = ? AND tenant = ?`, schemaKey, tenantName); err != nil {
+		return nil, fmt.Errorf("failed to clear telemetry documentation: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM attribute_docs WHERE schema_key = ? AND tenant = ?`, schemaKey, tenantName); err != nil {
+		return nil, fmt.Errorf("failed to clear attribute documentation: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO telemetry_docs (schema_key, brief, note, stability, tags, updated_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, schemaKey, doc.Brief, doc.Note, doc.Stability, string(tags), doc.UpdatedAt, tenantName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert telemetry documentation: %w", err)
+	}
+	for name, attr := range doc.Attributes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO attribute_docs (schema_key, attribute_name, brief, requirement_level, stability, tenant)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, schemaKey, name, attr.Brief, attr.RequirementLevel, attr.Stability, tenantName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert attribute documentation: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,73 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestDocumentation_SurvivesReingestion(t *testing.T) {
+	repo := setupTestDB(t)
+	docRepo := NewDocumentationRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	inferred := checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0)
+	inferred.Brief = "inferred description"
+	inferred.Note = "inferred description"
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{inferred}))
+
+	brief, stability, level := "Requests handled by checkout", "development", "required"
+	tags := []string{"checkout", " slo "}
+	doc, err := docRepo.UpdateDocumentation(ctx, "checkout.requests", schema.DocumentationPatch{
+		Brief:     &brief,
+		Stability: &stability,
+		Tags:      &tags,
+		Attributes: map[string]schema.AttributeDocumentationPatch{
+			"http.method": {RequirementLevel: &level},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"checkout", "slo"}, doc.Tags)
+
+	// Ingesting the telemetry again keeps the curated documentation
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{inferred}))
+
+	telemetry, err := repo.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, brief, telemetry.Brief)
+	require.Equal(t, "inferred description", telemetry.Note)
+	require.Equal(t, schema.StabilityDevelopment, telemetry.Stability)
+	require.Equal(t, []string{"checkout", "slo"}, telemetry.Tags)
+	require.Equal(t, schema.RequirementLevelRequired, telemetry.Attributes[0].RequirementLevel)
+
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, telemetries, 1)
+	require.Equal(t, brief, telemetries[0].Brief)
+
+	telemetrySchema, err := repo.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Equal(t, schema.RequirementLevelRequired, telemetrySchema.Attributes[0].RequirementLevel)
+
+	// A later patch only changes the fields it sets, and empty strings clear curated values
+	empty := ""
+	doc, err = docRepo.UpdateDocumentation(ctx, "checkout.requests", schema.DocumentationPatch{
+		Attributes: map[string]schema.AttributeDocumentationPatch{"http.method": {RequirementLevel: &empty}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, brief, doc.Brief)
+	require.Empty(t, doc.Attributes)
+
+	doc, err = docRepo.GetDocumentation(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, schema.StabilityDevelopment, doc.Stability)
+	require.Empty(t, doc.Attributes)
+
+	_, err = docRepo.GetDocumentation(ctx, "unknown")
+	require.ErrorIs(t, err, schema.ErrAssetNotFound)
+}
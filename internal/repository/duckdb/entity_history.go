@@ -0,0 +1,285 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type EntityHistoryRepository struct {
+	pool *ConnectionPool
+}
+
+func NewEntityHistoryRepository(pool *ConnectionPool) *EntityHistoryRepository {
+	return &EntityHistoryRepository{
+		pool: pool,
+	}
+}
+
+func (r *EntityHistoryRepository) ListEntityAttributeChanges(ctx context.Context, entityID string) ([]schema.EntityAttributeChange, error) {
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tenantFilter, args := tenantClause(ctx, "")
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, entity_id, name, old_value, new_value, changed_at
+		FROM entity_attribute_changes
+		WHERE entity_id = ?`+tenantFilter+`
+		ORDER BY changed_at ASC, id ASC`, append([]any{entityID}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entity attribute changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []schema.EntityAttributeChange{}
+	for rows.Next() {
+		var change schema.EntityAttributeChange
+		var oldValue sql.NullString
+		if err := rows.Scan(
+			&change.ID,
+			&change.EntityID,
+			&change.Name,
+			&oldValue,
+			&change.NewValue,
+			&change.ChangedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan entity attribute change row: %w", err)
+		}
+		if oldValue.Valid {
+			change.OldValue = &oldValue.String
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entity attribute change rows: %w", err)
+	}
+
+	return changes, nil
+}
+
+// ListEntitySchemaChanges lists the schema changes of an entity. With an attribute name only
+// the changes introducing the attribute are listed: the first schema of each schema key
+// carrying the attribute to appear on the entity.
+func (r *EntityHistoryRepository) ListEntitySchemaChanges(ctx context.Context, entityID string, attributeName string) ([]schema.EntitySchemaChange, error) {
+	db := r.pool.GetConnection()
+
+	where, tenantArgs := tenantClause(ctx, "esc")
+	args := append([]any{entityID}, tenantArgs...)
+	if attributeName != "" {
+		where += `
+			AND esc.change = ?
+			AND EXISTS (SELECT 1 FROM schema_attributes sa WHERE sa.schema_id = esc.schema_id AND sa.name = ?)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM entity_schema_changes prev
+				INNER JOIN schema_attributes psa ON psa.schema_id = prev.schema_id AND psa.name = ?
+				WHERE prev.entity_id = esc.entity_id AND prev.schema_key = esc.schema_key AND prev.change = ?
+					AND (prev.observed_at < esc.observed_at OR (prev.observed_at = esc.observed_at AND prev.id < esc.id))
+			)`
+		added := string(schema.SchemaChangeAdded)
+		args = append(args, added, attributeName, attributeName, added)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT esc.id, esc.entity_id, esc.schema_id, esc.schema_key, esc.change, esc.entity_attributes, esc.observed_at
+		FROM entity_schema_changes esc
+		WHERE esc.entity_id = ?`+where+`
+		ORDER BY esc.observed_at ASC, esc.id ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entity schema changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []schema.EntitySchemaChange{}
+	for rows.Next() {
+		var change schema.EntitySchemaChange
+		var entityAttributes sql.NullString
+		if err := rows.Scan(
+			&change.ID,
+			&change.EntityID,
+			&change.SchemaID,
+			&change.SchemaKey,
+			&change.Change,
+			&entityAttributes,
+			&change.ObservedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan entity schema change row: %w", err)
+		}
+		change.EntityAttributes = make(map[string]interface{})
+		if entityAttributes.Valid && entityAttributes.String != "" {
+			if err := json.Unmarshal([]byte(entityAttributes.String), &change.EntityAttributes); err != nil {
+				return nil, fmt.Errorf("failed to decode entity attributes for schema change %d: %w", change.ID, err)
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entity schema change rows: %w", err)
+	}
+
+	return changes, nil
+}
+
+// upsertEntityAttributes stores the latest attribute values of an entity and records
+// every value that changed for an entity that was already known
+func upsertEntityAttributes(ctx context.Context, tx *sql.Tx, entity *schema.Entity) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT name, value
+		FROM entity_attributes
+		WHERE entity_id = ?`, entity.ID)
+	if err != nil {
+		return fmt.Errorf("failed to query entity attributes: %w", err)
+	}
+
+	existing := make(map[string]string)
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan entity attribute: %w", err)
+		}
+		existing[name] = value.String
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating entity attribute rows: %w", err)
+	}
+
+	knownEntity := len(existing) > 0
+	for attrName, attrValue := range entity.Attributes {
+		value := fmt.Sprintf("%v", attrValue)
+
+		oldValue, found := existing[attrName]
+		switch {
+		case !found:
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO entity_attributes (entity_id, name, value, type)
+				VALUES (?, ?, ?, ?)
+			`, entity.ID, attrName, value, "string")
+			if err != nil {
+				return fmt.Errorf("failed to insert entity attribute: %w", err)
+			}
+		case oldValue != value:
+			_, err = tx.ExecContext(ctx, `
+				UPDATE entity_attributes SET value = ?
+				WHERE entity_id = ? AND name = ?
+			`, value, entity.ID, attrName)
+			if err != nil {
+				return fmt.Errorf("failed to update entity attribute: %w", err)
+			}
+		default:
+			continue
+		}
+
+		if !knownEntity {
+			continue
+		}
+
+		var old any
+		if found {
+			old = oldValue
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO entity_attribute_changes (entity_id, name, old_value, new_value, changed_at, tenant)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, entity.ID, attrName, old, value, entity.LastSeen, tenantName)
+		if err != nil {
+			return fmt.Errorf("failed to record entity attribute change: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// recordEntitySchemaChange records a schema appearing on an entity, unless it is already
+// active, and marks the other active schemas with the same key on that entity as removed
+func recordEntitySchemaChange(ctx context.Context, tx *sql.Tx, entity *schema.Entity, telemetry *schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	var lastChange sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT change
+		FROM entity_schema_changes
+		WHERE entity_id = ? AND schema_id = ?
+		ORDER BY id DESC
+		LIMIT 1`, entity.ID, telemetry.SchemaID).Scan(&lastChange)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to query entity schema changes: %w", err)
+	}
+	if lastChange.Valid && schema.SchemaChangeType(lastChange.String) == schema.SchemaChangeAdded {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT schema_id
+		FROM (
+			SELECT schema_id, change,
+				ROW_NUMBER() OVER (PARTITION BY schema_id ORDER BY id DESC) AS rn
+			FROM entity_schema_changes
+			WHERE entity_id = ? AND schema_key = ?
+		)
+		WHERE rn = 1 AND change = ? AND schema_id <> ?`,
+		entity.ID, telemetry.SchemaKey, string(schema.SchemaChangeAdded), telemetry.SchemaID)
+	if err != nil {
+		return fmt.Errorf("failed to query active entity schemas: %w", err)
+	}
+
+	var superseded []string
+	for rows.Next() {
+		var schemaID string
+		if err := rows.Scan(&schemaID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan active entity schema: %w", err)
+		}
+		superseded = append(superseded, schemaID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating active entity schema rows: %w", err)
+	}
+
+	entityAttributes, err := json.Marshal(entity.Attributes)
+	if err != nil {
+		return fmt.Errorf("failed to encode entity attributes: %w", err)
+	}
+
+	insert := `
+		INSERT INTO entity_schema_changes (entity_id, schema_id, schema_key, change, entity_attributes, observed_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	for _, schemaID := range superseded {
+		if _, err := tx.ExecContext(ctx, insert,
+			entity.ID, schemaID, telemetry.SchemaKey, string(schema.SchemaChangeRemoved), string(entityAttributes), entity.LastSeen, tenantName,
+		); err != nil {
+			return fmt.Errorf("failed to record removed entity schema: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, insert,
+		entity.ID, telemetry.SchemaID, telemetry.SchemaKey, string(schema.SchemaChangeAdded), string(entityAttributes), entity.LastSeen, tenantName,
+	); err != nil {
+		return fmt.Errorf("failed to record added entity schema: %w", err)
+	}
+
+	return nil
+}
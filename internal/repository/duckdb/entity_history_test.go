@@ -0,0 +1,190 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/schema"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func checkoutTelemetry(schemaID, version string, attributes []string, seenAt time.Time) schema.Telemetry {
+	attrs := make([]schema.Attribute, 0, len(attributes))
+	for _, name := range attributes {
+		attrs = append(attrs, schema.Attribute{
+			Name:   name,
+			Type:   schema.AttributeTypeStr,
+			Source: schema.AttributeSourceDataPoint,
+		})
+	}
+
+	return schema.Telemetry{
+		SchemaID:      schemaID,
+		SchemaKey:     "checkout.requests",
+		TelemetryType: schema.TelemetryTypeMetric,
+		MetricType:    schema.MetricTypeSum,
+		Protocol:      schema.TelemetryProtocolOTLP,
+		SeenCount:     1,
+		CreatedAt:     seenAt,
+		UpdatedAt:     seenAt,
+		Attributes:    attrs,
+		Entities: map[string]*schema.Entity{
+			"checkout": {
+				ID:   "checkout",
+				Type: "service",
+				Attributes: map[string]interface{}{
+					"service.name":    "checkout-service",
+					"service.version": version,
+				},
+				FirstSeen: seenAt,
+				LastSeen:  seenAt,
+			},
+		},
+	}
+}
+
+func TestEntityHistory_RecordsAttributeAndSchemaChanges(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewEntityHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"http.method", "payment.provider"}, t1),
+	}))
+	// Seeing the same release again must not record anything new
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"http.method", "payment.provider"}, t1.Add(time.Minute)),
+	}))
+
+	attributeChanges, err := historyRepo.ListEntityAttributeChanges(ctx, "checkout")
+	require.NoError(t, err)
+	require.Len(t, attributeChanges, 1)
+	require.Equal(t, "service.version", attributeChanges[0].Name)
+	require.NotNil(t, attributeChanges[0].OldValue)
+	require.Equal(t, "1.0.0", *attributeChanges[0].OldValue)
+	require.Equal(t, "1.1.0", attributeChanges[0].NewValue)
+	require.True(t, t1.Equal(attributeChanges[0].ChangedAt))
+
+	var attributeRows int
+	require.NoError(t, repo.pool.GetConnection().QueryRow(
+		`SELECT COUNT(*) FROM entity_attributes WHERE entity_id = 'checkout'`,
+	).Scan(&attributeRows))
+	require.Equal(t, 2, attributeRows)
+
+	schemaChanges, err := historyRepo.ListEntitySchemaChanges(ctx, "checkout", "")
+	require.NoError(t, err)
+	require.Len(t, schemaChanges, 3)
+	require.Equal(t, "v1", schemaChanges[0].SchemaID)
+	require.Equal(t, schema.SchemaChangeAdded, schemaChanges[0].Change)
+	require.Equal(t, "1.0.0", schemaChanges[0].EntityAttributes["service.version"])
+	require.Equal(t, "v1", schemaChanges[1].SchemaID)
+	require.Equal(t, schema.SchemaChangeRemoved, schemaChanges[1].Change)
+	require.Equal(t, "v2", schemaChanges[2].SchemaID)
+	require.Equal(t, schema.SchemaChangeAdded, schemaChanges[2].Change)
+	require.Equal(t, "1.1.0", schemaChanges[2].EntityAttributes["service.version"])
+
+	introduced, err := historyRepo.ListEntitySchemaChanges(ctx, "checkout", "payment.provider")
+	require.NoError(t, err)
+	require.Len(t, introduced, 1)
+	require.Equal(t, "v2", introduced[0].SchemaID)
+
+	rollouts := schema.CorrelateRollouts(attributeChanges, introduced)
+	require.Len(t, rollouts, 1)
+	require.Equal(t, "1.1.0", rollouts[0].AttributeChanges[0].NewValue)
+	require.Len(t, rollouts[0].SchemaChanges, 1)
+}
+
+func TestEntityHistory_SchemaReappears(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewEntityHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	// Roll out v2 and roll back to v1
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"payment.provider"}, t0.Add(time.Hour)),
+	}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0.Add(2*time.Hour)),
+	}))
+
+	schemaChanges, err := historyRepo.ListEntitySchemaChanges(ctx, "checkout", "")
+	require.NoError(t, err)
+	require.Len(t, schemaChanges, 5)
+	require.Equal(t, "v2", schemaChanges[3].SchemaID)
+	require.Equal(t, schema.SchemaChangeRemoved, schemaChanges[3].Change)
+	require.Equal(t, "v1", schemaChanges[4].SchemaID)
+	require.Equal(t, schema.SchemaChangeAdded, schemaChanges[4].Change)
+
+	attributeChanges, err := historyRepo.ListEntityAttributeChanges(ctx, "checkout")
+	require.NoError(t, err)
+	require.Len(t, attributeChanges, 2)
+
+	// Rolling v2 out again does not introduce payment.provider a second time
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"payment.provider"}, t0.Add(3*time.Hour)),
+	}))
+	introduced, err := historyRepo.ListEntitySchemaChanges(ctx, "checkout", "payment.provider")
+	require.NoError(t, err)
+	require.Len(t, introduced, 1)
+	require.Equal(t, "v2", introduced[0].SchemaID)
+	require.Equal(t, schema.SchemaChangeAdded, introduced[0].Change)
+	require.True(t, t0.Add(time.Hour).Equal(introduced[0].ObservedAt))
+}
+
+func TestEntityHistory_ReplicasRecordNoChanges(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewEntityHistoryRepository(repo.pool)
+	ctx := context.Background()
+	model := schema.DefaultEntityModel()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	replica := func(instanceID string, seenAt time.Time) schema.Telemetry {
+		resource := pcommon.NewMap()
+		resource.PutStr("service.name", "checkout-service")
+		resource.PutStr("service.version", "1.0.0")
+		resource.PutStr("service.instance.id", instanceID)
+
+		telemetry := checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, seenAt)
+		telemetry.Entities = map[string]*schema.Entity{}
+		for _, entity := range model.Detect(resource) {
+			entity.FirstSeen, entity.LastSeen = seenAt, seenAt
+			telemetry.Entities[entity.ID] = &entity
+		}
+		return telemetry
+	}
+
+	// Two replicas send interleaved batches, some in the same export
+	for i := 0; i < 3; i++ {
+		seenAt := t0.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+			replica("checkout-7d9f", seenAt),
+			replica("checkout-5c2a", seenAt),
+		}))
+		require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+			replica("checkout-5c2a", seenAt.Add(time.Second)),
+		}))
+	}
+
+	entityID := model.EntityID("service", map[string]interface{}{"service.name": "checkout-service"})
+	attributeChanges, err := historyRepo.ListEntityAttributeChanges(ctx, entityID)
+	require.NoError(t, err)
+	require.Empty(t, attributeChanges)
+
+	schemaChanges, err := historyRepo.ListEntitySchemaChanges(ctx, entityID, "")
+	require.NoError(t, err)
+	require.Len(t, schemaChanges, 1)
+	require.Equal(t, schema.SchemaChangeAdded, schemaChanges[0].Change)
+}
@@ -0,0 +1,217 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// RekeyEntities moves the entities stored under other IDs than the entity model gives them, as
+// entities stored by a release or an entity model identifying them differently, to their ID.
+// Entities moved to the same ID are merged with their links, history, series, usage and owners.
+// It returns the number of entities moved. Like backups, it is not bounded in time.
+func (r *TelemetrySchemaRepository) RekeyEntities(ctx context.Context, model *schema.EntityModel) (int, error) {
+	db := r.pool.GetConnection()
+
+	entities, tenants, err := loadStoredEntities(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	rekeys := schema.RekeyEntities(entities, func(e schema.Entity) string {
+		return tenant.ScopeID(tenants[e.ID], model.EntityID(e.Type, e.Attributes))
+	})
+	if len(rekeys) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved := 0
+	for _, rekey := range rekeys {
+		if err := storeRekeyedEntity(ctx, tx, rekey.Entity, tenants[rekey.PreviousIDs[0]]); err != nil {
+			return 0, err
+		}
+		for _, previousID := range rekey.PreviousIDs {
+			if err := moveEntityRows(ctx, tx, previousID, rekey.Entity.ID); err != nil {
+				return 0, err
+			}
+			moved++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// DuckDB rejects deleting a row referenced by a foreign key in the transaction deleting the rows
+	// referencing it, so the moved entities go in a second transaction. Entities left behind by a
+	// failure have no attributes or links anymore, and are removed by the retention policy.
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, rekey := range rekeys {
+		for _, previousID := range rekey.PreviousIDs {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM telemetry_entities WHERE entity_id = ?`, previousID); err != nil {
+				return 0, fmt.Errorf("failed to delete moved entity %s: %w", previousID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// loadStoredEntities returns every entity of every tenant with its attributes, and the tenant of
+// each entity by ID
+func loadStoredEntities(ctx context.Context, db *sql.DB) ([]schema.Entity, map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen, COALESCE(te.tenant, ?), ea.name, ea.value
+		FROM telemetry_entities te
+		LEFT JOIN entity_attributes ea ON ea.entity_id = te.entity_id
+		ORDER BY te.entity_id`, tenant.Default)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query entities: %w", err)
+	}
+	defer rows.Close()
+
+	var entities []schema.Entity
+	tenants := make(map[string]string)
+	for rows.Next() {
+		var entity schema.Entity
+		var tenantName string
+		var name, value sql.NullString
+		if err := rows.Scan(&entity.ID, &entity.Type, &entity.FirstSeen, &entity.LastSeen, &tenantName, &name, &value); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan entity row: %w", err)
+		}
+		if n := len(entities); n == 0 || entities[n-1].ID != entity.ID {
+			entity.Attributes = make(map[string]interface{})
+			entities = append(entities, entity)
+			tenants[entity.ID] = tenantName
+		}
+		if name.Valid {
+			entities[len(entities)-1].Attributes[name.String] = value.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating entity rows: %w", err)
+	}
+	return entities, tenants, nil
+}
+
+// storeRekeyedEntity stores a merged entity under its new ID, replacing any entity stored there
+func storeRekeyedEntity(ctx context.Context, tx *sql.Tx, entity schema.Entity, tenantName string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO telemetry_entities (entity_id, entity_type, first_seen, last_seen, tenant)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (entity_id) DO UPDATE SET
+			first_seen = excluded.first_seen,
+			last_seen = excluded.last_seen
+	`, entity.ID, entity.Type, entity.FirstSeen, entity.LastSeen, tenantName)
+	if err != nil {
+		return fmt.Errorf("failed to store entity %s: %w", entity.ID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM entity_attributes WHERE entity_id = ?`, entity.ID); err != nil {
+		return fmt.Errorf("failed to delete attributes of entity %s: %w", entity.ID, err)
+	}
+	for name, value := range entity.Attributes {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO entity_attributes (entity_id, name, value, type)
+			VALUES (?, ?, ?, ?)
+		`, entity.ID, name, fmt.Sprintf("%v", value), "string")
+		if err != nil {
+			return fmt.Errorf("failed to insert attribute of entity %s: %w", entity.ID, err)
+		}
+	}
+	return nil
+}
+
+// rekeyStatement moves the rows of a table referencing an entity or a producer
+type rekeyStatement struct {
+	name  string
+	query string
+	args  []any
+}
+
+// moveEntityRows moves the rows referencing an entity to its new ID, merging them with the rows
+// of the new ID
+func moveEntityRows(ctx context.Context, tx *sql.Tx, from, to string) error {
+	statements := []rekeyStatement{
+		{"schema links", `
+			INSERT INTO schema_entities (schema_id, entity_id, first_seen, last_seen, seen_count)
+			SELECT schema_id, ?, first_seen, last_seen, seen_count
+			FROM schema_entities
+			WHERE entity_id = ?
+			ON CONFLICT (schema_id, entity_id) DO UPDATE SET
+				first_seen = LEAST(COALESCE(schema_entities.first_seen, excluded.first_seen), excluded.first_seen),
+				last_seen = GREATEST(COALESCE(schema_entities.last_seen, excluded.last_seen), excluded.last_seen),
+				seen_count = COALESCE(schema_entities.seen_count, 0) + COALESCE(excluded.seen_count, 0)`,
+			[]any{to, from}},
+		{"schema links", `DELETE FROM schema_entities WHERE entity_id = ?`, []any{from}},
+		{"attributes", `DELETE FROM entity_attributes WHERE entity_id = ?`, []any{from}},
+		{"attribute changes", `UPDATE entity_attribute_changes SET entity_id = ? WHERE entity_id = ?`, []any{to, from}},
+		{"schema changes", `UPDATE entity_schema_changes SET entity_id = ? WHERE entity_id = ?`, []any{to, from}},
+		{"dependency nodes", `UPDATE dependency_nodes SET entity_id = ? WHERE entity_id = ?`, []any{to, from}},
+		{"metric series", `
+			INSERT INTO metric_series (schema_id, series_id, entity_id, first_seen, last_seen)
+			SELECT schema_id, series_id, ?, first_seen, last_seen
+			FROM metric_series
+			WHERE entity_id = ?
+			ON CONFLICT (schema_id, series_id, entity_id) DO UPDATE SET
+				first_seen = LEAST(metric_series.first_seen, excluded.first_seen),
+				last_seen = GREATEST(metric_series.last_seen, excluded.last_seen)`,
+			[]any{to, from}},
+		{"metric series", `DELETE FROM metric_series WHERE entity_id = ?`, []any{from}},
+		{"usage", `
+			INSERT INTO usage_rollups (
+				resolution, bucket, schema_id, schema_key, dimension, dimension_id,
+				seen_count, data_point_count, estimated_bytes, tenant
+			)
+			SELECT resolution, bucket, schema_id, schema_key, dimension, ?,
+				seen_count, data_point_count, estimated_bytes, tenant
+			FROM usage_rollups
+			WHERE dimension = ? AND dimension_id = ?
+			ON CONFLICT (resolution, bucket, schema_id, dimension, dimension_id) DO UPDATE SET
+				seen_count = usage_rollups.seen_count + excluded.seen_count,
+				data_point_count = usage_rollups.data_point_count + excluded.data_point_count,
+				estimated_bytes = usage_rollups.estimated_bytes + excluded.estimated_bytes`,
+			[]any{to, schema.UsageDimensionEntity, from}},
+		{"usage", `DELETE FROM usage_rollups WHERE dimension = ? AND dimension_id = ?`, []any{schema.UsageDimensionEntity, from}},
+	}
+	// Owners have no key, so the owners the new ID already has are not copied again
+	for _, table := range []string{"owner_assignments", "resolved_owners"} {
+		columns := "team, assigned_at, tenant"
+		if table == "resolved_owners" {
+			columns = "team, source, tenant"
+		}
+		statements = append(statements,
+			rekeyStatement{"owners", `
+				INSERT INTO ` + table + ` (kind, target_id, ` + columns + `)
+				SELECT kind, ?, ` + columns + `
+				FROM ` + table + ` o
+				WHERE kind = ? AND target_id = ? AND NOT EXISTS (
+					SELECT 1 FROM ` + table + ` n WHERE n.kind = o.kind AND n.target_id = ? AND n.team = o.team
+				)`, []any{to, schema.OwnedKindEntity, from, to}},
+			rekeyStatement{"owners", `DELETE FROM ` + table + ` WHERE kind = ? AND target_id = ?`, []any{schema.OwnedKindEntity, from}},
+		)
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+			return fmt.Errorf("failed to move %s of entity %s: %w", statement.name, from, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type IngestTokenRepository struct {
+	pool *ConnectionPool
+}
+
+func NewIngestTokenRepository(pool *ConnectionPool) *IngestTokenRepository {
+	return &IngestTokenRepository{
+		pool: pool,
+	}
+}
+
+// CreateIngestToken stores a token by its hash. Names stay taken once revoked, so the statistics of
+// a name always describe the same token.
+func (r *IngestTokenRepository) CreateIngestToken(ctx context.Context, token schema.IngestToken) (*schema.IngestToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM ingest_tokens WHERE name = ?)`, token.Name).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to check ingest token: %w", err)
+	}
+	if exists {
+		return nil, fmt.Errorf("%w: %s", schema.ErrIngestTokenExists, token.Name)
+	}
+
+	var createdBy sql.NullString
+	if token.CreatedBy != nil {
+		createdBy = sql.NullString{String: *token.CreatedBy, Valid: true}
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO ingest_tokens (name, token_hash, requests_per_second, schemas_per_second, created_at, created_by, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''))
+		RETURNING id
+	`, token.Name, token.Hash, token.RequestsPerSecond, token.SchemasPerSecond, token.CreatedAt, createdBy, token.Tenant).Scan(&token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert ingest token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &token, nil
+}
+
+// ListIngestTokens returns every token, revoked ones included, by name
+func (r *IngestTokenRepository) ListIngestTokens(ctx context.Context) ([]schema.IngestToken, error) {
+	return r.listIngestTokens(ctx, "")
+}
+
+func (r *IngestTokenRepository) ListActiveIngestTokens(ctx context.Context) ([]schema.IngestToken, error) {
+	return r.listIngestTokens(ctx, " WHERE revoked_at IS NULL")
+}
+
+func (r *IngestTokenRepository) listIngestTokens(ctx context.Context, where string) ([]schema.IngestToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT id, name, token_hash, requests_per_second, schemas_per_second, COALESCE(tenant, ''), created_at, created_by, revoked_at
+		FROM ingest_tokens`+where+`
+		ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingest tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []schema.IngestToken{}
+	for rows.Next() {
+		var token schema.IngestToken
+		var createdBy sql.NullString
+		var revokedAt sql.NullTime
+		if err := rows.Scan(
+			&token.ID,
+			&token.Name,
+			&token.Hash,
+			&token.RequestsPerSecond,
+			&token.SchemasPerSecond,
+			&token.Tenant,
+			&token.CreatedAt,
+			&createdBy,
+			&revokedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest token row: %w", err)
+		}
+		if createdBy.Valid {
+			token.CreatedBy = &createdBy.String
+		}
+		if revokedAt.Valid {
+			token.RevokedAt = &revokedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ingest token rows: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *IngestTokenRepository) RevokeIngestToken(ctx context.Context, name string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	res, err := r.pool.GetConnection().ExecContext(ctx, `
+		UPDATE ingest_tokens SET revoked_at = ? WHERE name = ? AND revoked_at IS NULL
+	`, at, name)
+	if err != nil {
+		return fmt.Errorf("failed to revoke ingest token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke ingest token: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", schema.ErrIngestTokenNotFound, name)
+	}
+	return nil
+}
+
+// RecordIngestUsage adds the exports of a token to its hourly counters
+func (r *IngestTokenRepository) RecordIngestUsage(ctx context.Context, usage schema.IngestUsage) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.pool.GetConnection().ExecContext(ctx, `
+		INSERT INTO ingest_usage (token_id, bucket, requests, schemas, throttled, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (token_id, bucket) DO UPDATE SET
+			requests = ingest_usage.requests + excluded.requests,
+			schemas = ingest_usage.schemas + excluded.schemas,
+			throttled = ingest_usage.throttled + excluded.throttled,
+			last_seen = GREATEST(ingest_usage.last_seen, excluded.last_seen)
+	`, usage.TokenID, usage.Timestamp.UTC().Truncate(time.Hour), usage.Requests, usage.Schemas, usage.Throttled, usage.Timestamp)
+	if err != nil {
+		return fmt.Errorf("failed to record ingest usage: %w", err)
+	}
+	return nil
+}
+
+// ListIngestTokenStats sums the exports of every token within the hours of a time window
+func (r *IngestTokenRepository) ListIngestTokenStats(ctx context.Context, window query.TimeWindow) ([]schema.IngestTokenStats, error) {
+	var args []any
+	on := ""
+	if !window.Since.IsZero() {
+		on += " AND u.bucket >= ?"
+		args = append(args, window.Since.UTC().Truncate(time.Hour))
+	}
+	if !window.Until.IsZero() {
+		on += " AND u.bucket <= ?"
+		args = append(args, window.Until)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT
+			t.name,
+			CAST(COALESCE(SUM(u.requests), 0) AS BIGINT),
+			CAST(COALESCE(SUM(u.schemas), 0) AS BIGINT),
+			CAST(COALESCE(SUM(u.throttled), 0) AS BIGINT),
+			MAX(u.last_seen)
+		FROM ingest_tokens t
+		LEFT JOIN ingest_usage u ON u.token_id = t.id`+on+`
+		GROUP BY t.name
+		ORDER BY t.name`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingest token stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []schema.IngestTokenStats{}
+	for rows.Next() {
+		var s schema.IngestTokenStats
+		var lastSeen sql.NullTime
+		if err := rows.Scan(&s.Name, &s.Requests, &s.Schemas, &s.Throttled, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest token stats row: %w", err)
+		}
+		if lastSeen.Valid {
+			s.LastSeen = &lastSeen.Time
+		}
+		stats = append(stats, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating ingest token stats rows: %w", err)
+	}
+	return stats, nil
+}
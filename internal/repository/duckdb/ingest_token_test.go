@@ -0,0 +1,73 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestIngestTokens_CreateRevokeAndStats(t *testing.T) {
+	repo := setupTestDB(t)
+	tokenRepo := NewIngestTokenRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	admin := "alice"
+	created, err := tokenRepo.CreateIngestToken(ctx, schema.IngestToken{
+		Name:              "gateway",
+		RequestsPerSecond: 10,
+		SchemasPerSecond:  100,
+		CreatedAt:         t0,
+		CreatedBy:         &admin,
+		Hash:              schema.HashIngestToken("tcit_gateway"),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, created.ID)
+
+	_, err = tokenRepo.CreateIngestToken(ctx, schema.IngestToken{Name: "gateway", CreatedAt: t0, Hash: schema.HashIngestToken("tcit_other")})
+	require.ErrorIs(t, err, schema.ErrIngestTokenExists)
+
+	_, err = tokenRepo.CreateIngestToken(ctx, schema.IngestToken{Name: "batch", CreatedAt: t0, Hash: schema.HashIngestToken("tcit_batch")})
+	require.NoError(t, err)
+
+	require.NoError(t, tokenRepo.RecordIngestUsage(ctx, schema.IngestUsage{TokenID: created.ID, Timestamp: t0, Requests: 1, Schemas: 3}))
+	require.NoError(t, tokenRepo.RecordIngestUsage(ctx, schema.IngestUsage{TokenID: created.ID, Timestamp: t0.Add(time.Minute), Requests: 1, Schemas: 2}))
+	require.NoError(t, tokenRepo.RecordIngestUsage(ctx, schema.IngestUsage{TokenID: created.ID, Timestamp: t0.Add(2 * time.Minute), Throttled: 1}))
+	require.NoError(t, tokenRepo.RecordIngestUsage(ctx, schema.IngestUsage{TokenID: created.ID, Timestamp: t0.Add(3 * time.Hour), Requests: 1, Schemas: 1}))
+
+	stats, err := tokenRepo.ListIngestTokenStats(ctx, query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	require.Equal(t, schema.IngestTokenStats{Name: "batch"}, stats[0])
+	require.Equal(t, "gateway", stats[1].Name)
+	require.Equal(t, int64(3), stats[1].Requests)
+	require.Equal(t, int64(6), stats[1].Schemas)
+	require.Equal(t, int64(1), stats[1].Throttled)
+	require.True(t, t0.Add(3*time.Hour).Equal(*stats[1].LastSeen))
+
+	stats, err = tokenRepo.ListIngestTokenStats(ctx, query.TimeWindow{Until: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), stats[1].Requests)
+	require.Equal(t, int64(5), stats[1].Schemas)
+
+	require.NoError(t, tokenRepo.RevokeIngestToken(ctx, "gateway", t0.Add(4*time.Hour)))
+	require.ErrorIs(t, tokenRepo.RevokeIngestToken(ctx, "gateway", t0.Add(5*time.Hour)), schema.ErrIngestTokenNotFound)
+	require.ErrorIs(t, tokenRepo.RevokeIngestToken(ctx, "unknown", t0), schema.ErrIngestTokenNotFound)
+
+	active, err := tokenRepo.ListActiveIngestTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	require.Equal(t, "batch", active[0].Name)
+
+	tokens, err := tokenRepo.ListIngestTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.Equal(t, "gateway", tokens[1].Name)
+	require.Equal(t, "alice", *tokens[1].CreatedBy)
+	require.NotNil(t, tokens[1].RevokedAt)
+	require.Equal(t, schema.HashIngestToken("tcit_gateway"), tokens[1].Hash)
+}
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_entity_schema_changes_schema_id;
+DROP INDEX IF EXISTS idx_entity_schema_changes_entity_id;
+DROP INDEX IF EXISTS idx_entity_attribute_changes_entity_id;
+DROP TABLE IF EXISTS entity_schema_changes;
+DROP SEQUENCE IF EXISTS entity_schema_changes_id_seq;
+DROP TABLE IF EXISTS entity_attribute_changes;
+DROP SEQUENCE IF EXISTS entity_attribute_changes_id_seq;
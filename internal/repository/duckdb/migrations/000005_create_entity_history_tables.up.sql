@@ -0,0 +1,34 @@
+-- Entity attributes were appended on every ingest; keep only the latest value per name
+DELETE FROM entity_attributes
+WHERE rowid NOT IN (
+    SELECT MAX(rowid) FROM entity_attributes GROUP BY entity_id, name
+);
+
+CREATE SEQUENCE IF NOT EXISTS entity_attribute_changes_id_seq START 1;
+
+-- Log of attribute value changes for a stable entity (e.g. a new service.version)
+CREATE TABLE IF NOT EXISTS entity_attribute_changes (
+    id INTEGER PRIMARY KEY DEFAULT nextval('entity_attribute_changes_id_seq'),
+    entity_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    old_value TEXT,
+    new_value TEXT,
+    changed_at TIMESTAMP NOT NULL
+);
+
+CREATE SEQUENCE IF NOT EXISTS entity_schema_changes_id_seq START 1;
+
+-- Schemas added to or removed from an entity, with the entity attributes at that time
+CREATE TABLE IF NOT EXISTS entity_schema_changes (
+    id INTEGER PRIMARY KEY DEFAULT nextval('entity_schema_changes_id_seq'),
+    entity_id TEXT NOT NULL,
+    schema_id TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    change TEXT NOT NULL,
+    entity_attributes TEXT,
+    observed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_entity_attribute_changes_entity_id ON entity_attribute_changes(entity_id);
+CREATE INDEX IF NOT EXISTS idx_entity_schema_changes_entity_id ON entity_schema_changes(entity_id);
+CREATE INDEX IF NOT EXISTS idx_entity_schema_changes_schema_id ON entity_schema_changes(schema_id);
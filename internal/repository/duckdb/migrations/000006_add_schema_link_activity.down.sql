@@ -0,0 +1,36 @@
+-- DuckDB cannot drop columns from a table with a primary key, so the link tables are rebuilt
+DROP INDEX IF EXISTS idx_schema_entities_last_seen;
+DROP INDEX IF EXISTS idx_schema_scopes_last_seen;
+DROP INDEX IF EXISTS idx_schema_entities_schema_id;
+DROP INDEX IF EXISTS idx_schema_entities_entity_id;
+DROP INDEX IF EXISTS idx_schema_scopes_schema_id;
+DROP INDEX IF EXISTS idx_schema_scopes_scope_id;
+
+CREATE TABLE schema_entities_backup AS SELECT schema_id, entity_id FROM schema_entities;
+DROP TABLE schema_entities;
+CREATE TABLE schema_entities (
+    schema_id TEXT,
+    entity_id TEXT,
+    FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
+    FOREIGN KEY (entity_id) REFERENCES telemetry_entities(entity_id),
+    PRIMARY KEY (schema_id, entity_id)
+);
+INSERT INTO schema_entities SELECT schema_id, entity_id FROM schema_entities_backup;
+DROP TABLE schema_entities_backup;
+
+CREATE TABLE schema_scopes_backup AS SELECT schema_id, scope_id FROM schema_scopes;
+DROP TABLE schema_scopes;
+CREATE TABLE schema_scopes (
+    schema_id TEXT,
+    scope_id TEXT,
+    FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
+    FOREIGN KEY (scope_id) REFERENCES telemetry_scopes(scope_id),
+    PRIMARY KEY (schema_id, scope_id)
+);
+INSERT INTO schema_scopes SELECT schema_id, scope_id FROM schema_scopes_backup;
+DROP TABLE schema_scopes_backup;
+
+CREATE INDEX IF NOT EXISTS idx_schema_entities_schema_id ON schema_entities(schema_id);
+CREATE INDEX IF NOT EXISTS idx_schema_entities_entity_id ON schema_entities(entity_id);
+CREATE INDEX IF NOT EXISTS idx_schema_scopes_schema_id ON schema_scopes(schema_id);
+CREATE INDEX IF NOT EXISTS idx_schema_scopes_scope_id ON schema_scopes(scope_id);
@@ -0,0 +1,26 @@
+-- Track when an entity or scope was first and last seen emitting a schema
+ALTER TABLE schema_entities ADD COLUMN first_seen TIMESTAMP;
+ALTER TABLE schema_entities ADD COLUMN last_seen TIMESTAMP;
+ALTER TABLE schema_entities ADD COLUMN seen_count BIGINT DEFAULT 0;
+
+ALTER TABLE schema_scopes ADD COLUMN first_seen TIMESTAMP;
+ALTER TABLE schema_scopes ADD COLUMN last_seen TIMESTAMP;
+ALTER TABLE schema_scopes ADD COLUMN seen_count BIGINT DEFAULT 0;
+
+-- Existing links inherit the activity of their schema
+UPDATE schema_entities SET
+    first_seen = ts.created_at,
+    last_seen = ts.updated_at,
+    seen_count = ts.seen_count
+FROM telemetry_schemas ts
+WHERE schema_entities.schema_id = ts.schema_id;
+
+UPDATE schema_scopes SET
+    first_seen = ts.created_at,
+    last_seen = ts.updated_at,
+    seen_count = ts.seen_count
+FROM telemetry_schemas ts
+WHERE schema_scopes.schema_id = ts.schema_id;
+
+CREATE INDEX IF NOT EXISTS idx_schema_entities_last_seen ON schema_entities(last_seen DESC);
+CREATE INDEX IF NOT EXISTS idx_schema_scopes_last_seen ON schema_scopes(last_seen DESC);
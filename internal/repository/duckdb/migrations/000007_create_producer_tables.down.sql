@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_schema_producers_producer_id;
+DROP INDEX IF EXISTS idx_schema_producers_schema_id;
+DROP INDEX IF EXISTS idx_telemetry_producers_name_version;
+
+DROP TABLE IF EXISTS schema_producers;
+DROP TABLE IF EXISTS telemetry_producers;
@@ -0,0 +1,26 @@
+-- Create telemetry_producers table
+CREATE TABLE IF NOT EXISTS telemetry_producers (
+    producer_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    namespace TEXT,
+    version TEXT,
+    instance_id TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL
+);
+
+-- Create schema_producers table (many-to-many relationship)
+CREATE TABLE IF NOT EXISTS schema_producers (
+    schema_id TEXT,
+    producer_id TEXT,
+    first_seen TIMESTAMP,
+    last_seen TIMESTAMP,
+    seen_count BIGINT DEFAULT 0,
+    FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
+    FOREIGN KEY (producer_id) REFERENCES telemetry_producers(producer_id),
+    PRIMARY KEY (schema_id, producer_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_producers_name_version ON telemetry_producers(name, version);
+CREATE INDEX IF NOT EXISTS idx_schema_producers_schema_id ON schema_producers(schema_id);
+CREATE INDEX IF NOT EXISTS idx_schema_producers_producer_id ON schema_producers(producer_id);
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_telemetry_scopes_last_seen ON telemetry_scopes(last_seen DESC);
@@ -0,0 +1,3 @@
+-- DuckDB rewrites updates of indexed columns as delete + insert, which violates the
+-- schema_scopes foreign key whenever a known scope is seen again
+DROP INDEX IF EXISTS idx_telemetry_scopes_last_seen;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_dependency_edges_callee_id;
+
+DROP TABLE IF EXISTS dependency_edges;
+DROP TABLE IF EXISTS dependency_nodes;
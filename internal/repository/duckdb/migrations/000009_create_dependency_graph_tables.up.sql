@@ -0,0 +1,22 @@
+-- Services and remote peers observed in traces
+CREATE TABLE IF NOT EXISTS dependency_nodes (
+    node_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    entity_id TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL
+);
+
+-- Calls between nodes, one row per protocol and calling span schema key
+CREATE TABLE IF NOT EXISTS dependency_edges (
+    caller_id TEXT NOT NULL,
+    callee_id TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    seen_count BIGINT DEFAULT 0,
+    PRIMARY KEY (caller_id, callee_id, protocol, schema_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dependency_edges_callee_id ON dependency_edges(callee_id);
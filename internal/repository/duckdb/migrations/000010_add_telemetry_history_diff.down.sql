@@ -0,0 +1,19 @@
+-- DuckDB cannot drop columns from a table with a primary key, so the history table is rebuilt
+CREATE TABLE telemetry_history_backup AS
+    SELECT id, schema_key, version, timestamp, author, summary, status, snapshot, created_at, updated_at
+    FROM telemetry_history;
+DROP TABLE telemetry_history;
+CREATE TABLE telemetry_history (
+    id INTEGER PRIMARY KEY DEFAULT nextval('telemetry_history_id_seq'),
+    schema_key TEXT NOT NULL,
+    version TEXT NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    author TEXT,
+    summary TEXT,
+    status TEXT,
+    snapshot BLOB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP,
+);
+INSERT INTO telemetry_history SELECT * FROM telemetry_history_backup;
+DROP TABLE telemetry_history_backup;
@@ -0,0 +1,2 @@
+ALTER TABLE telemetry_history ADD COLUMN schema_id TEXT;
+ALTER TABLE telemetry_history ADD COLUMN diff TEXT;
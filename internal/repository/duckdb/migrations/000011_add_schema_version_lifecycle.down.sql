@@ -0,0 +1,17 @@
+-- DuckDB cannot drop columns from a table with a primary key, so the version table is rebuilt
+CREATE TABLE schema_versions_backup AS
+    SELECT schema_id, version, assigned_by, reason, created_at, updated_at
+    FROM schema_versions;
+DROP TABLE schema_versions;
+CREATE TABLE schema_versions (
+    schema_id     TEXT,
+    version       TEXT,
+    assigned_by   TEXT,
+    reason        TEXT,
+    created_at    TIMESTAMP,
+    updated_at    TIMESTAMP,
+    FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
+    PRIMARY KEY (schema_id)
+);
+INSERT INTO schema_versions SELECT * FROM schema_versions_backup;
+DROP TABLE schema_versions_backup;
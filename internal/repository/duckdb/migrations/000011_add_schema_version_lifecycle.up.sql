@@ -0,0 +1,6 @@
+ALTER TABLE schema_versions ADD COLUMN status TEXT;
+ALTER TABLE schema_versions ADD COLUMN deprecated_at TIMESTAMP;
+ALTER TABLE schema_versions ADD COLUMN replaced_by TEXT;
+
+-- Versions assigned before the lifecycle existed are in use
+UPDATE schema_versions SET status = 'active';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_policy_violations_schema_key;
+DROP INDEX IF EXISTS idx_policy_violations_observed_at;
+DROP TABLE IF EXISTS policy_violations;
+DROP SEQUENCE IF EXISTS policy_violations_id_seq;
@@ -0,0 +1,16 @@
+CREATE SEQUENCE IF NOT EXISTS policy_violations_id_seq START 1;
+
+CREATE TABLE IF NOT EXISTS policy_violations (
+    id INTEGER PRIMARY KEY DEFAULT nextval('policy_violations_id_seq'),
+    policy TEXT NOT NULL,
+    mode TEXT NOT NULL,
+    rule TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    schema_id TEXT NOT NULL,
+    signal_type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    observed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_policy_violations_observed_at ON policy_violations(observed_at);
+CREATE INDEX IF NOT EXISTS idx_policy_violations_schema_key ON policy_violations(schema_key);
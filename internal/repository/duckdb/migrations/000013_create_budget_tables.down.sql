@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_budget_breaches_observed_at;
+DROP INDEX IF EXISTS idx_metric_series_entity_id;
+DROP TABLE IF EXISTS budget_breaches;
+DROP SEQUENCE IF EXISTS budget_breaches_id_seq;
+DROP TABLE IF EXISTS metric_series;
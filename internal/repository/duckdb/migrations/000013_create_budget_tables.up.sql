@@ -0,0 +1,25 @@
+-- Distinct metric series seen per schema and entity, used to estimate active series for budgets
+CREATE TABLE IF NOT EXISTS metric_series (
+    schema_id TEXT NOT NULL,
+    series_id TEXT NOT NULL,
+    -- Empty when the resource emitting the series has no detected entity
+    entity_id TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    PRIMARY KEY (schema_id, series_id, entity_id)
+);
+
+CREATE SEQUENCE IF NOT EXISTS budget_breaches_id_seq START 1;
+
+CREATE TABLE IF NOT EXISTS budget_breaches (
+    id INTEGER PRIMARY KEY DEFAULT nextval('budget_breaches_id_seq'),
+    budget TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    limit_name TEXT NOT NULL,
+    value INTEGER NOT NULL,
+    max_value INTEGER NOT NULL,
+    observed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_series_entity_id ON metric_series(entity_id);
+CREATE INDEX IF NOT EXISTS idx_budget_breaches_observed_at ON budget_breaches(observed_at);
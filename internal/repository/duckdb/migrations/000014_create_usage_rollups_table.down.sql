@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_usage_rollups_dimension_id;
+DROP INDEX IF EXISTS idx_usage_rollups_schema_key;
+DROP TABLE IF EXISTS usage_rollups;
@@ -0,0 +1,18 @@
+-- Usage of every schema per hour or day, recorded for the schema itself and for each entity and scope emitting it.
+-- Hourly buckets are downsampled into daily buckets once older than the hourly retention.
+CREATE TABLE IF NOT EXISTS usage_rollups (
+    resolution TEXT NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    schema_id TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    dimension TEXT NOT NULL,
+    -- Entity or scope ID, empty for the schema dimension
+    dimension_id TEXT NOT NULL,
+    seen_count BIGINT NOT NULL,
+    data_point_count BIGINT NOT NULL,
+    estimated_bytes BIGINT NOT NULL,
+    PRIMARY KEY (resolution, bucket, schema_id, dimension, dimension_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_rollups_schema_key ON usage_rollups(schema_key);
+CREATE INDEX IF NOT EXISTS idx_usage_rollups_dimension_id ON usage_rollups(dimension_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_telemetry_archive_schema_key;
+DROP INDEX IF EXISTS idx_stale_schemas_signal_type;
+DROP TABLE IF EXISTS telemetry_archive;
+DROP TABLE IF EXISTS stale_schemas;
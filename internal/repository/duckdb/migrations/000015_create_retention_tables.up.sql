@@ -0,0 +1,23 @@
+-- Schemas not seen for longer than the stale TTL of their signal, until seen again or removed
+CREATE TABLE IF NOT EXISTS stale_schemas (
+    schema_id TEXT PRIMARY KEY,
+    schema_key TEXT NOT NULL,
+    signal_type TEXT NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    stale_since TIMESTAMP NOT NULL,
+    -- Unset when the retention policy keeps stale schemas forever
+    expires_at TIMESTAMP
+);
+
+-- Snapshots of the schemas removed by the retention job with the archive action
+CREATE TABLE IF NOT EXISTS telemetry_archive (
+    schema_id TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    signal_type TEXT NOT NULL,
+    snapshot JSON NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stale_schemas_signal_type ON stale_schemas(signal_type);
+CREATE INDEX IF NOT EXISTS idx_telemetry_archive_schema_key ON telemetry_archive(schema_key);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_resolved_owners_team;
+DROP INDEX IF EXISTS idx_resolved_owners_target;
+DROP INDEX IF EXISTS idx_owner_assignments_target;
+DROP TABLE IF EXISTS resolved_owners;
+DROP TABLE IF EXISTS owner_assignments;
@@ -0,0 +1,20 @@
+-- Owners assigned through the API, taking precedence over the ownership rules
+CREATE TABLE IF NOT EXISTS owner_assignments (
+    kind TEXT NOT NULL,
+    -- Schema key, entity ID or scope ID
+    target_id TEXT NOT NULL,
+    team TEXT NOT NULL,
+    assigned_at TIMESTAMP NOT NULL
+);
+
+-- Owners resolved for every telemetry, entity and scope from assignments and rules
+CREATE TABLE IF NOT EXISTS resolved_owners (
+    kind TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    team TEXT NOT NULL,
+    source TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_owner_assignments_target ON owner_assignments(kind, target_id);
+CREATE INDEX IF NOT EXISTS idx_resolved_owners_target ON resolved_owners(kind, target_id);
+CREATE INDEX IF NOT EXISTS idx_resolved_owners_team ON resolved_owners(team);
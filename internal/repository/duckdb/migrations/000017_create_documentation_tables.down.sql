@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_attribute_docs_schema_key;
+DROP INDEX IF EXISTS idx_telemetry_docs_schema_key;
+DROP TABLE IF EXISTS attribute_docs;
+DROP TABLE IF EXISTS telemetry_docs;
@@ -0,0 +1,22 @@
+-- Documentation curated through the API, kept apart from the inferred schemas so that
+-- ingestion never overwrites it
+CREATE TABLE IF NOT EXISTS telemetry_docs (
+    schema_key TEXT NOT NULL,
+    brief TEXT,
+    note TEXT,
+    stability TEXT,
+    -- JSON array of free-form tags
+    tags TEXT,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS attribute_docs (
+    schema_key TEXT NOT NULL,
+    attribute_name TEXT NOT NULL,
+    brief TEXT,
+    requirement_level TEXT,
+    stability TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_docs_schema_key ON telemetry_docs(schema_key);
+CREATE INDEX IF NOT EXISTS idx_attribute_docs_schema_key ON attribute_docs(schema_key);
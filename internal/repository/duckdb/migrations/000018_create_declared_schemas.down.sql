@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_declared_schemas_scope_name;
+DROP INDEX IF EXISTS idx_declared_schemas_entity_type;
+DROP INDEX IF EXISTS idx_declared_schemas_registry;
+DROP TABLE IF EXISTS declared_schemas;
@@ -0,0 +1,22 @@
+-- Telemetry declared ahead of being emitted, imported from Weaver registries
+CREATE TABLE IF NOT EXISTS declared_schemas (
+    registry TEXT NOT NULL,
+    group_id TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    signal_type TEXT NOT NULL,
+    brief TEXT,
+    note TEXT,
+    stability TEXT,
+    unit TEXT,
+    metric_type TEXT,
+    span_kind TEXT,
+    -- JSON array of the declared attributes
+    attributes TEXT NOT NULL,
+    entity_type TEXT,
+    scope_name TEXT,
+    imported_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_declared_schemas_registry ON declared_schemas(registry);
+CREATE INDEX IF NOT EXISTS idx_declared_schemas_entity_type ON declared_schemas(entity_type);
+CREATE INDEX IF NOT EXISTS idx_declared_schemas_scope_name ON declared_schemas(scope_name);
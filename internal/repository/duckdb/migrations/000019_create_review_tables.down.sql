@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_review_notifications_created_at;
+DROP INDEX IF EXISTS idx_telemetry_reviews_schema_key;
+DROP TABLE IF EXISTS review_notifications;
+DROP SEQUENCE IF EXISTS review_notifications_id_seq;
+DROP TABLE IF EXISTS telemetry_reviews;
+DROP SEQUENCE IF EXISTS telemetry_reviews_id_seq;
@@ -0,0 +1,34 @@
+CREATE SEQUENCE IF NOT EXISTS telemetry_reviews_id_seq START 1;
+
+-- Newly observed schema keys and new variants of approved keys, waiting for a reviewer.
+-- Keys without any review predate the review queue and are considered approved.
+CREATE TABLE IF NOT EXISTS telemetry_reviews (
+    id INTEGER PRIMARY KEY DEFAULT nextval('telemetry_reviews_id_seq'),
+    schema_key TEXT NOT NULL,
+    schema_id TEXT NOT NULL,
+    signal_type TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    state TEXT NOT NULL,
+    detected_at TIMESTAMP NOT NULL,
+    reviewer TEXT,
+    comment TEXT,
+    -- drop or flag when rejecting created a policy
+    reject_action TEXT,
+    merged_into TEXT,
+    decided_at TIMESTAMP
+);
+
+CREATE SEQUENCE IF NOT EXISTS review_notifications_id_seq START 1;
+
+-- Notifications of telemetry entering the review queue
+CREATE TABLE IF NOT EXISTS review_notifications (
+    id INTEGER PRIMARY KEY DEFAULT nextval('review_notifications_id_seq'),
+    review_id INTEGER NOT NULL,
+    schema_key TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_reviews_schema_key ON telemetry_reviews(schema_key);
+CREATE INDEX IF NOT EXISTS idx_review_notifications_created_at ON review_notifications(created_at);
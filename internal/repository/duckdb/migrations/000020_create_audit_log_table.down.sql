@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_audit_log_subject;
+DROP INDEX IF EXISTS idx_audit_log_timestamp;
+DROP TABLE IF EXISTS audit_log;
+DROP SEQUENCE IF EXISTS audit_log_id_seq;
@@ -0,0 +1,17 @@
+CREATE SEQUENCE IF NOT EXISTS audit_log_id_seq START 1;
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY DEFAULT nextval('audit_log_id_seq'),
+    timestamp TIMESTAMP NOT NULL,
+    subject TEXT NOT NULL,
+    role TEXT NOT NULL,
+    auth_method TEXT NOT NULL,
+    method TEXT NOT NULL,
+    path TEXT NOT NULL,
+    route TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    remote_addr TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
+CREATE INDEX IF NOT EXISTS idx_audit_log_subject ON audit_log(subject);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_schema_senders_identity;
+DROP TABLE IF EXISTS schema_senders;
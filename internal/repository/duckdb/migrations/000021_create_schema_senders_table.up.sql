@@ -0,0 +1,11 @@
+-- Clients identified by their verified TLS certificate that sent a schema
+CREATE TABLE IF NOT EXISTS schema_senders (
+    schema_id TEXT NOT NULL,
+    identity TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    seen_count BIGINT DEFAULT 0,
+    PRIMARY KEY (schema_id, identity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schema_senders_identity ON schema_senders(identity);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ingest_usage;
+DROP TABLE IF EXISTS ingest_tokens;
+DROP SEQUENCE IF EXISTS ingest_tokens_id_seq;
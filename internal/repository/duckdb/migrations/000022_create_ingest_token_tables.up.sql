@@ -0,0 +1,24 @@
+CREATE SEQUENCE IF NOT EXISTS ingest_tokens_id_seq START 1;
+
+-- Tokens authenticating OTLP clients, stored as SHA-256 hashes
+CREATE TABLE IF NOT EXISTS ingest_tokens (
+    id INTEGER PRIMARY KEY DEFAULT nextval('ingest_tokens_id_seq'),
+    name TEXT NOT NULL UNIQUE,
+    token_hash TEXT NOT NULL UNIQUE,
+    requests_per_second DOUBLE NOT NULL DEFAULT 0,
+    schemas_per_second DOUBLE NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    created_by TEXT,
+    revoked_at TIMESTAMP
+);
+
+-- Exports of each ingest token, by hour
+CREATE TABLE IF NOT EXISTS ingest_usage (
+    token_id INTEGER NOT NULL,
+    bucket TIMESTAMP NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    schemas BIGINT NOT NULL DEFAULT 0,
+    throttled BIGINT NOT NULL DEFAULT 0,
+    last_seen TIMESTAMP NOT NULL,
+    PRIMARY KEY (token_id, bucket)
+);
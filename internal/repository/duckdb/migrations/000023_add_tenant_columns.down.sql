@@ -0,0 +1,37 @@
+-- DuckDB cannot drop columns from keyed or referenced tables, so the tenant columns stay and keep
+-- their 'default' value; only the dependency keys are restored, keeping the default tenant rows.
+DROP INDEX IF EXISTS idx_telemetry_producers_tenant;
+DROP INDEX IF EXISTS idx_telemetry_scopes_tenant;
+DROP INDEX IF EXISTS idx_telemetry_entities_tenant;
+DROP INDEX IF EXISTS idx_telemetry_schemas_tenant;
+
+CREATE TABLE dependency_edges_backup AS SELECT * FROM dependency_edges WHERE tenant = 'default';
+DROP INDEX IF EXISTS idx_dependency_edges_callee_id;
+DROP TABLE dependency_edges;
+CREATE TABLE dependency_edges (
+    caller_id TEXT NOT NULL,
+    callee_id TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    seen_count BIGINT DEFAULT 0,
+    PRIMARY KEY (caller_id, callee_id, protocol, schema_key)
+);
+INSERT INTO dependency_edges
+    SELECT caller_id, callee_id, protocol, schema_key, first_seen, last_seen, seen_count FROM dependency_edges_backup;
+DROP TABLE dependency_edges_backup;
+CREATE INDEX IF NOT EXISTS idx_dependency_edges_callee_id ON dependency_edges(callee_id);
+
+CREATE TABLE dependency_nodes_backup AS SELECT * FROM dependency_nodes WHERE tenant = 'default';
+DROP TABLE dependency_nodes;
+CREATE TABLE dependency_nodes (
+    node_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    entity_id TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL
+);
+INSERT INTO dependency_nodes
+    SELECT node_id, name, entity_id, first_seen, last_seen FROM dependency_nodes_backup;
+DROP TABLE dependency_nodes_backup;
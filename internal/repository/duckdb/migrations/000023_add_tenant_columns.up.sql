@@ -0,0 +1,66 @@
+-- Every catalog row belongs to a tenant, existing rows to the default tenant. Tables keyed by
+-- schema, entity, scope or producer IDs alone (attributes and links) need no column: those IDs
+-- are already unique per tenant.
+ALTER TABLE telemetry_schemas ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_entities ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_scopes ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_producers ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_history ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE schema_versions ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE entity_attribute_changes ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE entity_schema_changes ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE policy_violations ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE budget_breaches ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE usage_rollups ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE stale_schemas ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_archive ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE owner_assignments ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE resolved_owners ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_docs ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE attribute_docs ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE declared_schemas ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE telemetry_reviews ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE review_notifications ADD COLUMN tenant TEXT DEFAULT 'default';
+ALTER TABLE audit_log ADD COLUMN tenant TEXT DEFAULT 'default';
+-- Unset for tokens free to send to any tenant
+ALTER TABLE ingest_tokens ADD COLUMN tenant TEXT;
+
+-- Dependency nodes are keyed by service name, so their keys are rebuilt to include the tenant
+CREATE TABLE dependency_nodes_backup AS SELECT * FROM dependency_nodes;
+DROP TABLE dependency_nodes;
+CREATE TABLE dependency_nodes (
+    tenant TEXT NOT NULL DEFAULT 'default',
+    node_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    entity_id TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    PRIMARY KEY (tenant, node_id)
+);
+INSERT INTO dependency_nodes (node_id, name, entity_id, first_seen, last_seen)
+    SELECT node_id, name, entity_id, first_seen, last_seen FROM dependency_nodes_backup;
+DROP TABLE dependency_nodes_backup;
+
+CREATE TABLE dependency_edges_backup AS SELECT * FROM dependency_edges;
+DROP INDEX IF EXISTS idx_dependency_edges_callee_id;
+DROP TABLE dependency_edges;
+CREATE TABLE dependency_edges (
+    tenant TEXT NOT NULL DEFAULT 'default',
+    caller_id TEXT NOT NULL,
+    callee_id TEXT NOT NULL,
+    protocol TEXT NOT NULL,
+    schema_key TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    seen_count BIGINT DEFAULT 0,
+    PRIMARY KEY (tenant, caller_id, callee_id, protocol, schema_key)
+);
+INSERT INTO dependency_edges (caller_id, callee_id, protocol, schema_key, first_seen, last_seen, seen_count)
+    SELECT caller_id, callee_id, protocol, schema_key, first_seen, last_seen, seen_count FROM dependency_edges_backup;
+DROP TABLE dependency_edges_backup;
+CREATE INDEX IF NOT EXISTS idx_dependency_edges_callee_id ON dependency_edges(callee_id);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_schemas_tenant ON telemetry_schemas(tenant);
+CREATE INDEX IF NOT EXISTS idx_telemetry_entities_tenant ON telemetry_entities(tenant);
+CREATE INDEX IF NOT EXISTS idx_telemetry_scopes_tenant ON telemetry_scopes(tenant);
+CREATE INDEX IF NOT EXISTS idx_telemetry_producers_tenant ON telemetry_producers(tenant);
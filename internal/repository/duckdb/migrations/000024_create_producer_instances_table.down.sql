@@ -0,0 +1 @@
+DROP TABLE IF EXISTS producer_instances;
@@ -0,0 +1,3 @@
+-- The graph keyed on service entities is cleared and rebuilt keyed by service name
+DELETE FROM dependency_edges;
+DELETE FROM dependency_nodes;
@@ -0,0 +1,5 @@
+-- Dependency nodes were keyed by service name, merging services of different namespaces, and are
+-- now keyed on the ID of their service entity. Peer nodes get IDs hashed from their name, which
+-- SQL cannot compute, so the graph is cleared and rebuilt from the traces received from now on.
+DELETE FROM dependency_edges;
+DELETE FROM dependency_nodes;
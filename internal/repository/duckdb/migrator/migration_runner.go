@@ -5,26 +5,26 @@ import (
 	"log/slog"
 
 	"github.com/tallycat/tallycat/internal/repository/duckdb"
+	"github.com/tallycat/tallycat/internal/repository/migrator"
 )
 
 // ApplyMigrations runs all pending migrations in the database
 func ApplyMigrations(db *sql.DB) error {
-	logger := slog.Default()
-	migrator := New(db, logger)
+	m := migrator.New(db, slog.Default())
 
 	// Initialize the migrations table
-	if err := migrator.Initialize(); err != nil {
+	if err := m.Initialize(); err != nil {
 		return err
 	}
 
 	// Load migrations from the embedded filesystem
-	migrations, err := migrator.LoadMigrations(duckdb.EmbeddedMigrations)
+	migrations, err := m.LoadMigrations(duckdb.EmbeddedMigrations)
 	if err != nil {
 		return err
 	}
 
 	// Apply all pending migrations
-	if err := migrator.Migrate(migrations); err != nil {
+	if err := m.Migrate(migrations); err != nil {
 		return err
 	}
 
@@ -33,17 +33,16 @@ func ApplyMigrations(db *sql.DB) error {
 
 // RollbackLastMigration rolls back the most recently applied migration
 func RollbackLastMigration(db *sql.DB) error {
-	logger := slog.Default()
-	migrator := New(db, logger)
+	m := migrator.New(db, slog.Default())
 
 	// Load migrations from the embedded filesystem
-	migrations, err := migrator.LoadMigrations(duckdb.EmbeddedMigrations)
+	migrations, err := m.LoadMigrations(duckdb.EmbeddedMigrations)
 	if err != nil {
 		return err
 	}
 
 	// Rollback the last migration
-	if err := migrator.Rollback(migrations); err != nil {
+	if err := m.Rollback(migrations); err != nil {
 		return err
 	}
 
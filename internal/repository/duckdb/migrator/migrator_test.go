@@ -9,6 +9,7 @@ import (
 	_ "github.com/marcboeker/go-duckdb/v2"
 	"github.com/stretchr/testify/require"
 	"github.com/tallycat/tallycat/internal/repository/duckdb"
+	"github.com/tallycat/tallycat/internal/repository/migrator"
 )
 
 func TestListEmbeddedFiles(t *testing.T) {
@@ -30,7 +31,7 @@ func TestLoadMigrations(t *testing.T) {
 	defer db.Close()
 
 	// Create migrator
-	m := New(db, slog.Default())
+	m := migrator.New(db, slog.Default())
 
 	// Load migrations
 	migrations, err := m.LoadMigrations(duckdb.EmbeddedMigrations)
@@ -47,3 +48,27 @@ func TestLoadMigrations(t *testing.T) {
 		require.NotEmpty(t, migration.DownSQL, "Down SQL should not be empty")
 	}
 }
+
+func TestMigrateToRoundTrip(t *testing.T) {
+	db, err := sql.Open("duckdb", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := migrator.New(db, slog.Default())
+	require.NoError(t, m.Initialize())
+	migrations, err := m.LoadMigrations(duckdb.EmbeddedMigrations)
+	require.NoError(t, err)
+
+	// Every migration rolls back, so any version can be migrated to
+	require.NoError(t, m.Migrate(migrations))
+	require.NoError(t, m.MigrateTo(migrations, 0))
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Zero(t, version)
+
+	require.NoError(t, ApplyMigrations(db))
+	require.NoError(t, RollbackLastMigration(db))
+	version, err = m.Version()
+	require.NoError(t, err)
+	require.Equal(t, migrations[len(migrations)-2].Version, version)
+}
@@ -0,0 +1,521 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+type OwnershipRepository struct {
+	pool *ConnectionPool
+	// model resolves owners from rules, nil when ownership is only assigned explicitly
+	model *schema.OwnershipModel
+}
+
+func NewOwnershipRepository(pool *ConnectionPool, model *schema.OwnershipModel) *OwnershipRepository {
+	return &OwnershipRepository{
+		pool:  pool,
+		model: model,
+	}
+}
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ownedTargets lists the tenants and IDs of the assets of each kind, and the query checking one
+// exists, completed with the tenant condition
+var ownedTargets = map[schema.OwnedKind]struct {
+	list, exists string
+}{
+	schema.OwnedKindTelemetry: {
+		list:   `SELECT DISTINCT tenant, schema_key FROM telemetry_schemas`,
+		exists: `SELECT COUNT(*) FROM telemetry_schemas WHERE schema_key = ?`,
+	},
+	schema.OwnedKindEntity: {
+		list:   `SELECT tenant, entity_id FROM telemetry_entities`,
+		exists: `SELECT COUNT(*) FROM telemetry_entities WHERE entity_id = ?`,
+	},
+	schema.OwnedKindScope: {
+		list:   `SELECT tenant, scope_id FROM telemetry_scopes`,
+		exists: `SELECT COUNT(*) FROM telemetry_scopes WHERE scope_id = ?`,
+	},
+}
+
+// resolveRegisteredOwners resolves the owners of the schema keys, entities and scopes of the
+// registered schemas. Without an ownership model, only explicit assignments own assets and those
+// are resolved when assigned.
+func resolveRegisteredOwners(ctx context.Context, q execQuerier, model *schema.OwnershipModel, schemas []schema.Telemetry) error {
+	if model == nil {
+		return nil
+	}
+
+	targets := make(map[schema.OwnedKind]map[string]bool)
+	add := func(kind schema.OwnedKind, id string) {
+		if targets[kind] == nil {
+			targets[kind] = make(map[string]bool)
+		}
+		targets[kind][id] = true
+	}
+	for _, telemetry := range schemas {
+		add(schema.OwnedKindTelemetry, telemetry.SchemaKey)
+		for id := range telemetry.Entities {
+			add(schema.OwnedKindEntity, id)
+		}
+		if telemetry.Scope != nil {
+			add(schema.OwnedKindScope, telemetry.Scope.ID)
+		}
+	}
+
+	for kind, ids := range targets {
+		for id := range ids {
+			if err := resolveOwners(ctx, q, model, kind, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveOwners replaces the resolved owners of an asset: its explicit owners when it has any,
+// otherwise the owners matched by the ownership model
+func resolveOwners(ctx context.Context, q execQuerier, model *schema.OwnershipModel, kind schema.OwnedKind, id string) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+	explicit, err := explicitTeams(ctx, q, kind, id)
+	if err != nil {
+		return err
+	}
+
+	owners := schema.ExplicitOwners(explicit)
+	if len(owners) == 0 && model != nil {
+		switch kind {
+		case schema.OwnedKindTelemetry:
+			var entities []*schema.Entity
+			if model.HasResourceRules() {
+				if entities, err = loadTelemetryEntities(ctx, q, id); err != nil {
+					return err
+				}
+			}
+			owners = model.ResolveTelemetryOwners(id, entities)
+		case schema.OwnedKindEntity:
+			entity := &schema.Entity{ID: id}
+			if entity.Attributes, err = loadEntityAttributes(ctx, q, id); err != nil {
+				return err
+			}
+			owners = model.ResolveEntityOwners(entity)
+		case schema.OwnedKindScope:
+			scope := &schema.Scope{ID: id}
+			if err := q.QueryRowContext(ctx, `SELECT name FROM telemetry_scopes WHERE scope_id = ?`, id).Scan(&scope.Name); err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to get scope %s: %w", id, err)
+			}
+			owners = model.ResolveScopeOwners(scope)
+		}
+	}
+
+	if _, err := q.ExecContext(ctx, `DELETE FROM resolved_owners WHERE kind = ? AND target_id = ? AND tenant = ?`, kind, id, tenantName); err != nil {
+		return fmt.Errorf("failed to clear owners of %s %s: %w", kind, id, err)
+	}
+	for _, owner := range owners {
+		_, err := q.ExecContext(ctx, `
+			INSERT INTO resolved_owners (kind, target_id, team, source, tenant) VALUES (?, ?, ?, ?, ?)
+		`, kind, id, owner.Team, owner.Source, tenantName)
+		if err != nil {
+			return fmt.Errorf("failed to record owner of %s %s: %w", kind, id, err)
+		}
+	}
+	return nil
+}
+
+func explicitTeams(ctx context.Context, q execQuerier, kind schema.OwnedKind, id string) ([]string, error) {
+	tenantFilter, args := tenantClause(ctx, "")
+	rows, err := q.QueryContext(ctx, `
+		SELECT team FROM owner_assignments WHERE kind = ? AND target_id = ?`+tenantFilter+` ORDER BY team
+	`, append([]any{kind, id}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owner assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []string
+	for rows.Next() {
+		var team string
+		if err := rows.Scan(&team); err != nil {
+			return nil, fmt.Errorf("failed to scan owner assignment row: %w", err)
+		}
+		teams = append(teams, team)
+	}
+	return teams, rows.Err()
+}
+
+// loadTelemetryEntities returns the entities emitting a schema key with their attributes
+func loadTelemetryEntities(ctx context.Context, q execQuerier, schemaKey string) ([]*schema.Entity, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+	rows, err := q.QueryContext(ctx, `
+		SELECT DISTINCT ea.entity_id, ea.name, ea.value
+		FROM schema_entities se
+		INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id
+		INNER JOIN entity_attributes ea ON se.entity_id = ea.entity_id
+		WHERE ts.schema_key = ?`+tenantFilter+`
+		ORDER BY ea.entity_id
+	`, append([]any{schemaKey}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry entities: %w", err)
+	}
+	defer rows.Close()
+
+	var entities []*schema.Entity
+	for rows.Next() {
+		var entityID, name, value string
+		if err := rows.Scan(&entityID, &name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry entity row: %w", err)
+		}
+		if len(entities) == 0 || entities[len(entities)-1].ID != entityID {
+			entities = append(entities, &schema.Entity{ID: entityID, Attributes: map[string]interface{}{}})
+		}
+		entities[len(entities)-1].Attributes[name] = value
+	}
+	return entities, rows.Err()
+}
+
+func loadEntityAttributes(ctx context.Context, q execQuerier, entityID string) (map[string]interface{}, error) {
+	rows, err := q.QueryContext(ctx, `SELECT name, value FROM entity_attributes WHERE entity_id = ?`, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entity attributes: %w", err)
+	}
+	defer rows.Close()
+
+	attributes := make(map[string]interface{})
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan entity attribute: %w", err)
+		}
+		attributes[name] = value
+	}
+	return attributes, rows.Err()
+}
+
+// loadOwners returns the resolved owners of the assets of a kind, by ID
+func loadOwners(ctx context.Context, q execQuerier, kind schema.OwnedKind, ids []string) (map[string][]schema.Owner, error) {
+	owners := make(map[string][]schema.Owner)
+	if len(ids) == 0 {
+		return owners, nil
+	}
+
+	args := []any{kind}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	args = append(args, tenantArgs...)
+	rows, err := q.QueryContext(ctx, `
+		SELECT target_id, team, source FROM resolved_owners
+		WHERE kind = ? AND target_id IN (?`+strings.Repeat(", ?", len(ids)-1)+`)`+tenantFilter+`
+		ORDER BY target_id, team
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owners: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var owner schema.Owner
+		if err := rows.Scan(&id, &owner.Team, &owner.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan owner row: %w", err)
+		}
+		owners[id] = append(owners[id], owner)
+	}
+	return owners, rows.Err()
+}
+
+// ownerClause restricts a query to the assets of a kind owned by a team
+func ownerClause(tenantColumn, column string, kind schema.OwnedKind, team string) (string, []any) {
+	return fmt.Sprintf(" AND EXISTS (SELECT 1 FROM resolved_owners ro WHERE ro.kind = ? AND ro.team = ? AND ro.tenant = %s AND ro.target_id = %s)", tenantColumn, column), []any{kind, team}
+}
+
+// RefreshOwnership resolves the owners of every telemetry, entity and scope again, after the
+// ownership model changed
+func (r *OwnershipRepository) RefreshOwnership(ctx context.Context) error {
+	db := r.pool.GetConnection()
+
+	type ownedTarget struct{ tenant, id string }
+	targets := make(map[schema.OwnedKind][]ownedTarget)
+	for kind, target := range ownedTargets {
+		rows, err := db.QueryContext(ctx, target.list)
+		if err != nil {
+			return fmt.Errorf("failed to list %s assets: %w", kind, err)
+		}
+		for rows.Next() {
+			var t ownedTarget
+			if err := rows.Scan(&t.tenant, &t.id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan %s asset row: %w", kind, err)
+			}
+			targets[kind] = append(targets[kind], t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating %s asset rows: %w", kind, err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM resolved_owners`); err != nil {
+		return fmt.Errorf("failed to clear resolved owners: %w", err)
+	}
+	for kind, owned := range targets {
+		for _, t := range owned {
+			if err := resolveOwners(tenant.WithTenant(ctx, t.tenant), tx, r.model, kind, t.id); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	slog.Debug("refreshed ownership",
+		"telemetries", len(targets[schema.OwnedKindTelemetry]),
+		"entities", len(targets[schema.OwnedKindEntity]),
+		"scopes", len(targets[schema.OwnedKindScope]),
+	)
+	return nil
+}
+
+// AssignOwners replaces the explicit owners of an asset. Without teams, the asset falls back to the
+// owners matched by the ownership model. It returns schema.ErrUnknownTeam for teams the model does
+// not declare and schema.ErrAssetNotFound for unknown assets.
+func (r *OwnershipRepository) AssignOwners(ctx context.Context, kind schema.OwnedKind, id string, teams []string) (*schema.Ownership, error) {
+	for _, team := range teams {
+		if team == "" || !r.model.HasTeam(team) {
+			return nil, fmt.Errorf("%w %q", schema.ErrUnknownTeam, team)
+		}
+	}
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := r.pool.GetConnection()
+	if err := checkOwnedAsset(ctx, db, kind, id); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM owner_assignments WHERE kind = ? AND target_id = ? AND tenant = ?`, kind, id, tenantName); err != nil {
+		return nil, fmt.Errorf("failed to clear owner assignments: %w", err)
+	}
+	now := time.Now()
+	for _, owner := range schema.ExplicitOwners(teams) {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO owner_assignments (kind, target_id, team, assigned_at, tenant) VALUES (?, ?, ?, ?, ?)
+		`, kind, id, owner.Team, now, tenantName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to assign owner: %w", err)
+		}
+	}
+	if err := resolveOwners(ctx, tx, r.model, kind, id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.GetOwnership(ctx, kind, id)
+}
+
+// GetOwnership returns the resolved owners of an asset, or schema.ErrAssetNotFound for unknown assets
+func (r *OwnershipRepository) GetOwnership(ctx context.Context, kind schema.OwnedKind, id string) (*schema.Ownership, error) {
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if err := checkOwnedAsset(ctx, db, kind, id); err != nil {
+		return nil, err
+	}
+
+	owners, err := loadOwners(ctx, db, kind, []string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	ownership := &schema.Ownership{Kind: kind, ID: id, Owners: owners[id]}
+	if ownership.Owners == nil {
+		ownership.Owners = []schema.Owner{}
+	}
+	return ownership, nil
+}
+
+func checkOwnedAsset(ctx context.Context, q execQuerier, kind schema.OwnedKind, id string) error {
+	target, ok := ownedTargets[kind]
+	if !ok {
+		return fmt.Errorf("invalid kind %q", kind)
+	}
+	tenantFilter, args := tenantClause(ctx, "")
+	var count int
+	if err := q.QueryRowContext(ctx, target.exists+tenantFilter, append([]any{id}, args...)...).Scan(&count); err != nil {
+		return fmt.Errorf("failed to get %s %s: %w", kind, id, err)
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s %s", schema.ErrAssetNotFound, kind, id)
+	}
+	return nil
+}
+
+// ListTeams returns the teams of the ownership model, and the ones only assigned through the API,
+// with the number of assets each owns
+func (r *OwnershipRepository) ListTeams(ctx context.Context) ([]schema.TeamSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, args := tenantClause(ctx, "")
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT team, kind, COUNT(DISTINCT (tenant, target_id))
+		FROM resolved_owners
+		WHERE 1=1`+tenantFilter+`
+		GROUP BY team, kind
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count owned assets: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make(map[string]*schema.TeamSummary)
+	summary := func(name string) *schema.TeamSummary {
+		if s, ok := summaries[name]; ok {
+			return s
+		}
+		team, ok := r.model.Team(name)
+		if !ok {
+			team = schema.Team{Name: name}
+		}
+		summaries[name] = &schema.TeamSummary{Team: team}
+		return summaries[name]
+	}
+	if r.model != nil {
+		for _, team := range r.model.Teams {
+			summary(team.Name)
+		}
+	}
+
+	for rows.Next() {
+		var team string
+		var kind schema.OwnedKind
+		var count int
+		if err := rows.Scan(&team, &kind, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan owned asset count: %w", err)
+		}
+		s := summary(team)
+		switch kind {
+		case schema.OwnedKindTelemetry:
+			s.Telemetries = count
+		case schema.OwnedKindEntity:
+			s.Entities = count
+		case schema.OwnedKindScope:
+			s.Scopes = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating owned asset counts: %w", err)
+	}
+
+	teams := make([]schema.TeamSummary, 0, len(summaries))
+	for _, s := range summaries {
+		teams = append(teams, *s)
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Team.Name < teams[j].Team.Name })
+	return teams, nil
+}
+
+// GetTeamInventory returns the telemetries, entities and scopes owned by a team
+func (r *OwnershipRepository) GetTeamInventory(ctx context.Context, teamName string) (*schema.TeamInventory, error) {
+	team, ok := r.model.Team(teamName)
+	if !ok {
+		team = schema.Team{Name: teamName}
+	}
+	inventory := &schema.TeamInventory{
+		Team:        team,
+		Telemetries: []schema.OwnedAsset{},
+		Entities:    []schema.OwnedAsset{},
+		Scopes:      []schema.OwnedAsset{},
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, tenantArgs := tenantClause(ctx, "ro")
+	queries := []struct {
+		kind   schema.OwnedKind
+		query  string
+		assets *[]schema.OwnedAsset
+	}{
+		{schema.OwnedKindTelemetry, `
+			SELECT ro.target_id, MIN(ts.signal_type), '', ro.source
+			FROM resolved_owners ro
+			INNER JOIN telemetry_schemas ts ON ts.schema_key = ro.target_id AND ts.tenant = ro.tenant
+			WHERE ro.kind = ? AND ro.team = ?` + tenantFilter + `
+			GROUP BY ro.target_id, ro.source
+			ORDER BY ro.target_id`, &inventory.Telemetries},
+		{schema.OwnedKindEntity, `
+			SELECT ro.target_id, te.entity_type, '', ro.source
+			FROM resolved_owners ro
+			INNER JOIN telemetry_entities te ON te.entity_id = ro.target_id
+			WHERE ro.kind = ? AND ro.team = ?` + tenantFilter + `
+			ORDER BY ro.target_id`, &inventory.Entities},
+		{schema.OwnedKindScope, `
+			SELECT ro.target_id, '', ts.name, ro.source
+			FROM resolved_owners ro
+			INNER JOIN telemetry_scopes ts ON ts.scope_id = ro.target_id
+			WHERE ro.kind = ? AND ro.team = ?` + tenantFilter + `
+			ORDER BY ro.target_id`, &inventory.Scopes},
+	}
+	for _, q := range queries {
+		rows, err := db.QueryContext(ctx, q.query, append([]any{q.kind, teamName}, tenantArgs...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query owned %s assets: %w", q.kind, err)
+		}
+		for rows.Next() {
+			var asset schema.OwnedAsset
+			if err := rows.Scan(&asset.ID, &asset.Type, &asset.Name, &asset.Source); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan owned %s asset: %w", q.kind, err)
+			}
+			*q.assets = append(*q.assets, asset)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating owned %s assets: %w", q.kind, err)
+		}
+	}
+
+	return inventory, nil
+}
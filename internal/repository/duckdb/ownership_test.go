@@ -0,0 +1,119 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// withOwnershipModel returns the schema and ownership repositories of the database resolving owners
+// with the model
+func withOwnershipModel(t *testing.T, repo *TelemetrySchemaRepository, model string) (*TelemetrySchemaRepository, *OwnershipRepository) {
+	t.Helper()
+	parsed, err := schema.ParseOwnershipModel([]byte(model))
+	require.NoError(t, err)
+	return NewTelemetrySchemaRepository(repo.pool, parsed), NewOwnershipRepository(repo.pool, parsed)
+}
+
+func TestOwnership_ResolvedOnIngest(t *testing.T) {
+	repo, ownershipRepo := withOwnershipModel(t, setupTestDB(t), `
+teams:
+  - name: payments
+  - name: platform
+rules:
+  - team: payments
+    resource: service.name=checkout-service
+  - team: platform
+    scope: otel*
+`)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", t0, 1), paymentsLogTelemetry(t0)}))
+
+	ownership, err := ownershipRepo.GetOwnership(ctx, schema.OwnedKindTelemetry, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, []schema.Owner{{Team: "payments", Source: schema.OwnershipSourceRule}}, ownership.Owners)
+
+	ownership, err = ownershipRepo.GetOwnership(ctx, schema.OwnedKindScope, "otelhttp")
+	require.NoError(t, err)
+	require.Equal(t, []schema.Owner{{Team: "platform", Source: schema.OwnershipSourceRule}}, ownership.Owners)
+
+	ownership, err = ownershipRepo.GetOwnership(ctx, schema.OwnedKindTelemetry, "payments.audit")
+	require.NoError(t, err)
+	require.Empty(t, ownership.Owners)
+
+	_, err = ownershipRepo.GetOwnership(ctx, schema.OwnedKindEntity, "unknown")
+	require.ErrorIs(t, err, schema.ErrAssetNotFound)
+
+	telemetries, total, err := repo.ListTelemetries(ctx, query.ListQueryParams{Owner: "payments", Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "checkout.requests", telemetries[0].SchemaKey)
+	require.Equal(t, []schema.Owner{{Team: "payments", Source: schema.OwnershipSourceRule}}, telemetries[0].Owners)
+
+	entities, total, err := repo.ListEntities(ctx, query.ListQueryParams{Owner: "payments", Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "checkout", entities[0].ID)
+
+	inventory, err := ownershipRepo.GetTeamInventory(ctx, "payments")
+	require.NoError(t, err)
+	require.Equal(t, []schema.OwnedAsset{{ID: "checkout.requests", Type: "Metric", Source: schema.OwnershipSourceRule}}, inventory.Telemetries)
+	require.Equal(t, []schema.OwnedAsset{{ID: "checkout", Type: "service", Source: schema.OwnershipSourceRule}}, inventory.Entities)
+	require.Empty(t, inventory.Scopes)
+
+	teams, err := ownershipRepo.ListTeams(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []schema.TeamSummary{
+		{Team: schema.Team{Name: "payments"}, Telemetries: 1, Entities: 1},
+		{Team: schema.Team{Name: "platform"}, Scopes: 1},
+	}, teams)
+}
+
+func TestOwnership_ExplicitAssignment(t *testing.T) {
+	repo, ownershipRepo := withOwnershipModel(t, setupTestDB(t), `
+teams:
+  - name: payments
+  - name: audit
+rules:
+  - team: payments
+    resource: service.name=payments-service
+`)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{paymentsLogTelemetry(t0)}))
+
+	_, err := ownershipRepo.AssignOwners(ctx, schema.OwnedKindTelemetry, "payments.audit", []string{"unknown"})
+	require.ErrorIs(t, err, schema.ErrUnknownTeam)
+	_, err = ownershipRepo.AssignOwners(ctx, schema.OwnedKindTelemetry, "unknown.key", []string{"audit"})
+	require.ErrorIs(t, err, schema.ErrAssetNotFound)
+
+	ownership, err := ownershipRepo.AssignOwners(ctx, schema.OwnedKindTelemetry, "payments.audit", []string{"audit"})
+	require.NoError(t, err)
+	require.Equal(t, []schema.Owner{{Team: "audit", Source: schema.OwnershipSourceExplicit}}, ownership.Owners)
+
+	// Explicit owners survive ingest and a full refresh
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{paymentsLogTelemetry(t0.Add(time.Minute))}))
+	require.NoError(t, ownershipRepo.RefreshOwnership(ctx))
+	ownership, err = ownershipRepo.GetOwnership(ctx, schema.OwnedKindTelemetry, "payments.audit")
+	require.NoError(t, err)
+	require.Equal(t, []schema.Owner{{Team: "audit", Source: schema.OwnershipSourceExplicit}}, ownership.Owners)
+
+	// Removing the assignment falls back to the rules
+	ownership, err = ownershipRepo.AssignOwners(ctx, schema.OwnedKindTelemetry, "payments.audit", nil)
+	require.NoError(t, err)
+	require.Equal(t, []schema.Owner{{Team: "payments", Source: schema.OwnershipSourceRule}}, ownership.Owners)
+
+	// Without a model, rule owners are dropped on refresh
+	ownershipRepo = NewOwnershipRepository(repo.pool, nil)
+	require.NoError(t, ownershipRepo.RefreshOwnership(ctx))
+	ownership, err = ownershipRepo.GetOwnership(ctx, schema.OwnedKindTelemetry, "payments.audit")
+	require.NoError(t, err)
+	require.Empty(t, ownership.Owners)
+}
@@ -0,0 +1,152 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type PolicyViolationRepository struct {
+	pool *ConnectionPool
+}
+
+func NewPolicyViolationRepository(pool *ConnectionPool) *PolicyViolationRepository {
+	return &PolicyViolationRepository{
+		pool: pool,
+	}
+}
+
+func (r *PolicyViolationRepository) RecordPolicyViolations(ctx context.Context, violations []schema.PolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO policy_violations (policy, mode, rule, schema_key, schema_id, signal_type, message, observed_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare policy violation insert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, v := range violations {
+		_, err = stmt.ExecContext(ctx,
+			v.Policy,
+			v.Mode,
+			v.Rule,
+			v.SchemaKey,
+			v.SchemaID,
+			v.TelemetryType,
+			v.Message,
+			v.ObservedAt,
+			tenantName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert policy violation: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ListPolicyViolations returns the most recent violations first
+func (r *PolicyViolationRepository) ListPolicyViolations(ctx context.Context, params query.ListQueryParams, filter query.PolicyViolationFilter) ([]schema.PolicyViolation, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if filter.Policy != "" {
+		where += " AND policy = ?"
+		args = append(args, filter.Policy)
+	}
+	if filter.SchemaKey != "" {
+		where += " AND schema_key = ?"
+		args = append(args, filter.SchemaKey)
+	}
+	if filter.Mode != "" {
+		where += " AND mode = ?"
+		args = append(args, filter.Mode)
+	}
+	if !filter.Window.Since.IsZero() {
+		where += " AND observed_at >= ?"
+		args = append(args, filter.Window.Since)
+	}
+	if !filter.Window.Until.IsZero() {
+		where += " AND observed_at <= ?"
+		args = append(args, filter.Window.Until)
+	}
+	if params.Search != "" {
+		where += " AND (schema_key LIKE ? OR message LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM policy_violations WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count policy violations: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.PolicyViolation{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, policy, mode, rule, schema_key, schema_id, signal_type, message, observed_at
+		FROM policy_violations
+		WHERE 1=1`+where+`
+		ORDER BY observed_at DESC, id DESC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query policy violations: %w", err)
+	}
+	defer rows.Close()
+
+	violations := []schema.PolicyViolation{}
+	for rows.Next() {
+		var v schema.PolicyViolation
+		if err := rows.Scan(
+			&v.ID,
+			&v.Policy,
+			&v.Mode,
+			&v.Rule,
+			&v.SchemaKey,
+			&v.SchemaID,
+			&v.TelemetryType,
+			&v.Message,
+			&v.ObservedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan policy violation row: %w", err)
+		}
+		violations = append(violations, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating policy violation rows: %w", err)
+	}
+
+	return violations, total, nil
+}
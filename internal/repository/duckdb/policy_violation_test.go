@@ -0,0 +1,68 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestPolicyViolations_RecordAndList(t *testing.T) {
+	repo := setupTestDB(t)
+	violationRepo := NewPolicyViolationRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, violationRepo.RecordPolicyViolations(ctx, nil))
+	require.NoError(t, violationRepo.RecordPolicyViolations(ctx, []schema.PolicyViolation{
+		{
+			Policy:        "no-pii",
+			Mode:          schema.PolicyModeReject,
+			Rule:          schema.PolicyRuleForbiddenAttribute,
+			SchemaKey:     "http.server.duration",
+			SchemaID:      "s1",
+			TelemetryType: schema.TelemetryTypeMetric,
+			Message:       "datapoint attribute user.email is forbidden",
+			ObservedAt:    t0,
+		},
+		{
+			Policy:        "cardinality",
+			Mode:          schema.PolicyModeAudit,
+			Rule:          schema.PolicyRuleMaxAttributes,
+			SchemaKey:     "db.query",
+			SchemaID:      "s2",
+			TelemetryType: schema.TelemetryTypeSpan,
+			Message:       "12 attributes exceed the maximum of 10",
+			ObservedAt:    t0.Add(time.Hour),
+		},
+	}))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+
+	violations, total, err := violationRepo.ListPolicyViolations(ctx, params, query.PolicyViolationFilter{})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Equal(t, "cardinality", violations[0].Policy)
+	require.NotZero(t, violations[0].ID)
+	require.Equal(t, schema.TelemetryTypeSpan, violations[0].TelemetryType)
+
+	violations, total, err = violationRepo.ListPolicyViolations(ctx, params, query.PolicyViolationFilter{Mode: "reject"})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "http.server.duration", violations[0].SchemaKey)
+	require.True(t, t0.Equal(violations[0].ObservedAt))
+
+	_, total, err = violationRepo.ListPolicyViolations(ctx, params, query.PolicyViolationFilter{
+		Window: query.TimeWindow{Since: t0.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	violations, total, err = violationRepo.ListPolicyViolations(ctx, params, query.PolicyViolationFilter{Policy: "unknown"})
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+	require.Empty(t, violations)
+}
@@ -0,0 +1,393 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+func (r *TelemetrySchemaRepository) ListProducers(ctx context.Context, params query.ListQueryParams) ([]schema.Producer, int, error) {
+	where, args := tenantClause(ctx, "tp")
+
+	if params.Search != "" {
+		where += " AND (tp.name LIKE ? OR tp.namespace LIKE ? OR tp.version LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM telemetry_producers tp
+		WHERE 1=1` + where
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count producers: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.Producer{}, 0, nil
+	}
+
+	query := `
+		SELECT
+			tp.producer_id,
+			tp.name,
+			tp.namespace,
+			tp.version,
+			tp.first_seen,
+			tp.last_seen
+		FROM telemetry_producers tp
+		WHERE 1=1` + where + `
+		ORDER BY tp.last_seen DESC, tp.name ASC
+		LIMIT ? OFFSET ?`
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	ctx, cancel = context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query producers: %w", err)
+	}
+	defer rows.Close()
+
+	producers := []schema.Producer{}
+	for rows.Next() {
+		var producer schema.Producer
+		var namespace, version sql.NullString
+		if err := rows.Scan(
+			&producer.ID,
+			&producer.Name,
+			&namespace,
+			&version,
+			&producer.FirstSeen,
+			&producer.LastSeen,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan producer row: %w", err)
+		}
+		producer.Namespace = namespace.String
+		producer.Version = version.String
+
+		producers = append(producers, producer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating producer rows: %w", err)
+	}
+
+	if err := loadProducerInstances(ctx, db, producers); err != nil {
+		return nil, 0, err
+	}
+
+	return producers, total, nil
+}
+
+// ListTelemetriesByProducer returns the latest schema of every telemetry emitted by any instance
+// of the producer with the given name and version. An empty version matches producers without one.
+func (r *TelemetrySchemaRepository) ListTelemetriesByProducer(ctx context.Context, name, version string) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "tp")
+	query := `
+		WITH latest_schemas AS (
+			SELECT
+				t.tenant,
+				t.schema_id,
+				t.schema_version,
+				t.schema_url,
+				t.signal_type,
+				t.schema_key,
+				t.unit,
+				t.metric_type,
+				t.temporality,
+				t.brief,
+				t.log_event_name,
+				t.span_kind,
+				t.span_name,
+				t.note,
+				t.protocol,
+				t.seen_count,
+				t.created_at,
+				t.updated_at,
+				ROW_NUMBER() OVER (
+					PARTITION BY t.tenant, t.signal_type, t.schema_key
+					ORDER BY t.updated_at DESC
+				) as rn
+			FROM telemetry_schemas t
+			INNER JOIN schema_producers sp ON t.schema_id = sp.schema_id
+			INNER JOIN telemetry_producers tp ON sp.producer_id = tp.producer_id
+			WHERE tp.name = ? AND COALESCE(tp.version, '') = ?` + tenantFilter + `
+		)
+		SELECT
+			tenant, schema_id, schema_version, schema_url, signal_type, schema_key,
+			unit, metric_type, temporality, brief,
+			log_event_name, span_kind, span_name,
+			note, protocol, seen_count,
+			created_at, updated_at
+		FROM latest_schemas
+		WHERE rn = 1
+		ORDER BY schema_key ASC`
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, append([]any{name, version}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetries by producer: %w", err)
+	}
+	defer rows.Close()
+
+	var telemetries []schema.Telemetry
+	for rows.Next() {
+		var t schema.Telemetry
+		var schemaVersion, schemaURL, unit, metricType, temporality, brief, logEventName, spanKind, spanName, note sql.NullString
+		if err := rows.Scan(
+			&t.Tenant,
+			&t.SchemaID,
+			&schemaVersion,
+			&schemaURL,
+			&t.TelemetryType,
+			&t.SchemaKey,
+			&unit,
+			&metricType,
+			&temporality,
+			&brief,
+			&logEventName,
+			&spanKind,
+			&spanName,
+			&note,
+			&t.Protocol,
+			&t.SeenCount,
+			&t.CreatedAt,
+			&t.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry row: %w", err)
+		}
+		t.SchemaVersion = schemaVersion.String
+		t.SchemaURL = schemaURL.String
+		t.MetricUnit = unit.String
+		t.MetricType = schema.MetricType(metricType.String)
+		t.MetricTemporality = schema.MetricTemporality(temporality.String)
+		t.Brief = brief.String
+		t.LogEventName = logEventName.String
+		t.SpanKind = schema.SpanKind(spanKind.String)
+		t.SpanName = spanName.String
+		t.Note = note.String
+
+		telemetries = append(telemetries, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating telemetry rows: %w", err)
+	}
+
+	for i := range telemetries {
+		attrRows, err := db.QueryContext(ctx, `
+			SELECT DISTINCT name, type, source
+			FROM schema_attributes
+			WHERE schema_id = ?
+			ORDER BY name`, telemetries[i].SchemaID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query schema attributes: %w", err)
+		}
+
+		var attributes []schema.Attribute
+		for attrRows.Next() {
+			var attr schema.Attribute
+			if err := attrRows.Scan(&attr.Name, &attr.Type, &attr.Source); err != nil {
+				attrRows.Close()
+				return nil, fmt.Errorf("failed to scan attribute row: %w", err)
+			}
+			attributes = append(attributes, attr)
+		}
+		attrRows.Close()
+
+		telemetries[i].Attributes = attributes
+	}
+
+	if err := documentTelemetries(ctx, db, telemetries); err != nil {
+		return nil, err
+	}
+
+	return telemetries, nil
+}
+
+// loadProducerInstances sets the instances of the producers, the most recently seen first
+func loadProducerInstances(ctx context.Context, db *sql.DB, producers []schema.Producer) error {
+	if len(producers) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*schema.Producer, len(producers))
+	placeholders := make([]string, 0, len(producers))
+	args := make([]any, 0, len(producers))
+	for i := range producers {
+		byID[producers[i].ID] = &producers[i]
+		placeholders = append(placeholders, "?")
+		args = append(args, producers[i].ID)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT producer_id, instance_id, first_seen, last_seen
+		FROM producer_instances
+		WHERE producer_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY last_seen DESC, instance_id ASC`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query producer instances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var instance schema.ProducerInstance
+		var producerID string
+		if err := rows.Scan(&producerID, &instance.ID, &instance.FirstSeen, &instance.LastSeen); err != nil {
+			return fmt.Errorf("failed to scan producer instance row: %w", err)
+		}
+		producer := byID[producerID]
+		producer.Instances = append(producer.Instances, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating producer instance rows: %w", err)
+	}
+	return nil
+}
+
+// RekeyProducers moves the producers stored under other IDs than their own, as the producers
+// stored per instance by earlier releases, to their ID. Producers moved to the same ID are merged
+// with their schema links and instances. It returns the number of producers moved.
+func (r *TelemetrySchemaRepository) RekeyProducers(ctx context.Context) (int, error) {
+	db := r.pool.GetConnection()
+
+	producers, tenants, err := loadStoredProducers(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	rekeys := schema.RekeyProducers(producers, func(p schema.Producer) string {
+		return tenant.ScopeID(tenants[p.ID], p.ProducerID())
+	})
+	if len(rekeys) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved := 0
+	for _, rekey := range rekeys {
+		producer := rekey.Producer
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO telemetry_producers (producer_id, name, namespace, version, first_seen, last_seen, tenant)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (producer_id) DO UPDATE SET
+				first_seen = excluded.first_seen,
+				last_seen = excluded.last_seen
+		`, producer.ID, producer.Name, producer.Namespace, producer.Version, producer.FirstSeen, producer.LastSeen, tenants[rekey.PreviousIDs[0]])
+		if err != nil {
+			return 0, fmt.Errorf("failed to store producer %s: %w", producer.ID, err)
+		}
+
+		for _, previousID := range rekey.PreviousIDs {
+			statements := []rekeyStatement{
+				{"schema links", `
+					INSERT INTO schema_producers (schema_id, producer_id, first_seen, last_seen, seen_count)
+					SELECT schema_id, ?, first_seen, last_seen, seen_count
+					FROM schema_producers
+					WHERE producer_id = ?
+					ON CONFLICT (schema_id, producer_id) DO UPDATE SET
+						first_seen = LEAST(COALESCE(schema_producers.first_seen, excluded.first_seen), excluded.first_seen),
+						last_seen = GREATEST(COALESCE(schema_producers.last_seen, excluded.last_seen), excluded.last_seen),
+						seen_count = COALESCE(schema_producers.seen_count, 0) + COALESCE(excluded.seen_count, 0)`,
+					[]any{producer.ID, previousID}},
+				{"schema links", `DELETE FROM schema_producers WHERE producer_id = ?`, []any{previousID}},
+				{"instances", `
+					INSERT INTO producer_instances (producer_id, instance_id, first_seen, last_seen)
+					SELECT ?, instance_id, first_seen, last_seen
+					FROM producer_instances
+					WHERE producer_id = ?
+					ON CONFLICT (producer_id, instance_id) DO UPDATE SET
+						first_seen = LEAST(producer_instances.first_seen, excluded.first_seen),
+						last_seen = GREATEST(producer_instances.last_seen, excluded.last_seen)`,
+					[]any{producer.ID, previousID}},
+				{"instances", `DELETE FROM producer_instances WHERE producer_id = ?`, []any{previousID}},
+			}
+			for _, statement := range statements {
+				if _, err := tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+					return 0, fmt.Errorf("failed to move %s of producer %s: %w", statement.name, previousID, err)
+				}
+			}
+			moved++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// DuckDB rejects deleting a row referenced by a foreign key in the transaction deleting the rows
+	// referencing it, so the moved producers go in a second transaction. Producers left behind by a
+	// failure have no links anymore, and are removed by the retention policy.
+	tx, err = db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, rekey := range rekeys {
+		for _, previousID := range rekey.PreviousIDs {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM telemetry_producers WHERE producer_id = ?`, previousID); err != nil {
+				return 0, fmt.Errorf("failed to delete moved producer %s: %w", previousID, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// loadStoredProducers returns every producer of every tenant, and the tenant of each producer by ID
+func loadStoredProducers(ctx context.Context, db *sql.DB) ([]schema.Producer, map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT producer_id, name, namespace, version, first_seen, last_seen, COALESCE(tenant, ?)
+		FROM telemetry_producers`, tenant.Default)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query producers: %w", err)
+	}
+	defer rows.Close()
+
+	var producers []schema.Producer
+	tenants := make(map[string]string)
+	for rows.Next() {
+		var producer schema.Producer
+		var namespace, version sql.NullString
+		var tenantName string
+		if err := rows.Scan(&producer.ID, &producer.Name, &namespace, &version, &producer.FirstSeen, &producer.LastSeen, &tenantName); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan producer row: %w", err)
+		}
+		producer.Namespace = namespace.String
+		producer.Version = version.String
+		producers = append(producers, producer)
+		tenants[producer.ID] = tenantName
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating producer rows: %w", err)
+	}
+	return producers, tenants, nil
+}
@@ -0,0 +1,92 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func producerTelemetry(schemaID, schemaKey string, producer *schema.Producer, updatedAt time.Time) schema.Telemetry {
+	producer.ID = producer.ProducerID()
+	producer.FirstSeen = updatedAt
+	producer.LastSeen = updatedAt
+	for i := range producer.Instances {
+		producer.Instances[i].FirstSeen = updatedAt
+		producer.Instances[i].LastSeen = updatedAt
+	}
+
+	return schema.Telemetry{
+		SchemaID:      schemaID,
+		SchemaKey:     schemaKey,
+		TelemetryType: schema.TelemetryTypeMetric,
+		MetricType:    schema.MetricTypeGauge,
+		Protocol:      schema.TelemetryProtocolOTLP,
+		SeenCount:     1,
+		CreatedAt:     updatedAt,
+		UpdatedAt:     updatedAt,
+		Attributes: []schema.Attribute{
+			{Name: "cpu", Type: schema.AttributeTypeStr, Source: schema.AttributeSourceDataPoint},
+		},
+		Producers: map[string]*schema.Producer{producer.ID: producer},
+	}
+}
+
+func TestListProducers(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("s1", "system.cpu.usage", &schema.Producer{Name: "checkout", Version: "1.0.0", Instances: []schema.ProducerInstance{{ID: "a"}}}, now),
+		producerTelemetry("s2", "system.cpu.usage", &schema.Producer{Name: "checkout", Version: "1.0.0", Instances: []schema.ProducerInstance{{ID: "b"}}}, now),
+		producerTelemetry("s3", "system.memory.usage", &schema.Producer{Name: "payments"}, now),
+	}))
+
+	// Both instances of checkout are the same producer
+	producers, total, err := repo.ListProducers(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Len(t, producers, 2)
+	require.Equal(t, "checkout", producers[0].Name)
+	require.Len(t, producers[0].Instances, 2)
+	require.Equal(t, "a", producers[0].Instances[0].ID)
+	require.Empty(t, producers[1].Instances)
+
+	producers, total, err = repo.ListProducers(ctx, query.ListQueryParams{Search: "pay", Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "payments", producers[0].Name)
+	require.Empty(t, producers[0].Version)
+}
+
+func TestListTelemetriesByProducer(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("cpu_v1", "system.cpu.usage", &schema.Producer{Name: "checkout", Version: "1.0.0", Instances: []schema.ProducerInstance{{ID: "a"}}}, now.Add(-time.Hour)),
+		producerTelemetry("cpu_v2", "system.cpu.usage", &schema.Producer{Name: "checkout", Version: "1.0.0", Instances: []schema.ProducerInstance{{ID: "b"}}}, now),
+		producerTelemetry("mem_v1", "system.memory.usage", &schema.Producer{Name: "checkout", Version: "1.1.0"}, now),
+		producerTelemetry("disk_v1", "system.disk.io", &schema.Producer{Name: "checkout"}, now),
+	}))
+
+	telemetries, err := repo.ListTelemetriesByProducer(ctx, "checkout", "1.0.0")
+	require.NoError(t, err)
+	require.Len(t, telemetries, 1)
+	require.Equal(t, "cpu_v2", telemetries[0].SchemaID)
+	require.Len(t, telemetries[0].Attributes, 1)
+
+	telemetries, err = repo.ListTelemetriesByProducer(ctx, "checkout", "")
+	require.NoError(t, err)
+	require.Len(t, telemetries, 1)
+	require.Equal(t, "disk_v1", telemetries[0].SchemaID)
+
+	telemetries, err = repo.ListTelemetriesByProducer(ctx, "unknown", "1.0.0")
+	require.NoError(t, err)
+	require.Empty(t, telemetries)
+}
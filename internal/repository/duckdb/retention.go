@@ -0,0 +1,348 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+type RetentionRepository struct {
+	pool *ConnectionPool
+}
+
+func NewRetentionRepository(pool *ConnectionPool) *RetentionRepository {
+	return &RetentionRepository{
+		pool: pool,
+	}
+}
+
+// schemaChildTables hold the rows linked to a schema, removed along with it
+var schemaChildTables = []string{
+	"schema_attributes",
+	"schema_entities",
+	"schema_scopes",
+	"schema_producers",
+	"schema_senders",
+	"schema_versions",
+	"metric_series",
+}
+
+// RunRetention marks the schemas not seen for longer than the stale TTL of their signal as stale,
+// removes the stale schemas past their delete TTL with the policy action, garbage collects the
+// entities, scopes and producers left without schemas and compacts the database. It covers every tenant.
+func (r *RetentionRepository) RunRetention(ctx context.Context, policy *schema.RetentionPolicy, now time.Time) (*schema.RetentionResult, error) {
+	result := &schema.RetentionResult{}
+	db := r.pool.GetConnection()
+
+	for _, telemetryType := range []schema.TelemetryType{
+		schema.TelemetryTypeMetric, schema.TelemetryTypeLog, schema.TelemetryTypeSpan, schema.TelemetryTypeProfile,
+	} {
+		marked, err := markStaleSchemas(ctx, db, telemetryType, policy.TTL(telemetryType), now)
+		if err != nil {
+			return nil, err
+		}
+		result.MarkedStale += marked
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT schema_id FROM stale_schemas
+		WHERE expires_at IS NOT NULL AND expires_at <= ?
+		ORDER BY schema_id`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query expired schemas: %w", err)
+	}
+	var expired []string
+	for rows.Next() {
+		var schemaID string
+		if err := rows.Scan(&schemaID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan expired schema row: %w", err)
+		}
+		expired = append(expired, schemaID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expired schema rows: %w", err)
+	}
+
+	if len(expired) > 0 {
+		removed, err := r.removeSchemas(ctx, expired, policy.Action, now)
+		if err != nil {
+			return nil, err
+		}
+		removed.MarkedStale = result.MarkedStale
+		result = removed
+
+		// Reclaim the space of the deleted rows
+		if _, err := db.ExecContext(ctx, `CHECKPOINT`); err != nil {
+			return nil, fmt.Errorf("failed to checkpoint database: %w", err)
+		}
+	}
+
+	slog.Debug("ran retention",
+		"marked_stale", result.MarkedStale,
+		"archived", result.Archived,
+		"deleted_schemas", result.DeletedSchemas,
+		"deleted_entities", result.DeletedEntities,
+		"deleted_scopes", result.DeletedScopes,
+		"deleted_producers", result.DeletedProducers,
+	)
+
+	return result, nil
+}
+
+// markStaleSchemas records the schemas of a signal last seen before its stale TTL, and refreshes the
+// deadlines of the ones already stale so policy changes apply. It returns the number newly marked.
+func markStaleSchemas(ctx context.Context, db *sql.DB, telemetryType schema.TelemetryType, ttl schema.RetentionTTL, now time.Time) (int, error) {
+	staleAfter := ttl.StaleAfter.Microseconds()
+	var deleteAfter sql.NullInt64
+	if ttl.DeleteAfter > 0 {
+		deleteAfter = sql.NullInt64{Int64: ttl.DeleteAfter.Microseconds(), Valid: true}
+	}
+
+	res, err := db.ExecContext(ctx, `
+		INSERT INTO stale_schemas (schema_id, schema_key, signal_type, last_seen, stale_since, expires_at, tenant)
+		SELECT schema_id, schema_key, signal_type, updated_at,
+			updated_at + to_microseconds(CAST(? AS BIGINT)),
+			updated_at + to_microseconds(CAST(? AS BIGINT)),
+			tenant
+		FROM telemetry_schemas
+		WHERE signal_type = ? AND updated_at < ?
+			AND schema_id NOT IN (SELECT schema_id FROM stale_schemas)
+	`, staleAfter, deleteAfter, telemetryType, now.Add(-ttl.StaleAfter))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark stale %s schemas: %w", telemetryType, err)
+	}
+
+	_, err = db.ExecContext(ctx, `
+		UPDATE stale_schemas SET
+			stale_since = last_seen + to_microseconds(CAST(? AS BIGINT)),
+			expires_at = last_seen + to_microseconds(CAST(? AS BIGINT))
+		WHERE signal_type = ?
+	`, staleAfter, deleteAfter, telemetryType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update stale %s schemas: %w", telemetryType, err)
+	}
+
+	// Schemas no longer stale under the current TTL become regular schemas again
+	_, err = db.ExecContext(ctx, `
+		DELETE FROM stale_schemas WHERE signal_type = ? AND stale_since > ?
+	`, telemetryType, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmark stale %s schemas: %w", telemetryType, err)
+	}
+
+	marked, _ := res.RowsAffected()
+	return int(marked), nil
+}
+
+// removeSchemas archives, when asked to, and deletes schemas with their links, then deletes the
+// entities, scopes and producers no schema links to anymore
+func (r *RetentionRepository) removeSchemas(ctx context.Context, schemaIDs []string, action schema.RetentionAction, now time.Time) (*schema.RetentionResult, error) {
+	result := &schema.RetentionResult{}
+
+	var snapshots []*schema.TelemetrySchema
+	if action == schema.RetentionActionArchive {
+		// The schemas are looked up across tenants, each snapshot is read within its own tenant
+		allTenants := tenant.WithTenant(ctx, tenant.All)
+		schemaRepo := NewTelemetrySchemaRepository(r.pool, nil)
+		for _, schemaID := range schemaIDs {
+			snapshot, err := schemaRepo.GetTelemetrySchema(allTenants, schemaID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to snapshot schema %s: %w", schemaID, err)
+			}
+			if snapshot != nil {
+				snapshots = append(snapshots, snapshot)
+			}
+		}
+	}
+
+	// DuckDB rejects deleting a row referenced by a foreign key in the transaction deleting the rows
+	// referencing it, so links go first and the schemas, entities, scopes and producers follow in a
+	// second transaction. Schemas left without links by a failure are removed on the next run.
+	orphans := []struct {
+		kind, table, details, link, column string
+		count                              *int
+	}{
+		{"entities", "telemetry_entities", "entity_attributes", "schema_entities", "entity_id", &result.DeletedEntities},
+		{"scopes", "telemetry_scopes", "scope_attributes", "schema_scopes", "scope_id", &result.DeletedScopes},
+		{"producers", "telemetry_producers", "producer_instances", "schema_producers", "producer_id", &result.DeletedProducers},
+	}
+	orphaned := func(column, link string) string {
+		return column + ` NOT IN (SELECT ` + column + ` FROM ` + link + `)`
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, snapshot := range snapshots {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode schema snapshot: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO telemetry_archive (schema_id, schema_key, signal_type, snapshot, last_seen, archived_at, tenant)
+			SELECT schema_id, schema_key, signal_type, ?, last_seen, ?, tenant
+			FROM stale_schemas
+			WHERE schema_id = ?
+		`, string(data), now, snapshot.SchemaId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive schema %s: %w", snapshot.SchemaId, err)
+		}
+		result.Archived++
+	}
+
+	for _, schemaID := range schemaIDs {
+		for _, table := range schemaChildTables {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE schema_id = ?`, schemaID); err != nil {
+				return nil, fmt.Errorf("failed to delete %s of schema %s: %w", table, schemaID, err)
+			}
+		}
+	}
+
+	for _, o := range orphans {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+o.details+` WHERE `+orphaned(o.column, o.link)); err != nil {
+			return nil, fmt.Errorf("failed to delete details of orphaned %s: %w", o.kind, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	tx, err = r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, schemaID := range schemaIDs {
+		res, err := tx.ExecContext(ctx, `DELETE FROM telemetry_schemas WHERE schema_id = ?`, schemaID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete schema %s: %w", schemaID, err)
+		}
+		deleted, _ := res.RowsAffected()
+		result.DeletedSchemas += int(deleted)
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM stale_schemas WHERE schema_id = ?`, schemaID); err != nil {
+			return nil, fmt.Errorf("failed to delete stale schema %s: %w", schemaID, err)
+		}
+	}
+
+	for _, o := range orphans {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+o.table+` WHERE `+orphaned(o.column, o.link))
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete orphaned %s: %w", o.kind, err)
+		}
+		deleted, _ := res.RowsAffected()
+		*o.count = int(deleted)
+	}
+
+	// Owners assigned through the API are kept for the assets coming back
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM resolved_owners ro
+		WHERE (kind = ? AND NOT EXISTS (SELECT 1 FROM telemetry_schemas t WHERE t.tenant = ro.tenant AND t.schema_key = ro.target_id))
+			OR (kind = ? AND NOT EXISTS (SELECT 1 FROM telemetry_entities t WHERE t.tenant = ro.tenant AND t.entity_id = ro.target_id))
+			OR (kind = ? AND NOT EXISTS (SELECT 1 FROM telemetry_scopes t WHERE t.tenant = ro.tenant AND t.scope_id = ro.target_id))
+	`, schema.OwnedKindTelemetry, schema.OwnedKindEntity, schema.OwnedKindScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete owners of removed assets: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// ListStaleTelemetries returns the schemas marked stale, longest unseen first.
+// FilterType narrows them down to a signal type and Search matches the schema key.
+func (r *RetentionRepository) ListStaleTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.StaleTelemetry, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if params.FilterType != "" && params.FilterType != "all" {
+		where += " AND signal_type = ?"
+		args = append(args, cases.Title(language.English).String(params.FilterType))
+	}
+	if params.Search != "" {
+		where += " AND schema_key LIKE ?"
+		args = append(args, "%"+params.Search+"%")
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM stale_schemas WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count stale telemetries: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.StaleTelemetry{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT schema_id, schema_key, signal_type, last_seen, stale_since, expires_at
+		FROM stale_schemas
+		WHERE 1=1`+where+`
+		ORDER BY last_seen, schema_id
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query stale telemetries: %w", err)
+	}
+	defer rows.Close()
+
+	telemetries := []schema.StaleTelemetry{}
+	for rows.Next() {
+		var t schema.StaleTelemetry
+		var expiresAt sql.NullTime
+		if err := rows.Scan(&t.SchemaID, &t.SchemaKey, &t.TelemetryType, &t.LastSeen, &t.StaleSince, &expiresAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan stale telemetry row: %w", err)
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		telemetries = append(telemetries, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating stale telemetry rows: %w", err)
+	}
+
+	return telemetries, total, nil
+}
+
+// RemoveStaleTelemetry removes a stale schema right away with the policy action, once its owners
+// confirmed it is gone for good. It returns schema.ErrSchemaNotStale for schemas not marked stale.
+func (r *RetentionRepository) RemoveStaleTelemetry(ctx context.Context, schemaID string, action schema.RetentionAction) (*schema.RetentionResult, error) {
+	tenantFilter, args := tenantClause(ctx, "")
+	var count int
+	err := r.pool.GetConnection().QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM stale_schemas WHERE schema_id = ?`+tenantFilter,
+		append([]any{schemaID}, args...)...).Scan(&count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stale schema: %w", err)
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("%w: %s", schema.ErrSchemaNotStale, schemaID)
+	}
+
+	return r.removeSchemas(ctx, []string{schemaID}, action, time.Now())
+}
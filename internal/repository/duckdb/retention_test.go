@@ -0,0 +1,140 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func paymentsLogTelemetry(seenAt time.Time) schema.Telemetry {
+	return schema.Telemetry{
+		SchemaID:      "payments-log",
+		SchemaKey:     "payments.audit",
+		TelemetryType: schema.TelemetryTypeLog,
+		Protocol:      schema.TelemetryProtocolOTLP,
+		SeenCount:     1,
+		CreatedAt:     seenAt,
+		UpdatedAt:     seenAt,
+		Entities: map[string]*schema.Entity{
+			"payments": {
+				ID:         "payments",
+				Type:       "service",
+				Attributes: map[string]interface{}{"service.name": "payments-service"},
+				FirstSeen:  seenAt,
+				LastSeen:   seenAt,
+			},
+		},
+	}
+}
+
+func TestRetention_MarksAndRemovesStaleSchemas(t *testing.T) {
+	repo := setupTestDB(t)
+	retentionRepo := NewRetentionRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	checkout := usageTelemetry("v1", t0, 1)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{checkout, paymentsLogTelemetry(t0)}))
+
+	policy := &schema.RetentionPolicy{
+		Interval: time.Hour,
+		Action:   schema.RetentionActionArchive,
+		Default:  schema.RetentionTTL{StaleAfter: 24 * time.Hour, DeleteAfter: 72 * time.Hour},
+		Signals: map[schema.TelemetryType]schema.RetentionTTL{
+			schema.TelemetryTypeLog: {StaleAfter: 30 * 24 * time.Hour, DeleteAfter: 60 * 24 * time.Hour},
+		},
+	}
+	require.NoError(t, policy.Validate())
+
+	// The metric is stale after a day, the log keeps its longer TTL
+	result, err := retentionRepo.RunRetention(ctx, policy, t0.Add(48*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, &schema.RetentionResult{MarkedStale: 1}, result)
+
+	stale, total, err := retentionRepo.ListStaleTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "v1", stale[0].SchemaID)
+	require.Equal(t, schema.TelemetryTypeMetric, stale[0].TelemetryType)
+	require.True(t, stale[0].StaleSince.Equal(t0.Add(24*time.Hour)))
+	require.NotNil(t, stale[0].ExpiresAt)
+	require.True(t, stale[0].ExpiresAt.Equal(t0.Add(72*time.Hour)))
+
+	_, total, err = retentionRepo.ListStaleTelemetries(ctx, query.ListQueryParams{FilterType: "log", Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+
+	// Past the delete TTL the schema is archived, along with the entity and scope only it used
+	result, err = retentionRepo.RunRetention(ctx, policy, t0.Add(96*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, &schema.RetentionResult{Archived: 1, DeletedSchemas: 1, DeletedEntities: 1, DeletedScopes: 1}, result)
+
+	telemetry, err := repo.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Nil(t, telemetry)
+
+	entities, _, err := repo.ListEntities(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.Equal(t, "payments", entities[0].ID)
+
+	var archived string
+	require.NoError(t, repo.pool.GetConnection().QueryRowContext(ctx,
+		`SELECT CAST(snapshot AS TEXT) FROM telemetry_archive WHERE schema_id = ?`, "v1").Scan(&archived))
+	require.Contains(t, archived, "checkout.requests")
+
+	_, total, err = retentionRepo.ListStaleTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+}
+
+func TestRetention_SchemaSeenAgainIsNoLongerStale(t *testing.T) {
+	repo := setupTestDB(t)
+	retentionRepo := NewRetentionRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{paymentsLogTelemetry(t0)}))
+
+	policy := schema.DefaultRetentionPolicy()
+	result, err := retentionRepo.RunRetention(ctx, policy, t0.Add(8*24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, result.MarkedStale)
+
+	stale, _, err := retentionRepo.ListStaleTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Len(t, stale, 1)
+	require.Nil(t, stale[0].ExpiresAt)
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{paymentsLogTelemetry(t0.Add(8 * 24 * time.Hour))}))
+	_, total, err := retentionRepo.ListStaleTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+}
+
+func TestRetention_RemoveStaleTelemetry(t *testing.T) {
+	repo := setupTestDB(t)
+	retentionRepo := NewRetentionRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{paymentsLogTelemetry(t0)}))
+
+	_, err := retentionRepo.RemoveStaleTelemetry(ctx, "payments-log", schema.RetentionActionDelete)
+	require.ErrorIs(t, err, schema.ErrSchemaNotStale)
+
+	_, err = retentionRepo.RunRetention(ctx, schema.DefaultRetentionPolicy(), t0.Add(8*24*time.Hour))
+	require.NoError(t, err)
+
+	result, err := retentionRepo.RemoveStaleTelemetry(ctx, "payments-log", schema.RetentionActionDelete)
+	require.NoError(t, err)
+	require.Equal(t, &schema.RetentionResult{DeletedSchemas: 1, DeletedEntities: 1}, result)
+
+	var archived int
+	require.NoError(t, repo.pool.GetConnection().QueryRowContext(ctx, `SELECT COUNT(*) FROM telemetry_archive`).Scan(&archived))
+	require.Zero(t, archived)
+}
@@ -0,0 +1,467 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type ReviewRepository struct {
+	pool *ConnectionPool
+}
+
+func NewReviewRepository(pool *ConnectionPool) *ReviewRepository {
+	return &ReviewRepository{
+		pool: pool,
+	}
+}
+
+const reviewColumns = `id, schema_key, schema_id, signal_type, reason, state, detected_at,
+	reviewer, comment, reject_action, merged_into, decided_at`
+
+func scanReview(scan func(dest ...any) error) (schema.TelemetryReview, error) {
+	var review schema.TelemetryReview
+	var reviewer, comment, rejectAction, mergedInto sql.NullString
+	var decidedAt sql.NullTime
+	err := scan(
+		&review.ID,
+		&review.SchemaKey,
+		&review.SchemaID,
+		&review.TelemetryType,
+		&review.Reason,
+		&review.State,
+		&review.DetectedAt,
+		&reviewer,
+		&comment,
+		&rejectAction,
+		&mergedInto,
+		&decidedAt,
+	)
+	if err != nil {
+		return review, err
+	}
+	review.Reviewer = reviewer.String
+	review.Comment = comment.String
+	review.RejectAction = schema.RejectAction(rejectAction.String)
+	review.MergedInto = mergedInto.String
+	if decidedAt.Valid {
+		review.DecidedAt = &decidedAt.Time
+	}
+	return review, nil
+}
+
+// ListReviews returns the reviews matching the filter, the most recently detected first
+func (r *ReviewRepository) ListReviews(ctx context.Context, params query.ListQueryParams, filter query.ReviewFilter) ([]schema.TelemetryReview, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if filter.State != "" {
+		where += " AND state = ?"
+		args = append(args, filter.State)
+	}
+	if filter.SchemaKey != "" {
+		where += " AND schema_key = ?"
+		args = append(args, filter.SchemaKey)
+	}
+	if filter.Reason != "" {
+		where += " AND reason = ?"
+		args = append(args, filter.Reason)
+	}
+	if params.FilterType != "" && params.FilterType != "all" {
+		where += " AND lower(signal_type) = lower(?)"
+		args = append(args, params.FilterType)
+	}
+	if params.Search != "" {
+		where += " AND schema_key LIKE ?"
+		args = append(args, "%"+params.Search+"%")
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM telemetry_reviews WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count telemetry reviews: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.TelemetryReview{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM telemetry_reviews
+		WHERE 1=1`+where+`
+		ORDER BY detected_at DESC, id DESC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query telemetry reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []schema.TelemetryReview{}
+	for rows.Next() {
+		review, err := scanReview(rows.Scan)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan telemetry review row: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating telemetry review rows: %w", err)
+	}
+
+	return reviews, total, nil
+}
+
+// DecideReview approves, rejects or merges a pending review and records the decision in the
+// telemetry history. Telemetry sent under a merged key joins the existing key from then on, while the
+// variants already stored under it leave the catalog list and expire with the retention policy.
+func (r *ReviewRepository) DecideReview(ctx context.Context, id int64, decision schema.ReviewDecision) (*schema.TelemetryReview, error) {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	review, err := scanReview(tx.QueryRowContext(ctx, `SELECT `+reviewColumns+` FROM telemetry_reviews WHERE id = ? AND tenant = ?`, id, tenantName).Scan)
+	if err == sql.ErrNoRows {
+		return nil, schema.ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry review: %w", err)
+	}
+	if review.State != schema.ReviewStatePending {
+		return nil, fmt.Errorf("%w: review %d is %s", schema.ErrReviewDecided, id, review.State)
+	}
+	if err := decision.Validate(&review); err != nil {
+		return nil, err
+	}
+
+	if decision.Action == schema.ReviewActionMerge {
+		if err := checkMergeTarget(ctx, tx, &review, decision.MergeInto); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	review.State = decision.State()
+	review.Reviewer = decision.Author
+	review.Comment = decision.Comment
+	review.RejectAction = decision.RejectAction
+	review.MergedInto = decision.MergeInto
+	review.DecidedAt = &now
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE telemetry_reviews
+		SET state = ?, reviewer = ?, comment = NULLIF(?, ''), reject_action = NULLIF(?, ''),
+			merged_into = NULLIF(?, ''), decided_at = ?
+		WHERE id = ?
+	`, review.State, review.Reviewer, review.Comment, review.RejectAction, review.MergedInto, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update telemetry review: %w", err)
+	}
+
+	keys := []string{review.SchemaKey}
+	if review.MergedInto != "" {
+		keys = append(keys, review.MergedInto)
+	}
+	summary := decision.Summary(&review)
+	for _, key := range keys {
+		err := insertTelemetryHistory(ctx, tx, &schema.TelemetryHistory{
+			SchemaKey: key,
+			Timestamp: now,
+			Author:    &review.Reviewer,
+			Summary:   summary,
+			Status:    string(review.State),
+			SchemaID:  review.SchemaID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record review decision: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &review, nil
+}
+
+// checkMergeTarget checks the key a review is merged into exists with the same signal type
+func checkMergeTarget(ctx context.Context, tx *sql.Tx, review *schema.TelemetryReview, target string) error {
+	tenantFilter, args := tenantClause(ctx, "")
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM telemetry_schemas WHERE schema_key = ? AND signal_type = ?`+tenantFilter,
+		append([]any{target, review.TelemetryType}, args...)...).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check merge target: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("%w: no %s with schema key %s to merge into", schema.ErrInvalidReviewDecision, strings.ToLower(string(review.TelemetryType)), target)
+	}
+	return nil
+}
+
+// ListReviewNotifications returns the notifications of telemetry entering the review queue, the most recent first
+func (r *ReviewRepository) ListReviewNotifications(ctx context.Context, params query.ListQueryParams, window query.TimeWindow) ([]schema.ReviewNotification, int, error) {
+	where, args := tenantClause(ctx, "")
+
+	if !window.Since.IsZero() {
+		where += " AND created_at >= ?"
+		args = append(args, window.Since)
+	}
+	if !window.Until.IsZero() {
+		where += " AND created_at <= ?"
+		args = append(args, window.Until)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM review_notifications WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count review notifications: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.ReviewNotification{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, review_id, schema_key, reason, message, created_at
+		FROM review_notifications
+		WHERE 1=1`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query review notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []schema.ReviewNotification{}
+	for rows.Next() {
+		var n schema.ReviewNotification
+		if err := rows.Scan(&n.ID, &n.ReviewID, &n.SchemaKey, &n.Reason, &n.Message, &n.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review notification row: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating review notification rows: %w", err)
+	}
+
+	return notifications, total, nil
+}
+
+// ListReviewPolicies returns the policies created by rejecting telemetry with a drop or flag action
+func (r *ReviewRepository) ListReviewPolicies(ctx context.Context) ([]schema.Policy, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, args := tenantClause(ctx, "")
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT `+reviewColumns+`
+		FROM telemetry_reviews
+		WHERE state = ? AND reject_action IS NOT NULL`+tenantFilter+`
+		ORDER BY id
+	`, append([]any{schema.ReviewStateRejected}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query rejected reviews: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []schema.Policy{}
+	for rows.Next() {
+		review, err := scanReview(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan telemetry review row: %w", err)
+		}
+		if policy, ok := review.Policy(); ok {
+			policies = append(policies, policy)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating telemetry review rows: %w", err)
+	}
+	return policies, nil
+}
+
+// resolveMergedKeys renames the schemas sent under a schema key merged in review to the key it was
+// merged into. It must run before the schemas are registered.
+func resolveMergedKeys(ctx context.Context, tx *sql.Tx, telemetries []schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range telemetries {
+		t := &telemetries[i]
+		var target string
+		err := tx.QueryRowContext(ctx, `
+			SELECT merged_into
+			FROM telemetry_reviews
+			WHERE schema_key = ? AND signal_type = ? AND state = ? AND tenant = ?
+			ORDER BY decided_at DESC
+			LIMIT 1
+		`, t.SchemaKey, t.TelemetryType, schema.ReviewStateMerged, tenantName).Scan(&target)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query merged schema key: %w", err)
+		}
+		t.SchemaKey = target
+	}
+	return nil
+}
+
+// recordSchemaReview puts a schema key observed for the first time, or a new variant of an approved
+// key, in the review queue and records a notification. Keys without any review predate the review
+// queue and count as approved. It must run before the schema is inserted.
+func recordSchemaReview(ctx context.Context, tx *sql.Tx, telemetry *schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM telemetry_schemas WHERE schema_id = ?
+	`, telemetry.SchemaID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check schema existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	var knownKey bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM telemetry_schemas WHERE schema_key = ? AND signal_type = ? AND tenant = ?
+	`, telemetry.SchemaKey, telemetry.TelemetryType, tenantName).Scan(&knownKey)
+	if err != nil {
+		return fmt.Errorf("failed to check schema key existence: %w", err)
+	}
+
+	reason := schema.ReviewReasonNewKey
+	if knownKey {
+		var keyState string
+		err := tx.QueryRowContext(ctx, `
+			SELECT state
+			FROM telemetry_reviews
+			WHERE schema_key = ? AND signal_type = ? AND reason = ? AND tenant = ?
+			ORDER BY id DESC
+			LIMIT 1
+		`, telemetry.SchemaKey, telemetry.TelemetryType, schema.ReviewReasonNewKey, tenantName).Scan(&keyState)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to query schema key review: %w", err)
+		}
+		// Variants of a key still pending are covered by its review, and rejected keys are not reviewed again
+		if err == nil && schema.ReviewState(keyState) != schema.ReviewStateApproved {
+			return nil
+		}
+		reason = schema.ReviewReasonNewVariant
+	}
+
+	review := schema.TelemetryReview{
+		SchemaKey:     telemetry.SchemaKey,
+		SchemaID:      telemetry.SchemaID,
+		TelemetryType: telemetry.TelemetryType,
+		Reason:        reason,
+		State:         schema.ReviewStatePending,
+		DetectedAt:    telemetry.CreatedAt,
+	}
+	if review.DetectedAt.IsZero() {
+		review.DetectedAt = time.Now()
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO telemetry_reviews (schema_key, schema_id, signal_type, reason, state, detected_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, review.SchemaKey, review.SchemaID, review.TelemetryType, review.Reason, review.State, review.DetectedAt, tenantName).Scan(&review.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert telemetry review: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO review_notifications (review_id, schema_key, reason, message, created_at, tenant)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, review.ID, review.SchemaKey, review.Reason, review.Message(), review.DetectedAt, tenantName)
+	if err != nil {
+		return fmt.Errorf("failed to insert review notification: %w", err)
+	}
+	return nil
+}
+
+// reviewTelemetries sets the review state of telemetries: pending while the key or one of its
+// variants waits for a reviewer, otherwise the decision on the key, approved when it was never reviewed
+func reviewTelemetries(ctx context.Context, q execQuerier, telemetries []schema.Telemetry) error {
+	if len(telemetries) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(telemetries))
+	for _, t := range telemetries {
+		args = append(args, t.SchemaKey)
+	}
+	placeholders := "?" + strings.Repeat(", ?", len(telemetries)-1)
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	args = append(args, tenantArgs...)
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT schema_key, signal_type, reason, state
+		FROM telemetry_reviews
+		WHERE schema_key IN (`+placeholders+`)`+tenantFilter+`
+		ORDER BY id
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query telemetry reviews: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]schema.ReviewState)
+	for rows.Next() {
+		var key, signalType string
+		var reason schema.ReviewReason
+		var state schema.ReviewState
+		if err := rows.Scan(&key, &signalType, &reason, &state); err != nil {
+			return fmt.Errorf("failed to scan telemetry review row: %w", err)
+		}
+		id := signalType + "/" + key
+		switch {
+		case states[id] == schema.ReviewStatePending:
+		case state == schema.ReviewStatePending, reason == schema.ReviewReasonNewKey:
+			states[id] = state
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating telemetry review rows: %w", err)
+	}
+
+	for i := range telemetries {
+		state, ok := states[string(telemetries[i].TelemetryType)+"/"+telemetries[i].SchemaKey]
+		if !ok {
+			state = schema.ReviewStateApproved
+		}
+		telemetries[i].ReviewState = state
+	}
+	return nil
+}
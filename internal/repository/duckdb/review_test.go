@@ -0,0 +1,176 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func pendingReviews(t *testing.T, reviewRepo *ReviewRepository) []schema.TelemetryReview {
+	reviews, _, err := reviewRepo.ListReviews(context.Background(), query.ListQueryParams{Page: 1, PageSize: 50}, query.ReviewFilter{State: string(schema.ReviewStatePending)})
+	require.NoError(t, err)
+	return reviews
+}
+
+func TestReview_ApproveAndNewVariant(t *testing.T) {
+	repo := setupTestDB(t)
+	reviewRepo := NewReviewRepository(repo.pool)
+	historyRepo := NewTelemetryHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+
+	reviews := pendingReviews(t, reviewRepo)
+	require.Len(t, reviews, 1)
+	require.Equal(t, "checkout.requests", reviews[0].SchemaKey)
+	require.Equal(t, schema.ReviewReasonNewKey, reviews[0].Reason)
+
+	notifications, total, err := reviewRepo.ListReviewNotifications(ctx, query.ListQueryParams{Page: 1, PageSize: 10}, query.TimeWindow{})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, reviews[0].ID, notifications[0].ReviewID)
+	require.Equal(t, "New metric checkout.requests is waiting for review", notifications[0].Message)
+
+	telemetry, err := repo.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, schema.ReviewStatePending, telemetry.ReviewState)
+
+	// Another variant of a pending key is covered by the review of the key
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"http.method", "http.route"}, t0.Add(time.Hour)),
+	}))
+	require.Len(t, pendingReviews(t, reviewRepo), 1)
+
+	review, err := reviewRepo.DecideReview(ctx, reviews[0].ID, schema.ReviewDecision{Action: schema.ReviewActionApprove, Author: "alice", Comment: "looks good"})
+	require.NoError(t, err)
+	require.Equal(t, schema.ReviewStateApproved, review.State)
+	require.NotNil(t, review.DecidedAt)
+
+	_, err = reviewRepo.DecideReview(ctx, reviews[0].ID, schema.ReviewDecision{Action: schema.ReviewActionApprove, Author: "alice"})
+	require.ErrorIs(t, err, schema.ErrReviewDecided)
+	_, err = reviewRepo.DecideReview(ctx, 999, schema.ReviewDecision{Action: schema.ReviewActionApprove, Author: "alice"})
+	require.ErrorIs(t, err, schema.ErrReviewNotFound)
+
+	history, _, err := historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, "Approved new schema key checkout.requests: looks good", history[0].Summary)
+	require.Equal(t, string(schema.ReviewStateApproved), history[0].Status)
+	require.Equal(t, "alice", *history[0].Author)
+
+	telemetries, _, err := repo.ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, schema.ReviewStateApproved, telemetries[0].ReviewState)
+
+	// A new variant of an approved key waits for review again
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v3", "1.2.0", []string{"http.method", "http.status_code"}, t0.Add(2*time.Hour)),
+	}))
+	reviews = pendingReviews(t, reviewRepo)
+	require.Len(t, reviews, 1)
+	require.Equal(t, "v3", reviews[0].SchemaID)
+	require.Equal(t, schema.ReviewReasonNewVariant, reviews[0].Reason)
+
+	// A policy would drop or flag the approved variants of the key too
+	_, err = reviewRepo.DecideReview(ctx, reviews[0].ID, schema.ReviewDecision{Action: schema.ReviewActionReject, Author: "alice", RejectAction: schema.RejectActionDrop})
+	require.ErrorIs(t, err, schema.ErrInvalidReviewDecision)
+}
+
+func TestReview_RejectCreatesPolicy(t *testing.T) {
+	repo := setupTestDB(t)
+	reviewRepo := NewReviewRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("d1", "debug.requests", &schema.Producer{Name: "checkout"}, t0),
+		producerTelemetry("c1", "checkout.latency", &schema.Producer{Name: "checkout"}, t0),
+	}))
+
+	reviews := pendingReviews(t, reviewRepo)
+	require.Len(t, reviews, 2)
+	byKey := make(map[string]schema.TelemetryReview)
+	for _, review := range reviews {
+		byKey[review.SchemaKey] = review
+	}
+
+	_, err := reviewRepo.DecideReview(ctx, byKey["debug.requests"].ID, schema.ReviewDecision{Action: schema.ReviewActionReject, Author: "bob", RejectAction: schema.RejectActionDrop})
+	require.NoError(t, err)
+	_, err = reviewRepo.DecideReview(ctx, byKey["checkout.latency"].ID, schema.ReviewDecision{Action: schema.ReviewActionReject, Author: "bob"})
+	require.NoError(t, err)
+
+	policies, err := reviewRepo.ListReviewPolicies(ctx)
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	require.Equal(t, schema.PolicyModeDrop, policies[0].Mode)
+	require.Equal(t, "debug.requests", policies[0].Match)
+	require.True(t, policies[0].Deny)
+
+	// Rejected keys are not queued again
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("c2", "checkout.latency", &schema.Producer{Name: "checkout", Version: "2"}, t0.Add(time.Hour)),
+	}))
+	require.Empty(t, pendingReviews(t, reviewRepo))
+}
+
+func TestReview_MergeIntoExistingKey(t *testing.T) {
+	repo := setupTestDB(t)
+	reviewRepo := NewReviewRepository(repo.pool)
+	historyRepo := NewTelemetryHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("a1", "checkout.requests", &schema.Producer{Name: "checkout"}, t0),
+		producerTelemetry("b1", "checkout_requests", &schema.Producer{Name: "checkout"}, t0),
+	}))
+
+	var typo schema.TelemetryReview
+	for _, review := range pendingReviews(t, reviewRepo) {
+		if review.SchemaKey == "checkout_requests" {
+			typo = review
+			continue
+		}
+		_, err := reviewRepo.DecideReview(ctx, review.ID, schema.ReviewDecision{Action: schema.ReviewActionApprove, Author: "carol"})
+		require.NoError(t, err)
+	}
+
+	_, err := reviewRepo.DecideReview(ctx, typo.ID, schema.ReviewDecision{Action: schema.ReviewActionMerge, Author: "carol", MergeInto: "unknown"})
+	require.ErrorIs(t, err, schema.ErrInvalidReviewDecision)
+
+	review, err := reviewRepo.DecideReview(ctx, typo.ID, schema.ReviewDecision{Action: schema.ReviewActionMerge, Author: "carol", MergeInto: "checkout.requests"})
+	require.NoError(t, err)
+	require.Equal(t, schema.ReviewStateMerged, review.State)
+	require.Equal(t, "checkout.requests", review.MergedInto)
+
+	// The merged key leaves the catalog list
+	telemetries, total, err := repo.ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "checkout.requests", telemetries[0].SchemaKey)
+
+	history, _, err := historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, "Merged new schema key checkout_requests into checkout.requests", history[0].Summary)
+	require.Equal(t, "carol", *history[0].Author)
+
+	// Telemetry sent under the merged key joins the existing key from then on
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		producerTelemetry("b2", "checkout_requests", &schema.Producer{Name: "checkout", Version: "2"}, t0.Add(time.Hour)),
+	}))
+	schemas, total, err := repo.ListTelemetrySchemas(ctx, "checkout.requests", query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, total, "%v", schemas)
+
+	reviews := pendingReviews(t, reviewRepo)
+	require.Len(t, reviews, 1)
+	require.Equal(t, "b2", reviews[0].SchemaID)
+	require.Equal(t, "checkout.requests", reviews[0].SchemaKey)
+	require.Equal(t, schema.ReviewReasonNewVariant, reviews[0].Reason)
+}
@@ -0,0 +1,91 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestAssignTelemetrySchemaVersion_Lifecycle(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+
+	err := repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "one"})
+	require.ErrorIs(t, err, schema.ErrInvalidVersion)
+
+	err = repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "unknown", Version: "1.0.0"})
+	require.ErrorIs(t, err, schema.ErrSchemaNotFound)
+
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.0", Status: schema.VersionStatusDraft,
+	}))
+	// A draft can still change version
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusActive, Reason: "initial release",
+	}))
+
+	err = repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "1.0.2"})
+	require.ErrorIs(t, err, schema.ErrVersionConflict)
+
+	err = repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusRetired,
+	})
+	require.ErrorIs(t, err, schema.ErrInvalidStatusTransition)
+
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusDeprecated, ReplacedBy: "v2",
+	}))
+
+	telemetrySchema, err := repo.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.1", telemetrySchema.Version)
+	require.Equal(t, schema.VersionStatusDeprecated, telemetrySchema.Status)
+	require.NotNil(t, telemetrySchema.DeprecatedAt)
+	require.Equal(t, "v2", telemetrySchema.ReplacedBy)
+}
+
+func TestAssignTelemetrySchemaVersion_Compatibility(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method", "http.route"}, t0),
+		checkoutTelemetry("v2", "1.1.0", []string{"http.method", "http.route", "payment.provider"}, t0.Add(time.Hour)),
+		checkoutTelemetry("v3", "1.2.0", []string{"http.method"}, t0.Add(2*time.Hour)),
+	}))
+
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "1.0.0"}))
+
+	err := repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v2", Version: "1.0.0"})
+	require.ErrorIs(t, err, schema.ErrVersionConflict)
+
+	// Adding an attribute is a compatible minor bump
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v2", Version: "1.1.0"}))
+
+	// Removing attributes needs a major bump
+	err = repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v3", Version: "1.2.0"})
+	require.ErrorIs(t, err, schema.ErrIncompatibleVersion)
+	require.Contains(t, err.Error(), "attribute http.route removed")
+
+	// Drafts are not checked until they are activated
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "1.2.0", Status: schema.VersionStatusDraft,
+	}))
+	err = repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "1.2.0", Status: schema.VersionStatusActive,
+	})
+	require.ErrorIs(t, err, schema.ErrIncompatibleVersion)
+
+	require.NoError(t, repo.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "2.0.0", Status: schema.VersionStatusActive,
+	}))
+}
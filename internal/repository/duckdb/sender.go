@@ -0,0 +1,38 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// loadSenders returns the clients that sent any variant of a schema key, most recent first
+func loadSenders(ctx context.Context, q execQuerier, schemaKey string) ([]schema.Sender, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+	rows, err := q.QueryContext(ctx, `
+		SELECT ss.identity, MIN(ss.first_seen), MAX(ss.last_seen), CAST(SUM(ss.seen_count) AS BIGINT)
+		FROM schema_senders ss
+		JOIN telemetry_schemas ts ON ts.schema_id = ss.schema_id
+		WHERE ts.schema_key = ?`+tenantFilter+`
+		GROUP BY ss.identity
+		ORDER BY MAX(ss.last_seen) DESC, ss.identity
+	`, append([]any{schemaKey}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema senders: %w", err)
+	}
+	defer rows.Close()
+
+	var senders []schema.Sender
+	for rows.Next() {
+		var sender schema.Sender
+		if err := rows.Scan(&sender.Identity, &sender.FirstSeen, &sender.LastSeen, &sender.SeenCount); err != nil {
+			return nil, fmt.Errorf("failed to scan schema sender: %w", err)
+		}
+		senders = append(senders, sender)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema sender rows: %w", err)
+	}
+	return senders, nil
+}
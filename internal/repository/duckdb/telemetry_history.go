@@ -3,6 +3,7 @@ package duckdb
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -20,46 +21,78 @@ func NewTelemetryHistoryRepository(pool *ConnectionPool) *TelemetryHistoryReposi
 }
 
 func (r *TelemetryHistoryRepository) InsertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error {
-	db := r.pool.GetConnection()
+	return insertTelemetryHistory(ctx, r.pool.GetConnection(), h)
+}
+
+// rowQuerier is satisfied by both *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func insertTelemetryHistory(ctx context.Context, q rowQuerier, h *schema.TelemetryHistory) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	var author sql.NullString
+	if h.Author != nil {
+		author = sql.NullString{String: *h.Author, Valid: true}
+	}
+
+	var diff sql.NullString
+	if h.Diff != nil {
+		data, err := json.Marshal(h.Diff)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema diff: %w", err)
+		}
+		diff = sql.NullString{String: string(data), Valid: true}
+	}
+
 	query := `
 		INSERT INTO telemetry_history (
-			schema_key, version, timestamp, author, summary, status, snapshot, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			schema_key, version, timestamp, author, summary, status, snapshot, schema_id, diff, created_at, updated_at, tenant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
 		RETURNING id, created_at, updated_at
 	`
-	row := db.QueryRowContext(ctx, query,
+	row := q.QueryRowContext(ctx, query,
 		h.SchemaKey,
 		h.Version,
 		h.Timestamp,
-		h.Author,
+		author,
 		h.Summary,
 		h.Status,
 		h.Snapshot,
+		h.SchemaID,
+		diff,
 		time.Now(),
 		time.Now(),
+		tenantName,
 	)
 	return row.Scan(&h.Id, &h.CreatedAt, &h.UpdatedAt)
 }
 
 func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, telemetryID string, page, pageSize int) ([]schema.TelemetryHistory, int, error) {
 	db := r.pool.GetConnection()
+	tenantFilter, args := tenantClause(ctx, "")
+	args = append([]any{telemetryID}, args...)
 
 	// Get total count
-	countQuery := `SELECT COUNT(*) FROM telemetry_history WHERE schema_key = ?`
+	countQuery := `SELECT COUNT(*) FROM telemetry_history WHERE schema_key = ?` + tenantFilter
 	total := 0
-	if err := db.QueryRowContext(ctx, countQuery, telemetryID).Scan(&total); err != nil {
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count telemetry_history: %w", err)
 	}
 
 	// Get paginated results
 	query := `
-		SELECT id, schema_key, version, timestamp, author, summary, status, snapshot, created_at, updated_at
+		SELECT id, schema_key, version, timestamp, author, summary, status, snapshot, schema_id, diff, created_at, updated_at
 		FROM telemetry_history
-		WHERE schema_key = ?
+		WHERE schema_key = ?` + tenantFilter + `
 		ORDER BY timestamp DESC
 		LIMIT ? OFFSET ?
 	`
-	rows, err := db.QueryContext(ctx, query, telemetryID, pageSize, (page-1)*pageSize)
+	rows, err := db.QueryContext(ctx, query, append(args, pageSize, (page-1)*pageSize)...)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to query telemetry_history: %w", err)
 	}
@@ -68,7 +101,7 @@ func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, t
 	histories := []schema.TelemetryHistory{}
 	for rows.Next() {
 		var h schema.TelemetryHistory
-		var author sql.NullString
+		var author, schemaID, diff sql.NullString
 		if err := rows.Scan(
 			&h.Id,
 			&h.SchemaKey,
@@ -78,6 +111,8 @@ func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, t
 			&h.Summary,
 			&h.Status,
 			&h.Snapshot,
+			&schemaID,
+			&diff,
 			&h.CreatedAt,
 			&h.UpdatedAt,
 		); err != nil {
@@ -88,6 +123,13 @@ func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, t
 		} else {
 			h.Author = nil
 		}
+		h.SchemaID = schemaID.String
+		if diff.Valid {
+			h.Diff = &schema.SchemaDiff{}
+			if err := json.Unmarshal([]byte(diff.String), h.Diff); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal schema diff: %w", err)
+			}
+		}
 		histories = append(histories, h)
 	}
 	if err := rows.Err(); err != nil {
@@ -95,3 +137,149 @@ func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, t
 	}
 	return histories, total, nil
 }
+
+// GetSchemaIDAt returns the variant of the schema key that was current at the given time, or an empty
+// string when the key was not seen yet. It follows the variants recorded in the history snapshots and
+// falls back to the latest variant first seen by then, which covers keys with a single variant.
+func (r *TelemetryHistoryRepository) GetSchemaIDAt(ctx context.Context, schemaKey string, at time.Time) (string, error) {
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	args := append([]any{schemaKey, at}, tenantArgs...)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT schema_id, snapshot
+		FROM telemetry_history
+		WHERE schema_key = ? AND timestamp <= ?`+tenantFilter+`
+		ORDER BY timestamp DESC, id DESC
+	`, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to query telemetry_history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h schema.TelemetryHistory
+		var schemaID sql.NullString
+		if err := rows.Scan(&schemaID, &h.Snapshot); err != nil {
+			return "", fmt.Errorf("failed to scan telemetry_history row: %w", err)
+		}
+		h.SchemaID = schemaID.String
+		if id := h.SnapshotSchemaID(); id != "" {
+			return id, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating telemetry_history rows: %w", err)
+	}
+
+	var schemaID string
+	err = db.QueryRowContext(ctx, `
+		SELECT schema_id
+		FROM telemetry_schemas
+		WHERE schema_key = ? AND created_at <= ?`+tenantFilter+`
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, args...).Scan(&schemaID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query schema variant: %w", err)
+	}
+	return schemaID, nil
+}
+
+// recordSchemaVariant writes a history entry with the diff against the previous variant when a
+// schema ID not seen before appears for an existing schema key. It must run before the schema is inserted.
+func recordSchemaVariant(ctx context.Context, tx *sql.Tx, telemetry *schema.Telemetry) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM telemetry_schemas WHERE schema_id = ?
+	`, telemetry.SchemaID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check schema existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	previous := schema.Telemetry{}
+	var unit, metricType, temporality, spanKind, logSeverityText, logEventName, profileSampleUnit sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT schema_id, unit, metric_type, temporality, span_kind, log_severity_text, log_event_name, profile_sample_unit
+		FROM telemetry_schemas
+		WHERE schema_key = ? AND signal_type = ?`+tenantFilter+`
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, append([]any{telemetry.SchemaKey, telemetry.TelemetryType}, tenantArgs...)...).Scan(
+		&previous.SchemaID,
+		&unit,
+		&metricType,
+		&temporality,
+		&spanKind,
+		&logSeverityText,
+		&logEventName,
+		&profileSampleUnit,
+	)
+	if err == sql.ErrNoRows {
+		// First variant of this schema key, nothing to compare against
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query previous schema variant: %w", err)
+	}
+	previous.MetricUnit = unit.String
+	previous.MetricType = schema.MetricType(metricType.String)
+	previous.MetricTemporality = schema.MetricTemporality(temporality.String)
+	previous.SpanKind = schema.SpanKind(spanKind.String)
+	previous.LogSeverityText = logSeverityText.String
+	previous.LogEventName = logEventName.String
+	previous.ProfileSampleUnit = profileSampleUnit.String
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT name, type, source
+		FROM schema_attributes
+		WHERE schema_id = ?
+	`, previous.SchemaID)
+	if err != nil {
+		return fmt.Errorf("failed to query previous schema attributes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attr schema.Attribute
+		if err := rows.Scan(&attr.Name, &attr.Type, &attr.Source); err != nil {
+			return fmt.Errorf("failed to scan previous schema attribute: %w", err)
+		}
+		previous.Attributes = append(previous.Attributes, attr)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating previous schema attributes: %w", err)
+	}
+
+	// The snapshot only describes the schema itself, not where it was seen
+	snapshotTelemetry := *telemetry
+	snapshotTelemetry.Entities = nil
+	snapshotTelemetry.Scope = nil
+	snapshotTelemetry.Producers = nil
+	snapshotTelemetry.Senders = nil
+	snapshot, err := json.Marshal(snapshotTelemetry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema snapshot: %w", err)
+	}
+
+	diff := schema.DiffTelemetry(previous, *telemetry)
+	return insertTelemetryHistory(ctx, tx, &schema.TelemetryHistory{
+		SchemaKey: telemetry.SchemaKey,
+		Timestamp: telemetry.CreatedAt,
+		Summary:   diff.Summary(),
+		Status:    schema.TelemetryHistoryStatusDetected,
+		Snapshot:  snapshot,
+		SchemaID:  telemetry.SchemaID,
+		Diff:      &diff,
+	})
+}
@@ -0,0 +1,116 @@
+package duckdb
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestTelemetryHistory_RecordsNewSchemaVariant(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewTelemetryHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+	// Seeing the first variant again is not a change
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0.Add(time.Minute)),
+	}))
+
+	histories, total, err := historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 0, total)
+	require.Empty(t, histories)
+
+	v2 := checkoutTelemetry("v2", "1.1.0", []string{"payment.provider"}, t1)
+	v2.MetricUnit = "ms"
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{v2}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{v2}))
+
+	histories, total, err = historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, histories, 1)
+
+	history := histories[0]
+	require.Equal(t, "v2", history.SchemaID)
+	require.Equal(t, schema.TelemetryHistoryStatusDetected, history.Status)
+	require.True(t, t1.Equal(history.Timestamp))
+	require.Equal(t, `Schema changed: 1 attribute added, 1 attribute removed, unit changed from "" to "ms"`, history.Summary)
+
+	require.NotNil(t, history.Diff)
+	require.Equal(t, "v1", history.Diff.FromSchemaID)
+	require.Equal(t, "v2", history.Diff.ToSchemaID)
+	require.Len(t, history.Diff.AddedAttributes, 1)
+	require.Equal(t, "payment.provider", history.Diff.AddedAttributes[0].Name)
+	require.Len(t, history.Diff.RemovedAttributes, 1)
+	require.Equal(t, "http.method", history.Diff.RemovedAttributes[0].Name)
+
+	var snapshot schema.Telemetry
+	require.NoError(t, json.Unmarshal(history.Snapshot, &snapshot))
+	require.Equal(t, "v2", snapshot.SchemaID)
+	require.Nil(t, snapshot.Entities)
+}
+
+func TestTelemetryHistory_InsertWithoutDiff(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewTelemetryHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	history := &schema.TelemetryHistory{
+		SchemaKey: "checkout.requests",
+		Version:   "1.0.0",
+		Timestamp: time.Now(),
+		Summary:   "Assigned version 1.0.0",
+	}
+	require.NoError(t, historyRepo.InsertTelemetryHistory(ctx, history))
+	require.NotZero(t, history.Id)
+
+	histories, _, err := historyRepo.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Len(t, histories, 1)
+	require.Empty(t, histories[0].SchemaID)
+	require.Nil(t, histories[0].Diff)
+}
+
+func TestTelemetryHistory_GetSchemaIDAt(t *testing.T) {
+	repo := setupTestDB(t)
+	historyRepo := NewTelemetryHistoryRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+		checkoutTelemetry("v2", "1.1.0", []string{"http.method", "payment.provider"}, t1),
+	}))
+
+	schemaID, err := historyRepo.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, schemaID)
+
+	schemaID, err = historyRepo.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, "v1", schemaID)
+
+	schemaID, err = historyRepo.GetSchemaIDAt(ctx, "checkout.requests", t1.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, "v2", schemaID)
+
+	telemetrySchema, err := repo.GetTelemetrySchema(ctx, "v2")
+	require.NoError(t, err)
+	require.Equal(t, "checkout.requests", telemetrySchema.SchemaKey)
+	require.Equal(t, schema.MetricTypeSum, telemetrySchema.MetricType)
+}
@@ -9,21 +9,31 @@ import (
 
 	"github.com/tallycat/tallycat/internal/repository/query"
 	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
 type TelemetrySchemaRepository struct {
 	pool *ConnectionPool
+	// ownershipModel resolves the owners of registered schemas, nil when ownership is only
+	// assigned explicitly
+	ownershipModel *schema.OwnershipModel
 }
 
-func NewTelemetrySchemaRepository(pool *ConnectionPool) *TelemetrySchemaRepository {
+func NewTelemetrySchemaRepository(pool *ConnectionPool, ownershipModel *schema.OwnershipModel) *TelemetrySchemaRepository {
 	return &TelemetrySchemaRepository{
-		pool: pool,
+		pool:           pool,
+		ownershipModel: ownershipModel,
 	}
 }
 
 func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context, schemas []schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
 	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -37,8 +47,8 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 			log_severity_number, log_severity_text, log_body, log_flags, log_trace_id, log_span_id, log_event_name, log_dropped_attributes_count,
 			span_kind, span_name, span_id, span_trace_id,
 			profile_sample_aggregation_temporality, profile_sample_unit,
-			note, protocol, seen_count, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			note, protocol, seen_count, created_at, updated_at, tenant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT (schema_id) DO UPDATE SET
 			seen_count = telemetry_schemas.seen_count + excluded.seen_count,
 			updated_at = excluded.updated_at
@@ -59,7 +69,22 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 	}
 	defer attrStmt.Close()
 
+	// Telemetry sent under a schema key merged in review joins the key it was merged into
+	if err := resolveMergedKeys(ctx, tx, schemas); err != nil {
+		return err
+	}
+
 	for _, schema := range schemas {
+		// Queue new schema keys and new variants of approved keys for review
+		if err := recordSchemaReview(ctx, tx, &schema); err != nil {
+			return err
+		}
+
+		// Compare a new variant of an existing schema key against the previous one
+		if err := recordSchemaVariant(ctx, tx, &schema); err != nil {
+			return err
+		}
+
 		_, err = schemaStmt.ExecContext(ctx,
 			schema.SchemaID,
 			schema.SchemaKey,
@@ -89,11 +114,17 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 			schema.SeenCount,
 			schema.CreatedAt,
 			schema.UpdatedAt,
+			tenantName,
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert schema: %w", err)
 		}
 
+		// A schema seen again is no longer stale
+		if _, err = tx.ExecContext(ctx, `DELETE FROM stale_schemas WHERE schema_id = ?`, schema.SchemaID); err != nil {
+			return fmt.Errorf("failed to unmark stale schema: %w", err)
+		}
+
 		for _, attr := range schema.Attributes {
 			_, err = attrStmt.ExecContext(ctx,
 				schema.SchemaID,
@@ -110,35 +141,92 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 		for _, entity := range schema.Entities {
 			// First insert the entity itself
 			_, err = tx.ExecContext(ctx, `
-				INSERT INTO telemetry_entities (entity_id, entity_type, first_seen, last_seen)
-				VALUES (?, ?, ?, ?)
+				INSERT INTO telemetry_entities (entity_id, entity_type, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?)
 				ON CONFLICT (entity_id) DO UPDATE SET
 					last_seen = excluded.last_seen
 				WHERE excluded.last_seen > telemetry_entities.last_seen
-			`, entity.ID, entity.Type, entity.FirstSeen, entity.LastSeen)
+			`, entity.ID, entity.Type, entity.FirstSeen, entity.LastSeen, tenantName)
 			if err != nil {
 				return fmt.Errorf("failed to insert entity: %w", err)
 			}
 
-			// Insert entity attributes
-			for attrName, attrValue := range entity.Attributes {
+			// Store the latest entity attributes, recording any value that changed
+			if err := upsertEntityAttributes(ctx, tx, entity); err != nil {
+				return err
+			}
+
+			// Record the schema appearing on the entity before linking it
+			if err := recordEntitySchemaChange(ctx, tx, entity, &schema); err != nil {
+				return err
+			}
+
+			// Link schema to entity
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO schema_entities (schema_id, entity_id, first_seen, last_seen, seen_count)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (schema_id, entity_id) DO UPDATE SET
+					first_seen = LEAST(COALESCE(schema_entities.first_seen, excluded.first_seen), excluded.first_seen),
+					last_seen = GREATEST(COALESCE(schema_entities.last_seen, excluded.last_seen), excluded.last_seen),
+					seen_count = COALESCE(schema_entities.seen_count, 0) + excluded.seen_count
+			`, schema.SchemaID, entity.ID, schema.UpdatedAt, schema.UpdatedAt, schema.SeenCount)
+			if err != nil {
+				return fmt.Errorf("failed to link schema to entity: %w", err)
+			}
+		}
+
+		// Insert producers
+		for _, producer := range schema.Producers {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO telemetry_producers (producer_id, name, namespace, version, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (producer_id) DO UPDATE SET
+					last_seen = excluded.last_seen
+				WHERE excluded.last_seen > telemetry_producers.last_seen
+			`, producer.ID, producer.Name, producer.Namespace, producer.Version, producer.FirstSeen, producer.LastSeen, tenantName)
+			if err != nil {
+				return fmt.Errorf("failed to insert producer: %w", err)
+			}
+
+			for _, instance := range producer.Instances {
 				_, err = tx.ExecContext(ctx, `
-					INSERT INTO entity_attributes (entity_id, name, value, type)
+					INSERT INTO producer_instances (producer_id, instance_id, first_seen, last_seen)
 					VALUES (?, ?, ?, ?)
-				`, entity.ID, attrName, fmt.Sprintf("%v", attrValue), "string")
+					ON CONFLICT (producer_id, instance_id) DO UPDATE SET
+						first_seen = LEAST(producer_instances.first_seen, excluded.first_seen),
+						last_seen = GREATEST(producer_instances.last_seen, excluded.last_seen)
+				`, producer.ID, instance.ID, instance.FirstSeen, instance.LastSeen)
 				if err != nil {
-					return fmt.Errorf("failed to insert entity attribute: %w", err)
+					return fmt.Errorf("failed to insert producer instance: %w", err)
 				}
 			}
 
-			// Link schema to entity
+			// Link schema to producer
 			_, err = tx.ExecContext(ctx, `
-				INSERT INTO schema_entities (schema_id, entity_id)
-				VALUES (?, ?)
-				ON CONFLICT (schema_id, entity_id) DO NOTHING
-			`, schema.SchemaID, entity.ID)
+				INSERT INTO schema_producers (schema_id, producer_id, first_seen, last_seen, seen_count)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (schema_id, producer_id) DO UPDATE SET
+					first_seen = LEAST(COALESCE(schema_producers.first_seen, excluded.first_seen), excluded.first_seen),
+					last_seen = GREATEST(COALESCE(schema_producers.last_seen, excluded.last_seen), excluded.last_seen),
+					seen_count = COALESCE(schema_producers.seen_count, 0) + excluded.seen_count
+			`, schema.SchemaID, producer.ID, schema.UpdatedAt, schema.UpdatedAt, schema.SeenCount)
 			if err != nil {
-				return fmt.Errorf("failed to link schema to entity: %w", err)
+				return fmt.Errorf("failed to link schema to producer: %w", err)
+			}
+		}
+
+		// Record the client that sent the schema, when it presented a verified certificate
+		if schema.Sender != "" {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO schema_senders (schema_id, identity, first_seen, last_seen, seen_count)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (schema_id, identity) DO UPDATE SET
+					first_seen = LEAST(schema_senders.first_seen, excluded.first_seen),
+					last_seen = GREATEST(schema_senders.last_seen, excluded.last_seen),
+					seen_count = COALESCE(schema_senders.seen_count, 0) + excluded.seen_count
+			`, schema.SchemaID, schema.Sender, schema.UpdatedAt, schema.UpdatedAt, schema.SeenCount)
+			if err != nil {
+				return fmt.Errorf("failed to record schema sender: %w", err)
 			}
 		}
 
@@ -147,12 +235,12 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 			scope := schema.Scope
 			// First insert the scope itself
 			_, err = tx.ExecContext(ctx, `
-				INSERT INTO telemetry_scopes (scope_id, name, version, schema_url, first_seen, last_seen)
-				VALUES (?, ?, ?, ?, ?, ?)
+				INSERT INTO telemetry_scopes (scope_id, name, version, schema_url, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
 				ON CONFLICT (scope_id) DO UPDATE SET
 					last_seen = excluded.last_seen
 				WHERE excluded.last_seen > telemetry_scopes.last_seen
-			`, scope.ID, scope.Name, scope.Version, scope.SchemaURL, scope.FirstSeen, scope.LastSeen)
+			`, scope.ID, scope.Name, scope.Version, scope.SchemaURL, scope.FirstSeen, scope.LastSeen, tenantName)
 			if err != nil {
 				return fmt.Errorf("failed to insert scope: %w", err)
 			}
@@ -170,14 +258,44 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 
 			// Link schema to scope
 			_, err = tx.ExecContext(ctx, `
-				INSERT INTO schema_scopes (schema_id, scope_id)
-				VALUES (?, ?)
-				ON CONFLICT (schema_id, scope_id) DO NOTHING
-			`, schema.SchemaID, scope.ID)
+				INSERT INTO schema_scopes (schema_id, scope_id, first_seen, last_seen, seen_count)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (schema_id, scope_id) DO UPDATE SET
+					first_seen = LEAST(COALESCE(schema_scopes.first_seen, excluded.first_seen), excluded.first_seen),
+					last_seen = GREATEST(COALESCE(schema_scopes.last_seen, excluded.last_seen), excluded.last_seen),
+					seen_count = COALESCE(schema_scopes.seen_count, 0) + excluded.seen_count
+			`, schema.SchemaID, scope.ID, schema.UpdatedAt, schema.UpdatedAt, schema.SeenCount)
 			if err != nil {
 				return fmt.Errorf("failed to link schema to scope: %w", err)
 			}
 		}
+
+		if err := recordUsage(ctx, tx, &schema); err != nil {
+			return err
+		}
+
+		// Record the metric series seen, once per entity of the resource emitting them
+		for _, series := range schema.Series {
+			entityIDs := series.EntityIDs
+			if len(entityIDs) == 0 {
+				entityIDs = []string{""}
+			}
+			for _, entityID := range entityIDs {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO metric_series (schema_id, series_id, entity_id, first_seen, last_seen)
+					VALUES (?, ?, ?, ?, ?)
+					ON CONFLICT (schema_id, series_id, entity_id) DO UPDATE SET
+						last_seen = GREATEST(metric_series.last_seen, excluded.last_seen)
+				`, schema.SchemaID, series.ID, entityID, schema.UpdatedAt, schema.UpdatedAt)
+				if err != nil {
+					return fmt.Errorf("failed to record metric series: %w", err)
+				}
+			}
+		}
+	}
+
+	if err := resolveRegisteredOwners(ctx, tx, r.ownershipModel, schemas); err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -200,8 +318,14 @@ func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context
 }
 
 func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.Telemetry, int, error) {
-	var args []any
-	where := ""
+	// Keys merged in review are left out, their telemetry joins the key they were merged into
+	where, args := tenantClause(ctx, "t")
+	where += `
+		AND NOT EXISTS (
+			SELECT 1 FROM telemetry_reviews mr
+			WHERE mr.tenant = t.tenant AND mr.schema_key = t.schema_key AND mr.signal_type = t.signal_type AND mr.state = ?
+		)`
+	args = append(args, schema.ReviewStateMerged)
 
 	if params.FilterType != "" && params.FilterType != "all" {
 		where += " AND t.signal_type = ?"
@@ -214,8 +338,14 @@ func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params
 		args = append(args, searchTerm, searchTerm, searchTerm, searchTerm)
 	}
 
+	if params.Owner != "" {
+		clause, ownerArgs := ownerClause("t.tenant", "t.schema_key", schema.OwnedKindTelemetry, params.Owner)
+		where += clause
+		args = append(args, ownerArgs...)
+	}
+
 	countQuery := `
-		SELECT COUNT(DISTINCT (t.signal_type, t.schema_key))
+		SELECT COUNT(DISTINCT (t.tenant, t.signal_type, t.schema_key))
 		FROM telemetry_schemas t
 		WHERE 1=1` + where
 
@@ -270,16 +400,17 @@ func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params
 				t.seen_count,
 				t.created_at,
 				t.updated_at,
-				COUNT(*) OVER (PARTITION BY t.signal_type, t.schema_key) as version_count,
+				t.tenant,
+				COUNT(*) OVER (PARTITION BY t.tenant, t.signal_type, t.schema_key) as version_count,
 				ROW_NUMBER() OVER (
-					PARTITION BY t.signal_type, t.schema_key 
+					PARTITION BY t.tenant, t.signal_type, t.schema_key 
 					ORDER BY t.updated_at DESC
 				) as rn
 			FROM telemetry_schemas t
 			WHERE 1=1` + where + `
 		)
 		SELECT 
-			schema_id, schema_version, schema_url, signal_type, schema_key, 
+			tenant, schema_id, schema_version, schema_url, signal_type, schema_key, 
 			unit, metric_type, temporality, brief,
 			log_severity_number, log_severity_text, log_body, log_flags, log_trace_id, log_span_id, log_event_name, log_dropped_attributes_count,
 			span_kind, span_name, span_id, span_trace_id,
@@ -311,6 +442,7 @@ func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params
 		var versionCount int
 
 		if err := rows.Scan(
+			&schema.Tenant,
 			&schema.SchemaID,
 			&schema.SchemaVersion,
 			&schema.SchemaURL,
@@ -470,13 +602,19 @@ func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params
 		}
 	}
 
+	if err := enrichTelemetries(ctx, db, schemas); err != nil {
+		return nil, 0, err
+	}
+
 	return schemas, total, nil
 }
 
 func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey string) (*schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "t")
 	queryStr := `
 		WITH latest_schema
-			AS (SELECT 	t.schema_id,
+			AS (SELECT 	t.tenant,
+						t.schema_id,
 						t.schema_version,
 						t.schema_url,
 						t.signal_type,
@@ -517,8 +655,9 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 							partition BY t.signal_type, t.schema_key
 							ORDER BY t.updated_at DESC )              AS rn
 				FROM   telemetry_schemas t
-				WHERE  t.schema_key = ?)
-		SELECT schema_id,
+				WHERE  t.schema_key = ?` + tenantFilter + `)
+		SELECT tenant,
+			schema_id,
 			schema_version,
 			schema_url,
 			signal_type,
@@ -564,7 +703,8 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 	var s schema.Telemetry
 	var versionCount int
 
-	err := db.QueryRowContext(ctx, queryStr, schemaKey).Scan(
+	err := db.QueryRowContext(ctx, queryStr, append([]any{schemaKey}, args...)...).Scan(
+		&s.Tenant,
 		&s.SchemaID,
 		&s.SchemaVersion,
 		&s.SchemaURL,
@@ -602,6 +742,9 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 		return nil, fmt.Errorf("failed to query schema: %w", err)
 	}
 
+	// The rest of the telemetry is read within its tenant, even in the cross-tenant views
+	ctx = tenant.WithTenant(ctx, s.Tenant)
+
 	// Get attributes for this schema
 	attrQuery := `
 		SELECT DISTINCT name, type, source
@@ -636,9 +779,9 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 		FROM telemetry_entities te
 		INNER JOIN schema_entities se ON te.entity_id = se.entity_id
 		INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id
-		WHERE ts.schema_key = ?`
+		WHERE ts.schema_key = ? AND ts.tenant = ?`
 
-	rows, err = db.QueryContext(ctx, entityQuery, s.SchemaKey)
+	rows, err = db.QueryContext(ctx, entityQuery, s.SchemaKey, s.Tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query schema entities: %w", err)
 	}
@@ -693,9 +836,9 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 		FROM telemetry_scopes ts
 		INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
 		INNER JOIN telemetry_schemas tschema ON ss.schema_id = tschema.schema_id
-		WHERE tschema.schema_key = ?`
+		WHERE tschema.schema_key = ? AND tschema.tenant = ?`
 
-	scopeRows, err := db.QueryContext(ctx, scopeQuery, s.SchemaKey)
+	scopeRows, err := db.QueryContext(ctx, scopeQuery, s.SchemaKey, s.Tenant)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query schema scope: %w", err)
 	}
@@ -744,39 +887,118 @@ func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey
 		return nil, fmt.Errorf("error iterating scope rows: %w", err)
 	}
 
+	owners, err := loadOwners(ctx, db, schema.OwnedKindTelemetry, []string{s.SchemaKey})
+	if err != nil {
+		return nil, err
+	}
+	s.Owners = owners[s.SchemaKey]
+
+	docs, err := loadDocumentation(ctx, db, []string{s.SchemaKey})
+	if err != nil {
+		return nil, err
+	}
+	docs[s.SchemaKey].Document(&s)
+
+	reviewed := []schema.Telemetry{s}
+	if err := reviewTelemetries(ctx, db, reviewed); err != nil {
+		return nil, err
+	}
+	s.ReviewState = reviewed[0].ReviewState
+
+	if s.Senders, err = loadSenders(ctx, db, s.SchemaKey); err != nil {
+		return nil, err
+	}
+
 	return &s, nil
 }
 
-func (r *TelemetrySchemaRepository) AssignTelemetrySchemaVersion(ctx context.Context, assgiment schema.SchemaAssignment) error {
+// AssignTelemetrySchemaVersion assigns a SemVer version and lifecycle status to a schema once
+// schema.ResolveVersionAssignment accepts it against the versions of the schema key.
+func (r *TelemetrySchemaRepository) AssignTelemetrySchemaVersion(ctx context.Context, assignment schema.SchemaAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return err
+	}
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, err := r.GetTelemetrySchema(ctx, assignment.SchemaId)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %s", schema.ErrSchemaNotFound, assignment.SchemaId)
+	}
+
 	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO schema_versions (schema_id, version, reason, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?)
+	var current *schema.AssignedVersion
+	var others []schema.AssignedVersion
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sv.schema_id, sv.version, COALESCE(sv.status, '')
+		FROM schema_versions sv
+		INNER JOIN telemetry_schemas t ON sv.schema_id = t.schema_id
+		WHERE t.schema_key = ? AND t.tenant = ?
+	`, target.SchemaKey, tenantName)
+	if err != nil {
+		return fmt.Errorf("failed to query schema versions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var assigned schema.AssignedVersion
+		if err := rows.Scan(&assigned.SchemaID, &assigned.Version, &assigned.Status); err != nil {
+			return fmt.Errorf("failed to scan schema version row: %w", err)
+		}
+		if assigned.SchemaID == assignment.SchemaId {
+			current = &assigned
+			continue
+		}
+		others = append(others, assigned)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating schema version rows: %w", err)
+	}
+
+	change, err := schema.ResolveVersionAssignment(assignment, target, current, others, func(schemaID string) (*schema.TelemetrySchema, error) {
+		return r.GetTelemetrySchema(ctx, schemaID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var deprecatedAt sql.NullTime
+	if change.DeprecatedAt != nil {
+		deprecatedAt = sql.NullTime{Time: *change.DeprecatedAt, Valid: true}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schema_versions (schema_id, version, reason, status, deprecated_at, replaced_by, created_at, updated_at, tenant)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
 		ON CONFLICT (schema_id) DO UPDATE SET
 			version = excluded.version,
 			reason = excluded.reason,
+			status = excluded.status,
+			deprecated_at = COALESCE(excluded.deprecated_at, schema_versions.deprecated_at),
+			replaced_by = COALESCE(excluded.replaced_by, schema_versions.replaced_by),
 			updated_at = excluded.updated_at
-		WHERE excluded.updated_at > schema_versions.updated_at;
-	`)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.ExecContext(ctx,
-		assgiment.SchemaId,
-		assgiment.Version,
-		assgiment.Reason,
+	`,
+		assignment.SchemaId,
+		assignment.Version,
+		assignment.Reason,
+		change.Status,
+		deprecatedAt,
+		assignment.ReplacedBy,
 		time.Now(),
 		time.Now(),
+		tenantName,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to execute statement: %w", err)
+		return fmt.Errorf("failed to assign schema version: %w", err)
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -787,8 +1009,8 @@ func (r *TelemetrySchemaRepository) AssignTelemetrySchemaVersion(ctx context.Con
 }
 
 func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, schemaKey string, params query.ListQueryParams) ([]schema.TelemetrySchema, int, error) {
-	var args []any
-	where := " AND t.schema_key = ?"
+	where, args := tenantClause(ctx, "t")
+	where += " AND t.schema_key = ?"
 	args = append(args, schemaKey)
 
 	if params.FilterType != "" && params.FilterType != "all" {
@@ -825,6 +1047,9 @@ func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, sc
 		SELECT
 			t.schema_id,
 			COALESCE(sv.version, 'Unassigned') AS version,
+			sv.status,
+			sv.deprecated_at,
+			sv.replaced_by,
 			COUNT(DISTINCT se.entity_id) AS entity_count,
 			MAX(te.last_seen) AS last_seen
 		FROM telemetry_schemas t
@@ -832,7 +1057,7 @@ func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, sc
 		LEFT JOIN schema_entities se ON t.schema_id = se.schema_id
 		LEFT JOIN telemetry_entities te ON se.entity_id = te.entity_id
 		WHERE 1=1` + where + `
-		GROUP BY t.schema_id, sv.version
+		GROUP BY t.schema_id, sv.version, sv.status, sv.deprecated_at, sv.replaced_by
 		ORDER BY MAX(te.last_seen) DESC NULLS LAST
 		LIMIT ? OFFSET ?`
 
@@ -850,10 +1075,16 @@ func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, sc
 	var assignments []schema.TelemetrySchema
 	for rows.Next() {
 		var row schema.TelemetrySchema
-		var lastSeen sql.NullTime
-		if err := rows.Scan(&row.SchemaId, &row.Version, &row.EntityCount, &lastSeen); err != nil {
+		var lastSeen, deprecatedAt sql.NullTime
+		var status, replacedBy sql.NullString
+		if err := rows.Scan(&row.SchemaId, &row.Version, &status, &deprecatedAt, &replacedBy, &row.EntityCount, &lastSeen); err != nil {
 			return nil, 0, fmt.Errorf("failed to scan schema assignment row: %w", err)
 		}
+		row.Status = schema.VersionStatus(status.String)
+		if deprecatedAt.Valid {
+			row.DeprecatedAt = &deprecatedAt.Time
+		}
+		row.ReplacedBy = replacedBy.String
 		if lastSeen.Valid {
 			row.LastSeen = &lastSeen.Time
 		} else {
@@ -870,18 +1101,34 @@ func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, sc
 }
 
 func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, schemaId string) (*schema.TelemetrySchema, error) {
+	tenantFilter, tenantArgs := tenantClause(ctx, "t")
 	query := `
 		SELECT
+			t.tenant,
 			t.schema_id,
+			t.schema_key,
+			t.signal_type,
+			t.unit,
+			t.metric_type,
+			t.temporality,
+			t.span_kind,
+			t.log_severity_text,
+			t.log_event_name,
+			t.profile_sample_unit,
 			COALESCE(sv.version, 'Unassigned') AS version,
+			sv.status,
+			sv.deprecated_at,
+			sv.replaced_by,
 			COUNT(DISTINCT se.entity_id) AS entity_count,
 			MAX(te.last_seen) AS last_seen
 		FROM telemetry_schemas t
 		LEFT JOIN schema_versions sv ON t.schema_id = sv.schema_id
 		LEFT JOIN schema_entities se ON t.schema_id = se.schema_id
 		LEFT JOIN telemetry_entities te ON se.entity_id = te.entity_id
-		WHERE t.schema_id = ?
-		GROUP BY t.schema_id, sv.version`
+		WHERE t.schema_id = ?` + tenantFilter + `
+		GROUP BY t.tenant, t.schema_id, t.schema_key, t.signal_type, t.unit, t.metric_type, t.temporality,
+			t.span_kind, t.log_severity_text, t.log_event_name, t.profile_sample_unit,
+			sv.version, sv.status, sv.deprecated_at, sv.replaced_by`
 
 	db := r.pool.GetConnection()
 
@@ -890,11 +1137,27 @@ func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, sche
 	defer cancel()
 
 	var s schema.TelemetrySchema
-	var lastSeen sql.NullTime
+	var lastSeen, deprecatedAt sql.NullTime
+	var unit, metricType, temporality, spanKind, logSeverityText, logEventName, profileSampleUnit sql.NullString
+	var status, replacedBy sql.NullString
+	var schemaTenant string
 
-	err := db.QueryRowContext(ctx, query, schemaId).Scan(
+	err := db.QueryRowContext(ctx, query, append([]any{schemaId}, tenantArgs...)...).Scan(
+		&schemaTenant,
 		&s.SchemaId,
+		&s.SchemaKey,
+		&s.TelemetryType,
+		&unit,
+		&metricType,
+		&temporality,
+		&spanKind,
+		&logSeverityText,
+		&logEventName,
+		&profileSampleUnit,
 		&s.Version,
+		&status,
+		&deprecatedAt,
+		&replacedBy,
 		&s.EntityCount,
 		&lastSeen,
 	)
@@ -904,10 +1167,23 @@ func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, sche
 	if err != nil {
 		return nil, fmt.Errorf("failed to query schema: %w", err)
 	}
+	ctx = tenant.WithTenant(ctx, schemaTenant)
 
 	if lastSeen.Valid {
 		s.LastSeen = &lastSeen.Time
 	}
+	s.MetricUnit = unit.String
+	s.MetricType = schema.MetricType(metricType.String)
+	s.MetricTemporality = schema.MetricTemporality(temporality.String)
+	s.SpanKind = schema.SpanKind(spanKind.String)
+	s.LogSeverityText = logSeverityText.String
+	s.LogEventName = logEventName.String
+	s.ProfileSampleUnit = profileSampleUnit.String
+	s.Status = schema.VersionStatus(status.String)
+	if deprecatedAt.Valid {
+		s.DeprecatedAt = &deprecatedAt.Time
+	}
+	s.ReplacedBy = replacedBy.String
 
 	// Get attributes for this schema
 	attrQuery := `
@@ -991,13 +1267,60 @@ func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, sche
 		return nil, fmt.Errorf("error iterating entity rows: %w", err)
 	}
 
+	// Get scopes for this schema
+	scopeQuery := `
+		SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+		FROM telemetry_scopes ts
+		INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+		WHERE ss.schema_id = ?
+		ORDER BY ts.name, ts.version`
+
+	rows, err = db.QueryContext(ctx, scopeQuery, schemaId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema scopes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scope schema.Scope
+		var version, schemaURL sql.NullString
+		if err := rows.Scan(
+			&scope.ID,
+			&scope.Name,
+			&version,
+			&schemaURL,
+			&scope.FirstSeen,
+			&scope.LastSeen,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan scope row: %w", err)
+		}
+		scope.Version = version.String
+		scope.SchemaURL = schemaURL.String
+		s.Scopes = append(s.Scopes, scope)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scope rows: %w", err)
+	}
+
+	docs, err := loadDocumentation(ctx, db, []string{s.SchemaKey})
+	if err != nil {
+		return nil, err
+	}
+	docs[s.SchemaKey].DocumentAttributes(s.Attributes)
+
 	return &s, nil
 }
 
-func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string) ([]schema.Telemetry, error) {
+func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string, window query.TimeWindow) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "te")
+	windowClause, windowArgs := timeWindowClause("se", window)
+	args = append(args, windowArgs...)
+
 	query := `
 		WITH latest_schemas AS (
 			SELECT 
+				t.tenant,
 				t.schema_id,
 				t.schema_version,
 				t.schema_url,
@@ -1032,16 +1355,16 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 				t.created_at,
 				t.updated_at,
 				ROW_NUMBER() OVER (
-					PARTITION BY t.signal_type, t.schema_key 
+					PARTITION BY t.tenant, t.signal_type, t.schema_key 
 					ORDER BY t.updated_at DESC
 				) as rn
 			FROM telemetry_schemas t
 			INNER JOIN schema_entities se ON t.schema_id = se.schema_id
 			INNER JOIN telemetry_entities te ON se.entity_id = te.entity_id
-			WHERE te.entity_type = ?
+			WHERE te.entity_type = ?` + tenantFilter + windowClause + `
 		)
 		SELECT 
-			schema_id, schema_version, schema_url, signal_type, schema_key,
+			tenant, schema_id, schema_version, schema_url, signal_type, schema_key,
 			unit, metric_type, temporality, brief,
 			log_severity_number, log_severity_text, log_body, log_flags, log_trace_id, log_span_id, log_event_name, log_dropped_attributes_count,
 			span_kind, span_name, span_id, span_trace_id,
@@ -1054,7 +1377,7 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 
 	db := r.pool.GetConnection()
 
-	rows, err := db.QueryContext(ctx, query, entityType)
+	rows, err := db.QueryContext(ctx, query, append([]any{entityType}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query telemetries by entity: %w", err)
 	}
@@ -1065,6 +1388,7 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 		var t schema.Telemetry
 
 		err := rows.Scan(
+			&t.Tenant,
 			&t.SchemaID,
 			&t.SchemaVersion,
 			&t.SchemaURL,
@@ -1134,12 +1458,13 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 
 		// Get entities
 		entityQuery := `
-			SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+			SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen,
+				se.first_seen, se.last_seen, se.seen_count
 			FROM telemetry_entities te
 			INNER JOIN schema_entities se ON te.entity_id = se.entity_id
-			WHERE se.schema_id = ?`
+			WHERE se.schema_id = ?` + windowClause
 
-		entityRows, err := db.QueryContext(ctx, entityQuery, telemetries[i].SchemaID)
+		entityRows, err := db.QueryContext(ctx, entityQuery, append([]any{telemetries[i].SchemaID}, windowArgs...)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to query schema entities: %w", err)
 		}
@@ -1147,15 +1472,20 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 		telemetries[i].Entities = make(map[string]*schema.Entity)
 		for entityRows.Next() {
 			var entity schema.Entity
+			var link schemaLinkColumns
 			if err := entityRows.Scan(
 				&entity.ID,
 				&entity.Type,
 				&entity.FirstSeen,
 				&entity.LastSeen,
+				&link.firstSeen,
+				&link.lastSeen,
+				&link.seenCount,
 			); err != nil {
 				entityRows.Close()
 				return nil, fmt.Errorf("failed to scan entity row: %w", err)
 			}
+			entity.Link = link.toSchemaLink()
 
 			// Get entity attributes
 			attrQuery := `
@@ -1191,7 +1521,8 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 
 		// Get scope for this schema
 		scopeQuery := `
-			SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+			SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen,
+				ss.first_seen, ss.last_seen, ss.seen_count
 			FROM telemetry_scopes ts
 			INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
 			WHERE ss.schema_id = ?`
@@ -1204,6 +1535,7 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 		// Each schema should have at most one scope
 		if scopeRows.Next() {
 			var scope schema.Scope
+			var link schemaLinkColumns
 			if err := scopeRows.Scan(
 				&scope.ID,
 				&scope.Name,
@@ -1211,10 +1543,14 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 				&scope.SchemaURL,
 				&scope.FirstSeen,
 				&scope.LastSeen,
+				&link.firstSeen,
+				&link.lastSeen,
+				&link.seenCount,
 			); err != nil {
 				scopeRows.Close()
 				return nil, fmt.Errorf("failed to scan scope row: %w", err)
 			}
+			scope.Link = link.toSchemaLink()
 
 			// Get scope attributes
 			scopeAttrQuery := `
@@ -1249,13 +1585,19 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context,
 		}
 	}
 
+	if err := documentTelemetries(ctx, db, telemetries); err != nil {
+		return nil, err
+	}
+
 	return telemetries, nil
 }
 
 func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context, scopeName string) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
 	query := `
 		WITH latest_schemas AS (
 			SELECT 
+				t.tenant,
 				t.schema_id,
 				t.schema_version,
 				t.schema_url,
@@ -1290,16 +1632,16 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context,
 				t.created_at,
 				t.updated_at,
 				ROW_NUMBER() OVER (
-					PARTITION BY t.signal_type, t.schema_key 
+					PARTITION BY t.tenant, t.signal_type, t.schema_key 
 					ORDER BY t.updated_at DESC
 				) as rn
 			FROM telemetry_schemas t
 			INNER JOIN schema_scopes ss ON t.schema_id = ss.schema_id
 			INNER JOIN telemetry_scopes ts ON ss.scope_id = ts.scope_id
-			WHERE ts.name = ?
+			WHERE ts.name = ?` + tenantFilter + `
 		)
 		SELECT 
-			schema_id, schema_version, schema_url, signal_type, schema_key,
+			tenant, schema_id, schema_version, schema_url, signal_type, schema_key,
 			unit, metric_type, temporality, brief,
 			log_severity_number, log_severity_text, log_body, log_flags, log_trace_id, log_span_id, log_event_name, log_dropped_attributes_count,
 			span_kind, span_name, span_id, span_trace_id,
@@ -1312,7 +1654,7 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context,
 
 	db := r.pool.GetConnection()
 
-	rows, err := db.QueryContext(ctx, query, scopeName)
+	rows, err := db.QueryContext(ctx, query, append([]any{scopeName}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query telemetries by scope: %w", err)
 	}
@@ -1323,6 +1665,7 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context,
 		var t schema.Telemetry
 
 		err := rows.Scan(
+			&t.Tenant,
 			&t.SchemaID,
 			&t.SchemaVersion,
 			&t.SchemaURL,
@@ -1507,12 +1850,15 @@ func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context,
 		}
 	}
 
+	if err := documentTelemetries(ctx, db, telemetries); err != nil {
+		return nil, err
+	}
+
 	return telemetries, nil
 }
 
 func (r *TelemetrySchemaRepository) ListEntities(ctx context.Context, params query.ListQueryParams) ([]schema.Entity, int, error) {
-	var args []any
-	where := ""
+	where, args := tenantClause(ctx, "te")
 
 	if params.Search != "" {
 		where += " AND (te.entity_type LIKE ? OR te.entity_id LIKE ?)"
@@ -1520,6 +1866,12 @@ func (r *TelemetrySchemaRepository) ListEntities(ctx context.Context, params que
 		args = append(args, searchTerm, searchTerm)
 	}
 
+	if params.Owner != "" {
+		clause, ownerArgs := ownerClause("te.tenant", "te.entity_id", schema.OwnedKindEntity, params.Owner)
+		where += clause
+		args = append(args, ownerArgs...)
+	}
+
 	db := r.pool.GetConnection()
 
 	countQuery := `
@@ -1602,31 +1954,49 @@ func (r *TelemetrySchemaRepository) ListEntities(ctx context.Context, params que
 		return nil, 0, fmt.Errorf("error iterating entity rows: %w", err)
 	}
 
+	ids := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		ids = append(ids, entity.ID)
+	}
+	owners, err := loadOwners(ctx, db, schema.OwnedKindEntity, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range entities {
+		entities[i].Owners = owners[entities[i].ID]
+	}
+
 	return entities, total, nil
 }
 
-func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Entity, error) {
+func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string, window query.TimeWindow) ([]schema.Entity, error) {
 	db := r.pool.GetConnection()
 
+	tenantFilter, args := tenantClause(ctx, "ts")
+	windowClause, windowArgs := timeWindowClause("se", window)
+	args = append(args, windowArgs...)
+
+	// Link activity is aggregated over every schema version of the telemetry
 	query := `
 		SELECT 
 			entities.entity_id,
 			entities.entity_type,
 			entities.first_seen,
-			entities.last_seen
+			entities.last_seen,
+			MIN(se.first_seen),
+			MAX(se.last_seen),
+			SUM(se.seen_count)
 		FROM telemetry_entities entities
-		WHERE EXISTS (
-			SELECT 1 FROM schema_entities se 
-			INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id 
-			WHERE se.entity_id = entities.entity_id 
-			AND ts.schema_key = ?
-		)
+		INNER JOIN schema_entities se ON se.entity_id = entities.entity_id
+		INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id
+		WHERE ts.schema_key = ?` + tenantFilter + windowClause + `
+		GROUP BY entities.entity_id, entities.entity_type, entities.first_seen, entities.last_seen
 		ORDER BY entities.last_seen DESC`
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, telemetryKey)
+	rows, err := db.QueryContext(ctx, query, append([]any{telemetryKey}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query entities for telemetry: %w", err)
 	}
@@ -1635,14 +2005,19 @@ func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context,
 	var entities []schema.Entity
 	for rows.Next() {
 		var entity schema.Entity
+		var link schemaLinkColumns
 		if err := rows.Scan(
 			&entity.ID,
 			&entity.Type,
 			&entity.FirstSeen,
 			&entity.LastSeen,
+			&link.firstSeen,
+			&link.lastSeen,
+			&link.seenCount,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan entity row: %w", err)
 		}
+		entity.Link = link.toSchemaLink()
 
 		// Get entity attributes
 		attrQuery := `
@@ -1677,8 +2052,7 @@ func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context,
 }
 
 func (r *TelemetrySchemaRepository) ListScopes(ctx context.Context, params query.ListQueryParams) ([]schema.Scope, int, error) {
-	var args []any
-	where := ""
+	where, args := tenantClause(ctx, "ts")
 
 	if params.Search != "" {
 		where += " AND (ts.name LIKE ? OR ts.version LIKE ? OR ts.schema_url LIKE ?)"
@@ -1686,6 +2060,12 @@ func (r *TelemetrySchemaRepository) ListScopes(ctx context.Context, params query
 		args = append(args, searchTerm, searchTerm, searchTerm)
 	}
 
+	if params.Owner != "" {
+		clause, ownerArgs := ownerClause("ts.tenant", "ts.scope_id", schema.OwnedKindScope, params.Owner)
+		where += clause
+		args = append(args, ownerArgs...)
+	}
+
 	db := r.pool.GetConnection()
 
 	countQuery := `
@@ -1772,12 +2152,26 @@ func (r *TelemetrySchemaRepository) ListScopes(ctx context.Context, params query
 		return nil, 0, fmt.Errorf("error iterating scope rows: %w", err)
 	}
 
+	ids := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		ids = append(ids, scope.ID)
+	}
+	owners, err := loadOwners(ctx, db, schema.OwnedKindScope, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range scopes {
+		scopes[i].Owners = owners[scopes[i].ID]
+	}
+
 	return scopes, total, nil
 }
 
 func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Scope, error) {
 	db := r.pool.GetConnection()
+	tenantFilter, args := tenantClause(ctx, "ts")
 
+	// Link activity is aggregated over every schema version of the telemetry
 	query := `
 		SELECT 
 			scopes.scope_id,
@@ -1785,20 +2179,21 @@ func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, t
 			scopes.version,
 			scopes.schema_url,
 			scopes.first_seen,
-			scopes.last_seen
+			scopes.last_seen,
+			MIN(ss.first_seen),
+			MAX(ss.last_seen),
+			SUM(ss.seen_count)
 		FROM telemetry_scopes scopes
-		WHERE EXISTS (
-			SELECT 1 FROM schema_scopes ss 
-			INNER JOIN telemetry_schemas ts ON ss.schema_id = ts.schema_id 
-			WHERE ss.scope_id = scopes.scope_id 
-			AND ts.schema_key = ?
-		)
+		INNER JOIN schema_scopes ss ON ss.scope_id = scopes.scope_id
+		INNER JOIN telemetry_schemas ts ON ss.schema_id = ts.schema_id
+		WHERE ts.schema_key = ?` + tenantFilter + `
+		GROUP BY scopes.scope_id, scopes.name, scopes.version, scopes.schema_url, scopes.first_seen, scopes.last_seen
 		ORDER BY scopes.last_seen DESC`
 
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query, telemetryKey)
+	rows, err := db.QueryContext(ctx, query, append([]any{telemetryKey}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query scopes for telemetry: %w", err)
 	}
@@ -1807,6 +2202,7 @@ func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, t
 	var scopes []schema.Scope
 	for rows.Next() {
 		var scope schema.Scope
+		var link schemaLinkColumns
 		if err := rows.Scan(
 			&scope.ID,
 			&scope.Name,
@@ -1814,9 +2210,13 @@ func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, t
 			&scope.SchemaURL,
 			&scope.FirstSeen,
 			&scope.LastSeen,
+			&link.firstSeen,
+			&link.lastSeen,
+			&link.seenCount,
 		); err != nil {
 			return nil, fmt.Errorf("failed to scan scope row: %w", err)
 		}
+		scope.Link = link.toSchemaLink()
 
 		// Get scope attributes
 		attrQuery := `
@@ -1853,3 +2253,84 @@ func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, t
 func (r *TelemetrySchemaRepository) Pool() *ConnectionPool {
 	return r.pool
 }
+
+// forEachTenant calls fn with the telemetries of each tenant and a context scoped to it, so the
+// telemetries read in the cross-tenant views are completed within their own tenant
+func forEachTenant(ctx context.Context, telemetries []schema.Telemetry, fn func(ctx context.Context, group []schema.Telemetry) error) error {
+	byTenant := make(map[string][]int)
+	for i := range telemetries {
+		byTenant[telemetries[i].Tenant] = append(byTenant[telemetries[i].Tenant], i)
+	}
+
+	for name, indexes := range byTenant {
+		tenantCtx := ctx
+		if name != "" {
+			tenantCtx = tenant.WithTenant(ctx, name)
+		}
+		group := make([]schema.Telemetry, len(indexes))
+		for j, i := range indexes {
+			group[j] = telemetries[i]
+		}
+		if err := fn(tenantCtx, group); err != nil {
+			return err
+		}
+		for j, i := range indexes {
+			telemetries[i] = group[j]
+		}
+	}
+	return nil
+}
+
+// enrichTelemetries sets the owners, curated documentation and review state of telemetries
+func enrichTelemetries(ctx context.Context, q execQuerier, telemetries []schema.Telemetry) error {
+	return forEachTenant(ctx, telemetries, func(ctx context.Context, group []schema.Telemetry) error {
+		keys := make([]string, len(group))
+		for i := range group {
+			keys[i] = group[i].SchemaKey
+		}
+		owners, err := loadOwners(ctx, q, schema.OwnedKindTelemetry, keys)
+		if err != nil {
+			return err
+		}
+		for i := range group {
+			group[i].Owners = owners[group[i].SchemaKey]
+		}
+		if err := documentTelemetries(ctx, q, group); err != nil {
+			return err
+		}
+		return reviewTelemetries(ctx, q, group)
+	})
+}
+
+// schemaLinkColumns holds the nullable activity columns of a schema_entities or schema_scopes row
+type schemaLinkColumns struct {
+	firstSeen sql.NullTime
+	lastSeen  sql.NullTime
+	seenCount sql.NullInt64
+}
+
+func (c schemaLinkColumns) toSchemaLink() *schema.SchemaLink {
+	if !c.firstSeen.Valid && !c.lastSeen.Valid {
+		return nil
+	}
+	return &schema.SchemaLink{
+		FirstSeen: c.firstSeen.Time,
+		LastSeen:  c.lastSeen.Time,
+		SeenCount: c.seenCount.Int64,
+	}
+}
+
+// timeWindowClause returns the conditions restricting a link table alias to links active within the window
+func timeWindowClause(alias string, window query.TimeWindow) (string, []any) {
+	clause := ""
+	var args []any
+	if !window.Since.IsZero() {
+		clause += fmt.Sprintf(" AND %s.last_seen >= ?", alias)
+		args = append(args, window.Since)
+	}
+	if !window.Until.IsZero() {
+		clause += fmt.Sprintf(" AND %s.first_seen <= ?", alias)
+		args = append(args, window.Until)
+	}
+	return clause, args
+}
@@ -3,7 +3,9 @@ package duckdb
 import (
 	"context"
 	"database/sql"
+	"io/fs"
 	"log/slog"
+	"sort"
 	"testing"
 	"time"
 
@@ -25,107 +27,16 @@ func setupTestDB(t *testing.T) *TelemetrySchemaRepository {
 		logger: slog.Default(),
 	}
 
-	// Create tables manually to avoid import cycle
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS telemetry_schemas (
-			schema_id TEXT PRIMARY KEY,
-			schema_key TEXT,
-			schema_version TEXT,
-			schema_url TEXT,
-			signal_type TEXT,
-			-- Metric fields
-			metric_type TEXT,
-			temporality TEXT,
-			unit TEXT,
-			brief TEXT,
-			-- Log fields
-			log_severity_number INTEGER,
-			log_severity_text TEXT,
-			log_body TEXT,
-			log_flags INTEGER,
-			log_trace_id TEXT,
-			log_span_id TEXT,
-			log_event_name TEXT,
-			log_dropped_attributes_count INTEGER,
-			-- Span fields
-			span_kind TEXT,
-			span_name TEXT,
-			span_id TEXT,
-			span_trace_id TEXT,
-			-- Profile fields
-			profile_sample_aggregation_temporality TEXT,
-			profile_sample_unit TEXT,
-			-- Common fields
-			note TEXT,
-			protocol TEXT,
-			seen_count INTEGER,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP
-		);
-
-		CREATE TABLE IF NOT EXISTS schema_attributes (
-			schema_id TEXT,
-			name TEXT,
-			type TEXT,
-			source TEXT,
-			FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id)
-		);
-
-		-- Create telemetry_entities table
-		CREATE TABLE IF NOT EXISTS telemetry_entities (
-			entity_id TEXT PRIMARY KEY,
-			entity_type TEXT NOT NULL,
-			first_seen TIMESTAMP NOT NULL,
-			last_seen TIMESTAMP NOT NULL
-		);
-
-		-- Create entity_attributes table
-		CREATE TABLE IF NOT EXISTS entity_attributes (
-			entity_id TEXT,
-			name TEXT,
-			value TEXT,
-			type TEXT,
-			FOREIGN KEY (entity_id) REFERENCES telemetry_entities(entity_id)
-		);
-
-		-- Create schema_entities table (many-to-many relationship)
-		CREATE TABLE IF NOT EXISTS schema_entities (
-			schema_id TEXT,
-			entity_id TEXT,
-			FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
-			FOREIGN KEY (entity_id) REFERENCES telemetry_entities(entity_id),
-			PRIMARY KEY (schema_id, entity_id)
-		);
-
-		-- Create telemetry_scopes table
-		CREATE TABLE IF NOT EXISTS telemetry_scopes (
-			scope_id TEXT PRIMARY KEY,
-			name TEXT NOT NULL,
-			version TEXT,
-			schema_url TEXT,
-			first_seen TIMESTAMP NOT NULL,
-			last_seen TIMESTAMP NOT NULL
-		);
-
-		-- Create scope_attributes table
-		CREATE TABLE IF NOT EXISTS scope_attributes (
-			scope_id TEXT,
-			name TEXT,
-			value TEXT,
-			type TEXT,
-			FOREIGN KEY (scope_id) REFERENCES telemetry_scopes(scope_id)
-		);
-
-		-- Create schema_scopes table (many-to-many relationship)
-		CREATE TABLE IF NOT EXISTS schema_scopes (
-			schema_id TEXT,
-			scope_id TEXT,
-			FOREIGN KEY (schema_id) REFERENCES telemetry_schemas(schema_id),
-			FOREIGN KEY (scope_id) REFERENCES telemetry_scopes(scope_id),
-			PRIMARY KEY (schema_id, scope_id)
-		);
-	`)
+	// Apply the embedded up migrations directly, the migrator package imports this one
+	files, err := fs.Glob(EmbeddedMigrations, "migrations/*.up.sql")
 	require.NoError(t, err)
+	sort.Strings(files)
+	for _, file := range files {
+		content, err := EmbeddedMigrations.ReadFile(file)
+		require.NoError(t, err)
+		_, err = db.Exec(string(content))
+		require.NoError(t, err, "failed to apply %s", file)
+	}
 
 	return &TelemetrySchemaRepository{
 		pool: pool,
@@ -136,7 +47,7 @@ func TestListTelemetriesByEntity_EntityNotFound(t *testing.T) {
 	repo := setupTestDB(t)
 	ctx := context.Background()
 
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Empty(t, telemetries)
@@ -283,7 +194,7 @@ func TestListTelemetriesByEntity_EntityWithMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get metrics for service entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Len(t, telemetries, 2)
@@ -353,7 +264,7 @@ func TestListTelemetriesByEntity_EntityWithNoMetrics(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Look for a entity that has no metrics
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Empty(t, telemetries)
@@ -503,7 +414,7 @@ func TestListTelemetriesByEntity_EntityWithLogRecords(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get logs for service entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Len(t, telemetries, 2)
@@ -575,7 +486,7 @@ func TestListTelemetriesByEntity_EntityWithNoLogRecords(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get logs for service entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Empty(t, telemetries)
@@ -725,7 +636,7 @@ func TestListTelemetriesByEntity_EntityWithTraces(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get spans for service entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Len(t, telemetries, 2)
@@ -796,7 +707,7 @@ func TestListTelemetriesByEntity_EntityWithNoTraces(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get telemetries for nonexistent entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Empty(t, telemetries)
@@ -946,7 +857,7 @@ func TestListTelemetriesByEntity_EntityWithProfiles(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get profiles for service entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Len(t, telemetries, 2)
@@ -1017,7 +928,7 @@ func TestListTelemetriesByEntity_EntityWithNoProfiles(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Get telemetries for nonexistent entity
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "k8s", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Empty(t, telemetries)
@@ -1087,7 +998,7 @@ func TestListTelemetriesByEntity_LatestSchemaVersionOnly(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test: Should return only the latest version
-	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service")
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
 
 	require.NoError(t, err)
 	require.Len(t, telemetries, 1)
@@ -1718,3 +1629,107 @@ func TestListTelemetriesByScope_LatestSchemaVersionOnly(t *testing.T) {
 	require.Equal(t, "HTTP server request duration v2", telemetry.Brief)
 	require.Equal(t, "metric1_v2_schema_id", telemetry.SchemaID)
 }
+
+func TestSchemaLinkActivity_TimeWindow(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	telemetry := func(entityID, serviceName string, seenAt time.Time) schema.Telemetry {
+		return schema.Telemetry{
+			SchemaID:      "requests_schema_id",
+			SchemaKey:     "http.server.requests",
+			TelemetryType: schema.TelemetryTypeMetric,
+			MetricType:    schema.MetricTypeSum,
+			Protocol:      schema.TelemetryProtocolOTLP,
+			SeenCount:     2,
+			CreatedAt:     seenAt,
+			UpdatedAt:     seenAt,
+			Entities: map[string]*schema.Entity{
+				entityID: {
+					ID:         entityID,
+					Type:       "service",
+					Attributes: map[string]interface{}{"service.name": serviceName},
+					FirstSeen:  seenAt,
+					LastSeen:   seenAt,
+				},
+			},
+			Scope: &schema.Scope{
+				ID:        "scope1",
+				Name:      "otelhttp",
+				FirstSeen: seenAt,
+				LastSeen:  seenAt,
+			},
+		}
+	}
+
+	// The old service stopped emitting the schema after the first day
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{telemetry("old", "legacy", t0)}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{telemetry("new", "checkout", t0.Add(24*time.Hour))}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{telemetry("new", "checkout", t0.Add(48*time.Hour))}))
+
+	entities, err := repo.ListEntitiesByTelemetry(ctx, "http.server.requests", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+
+	byID := map[string]schema.Entity{}
+	for _, e := range entities {
+		byID[e.ID] = e
+	}
+	require.NotNil(t, byID["new"].Link)
+	require.True(t, t0.Add(24*time.Hour).Equal(byID["new"].Link.FirstSeen))
+	require.True(t, t0.Add(48*time.Hour).Equal(byID["new"].Link.LastSeen))
+	require.Equal(t, int64(4), byID["new"].Link.SeenCount)
+	require.Equal(t, int64(2), byID["old"].Link.SeenCount)
+
+	// Only links still active after the first day
+	entities, err = repo.ListEntitiesByTelemetry(ctx, "http.server.requests", query.TimeWindow{Since: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.Equal(t, "new", entities[0].ID)
+
+	// Only links that existed during the first day
+	entities, err = repo.ListEntitiesByTelemetry(ctx, "http.server.requests", query.TimeWindow{Until: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.Equal(t, "old", entities[0].ID)
+
+	telemetries, err := repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{Since: t0.Add(time.Hour)})
+	require.NoError(t, err)
+	require.Len(t, telemetries, 1)
+	require.Len(t, telemetries[0].Entities, 1)
+	require.Contains(t, telemetries[0].Entities, "new")
+	require.NotNil(t, telemetries[0].Scope.Link)
+	require.Equal(t, int64(6), telemetries[0].Scope.Link.SeenCount)
+
+	telemetries, err = repo.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{Since: t0.Add(72 * time.Hour)})
+	require.NoError(t, err)
+	require.Empty(t, telemetries)
+}
+
+// Every export seeing a known scope moves its last_seen. DuckDB runs an update of an indexed column
+// as a delete and an insert, and the delete violates the foreign key of schema_scopes, so
+// migration 000008 dropped the last_seen index of telemetry_scopes. Scopes are listed by a scan of
+// the table, one row per instrumentation scope, like entities and producers.
+func TestRegisterTelemetrySchemas_KnownScopeSeenAgain(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", t0, 1)}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", t0.Add(time.Hour), 1)}))
+
+	scopes, total, err := repo.ListScopes(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.True(t, scopes[0].FirstSeen.Equal(t0))
+	require.True(t, scopes[0].LastSeen.Equal(t0.Add(time.Hour)))
+
+	var indexes int
+	require.NoError(t, repo.pool.GetConnection().QueryRow(`
+		SELECT COUNT(*) FROM duckdb_indexes()
+		WHERE table_name = 'telemetry_scopes' AND expressions LIKE '%last_seen%'
+	`).Scan(&indexes))
+	require.Zero(t, indexes, "an index on telemetry_scopes.last_seen fails every export of a known scope")
+}
@@ -0,0 +1,80 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// tenantClause returns the condition restricting a table alias to the tenant of the context.
+// It is empty in the cross-tenant views, which read every tenant.
+func tenantClause(ctx context.Context, alias string) (string, []any) {
+	name := tenant.FromContext(ctx)
+	if name == tenant.All {
+		return "", nil
+	}
+	column := "tenant"
+	if alias != "" {
+		column = alias + ".tenant"
+	}
+	return fmt.Sprintf(" AND %s = ?", column), []any{name}
+}
+
+// writeTenant returns the tenant rows written in the context are stored under. Writes are refused
+// in the cross-tenant views.
+func writeTenant(ctx context.Context) (string, error) {
+	name := tenant.FromContext(ctx)
+	if name == tenant.All {
+		return "", fmt.Errorf("%w: cannot write to all tenants at once", tenant.ErrInvalidTenant)
+	}
+	return name, nil
+}
+
+type TenantRepository struct {
+	pool *ConnectionPool
+}
+
+func NewTenantRepository(pool *ConnectionPool) *TenantRepository {
+	return &TenantRepository{
+		pool: pool,
+	}
+}
+
+// ListTenants summarizes the catalog of every tenant that sent telemetry
+func (r *TenantRepository) ListTenants(ctx context.Context) ([]schema.TenantSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT
+			t.tenant,
+			COUNT(DISTINCT (t.signal_type, t.schema_key)),
+			COUNT(*),
+			(SELECT COUNT(*) FROM telemetry_entities e WHERE e.tenant = t.tenant),
+			(SELECT COUNT(*) FROM telemetry_scopes s WHERE s.tenant = t.tenant),
+			MAX(t.updated_at)
+		FROM telemetry_schemas t
+		GROUP BY t.tenant
+		ORDER BY t.tenant`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := []schema.TenantSummary{}
+	for rows.Next() {
+		var t schema.TenantSummary
+		if err := rows.Scan(&t.Name, &t.Telemetries, &t.Schemas, &t.Entities, &t.Scopes, &t.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan tenant row: %w", err)
+		}
+		tenants = append(tenants, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tenant rows: %w", err)
+	}
+	return tenants, nil
+}
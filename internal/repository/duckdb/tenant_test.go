@@ -0,0 +1,65 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+func TestTenant_IsolatesCatalogs(t *testing.T) {
+	repo := setupTestDB(t)
+	tenantRepo := NewTenantRepository(repo.pool)
+	defaultCtx := context.Background()
+	paymentsCtx := tenant.WithTenant(defaultCtx, "payments")
+	allCtx := tenant.WithTenant(defaultCtx, tenant.All)
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(defaultCtx, []schema.Telemetry{
+		checkoutTelemetry("v1", "1.0.0", []string{"http.method"}, t0),
+	}))
+
+	// Ingest derives the IDs of other tenants, as the same telemetry is sent to both
+	payments := checkoutTelemetry(tenant.ScopeID("payments", "v1"), "1.0.0", []string{"http.method", "payment.provider"}, t0.Add(time.Hour))
+	entity := *payments.Entities["checkout"]
+	entity.ID = tenant.ScopeID("payments", "checkout")
+	payments.Entities = map[string]*schema.Entity{entity.ID: &entity}
+	require.NoError(t, repo.RegisterTelemetrySchemas(paymentsCtx, []schema.Telemetry{payments}))
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+	_, total, err := repo.ListTelemetries(defaultCtx, params)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	telemetry, err := repo.GetTelemetry(defaultCtx, "checkout.requests")
+	require.NoError(t, err)
+	require.Len(t, telemetry.Attributes, 1)
+	telemetry, err = repo.GetTelemetry(paymentsCtx, "checkout.requests")
+	require.NoError(t, err)
+	require.Len(t, telemetry.Attributes, 2)
+
+	_, total, err = repo.ListTelemetries(allCtx, params)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+
+	_, total, err = repo.ListTelemetries(tenant.WithTenant(defaultCtx, "search"), params)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	// The cross-tenant view is read only
+	err = repo.RegisterTelemetrySchemas(allCtx, []schema.Telemetry{checkoutTelemetry("v2", "1.0.0", nil, t0)})
+	require.ErrorIs(t, err, tenant.ErrInvalidTenant)
+
+	tenants, err := tenantRepo.ListTenants(defaultCtx)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	require.Equal(t, "default", tenants[0].Name)
+	require.Equal(t, "payments", tenants[1].Name)
+	require.Equal(t, int64(1), tenants[1].Telemetries)
+	require.Equal(t, int64(1), tenants[1].Entities)
+	require.Equal(t, t0.Add(time.Hour), tenants[1].LastSeen.UTC())
+}
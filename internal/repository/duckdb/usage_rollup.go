@@ -0,0 +1,210 @@
+package duckdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type UsageRollupRepository struct {
+	pool *ConnectionPool
+}
+
+func NewUsageRollupRepository(pool *ConnectionPool) *UsageRollupRepository {
+	return &UsageRollupRepository{
+		pool: pool,
+	}
+}
+
+// recordUsage adds the usage of a telemetry to the hourly bucket of its schema, entities and scope
+func recordUsage(ctx context.Context, tx *sql.Tx, telemetry *schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+	bucket := schema.UsageResolutionHour.Bucket(telemetry.UpdatedAt)
+
+	type dimension struct {
+		name string
+		id   string
+	}
+	dimensions := []dimension{{schema.UsageDimensionSchema, ""}}
+	for id := range telemetry.Entities {
+		dimensions = append(dimensions, dimension{schema.UsageDimensionEntity, id})
+	}
+	if telemetry.Scope != nil {
+		dimensions = append(dimensions, dimension{schema.UsageDimensionScope, telemetry.Scope.ID})
+	}
+
+	for _, d := range dimensions {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO usage_rollups (
+				resolution, bucket, schema_id, schema_key, dimension, dimension_id,
+				seen_count, data_point_count, estimated_bytes, tenant
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (resolution, bucket, schema_id, dimension, dimension_id) DO UPDATE SET
+				seen_count = usage_rollups.seen_count + excluded.seen_count,
+				data_point_count = usage_rollups.data_point_count + excluded.data_point_count,
+				estimated_bytes = usage_rollups.estimated_bytes + excluded.estimated_bytes
+		`,
+			schema.UsageResolutionHour,
+			bucket,
+			telemetry.SchemaID,
+			telemetry.SchemaKey,
+			d.name,
+			d.id,
+			telemetry.SeenCount,
+			telemetry.DataPointCount,
+			telemetry.EstimatedBytes,
+			tenantName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record usage: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetUsage returns the usage of a telemetry key, across its schemas, or of an entity per time bucket.
+// Daily buckets include the hourly buckets not downsampled yet. Without a window, the last day of
+// hourly buckets or the last 30 days of daily buckets are returned.
+func (r *UsageRollupRepository) GetUsage(ctx context.Context, filter query.UsageFilter) (*schema.UsageSeries, error) {
+	resolution, err := schema.ParseUsageResolution(filter.Resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	until := filter.Window.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since := filter.Window.Since
+	if since.IsZero() {
+		since = until.Add(-resolution.DefaultWindow())
+	}
+	since = resolution.Bucket(since)
+
+	var where string
+	var args []any
+	switch {
+	case filter.SchemaKey != "":
+		where = "dimension = ? AND schema_key = ?"
+		args = append(args, schema.UsageDimensionSchema, filter.SchemaKey)
+	case filter.EntityID != "":
+		where = "dimension = ? AND dimension_id = ?"
+		args = append(args, schema.UsageDimensionEntity, filter.EntityID)
+	default:
+		return nil, fmt.Errorf("usage filter requires a schema key or an entity ID")
+	}
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	where += tenantFilter
+	args = append(args, tenantArgs...)
+
+	bucket := "bucket"
+	if resolution == schema.UsageResolutionDay {
+		bucket = "date_trunc('day', bucket)"
+	} else {
+		where += " AND resolution = ?"
+		args = append(args, schema.UsageResolutionHour)
+	}
+	args = append(args, since, until)
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := r.pool.GetConnection().QueryContext(ctx, `
+		SELECT `+bucket+` AS b, SUM(seen_count), SUM(data_point_count), SUM(estimated_bytes)
+		FROM usage_rollups
+		WHERE `+where+` AND `+bucket+` >= ? AND `+bucket+` <= ?
+		GROUP BY b
+		ORDER BY b`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	series := &schema.UsageSeries{
+		SchemaKey:  filter.SchemaKey,
+		EntityID:   filter.EntityID,
+		Resolution: resolution,
+		Since:      since,
+		Until:      until,
+		Points:     []schema.UsagePoint{},
+	}
+	for rows.Next() {
+		var p schema.UsagePoint
+		if err := rows.Scan(&p.Bucket, &p.SeenCount, &p.DataPointCount, &p.EstimatedBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage row: %w", err)
+		}
+		p.Bucket = p.Bucket.UTC()
+		series.Points = append(series.Points, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating usage rows: %w", err)
+	}
+
+	return series, nil
+}
+
+// CompactUsage downsamples the hourly buckets of the days older than the hourly retention into daily
+// buckets, and deletes the daily buckets older than the daily retention
+func (r *UsageRollupRepository) CompactUsage(ctx context.Context, now time.Time, retention schema.UsageRetention) error {
+	hourlyCutoff := schema.StartOfDay(now.Add(-retention.Hourly))
+	dailyCutoff := schema.StartOfDay(now.Add(-retention.Daily))
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO usage_rollups (
+			resolution, bucket, schema_id, schema_key, dimension, dimension_id,
+			seen_count, data_point_count, estimated_bytes, tenant
+		)
+		SELECT ?, date_trunc('day', bucket), schema_id, schema_key, dimension, dimension_id,
+			SUM(seen_count), SUM(data_point_count), SUM(estimated_bytes), tenant
+		FROM usage_rollups
+		WHERE resolution = ? AND bucket < ?
+		GROUP BY date_trunc('day', bucket), schema_id, schema_key, dimension, dimension_id, tenant
+		ON CONFLICT (resolution, bucket, schema_id, dimension, dimension_id) DO UPDATE SET
+			seen_count = usage_rollups.seen_count + excluded.seen_count,
+			data_point_count = usage_rollups.data_point_count + excluded.data_point_count,
+			estimated_bytes = usage_rollups.estimated_bytes + excluded.estimated_bytes
+	`, schema.UsageResolutionDay, schema.UsageResolutionHour, hourlyCutoff)
+	if err != nil {
+		return fmt.Errorf("failed to downsample hourly usage: %w", err)
+	}
+
+	downsampled, err := tx.ExecContext(ctx, `
+		DELETE FROM usage_rollups WHERE resolution = ? AND bucket < ?
+	`, schema.UsageResolutionHour, hourlyCutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete downsampled hourly usage: %w", err)
+	}
+
+	expired, err := tx.ExecContext(ctx, `
+		DELETE FROM usage_rollups WHERE resolution = ? AND bucket < ?
+	`, schema.UsageResolutionDay, dailyCutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete expired daily usage: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	downsampledRows, _ := downsampled.RowsAffected()
+	expiredRows, _ := expired.RowsAffected()
+	slog.Debug("compacted usage rollups", "downsampled_rows", downsampledRows, "expired_rows", expiredRows)
+
+	return nil
+}
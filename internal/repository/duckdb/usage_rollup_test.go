@@ -0,0 +1,105 @@
+package duckdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func usageTelemetry(schemaID string, seenAt time.Time, dataPoints int64) schema.Telemetry {
+	telemetry := checkoutTelemetry(schemaID, "1.0.0", []string{"http.method"}, seenAt)
+	telemetry.DataPointCount = dataPoints
+	telemetry.EstimatedBytes = dataPoints * 100
+	telemetry.Scope = &schema.Scope{ID: "otelhttp", Name: "otelhttp", FirstSeen: seenAt, LastSeen: seenAt}
+	return telemetry
+}
+
+func TestUsageRollups_GetUsage(t *testing.T) {
+	repo := setupTestDB(t)
+	usageRepo := NewUsageRollupRepository(repo.pool)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 15, 0, 0, time.UTC)
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", t0, 3)}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", t0.Add(10*time.Minute), 2)}))
+	require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v2", t0.Add(time.Hour), 4)}))
+
+	usage, err := usageRepo.GetUsage(ctx, query.UsageFilter{
+		SchemaKey: "checkout.requests",
+		Window:    query.TimeWindow{Since: t0, Until: t0.Add(2 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, schema.UsageResolutionHour, usage.Resolution)
+	require.Equal(t, []schema.UsagePoint{
+		{Bucket: time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC), SeenCount: 2, DataPointCount: 5, EstimatedBytes: 500},
+		{Bucket: time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC), SeenCount: 1, DataPointCount: 4, EstimatedBytes: 400},
+	}, usage.Points)
+
+	usage, err = usageRepo.GetUsage(ctx, query.UsageFilter{
+		EntityID:   "checkout",
+		Resolution: "day",
+		Window:     query.TimeWindow{Until: t0.Add(2 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []schema.UsagePoint{
+		{Bucket: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), SeenCount: 3, DataPointCount: 9, EstimatedBytes: 900},
+	}, usage.Points)
+
+	usage, err = usageRepo.GetUsage(ctx, query.UsageFilter{
+		EntityID: "unknown",
+		Window:   query.TimeWindow{Since: t0, Until: t0.Add(2 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.Empty(t, usage.Points)
+
+	_, err = usageRepo.GetUsage(ctx, query.UsageFilter{SchemaKey: "checkout.requests", Resolution: "minute"})
+	require.Error(t, err)
+	_, err = usageRepo.GetUsage(ctx, query.UsageFilter{})
+	require.Error(t, err)
+}
+
+func TestUsageRollups_CompactUsage(t *testing.T) {
+	repo := setupTestDB(t)
+	usageRepo := NewUsageRollupRepository(repo.pool)
+	ctx := context.Background()
+
+	day1 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	for _, seenAt := range []time.Time{day1, day1.Add(time.Hour), day2, day2.Add(time.Hour)} {
+		require.NoError(t, repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{usageTelemetry("v1", seenAt, 1)}))
+	}
+
+	// Day one is older than the hourly retention, day two is kept hourly
+	now := day2.Add(2 * time.Hour)
+	retention := schema.UsageRetention{Hourly: 12 * time.Hour, Daily: 30 * 24 * time.Hour}
+	require.NoError(t, usageRepo.CompactUsage(ctx, now, retention))
+
+	window := query.TimeWindow{Since: day1.Add(-24 * time.Hour), Until: now}
+	usage, err := usageRepo.GetUsage(ctx, query.UsageFilter{SchemaKey: "checkout.requests", Window: window})
+	require.NoError(t, err)
+	require.Len(t, usage.Points, 2, "hourly buckets of day one are downsampled")
+	require.Equal(t, day2, usage.Points[0].Bucket)
+
+	usage, err = usageRepo.GetUsage(ctx, query.UsageFilter{SchemaKey: "checkout.requests", Resolution: "day", Window: window})
+	require.NoError(t, err)
+	require.Equal(t, []schema.UsagePoint{
+		{Bucket: schema.StartOfDay(day1), SeenCount: 2, DataPointCount: 2, EstimatedBytes: 200},
+		{Bucket: schema.StartOfDay(day2), SeenCount: 2, DataPointCount: 2, EstimatedBytes: 200},
+	}, usage.Points)
+
+	// Compacting again does not count the downsampled buckets twice
+	require.NoError(t, usageRepo.CompactUsage(ctx, now, retention))
+	usage, err = usageRepo.GetUsage(ctx, query.UsageFilter{EntityID: "checkout", Resolution: "day", Window: window})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), usage.Points[0].SeenCount)
+
+	// Daily buckets older than the daily retention are deleted
+	require.NoError(t, usageRepo.CompactUsage(ctx, day2.Add(40*24*time.Hour), retention))
+	usage, err = usageRepo.GetUsage(ctx, query.UsageFilter{SchemaKey: "checkout.requests", Resolution: "day", Window: window})
+	require.NoError(t, err)
+	require.Empty(t, usage.Points)
+}
@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/tallycat/tallycat/internal/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		store := NewStore()
+		return repositorytest.Backend{
+			Schemas: NewTelemetrySchemaRepository(store),
+			History: NewTelemetryHistoryRepository(store),
+			Tenants: NewTenantRepository(store),
+		}
+	})
+}
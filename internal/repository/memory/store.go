@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// Store keeps a catalog in memory, lost when the process exits. It is the reference the other
+// backends are checked against, so it favours following the semantics of the DuckDB backend over
+// speed. The repositories sharing a store lock it for every call.
+type Store struct {
+	mu sync.RWMutex
+
+	schemas   map[string]*schemaRecord
+	entities  map[string]*entityRecord
+	scopes    map[string]*scopeRecord
+	producers map[string]*producerRecord
+	versions  map[string]*versionRecord
+	history   []historyRecord
+	historyID int
+}
+
+func NewStore() *Store {
+	return &Store{
+		schemas:   make(map[string]*schemaRecord),
+		entities:  make(map[string]*entityRecord),
+		scopes:    make(map[string]*scopeRecord),
+		producers: make(map[string]*producerRecord),
+		versions:  make(map[string]*versionRecord),
+	}
+}
+
+// schemaRecord is a schema variant without the entities, scopes, producers and senders it was
+// seen with, which are kept as links
+type schemaRecord struct {
+	telemetry  schema.Telemetry
+	attributes map[schema.Attribute]struct{}
+	entities   map[string]*link
+	scopes     map[string]*link
+	producers  map[string]*link
+	senders    map[string]*link
+}
+
+// link records when a schema was first and last seen with an entity, scope, producer or sender
+type link struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	seenCount int64
+}
+
+func (l *link) merge(seenAt time.Time, seenCount int64) {
+	if seenAt.Before(l.firstSeen) {
+		l.firstSeen = seenAt
+	}
+	if seenAt.After(l.lastSeen) {
+		l.lastSeen = seenAt
+	}
+	l.seenCount += seenCount
+}
+
+func (l *link) schemaLink() *schema.SchemaLink {
+	return &schema.SchemaLink{FirstSeen: l.firstSeen, LastSeen: l.lastSeen, SeenCount: l.seenCount}
+}
+
+// inWindow reports whether the link was active within the window
+func (l *link) inWindow(since, until time.Time) bool {
+	if !since.IsZero() && l.lastSeen.Before(since) {
+		return false
+	}
+	if !until.IsZero() && l.firstSeen.After(until) {
+		return false
+	}
+	return true
+}
+
+type entityRecord struct {
+	tenant string
+	entity schema.Entity
+}
+
+type scopeRecord struct {
+	tenant string
+	scope  schema.Scope
+}
+
+type producerRecord struct {
+	tenant    string
+	producer  schema.Producer
+	instances map[string]*schema.ProducerInstance
+}
+
+type versionRecord struct {
+	tenant       string
+	version      string
+	reason       string
+	status       schema.VersionStatus
+	deprecatedAt *time.Time
+	replacedBy   string
+	createdAt    time.Time
+	updatedAt    time.Time
+}
+
+type historyRecord struct {
+	tenant  string
+	history schema.TelemetryHistory
+}
+
+// visible reports whether a row stored under a tenant is read in the context. Every tenant is
+// read in the cross-tenant views.
+func visible(ctx context.Context, rowTenant string) bool {
+	name := tenant.FromContext(ctx)
+	return name == tenant.All || name == rowTenant
+}
+
+// writeTenant returns the tenant rows written in the context are stored under. Writes are refused
+// in the cross-tenant views.
+func writeTenant(ctx context.Context) (string, error) {
+	name := tenant.FromContext(ctx)
+	if name == tenant.All {
+		return "", fmt.Errorf("%w: cannot write to all tenants at once", tenant.ErrInvalidTenant)
+	}
+	return name, nil
+}
+
+// copyAttributes copies the attributes of an entity or scope, so callers cannot change the store
+func copyAttributes(attributes map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		copied[name] = value
+	}
+	return copied
+}
+
+// stringAttributes stores the attributes of an entity or scope as text, as the SQL backends do
+func stringAttributes(attributes map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		values[name] = fmt.Sprintf("%v", value)
+	}
+	return values
+}
+
+// pageBounds returns the bounds of a page of n rows
+func pageBounds(n, pageNumber, pageSize int) (int, int) {
+	start := min(max(pageNumber-1, 0)*pageSize, n)
+	return start, min(start+pageSize, n)
+}
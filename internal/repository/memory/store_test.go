@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/repository/repositorytest"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestStore_ConcurrentWritersAndReaders(t *testing.T) {
+	store := NewStore()
+	repo := NewTelemetrySchemaRepository(store)
+	history := NewTelemetryHistoryRepository(store)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+				repositorytest.Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(time.Duration(i)*time.Second)),
+			})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, _, err := repo.ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10}); err != nil {
+				errs <- err
+				return
+			}
+			_, _, err := history.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	entities, err := repo.ListEntitiesByTelemetry(ctx, "checkout.requests", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.Equal(t, int64(8), entities[0].Link.SeenCount)
+}
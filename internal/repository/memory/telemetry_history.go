@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type TelemetryHistoryRepository struct {
+	store *Store
+}
+
+func NewTelemetryHistoryRepository(store *Store) *TelemetryHistoryRepository {
+	return &TelemetryHistoryRepository{
+		store: store,
+	}
+}
+
+func (r *TelemetryHistoryRepository) InsertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.insertTelemetryHistory(ctx, h)
+}
+
+func (s *Store) insertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.historyID++
+	h.Id = s.historyID
+	h.CreatedAt = time.Now()
+	h.UpdatedAt = h.CreatedAt
+
+	stored := *h
+	if h.Author != nil {
+		author := *h.Author
+		stored.Author = &author
+	}
+	if h.Diff != nil {
+		diff := *h.Diff
+		stored.Diff = &diff
+	}
+	s.history = append(s.history, historyRecord{tenant: tenantName, history: stored})
+	return nil
+}
+
+func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, telemetryID string, page, pageSize int) ([]schema.TelemetryHistory, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	histories := []schema.TelemetryHistory{}
+	for _, record := range r.store.history {
+		if record.history.SchemaKey == telemetryID && visible(ctx, record.tenant) {
+			histories = append(histories, record.history)
+		}
+	}
+	sortHistory(histories)
+
+	start, end := pageBounds(len(histories), page, pageSize)
+	return histories[start:end], len(histories), nil
+}
+
+// GetSchemaIDAt returns the variant of the schema key that was current at the given time, or an empty
+// string when the key was not seen yet. It follows the variants recorded in the history snapshots and
+// falls back to the latest variant first seen by then, which covers keys with a single variant.
+func (r *TelemetryHistoryRepository) GetSchemaIDAt(ctx context.Context, schemaKey string, at time.Time) (string, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var histories []schema.TelemetryHistory
+	for _, record := range r.store.history {
+		h := record.history
+		if h.SchemaKey == schemaKey && !h.Timestamp.After(at) && visible(ctx, record.tenant) {
+			histories = append(histories, h)
+		}
+	}
+	sortHistory(histories)
+	for _, h := range histories {
+		if id := h.SnapshotSchemaID(); id != "" {
+			return id, nil
+		}
+	}
+
+	var latest *schema.Telemetry
+	for _, record := range r.store.schemas {
+		t := record.telemetry
+		if t.SchemaKey == schemaKey && !t.CreatedAt.After(at) && visible(ctx, t.Tenant) &&
+			(latest == nil || t.CreatedAt.After(latest.CreatedAt)) {
+			latest = &t
+		}
+	}
+	if latest == nil {
+		return "", nil
+	}
+	return latest.SchemaID, nil
+}
+
+// sortHistory orders history entries by time, the latest first
+func sortHistory(histories []schema.TelemetryHistory) {
+	sort.Slice(histories, func(i, j int) bool {
+		if !histories[i].Timestamp.Equal(histories[j].Timestamp) {
+			return histories[i].Timestamp.After(histories[j].Timestamp)
+		}
+		return histories[i].Id > histories[j].Id
+	})
+}
+
+// recordSchemaVariant writes a history entry with the diff against the previous variant when a
+// schema ID not seen before appears for an existing schema key. It must run before the schema is stored.
+func (s *Store) recordSchemaVariant(ctx context.Context, telemetry *schema.Telemetry) error {
+	if _, exists := s.schemas[telemetry.SchemaID]; exists {
+		return nil
+	}
+
+	var latest *schemaRecord
+	for _, record := range s.schemas {
+		t := record.telemetry
+		if t.SchemaKey == telemetry.SchemaKey && t.TelemetryType == telemetry.TelemetryType && visible(ctx, t.Tenant) &&
+			(latest == nil || t.UpdatedAt.After(latest.telemetry.UpdatedAt)) {
+			latest = record
+		}
+	}
+	if latest == nil {
+		// First variant of this schema key, nothing to compare against
+		return nil
+	}
+
+	previous := schema.Telemetry{
+		SchemaID:          latest.telemetry.SchemaID,
+		MetricUnit:        latest.telemetry.MetricUnit,
+		MetricType:        latest.telemetry.MetricType,
+		MetricTemporality: latest.telemetry.MetricTemporality,
+		SpanKind:          latest.telemetry.SpanKind,
+		LogSeverityText:   latest.telemetry.LogSeverityText,
+		LogEventName:      latest.telemetry.LogEventName,
+		ProfileSampleUnit: latest.telemetry.ProfileSampleUnit,
+		Attributes:        latest.sortedAttributes(),
+	}
+
+	// The snapshot only describes the schema itself, not where it was seen
+	snapshotTelemetry := *telemetry
+	snapshotTelemetry.Entities = nil
+	snapshotTelemetry.Scope = nil
+	snapshotTelemetry.Producers = nil
+	snapshotTelemetry.Senders = nil
+	snapshot, err := json.Marshal(snapshotTelemetry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema snapshot: %w", err)
+	}
+
+	diff := schema.DiffTelemetry(previous, *telemetry)
+	return s.insertTelemetryHistory(ctx, &schema.TelemetryHistory{
+		SchemaKey: telemetry.SchemaKey,
+		Timestamp: telemetry.CreatedAt,
+		Summary:   diff.Summary(),
+		Status:    schema.TelemetryHistoryStatusDetected,
+		Snapshot:  snapshot,
+		SchemaID:  telemetry.SchemaID,
+		Diff:      &diff,
+	})
+}
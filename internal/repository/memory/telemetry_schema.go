@@ -0,0 +1,819 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// TelemetrySchemaRepository reads and writes the catalog of a Store. Like the SQLite backend it
+// keeps no review, ownership, documentation or usage data.
+type TelemetrySchemaRepository struct {
+	store *Store
+}
+
+func NewTelemetrySchemaRepository(store *Store) *TelemetrySchemaRepository {
+	return &TelemetrySchemaRepository{
+		store: store,
+	}
+}
+
+// ownedListing reports whether a listing only keeps what a team owns. Ownership is not kept by
+// this backend, so nothing is owned by any team and such listings are empty.
+func ownedListing(params query.ListQueryParams) bool {
+	return params.Owner != ""
+}
+
+func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context, schemas []schema.Telemetry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := r.store
+	for _, telemetry := range schemas {
+		// Compare a new variant of an existing schema key against the previous one
+		if err := s.recordSchemaVariant(ctx, &telemetry); err != nil {
+			return err
+		}
+
+		record, ok := s.schemas[telemetry.SchemaID]
+		if !ok {
+			stored := telemetry
+			stored.Tenant = tenantName
+			stored.Attributes = nil
+			stored.Entities = nil
+			stored.Scope = nil
+			stored.Producers = nil
+			stored.Senders = nil
+			stored.Sender = ""
+			stored.Owners = nil
+			stored.ReviewState = ""
+			stored.DataPointCount = 0
+			stored.EstimatedBytes = 0
+			stored.Series = nil
+			record = &schemaRecord{
+				telemetry:  stored,
+				attributes: make(map[schema.Attribute]struct{}),
+				entities:   make(map[string]*link),
+				scopes:     make(map[string]*link),
+				producers:  make(map[string]*link),
+				senders:    make(map[string]*link),
+			}
+			s.schemas[telemetry.SchemaID] = record
+		} else if telemetry.UpdatedAt.After(record.telemetry.UpdatedAt) {
+			record.telemetry.SeenCount += telemetry.SeenCount
+			record.telemetry.UpdatedAt = telemetry.UpdatedAt
+		}
+
+		for _, attr := range telemetry.Attributes {
+			record.attributes[schema.Attribute{Name: attr.Name, Type: attr.Type, Source: attr.Source}] = struct{}{}
+		}
+
+		for _, entity := range telemetry.Entities {
+			stored, ok := s.entities[entity.ID]
+			if !ok {
+				stored = &entityRecord{tenant: tenantName, entity: schema.Entity{
+					ID:         entity.ID,
+					Type:       entity.Type,
+					Attributes: map[string]interface{}{},
+					FirstSeen:  entity.FirstSeen,
+					LastSeen:   entity.LastSeen,
+				}}
+				s.entities[entity.ID] = stored
+			} else if entity.LastSeen.After(stored.entity.LastSeen) {
+				stored.entity.LastSeen = entity.LastSeen
+			}
+			// Only the latest value of each entity attribute is kept
+			for name, value := range stringAttributes(entity.Attributes) {
+				stored.entity.Attributes[name] = value
+			}
+			linkSchema(record.entities, entity.ID, &telemetry)
+		}
+
+		for _, producer := range telemetry.Producers {
+			stored, ok := s.producers[producer.ID]
+			if !ok {
+				stored = &producerRecord{tenant: tenantName, producer: *producer, instances: map[string]*schema.ProducerInstance{}}
+				stored.producer.Instances = nil
+				s.producers[producer.ID] = stored
+			} else if producer.LastSeen.After(stored.producer.LastSeen) {
+				stored.producer.LastSeen = producer.LastSeen
+			}
+			for _, instance := range producer.Instances {
+				storedInstance, ok := stored.instances[instance.ID]
+				if !ok {
+					copied := instance
+					stored.instances[instance.ID] = &copied
+					continue
+				}
+				if instance.FirstSeen.Before(storedInstance.FirstSeen) {
+					storedInstance.FirstSeen = instance.FirstSeen
+				}
+				if instance.LastSeen.After(storedInstance.LastSeen) {
+					storedInstance.LastSeen = instance.LastSeen
+				}
+			}
+			linkSchema(record.producers, producer.ID, &telemetry)
+		}
+
+		// Record the client that sent the schema, when it presented a verified certificate
+		if telemetry.Sender != "" {
+			linkSchema(record.senders, telemetry.Sender, &telemetry)
+		}
+
+		if scope := telemetry.Scope; scope != nil {
+			stored, ok := s.scopes[scope.ID]
+			if !ok {
+				stored = &scopeRecord{tenant: tenantName, scope: schema.Scope{
+					ID:         scope.ID,
+					Name:       scope.Name,
+					Version:    scope.Version,
+					SchemaURL:  scope.SchemaURL,
+					Attributes: map[string]interface{}{},
+					FirstSeen:  scope.FirstSeen,
+					LastSeen:   scope.LastSeen,
+				}}
+				s.scopes[scope.ID] = stored
+			} else if scope.LastSeen.After(stored.scope.LastSeen) {
+				stored.scope.LastSeen = scope.LastSeen
+			}
+			for name, value := range stringAttributes(scope.Attributes) {
+				stored.scope.Attributes[name] = value
+			}
+			linkSchema(record.scopes, scope.ID, &telemetry)
+		}
+	}
+
+	return nil
+}
+
+// linkSchema records a schema being seen with the entity, scope, producer or sender of a link,
+// widening the activity of the link
+func linkSchema(links map[string]*link, id string, telemetry *schema.Telemetry) {
+	l, ok := links[id]
+	if !ok {
+		links[id] = &link{firstSeen: telemetry.UpdatedAt, lastSeen: telemetry.UpdatedAt, seenCount: int64(telemetry.SeenCount)}
+		return
+	}
+	l.merge(telemetry.UpdatedAt, int64(telemetry.SeenCount))
+}
+
+// latestVariants returns the latest variant of every schema key among the matching schemas
+func (s *Store) latestVariants(match func(*schemaRecord) bool) []*schemaRecord {
+	latest := make(map[string]*schemaRecord)
+	for _, record := range s.schemas {
+		if !match(record) {
+			continue
+		}
+		t := record.telemetry
+		key := t.Tenant + "\x00" + string(t.TelemetryType) + "\x00" + t.SchemaKey
+		current, ok := latest[key]
+		if !ok || t.UpdatedAt.After(current.telemetry.UpdatedAt) ||
+			(t.UpdatedAt.Equal(current.telemetry.UpdatedAt) && t.SchemaID > current.telemetry.SchemaID) {
+			latest[key] = record
+		}
+	}
+
+	records := make([]*schemaRecord, 0, len(latest))
+	for _, record := range latest {
+		records = append(records, record)
+	}
+	return records
+}
+
+// byUpdatedAt orders schemas by their last update, the latest first
+func byUpdatedAt(records []*schemaRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i].telemetry, records[j].telemetry
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		return a.SchemaKey < b.SchemaKey
+	})
+}
+
+// matchesSearch applies the type filter and search term of the list parameters to a schema
+func matchesSearch(t schema.Telemetry, params query.ListQueryParams) bool {
+	if params.FilterType != "" && params.FilterType != "all" &&
+		string(t.TelemetryType) != cases.Title(language.English).String(params.FilterType) {
+		return false
+	}
+	if params.Search != "" {
+		return strings.Contains(t.SchemaID, params.Search) ||
+			strings.Contains(t.SchemaKey, params.Search) ||
+			strings.Contains(string(t.MetricType), params.Search) ||
+			strings.Contains(t.MetricUnit, params.Search)
+	}
+	return true
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.Telemetry, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if ownedListing(params) {
+		return []schema.Telemetry{}, 0, nil
+	}
+
+	s := r.store
+	records := s.latestVariants(func(record *schemaRecord) bool {
+		return visible(ctx, record.telemetry.Tenant) && matchesSearch(record.telemetry, params)
+	})
+	if len(records) == 0 {
+		return []schema.Telemetry{}, 0, nil
+	}
+	byUpdatedAt(records)
+
+	start, end := pageBounds(len(records), params.Page, params.PageSize)
+	telemetries := make([]schema.Telemetry, 0, end-start)
+	for _, record := range records[start:end] {
+		t := record.telemetry
+		t.Entities = s.entityMap(record.entities, nil)
+		t.Scope = s.latestScope([]*schemaRecord{record}, nil)
+		telemetries = append(telemetries, t)
+	}
+	return telemetries, len(records), nil
+}
+
+func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey string) (*schema.Telemetry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	var latest *schemaRecord
+	for _, record := range s.schemas {
+		t := record.telemetry
+		if t.SchemaKey != schemaKey || !visible(ctx, t.Tenant) {
+			continue
+		}
+		if latest == nil || t.UpdatedAt.After(latest.telemetry.UpdatedAt) {
+			latest = record
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+
+	// Entities, scopes and senders are those of every variant of the schema key
+	var variants []*schemaRecord
+	for _, record := range s.schemas {
+		if record.telemetry.SchemaKey == schemaKey && record.telemetry.Tenant == latest.telemetry.Tenant {
+			variants = append(variants, record)
+		}
+	}
+
+	t := latest.telemetry
+	t.Attributes = latest.sortedAttributes()
+	t.Entities = make(map[string]*schema.Entity)
+	for _, variant := range variants {
+		for id, entity := range s.entityMap(variant.entities, nil) {
+			t.Entities[id] = entity
+		}
+	}
+	t.Scope = s.latestScope(variants, nil)
+	t.Senders = aggregateSenders(variants)
+	return &t, nil
+}
+
+// AssignTelemetrySchemaVersion assigns a SemVer version and lifecycle status to a schema once
+// schema.ResolveVersionAssignment accepts it against the versions of the schema key.
+func (r *TelemetrySchemaRepository) AssignTelemetrySchemaVersion(ctx context.Context, assignment schema.SchemaAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return err
+	}
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := s.telemetrySchemaDetails(ctx, assignment.SchemaId)
+	if target == nil {
+		return fmt.Errorf("%w: %s", schema.ErrSchemaNotFound, assignment.SchemaId)
+	}
+
+	var current *schema.AssignedVersion
+	assigned, ok := s.versions[assignment.SchemaId]
+	if ok {
+		current = &schema.AssignedVersion{SchemaID: assignment.SchemaId, Version: assigned.version, Status: assigned.status}
+	}
+	var others []schema.AssignedVersion
+	for otherID, other := range s.versions {
+		record, ok := s.schemas[otherID]
+		if !ok || otherID == assignment.SchemaId || record.telemetry.SchemaKey != target.SchemaKey || record.telemetry.Tenant != tenantName {
+			continue
+		}
+		others = append(others, schema.AssignedVersion{SchemaID: otherID, Version: other.version, Status: other.status})
+	}
+
+	change, err := schema.ResolveVersionAssignment(assignment, target, current, others, func(schemaID string) (*schema.TelemetrySchema, error) {
+		return s.telemetrySchemaDetails(ctx, schemaID), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !ok {
+		assigned = &versionRecord{tenant: tenantName, createdAt: now}
+		s.versions[assignment.SchemaId] = assigned
+	}
+	if change.DeprecatedAt != nil {
+		deprecatedAt := *change.DeprecatedAt
+		assigned.deprecatedAt = &deprecatedAt
+	}
+	if assignment.ReplacedBy != "" {
+		assigned.replacedBy = assignment.ReplacedBy
+	}
+	assigned.version = assignment.Version
+	assigned.reason = assignment.Reason
+	assigned.status = change.Status
+	assigned.updatedAt = now
+	return nil
+}
+
+// telemetrySchema summarizes a schema variant with its assigned version
+func (s *Store) telemetrySchema(record *schemaRecord) schema.TelemetrySchema {
+	t := record.telemetry
+	row := schema.TelemetrySchema{
+		SchemaId:          t.SchemaID,
+		SchemaKey:         t.SchemaKey,
+		TelemetryType:     t.TelemetryType,
+		Version:           "Unassigned",
+		EntityCount:       len(record.entities),
+		MetricUnit:        t.MetricUnit,
+		MetricType:        t.MetricType,
+		MetricTemporality: t.MetricTemporality,
+		SpanKind:          t.SpanKind,
+		LogSeverityText:   t.LogSeverityText,
+		LogEventName:      t.LogEventName,
+		ProfileSampleUnit: t.ProfileSampleUnit,
+	}
+	if assigned, ok := s.versions[t.SchemaID]; ok {
+		row.Version = assigned.version
+		row.Status = assigned.status
+		if assigned.deprecatedAt != nil {
+			deprecatedAt := *assigned.deprecatedAt
+			row.DeprecatedAt = &deprecatedAt
+		}
+		row.ReplacedBy = assigned.replacedBy
+	}
+	for id := range record.entities {
+		if entity, ok := s.entities[id]; ok && (row.LastSeen == nil || entity.entity.LastSeen.After(*row.LastSeen)) {
+			lastSeen := entity.entity.LastSeen
+			row.LastSeen = &lastSeen
+		}
+	}
+	return row
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, schemaKey string, params query.ListQueryParams) ([]schema.TelemetrySchema, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	var rows []schema.TelemetrySchema
+	for _, record := range s.schemas {
+		t := record.telemetry
+		if t.SchemaKey == schemaKey && visible(ctx, t.Tenant) && matchesSearch(t, params) {
+			rows = append(rows, s.telemetrySchema(record))
+		}
+	}
+	if len(rows) == 0 {
+		return []schema.TelemetrySchema{}, 0, nil
+	}
+
+	// The variants last seen most recently come first, those never seen by an entity last
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i].LastSeen, rows[j].LastSeen
+		if (a == nil) != (b == nil) {
+			return b == nil
+		}
+		if a != nil && !a.Equal(*b) {
+			return a.After(*b)
+		}
+		return rows[i].SchemaId < rows[j].SchemaId
+	})
+
+	start, end := pageBounds(len(rows), params.Page, params.PageSize)
+	return rows[start:end], len(rows), nil
+}
+
+func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, schemaId string) (*schema.TelemetrySchema, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	return r.store.telemetrySchemaDetails(ctx, schemaId), nil
+}
+
+// telemetrySchemaDetails returns a schema variant with its attributes, entities and scopes
+func (s *Store) telemetrySchemaDetails(ctx context.Context, schemaId string) *schema.TelemetrySchema {
+	record, ok := s.schemas[schemaId]
+	if !ok || !visible(ctx, record.telemetry.Tenant) {
+		return nil
+	}
+
+	row := s.telemetrySchema(record)
+	row.Attributes = record.sortedAttributes()
+	row.Entities = s.entityMap(record.entities, nil)
+
+	// The scopes of a variant are listed without their attributes
+	for _, scope := range s.linkedScopes(record.scopes, false) {
+		scope.Attributes = nil
+		row.Scopes = append(row.Scopes, scope)
+	}
+	sort.Slice(row.Scopes, func(i, j int) bool {
+		if row.Scopes[i].Name != row.Scopes[j].Name {
+			return row.Scopes[i].Name < row.Scopes[j].Name
+		}
+		return row.Scopes[i].Version < row.Scopes[j].Version
+	})
+	return &row
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string, window query.TimeWindow) ([]schema.Telemetry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	records := s.latestVariants(func(record *schemaRecord) bool {
+		for id, l := range record.entities {
+			entity, ok := s.entities[id]
+			if ok && entity.entity.Type == entityType && visible(ctx, entity.tenant) && l.inWindow(window.Since, window.Until) {
+				return true
+			}
+		}
+		return false
+	})
+	byUpdatedAt(records)
+
+	var telemetries []schema.Telemetry
+	for _, record := range records {
+		t := record.telemetry
+		t.Attributes = record.sortedAttributes()
+		t.Entities = s.entityMap(record.entities, func(l *link) bool { return l.inWindow(window.Since, window.Until) })
+		for id, entity := range t.Entities {
+			entity.Link = record.entities[id].schemaLink()
+		}
+		t.Scope = s.latestScope([]*schemaRecord{record}, record.scopes)
+		telemetries = append(telemetries, t)
+	}
+	return telemetries, nil
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context, scopeName string) ([]schema.Telemetry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	records := s.latestVariants(func(record *schemaRecord) bool {
+		for id := range record.scopes {
+			scope, ok := s.scopes[id]
+			if ok && scope.scope.Name == scopeName && visible(ctx, scope.tenant) {
+				return true
+			}
+		}
+		return false
+	})
+	byUpdatedAt(records)
+
+	var telemetries []schema.Telemetry
+	for _, record := range records {
+		t := record.telemetry
+		t.Attributes = record.sortedAttributes()
+		t.Entities = s.entityMap(record.entities, nil)
+		t.Scope = s.latestScope([]*schemaRecord{record}, nil)
+		telemetries = append(telemetries, t)
+	}
+	return telemetries, nil
+}
+
+func (r *TelemetrySchemaRepository) ListEntities(ctx context.Context, params query.ListQueryParams) ([]schema.Entity, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if ownedListing(params) {
+		return []schema.Entity{}, 0, nil
+	}
+
+	entities := []schema.Entity{}
+	for _, record := range r.store.entities {
+		e := record.entity
+		if !visible(ctx, record.tenant) {
+			continue
+		}
+		if params.Search != "" && !strings.Contains(e.Type, params.Search) && !strings.Contains(e.ID, params.Search) {
+			continue
+		}
+		e.Attributes = copyAttributes(e.Attributes)
+		entities = append(entities, e)
+	}
+	sortEntities(entities)
+
+	start, end := pageBounds(len(entities), params.Page, params.PageSize)
+	return entities[start:end], len(entities), nil
+}
+
+func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string, window query.TimeWindow) ([]schema.Entity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	// Link activity is aggregated over every schema version of the telemetry
+	links := make(map[string]*link)
+	for _, record := range s.schemas {
+		if record.telemetry.SchemaKey != telemetryKey || !visible(ctx, record.telemetry.Tenant) {
+			continue
+		}
+		for id, l := range record.entities {
+			if l.inWindow(window.Since, window.Until) {
+				aggregateLink(links, id, l)
+			}
+		}
+	}
+
+	entities := []schema.Entity{}
+	for id, l := range links {
+		record, ok := s.entities[id]
+		if !ok {
+			continue
+		}
+		e := record.entity
+		e.Attributes = copyAttributes(e.Attributes)
+		e.Link = l.schemaLink()
+		entities = append(entities, e)
+	}
+	sortEntities(entities)
+	return entities, nil
+}
+
+func (r *TelemetrySchemaRepository) ListScopes(ctx context.Context, params query.ListQueryParams) ([]schema.Scope, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if ownedListing(params) {
+		return []schema.Scope{}, 0, nil
+	}
+
+	scopes := []schema.Scope{}
+	for _, record := range r.store.scopes {
+		sc := record.scope
+		if !visible(ctx, record.tenant) {
+			continue
+		}
+		if params.Search != "" && !strings.Contains(sc.Name, params.Search) &&
+			!strings.Contains(sc.Version, params.Search) && !strings.Contains(sc.SchemaURL, params.Search) {
+			continue
+		}
+		sc.Attributes = copyAttributes(sc.Attributes)
+		scopes = append(scopes, sc)
+	}
+	sortScopes(scopes)
+
+	start, end := pageBounds(len(scopes), params.Page, params.PageSize)
+	return scopes[start:end], len(scopes), nil
+}
+
+func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Scope, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	// Link activity is aggregated over every schema version of the telemetry
+	links := make(map[string]*link)
+	for _, record := range s.schemas {
+		if record.telemetry.SchemaKey != telemetryKey || !visible(ctx, record.telemetry.Tenant) {
+			continue
+		}
+		for id, l := range record.scopes {
+			aggregateLink(links, id, l)
+		}
+	}
+
+	scopes := []schema.Scope{}
+	for id, l := range links {
+		record, ok := s.scopes[id]
+		if !ok {
+			continue
+		}
+		sc := record.scope
+		sc.Attributes = copyAttributes(sc.Attributes)
+		sc.Link = l.schemaLink()
+		scopes = append(scopes, sc)
+	}
+	sortScopes(scopes)
+	return scopes, nil
+}
+
+func (r *TelemetrySchemaRepository) ListProducers(ctx context.Context, params query.ListQueryParams) ([]schema.Producer, int, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	producers := []schema.Producer{}
+	for _, record := range r.store.producers {
+		p := record.producer
+		if !visible(ctx, record.tenant) {
+			continue
+		}
+		if params.Search != "" && !strings.Contains(p.Name, params.Search) &&
+			!strings.Contains(p.Namespace, params.Search) && !strings.Contains(p.Version, params.Search) {
+			continue
+		}
+		p.Instances = record.sortedInstances()
+		producers = append(producers, p)
+	}
+	sort.Slice(producers, func(i, j int) bool {
+		if !producers[i].LastSeen.Equal(producers[j].LastSeen) {
+			return producers[i].LastSeen.After(producers[j].LastSeen)
+		}
+		if producers[i].Name != producers[j].Name {
+			return producers[i].Name < producers[j].Name
+		}
+		return producers[i].ID < producers[j].ID
+	})
+
+	start, end := pageBounds(len(producers), params.Page, params.PageSize)
+	return producers[start:end], len(producers), nil
+}
+
+// ListTelemetriesByProducer returns the latest schema of every telemetry emitted by any instance
+// of the producer with the given name and version. An empty version matches producers without one.
+func (r *TelemetrySchemaRepository) ListTelemetriesByProducer(ctx context.Context, name, version string) ([]schema.Telemetry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	records := s.latestVariants(func(record *schemaRecord) bool {
+		for id := range record.producers {
+			producer, ok := s.producers[id]
+			if ok && producer.producer.Name == name && producer.producer.Version == version && visible(ctx, producer.tenant) {
+				return true
+			}
+		}
+		return false
+	})
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].telemetry.SchemaKey < records[j].telemetry.SchemaKey
+	})
+
+	var telemetries []schema.Telemetry
+	for _, record := range records {
+		t := record.telemetry
+		t.Attributes = record.sortedAttributes()
+		telemetries = append(telemetries, t)
+	}
+	return telemetries, nil
+}
+
+// sortedAttributes returns the attributes of a schema ordered by name
+// sortedInstances returns the instances of the producer, the most recently seen first
+func (record *producerRecord) sortedInstances() []schema.ProducerInstance {
+	var instances []schema.ProducerInstance
+	for _, instance := range record.instances {
+		instances = append(instances, *instance)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if !instances[i].LastSeen.Equal(instances[j].LastSeen) {
+			return instances[i].LastSeen.After(instances[j].LastSeen)
+		}
+		return instances[i].ID < instances[j].ID
+	})
+	return instances
+}
+
+func (record *schemaRecord) sortedAttributes() []schema.Attribute {
+	var attributes []schema.Attribute
+	for attr := range record.attributes {
+		attributes = append(attributes, attr)
+	}
+	sort.Slice(attributes, func(i, j int) bool {
+		a, b := attributes[i], attributes[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Source < b.Source
+	})
+	return attributes
+}
+
+// entityMap returns the linked entities, keeping those whose link matches when match is set
+func (s *Store) entityMap(links map[string]*link, match func(*link) bool) map[string]*schema.Entity {
+	entities := make(map[string]*schema.Entity, len(links))
+	for id, l := range links {
+		record, ok := s.entities[id]
+		if !ok || (match != nil && !match(l)) {
+			continue
+		}
+		e := record.entity
+		e.Attributes = copyAttributes(e.Attributes)
+		entities[id] = &e
+	}
+	return entities
+}
+
+// linkedScopes returns the linked scopes, with the activity of their link when withLink is set
+func (s *Store) linkedScopes(links map[string]*link, withLink bool) []schema.Scope {
+	var scopes []schema.Scope
+	for id, l := range links {
+		record, ok := s.scopes[id]
+		if !ok {
+			continue
+		}
+		sc := record.scope
+		sc.Attributes = copyAttributes(sc.Attributes)
+		if withLink {
+			sc.Link = l.schemaLink()
+		}
+		scopes = append(scopes, sc)
+	}
+	return scopes
+}
+
+// latestScope returns the scope of the schemas seen most recently, with the activity of its link
+// from links when they are set
+func (s *Store) latestScope(records []*schemaRecord, links map[string]*link) *schema.Scope {
+	var latest *schema.Scope
+	for _, record := range records {
+		for _, sc := range s.linkedScopes(record.scopes, links != nil) {
+			if latest == nil || sc.LastSeen.After(latest.LastSeen) {
+				sc := sc
+				latest = &sc
+			}
+		}
+	}
+	return latest
+}
+
+// aggregateLink widens the link of an ID in links by the activity of another link
+func aggregateLink(links map[string]*link, id string, l *link) {
+	aggregated, ok := links[id]
+	if !ok {
+		copied := *l
+		links[id] = &copied
+		return
+	}
+	if l.firstSeen.Before(aggregated.firstSeen) {
+		aggregated.firstSeen = l.firstSeen
+	}
+	if l.lastSeen.After(aggregated.lastSeen) {
+		aggregated.lastSeen = l.lastSeen
+	}
+	aggregated.seenCount += l.seenCount
+}
+
+// aggregateSenders aggregates the clients that sent any of the schemas
+func aggregateSenders(records []*schemaRecord) []schema.Sender {
+	links := make(map[string]*link)
+	for _, record := range records {
+		for identity, l := range record.senders {
+			aggregateLink(links, identity, l)
+		}
+	}
+
+	var senders []schema.Sender
+	for identity, l := range links {
+		senders = append(senders, schema.Sender{Identity: identity, FirstSeen: l.firstSeen, LastSeen: l.lastSeen, SeenCount: l.seenCount})
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		if !senders[i].LastSeen.Equal(senders[j].LastSeen) {
+			return senders[i].LastSeen.After(senders[j].LastSeen)
+		}
+		return senders[i].Identity < senders[j].Identity
+	})
+	return senders
+}
+
+func sortEntities(entities []schema.Entity) {
+	sort.Slice(entities, func(i, j int) bool {
+		if !entities[i].LastSeen.Equal(entities[j].LastSeen) {
+			return entities[i].LastSeen.After(entities[j].LastSeen)
+		}
+		return entities[i].ID < entities[j].ID
+	})
+}
+
+func sortScopes(scopes []schema.Scope) {
+	sort.Slice(scopes, func(i, j int) bool {
+		if !scopes[i].LastSeen.Equal(scopes[j].LastSeen) {
+			return scopes[i].LastSeen.After(scopes[j].LastSeen)
+		}
+		return scopes[i].ID < scopes[j].ID
+	})
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type TenantRepository struct {
+	store *Store
+}
+
+func NewTenantRepository(store *Store) *TenantRepository {
+	return &TenantRepository{
+		store: store,
+	}
+}
+
+// ListTenants sizes the catalog of every tenant with telemetry
+func (r *TenantRepository) ListTenants(ctx context.Context) ([]schema.TenantSummary, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	s := r.store
+	summaries := make(map[string]*schema.TenantSummary)
+	keys := make(map[[3]string]struct{})
+	for _, record := range s.schemas {
+		t := record.telemetry
+		summary, ok := summaries[t.Tenant]
+		if !ok {
+			summary = &schema.TenantSummary{Name: t.Tenant}
+			summaries[t.Tenant] = summary
+		}
+		key := [3]string{t.Tenant, string(t.TelemetryType), t.SchemaKey}
+		if _, ok := keys[key]; !ok {
+			keys[key] = struct{}{}
+			summary.Telemetries++
+		}
+		summary.Schemas++
+		if t.UpdatedAt.After(summary.LastSeen) {
+			summary.LastSeen = t.UpdatedAt
+		}
+	}
+	for _, entity := range s.entities {
+		if summary, ok := summaries[entity.tenant]; ok {
+			summary.Entities++
+		}
+	}
+	for _, scope := range s.scopes {
+		if summary, ok := summaries[scope.tenant]; ok {
+			summary.Scopes++
+		}
+	}
+
+	tenants := make([]schema.TenantSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		tenants = append(tenants, *summary)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].Name < tenants[j].Name })
+	return tenants, nil
+}
@@ -0,0 +1,554 @@
+package migrator
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrChecksumMismatch is returned when an applied migration was edited since it was applied
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	// ErrUnknownMigration is returned when the database was migrated by a newer release
+	ErrUnknownMigration = errors.New("unknown migration")
+	// ErrLocked is returned when another migrator holds the migration lock
+	ErrLocked = errors.New("migrations are locked")
+	// ErrLockLost is returned when the migration lock was broken by another migrator while
+	// migrations ran
+	ErrLockLost = errors.New("migration lock lost")
+)
+
+// StaleLockAfter is how long a migration lock goes without a heartbeat before it is taken to be
+// left by a crashed migrator and broken
+const StaleLockAfter = 15 * time.Minute
+
+// lockHeartbeat is how often a held migration lock is refreshed, well within StaleLockAfter
+const lockHeartbeat = StaleLockAfter / 5
+
+// Migration represents a database migration
+type Migration struct {
+	Version int
+	Name    string
+	UpSQL   string
+	DownSQL string
+	// Checksum is the SHA-256 of the up and down SQL, recorded when the migration is applied
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// MigrationState is the state of a migration in a database
+type MigrationState string
+
+const (
+	MigrationStatePending MigrationState = "pending"
+	MigrationStateApplied MigrationState = "applied"
+	// MigrationStateModified migrations were edited since they were applied
+	MigrationStateModified MigrationState = "modified"
+	// MigrationStateUnknown migrations were applied by a newer release
+	MigrationStateUnknown MigrationState = "unknown"
+)
+
+// MigrationStatus is a migration known to the release or applied to the database
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	State     MigrationState
+	AppliedAt *time.Time
+}
+
+// Migrator handles database migrations
+type Migrator struct {
+	db     *sql.DB
+	logger *slog.Logger
+	// heartbeat is how often the migration lock is refreshed while held
+	heartbeat time.Duration
+	// owner identifies the migration lock taken by this migrator, empty when it holds none
+	owner string
+}
+
+// New creates a new Migrator instance
+func New(db *sql.DB, logger *slog.Logger) *Migrator {
+	return &Migrator{
+		db:        db,
+		logger:    logger,
+		heartbeat: lockHeartbeat,
+	}
+}
+
+// Initialize creates the migrations and lock tables if they don't exist. Migration tables
+// created before checksums were recorded get a checksum column, filled on the next migration.
+func (m *Migrator) Initialize() error {
+	if _, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			checksum TEXT
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	if _, err := m.db.Exec(`SELECT checksum FROM schema_migrations LIMIT 0`); err != nil {
+		if _, err := m.db.Exec(`ALTER TABLE schema_migrations ADD COLUMN checksum TEXT`); err != nil {
+			return fmt.Errorf("failed to add checksum column to migrations table: %w", err)
+		}
+	}
+
+	if _, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+			id INTEGER PRIMARY KEY,
+			locked_by TEXT NOT NULL,
+			locked_at TIMESTAMP NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create migration lock table: %w", err)
+	}
+	return nil
+}
+
+// LoadMigrations loads migrations from the embedded filesystem
+func (m *Migrator) LoadMigrations(migrationsFS fs.FS) ([]Migration, error) {
+	var migrations []Migration
+	migrationMap := make(map[int]*Migration)
+
+	err := fs.WalkDir(migrationsFS, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || !strings.HasSuffix(path, ".sql") {
+			return nil
+		}
+
+		m.logger.Debug("processing migration file", "path", path)
+
+		// Parse version and name from filename (e.g., 000001_create_users.up.sql)
+		parts := strings.Split(d.Name(), "_")
+		if len(parts) < 2 {
+			return fmt.Errorf("invalid migration filename: %s", d.Name())
+		}
+
+		version := 0
+		fmt.Sscanf(parts[0], "%d", &version)
+		if version == 0 {
+			return fmt.Errorf("invalid migration version in filename: %s", d.Name())
+		}
+
+		// Read migration file
+		content, err := fs.ReadFile(migrationsFS, path)
+		if err != nil {
+			return fmt.Errorf("failed to read migration file %s: %w", path, err)
+		}
+
+		m.logger.Debug("read migration file", "path", path, "content_length", len(content))
+
+		// Get or create migration
+		migration, exists := migrationMap[version]
+		if !exists {
+			name := strings.Join(parts[1:], "_")
+			name = strings.TrimSuffix(strings.TrimSuffix(name, ".up.sql"), ".down.sql")
+			migration = &Migration{
+				Version: version,
+				Name:    name,
+			}
+			migrationMap[version] = migration
+		}
+
+		// Set SQL content based on file type
+		if strings.HasSuffix(path, ".up.sql") {
+			migration.UpSQL = string(content)
+			m.logger.Debug("set up migration SQL", "version", version, "sql_length", len(migration.UpSQL))
+		} else if strings.HasSuffix(path, ".down.sql") {
+			migration.DownSQL = string(content)
+			m.logger.Debug("set down migration SQL", "version", version, "sql_length", len(migration.DownSQL))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert map to slice after all SQL content is set
+	for _, migration := range migrationMap {
+		sum := sha256.Sum256([]byte(migration.UpSQL + "\x00" + migration.DownSQL))
+		migration.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *migration)
+	}
+
+	// Sort migrations by version
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	// Log loaded migrations
+	for _, migration := range migrations {
+		m.logger.Debug("loaded migration",
+			"version", migration.Version,
+			"name", migration.Name,
+			"up_sql_length", len(migration.UpSQL),
+			"down_sql_length", len(migration.DownSQL))
+	}
+
+	return migrations, nil
+}
+
+// GetAppliedMigrations returns a map of applied migrations
+func (m *Migrator) GetAppliedMigrations() (map[int]Migration, error) {
+	rows, err := m.db.Query("SELECT version, name, applied_at, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]Migration)
+	for rows.Next() {
+		var migration Migration
+		var checksum sql.NullString
+		if err := rows.Scan(&migration.Version, &migration.Name, &migration.AppliedAt, &checksum); err != nil {
+			return nil, err
+		}
+		migration.Checksum = checksum.String
+		applied[migration.Version] = migration
+	}
+	return applied, rows.Err()
+}
+
+// Version returns the highest migration applied to the database, 0 when none is
+func (m *Migrator) Version() (int, error) {
+	var version int
+	if err := m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return version, nil
+}
+
+// Status returns the state of every migration, known to the release or applied to the database,
+// by version
+func (m *Migrator) Status(migrations []Migration) ([]MigrationStatus, error) {
+	applied, err := m.GetAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: MigrationStatePending}
+		if a, ok := applied[migration.Version]; ok {
+			appliedAt := a.AppliedAt
+			status.AppliedAt = &appliedAt
+			status.State = MigrationStateApplied
+			if a.Checksum != "" && a.Checksum != migration.Checksum {
+				status.State = MigrationStateModified
+			}
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, MigrationStatus{Version: a.Version, Name: a.Name, State: MigrationStateUnknown, AppliedAt: &appliedAt})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Verify checks every applied migration is known to the release and unchanged since it was
+// applied. Migrations applied before checksums were recorded are trusted, and their checksum is
+// recorded.
+func (m *Migrator) Verify(migrations []Migration) error {
+	applied, err := m.GetAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		known[migration.Version] = migration
+	}
+
+	versions := make([]int, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+
+	for _, version := range versions {
+		a := applied[version]
+		migration, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: the database has migration %d (%s) applied, which this release does not know, it was likely migrated by a newer release",
+				ErrUnknownMigration, version, a.Name)
+		}
+		if a.Checksum == "" {
+			if _, err := m.db.Exec("UPDATE schema_migrations SET checksum = ? WHERE version = ?", migration.Checksum, version); err != nil {
+				return fmt.Errorf("failed to record checksum of migration %d: %w", version, err)
+			}
+			continue
+		}
+		if a.Checksum != migration.Checksum {
+			return fmt.Errorf("%w: migration %d (%s) was edited after it was applied", ErrChecksumMismatch, version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// Lock takes the migration lock, so that a single migrator changes the database at a time. The lock
+// is refreshed by a heartbeat while held, and locks without one for StaleLockAfter are broken. The
+// returned function releases the lock.
+func (m *Migrator) Lock() (func(), error) {
+	hostname, _ := os.Hostname()
+	nonce := make([]byte, 4)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate migration lock owner: %w", err)
+	}
+	owner := fmt.Sprintf("%s:%d:%s", hostname, os.Getpid(), hex.EncodeToString(nonce))
+
+	var lockedBy string
+	var lockedAt time.Time
+	err := m.db.QueryRow("SELECT locked_by, locked_at FROM schema_migrations_lock WHERE id = 1").Scan(&lockedBy, &lockedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read migration lock: %w", err)
+	case time.Since(lockedAt) < StaleLockAfter:
+		return nil, fmt.Errorf("%w by %s since %s", ErrLocked, lockedBy, lockedAt.Format(time.RFC3339))
+	default:
+		m.logger.Warn("breaking stale migration lock", "locked_by", lockedBy, "locked_at", lockedAt)
+		// A heartbeat since the lock was read keeps it
+		if _, err := m.db.Exec("DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_by = ? AND locked_at = ?", lockedBy, lockedAt); err != nil {
+			return nil, fmt.Errorf("failed to break stale migration lock: %w", err)
+		}
+	}
+
+	// The primary key refuses a second lock taken at the same time
+	if _, err := m.db.Exec("INSERT INTO schema_migrations_lock (id, locked_by, locked_at) VALUES (1, ?, ?)", owner, time.Now().UTC()); err != nil {
+		return nil, fmt.Errorf("%w: failed to take migration lock: %v", ErrLocked, err)
+	}
+	m.owner = owner
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(m.heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			result, err := m.db.Exec("UPDATE schema_migrations_lock SET locked_at = ? WHERE id = 1 AND locked_by = ?", time.Now().UTC(), owner)
+			if err != nil {
+				m.logger.Error("failed to refresh migration lock", "error", err)
+				continue
+			}
+			if n, err := result.RowsAffected(); err == nil && n == 0 {
+				m.logger.Error("migration lock was broken by another migrator", "owner", owner)
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+		m.owner = ""
+		if _, err := m.db.Exec("DELETE FROM schema_migrations_lock WHERE id = 1 AND locked_by = ?", owner); err != nil {
+			m.logger.Error("failed to release migration lock", "error", err)
+		}
+	}, nil
+}
+
+// checkLock returns ErrLockLost unless the migration lock is still held by this migrator
+func (m *Migrator) checkLock(tx *sql.Tx) error {
+	var lockedBy string
+	err := tx.QueryRow("SELECT locked_by FROM schema_migrations_lock WHERE id = 1").Scan(&lockedBy)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("%w: the lock was released", ErrLockLost)
+	case err != nil:
+		return fmt.Errorf("failed to read migration lock: %w", err)
+	case lockedBy != m.owner:
+		return fmt.Errorf("%w: the lock is held by %s", ErrLockLost, lockedBy)
+	}
+	return nil
+}
+
+// Migrate applies all pending migrations
+func (m *Migrator) Migrate(migrations []Migration) error {
+	if len(migrations) == 0 {
+		return nil
+	}
+	return m.MigrateTo(migrations, migrations[len(migrations)-1].Version)
+}
+
+// MigrateTo applies the pending migrations up to the version and rolls back the applied ones
+// above it, under the migration lock. Nothing is done unless every applied migration is known
+// and unchanged.
+func (m *Migrator) MigrateTo(migrations []Migration, version int) error {
+	if version < 0 {
+		return fmt.Errorf("invalid migration version %d", version)
+	}
+	if version > 0 && !hasVersion(migrations, version) {
+		return fmt.Errorf("%w: migration %d does not exist", ErrUnknownMigration, version)
+	}
+
+	unlock, err := m.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := m.Verify(migrations); err != nil {
+		return err
+	}
+	applied, err := m.GetAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	// Roll back from the last applied migration down, then apply from the first pending one up
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, exists := applied[migration.Version]; !exists || migration.Version <= version {
+			continue
+		}
+		if err := m.down(migration); err != nil {
+			return err
+		}
+	}
+	for _, migration := range migrations {
+		if _, exists := applied[migration.Version]; exists || migration.Version > version {
+			continue
+		}
+		if err := m.up(migration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasVersion(migrations []Migration, version int) bool {
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// up applies a migration and records it with its checksum
+func (m *Migrator) up(migration Migration) error {
+	m.logger.Info("applying migration",
+		"version", migration.Version,
+		"name", migration.Name,
+		"sql_length", len(migration.UpSQL))
+
+	if len(migration.UpSQL) == 0 {
+		return fmt.Errorf("empty SQL for migration %d", migration.Version)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.checkLock(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("refusing to apply migration %d: %w", migration.Version, err)
+	}
+
+	// Apply migration
+	if _, err := tx.Exec(migration.UpSQL); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+	}
+
+	// Record migration
+	if _, err := tx.Exec(
+		"INSERT INTO schema_migrations (version, name, checksum) VALUES (?, ?, ?)",
+		migration.Version,
+		migration.Name,
+		migration.Checksum,
+	); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+	}
+
+	m.logger.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// Rollback rolls back the last applied migration
+func (m *Migrator) Rollback(migrations []Migration) error {
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+	if version == 0 {
+		return fmt.Errorf("no migrations to rollback")
+	}
+
+	// The previous version is the highest known migration below the last applied one
+	previous := 0
+	for _, migration := range migrations {
+		if migration.Version < version {
+			previous = migration.Version
+		}
+	}
+	return m.MigrateTo(migrations, previous)
+}
+
+// down rolls back a migration and removes its record
+func (m *Migrator) down(migration Migration) error {
+	m.logger.Info("rolling back migration", "version", migration.Version, "name", migration.Name)
+
+	if len(migration.DownSQL) == 0 {
+		return fmt.Errorf("no down SQL for migration %d", migration.Version)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := m.checkLock(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("refusing to roll back migration %d: %w", migration.Version, err)
+	}
+
+	// Apply down migration
+	if _, err := tx.Exec(migration.DownSQL); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to rollback migration %d: %w", migration.Version, err)
+	}
+
+	// Remove migration record
+	if _, err := tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to remove migration record %d: %w", migration.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rollback of migration %d: %w", migration.Version, err)
+	}
+
+	m.logger.Info("rolled back migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
@@ -0,0 +1,184 @@
+package migrator
+
+import (
+	"database/sql"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"migrations/000001_create_teams.up.sql":      {Data: []byte(`CREATE TABLE teams (name TEXT PRIMARY KEY);`)},
+		"migrations/000001_create_teams.down.sql":    {Data: []byte(`DROP TABLE teams;`)},
+		"migrations/000002_create_members.up.sql":    {Data: []byte(`CREATE TABLE members (name TEXT PRIMARY KEY, team TEXT);`)},
+		"migrations/000002_create_members.down.sql":  {Data: []byte(`DROP TABLE members;`)},
+		"migrations/000003_add_member_role.up.sql":   {Data: []byte(`ALTER TABLE members ADD COLUMN role TEXT;`)},
+		"migrations/000003_add_member_role.down.sql": {Data: []byte(`ALTER TABLE members DROP COLUMN role;`)},
+	}
+}
+
+func setupMigrator(t *testing.T) (*Migrator, *sql.DB) {
+	// The heartbeat writes while migrations read, as with the WAL mode of the SQLite backend
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrations.db")+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(10000)")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	m := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, m.Initialize())
+	return m, db
+}
+
+func states(t *testing.T, m *Migrator, migrations []Migration) []MigrationState {
+	statuses, err := m.Status(migrations)
+	require.NoError(t, err)
+	var states []MigrationState
+	for _, status := range statuses {
+		states = append(states, status.State)
+	}
+	return states
+}
+
+func TestMigrator_MigrateToAndRollback(t *testing.T) {
+	m, _ := setupMigrator(t)
+	migrations, err := m.LoadMigrations(testMigrations())
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	require.Equal(t, "create_teams", migrations[0].Name)
+	require.Equal(t, "add_member_role", migrations[2].Name)
+
+	require.NoError(t, m.MigrateTo(migrations, 2))
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+	require.Equal(t, []MigrationState{MigrationStateApplied, MigrationStateApplied, MigrationStatePending}, states(t, m, migrations))
+
+	require.NoError(t, m.Migrate(migrations))
+	version, err = m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 3, version)
+
+	require.NoError(t, m.Rollback(migrations))
+	version, err = m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	require.NoError(t, m.MigrateTo(migrations, 0))
+	require.Equal(t, []MigrationState{MigrationStatePending, MigrationStatePending, MigrationStatePending}, states(t, m, migrations))
+
+	require.ErrorIs(t, m.MigrateTo(migrations, 7), ErrUnknownMigration)
+}
+
+func TestMigrator_RefusesEditedAndUnknownMigrations(t *testing.T) {
+	m, db := setupMigrator(t)
+	migrations, err := m.LoadMigrations(testMigrations())
+	require.NoError(t, err)
+	require.NoError(t, m.Migrate(migrations))
+
+	// Editing an applied migration is detected
+	edited := testMigrations()
+	edited["migrations/000002_create_members.up.sql"] = &fstest.MapFile{Data: []byte(`CREATE TABLE members (name TEXT);`)}
+	editedMigrations, err := m.LoadMigrations(edited)
+	require.NoError(t, err)
+	require.ErrorIs(t, m.Migrate(editedMigrations), ErrChecksumMismatch)
+	require.Equal(t, []MigrationState{MigrationStateApplied, MigrationStateModified, MigrationStateApplied}, states(t, m, editedMigrations))
+
+	// An older release does not know the last migration
+	older, err := m.LoadMigrations(testMigrations())
+	require.NoError(t, err)
+	older = older[:2]
+	require.ErrorIs(t, m.Migrate(older), ErrUnknownMigration)
+	require.ErrorIs(t, m.Rollback(older), ErrUnknownMigration)
+	require.Equal(t, []MigrationState{MigrationStateApplied, MigrationStateApplied, MigrationStateUnknown}, states(t, m, older))
+
+	// Migrations applied before checksums were recorded are trusted once
+	_, err = db.Exec(`UPDATE schema_migrations SET checksum = NULL`)
+	require.NoError(t, err)
+	require.NoError(t, m.Verify(migrations))
+	require.ErrorIs(t, m.Verify(editedMigrations), ErrChecksumMismatch)
+}
+
+func TestMigrator_Lock(t *testing.T) {
+	m, db := setupMigrator(t)
+	migrations, err := m.LoadMigrations(testMigrations())
+	require.NoError(t, err)
+
+	unlock, err := m.Lock()
+	require.NoError(t, err)
+	_, err = m.Lock()
+	require.ErrorIs(t, err, ErrLocked)
+	require.ErrorIs(t, m.Migrate(migrations), ErrLocked)
+	unlock()
+
+	require.NoError(t, m.Migrate(migrations))
+
+	// A lock left by a crashed migrator is broken once stale
+	_, err = db.Exec(`INSERT INTO schema_migrations_lock (id, locked_by, locked_at) VALUES (1, 'crashed:1', ?)`, time.Now().UTC().Add(-2*StaleLockAfter))
+	require.NoError(t, err)
+	unlock, err = m.Lock()
+	require.NoError(t, err)
+	unlock()
+}
+
+func TestMigrator_LockHeartbeat(t *testing.T) {
+	m, db := setupMigrator(t)
+	m.heartbeat = 10 * time.Millisecond
+
+	unlock, err := m.Lock()
+	require.NoError(t, err)
+	var takenAt time.Time
+	require.NoError(t, db.QueryRow(`SELECT locked_at FROM schema_migrations_lock`).Scan(&takenAt))
+
+	// A held lock is refreshed, so it is never taken to be stale
+	require.Eventually(t, func() bool {
+		var lockedAt time.Time
+		require.NoError(t, db.QueryRow(`SELECT locked_at FROM schema_migrations_lock`).Scan(&lockedAt))
+		return lockedAt.After(takenAt)
+	}, time.Second, 10*time.Millisecond)
+	unlock()
+
+	// A migrator whose lock was broken while migrating stops before the next migration
+	migrations, err := m.LoadMigrations(fstest.MapFS{
+		"migrations/000001_create_teams.up.sql":     {Data: []byte(`CREATE TABLE teams (name TEXT PRIMARY KEY); UPDATE schema_migrations_lock SET locked_by = 'other:1';`)},
+		"migrations/000001_create_teams.down.sql":   {Data: []byte(`DROP TABLE teams;`)},
+		"migrations/000002_create_members.up.sql":   {Data: []byte(`CREATE TABLE members (name TEXT PRIMARY KEY, team TEXT);`)},
+		"migrations/000002_create_members.down.sql": {Data: []byte(`DROP TABLE members;`)},
+	})
+	require.NoError(t, err)
+	require.ErrorIs(t, m.Migrate(migrations), ErrLockLost)
+	version, err := m.Version()
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+}
+
+func TestMigrator_InitializeAddsChecksumColumn(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrations.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The migrations table as created before checksums were recorded
+	_, err = db.Exec(`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO schema_migrations (version, name) VALUES (1, 'create_teams')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE teams (name TEXT PRIMARY KEY)`)
+	require.NoError(t, err)
+
+	m := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, m.Initialize())
+	require.NoError(t, m.Initialize())
+	migrations, err := m.LoadMigrations(testMigrations())
+	require.NoError(t, err)
+	require.NoError(t, m.Migrate(migrations))
+
+	applied, err := m.GetAppliedMigrations()
+	require.NoError(t, err)
+	require.Len(t, applied, 3)
+	require.Equal(t, migrations[0].Checksum, applied[1].Checksum)
+}
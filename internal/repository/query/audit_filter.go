@@ -0,0 +1,9 @@
+package query
+
+// AuditFilter narrows audit entries down to a subject, HTTP method and time window.
+// Empty fields match everything.
+type AuditFilter struct {
+	Subject string
+	Method  string
+	Window  TimeWindow
+}
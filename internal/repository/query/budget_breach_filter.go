@@ -0,0 +1,9 @@
+package query
+
+// BudgetBreachFilter narrows budget breaches down to a budget, limit and time window.
+// Empty fields match everything.
+type BudgetBreachFilter struct {
+	Budget string
+	Limit  string
+	Window TimeWindow
+}
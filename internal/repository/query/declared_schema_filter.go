@@ -0,0 +1,9 @@
+package query
+
+// DeclaredSchemaFilter narrows declared schemas down to a registry, entity type and scope.
+// Empty fields match everything.
+type DeclaredSchemaFilter struct {
+	Registry   string
+	EntityType string
+	ScopeName  string
+}
@@ -3,6 +3,8 @@ package query
 type ListQueryParams struct {
 	FilterType string
 	Search     string
-	Page       int
-	PageSize   int
+	// Owner restricts results to the assets owned by a team
+	Owner    string
+	Page     int
+	PageSize int
 }
@@ -0,0 +1,10 @@
+package query
+
+// PolicyViolationFilter narrows policy violations down to a policy, schema key, mode and time window.
+// Empty fields match everything.
+type PolicyViolationFilter struct {
+	Policy    string
+	SchemaKey string
+	Mode      string
+	Window    TimeWindow
+}
@@ -0,0 +1,9 @@
+package query
+
+// ReviewFilter narrows telemetry reviews down to a state, schema key and reason.
+// Empty fields match everything.
+type ReviewFilter struct {
+	State     string
+	SchemaKey string
+	Reason    string
+}
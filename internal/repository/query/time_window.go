@@ -0,0 +1,14 @@
+package query
+
+import "time"
+
+// TimeWindow restricts results to relationships active between Since and Until.
+// A zero bound is unbounded.
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+}
+
+func (w TimeWindow) IsZero() bool {
+	return w.Since.IsZero() && w.Until.IsZero()
+}
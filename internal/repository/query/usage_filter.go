@@ -0,0 +1,10 @@
+package query
+
+// UsageFilter selects the usage rollups of a telemetry key or an entity.
+// Resolution is hour or day.
+type UsageFilter struct {
+	SchemaKey  string
+	EntityID   string
+	Resolution string
+	Window     TimeWindow
+}
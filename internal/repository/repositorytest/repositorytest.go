@@ -0,0 +1,786 @@
+// Package repositorytest checks that implementations of the catalog repositories behave alike.
+// Each backend runs the suite from its own tests.
+package repositorytest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// Backend holds the repositories of a storage backend, sharing one empty catalog
+type Backend struct {
+	Schemas repository.TelemetrySchemaRepository
+	History repository.TelemetryHistoryRepository
+	Tenants repository.TenantRepository
+	// Rekey is unset for backends keeping nothing across restarts, whose entities always have
+	// the IDs of the entity model they run with
+	Rekey repository.EntityRekeyRepository
+	// ProducerRekey is unset for backends keeping nothing across restarts, whose producers always
+	// have their own IDs
+	ProducerRekey repository.ProducerRekeyRepository
+}
+
+// Run runs the conformance suite, calling newBackend for an empty catalog in every test
+func Run(t *testing.T, newBackend func(t *testing.T) Backend) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, b Backend)
+	}{
+		{"RegistrationIsIdempotent", testRegistrationIsIdempotent},
+		{"SeenCountsMerge", testSeenCountsMerge},
+		{"LatestVariant", testLatestVariant},
+		{"EntityAndScopeLinks", testEntityAndScopeLinks},
+		{"Pagination", testPagination},
+		{"Search", testSearch},
+		{"VersionAssignment", testVersionAssignment},
+		{"VersionCompatibility", testVersionCompatibility},
+		{"History", testHistory},
+		{"TenantIsolation", testTenantIsolation},
+		{"TenantSummaries", testTenantSummaries},
+		{"EntityRekey", testEntityRekey},
+		{"ProducerInstances", testProducerInstances},
+		{"ProducerRekey", testProducerRekey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newBackend(t))
+		})
+	}
+}
+
+var t0 = time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+// Telemetry returns a sum metric of the schema key seen at the given time by the checkout
+// service, through the otelhttp scope
+func Telemetry(schemaID, schemaKey string, attributes []string, seenAt time.Time) schema.Telemetry {
+	attrs := make([]schema.Attribute, 0, len(attributes))
+	for _, name := range attributes {
+		attrs = append(attrs, schema.Attribute{
+			Name:   name,
+			Type:   schema.AttributeTypeStr,
+			Source: schema.AttributeSourceDataPoint,
+		})
+	}
+
+	return schema.Telemetry{
+		SchemaID:      schemaID,
+		SchemaKey:     schemaKey,
+		TelemetryType: schema.TelemetryTypeMetric,
+		MetricType:    schema.MetricTypeSum,
+		MetricUnit:    "1",
+		Protocol:      schema.TelemetryProtocolOTLP,
+		SeenCount:     1,
+		CreatedAt:     seenAt,
+		UpdatedAt:     seenAt,
+		Attributes:    attrs,
+		Entities: map[string]*schema.Entity{
+			"checkout": {
+				ID:   "checkout",
+				Type: "service",
+				Attributes: map[string]interface{}{
+					"service.name": "checkout-service",
+				},
+				FirstSeen: seenAt,
+				LastSeen:  seenAt,
+			},
+		},
+		Scope: &schema.Scope{
+			ID:         "otelhttp",
+			Name:       "go.opentelemetry.io/contrib/otelhttp",
+			Version:    "0.60.0",
+			Attributes: map[string]interface{}{},
+			FirstSeen:  seenAt,
+			LastSeen:   seenAt,
+		},
+	}
+}
+
+func register(t *testing.T, ctx context.Context, b Backend, telemetries ...schema.Telemetry) {
+	t.Helper()
+	require.NoError(t, b.Schemas.RegisterTelemetrySchemas(ctx, telemetries))
+}
+
+func attributeNames(attributes []schema.Attribute) []string {
+	names := make([]string, 0, len(attributes))
+	for _, attr := range attributes {
+		names = append(names, attr.Name)
+	}
+	return names
+}
+
+func requireTime(t *testing.T, expected, actual time.Time) {
+	t.Helper()
+	require.True(t, expected.Equal(actual), "expected %v, got %v", expected, actual)
+}
+
+func testRegistrationIsIdempotent(t *testing.T, b Backend) {
+	ctx := context.Background()
+	batch := []schema.Telemetry{
+		Telemetry("v1", "checkout.requests", []string{"http.method", "http.route"}, t0),
+		Telemetry("d1", "checkout.duration", []string{"http.method"}, t0),
+	}
+	register(t, ctx, b, batch...)
+	register(t, ctx, b, batch...)
+
+	telemetries, total, err := b.Schemas.ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Len(t, telemetries, 2)
+
+	telemetry, err := b.Schemas.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.NotNil(t, telemetry)
+	require.Equal(t, "v1", telemetry.SchemaID)
+	// Seeing a schema again at the same time is not counted twice
+	require.Equal(t, 1, telemetry.SeenCount)
+	require.Equal(t, []string{"http.method", "http.route"}, attributeNames(telemetry.Attributes))
+	require.Len(t, telemetry.Entities, 1)
+
+	schemas, total, err := b.Schemas.ListTelemetrySchemas(ctx, "checkout.requests", query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "v1", schemas[0].SchemaId)
+	require.Equal(t, 1, schemas[0].EntityCount)
+
+	entities, total, err := b.Schemas.ListEntities(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Len(t, entities, 1)
+
+	_, total, err = b.Schemas.ListScopes(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	// A single variant of a key has no history
+	_, total, err = b.History.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Zero(t, total)
+}
+
+func testSeenCountsMerge(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0))
+	seen := Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(time.Hour))
+	seen.SeenCount = 4
+	register(t, ctx, b, seen)
+	// An export older than the last one does not move the schema back
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(-time.Hour)))
+
+	telemetry, err := b.Schemas.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, 5, telemetry.SeenCount)
+	requireTime(t, t0.Add(time.Hour), telemetry.UpdatedAt)
+	requireTime(t, t0, telemetry.CreatedAt)
+
+	// Links count every sighting and span all of them
+	entities, err := b.Schemas.ListEntitiesByTelemetry(ctx, "checkout.requests", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.NotNil(t, entities[0].Link)
+	require.Equal(t, int64(6), entities[0].Link.SeenCount)
+	requireTime(t, t0.Add(-time.Hour), entities[0].Link.FirstSeen)
+	requireTime(t, t0.Add(time.Hour), entities[0].Link.LastSeen)
+	requireTime(t, t0.Add(time.Hour), entities[0].LastSeen)
+
+	// Links are filtered by their activity
+	entities, err = b.Schemas.ListEntitiesByTelemetry(ctx, "checkout.requests", query.TimeWindow{Since: t0.Add(2 * time.Hour)})
+	require.NoError(t, err)
+	require.Empty(t, entities)
+
+	scopes, err := b.Schemas.ListScopesByTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	require.NotNil(t, scopes[0].Link)
+	require.Equal(t, int64(6), scopes[0].Link.SeenCount)
+}
+
+func testLatestVariant(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b,
+		Telemetry("v1", "checkout.requests", []string{"http.method"}, t0),
+		Telemetry("v2", "checkout.requests", []string{"http.method", "payment.provider"}, t0.Add(time.Hour)),
+	)
+	// Seeing the first variant again makes it the latest
+	register(t, ctx, b, Telemetry("v3", "checkout.requests", []string{"http.route"}, t0.Add(30*time.Minute)))
+
+	telemetries, total, err := b.Schemas.ListTelemetries(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "v2", telemetries[0].SchemaID)
+
+	telemetry, err := b.Schemas.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, "v2", telemetry.SchemaID)
+	require.Equal(t, []string{"http.method", "payment.provider"}, attributeNames(telemetry.Attributes))
+
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(2*time.Hour)))
+	telemetry, err = b.Schemas.GetTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Equal(t, "v1", telemetry.SchemaID)
+
+	byEntity, err := b.Schemas.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, byEntity, 1)
+	require.Equal(t, "v1", byEntity[0].SchemaID)
+
+	byScope, err := b.Schemas.ListTelemetriesByScope(ctx, "go.opentelemetry.io/contrib/otelhttp")
+	require.NoError(t, err)
+	require.Len(t, byScope, 1)
+	require.Equal(t, "v1", byScope[0].SchemaID)
+
+	schemas, total, err := b.Schemas.ListTelemetrySchemas(ctx, "checkout.requests", query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
+	require.Len(t, schemas, 3)
+
+	missing, err := b.Schemas.GetTelemetry(ctx, "unknown")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+}
+
+func testEntityAndScopeLinks(t *testing.T, b Backend) {
+	ctx := context.Background()
+	checkout := Telemetry("v1", "checkout.requests", []string{"http.method"}, t0)
+	checkout.Producers = map[string]*schema.Producer{
+		"checkout-1": {ID: "checkout-1", Name: "checkout-service", Version: "1.4.0", FirstSeen: t0, LastSeen: t0},
+	}
+	payments := Telemetry("p1", "payments.requests", []string{"payment.provider"}, t0.Add(time.Hour))
+	payments.Entities = map[string]*schema.Entity{
+		"payments": {
+			ID:         "payments",
+			Type:       "service",
+			Attributes: map[string]interface{}{"service.name": "payments-service"},
+			FirstSeen:  t0.Add(time.Hour),
+			LastSeen:   t0.Add(time.Hour),
+		},
+		"node-1": {
+			ID:         "node-1",
+			Type:       "host",
+			Attributes: map[string]interface{}{"host.name": "node-1"},
+			FirstSeen:  t0.Add(time.Hour),
+			LastSeen:   t0.Add(time.Hour),
+		},
+	}
+	payments.Scope = &schema.Scope{
+		ID:         "payments-sdk",
+		Name:       "payments/sdk",
+		Version:    "2.0.0",
+		Attributes: map[string]interface{}{"sdk.language": "go"},
+		FirstSeen:  t0.Add(time.Hour),
+		LastSeen:   t0.Add(time.Hour),
+	}
+	register(t, ctx, b, checkout, payments)
+
+	telemetry, err := b.Schemas.GetTelemetry(ctx, "payments.requests")
+	require.NoError(t, err)
+	require.Len(t, telemetry.Entities, 2)
+	require.Equal(t, "payments-service", telemetry.Entities["payments"].Attributes["service.name"])
+	require.NotNil(t, telemetry.Scope)
+	require.Equal(t, "payments/sdk", telemetry.Scope.Name)
+	require.Equal(t, "go", telemetry.Scope.Attributes["sdk.language"])
+
+	byService, err := b.Schemas.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, byService, 2)
+	require.Equal(t, "p1", byService[0].SchemaID)
+	require.Equal(t, "v1", byService[1].SchemaID)
+	require.NotNil(t, byService[0].Entities["payments"].Link)
+	require.NotNil(t, byService[0].Scope)
+	require.NotNil(t, byService[0].Scope.Link)
+
+	byHost, err := b.Schemas.ListTelemetriesByEntity(ctx, "host", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, byHost, 1)
+	require.Equal(t, "p1", byHost[0].SchemaID)
+
+	// Links are filtered by their activity
+	recent, err := b.Schemas.ListTelemetriesByEntity(ctx, "service", query.TimeWindow{Since: t0.Add(30 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, recent, 1)
+	require.Equal(t, "p1", recent[0].SchemaID)
+
+	entities, err := b.Schemas.ListEntitiesByTelemetry(ctx, "payments.requests", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, entities, 2)
+	entities, err = b.Schemas.ListEntitiesByTelemetry(ctx, "payments.requests", query.TimeWindow{Until: t0})
+	require.NoError(t, err)
+	require.Empty(t, entities)
+
+	byScope, err := b.Schemas.ListTelemetriesByScope(ctx, "payments/sdk")
+	require.NoError(t, err)
+	require.Len(t, byScope, 1)
+	require.Equal(t, "p1", byScope[0].SchemaID)
+	require.Equal(t, []string{"payment.provider"}, attributeNames(byScope[0].Attributes))
+
+	scopes, err := b.Schemas.ListScopesByTelemetry(ctx, "checkout.requests")
+	require.NoError(t, err)
+	require.Len(t, scopes, 1)
+	require.Equal(t, "otelhttp", scopes[0].ID)
+
+	telemetrySchema, err := b.Schemas.GetTelemetrySchema(ctx, "p1")
+	require.NoError(t, err)
+	require.Equal(t, 2, telemetrySchema.EntityCount)
+	require.Len(t, telemetrySchema.Scopes, 1)
+	require.NotNil(t, telemetrySchema.LastSeen)
+	requireTime(t, t0.Add(time.Hour), *telemetrySchema.LastSeen)
+
+	producers, total, err := b.Schemas.ListProducers(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "checkout-service", producers[0].Name)
+
+	byProducer, err := b.Schemas.ListTelemetriesByProducer(ctx, "checkout-service", "1.4.0")
+	require.NoError(t, err)
+	require.Len(t, byProducer, 1)
+	require.Equal(t, "v1", byProducer[0].SchemaID)
+	byProducer, err = b.Schemas.ListTelemetriesByProducer(ctx, "checkout-service", "")
+	require.NoError(t, err)
+	require.Empty(t, byProducer)
+}
+
+func testPagination(t *testing.T, b Backend) {
+	ctx := context.Background()
+	var telemetries []schema.Telemetry
+	for i := 0; i < 7; i++ {
+		telemetry := Telemetry(fmt.Sprintf("orders-%d", i), fmt.Sprintf("orders.%d", i), []string{"http.method"}, t0.Add(time.Duration(i)*time.Minute))
+		entity := *telemetry.Entities["checkout"]
+		entity.ID = fmt.Sprintf("orders-%d", i)
+		telemetry.Entities = map[string]*schema.Entity{entity.ID: &entity}
+		telemetries = append(telemetries, telemetry)
+	}
+	register(t, ctx, b, telemetries...)
+
+	var keys []string
+	for page := 1; page <= 3; page++ {
+		results, total, err := b.Schemas.ListTelemetries(ctx, query.ListQueryParams{Page: page, PageSize: 3})
+		require.NoError(t, err)
+		require.Equal(t, 7, total)
+		for _, result := range results {
+			keys = append(keys, result.SchemaKey)
+		}
+	}
+	// The telemetries seen most recently come first
+	require.Equal(t, []string{"orders.6", "orders.5", "orders.4", "orders.3", "orders.2", "orders.1", "orders.0"}, keys)
+
+	results, total, err := b.Schemas.ListTelemetries(ctx, query.ListQueryParams{Page: 4, PageSize: 3})
+	require.NoError(t, err)
+	require.Equal(t, 7, total)
+	require.Empty(t, results)
+
+	entities, total, err := b.Schemas.ListEntities(ctx, query.ListQueryParams{Page: 2, PageSize: 5})
+	require.NoError(t, err)
+	require.Equal(t, 7, total)
+	require.Len(t, entities, 2)
+	require.Equal(t, "orders-1", entities[0].ID)
+	require.Equal(t, "orders-0", entities[1].ID)
+}
+
+func testSearch(t *testing.T, b Backend) {
+	ctx := context.Background()
+	duration := Telemetry("d1", "http.server.duration", []string{"http.method"}, t0)
+	duration.MetricType = schema.MetricTypeHistogram
+	duration.MetricUnit = "ms"
+	logs := Telemetry("l1", "checkout.audit", nil, t0)
+	logs.TelemetryType = schema.TelemetryTypeLog
+	logs.MetricType = ""
+	logs.MetricUnit = ""
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0), duration, logs)
+
+	search := func(params query.ListQueryParams) []string {
+		params.Page, params.PageSize = 1, 10
+		results, total, err := b.Schemas.ListTelemetries(ctx, params)
+		require.NoError(t, err)
+		require.Equal(t, total, len(results))
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.SchemaID)
+		}
+		return ids
+	}
+
+	require.ElementsMatch(t, []string{"v1", "l1"}, search(query.ListQueryParams{Search: "checkout"}))
+	require.Equal(t, []string{"d1"}, search(query.ListQueryParams{Search: "Histogram"}))
+	require.Equal(t, []string{"d1"}, search(query.ListQueryParams{Search: "ms"}))
+	require.Empty(t, search(query.ListQueryParams{Search: "CHECKOUT"}))
+	require.Equal(t, []string{"l1"}, search(query.ListQueryParams{FilterType: "log"}))
+	require.ElementsMatch(t, []string{"v1", "d1"}, search(query.ListQueryParams{FilterType: "metric"}))
+	require.Equal(t, []string{"v1"}, search(query.ListQueryParams{FilterType: "metric", Search: "checkout"}))
+
+	entities, total, err := b.Schemas.ListEntities(ctx, query.ListQueryParams{Page: 1, PageSize: 10, Search: "service"})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, "checkout", entities[0].ID)
+
+	_, total, err = b.Schemas.ListScopes(ctx, query.ListQueryParams{Page: 1, PageSize: 10, Search: "otelhttp"})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	_, total, err = b.Schemas.ListScopes(ctx, query.ListQueryParams{Page: 1, PageSize: 10, Search: "grpc"})
+	require.NoError(t, err)
+	require.Zero(t, total)
+}
+
+func testVersionAssignment(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0))
+
+	telemetrySchema, err := b.Schemas.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "Unassigned", telemetrySchema.Version)
+
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "one"})
+	require.ErrorIs(t, err, schema.ErrInvalidVersion)
+
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "unknown", Version: "1.0.0"})
+	require.ErrorIs(t, err, schema.ErrSchemaNotFound)
+
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.0", Status: schema.VersionStatusDraft,
+	}))
+	// A draft can still change version
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusActive, Reason: "initial release",
+	}))
+
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "1.0.2"})
+	require.ErrorIs(t, err, schema.ErrVersionConflict)
+
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusRetired,
+	})
+	require.ErrorIs(t, err, schema.ErrInvalidStatusTransition)
+
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v1", Version: "1.0.1", Status: schema.VersionStatusDeprecated, ReplacedBy: "v2",
+	}))
+
+	telemetrySchema, err = b.Schemas.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Equal(t, "1.0.1", telemetrySchema.Version)
+	require.Equal(t, schema.VersionStatusDeprecated, telemetrySchema.Status)
+	require.NotNil(t, telemetrySchema.DeprecatedAt)
+	require.Equal(t, "v2", telemetrySchema.ReplacedBy)
+
+	schemas, _, err := b.Schemas.ListTelemetrySchemas(ctx, "checkout.requests", query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, "1.0.1", schemas[0].Version)
+	require.Equal(t, schema.VersionStatusDeprecated, schemas[0].Status)
+}
+
+func testVersionCompatibility(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b,
+		Telemetry("v1", "checkout.requests", []string{"http.method", "http.route"}, t0),
+		Telemetry("v2", "checkout.requests", []string{"http.method", "http.route", "payment.provider"}, t0.Add(time.Hour)),
+		Telemetry("v3", "checkout.requests", []string{"http.method"}, t0.Add(2*time.Hour)),
+	)
+
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v1", Version: "1.0.0"}))
+
+	err := b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v2", Version: "1.0.0"})
+	require.ErrorIs(t, err, schema.ErrVersionConflict)
+
+	// Adding an attribute is a compatible minor bump
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v2", Version: "1.1.0"}))
+
+	// Removing attributes needs a major bump
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{SchemaId: "v3", Version: "1.2.0"})
+	require.ErrorIs(t, err, schema.ErrIncompatibleVersion)
+
+	// Drafts are not checked until they are activated
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "1.2.0", Status: schema.VersionStatusDraft,
+	}))
+	err = b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "1.2.0", Status: schema.VersionStatusActive,
+	})
+	require.ErrorIs(t, err, schema.ErrIncompatibleVersion)
+
+	require.NoError(t, b.Schemas.AssignTelemetrySchemaVersion(ctx, schema.SchemaAssignment{
+		SchemaId: "v3", Version: "2.0.0", Status: schema.VersionStatusActive,
+	}))
+}
+
+func testHistory(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0))
+	register(t, ctx, b, Telemetry("v2", "checkout.requests", []string{"payment.provider"}, t0.Add(time.Hour)))
+	register(t, ctx, b, Telemetry("v2", "checkout.requests", []string{"payment.provider"}, t0.Add(2*time.Hour)))
+
+	histories, total, err := b.History.ListTelemetryHistory(ctx, "checkout.requests", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	history := histories[0]
+	require.NotZero(t, history.Id)
+	require.Equal(t, "v2", history.SchemaID)
+	require.Equal(t, schema.TelemetryHistoryStatusDetected, history.Status)
+	requireTime(t, t0.Add(time.Hour), history.Timestamp)
+	require.NotNil(t, history.Diff)
+	require.Equal(t, "v1", history.Diff.FromSchemaID)
+	require.Equal(t, "v2", history.Diff.ToSchemaID)
+	require.Len(t, history.Diff.AddedAttributes, 1)
+	require.Len(t, history.Diff.RemovedAttributes, 1)
+
+	author := "alice"
+	require.NoError(t, b.History.InsertTelemetryHistory(ctx, &schema.TelemetryHistory{
+		SchemaKey: "checkout.requests",
+		Version:   "1.1.0",
+		Timestamp: t0.Add(3 * time.Hour),
+		Author:    &author,
+		Summary:   "Assigned version 1.1.0",
+	}))
+
+	histories, total, err = b.History.ListTelemetryHistory(ctx, "checkout.requests", 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	require.Len(t, histories, 1)
+	require.Equal(t, "Assigned version 1.1.0", histories[0].Summary)
+	require.Equal(t, "alice", *histories[0].Author)
+	require.Empty(t, histories[0].SchemaID)
+	require.Nil(t, histories[0].Diff)
+
+	schemaID, err := b.History.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, schemaID)
+	schemaID, err = b.History.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, "v1", schemaID)
+	schemaID, err = b.History.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(4*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "v2", schemaID)
+
+	// Entries without a schema ID are followed through the telemetry of their snapshot
+	snapshot, err := json.Marshal(Telemetry("v1", "checkout.requests", []string{"http.method"}, t0))
+	require.NoError(t, err)
+	require.NoError(t, b.History.InsertTelemetryHistory(ctx, &schema.TelemetryHistory{
+		SchemaKey: "checkout.requests",
+		Timestamp: t0.Add(5 * time.Hour),
+		Summary:   "Rolled back to v1",
+		Snapshot:  snapshot,
+	}))
+	schemaID, err = b.History.GetSchemaIDAt(ctx, "checkout.requests", t0.Add(6*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "v1", schemaID)
+}
+
+func testTenantIsolation(t *testing.T, b Backend) {
+	defaultCtx := context.Background()
+	paymentsCtx := tenant.WithTenant(defaultCtx, "payments")
+	allCtx := tenant.WithTenant(defaultCtx, tenant.All)
+
+	register(t, defaultCtx, b, Telemetry("v1", "checkout.requests", []string{"http.method"}, t0))
+	// Ingest derives the IDs of other tenants, as the same telemetry is sent to both
+	payments := Telemetry(tenant.ScopeID("payments", "v1"), "checkout.requests", []string{"http.method", "payment.provider"}, t0)
+	entity := *payments.Entities["checkout"]
+	entity.ID = tenant.ScopeID("payments", "checkout")
+	payments.Entities = map[string]*schema.Entity{entity.ID: &entity}
+	scope := *payments.Scope
+	scope.ID = tenant.ScopeID("payments", "otelhttp")
+	payments.Scope = &scope
+	register(t, paymentsCtx, b, payments)
+
+	params := query.ListQueryParams{Page: 1, PageSize: 10}
+	_, total, err := b.Schemas.ListTelemetries(defaultCtx, params)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	_, total, err = b.Schemas.ListTelemetries(allCtx, params)
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	_, total, err = b.Schemas.ListTelemetries(tenant.WithTenant(defaultCtx, "search"), params)
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	telemetry, err := b.Schemas.GetTelemetry(paymentsCtx, "checkout.requests")
+	require.NoError(t, err)
+	require.Len(t, telemetry.Attributes, 2)
+	require.Equal(t, "payments", telemetry.Tenant)
+
+	_, total, err = b.Schemas.ListEntities(paymentsCtx, params)
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	missing, err := b.Schemas.GetTelemetrySchema(paymentsCtx, "v1")
+	require.NoError(t, err)
+	require.Nil(t, missing)
+
+	// The cross-tenant view is read only
+	err = b.Schemas.RegisterTelemetrySchemas(allCtx, []schema.Telemetry{Telemetry("v2", "checkout.requests", nil, t0)})
+	require.ErrorIs(t, err, tenant.ErrInvalidTenant)
+}
+
+func testTenantSummaries(t *testing.T, b Backend) {
+	ctx := context.Background()
+	register(t, ctx, b,
+		Telemetry("v1", "checkout.requests", []string{"http.method"}, t0),
+		Telemetry("v2", "checkout.requests", []string{"http.route"}, t0.Add(time.Minute)),
+	)
+	payments := Telemetry(tenant.ScopeID("payments", "v1"), "checkout.requests", nil, t0.Add(time.Hour))
+	entity := *payments.Entities["checkout"]
+	entity.ID = tenant.ScopeID("payments", "checkout")
+	payments.Entities = map[string]*schema.Entity{entity.ID: &entity}
+	payments.Scope = nil
+	register(t, tenant.WithTenant(ctx, "payments"), b, payments)
+
+	tenants, err := b.Tenants.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+	for i, expected := range []schema.TenantSummary{
+		{Name: "default", Telemetries: 1, Schemas: 2, Entities: 1, Scopes: 1, LastSeen: t0.Add(time.Minute)},
+		{Name: "payments", Telemetries: 1, Schemas: 1, Entities: 1, LastSeen: t0.Add(time.Hour)},
+	} {
+		// Backends read the last seen time back in their own location
+		requireTime(t, expected.LastSeen, tenants[i].LastSeen)
+		tenants[i].LastSeen = expected.LastSeen
+		require.Equal(t, expected, tenants[i])
+	}
+}
+
+func testEntityRekey(t *testing.T, b Backend) {
+	if b.Rekey == nil {
+		t.Skip("the backend keeps no entities across restarts")
+	}
+	ctx := context.Background()
+	paymentsCtx := tenant.WithTenant(ctx, "payments")
+	model := schema.DefaultEntityModel()
+
+	// Every instance of checkout stored as an entity of its own, as before instances were descriptive
+	instance := func(id, instanceID, version string, seenAt time.Time) map[string]*schema.Entity {
+		return map[string]*schema.Entity{id: {
+			ID:   id,
+			Type: "service",
+			Attributes: map[string]interface{}{
+				"service.name":        "checkout-service",
+				"service.instance.id": instanceID,
+				"service.version":     version,
+			},
+			FirstSeen: seenAt,
+			LastSeen:  seenAt,
+		}}
+	}
+	v1 := Telemetry("v1", "checkout.requests", []string{"http.method"}, t0)
+	v1.Entities = instance("pod-1", "checkout-7d9f", "1.0.0", t0)
+	v2 := Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(time.Hour))
+	v2.Entities = instance("pod-2", "checkout-5c2a", "1.1.0", t0.Add(time.Hour))
+	register(t, ctx, b, v1)
+	register(t, ctx, b, v2)
+	payments := Telemetry(tenant.ScopeID("payments", "v1"), "checkout.requests", []string{"http.method"}, t0)
+	payments.Entities = instance(tenant.ScopeID("payments", "pod-1"), "checkout-7d9f", "1.0.0", t0)
+	register(t, paymentsCtx, b, payments)
+
+	moved, err := b.Rekey.RekeyEntities(ctx, model)
+	require.NoError(t, err)
+	require.Equal(t, 3, moved)
+
+	checkoutID := model.EntityID("service", map[string]interface{}{"service.name": "checkout-service"})
+	entities, total, err := b.Schemas.ListEntities(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, checkoutID, entities[0].ID)
+	require.Equal(t, "1.1.0", entities[0].Attributes["service.version"])
+	requireTime(t, t0, entities[0].FirstSeen)
+	requireTime(t, t0.Add(time.Hour), entities[0].LastSeen)
+
+	telemetrySchema, err := b.Schemas.GetTelemetrySchema(ctx, "v1")
+	require.NoError(t, err)
+	require.Equal(t, 1, telemetrySchema.EntityCount)
+	require.Contains(t, telemetrySchema.Entities, checkoutID)
+
+	// Entities of other tenants keep their tenant
+	entities, total, err = b.Schemas.ListEntities(paymentsCtx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, tenant.ScopeID("payments", checkoutID), entities[0].ID)
+
+	// Entities under their ID are left alone
+	moved, err = b.Rekey.RekeyEntities(ctx, model)
+	require.NoError(t, err)
+	require.Zero(t, moved)
+}
+
+// producerTelemetry returns a schema emitted by an instance of checkout, stored under the given
+// producer ID
+func producerTelemetry(producerID, instanceID string, seenAt time.Time) schema.Telemetry {
+	telemetry := Telemetry("v1", "checkout.requests", []string{"http.method"}, seenAt)
+	telemetry.Producers = map[string]*schema.Producer{producerID: {
+		ID:        producerID,
+		Name:      "checkout-service",
+		Version:   "1.4.0",
+		Instances: []schema.ProducerInstance{{ID: instanceID, FirstSeen: seenAt, LastSeen: seenAt}},
+		FirstSeen: seenAt,
+		LastSeen:  seenAt,
+	}}
+	return telemetry
+}
+
+func testProducerInstances(t *testing.T, b Backend) {
+	ctx := context.Background()
+	checkout := (&schema.Producer{Name: "checkout-service", Version: "1.4.0"}).ProducerID()
+
+	// Restarting checkout yields a new instance of the same producer
+	register(t, ctx, b, producerTelemetry(checkout, "pod-1", t0))
+	register(t, ctx, b, producerTelemetry(checkout, "pod-2", t0.Add(time.Hour)))
+	register(t, ctx, b, producerTelemetry(checkout, "pod-1", t0.Add(-time.Hour)))
+
+	producers, total, err := b.Schemas.ListProducers(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, checkout, producers[0].ID)
+	require.Len(t, producers[0].Instances, 2)
+	require.Equal(t, "pod-2", producers[0].Instances[0].ID)
+	require.Equal(t, "pod-1", producers[0].Instances[1].ID)
+	requireTime(t, t0.Add(-time.Hour), producers[0].Instances[1].FirstSeen)
+	requireTime(t, t0, producers[0].Instances[1].LastSeen)
+}
+
+func testProducerRekey(t *testing.T, b Backend) {
+	if b.ProducerRekey == nil {
+		t.Skip("the backend keeps no producers across restarts")
+	}
+	ctx := context.Background()
+	paymentsCtx := tenant.WithTenant(ctx, "payments")
+
+	// Every instance of checkout stored as a producer of its own, as before instances were a detail
+	register(t, ctx, b, producerTelemetry("pod-1", "checkout-7d9f", t0))
+	register(t, ctx, b, producerTelemetry("pod-2", "checkout-5c2a", t0.Add(time.Hour)))
+	payments := producerTelemetry(tenant.ScopeID("payments", "pod-1"), "checkout-7d9f", t0)
+	payments.SchemaID = tenant.ScopeID("payments", "v1")
+	register(t, paymentsCtx, b, payments)
+
+	moved, err := b.ProducerRekey.RekeyProducers(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 3, moved)
+
+	checkout := (&schema.Producer{Name: "checkout-service", Version: "1.4.0"}).ProducerID()
+	producers, total, err := b.Schemas.ListProducers(ctx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, checkout, producers[0].ID)
+	requireTime(t, t0, producers[0].FirstSeen)
+	requireTime(t, t0.Add(time.Hour), producers[0].LastSeen)
+	require.Len(t, producers[0].Instances, 2)
+	require.Equal(t, "checkout-5c2a", producers[0].Instances[0].ID)
+
+	byProducer, err := b.Schemas.ListTelemetriesByProducer(ctx, "checkout-service", "1.4.0")
+	require.NoError(t, err)
+	require.Len(t, byProducer, 1)
+	require.Equal(t, "v1", byProducer[0].SchemaID)
+
+	// Producers of other tenants keep their tenant
+	producers, total, err = b.Schemas.ListProducers(paymentsCtx, query.ListQueryParams{Page: 1, PageSize: 10})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+	require.Equal(t, tenant.ScopeID("payments", checkout), producers[0].ID)
+
+	// Producers under their ID are left alone
+	moved, err = b.ProducerRekey.RekeyProducers(ctx)
+	require.NoError(t, err)
+	require.Zero(t, moved)
+}
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/repositorytest"
+)
+
+func setupTestDB(t *testing.T) *TelemetrySchemaRepository {
+	provider, err := NewConnectionPool(&Config{
+		DatabasePath: filepath.Join(t.TempDir(), "tallycat.db"),
+		MaxOpenConns: 4,
+		MaxIdleConns: 4,
+	}, slog.Default())
+	require.NoError(t, err)
+	pool := provider.(*ConnectionPool)
+	t.Cleanup(func() { pool.Close() })
+
+	require.NoError(t, ApplyMigrations(pool.GetConnection()))
+
+	return NewTelemetrySchemaRepository(pool)
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Backend {
+		repo := setupTestDB(t)
+		return repositorytest.Backend{
+			Schemas:       repo,
+			History:       NewTelemetryHistoryRepository(repo.pool),
+			Tenants:       NewTenantRepository(repo.pool),
+			Rekey:         repo,
+			ProducerRekey: repo,
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository"
+	_ "modernc.org/sqlite"
+)
+
+type Config struct {
+	DatabasePath    string        // Path to SQLite database file
+	MaxOpenConns    int           // Maximum number of open connections
+	MaxIdleConns    int           // Maximum number of idle connections
+	ConnMaxLifetime time.Duration // Maximum lifetime of a connection
+	ConnMaxIdleTime time.Duration // Maximum idle time of a connection
+}
+
+type ConnectionPool struct {
+	db     *sql.DB
+	config *Config
+	logger *slog.Logger
+}
+
+// NewConnectionPool opens a SQLite database in WAL mode, so readers never wait for the writer.
+// Transactions take the write lock when they begin and wait for it instead of failing, which
+// serializes the concurrent writers of the ingest services.
+func NewConnectionPool(config *Config, logger *slog.Logger) (repository.ConnectionProvider, error) {
+	db, err := sql.Open("sqlite", dataSourceName(config.DatabasePath))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	return &ConnectionPool{
+		db:     db,
+		config: config,
+		logger: logger,
+	}, nil
+}
+
+func dataSourceName(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	// LIKE matches case as in DuckDB
+	params.Add("_pragma", "case_sensitive_like(ON)")
+	params.Set("_txlock", "immediate")
+	params.Set("_time_format", "sqlite")
+	return "file:" + path + "?" + params.Encode()
+}
+
+func (p *ConnectionPool) Close() error {
+	return p.db.Close()
+}
+
+func (p *ConnectionPool) GetConnection() *sql.DB {
+	return p.db
+}
+
+func (p *ConnectionPool) HealthCheck() error {
+	return p.db.Ping()
+}
+
+func (p *ConnectionPool) ValidateConnection() error {
+	if p.db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+	return p.db.Ping()
+}
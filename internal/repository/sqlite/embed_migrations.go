@@ -0,0 +1,28 @@
+package sqlite
+
+import (
+	"database/sql"
+	"embed"
+	"log/slog"
+
+	"github.com/tallycat/tallycat/internal/repository/migrator"
+)
+
+// EmbeddedMigrations contains all SQL migration files embedded in the binary
+//
+//go:embed migrations/*.sql
+var EmbeddedMigrations embed.FS
+
+// ApplyMigrations runs all pending migrations in the database
+func ApplyMigrations(db *sql.DB) error {
+	m := migrator.New(db, slog.Default())
+	if err := m.Initialize(); err != nil {
+		return err
+	}
+
+	migrations, err := m.LoadMigrations(EmbeddedMigrations)
+	if err != nil {
+		return err
+	}
+	return m.Migrate(migrations)
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+// RekeyEntities moves the entities stored under other IDs than the entity model gives them, as
+// entities stored by a release or an entity model identifying them differently, to their ID.
+// Entities moved to the same ID are merged with their schema links. It returns the number of
+// entities moved.
+func (r *TelemetrySchemaRepository) RekeyEntities(ctx context.Context, model *schema.EntityModel) (int, error) {
+	db := r.pool.GetConnection()
+
+	entities, tenants, err := loadStoredEntities(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	rekeys := schema.RekeyEntities(entities, func(e schema.Entity) string {
+		return tenant.ScopeID(tenants[e.ID], model.EntityID(e.Type, e.Attributes))
+	})
+	if len(rekeys) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved := 0
+	for _, rekey := range rekeys {
+		entity := rekey.Entity
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO telemetry_entities (entity_id, entity_type, first_seen, last_seen, tenant)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT (entity_id) DO UPDATE SET
+				first_seen = excluded.first_seen,
+				last_seen = excluded.last_seen
+		`, entity.ID, entity.Type, utc(entity.FirstSeen), utc(entity.LastSeen), tenants[rekey.PreviousIDs[0]])
+		if err != nil {
+			return 0, fmt.Errorf("failed to store entity %s: %w", entity.ID, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM entity_attributes WHERE entity_id = ?`, entity.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete attributes of entity %s: %w", entity.ID, err)
+		}
+		for name, value := range entity.Attributes {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO entity_attributes (entity_id, name, value, type)
+				VALUES (?, ?, ?, ?)
+			`, entity.ID, name, fmt.Sprintf("%v", value), "string")
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert attribute of entity %s: %w", entity.ID, err)
+			}
+		}
+
+		for _, previousID := range rekey.PreviousIDs {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO schema_entities (schema_id, entity_id, first_seen, last_seen, seen_count)
+				SELECT schema_id, ?, first_seen, last_seen, seen_count
+				FROM schema_entities
+				WHERE entity_id = ?
+				ON CONFLICT (schema_id, entity_id) DO UPDATE SET
+					first_seen = MIN(COALESCE(schema_entities.first_seen, excluded.first_seen), excluded.first_seen),
+					last_seen = MAX(COALESCE(schema_entities.last_seen, excluded.last_seen), excluded.last_seen),
+					seen_count = COALESCE(schema_entities.seen_count, 0) + COALESCE(excluded.seen_count, 0)
+			`, entity.ID, previousID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to move schema links of entity %s: %w", previousID, err)
+			}
+			for _, table := range []string{"schema_entities", "entity_attributes", "telemetry_entities"} {
+				if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE entity_id = ?`, previousID); err != nil {
+					return 0, fmt.Errorf("failed to delete %s of moved entity %s: %w", table, previousID, err)
+				}
+			}
+			moved++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// loadStoredEntities returns every entity of every tenant with its attributes, and the tenant of
+// each entity by ID
+func loadStoredEntities(ctx context.Context, db *sql.DB) ([]schema.Entity, map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen, te.tenant, ea.name, ea.value
+		FROM telemetry_entities te
+		LEFT JOIN entity_attributes ea ON ea.entity_id = te.entity_id
+		ORDER BY te.entity_id`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query entities: %w", err)
+	}
+	defer rows.Close()
+
+	var entities []schema.Entity
+	tenants := make(map[string]string)
+	for rows.Next() {
+		var entity schema.Entity
+		var tenantName string
+		var firstSeen, lastSeen nullTime
+		var name, value sql.NullString
+		if err := rows.Scan(&entity.ID, &entity.Type, &firstSeen, &lastSeen, &tenantName, &name, &value); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan entity row: %w", err)
+		}
+		if n := len(entities); n == 0 || entities[n-1].ID != entity.ID {
+			entity.FirstSeen = firstSeen.Time
+			entity.LastSeen = lastSeen.Time
+			entity.Attributes = make(map[string]interface{})
+			entities = append(entities, entity)
+			tenants[entity.ID] = tenantName
+		}
+		if name.Valid {
+			entities[len(entities)-1].Attributes[name.String] = value.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating entity rows: %w", err)
+	}
+	return entities, tenants, nil
+}
@@ -0,0 +1,23 @@
+DROP INDEX IF EXISTS idx_telemetry_history_key;
+DROP INDEX IF EXISTS idx_schema_producers_producer_id;
+DROP INDEX IF EXISTS idx_schema_scopes_scope_id;
+DROP INDEX IF EXISTS idx_schema_entities_entity_id;
+DROP INDEX IF EXISTS idx_telemetry_producers_name_version;
+DROP INDEX IF EXISTS idx_telemetry_scopes_name;
+DROP INDEX IF EXISTS idx_telemetry_entities_last_seen;
+DROP INDEX IF EXISTS idx_telemetry_schemas_updated_at;
+DROP INDEX IF EXISTS idx_telemetry_schemas_key;
+
+DROP TABLE IF EXISTS telemetry_history;
+DROP TABLE IF EXISTS schema_versions;
+DROP TABLE IF EXISTS schema_senders;
+DROP TABLE IF EXISTS schema_producers;
+DROP TABLE IF EXISTS telemetry_producers;
+DROP TABLE IF EXISTS schema_scopes;
+DROP TABLE IF EXISTS scope_attributes;
+DROP TABLE IF EXISTS telemetry_scopes;
+DROP TABLE IF EXISTS schema_entities;
+DROP TABLE IF EXISTS entity_attributes;
+DROP TABLE IF EXISTS telemetry_entities;
+DROP TABLE IF EXISTS schema_attributes;
+DROP TABLE IF EXISTS telemetry_schemas;
@@ -0,0 +1,168 @@
+-- The catalog of observed telemetry: schemas and their variants, the entities, scopes and
+-- producers emitting them, their assigned versions and their history. Times are stored as UTC
+-- text, so they order as strings.
+CREATE TABLE IF NOT EXISTS telemetry_schemas (
+    schema_id TEXT PRIMARY KEY,
+    schema_key TEXT NOT NULL,
+    schema_version TEXT,
+    schema_url TEXT,
+    signal_type TEXT,
+    -- Metric fields
+    metric_type TEXT,
+    temporality TEXT,
+    unit TEXT,
+    brief TEXT,
+    -- Log fields
+    log_severity_number INTEGER,
+    log_severity_text TEXT,
+    log_body TEXT,
+    log_flags INTEGER,
+    log_trace_id TEXT,
+    log_span_id TEXT,
+    log_event_name TEXT,
+    log_dropped_attributes_count INTEGER,
+    -- Span fields
+    span_kind TEXT,
+    span_name TEXT,
+    span_id TEXT,
+    span_trace_id TEXT,
+    -- Profile fields
+    profile_sample_aggregation_temporality TEXT,
+    profile_sample_unit TEXT,
+    -- Common fields
+    note TEXT,
+    protocol TEXT,
+    seen_count INTEGER,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS schema_attributes (
+    schema_id TEXT NOT NULL REFERENCES telemetry_schemas(schema_id),
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    source TEXT NOT NULL,
+    PRIMARY KEY (schema_id, name, type, source)
+);
+
+CREATE TABLE IF NOT EXISTS telemetry_entities (
+    entity_id TEXT PRIMARY KEY,
+    entity_type TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS entity_attributes (
+    entity_id TEXT NOT NULL REFERENCES telemetry_entities(entity_id),
+    name TEXT NOT NULL,
+    value TEXT,
+    type TEXT,
+    PRIMARY KEY (entity_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS schema_entities (
+    schema_id TEXT NOT NULL REFERENCES telemetry_schemas(schema_id),
+    entity_id TEXT NOT NULL REFERENCES telemetry_entities(entity_id),
+    first_seen TIMESTAMP,
+    last_seen TIMESTAMP,
+    seen_count INTEGER DEFAULT 0,
+    PRIMARY KEY (schema_id, entity_id)
+);
+
+CREATE TABLE IF NOT EXISTS telemetry_scopes (
+    scope_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    version TEXT,
+    schema_url TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS scope_attributes (
+    scope_id TEXT NOT NULL REFERENCES telemetry_scopes(scope_id),
+    name TEXT NOT NULL,
+    value TEXT,
+    type TEXT,
+    PRIMARY KEY (scope_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS schema_scopes (
+    schema_id TEXT NOT NULL REFERENCES telemetry_schemas(schema_id),
+    scope_id TEXT NOT NULL REFERENCES telemetry_scopes(scope_id),
+    first_seen TIMESTAMP,
+    last_seen TIMESTAMP,
+    seen_count INTEGER DEFAULT 0,
+    PRIMARY KEY (schema_id, scope_id)
+);
+
+CREATE TABLE IF NOT EXISTS telemetry_producers (
+    producer_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    namespace TEXT,
+    version TEXT,
+    instance_id TEXT,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS schema_producers (
+    schema_id TEXT NOT NULL REFERENCES telemetry_schemas(schema_id),
+    producer_id TEXT NOT NULL REFERENCES telemetry_producers(producer_id),
+    first_seen TIMESTAMP,
+    last_seen TIMESTAMP,
+    seen_count INTEGER DEFAULT 0,
+    PRIMARY KEY (schema_id, producer_id)
+);
+
+-- Clients identified by their verified TLS certificate that sent a schema
+CREATE TABLE IF NOT EXISTS schema_senders (
+    schema_id TEXT NOT NULL REFERENCES telemetry_schemas(schema_id),
+    identity TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    seen_count INTEGER DEFAULT 0,
+    PRIMARY KEY (schema_id, identity)
+);
+
+CREATE TABLE IF NOT EXISTS schema_versions (
+    schema_id TEXT PRIMARY KEY REFERENCES telemetry_schemas(schema_id),
+    version TEXT,
+    assigned_by TEXT,
+    reason TEXT,
+    status TEXT,
+    deprecated_at TIMESTAMP,
+    replaced_by TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS telemetry_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    schema_key TEXT NOT NULL,
+    version TEXT NOT NULL,
+    timestamp TIMESTAMP NOT NULL,
+    author TEXT,
+    summary TEXT,
+    status TEXT,
+    snapshot BLOB,
+    schema_id TEXT,
+    diff TEXT,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    tenant TEXT NOT NULL DEFAULT 'default'
+);
+
+CREATE INDEX IF NOT EXISTS idx_telemetry_schemas_key ON telemetry_schemas(tenant, schema_key, signal_type);
+CREATE INDEX IF NOT EXISTS idx_telemetry_schemas_updated_at ON telemetry_schemas(tenant, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_telemetry_entities_last_seen ON telemetry_entities(tenant, last_seen DESC);
+CREATE INDEX IF NOT EXISTS idx_telemetry_scopes_name ON telemetry_scopes(tenant, name);
+CREATE INDEX IF NOT EXISTS idx_telemetry_producers_name_version ON telemetry_producers(tenant, name, version);
+CREATE INDEX IF NOT EXISTS idx_schema_entities_entity_id ON schema_entities(entity_id);
+CREATE INDEX IF NOT EXISTS idx_schema_scopes_scope_id ON schema_scopes(scope_id);
+CREATE INDEX IF NOT EXISTS idx_schema_producers_producer_id ON schema_producers(producer_id);
+CREATE INDEX IF NOT EXISTS idx_telemetry_history_key ON telemetry_history(tenant, schema_key, timestamp DESC);
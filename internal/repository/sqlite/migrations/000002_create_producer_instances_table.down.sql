@@ -0,0 +1,11 @@
+ALTER TABLE telemetry_producers ADD COLUMN instance_id TEXT;
+
+UPDATE telemetry_producers
+SET instance_id = (
+    SELECT instance_id FROM producer_instances pi
+    WHERE pi.producer_id = telemetry_producers.producer_id
+    ORDER BY pi.last_seen DESC
+    LIMIT 1
+);
+
+DROP TABLE IF EXISTS producer_instances;
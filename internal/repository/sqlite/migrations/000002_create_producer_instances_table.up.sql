@@ -0,0 +1,17 @@
+-- Producers are keyed on their service name, namespace and version, and the instances running
+-- them are kept apart, so restarting a service no longer adds a producer. The producers stored per
+-- instance are moved to their new IDs on start, as the IDs are hashes SQL cannot compute.
+CREATE TABLE IF NOT EXISTS producer_instances (
+    producer_id TEXT NOT NULL,
+    instance_id TEXT NOT NULL,
+    first_seen TIMESTAMP NOT NULL,
+    last_seen TIMESTAMP NOT NULL,
+    PRIMARY KEY (producer_id, instance_id)
+);
+
+INSERT INTO producer_instances (producer_id, instance_id, first_seen, last_seen)
+SELECT producer_id, instance_id, first_seen, last_seen
+FROM telemetry_producers
+WHERE instance_id IS NOT NULL AND instance_id <> '';
+
+ALTER TABLE telemetry_producers DROP COLUMN instance_id;
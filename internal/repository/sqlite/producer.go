@@ -0,0 +1,279 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+func (r *TelemetrySchemaRepository) ListProducers(ctx context.Context, params query.ListQueryParams) ([]schema.Producer, int, error) {
+	where, args := tenantClause(ctx, "tp")
+
+	if params.Search != "" {
+		where += " AND (tp.name LIKE ? OR tp.namespace LIKE ? OR tp.version LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM telemetry_producers tp
+		WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count producers: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.Producer{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			tp.producer_id,
+			tp.name,
+			tp.namespace,
+			tp.version,
+			tp.first_seen,
+			tp.last_seen
+		FROM telemetry_producers tp
+		WHERE 1=1`+where+`
+		ORDER BY tp.last_seen DESC, tp.name ASC
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query producers: %w", err)
+	}
+	defer rows.Close()
+
+	producers := []schema.Producer{}
+	for rows.Next() {
+		var producer schema.Producer
+		var namespace, version sql.NullString
+		var firstSeen, lastSeen nullTime
+		if err := rows.Scan(
+			&producer.ID,
+			&producer.Name,
+			&namespace,
+			&version,
+			&firstSeen,
+			&lastSeen,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan producer row: %w", err)
+		}
+		producer.Namespace = namespace.String
+		producer.Version = version.String
+		producer.FirstSeen = firstSeen.Time
+		producer.LastSeen = lastSeen.Time
+
+		producers = append(producers, producer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating producer rows: %w", err)
+	}
+
+	if err := loadProducerInstances(ctx, db, producers); err != nil {
+		return nil, 0, err
+	}
+
+	return producers, total, nil
+}
+
+// ListTelemetriesByProducer returns the latest schema of every telemetry emitted by any instance
+// of the producer with the given name and version. An empty version matches producers without one.
+func (r *TelemetrySchemaRepository) ListTelemetriesByProducer(ctx context.Context, name, version string) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "tp")
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	telemetries, err := queryTelemetries(ctx, db, latestTelemetriesQuery(`
+			INNER JOIN schema_producers sp ON t.schema_id = sp.schema_id
+			INNER JOIN telemetry_producers tp ON sp.producer_id = tp.producer_id`,
+		" AND tp.name = ? AND COALESCE(tp.version, '') = ?"+tenantFilter, "t.schema_key ASC"),
+		append([]any{name, version}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetries by producer: %w", err)
+	}
+
+	for i := range telemetries {
+		if telemetries[i].Attributes, err = loadSchemaAttributes(ctx, db, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+	}
+
+	return telemetries, nil
+}
+
+// loadProducerInstances sets the instances of the producers, the most recently seen first
+func loadProducerInstances(ctx context.Context, db *sql.DB, producers []schema.Producer) error {
+	if len(producers) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*schema.Producer, len(producers))
+	placeholders := make([]string, 0, len(producers))
+	args := make([]any, 0, len(producers))
+	for i := range producers {
+		byID[producers[i].ID] = &producers[i]
+		placeholders = append(placeholders, "?")
+		args = append(args, producers[i].ID)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT producer_id, instance_id, first_seen, last_seen
+		FROM producer_instances
+		WHERE producer_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY last_seen DESC, instance_id ASC`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query producer instances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var instance schema.ProducerInstance
+		var producerID string
+		var firstSeen, lastSeen nullTime
+		if err := rows.Scan(&producerID, &instance.ID, &firstSeen, &lastSeen); err != nil {
+			return fmt.Errorf("failed to scan producer instance row: %w", err)
+		}
+		instance.FirstSeen = firstSeen.Time
+		instance.LastSeen = lastSeen.Time
+		producer := byID[producerID]
+		producer.Instances = append(producer.Instances, instance)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating producer instance rows: %w", err)
+	}
+	return nil
+}
+
+// RekeyProducers moves the producers stored under other IDs than their own, as the producers
+// stored per instance by earlier releases, to their ID. Producers moved to the same ID are merged
+// with their schema links and instances. It returns the number of producers moved.
+func (r *TelemetrySchemaRepository) RekeyProducers(ctx context.Context) (int, error) {
+	db := r.pool.GetConnection()
+
+	producers, tenants, err := loadStoredProducers(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	rekeys := schema.RekeyProducers(producers, func(p schema.Producer) string {
+		return tenant.ScopeID(tenants[p.ID], p.ProducerID())
+	})
+	if len(rekeys) == 0 {
+		return 0, nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved := 0
+	for _, rekey := range rekeys {
+		producer := rekey.Producer
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO telemetry_producers (producer_id, name, namespace, version, first_seen, last_seen, tenant)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (producer_id) DO UPDATE SET
+				first_seen = excluded.first_seen,
+				last_seen = excluded.last_seen
+		`, producer.ID, producer.Name, producer.Namespace, producer.Version, utc(producer.FirstSeen), utc(producer.LastSeen), tenants[rekey.PreviousIDs[0]])
+		if err != nil {
+			return 0, fmt.Errorf("failed to store producer %s: %w", producer.ID, err)
+		}
+
+		for _, previousID := range rekey.PreviousIDs {
+			statements := []rekeyStatement{
+				{"schema links", `
+					INSERT INTO schema_producers (schema_id, producer_id, first_seen, last_seen, seen_count)
+					SELECT schema_id, ?, first_seen, last_seen, seen_count
+					FROM schema_producers
+					WHERE producer_id = ?
+					ON CONFLICT (schema_id, producer_id) DO UPDATE SET
+						first_seen = MIN(COALESCE(schema_producers.first_seen, excluded.first_seen), excluded.first_seen),
+						last_seen = MAX(COALESCE(schema_producers.last_seen, excluded.last_seen), excluded.last_seen),
+						seen_count = COALESCE(schema_producers.seen_count, 0) + COALESCE(excluded.seen_count, 0)`,
+					[]any{producer.ID, previousID}},
+				{"schema links", `DELETE FROM schema_producers WHERE producer_id = ?`, []any{previousID}},
+				{"instances", `
+					INSERT INTO producer_instances (producer_id, instance_id, first_seen, last_seen)
+					SELECT ?, instance_id, first_seen, last_seen
+					FROM producer_instances
+					WHERE producer_id = ?
+					ON CONFLICT (producer_id, instance_id) DO UPDATE SET
+						first_seen = MIN(producer_instances.first_seen, excluded.first_seen),
+						last_seen = MAX(producer_instances.last_seen, excluded.last_seen)`,
+					[]any{producer.ID, previousID}},
+				{"instances", `DELETE FROM producer_instances WHERE producer_id = ?`, []any{previousID}},
+				{"producer", `DELETE FROM telemetry_producers WHERE producer_id = ?`, []any{previousID}},
+			}
+			for _, statement := range statements {
+				if _, err := tx.ExecContext(ctx, statement.query, statement.args...); err != nil {
+					return 0, fmt.Errorf("failed to move %s of producer %s: %w", statement.name, previousID, err)
+				}
+			}
+			moved++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return moved, nil
+}
+
+// loadStoredProducers returns every producer of every tenant, and the tenant of each producer by ID
+func loadStoredProducers(ctx context.Context, db *sql.DB) ([]schema.Producer, map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT producer_id, name, namespace, version, first_seen, last_seen, tenant
+		FROM telemetry_producers`)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query producers: %w", err)
+	}
+	defer rows.Close()
+
+	var producers []schema.Producer
+	tenants := make(map[string]string)
+	for rows.Next() {
+		var producer schema.Producer
+		var namespace, version sql.NullString
+		var firstSeen, lastSeen nullTime
+		var tenantName string
+		if err := rows.Scan(&producer.ID, &producer.Name, &namespace, &version, &firstSeen, &lastSeen, &tenantName); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan producer row: %w", err)
+		}
+		producer.Namespace = namespace.String
+		producer.Version = version.String
+		producer.FirstSeen = firstSeen.Time
+		producer.LastSeen = lastSeen.Time
+		producers = append(producers, producer)
+		tenants[producer.ID] = tenantName
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating producer rows: %w", err)
+	}
+	return producers, tenants, nil
+}
+
+// rekeyStatement moves the rows of a table referencing a producer
+type rekeyStatement struct {
+	name  string
+	query string
+	args  []any
+}
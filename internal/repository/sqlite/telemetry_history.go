@@ -0,0 +1,267 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+type TelemetryHistoryRepository struct {
+	pool *ConnectionPool
+}
+
+func NewTelemetryHistoryRepository(pool *ConnectionPool) *TelemetryHistoryRepository {
+	return &TelemetryHistoryRepository{
+		pool: pool,
+	}
+}
+
+func (r *TelemetryHistoryRepository) InsertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error {
+	return insertTelemetryHistory(ctx, r.pool.GetConnection(), h)
+}
+
+func insertTelemetryHistory(ctx context.Context, q execQuerier, h *schema.TelemetryHistory) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	var author sql.NullString
+	if h.Author != nil {
+		author = sql.NullString{String: *h.Author, Valid: true}
+	}
+
+	var diff sql.NullString
+	if h.Diff != nil {
+		data, err := json.Marshal(h.Diff)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema diff: %w", err)
+		}
+		diff = sql.NullString{String: string(data), Valid: true}
+	}
+
+	now := utc(time.Now())
+	var createdAt, updatedAt nullTime
+	err = q.QueryRowContext(ctx, `
+		INSERT INTO telemetry_history (
+			schema_key, version, timestamp, author, summary, status, snapshot, schema_id, diff, created_at, updated_at, tenant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?)
+		RETURNING id, created_at, updated_at
+	`,
+		h.SchemaKey,
+		h.Version,
+		utc(h.Timestamp),
+		author,
+		h.Summary,
+		h.Status,
+		h.Snapshot,
+		h.SchemaID,
+		diff,
+		now,
+		now,
+		tenantName,
+	).Scan(&h.Id, &createdAt, &updatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert telemetry_history: %w", err)
+	}
+	h.CreatedAt = createdAt.Time
+	h.UpdatedAt = updatedAt.Time
+	return nil
+}
+
+func (r *TelemetryHistoryRepository) ListTelemetryHistory(ctx context.Context, telemetryID string, page, pageSize int) ([]schema.TelemetryHistory, int, error) {
+	db := r.pool.GetConnection()
+	tenantFilter, args := tenantClause(ctx, "")
+	args = append([]any{telemetryID}, args...)
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM telemetry_history WHERE schema_key = ?`+tenantFilter, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count telemetry_history: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, schema_key, version, timestamp, author, summary, status, snapshot, schema_id, diff, created_at, updated_at
+		FROM telemetry_history
+		WHERE schema_key = ?`+tenantFilter+`
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, pageSize, (page-1)*pageSize)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query telemetry_history: %w", err)
+	}
+	defer rows.Close()
+
+	histories := []schema.TelemetryHistory{}
+	for rows.Next() {
+		var h schema.TelemetryHistory
+		var author, schemaID, diff sql.NullString
+		var timestamp, createdAt, updatedAt nullTime
+		if err := rows.Scan(
+			&h.Id,
+			&h.SchemaKey,
+			&h.Version,
+			&timestamp,
+			&author,
+			&h.Summary,
+			&h.Status,
+			&h.Snapshot,
+			&schemaID,
+			&diff,
+			&createdAt,
+			&updatedAt,
+		); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan telemetry_history row: %w", err)
+		}
+		h.Timestamp = timestamp.Time
+		h.CreatedAt = createdAt.Time
+		h.UpdatedAt = updatedAt.Time
+		if author.Valid {
+			h.Author = &author.String
+		}
+		h.SchemaID = schemaID.String
+		if diff.Valid {
+			h.Diff = &schema.SchemaDiff{}
+			if err := json.Unmarshal([]byte(diff.String), h.Diff); err != nil {
+				return nil, 0, fmt.Errorf("failed to unmarshal schema diff: %w", err)
+			}
+		}
+		histories = append(histories, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating telemetry_history rows: %w", err)
+	}
+	return histories, total, nil
+}
+
+// GetSchemaIDAt returns the variant of the schema key that was current at the given time, or an empty
+// string when the key was not seen yet. It follows the variants recorded in the history snapshots and
+// falls back to the latest variant first seen by then, which covers keys with a single variant.
+func (r *TelemetryHistoryRepository) GetSchemaIDAt(ctx context.Context, schemaKey string, at time.Time) (string, error) {
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	args := append([]any{schemaKey, utc(at)}, tenantArgs...)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT schema_id, snapshot
+		FROM telemetry_history
+		WHERE schema_key = ? AND timestamp <= ?`+tenantFilter+`
+		ORDER BY timestamp DESC, id DESC
+	`, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to query telemetry_history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var h schema.TelemetryHistory
+		var schemaID sql.NullString
+		if err := rows.Scan(&schemaID, &h.Snapshot); err != nil {
+			return "", fmt.Errorf("failed to scan telemetry_history row: %w", err)
+		}
+		h.SchemaID = schemaID.String
+		if id := h.SnapshotSchemaID(); id != "" {
+			return id, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating telemetry_history rows: %w", err)
+	}
+
+	var schemaID string
+	err = db.QueryRowContext(ctx, `
+		SELECT schema_id
+		FROM telemetry_schemas
+		WHERE schema_key = ? AND created_at <= ?`+tenantFilter+`
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, args...).Scan(&schemaID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query schema variant: %w", err)
+	}
+	return schemaID, nil
+}
+
+// recordSchemaVariant writes a history entry with the diff against the previous variant when a
+// schema ID not seen before appears for an existing schema key. It must run before the schema is inserted.
+func recordSchemaVariant(ctx context.Context, tx *sql.Tx, telemetry *schema.Telemetry) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) > 0 FROM telemetry_schemas WHERE schema_id = ?
+	`, telemetry.SchemaID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check schema existence: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	tenantFilter, tenantArgs := tenantClause(ctx, "")
+	previous := schema.Telemetry{}
+	var unit, metricType, temporality, spanKind, logSeverityText, logEventName, profileSampleUnit sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT schema_id, unit, metric_type, temporality, span_kind, log_severity_text, log_event_name, profile_sample_unit
+		FROM telemetry_schemas
+		WHERE schema_key = ? AND signal_type = ?`+tenantFilter+`
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`, append([]any{telemetry.SchemaKey, telemetry.TelemetryType}, tenantArgs...)...).Scan(
+		&previous.SchemaID,
+		&unit,
+		&metricType,
+		&temporality,
+		&spanKind,
+		&logSeverityText,
+		&logEventName,
+		&profileSampleUnit,
+	)
+	if err == sql.ErrNoRows {
+		// First variant of this schema key, nothing to compare against
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query previous schema variant: %w", err)
+	}
+	previous.MetricUnit = unit.String
+	previous.MetricType = schema.MetricType(metricType.String)
+	previous.MetricTemporality = schema.MetricTemporality(temporality.String)
+	previous.SpanKind = schema.SpanKind(spanKind.String)
+	previous.LogSeverityText = logSeverityText.String
+	previous.LogEventName = logEventName.String
+	previous.ProfileSampleUnit = profileSampleUnit.String
+
+	if previous.Attributes, err = loadSchemaAttributes(ctx, tx, previous.SchemaID); err != nil {
+		return err
+	}
+
+	// The snapshot only describes the schema itself, not where it was seen
+	snapshotTelemetry := *telemetry
+	snapshotTelemetry.Entities = nil
+	snapshotTelemetry.Scope = nil
+	snapshotTelemetry.Producers = nil
+	snapshotTelemetry.Senders = nil
+	snapshot, err := json.Marshal(snapshotTelemetry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schema snapshot: %w", err)
+	}
+
+	diff := schema.DiffTelemetry(previous, *telemetry)
+	return insertTelemetryHistory(ctx, tx, &schema.TelemetryHistory{
+		SchemaKey: telemetry.SchemaKey,
+		Timestamp: telemetry.CreatedAt,
+		Summary:   diff.Summary(),
+		Status:    schema.TelemetryHistoryStatusDetected,
+		Snapshot:  snapshot,
+		SchemaID:  telemetry.SchemaID,
+		Diff:      &diff,
+	})
+}
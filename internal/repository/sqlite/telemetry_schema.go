@@ -0,0 +1,1199 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/schema"
+	"github.com/tallycat/tallycat/internal/tenant"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// TelemetrySchemaRepository stores the catalog in SQLite. Unlike the DuckDB repository it keeps
+// no review, ownership, documentation or usage data, so telemetries are read without them.
+type TelemetrySchemaRepository struct {
+	pool *ConnectionPool
+}
+
+func NewTelemetrySchemaRepository(pool *ConnectionPool) *TelemetrySchemaRepository {
+	return &TelemetrySchemaRepository{
+		pool: pool,
+	}
+}
+
+// ownedListing reports whether a listing only keeps what a team owns. Ownership is not kept by
+// this backend, so nothing is owned by any team and such listings are empty.
+func ownedListing(params query.ListQueryParams) bool {
+	return params.Owner != ""
+}
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx
+type execQuerier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func (r *TelemetrySchemaRepository) RegisterTelemetrySchemas(ctx context.Context, schemas []schema.Telemetry) error {
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	schemaStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO telemetry_schemas (
+			schema_id, schema_key, schema_version, schema_url, signal_type,
+			metric_type, temporality, unit, brief,
+			log_severity_number, log_severity_text, log_body, log_flags, log_trace_id, log_span_id, log_event_name, log_dropped_attributes_count,
+			span_kind, span_name, span_id, span_trace_id,
+			profile_sample_aggregation_temporality, profile_sample_unit,
+			note, protocol, seen_count, created_at, updated_at, tenant
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (schema_id) DO UPDATE SET
+			seen_count = telemetry_schemas.seen_count + excluded.seen_count,
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at > telemetry_schemas.updated_at
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare schema insert statement: %w", err)
+	}
+	defer schemaStmt.Close()
+
+	attrStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO schema_attributes (schema_id, name, type, source)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare attribute insert statement: %w", err)
+	}
+	defer attrStmt.Close()
+
+	for _, schema := range schemas {
+		// Compare a new variant of an existing schema key against the previous one
+		if err := recordSchemaVariant(ctx, tx, &schema); err != nil {
+			return err
+		}
+
+		_, err = schemaStmt.ExecContext(ctx,
+			schema.SchemaID,
+			schema.SchemaKey,
+			schema.SchemaVersion,
+			schema.SchemaURL,
+			schema.TelemetryType,
+			schema.MetricType,
+			schema.MetricTemporality,
+			schema.MetricUnit,
+			schema.Brief,
+			schema.LogSeverityNumber,
+			schema.LogSeverityText,
+			schema.LogBody,
+			schema.LogFlags,
+			schema.LogTraceID,
+			schema.LogSpanID,
+			schema.LogEventName,
+			schema.LogDroppedAttributesCount,
+			schema.SpanKind,
+			schema.SpanName,
+			schema.SpanID,
+			schema.SpanTraceID,
+			schema.ProfileSampleAggregationTemporality,
+			schema.ProfileSampleUnit,
+			schema.Note,
+			schema.Protocol,
+			schema.SeenCount,
+			utc(schema.CreatedAt),
+			utc(schema.UpdatedAt),
+			tenantName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert schema: %w", err)
+		}
+
+		for _, attr := range schema.Attributes {
+			if _, err = attrStmt.ExecContext(ctx, schema.SchemaID, attr.Name, attr.Type, attr.Source); err != nil {
+				return fmt.Errorf("failed to insert attribute for schema %v: %w", schema.SchemaID, err)
+			}
+		}
+
+		for _, entity := range schema.Entities {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO telemetry_entities (entity_id, entity_type, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (entity_id) DO UPDATE SET
+					last_seen = excluded.last_seen
+				WHERE excluded.last_seen > telemetry_entities.last_seen
+			`, entity.ID, entity.Type, utc(entity.FirstSeen), utc(entity.LastSeen), tenantName)
+			if err != nil {
+				return fmt.Errorf("failed to insert entity: %w", err)
+			}
+
+			// Only the latest value of each entity attribute is kept
+			for attrName, attrValue := range entity.Attributes {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO entity_attributes (entity_id, name, value, type)
+					VALUES (?, ?, ?, ?)
+					ON CONFLICT (entity_id, name) DO UPDATE SET
+						value = excluded.value
+				`, entity.ID, attrName, fmt.Sprintf("%v", attrValue), "string")
+				if err != nil {
+					return fmt.Errorf("failed to upsert entity attribute: %w", err)
+				}
+			}
+
+			if err := linkSchema(ctx, tx, "schema_entities", "entity_id", &schema, entity.ID); err != nil {
+				return fmt.Errorf("failed to link schema to entity: %w", err)
+			}
+		}
+
+		for _, producer := range schema.Producers {
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO telemetry_producers (producer_id, name, namespace, version, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (producer_id) DO UPDATE SET
+					last_seen = excluded.last_seen
+				WHERE excluded.last_seen > telemetry_producers.last_seen
+			`, producer.ID, producer.Name, producer.Namespace, producer.Version, utc(producer.FirstSeen), utc(producer.LastSeen), tenantName)
+			if err != nil {
+				return fmt.Errorf("failed to insert producer: %w", err)
+			}
+
+			for _, instance := range producer.Instances {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO producer_instances (producer_id, instance_id, first_seen, last_seen)
+					VALUES (?, ?, ?, ?)
+					ON CONFLICT (producer_id, instance_id) DO UPDATE SET
+						first_seen = MIN(producer_instances.first_seen, excluded.first_seen),
+						last_seen = MAX(producer_instances.last_seen, excluded.last_seen)
+				`, producer.ID, instance.ID, utc(instance.FirstSeen), utc(instance.LastSeen))
+				if err != nil {
+					return fmt.Errorf("failed to insert producer instance: %w", err)
+				}
+			}
+
+			if err := linkSchema(ctx, tx, "schema_producers", "producer_id", &schema, producer.ID); err != nil {
+				return fmt.Errorf("failed to link schema to producer: %w", err)
+			}
+		}
+
+		// Record the client that sent the schema, when it presented a verified certificate
+		if schema.Sender != "" {
+			if err := linkSchema(ctx, tx, "schema_senders", "identity", &schema, schema.Sender); err != nil {
+				return fmt.Errorf("failed to record schema sender: %w", err)
+			}
+		}
+
+		if schema.Scope != nil {
+			scope := schema.Scope
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO telemetry_scopes (scope_id, name, version, schema_url, first_seen, last_seen, tenant)
+				VALUES (?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (scope_id) DO UPDATE SET
+					last_seen = excluded.last_seen
+				WHERE excluded.last_seen > telemetry_scopes.last_seen
+			`, scope.ID, scope.Name, scope.Version, scope.SchemaURL, utc(scope.FirstSeen), utc(scope.LastSeen), tenantName)
+			if err != nil {
+				return fmt.Errorf("failed to insert scope: %w", err)
+			}
+
+			for attrName, attrValue := range scope.Attributes {
+				_, err = tx.ExecContext(ctx, `
+					INSERT INTO scope_attributes (scope_id, name, value, type)
+					VALUES (?, ?, ?, ?)
+					ON CONFLICT (scope_id, name) DO UPDATE SET
+						value = excluded.value
+				`, scope.ID, attrName, fmt.Sprintf("%v", attrValue), "string")
+				if err != nil {
+					return fmt.Errorf("failed to insert scope attribute: %w", err)
+				}
+			}
+
+			if err := linkSchema(ctx, tx, "schema_scopes", "scope_id", &schema, scope.ID); err != nil {
+				return fmt.Errorf("failed to link schema to scope: %w", err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	attributeCount := 0
+	for _, schema := range schemas {
+		attributeCount += len(schema.Attributes)
+	}
+
+	slog.Debug(
+		"successfully registered telemetry schemas",
+		"schema_count", len(schemas),
+		"attribute_count", attributeCount,
+	)
+	return nil
+}
+
+// linkSchema records a schema being seen with the entity, scope, producer or sender of a link
+// table, widening the activity of the link
+func linkSchema(ctx context.Context, tx *sql.Tx, table, column string, telemetry *schema.Telemetry, id string) error {
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s (schema_id, %[2]s, first_seen, last_seen, seen_count)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (schema_id, %[2]s) DO UPDATE SET
+			first_seen = MIN(COALESCE(%[1]s.first_seen, excluded.first_seen), excluded.first_seen),
+			last_seen = MAX(COALESCE(%[1]s.last_seen, excluded.last_seen), excluded.last_seen),
+			seen_count = COALESCE(%[1]s.seen_count, 0) + excluded.seen_count
+	`, table, column), telemetry.SchemaID, id, utc(telemetry.UpdatedAt), utc(telemetry.UpdatedAt), telemetry.SeenCount)
+	return err
+}
+
+// telemetryColumns are the columns of telemetry_schemas read by scanTelemetry
+const telemetryColumns = `
+	t.tenant, t.schema_id, t.schema_version, t.schema_url, t.signal_type, t.schema_key,
+	t.unit, t.metric_type, t.temporality, t.brief,
+	t.log_severity_number, t.log_severity_text, t.log_body, t.log_flags, t.log_trace_id, t.log_span_id, t.log_event_name, t.log_dropped_attributes_count,
+	t.span_kind, t.span_name, t.span_id, t.span_trace_id,
+	t.profile_sample_aggregation_temporality, t.profile_sample_unit,
+	t.note, t.protocol, t.seen_count, t.created_at, t.updated_at`
+
+// latestTelemetriesQuery selects the latest variant of every schema key of telemetry_schemas t
+// matching the joins and conditions
+func latestTelemetriesQuery(joins, where, orderBy string) string {
+	return `
+		SELECT ` + telemetryColumns + `
+		FROM (
+			SELECT t.*, ROW_NUMBER() OVER (
+				PARTITION BY t.tenant, t.signal_type, t.schema_key
+				ORDER BY t.updated_at DESC
+			) AS rn
+			FROM telemetry_schemas t` + joins + `
+			WHERE 1=1` + where + `
+		) t
+		WHERE t.rn = 1
+		ORDER BY ` + orderBy
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTelemetry(row rowScanner) (schema.Telemetry, error) {
+	var t schema.Telemetry
+	var createdAt, updatedAt nullTime
+	err := row.Scan(
+		&t.Tenant,
+		&t.SchemaID,
+		&t.SchemaVersion,
+		&t.SchemaURL,
+		&t.TelemetryType,
+		&t.SchemaKey,
+		&t.MetricUnit,
+		&t.MetricType,
+		&t.MetricTemporality,
+		&t.Brief,
+		&t.LogSeverityNumber,
+		&t.LogSeverityText,
+		&t.LogBody,
+		&t.LogFlags,
+		&t.LogTraceID,
+		&t.LogSpanID,
+		&t.LogEventName,
+		&t.LogDroppedAttributesCount,
+		&t.SpanKind,
+		&t.SpanName,
+		&t.SpanID,
+		&t.SpanTraceID,
+		&t.ProfileSampleAggregationTemporality,
+		&t.ProfileSampleUnit,
+		&t.Note,
+		&t.Protocol,
+		&t.SeenCount,
+		&createdAt,
+		&updatedAt,
+	)
+	t.CreatedAt = createdAt.Time
+	t.UpdatedAt = updatedAt.Time
+	return t, err
+}
+
+func queryTelemetries(ctx context.Context, q execQuerier, query string, args ...any) ([]schema.Telemetry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var telemetries []schema.Telemetry
+	for rows.Next() {
+		t, err := scanTelemetry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan schema row: %w", err)
+		}
+		telemetries = append(telemetries, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema rows: %w", err)
+	}
+	return telemetries, nil
+}
+
+// searchClause matches the search term against the IDs, keys, types and units of telemetry_schemas t
+func searchClause(params query.ListQueryParams) (string, []any) {
+	where := ""
+	var args []any
+	if params.FilterType != "" && params.FilterType != "all" {
+		where += " AND t.signal_type = ?"
+		args = append(args, cases.Title(language.English).String(params.FilterType))
+	}
+
+	if params.Search != "" {
+		where += " AND (t.schema_id LIKE ? OR t.schema_key LIKE ? OR t.metric_type LIKE ? OR t.unit LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm, searchTerm)
+	}
+	return where, args
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.Telemetry, int, error) {
+	if ownedListing(params) {
+		return []schema.Telemetry{}, 0, nil
+	}
+
+	where, args := tenantClause(ctx, "t")
+	searchWhere, searchArgs := searchClause(params)
+	where += searchWhere
+	args = append(args, searchArgs...)
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	countQuery := `
+		SELECT COUNT(*) FROM (
+			SELECT 1
+			FROM telemetry_schemas t
+			WHERE 1=1` + where + `
+			GROUP BY t.tenant, t.signal_type, t.schema_key
+		)`
+	if err := db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count schemas: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.Telemetry{}, 0, nil
+	}
+
+	query := latestTelemetriesQuery("", where, "t.updated_at DESC, t.schema_key LIMIT ? OFFSET ?")
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	schemas, err := queryTelemetries(ctx, db, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for i := range schemas {
+		if schemas[i].Entities, err = loadEntityMap(ctx, db, `
+			SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+			FROM telemetry_entities te
+			INNER JOIN schema_entities se ON te.entity_id = se.entity_id
+			WHERE se.schema_id = ?`, schemas[i].SchemaID); err != nil {
+			return nil, 0, err
+		}
+		if schemas[i].Scope, err = loadScope(ctx, db, false, `
+			SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+			FROM telemetry_scopes ts
+			INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+			WHERE ss.schema_id = ?`, schemas[i].SchemaID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return schemas, total, nil
+}
+
+func (r *TelemetrySchemaRepository) GetTelemetry(ctx context.Context, schemaKey string) (*schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "t")
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	s, err := scanTelemetry(db.QueryRowContext(ctx, `
+		SELECT `+telemetryColumns+`
+		FROM telemetry_schemas t
+		WHERE t.schema_key = ?`+tenantFilter+`
+		ORDER BY t.updated_at DESC
+		LIMIT 1`, append([]any{schemaKey}, args...)...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema: %w", err)
+	}
+
+	// The rest of the telemetry is read within its tenant, even in the cross-tenant views
+	ctx = tenant.WithTenant(ctx, s.Tenant)
+
+	if s.Attributes, err = loadSchemaAttributes(ctx, db, s.SchemaID); err != nil {
+		return nil, err
+	}
+
+	// Entities and scopes are those of every variant of the schema key
+	if s.Entities, err = loadEntityMap(ctx, db, `
+		SELECT DISTINCT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+		FROM telemetry_entities te
+		INNER JOIN schema_entities se ON te.entity_id = se.entity_id
+		INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id
+		WHERE ts.schema_key = ? AND ts.tenant = ?`, s.SchemaKey, s.Tenant); err != nil {
+		return nil, err
+	}
+	if s.Scope, err = loadScope(ctx, db, false, `
+		SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+		FROM telemetry_scopes ts
+		INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+		INNER JOIN telemetry_schemas tschema ON ss.schema_id = tschema.schema_id
+		WHERE tschema.schema_key = ? AND tschema.tenant = ?
+		ORDER BY ts.last_seen DESC`, s.SchemaKey, s.Tenant); err != nil {
+		return nil, err
+	}
+
+	if s.Senders, err = loadSenders(ctx, db, s.SchemaKey); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// AssignTelemetrySchemaVersion assigns a SemVer version and lifecycle status to a schema once
+// schema.ResolveVersionAssignment accepts it against the versions of the schema key.
+func (r *TelemetrySchemaRepository) AssignTelemetrySchemaVersion(ctx context.Context, assignment schema.SchemaAssignment) error {
+	if err := assignment.Validate(); err != nil {
+		return err
+	}
+	tenantName, err := writeTenant(ctx)
+	if err != nil {
+		return err
+	}
+
+	target, err := r.GetTelemetrySchema(ctx, assignment.SchemaId)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("%w: %s", schema.ErrSchemaNotFound, assignment.SchemaId)
+	}
+
+	tx, err := r.pool.GetConnection().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current *schema.AssignedVersion
+	var others []schema.AssignedVersion
+	rows, err := tx.QueryContext(ctx, `
+		SELECT sv.schema_id, sv.version, COALESCE(sv.status, '')
+		FROM schema_versions sv
+		INNER JOIN telemetry_schemas t ON sv.schema_id = t.schema_id
+		WHERE t.schema_key = ? AND t.tenant = ?
+	`, target.SchemaKey, tenantName)
+	if err != nil {
+		return fmt.Errorf("failed to query schema versions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var assigned schema.AssignedVersion
+		if err := rows.Scan(&assigned.SchemaID, &assigned.Version, &assigned.Status); err != nil {
+			return fmt.Errorf("failed to scan schema version row: %w", err)
+		}
+		if assigned.SchemaID == assignment.SchemaId {
+			current = &assigned
+			continue
+		}
+		others = append(others, assigned)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating schema version rows: %w", err)
+	}
+
+	// The previous version is read on another connection, which WAL mode lets read while this
+	// transaction holds the write lock
+	change, err := schema.ResolveVersionAssignment(assignment, target, current, others, func(schemaID string) (*schema.TelemetrySchema, error) {
+		return r.GetTelemetrySchema(ctx, schemaID)
+	})
+	if err != nil {
+		return err
+	}
+
+	var deprecatedAt sql.NullTime
+	if change.DeprecatedAt != nil {
+		deprecatedAt = sql.NullTime{Time: utc(*change.DeprecatedAt), Valid: true}
+	}
+
+	now := utc(time.Now())
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO schema_versions (schema_id, version, reason, status, deprecated_at, replaced_by, created_at, updated_at, tenant)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT (schema_id) DO UPDATE SET
+			version = excluded.version,
+			reason = excluded.reason,
+			status = excluded.status,
+			deprecated_at = COALESCE(excluded.deprecated_at, schema_versions.deprecated_at),
+			replaced_by = COALESCE(excluded.replaced_by, schema_versions.replaced_by),
+			updated_at = excluded.updated_at
+	`,
+		assignment.SchemaId,
+		assignment.Version,
+		assignment.Reason,
+		change.Status,
+		deprecatedAt,
+		assignment.ReplacedBy,
+		now,
+		now,
+		tenantName,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to assign schema version: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetrySchemas(ctx context.Context, schemaKey string, params query.ListQueryParams) ([]schema.TelemetrySchema, int, error) {
+	where, args := tenantClause(ctx, "t")
+	where += " AND t.schema_key = ?"
+	args = append(args, schemaKey)
+	searchWhere, searchArgs := searchClause(params)
+	where += searchWhere
+	args = append(args, searchArgs...)
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM telemetry_schemas t
+		WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count schema assignments: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.TelemetrySchema{}, 0, nil
+	}
+
+	query := `
+		SELECT
+			t.schema_id,
+			COALESCE(sv.version, 'Unassigned') AS version,
+			sv.status,
+			sv.deprecated_at,
+			sv.replaced_by,
+			COUNT(DISTINCT se.entity_id) AS entity_count,
+			MAX(te.last_seen) AS last_seen
+		FROM telemetry_schemas t
+		LEFT JOIN schema_versions sv ON t.schema_id = sv.schema_id
+		LEFT JOIN schema_entities se ON t.schema_id = se.schema_id
+		LEFT JOIN telemetry_entities te ON se.entity_id = te.entity_id
+		WHERE 1=1` + where + `
+		GROUP BY t.schema_id, sv.version, sv.status, sv.deprecated_at, sv.replaced_by
+		ORDER BY MAX(te.last_seen) DESC NULLS LAST, t.schema_id
+		LIMIT ? OFFSET ?`
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query schema assignments: %w", err)
+	}
+	defer rows.Close()
+
+	var assignments []schema.TelemetrySchema
+	for rows.Next() {
+		var row schema.TelemetrySchema
+		var lastSeen, deprecatedAt nullTime
+		var status, replacedBy sql.NullString
+		if err := rows.Scan(&row.SchemaId, &row.Version, &status, &deprecatedAt, &replacedBy, &row.EntityCount, &lastSeen); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan schema assignment row: %w", err)
+		}
+		row.Status = schema.VersionStatus(status.String)
+		if deprecatedAt.Valid {
+			row.DeprecatedAt = &deprecatedAt.Time
+		}
+		row.ReplacedBy = replacedBy.String
+		if lastSeen.Valid {
+			row.LastSeen = &lastSeen.Time
+		}
+		assignments = append(assignments, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating schema assignment rows: %w", err)
+	}
+
+	return assignments, total, nil
+}
+
+func (r *TelemetrySchemaRepository) GetTelemetrySchema(ctx context.Context, schemaId string) (*schema.TelemetrySchema, error) {
+	tenantFilter, tenantArgs := tenantClause(ctx, "t")
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var s schema.TelemetrySchema
+	var lastSeen, deprecatedAt nullTime
+	var status, replacedBy sql.NullString
+	err := db.QueryRowContext(ctx, `
+		SELECT
+			t.schema_id,
+			t.schema_key,
+			t.signal_type,
+			t.unit,
+			t.metric_type,
+			t.temporality,
+			t.span_kind,
+			t.log_severity_text,
+			t.log_event_name,
+			t.profile_sample_unit,
+			COALESCE(sv.version, 'Unassigned') AS version,
+			sv.status,
+			sv.deprecated_at,
+			sv.replaced_by,
+			(SELECT COUNT(*) FROM schema_entities se WHERE se.schema_id = t.schema_id) AS entity_count,
+			(SELECT MAX(te.last_seen) FROM schema_entities se
+				INNER JOIN telemetry_entities te ON se.entity_id = te.entity_id
+				WHERE se.schema_id = t.schema_id) AS last_seen
+		FROM telemetry_schemas t
+		LEFT JOIN schema_versions sv ON t.schema_id = sv.schema_id
+		WHERE t.schema_id = ?`+tenantFilter,
+		append([]any{schemaId}, tenantArgs...)...).Scan(
+		&s.SchemaId,
+		&s.SchemaKey,
+		&s.TelemetryType,
+		&s.MetricUnit,
+		&s.MetricType,
+		&s.MetricTemporality,
+		&s.SpanKind,
+		&s.LogSeverityText,
+		&s.LogEventName,
+		&s.ProfileSampleUnit,
+		&s.Version,
+		&status,
+		&deprecatedAt,
+		&replacedBy,
+		&s.EntityCount,
+		&lastSeen,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema: %w", err)
+	}
+
+	if lastSeen.Valid {
+		s.LastSeen = &lastSeen.Time
+	}
+	s.Status = schema.VersionStatus(status.String)
+	if deprecatedAt.Valid {
+		s.DeprecatedAt = &deprecatedAt.Time
+	}
+	s.ReplacedBy = replacedBy.String
+
+	if s.Attributes, err = loadSchemaAttributes(ctx, db, schemaId); err != nil {
+		return nil, err
+	}
+
+	if s.Entities, err = loadEntityMap(ctx, db, `
+		SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+		FROM telemetry_entities te
+		INNER JOIN schema_entities se ON te.entity_id = se.entity_id
+		WHERE se.schema_id = ?`, schemaId); err != nil {
+		return nil, err
+	}
+
+	scopes, err := loadScopes(ctx, db, false, `
+		SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+		FROM telemetry_scopes ts
+		INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+		WHERE ss.schema_id = ?
+		ORDER BY ts.name, ts.version`, schemaId)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range scopes {
+		// The scopes of a variant are listed without their attributes
+		scope.Attributes = nil
+		s.Scopes = append(s.Scopes, scope)
+	}
+
+	return &s, nil
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetriesByEntity(ctx context.Context, entityType string, window query.TimeWindow) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "te")
+	windowClause, windowArgs := timeWindowClause("se", window)
+	args = append(args, windowArgs...)
+
+	db := r.pool.GetConnection()
+
+	telemetries, err := queryTelemetries(ctx, db, latestTelemetriesQuery(`
+			INNER JOIN schema_entities se ON t.schema_id = se.schema_id
+			INNER JOIN telemetry_entities te ON se.entity_id = te.entity_id`,
+		" AND te.entity_type = ?"+tenantFilter+windowClause, "t.updated_at DESC"),
+		append([]any{entityType}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetries by entity: %w", err)
+	}
+
+	for i := range telemetries {
+		if telemetries[i].Attributes, err = loadSchemaAttributes(ctx, db, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+
+		entities, err := loadEntities(ctx, db, true, `
+			SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen,
+				se.first_seen, se.last_seen, se.seen_count
+			FROM telemetry_entities te
+			INNER JOIN schema_entities se ON te.entity_id = se.entity_id
+			WHERE se.schema_id = ?`+windowClause, append([]any{telemetries[i].SchemaID}, windowArgs...)...)
+		if err != nil {
+			return nil, err
+		}
+		telemetries[i].Entities = entityMap(entities)
+
+		if telemetries[i].Scope, err = loadScope(ctx, db, true, `
+			SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen,
+				ss.first_seen, ss.last_seen, ss.seen_count
+			FROM telemetry_scopes ts
+			INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+			WHERE ss.schema_id = ?`, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+	}
+
+	return telemetries, nil
+}
+
+func (r *TelemetrySchemaRepository) ListTelemetriesByScope(ctx context.Context, scopeName string) ([]schema.Telemetry, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+
+	db := r.pool.GetConnection()
+
+	telemetries, err := queryTelemetries(ctx, db, latestTelemetriesQuery(`
+			INNER JOIN schema_scopes ss ON t.schema_id = ss.schema_id
+			INNER JOIN telemetry_scopes ts ON ss.scope_id = ts.scope_id`,
+		" AND ts.name = ?"+tenantFilter, "t.updated_at DESC"),
+		append([]any{scopeName}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetries by scope: %w", err)
+	}
+
+	for i := range telemetries {
+		if telemetries[i].Attributes, err = loadSchemaAttributes(ctx, db, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+		if telemetries[i].Entities, err = loadEntityMap(ctx, db, `
+			SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+			FROM telemetry_entities te
+			INNER JOIN schema_entities se ON te.entity_id = se.entity_id
+			WHERE se.schema_id = ?`, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+		if telemetries[i].Scope, err = loadScope(ctx, db, false, `
+			SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+			FROM telemetry_scopes ts
+			INNER JOIN schema_scopes ss ON ts.scope_id = ss.scope_id
+			WHERE ss.schema_id = ?`, telemetries[i].SchemaID); err != nil {
+			return nil, err
+		}
+	}
+
+	return telemetries, nil
+}
+
+func (r *TelemetrySchemaRepository) ListEntities(ctx context.Context, params query.ListQueryParams) ([]schema.Entity, int, error) {
+	if ownedListing(params) {
+		return []schema.Entity{}, 0, nil
+	}
+
+	where, args := tenantClause(ctx, "te")
+	if params.Search != "" {
+		where += " AND (te.entity_type LIKE ? OR te.entity_id LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM telemetry_entities te
+		WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count entities: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.Entity{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	entities, err := loadEntities(ctx, db, false, `
+		SELECT te.entity_id, te.entity_type, te.first_seen, te.last_seen
+		FROM telemetry_entities te
+		WHERE 1=1`+where+`
+		ORDER BY te.last_seen DESC, te.entity_id
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return entities, total, nil
+}
+
+func (r *TelemetrySchemaRepository) ListEntitiesByTelemetry(ctx context.Context, telemetryKey string, window query.TimeWindow) ([]schema.Entity, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+	windowClause, windowArgs := timeWindowClause("se", window)
+	args = append(args, windowArgs...)
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Link activity is aggregated over every schema version of the telemetry
+	return loadEntities(ctx, r.pool.GetConnection(), true, `
+		SELECT
+			entities.entity_id,
+			entities.entity_type,
+			entities.first_seen,
+			entities.last_seen,
+			MIN(se.first_seen),
+			MAX(se.last_seen),
+			SUM(se.seen_count)
+		FROM telemetry_entities entities
+		INNER JOIN schema_entities se ON se.entity_id = entities.entity_id
+		INNER JOIN telemetry_schemas ts ON se.schema_id = ts.schema_id
+		WHERE ts.schema_key = ?`+tenantFilter+windowClause+`
+		GROUP BY entities.entity_id, entities.entity_type, entities.first_seen, entities.last_seen
+		ORDER BY entities.last_seen DESC`, append([]any{telemetryKey}, args...)...)
+}
+
+func (r *TelemetrySchemaRepository) ListScopes(ctx context.Context, params query.ListQueryParams) ([]schema.Scope, int, error) {
+	if ownedListing(params) {
+		return []schema.Scope{}, 0, nil
+	}
+
+	where, args := tenantClause(ctx, "ts")
+	if params.Search != "" {
+		where += " AND (ts.name LIKE ? OR ts.version LIKE ? OR ts.schema_url LIKE ?)"
+		searchTerm := "%" + params.Search + "%"
+		args = append(args, searchTerm, searchTerm, searchTerm)
+	}
+
+	db := r.pool.GetConnection()
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	total := 0
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM telemetry_scopes ts
+		WHERE 1=1`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count scopes: %w", err)
+	}
+
+	if total == 0 {
+		return []schema.Scope{}, 0, nil
+	}
+
+	args = append(args, params.PageSize, (params.Page-1)*params.PageSize)
+	scopes, err := loadScopes(ctx, db, false, `
+		SELECT ts.scope_id, ts.name, ts.version, ts.schema_url, ts.first_seen, ts.last_seen
+		FROM telemetry_scopes ts
+		WHERE 1=1`+where+`
+		ORDER BY ts.last_seen DESC, ts.scope_id
+		LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return scopes, total, nil
+}
+
+func (r *TelemetrySchemaRepository) ListScopesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Scope, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	// Link activity is aggregated over every schema version of the telemetry
+	return loadScopes(ctx, r.pool.GetConnection(), true, `
+		SELECT
+			scopes.scope_id,
+			scopes.name,
+			scopes.version,
+			scopes.schema_url,
+			scopes.first_seen,
+			scopes.last_seen,
+			MIN(ss.first_seen),
+			MAX(ss.last_seen),
+			SUM(ss.seen_count)
+		FROM telemetry_scopes scopes
+		INNER JOIN schema_scopes ss ON ss.scope_id = scopes.scope_id
+		INNER JOIN telemetry_schemas ts ON ss.schema_id = ts.schema_id
+		WHERE ts.schema_key = ?`+tenantFilter+`
+		GROUP BY scopes.scope_id, scopes.name, scopes.version, scopes.schema_url, scopes.first_seen, scopes.last_seen
+		ORDER BY scopes.last_seen DESC`, append([]any{telemetryKey}, args...)...)
+}
+
+func (r *TelemetrySchemaRepository) Pool() *ConnectionPool {
+	return r.pool
+}
+
+// loadSchemaAttributes returns the attributes of a schema ordered by name
+func loadSchemaAttributes(ctx context.Context, q execQuerier, schemaID string) ([]schema.Attribute, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT name, type, source
+		FROM schema_attributes
+		WHERE schema_id = ?
+		ORDER BY name, type, source`, schemaID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attributes []schema.Attribute
+	for rows.Next() {
+		var attr schema.Attribute
+		if err := rows.Scan(&attr.Name, &attr.Type, &attr.Source); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute row: %w", err)
+		}
+		attributes = append(attributes, attr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attribute rows: %w", err)
+	}
+	return attributes, nil
+}
+
+// loadAttributeValues returns the attributes of an entity or scope from entity_attributes or scope_attributes
+func loadAttributeValues(ctx context.Context, q execQuerier, table, column, id string) (map[string]interface{}, error) {
+	rows, err := q.QueryContext(ctx, fmt.Sprintf(`SELECT name, value FROM %s WHERE %s = ?`, table, column), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	attributes := make(map[string]interface{})
+	for rows.Next() {
+		var name string
+		var value sql.NullString
+		if err := rows.Scan(&name, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan %s row: %w", table, err)
+		}
+		attributes[name] = value.String
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s rows: %w", table, err)
+	}
+	return attributes, nil
+}
+
+// linkColumns holds the nullable activity columns of a schema_entities or schema_scopes row
+type linkColumns struct {
+	firstSeen nullTime
+	lastSeen  nullTime
+	seenCount sql.NullInt64
+}
+
+func (c linkColumns) toSchemaLink() *schema.SchemaLink {
+	if !c.firstSeen.Valid && !c.lastSeen.Valid {
+		return nil
+	}
+	return &schema.SchemaLink{
+		FirstSeen: c.firstSeen.Time,
+		LastSeen:  c.lastSeen.Time,
+		SeenCount: c.seenCount.Int64,
+	}
+}
+
+// loadEntities reads the entities selected by a query of their ID, type, first and last seen
+// times, followed by the activity columns of their link when withLink is set, and their attributes
+func loadEntities(ctx context.Context, q execQuerier, withLink bool, query string, args ...any) ([]schema.Entity, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entities: %w", err)
+	}
+	defer rows.Close()
+
+	entities := []schema.Entity{}
+	for rows.Next() {
+		var entity schema.Entity
+		var firstSeen, lastSeen nullTime
+		var link linkColumns
+		dest := []any{&entity.ID, &entity.Type, &firstSeen, &lastSeen}
+		if withLink {
+			dest = append(dest, &link.firstSeen, &link.lastSeen, &link.seenCount)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan entity row: %w", err)
+		}
+		entity.FirstSeen = firstSeen.Time
+		entity.LastSeen = lastSeen.Time
+		entity.Link = link.toSchemaLink()
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entity rows: %w", err)
+	}
+	rows.Close()
+
+	for i := range entities {
+		if entities[i].Attributes, err = loadAttributeValues(ctx, q, "entity_attributes", "entity_id", entities[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return entities, nil
+}
+
+func loadEntityMap(ctx context.Context, q execQuerier, query string, args ...any) (map[string]*schema.Entity, error) {
+	entities, err := loadEntities(ctx, q, false, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return entityMap(entities), nil
+}
+
+func entityMap(entities []schema.Entity) map[string]*schema.Entity {
+	byID := make(map[string]*schema.Entity, len(entities))
+	for i := range entities {
+		byID[entities[i].ID] = &entities[i]
+	}
+	return byID
+}
+
+// loadScopes reads the scopes selected by a query of their ID, name, version, schema URL, first
+// and last seen times, followed by the activity columns of their link when withLink is set, and
+// their attributes
+func loadScopes(ctx context.Context, q execQuerier, withLink bool, query string, args ...any) ([]schema.Scope, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scopes: %w", err)
+	}
+	defer rows.Close()
+
+	scopes := []schema.Scope{}
+	for rows.Next() {
+		var scope schema.Scope
+		var version, schemaURL sql.NullString
+		var firstSeen, lastSeen nullTime
+		var link linkColumns
+		dest := []any{&scope.ID, &scope.Name, &version, &schemaURL, &firstSeen, &lastSeen}
+		if withLink {
+			dest = append(dest, &link.firstSeen, &link.lastSeen, &link.seenCount)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan scope row: %w", err)
+		}
+		scope.Version = version.String
+		scope.SchemaURL = schemaURL.String
+		scope.FirstSeen = firstSeen.Time
+		scope.LastSeen = lastSeen.Time
+		scope.Link = link.toSchemaLink()
+		scopes = append(scopes, scope)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating scope rows: %w", err)
+	}
+	rows.Close()
+
+	for i := range scopes {
+		if scopes[i].Attributes, err = loadAttributeValues(ctx, q, "scope_attributes", "scope_id", scopes[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return scopes, nil
+}
+
+// loadScope returns the first scope selected by the query, each schema having at most one scope
+func loadScope(ctx context.Context, q execQuerier, withLink bool, query string, args ...any) (*schema.Scope, error) {
+	scopes, err := loadScopes(ctx, q, withLink, query+" LIMIT 1", args...)
+	if err != nil || len(scopes) == 0 {
+		return nil, err
+	}
+	return &scopes[0], nil
+}
+
+// loadSenders aggregates the clients that sent any variant of a schema key
+func loadSenders(ctx context.Context, q execQuerier, schemaKey string) ([]schema.Sender, error) {
+	tenantFilter, args := tenantClause(ctx, "ts")
+	rows, err := q.QueryContext(ctx, `
+		SELECT ss.identity, MIN(ss.first_seen), MAX(ss.last_seen), SUM(ss.seen_count)
+		FROM schema_senders ss
+		JOIN telemetry_schemas ts ON ts.schema_id = ss.schema_id
+		WHERE ts.schema_key = ?`+tenantFilter+`
+		GROUP BY ss.identity
+		ORDER BY MAX(ss.last_seen) DESC, ss.identity`, append([]any{schemaKey}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema senders: %w", err)
+	}
+	defer rows.Close()
+
+	var senders []schema.Sender
+	for rows.Next() {
+		var sender schema.Sender
+		var firstSeen, lastSeen nullTime
+		if err := rows.Scan(&sender.Identity, &firstSeen, &lastSeen, &sender.SeenCount); err != nil {
+			return nil, fmt.Errorf("failed to scan schema sender: %w", err)
+		}
+		sender.FirstSeen = firstSeen.Time
+		sender.LastSeen = lastSeen.Time
+		senders = append(senders, sender)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating schema senders: %w", err)
+	}
+	return senders, nil
+}
+
+// timeWindowClause returns the conditions restricting a link table alias to links active within the window
+func timeWindowClause(alias string, window query.TimeWindow) (string, []any) {
+	clause := ""
+	var args []any
+	if !window.Since.IsZero() {
+		clause += fmt.Sprintf(" AND %s.last_seen >= ?", alias)
+		args = append(args, utc(window.Since))
+	}
+	if !window.Until.IsZero() {
+		clause += fmt.Sprintf(" AND %s.first_seen <= ?", alias)
+		args = append(args, utc(window.Until))
+	}
+	return clause, args
+}
@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/repository/query"
+	"github.com/tallycat/tallycat/internal/repository/repositorytest"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+// Writers wait for the write lock their transaction takes when it begins instead of failing
+// with SQLITE_BUSY
+func TestRegisterTelemetrySchemas_ConcurrentWriters(t *testing.T) {
+	repo := setupTestDB(t)
+	ctx := context.Background()
+
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repo.RegisterTelemetrySchemas(ctx, []schema.Telemetry{
+				repositorytest.Telemetry("v1", "checkout.requests", []string{"http.method"}, t0.Add(time.Duration(i)*time.Second)),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	entities, err := repo.ListEntitiesByTelemetry(ctx, "checkout.requests", query.TimeWindow{})
+	require.NoError(t, err)
+	require.Len(t, entities, 1)
+	require.Equal(t, int64(8), entities[0].Link.SeenCount)
+}
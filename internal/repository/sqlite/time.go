@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeLayout is the layout of the times written by the driver, see the _time_format parameter
+const timeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+// utc returns the time written for t. Times are stored in UTC so their text orders as the times do.
+func utc(t time.Time) time.Time {
+	return t.UTC()
+}
+
+// nullTime scans the times of columns declared as TIMESTAMP, read as times, and of aggregates
+// over them, read as text
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *nullTime) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time, t.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+		return nil
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	}
+	return fmt.Errorf("cannot scan %T into a time", value)
+}
+
+func (t *nullTime) parse(value string) error {
+	parsed, err := time.Parse(timeLayout, value)
+	if err != nil {
+		return fmt.Errorf("failed to parse time %q: %w", value, err)
+	}
+	t.Time, t.Valid = parsed, true
+	return nil
+}
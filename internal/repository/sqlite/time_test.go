@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullTime_Scan(t *testing.T) {
+	expected := time.Date(2025, 6, 1, 10, 0, 0, 500, time.UTC)
+
+	// Aggregates over TIMESTAMP columns are read back as the text the driver wrote
+	for _, value := range []any{expected, "2025-06-01 10:00:00.0000005+00:00", []byte("2025-06-01 10:00:00.0000005+00:00")} {
+		var scanned nullTime
+		require.NoError(t, scanned.Scan(value), "%T", value)
+		assert.True(t, scanned.Valid)
+		assert.True(t, expected.Equal(scanned.Time), "%T: %v", value, scanned.Time)
+	}
+
+	scanned := nullTime{Time: expected, Valid: true}
+	require.NoError(t, scanned.Scan(nil))
+	assert.False(t, scanned.Valid)
+	assert.True(t, scanned.Time.IsZero())
+
+	assert.Error(t, scanned.Scan("2025-06-01T10:00:00Z"))
+	assert.Error(t, scanned.Scan(42))
+}
+
+// Times are written in UTC, so their text orders as the times do whatever the local zone
+func TestUTC_OrdersAsText(t *testing.T) {
+	earlier := time.Date(2025, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	later := time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC)
+	require.True(t, earlier.Before(later))
+	assert.Less(t, utc(earlier).Format(timeLayout), utc(later).Format(timeLayout))
+}
@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/tallycat/tallycat/internal/repository/query"
 	"github.com/tallycat/tallycat/internal/schema"
 )
 
+// The repositories read and write the catalog of the tenant of their context, see the tenant package
 type TelemetrySchemaRepository interface {
 	RegisterTelemetrySchemas(ctx context.Context, schemas []schema.Telemetry) error
 	ListTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.Telemetry, int, error)
@@ -15,14 +17,116 @@ type TelemetrySchemaRepository interface {
 	AssignTelemetrySchemaVersion(ctx context.Context, assignment schema.SchemaAssignment) error
 	GetTelemetrySchema(ctx context.Context, schemaId string) (*schema.TelemetrySchema, error)
 	ListEntities(ctx context.Context, params query.ListQueryParams) ([]schema.Entity, int, error)
-	ListEntitiesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Entity, error)
-	ListTelemetriesByEntity(ctx context.Context, entityType string) ([]schema.Telemetry, error)
+	ListEntitiesByTelemetry(ctx context.Context, telemetryKey string, window query.TimeWindow) ([]schema.Entity, error)
+	ListTelemetriesByEntity(ctx context.Context, entityType string, window query.TimeWindow) ([]schema.Telemetry, error)
 	ListScopes(ctx context.Context, params query.ListQueryParams) ([]schema.Scope, int, error)
 	ListScopesByTelemetry(ctx context.Context, telemetryKey string) ([]schema.Scope, error)
 	ListTelemetriesByScope(ctx context.Context, scopeName string) ([]schema.Telemetry, error)
+	ListProducers(ctx context.Context, params query.ListQueryParams) ([]schema.Producer, int, error)
+	ListTelemetriesByProducer(ctx context.Context, name, version string) ([]schema.Telemetry, error)
 }
 
 type TelemetryHistoryRepository interface {
 	InsertTelemetryHistory(ctx context.Context, h *schema.TelemetryHistory) error
 	ListTelemetryHistory(ctx context.Context, telemetryID string, page, pageSize int) ([]schema.TelemetryHistory, int, error)
+	GetSchemaIDAt(ctx context.Context, schemaKey string, at time.Time) (string, error)
+}
+
+type EntityHistoryRepository interface {
+	ListEntityAttributeChanges(ctx context.Context, entityID string) ([]schema.EntityAttributeChange, error)
+	ListEntitySchemaChanges(ctx context.Context, entityID string, attributeName string) ([]schema.EntitySchemaChange, error)
+}
+
+// EntityRekeyRepository moves the entities stored under other IDs than the entity model gives
+// them, as the model may identify entities differently than the release that stored them
+type EntityRekeyRepository interface {
+	RekeyEntities(ctx context.Context, model *schema.EntityModel) (int, error)
+}
+
+// ProducerRekeyRepository moves the producers stored under other IDs than their own, as the
+// producers stored per instance by earlier releases
+type ProducerRekeyRepository interface {
+	RekeyProducers(ctx context.Context) (int, error)
+}
+
+type DependencyGraphRepository interface {
+	RegisterDependencies(ctx context.Context, graph schema.DependencyGraph) error
+	GetDependencyGraph(ctx context.Context, window query.TimeWindow, node string) (*schema.DependencyGraph, error)
+}
+
+type PolicyViolationRepository interface {
+	RecordPolicyViolations(ctx context.Context, violations []schema.PolicyViolation) error
+	ListPolicyViolations(ctx context.Context, params query.ListQueryParams, filter query.PolicyViolationFilter) ([]schema.PolicyViolation, int, error)
+}
+
+type BudgetRepository interface {
+	ListScopeSchemaIDs(ctx context.Context, scope schema.BudgetScope, telemetryType schema.TelemetryType, since time.Time) ([]string, error)
+	CountActiveSeries(ctx context.Context, scope schema.BudgetScope, since time.Time) (int, error)
+	GetBudgetUsage(ctx context.Context, budget schema.Budget, activeSeriesWindow time.Duration, now time.Time) (*schema.BudgetUsage, error)
+	RecordBudgetBreaches(ctx context.Context, breaches []schema.BudgetBreach) error
+	ListBudgetBreaches(ctx context.Context, params query.ListQueryParams, filter query.BudgetBreachFilter) ([]schema.BudgetBreach, int, error)
+}
+
+type UsageRollupRepository interface {
+	GetUsage(ctx context.Context, filter query.UsageFilter) (*schema.UsageSeries, error)
+	CompactUsage(ctx context.Context, now time.Time, retention schema.UsageRetention) error
+}
+
+type RetentionRepository interface {
+	RunRetention(ctx context.Context, policy *schema.RetentionPolicy, now time.Time) (*schema.RetentionResult, error)
+	ListStaleTelemetries(ctx context.Context, params query.ListQueryParams) ([]schema.StaleTelemetry, int, error)
+	RemoveStaleTelemetry(ctx context.Context, schemaID string, action schema.RetentionAction) (*schema.RetentionResult, error)
+}
+
+type OwnershipRepository interface {
+	RefreshOwnership(ctx context.Context) error
+	AssignOwners(ctx context.Context, kind schema.OwnedKind, id string, teams []string) (*schema.Ownership, error)
+	GetOwnership(ctx context.Context, kind schema.OwnedKind, id string) (*schema.Ownership, error)
+	ListTeams(ctx context.Context) ([]schema.TeamSummary, error)
+	GetTeamInventory(ctx context.Context, team string) (*schema.TeamInventory, error)
+}
+
+type DocumentationRepository interface {
+	GetDocumentation(ctx context.Context, schemaKey string) (*schema.TelemetryDocumentation, error)
+	UpdateDocumentation(ctx context.Context, schemaKey string, patch schema.DocumentationPatch) (*schema.TelemetryDocumentation, error)
+}
+
+type DeclaredSchemaRepository interface {
+	ImportDeclaredSchemas(ctx context.Context, registry string, declared []schema.DeclaredTelemetry) error
+	ListDeclaredSchemas(ctx context.Context, filter query.DeclaredSchemaFilter) ([]schema.DeclaredTelemetry, error)
+}
+
+type ReviewRepository interface {
+	ListReviews(ctx context.Context, params query.ListQueryParams, filter query.ReviewFilter) ([]schema.TelemetryReview, int, error)
+	DecideReview(ctx context.Context, id int64, decision schema.ReviewDecision) (*schema.TelemetryReview, error)
+	ListReviewNotifications(ctx context.Context, params query.ListQueryParams, window query.TimeWindow) ([]schema.ReviewNotification, int, error)
+	ListReviewPolicies(ctx context.Context) ([]schema.Policy, error)
+}
+
+// AuditLogRepository is append-only, entries are never updated or removed
+type AuditLogRepository interface {
+	RecordAuditEntry(ctx context.Context, entry schema.AuditEntry) error
+	ListAuditEntries(ctx context.Context, params query.ListQueryParams, filter query.AuditFilter) ([]schema.AuditEntry, int, error)
+}
+
+type IngestTokenRepository interface {
+	CreateIngestToken(ctx context.Context, token schema.IngestToken) (*schema.IngestToken, error)
+	ListIngestTokens(ctx context.Context) ([]schema.IngestToken, error)
+	// ListActiveIngestTokens returns the tokens not revoked, with their hash
+	ListActiveIngestTokens(ctx context.Context) ([]schema.IngestToken, error)
+	RevokeIngestToken(ctx context.Context, name string, at time.Time) error
+	RecordIngestUsage(ctx context.Context, usage schema.IngestUsage) error
+	ListIngestTokenStats(ctx context.Context, window query.TimeWindow) ([]schema.IngestTokenStats, error)
+}
+
+type TenantRepository interface {
+	// ListTenants summarizes the catalog of every tenant, whatever the tenant of the context
+	ListTenants(ctx context.Context) ([]schema.TenantSummary, error)
+}
+
+// BackupRepository exports the catalog of every tenant to a directory of Parquet files and a
+// manifest, and restores such a directory into an empty catalog
+type BackupRepository interface {
+	Backup(ctx context.Context, dir string) (*schema.BackupManifest, error)
+	Restore(ctx context.Context, dir string) (*schema.BackupManifest, error)
 }
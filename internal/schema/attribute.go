@@ -56,6 +56,9 @@ type Attribute struct {
 	// specify the conditions under which the attribute is required.
 	RequirementLevel RequirementLevel `json:"requirement_level,omitempty"`
 
+	// Stability is the curated maturity of the attribute, exported as stable when unset.
+	Stability Stability `json:"stability,omitempty"`
+
 	// Source is the source of the attribute.
 	// If the attribute is a resource attribute, the source is "Resource".
 	// If the attribute is a scope attribute, the source is "Scope".
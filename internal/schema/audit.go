@@ -0,0 +1,21 @@
+package schema
+
+import "time"
+
+// AuditEntry records a mutating request to the HTTP API, including refused attempts
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	// Subject, Role and AuthMethod are empty when the caller could not be authenticated
+	Subject    string `json:"subject"`
+	Role       string `json:"role"`
+	AuthMethod string `json:"authMethod"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	// Route is the route pattern the request matched, e.g. /api/v1/reviews/{id}/decision
+	Route      string `json:"route"`
+	Status     int    `json:"status"`
+	RemoteAddr string `json:"remoteAddr"`
+	// Tenant is the tenant the request was scoped to
+	Tenant string `json:"tenant,omitempty"`
+}
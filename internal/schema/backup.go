@@ -0,0 +1,90 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var (
+	// ErrInvalidBackup is returned for a backup with a missing, altered or unexpected file
+	ErrInvalidBackup = errors.New("invalid backup")
+	// ErrBackupVersionMismatch is returned when restoring a backup taken at another migration version
+	ErrBackupVersionMismatch = errors.New("backup migration version mismatch")
+	// ErrCatalogNotEmpty is returned when restoring into a catalog that already holds telemetry
+	ErrCatalogNotEmpty = errors.New("catalog is not empty")
+	// ErrBackupExists is returned when backing up into a directory that already holds files
+	ErrBackupExists = errors.New("backup already exists")
+)
+
+// BackupFormatVersion is the version of the backup layout, bumped when restoring older backups
+// needs converting them
+const BackupFormatVersion = 1
+
+// BackupManifestFile names the manifest in a backup directory
+const BackupManifestFile = "manifest.json"
+
+var backupName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// BackupManifest describes a backup, a directory holding a Parquet file per catalog table
+type BackupManifest struct {
+	FormatVersion int `json:"formatVersion"`
+	// MigrationVersion is the last migration applied to the database backed up, backups restore
+	// into databases at the same version only
+	MigrationVersion int           `json:"migrationVersion"`
+	CreatedAt        time.Time     `json:"createdAt"`
+	Tables           []BackupTable `json:"tables"`
+}
+
+// BackupTable is the Parquet file of a catalog table
+type BackupTable struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+// Backup is a backup kept in the backup directory of the server
+type Backup struct {
+	Name     string         `json:"name"`
+	Manifest BackupManifest `json:"manifest"`
+}
+
+// ValidBackupName reports whether a backup or backup file name is letters, digits, dots,
+// dashes and underscores, so it cannot name a path outside the backup directory
+func ValidBackupName(name string) bool {
+	return backupName.MatchString(name) && name != "." && name != ".."
+}
+
+// Validate checks the manifest is of a known format and lists each table once, in a file of
+// the backup directory
+func (m *BackupManifest) Validate() error {
+	if m.FormatVersion != BackupFormatVersion {
+		return fmt.Errorf("%w: unsupported format version %d", ErrInvalidBackup, m.FormatVersion)
+	}
+	if m.MigrationVersion <= 0 {
+		return fmt.Errorf("%w: missing migration version", ErrInvalidBackup)
+	}
+	if len(m.Tables) == 0 {
+		return fmt.Errorf("%w: no tables", ErrInvalidBackup)
+	}
+
+	seen := make(map[string]bool, len(m.Tables))
+	for _, table := range m.Tables {
+		if seen[table.Name] {
+			return fmt.Errorf("%w: table %s is listed twice", ErrInvalidBackup, table.Name)
+		}
+		seen[table.Name] = true
+		if !ValidBackupName(table.File) || table.File == BackupManifestFile {
+			return fmt.Errorf("%w: invalid file %q for table %s", ErrInvalidBackup, table.File, table.Name)
+		}
+		if table.Rows < 0 {
+			return fmt.Errorf("%w: negative row count for table %s", ErrInvalidBackup, table.Name)
+		}
+		if len(table.SHA256) != 64 {
+			return fmt.Errorf("%w: invalid checksum for table %s", ErrInvalidBackup, table.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupManifest_Validate(t *testing.T) {
+	checksum := strings.Repeat("a", 64)
+	valid := func() BackupManifest {
+		return BackupManifest{
+			FormatVersion:    BackupFormatVersion,
+			MigrationVersion: 23,
+			Tables: []BackupTable{
+				{Name: "telemetry_schemas", File: "telemetry_schemas.parquet", Rows: 2, SHA256: checksum},
+				{Name: "schema_attributes", File: "schema_attributes.parquet", SHA256: checksum},
+			},
+		}
+	}
+
+	manifest := valid()
+	require.NoError(t, manifest.Validate())
+
+	tests := []struct {
+		name   string
+		change func(m *BackupManifest)
+	}{
+		{"unknown format", func(m *BackupManifest) { m.FormatVersion = 2 }},
+		{"no migration version", func(m *BackupManifest) { m.MigrationVersion = 0 }},
+		{"no tables", func(m *BackupManifest) { m.Tables = nil }},
+		{"table listed twice", func(m *BackupManifest) { m.Tables[1].Name = "telemetry_schemas" }},
+		{"file outside the backup", func(m *BackupManifest) { m.Tables[0].File = "../telemetry_schemas.parquet" }},
+		{"file named as the manifest", func(m *BackupManifest) { m.Tables[0].File = BackupManifestFile }},
+		{"negative rows", func(m *BackupManifest) { m.Tables[0].Rows = -1 }},
+		{"missing checksum", func(m *BackupManifest) { m.Tables[0].SHA256 = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := valid()
+			tt.change(&manifest)
+			assert.ErrorIs(t, manifest.Validate(), ErrInvalidBackup)
+		})
+	}
+}
+
+func TestValidBackupName(t *testing.T) {
+	assert.True(t, ValidBackupName("20250601T100000Z"))
+	assert.True(t, ValidBackupName("telemetry_schemas.parquet"))
+	assert.False(t, ValidBackupName(""))
+	assert.False(t, ValidBackupName(".."))
+	assert.False(t, ValidBackupName("../etc"))
+	assert.False(t, ValidBackupName("a/b"))
+}
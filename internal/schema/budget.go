@@ -0,0 +1,310 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultActiveSeriesWindow is how long a metric series counts as active after it was last seen
+const DefaultActiveSeriesWindow = time.Hour
+
+// DefaultBudgetEvaluationInterval is how often the usage of a budget is evaluated on ingest
+const DefaultBudgetEvaluationInterval = 30 * time.Second
+
+// BudgetScopeKind is what a budget counts usage for
+type BudgetScopeKind string
+
+const (
+	// BudgetScopeEntity counts the usage of the entities matching a selector, e.g. service.name=checkout
+	BudgetScopeEntity BudgetScopeKind = "entity"
+	// BudgetScopeGroup counts the usage of a named group of entities, such as the services owned by a team
+	BudgetScopeGroup BudgetScopeKind = "group"
+	// BudgetScopeTenant counts all the telemetry received
+	BudgetScopeTenant BudgetScopeKind = "tenant"
+)
+
+const (
+	BudgetLimitMetricSchemas    = "max_metric_schemas"
+	BudgetLimitActiveSeries     = "max_active_series"
+	BudgetLimitLogSchemasPerDay = "max_log_schemas_per_day"
+)
+
+// EntitySelector matches entities having all of its attribute values
+type EntitySelector map[string]string
+
+// ParseEntitySelector parses a key=value[,key=value] selector
+func ParseEntitySelector(s string) (EntitySelector, error) {
+	selector := EntitySelector{}
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid selector %q, expected key=value[,key=value]", s)
+		}
+		selector[key] = strings.TrimSpace(value)
+	}
+	return selector, nil
+}
+
+// Matches reports whether the entity has every attribute value of the selector
+func (s EntitySelector) Matches(entity *Entity) bool {
+	for key, value := range s {
+		v, ok := entity.Attributes[key]
+		if !ok || fmt.Sprintf("%v", v) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// BudgetScope selects the telemetry a budget counts. An empty scope covers the whole tenant.
+type BudgetScope struct {
+	// Entity is a selector such as service.name=checkout
+	Entity string `yaml:"entity,omitempty" json:"entity,omitempty"`
+	// Group names a set of entities, each matched by one of Entities
+	Group    string   `yaml:"group,omitempty" json:"group,omitempty"`
+	Entities []string `yaml:"entities,omitempty" json:"entities,omitempty"`
+
+	selectors []EntitySelector
+}
+
+// Kind returns what the scope counts usage for
+func (s *BudgetScope) Kind() BudgetScopeKind {
+	switch {
+	case s.Entity != "":
+		return BudgetScopeEntity
+	case s.Group != "":
+		return BudgetScopeGroup
+	}
+	return BudgetScopeTenant
+}
+
+// String describes the scope, e.g. entity service.name=checkout
+func (s *BudgetScope) String() string {
+	switch s.Kind() {
+	case BudgetScopeEntity:
+		return "entity " + s.Entity
+	case BudgetScopeGroup:
+		return "group " + s.Group
+	}
+	return string(BudgetScopeTenant)
+}
+
+// Selectors returns the entity selectors of the scope, none for a tenant scope
+func (s *BudgetScope) Selectors() []EntitySelector {
+	return s.selectors
+}
+
+func (s *BudgetScope) parse() error {
+	s.selectors = nil
+	switch s.Kind() {
+	case BudgetScopeEntity:
+		if s.Group != "" || len(s.Entities) > 0 {
+			return fmt.Errorf("entity scope cannot also define a group")
+		}
+		selector, err := ParseEntitySelector(s.Entity)
+		if err != nil {
+			return err
+		}
+		s.selectors = []EntitySelector{selector}
+	case BudgetScopeGroup:
+		if len(s.Entities) == 0 {
+			return fmt.Errorf("group %q must list its entities", s.Group)
+		}
+		for _, entity := range s.Entities {
+			selector, err := ParseEntitySelector(entity)
+			if err != nil {
+				return err
+			}
+			s.selectors = append(s.selectors, selector)
+		}
+	default:
+		if len(s.Entities) > 0 {
+			return fmt.Errorf("entities require a group name")
+		}
+	}
+	return nil
+}
+
+// Covers reports whether the telemetry was emitted by an entity of the scope
+func (s *BudgetScope) Covers(telemetry *Telemetry) bool {
+	if s.Kind() == BudgetScopeTenant {
+		return true
+	}
+	for _, entity := range telemetry.Entities {
+		for _, selector := range s.selectors {
+			if selector.Matches(entity) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Budget caps the cardinality and volume of the telemetry of a scope. A zero limit is not checked.
+type Budget struct {
+	Name        string      `yaml:"name" json:"name"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Scope       BudgetScope `yaml:"scope,omitempty" json:"scope"`
+
+	MaxMetricSchemas    int `yaml:"max_metric_schemas,omitempty" json:"maxMetricSchemas,omitempty"`
+	MaxActiveSeries     int `yaml:"max_active_series,omitempty" json:"maxActiveSeries,omitempty"`
+	MaxLogSchemasPerDay int `yaml:"max_log_schemas_per_day,omitempty" json:"maxLogSchemasPerDay,omitempty"`
+
+	// Enforce rejects new metric and log schemas that would exceed the budget on ingest
+	Enforce bool `yaml:"enforce,omitempty" json:"enforce"`
+}
+
+// BudgetSet is the set of budgets loaded from disk
+type BudgetSet struct {
+	// ActiveSeriesWindow is how long a series counts as active after it was last seen
+	ActiveSeriesWindow time.Duration `yaml:"active_series_window,omitempty" json:"activeSeriesWindow"`
+	// EvaluationInterval is how often the usage of each budget is evaluated per tenant, so
+	// breaches are recorded up to an interval after they happen
+	EvaluationInterval time.Duration `yaml:"evaluation_interval,omitempty" json:"evaluationInterval"`
+	Budgets            []Budget      `yaml:"budgets" json:"budgets"`
+}
+
+// BudgetUsage is the usage of a budget scope at a point in time
+type BudgetUsage struct {
+	MetricSchemas   int       `json:"metricSchemas"`
+	ActiveSeries    int       `json:"activeSeries"`
+	LogSchemasToday int       `json:"logSchemasToday"`
+	EvaluatedAt     time.Time `json:"evaluatedAt"`
+}
+
+// BudgetStatus is a budget with its current usage and the limits it exceeds
+type BudgetStatus struct {
+	Budget   Budget         `json:"budget"`
+	Usage    BudgetUsage    `json:"usage"`
+	Breaches []BudgetBreach `json:"breaches"`
+}
+
+// BudgetBreach records a budget limit exceeded by the usage of its scope
+type BudgetBreach struct {
+	ID         int64     `json:"id"`
+	Budget     string    `json:"budget"`
+	Scope      string    `json:"scope"`
+	Limit      string    `json:"limit"`
+	Value      int       `json:"value"`
+	Max        int       `json:"max"`
+	ObservedAt time.Time `json:"observedAt"`
+}
+
+// LoadBudgets reads a budget set from a YAML file
+func LoadBudgets(path string) (*BudgetSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read budgets %s: %w", path, err)
+	}
+	return ParseBudgets(data)
+}
+
+// ParseBudgets parses and validates a budget set from YAML content
+func ParseBudgets(data []byte) (*BudgetSet, error) {
+	var set BudgetSet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse budgets: %w", err)
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Validate checks that budgets are uniquely named, have a valid scope and define at least one limit.
+// The active series window defaults to DefaultActiveSeriesWindow and the evaluation interval to
+// DefaultBudgetEvaluationInterval.
+func (s *BudgetSet) Validate() error {
+	if s.ActiveSeriesWindow < 0 {
+		return fmt.Errorf("active series window cannot be negative")
+	}
+	if s.ActiveSeriesWindow == 0 {
+		s.ActiveSeriesWindow = DefaultActiveSeriesWindow
+	}
+	if s.EvaluationInterval < 0 {
+		return fmt.Errorf("evaluation interval cannot be negative")
+	}
+	if s.EvaluationInterval == 0 {
+		s.EvaluationInterval = DefaultBudgetEvaluationInterval
+	}
+
+	names := make(map[string]bool)
+	for i := range s.Budgets {
+		b := &s.Budgets[i]
+		if b.Name == "" {
+			return fmt.Errorf("budget name cannot be empty")
+		}
+		if names[b.Name] {
+			return fmt.Errorf("budget %q is defined more than once", b.Name)
+		}
+		names[b.Name] = true
+
+		if err := b.Scope.parse(); err != nil {
+			return fmt.Errorf("budget %q has an invalid scope: %w", b.Name, err)
+		}
+
+		if b.MaxMetricSchemas < 0 || b.MaxActiveSeries < 0 || b.MaxLogSchemasPerDay < 0 {
+			return fmt.Errorf("budget %q limits cannot be negative", b.Name)
+		}
+		if b.MaxMetricSchemas == 0 && b.MaxActiveSeries == 0 && b.MaxLogSchemasPerDay == 0 {
+			return fmt.Errorf("budget %q must define at least one limit", b.Name)
+		}
+	}
+	return nil
+}
+
+// Check returns the limits of the budget exceeded by the usage
+func (b *Budget) Check(usage BudgetUsage) []BudgetBreach {
+	limits := []struct {
+		name       string
+		value, max int
+	}{
+		{BudgetLimitMetricSchemas, usage.MetricSchemas, b.MaxMetricSchemas},
+		{BudgetLimitActiveSeries, usage.ActiveSeries, b.MaxActiveSeries},
+		{BudgetLimitLogSchemasPerDay, usage.LogSchemasToday, b.MaxLogSchemasPerDay},
+	}
+
+	breaches := []BudgetBreach{}
+	for _, limit := range limits {
+		if limit.max > 0 && limit.value > limit.max {
+			breaches = append(breaches, BudgetBreach{
+				Budget:     b.Name,
+				Scope:      b.Scope.String(),
+				Limit:      limit.name,
+				Value:      limit.value,
+				Max:        limit.max,
+				ObservedAt: usage.EvaluatedAt,
+			})
+		}
+	}
+	return breaches
+}
+
+// AdmitNewSchemas splits the schema IDs new to a scope into the ones fitting within max and the ones
+// beyond it, given the number of schemas already counted. IDs are admitted in sorted order.
+func AdmitNewSchemas(newSchemaIDs []string, counted, max int) (admitted, rejected []string) {
+	sorted := append([]string(nil), newSchemaIDs...)
+	sort.Strings(sorted)
+	if max <= 0 {
+		return sorted, nil
+	}
+	room := max - counted
+	if room < 0 {
+		room = 0
+	}
+	if room >= len(sorted) {
+		return sorted, nil
+	}
+	return sorted[:room], sorted[room:]
+}
+
+// StartOfDay returns midnight UTC of the day of t, the start of the per day budgets
+func StartOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,125 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets([]byte(`
+budgets:
+  - name: checkout
+    scope:
+      entity: service.name=checkout
+    max_metric_schemas: 50
+    enforce: true
+  - name: payments-team
+    scope:
+      group: payments
+      entities: ["service.name=checkout", "service.name=payment, deployment.environment.name=prod"]
+    max_active_series: 10000
+  - name: tenant
+    max_log_schemas_per_day: 20
+`))
+	require.NoError(t, err)
+	require.Len(t, budgets.Budgets, 3)
+	assert.Equal(t, DefaultActiveSeriesWindow, budgets.ActiveSeriesWindow)
+	assert.Equal(t, DefaultBudgetEvaluationInterval, budgets.EvaluationInterval)
+
+	assert.Equal(t, BudgetScopeEntity, budgets.Budgets[0].Scope.Kind())
+	assert.Equal(t, "entity service.name=checkout", budgets.Budgets[0].Scope.String())
+	assert.Equal(t, BudgetScopeGroup, budgets.Budgets[1].Scope.Kind())
+	assert.Equal(t, []EntitySelector{
+		{"service.name": "checkout"},
+		{"service.name": "payment", "deployment.environment.name": "prod"},
+	}, budgets.Budgets[1].Scope.Selectors())
+	assert.Equal(t, BudgetScopeTenant, budgets.Budgets[2].Scope.Kind())
+	assert.Empty(t, budgets.Budgets[2].Scope.Selectors())
+}
+
+func TestParseBudgets_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		budgets string
+		wantErr string
+	}{
+		{"missing name", "budgets:\n  - max_metric_schemas: 1\n", "budget name cannot be empty"},
+		{"duplicate", "budgets:\n  - {name: a, max_metric_schemas: 1}\n  - {name: a, max_metric_schemas: 1}\n", "defined more than once"},
+		{"no limit", "budgets:\n  - name: a\n", "must define at least one limit"},
+		{"negative limit", "budgets:\n  - {name: a, max_active_series: -1}\n", "cannot be negative"},
+		{"bad selector", "budgets:\n  - {name: a, max_active_series: 1, scope: {entity: checkout}}\n", "invalid selector"},
+		{"group without entities", "budgets:\n  - {name: a, max_active_series: 1, scope: {group: payments}}\n", "must list its entities"},
+		{"entity and group", "budgets:\n  - {name: a, max_active_series: 1, scope: {entity: a=b, group: g}}\n", "cannot also define a group"},
+		{"negative window", "active_series_window: -1h\nbudgets: []\n", "active series window cannot be negative"},
+		{"negative interval", "evaluation_interval: -1s\nbudgets: []\n", "evaluation interval cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseBudgets([]byte(tt.budgets))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestBudgetScope_Covers(t *testing.T) {
+	checkout := &Telemetry{Entities: map[string]*Entity{
+		"e1": {ID: "e1", Type: "service", Attributes: map[string]interface{}{"service.name": "checkout", "deployment.environment.name": "prod"}},
+	}}
+	unknown := &Telemetry{}
+
+	budgets, err := ParseBudgets([]byte(`
+budgets:
+  - {name: entity, max_metric_schemas: 1, scope: {entity: "service.name=checkout"}}
+  - {name: staging, max_metric_schemas: 1, scope: {entity: "service.name=checkout,deployment.environment.name=staging"}}
+  - {name: group, max_metric_schemas: 1, scope: {group: team, entities: ["service.name=cart", "service.name=checkout"]}}
+  - {name: tenant, max_metric_schemas: 1}
+`))
+	require.NoError(t, err)
+
+	assert.True(t, budgets.Budgets[0].Scope.Covers(checkout))
+	assert.False(t, budgets.Budgets[0].Scope.Covers(unknown))
+	assert.False(t, budgets.Budgets[1].Scope.Covers(checkout))
+	assert.True(t, budgets.Budgets[2].Scope.Covers(checkout))
+	assert.True(t, budgets.Budgets[3].Scope.Covers(unknown))
+}
+
+func TestBudget_Check(t *testing.T) {
+	now := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	budget := Budget{Name: "checkout", MaxMetricSchemas: 2, MaxActiveSeries: 100}
+
+	breaches := budget.Check(BudgetUsage{MetricSchemas: 2, ActiveSeries: 101, LogSchemasToday: 500, EvaluatedAt: now})
+	require.Len(t, breaches, 1)
+	assert.Equal(t, BudgetBreach{
+		Budget:     "checkout",
+		Scope:      "tenant",
+		Limit:      BudgetLimitActiveSeries,
+		Value:      101,
+		Max:        100,
+		ObservedAt: now,
+	}, breaches[0])
+
+	assert.Empty(t, budget.Check(BudgetUsage{MetricSchemas: 1, ActiveSeries: 10}))
+}
+
+func TestAdmitNewSchemas(t *testing.T) {
+	admitted, rejected := AdmitNewSchemas([]string{"c", "a", "b"}, 1, 3)
+	assert.Equal(t, []string{"a", "b"}, admitted)
+	assert.Equal(t, []string{"c"}, rejected)
+
+	admitted, rejected = AdmitNewSchemas([]string{"a"}, 5, 3)
+	assert.Empty(t, admitted)
+	assert.Equal(t, []string{"a"}, rejected)
+
+	admitted, rejected = AdmitNewSchemas([]string{"a"}, 5, 0)
+	assert.Equal(t, []string{"a"}, admitted)
+	assert.Empty(t, rejected)
+}
+
+func TestStartOfDay(t *testing.T) {
+	at := time.Date(2025, 6, 1, 22, 30, 0, 0, time.FixedZone("CEST", 2*3600))
+	assert.Equal(t, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), StartOfDay(at))
+}
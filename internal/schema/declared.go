@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"sort"
+	"time"
+)
+
+// DeclaredTelemetry is a telemetry registered ahead of being emitted, imported from a Weaver registry
+type DeclaredTelemetry struct {
+	// Registry names the import the declaration belongs to, importing a registry again replaces it
+	Registry      string        `json:"registry"`
+	GroupID       string        `json:"groupId"`
+	SchemaKey     string        `json:"schemaKey"`
+	TelemetryType TelemetryType `json:"telemetryType"`
+	Brief         string        `json:"brief,omitempty"`
+	Note          string        `json:"note,omitempty"`
+	Stability     Stability     `json:"stability,omitempty"`
+	MetricUnit    string        `json:"metricUnit,omitempty"`
+	MetricType    MetricType    `json:"metricType,omitempty"`
+	SpanKind      SpanKind      `json:"spanKind,omitempty"`
+	Attributes    []Attribute   `json:"attributes"`
+	// EntityType and ScopeName are the entity type and instrumentation scope expected to emit the
+	// telemetry. Coverage reports only compare the declarations of their entity type or scope.
+	EntityType string    `json:"entityType,omitempty"`
+	ScopeName  string    `json:"scopeName,omitempty"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// CoverageStatus classifies a schema key by whether it was declared, observed or both
+type CoverageStatus string
+
+const (
+	CoverageStatusDeclaredAndObserved   CoverageStatus = "declared_and_observed"
+	CoverageStatusDeclaredButMissing    CoverageStatus = "declared_but_missing"
+	CoverageStatusObservedButUndeclared CoverageStatus = "observed_but_undeclared"
+)
+
+// AttributeMismatchKind is how an observed attribute differs from its declaration
+type AttributeMismatchKind string
+
+const (
+	// AttributeMismatchMissing is a declared attribute never observed
+	AttributeMismatchMissing AttributeMismatchKind = "missing"
+	// AttributeMismatchUndeclared is an observed attribute the declaration does not list
+	AttributeMismatchUndeclared AttributeMismatchKind = "undeclared"
+	// AttributeMismatchType is an attribute observed with another type than the declared one
+	AttributeMismatchType AttributeMismatchKind = "type"
+)
+
+// AttributeMismatch is an attribute of a declared and observed telemetry that differs between both
+type AttributeMismatch struct {
+	Attribute        string                `json:"attribute"`
+	Kind             AttributeMismatchKind `json:"kind"`
+	DeclaredType     AttributeType         `json:"declaredType,omitempty"`
+	ObservedType     AttributeType         `json:"observedType,omitempty"`
+	RequirementLevel RequirementLevel      `json:"requirementLevel,omitempty"`
+}
+
+// CoverageItem is the coverage of a schema key
+type CoverageItem struct {
+	SchemaKey     string              `json:"schemaKey"`
+	TelemetryType TelemetryType       `json:"telemetryType"`
+	Status        CoverageStatus      `json:"status"`
+	Registry      string              `json:"registry,omitempty"`
+	Mismatches    []AttributeMismatch `json:"mismatches"`
+}
+
+// CoverageReport compares the telemetry declared for an entity type or a scope with the observed one
+type CoverageReport struct {
+	DeclaredAndObserved   int            `json:"declaredAndObserved"`
+	DeclaredButMissing    int            `json:"declaredButMissing"`
+	ObservedButUndeclared int            `json:"observedButUndeclared"`
+	Items                 []CoverageItem `json:"items"`
+}
+
+// signalAttributeSources are the attribute sources of the signal itself. Resource and scope attributes
+// are not reported as undeclared, as registries usually leave them to the entity and scope definitions.
+var signalAttributeSources = map[AttributeSource]bool{
+	AttributeSourceDataPoint: true,
+	AttributeSourceLogRecord: true,
+	AttributeSourceSpan:      true,
+	AttributeSourceProfile:   true,
+}
+
+// ComputeCoverage matches declared and observed telemetry by schema key and signal type, and lists
+// the attribute mismatches of the ones both declared and observed. Items are sorted by schema key.
+func ComputeCoverage(declared []DeclaredTelemetry, observed []Telemetry) *CoverageReport {
+	type coverageKey struct {
+		schemaKey     string
+		telemetryType TelemetryType
+	}
+
+	observedByKey := make(map[coverageKey]*Telemetry, len(observed))
+	for i := range observed {
+		observedByKey[coverageKey{observed[i].SchemaKey, observed[i].TelemetryType}] = &observed[i]
+	}
+
+	report := &CoverageReport{Items: []CoverageItem{}}
+	declaredKeys := make(map[coverageKey]bool, len(declared))
+	for _, d := range declared {
+		key := coverageKey{d.SchemaKey, d.TelemetryType}
+		if declaredKeys[key] {
+			continue
+		}
+		declaredKeys[key] = true
+
+		item := CoverageItem{SchemaKey: d.SchemaKey, TelemetryType: d.TelemetryType, Registry: d.Registry, Mismatches: []AttributeMismatch{}}
+		if t, ok := observedByKey[key]; ok {
+			item.Status = CoverageStatusDeclaredAndObserved
+			item.Mismatches = compareAttributes(d.Attributes, t.Attributes)
+			report.DeclaredAndObserved++
+		} else {
+			item.Status = CoverageStatusDeclaredButMissing
+			report.DeclaredButMissing++
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	for _, t := range observed {
+		if declaredKeys[coverageKey{t.SchemaKey, t.TelemetryType}] {
+			continue
+		}
+		report.Items = append(report.Items, CoverageItem{
+			SchemaKey:     t.SchemaKey,
+			TelemetryType: t.TelemetryType,
+			Status:        CoverageStatusObservedButUndeclared,
+			Mismatches:    []AttributeMismatch{},
+		})
+		report.ObservedButUndeclared++
+	}
+
+	sort.Slice(report.Items, func(i, j int) bool {
+		if report.Items[i].SchemaKey != report.Items[j].SchemaKey {
+			return report.Items[i].SchemaKey < report.Items[j].SchemaKey
+		}
+		return report.Items[i].TelemetryType < report.Items[j].TelemetryType
+	})
+	return report
+}
+
+func compareAttributes(declared, observed []Attribute) []AttributeMismatch {
+	observedByName := make(map[string]Attribute, len(observed))
+	for _, attr := range observed {
+		observedByName[attr.Name] = attr
+	}
+	declaredByName := make(map[string]bool, len(declared))
+
+	mismatches := []AttributeMismatch{}
+	for _, d := range declared {
+		declaredByName[d.Name] = true
+		o, ok := observedByName[d.Name]
+		switch {
+		case !ok:
+			mismatches = append(mismatches, AttributeMismatch{Attribute: d.Name, Kind: AttributeMismatchMissing, DeclaredType: d.Type, RequirementLevel: d.RequirementLevel})
+		case !attributeTypesMatch(d.Type, o.Type):
+			mismatches = append(mismatches, AttributeMismatch{Attribute: d.Name, Kind: AttributeMismatchType, DeclaredType: d.Type, ObservedType: o.Type, RequirementLevel: d.RequirementLevel})
+		}
+	}
+	for _, o := range observed {
+		if !declaredByName[o.Name] && signalAttributeSources[o.Source] {
+			declaredByName[o.Name] = true
+			mismatches = append(mismatches, AttributeMismatch{Attribute: o.Name, Kind: AttributeMismatchUndeclared, ObservedType: o.Type})
+		}
+	}
+
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Attribute < mismatches[j].Attribute })
+	return mismatches
+}
+
+// attributeTypesMatch compares a declared type with an observed one. Weaver declares maps and bytes
+// as strings, so these match a declared string.
+func attributeTypesMatch(declared, observed AttributeType) bool {
+	if declared == observed || declared == "" {
+		return true
+	}
+	if declared == AttributeTypeStr {
+		switch observed {
+		case AttributeTypeMap, AttributeTypeBytes, AttributeTypeEmpty:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeCoverage(t *testing.T) {
+	declared := []DeclaredTelemetry{
+		{
+			Registry:      "checkout",
+			SchemaKey:     "checkout.requests",
+			TelemetryType: TelemetryTypeMetric,
+			Attributes: []Attribute{
+				{Name: "http.method", Type: AttributeTypeStr, RequirementLevel: RequirementLevelRequired},
+				{Name: "http.status_code", Type: AttributeTypeInt},
+				{Name: "payment.method", Type: AttributeTypeStr, RequirementLevel: RequirementLevelOptIn},
+				{Name: "http.request.header", Type: AttributeTypeStr},
+			},
+		},
+		{Registry: "checkout", SchemaKey: "checkout.refunds", TelemetryType: TelemetryTypeMetric},
+	}
+	observed := []Telemetry{
+		{
+			SchemaKey:     "checkout.requests",
+			TelemetryType: TelemetryTypeMetric,
+			Attributes: []Attribute{
+				{Name: "http.method", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+				{Name: "http.status_code", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+				{Name: "http.request.header", Type: AttributeTypeMap, Source: AttributeSourceDataPoint},
+				{Name: "http.route", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+				{Name: "service.name", Type: AttributeTypeStr, Source: AttributeSourceResource},
+			},
+		},
+		{SchemaKey: "checkout.queue.size", TelemetryType: TelemetryTypeMetric},
+	}
+
+	report := ComputeCoverage(declared, observed)
+
+	require.Equal(t, 1, report.DeclaredAndObserved)
+	require.Equal(t, 1, report.DeclaredButMissing)
+	require.Equal(t, 1, report.ObservedButUndeclared)
+	require.Equal(t, []CoverageItem{
+		{SchemaKey: "checkout.queue.size", TelemetryType: TelemetryTypeMetric, Status: CoverageStatusObservedButUndeclared, Mismatches: []AttributeMismatch{}},
+		{SchemaKey: "checkout.refunds", TelemetryType: TelemetryTypeMetric, Status: CoverageStatusDeclaredButMissing, Registry: "checkout", Mismatches: []AttributeMismatch{}},
+		{
+			SchemaKey:     "checkout.requests",
+			TelemetryType: TelemetryTypeMetric,
+			Status:        CoverageStatusDeclaredAndObserved,
+			Registry:      "checkout",
+			Mismatches: []AttributeMismatch{
+				{Attribute: "http.route", Kind: AttributeMismatchUndeclared, ObservedType: AttributeTypeStr},
+				{Attribute: "http.status_code", Kind: AttributeMismatchType, DeclaredType: AttributeTypeInt, ObservedType: AttributeTypeStr},
+				{Attribute: "payment.method", Kind: AttributeMismatchMissing, DeclaredType: AttributeTypeStr, RequirementLevel: RequirementLevelOptIn},
+			},
+		},
+	}, report.Items)
+}
@@ -0,0 +1,300 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// DependencyNode is a service, or a remote peer known only by its address, in the dependency graph.
+// Nodes are keyed on the ID of their service entity, so services with the same name in different
+// namespaces are different nodes.
+type DependencyNode struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	EntityID  string    `json:"entityId,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// DependencyEdge is a call from one node to another, keyed by protocol and the schema key of the calling span
+type DependencyEdge struct {
+	CallerID  string    `json:"callerId"`
+	CalleeID  string    `json:"calleeId"`
+	Protocol  string    `json:"protocol"`
+	SchemaKey string    `json:"schemaKey"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	SeenCount int64     `json:"seenCount"`
+}
+
+type DependencyGraph struct {
+	Nodes []DependencyNode `json:"nodes"`
+	Edges []DependencyEdge `json:"edges"`
+}
+
+type spanOrigin struct {
+	node      string
+	label     string
+	namespace string
+	entityID  string
+	name      string
+	protocol  string
+	clientish bool
+}
+
+// serviceNode returns the node ID and name of a service: the ID the model gives its service
+// entity, or its name when the model has no service entity
+func serviceNode(model *EntityModel, name, namespace string) (string, string) {
+	label := name
+	if namespace != "" {
+		label = namespace + "/" + name
+	}
+	if _, ok := model.Definition("service"); !ok {
+		return label, label
+	}
+	attributes := map[string]interface{}{"service.name": name}
+	if namespace != "" {
+		attributes["service.namespace"] = namespace
+	}
+	return model.EntityID("service", attributes), label
+}
+
+// ExtractDependencies builds the service dependency graph observed in a batch of traces.
+// Edges come from CLIENT and PRODUCER spans naming their peer through peer.service or
+// server.address, and from parent/child spans emitted by different services within the batch.
+// A call whose both spans are in the batch is counted once, through the service of its child span.
+// Nodes are keyed on the service entities the model detects. Peers carry a name only, so they are
+// taken for services in the namespace of their caller.
+func ExtractDependencies(traces ptrace.Traces, model *EntityModel) DependencyGraph {
+	now := time.Now()
+
+	type spanKey struct {
+		traceID pcommon.TraceID
+		spanID  pcommon.SpanID
+	}
+	origins := make(map[spanKey]spanOrigin)
+
+	forEachSpan := func(fn func(origin spanOrigin, span ptrace.Span)) {
+		for i := range traces.ResourceSpans().Len() {
+			resourceSpan := traces.ResourceSpans().At(i)
+			resourceAttributes := resourceSpan.Resource().Attributes()
+
+			serviceName, ok := resourceAttributes.Get("service.name")
+			if !ok || serviceName.AsString() == "" {
+				continue
+			}
+
+			namespace := ""
+			if value, ok := resourceAttributes.Get("service.namespace"); ok {
+				namespace = value.AsString()
+			}
+			node, label := serviceNode(model, serviceName.AsString(), namespace)
+			entityID := ""
+			for _, entity := range model.Detect(resourceAttributes) {
+				if entity.Type == "service" {
+					entityID = entity.ID
+				}
+			}
+
+			for k := range resourceSpan.ScopeSpans().Len() {
+				spans := resourceSpan.ScopeSpans().At(k).Spans()
+				for l := range spans.Len() {
+					span := spans.At(l)
+					fn(spanOrigin{
+						node:      node,
+						label:     label,
+						namespace: namespace,
+						entityID:  entityID,
+						name:      span.Name(),
+						protocol:  spanProtocol(span.Attributes()),
+						clientish: span.Kind() == ptrace.SpanKindClient || span.Kind() == ptrace.SpanKindProducer,
+					}, span)
+				}
+			}
+		}
+	}
+
+	forEachSpan(func(origin spanOrigin, span ptrace.Span) {
+		origins[spanKey{span.TraceID(), span.SpanID()}] = origin
+	})
+
+	// Spans with a child emitted by another service, whose edge is resolved from the child
+	resolved := make(map[spanKey]bool)
+	forEachSpan(func(origin spanOrigin, span ptrace.Span) {
+		parentKey := spanKey{span.TraceID(), span.ParentSpanID()}
+		if parent, ok := origins[parentKey]; ok && !span.ParentSpanID().IsEmpty() && parent.node != origin.node {
+			resolved[parentKey] = true
+		}
+	})
+
+	nodes := make(map[string]*DependencyNode)
+	edges := make(map[string]*DependencyEdge)
+
+	addNode := func(id, name, entityID string) {
+		node, ok := nodes[id]
+		if !ok {
+			node = &DependencyNode{ID: id, Name: name, FirstSeen: now, LastSeen: now}
+			nodes[id] = node
+		}
+		if node.EntityID == "" {
+			node.EntityID = entityID
+		}
+	}
+	addEdge := func(caller, callee, protocol, schemaKey string) {
+		key := strings.Join([]string{caller, callee, protocol, schemaKey}, "|")
+		if edge, ok := edges[key]; ok {
+			edge.SeenCount++
+			return
+		}
+		edges[key] = &DependencyEdge{
+			CallerID:  caller,
+			CalleeID:  callee,
+			Protocol:  protocol,
+			SchemaKey: schemaKey,
+			FirstSeen: now,
+			LastSeen:  now,
+			SeenCount: 1,
+		}
+	}
+
+	forEachSpan(func(origin spanOrigin, span ptrace.Span) {
+		addNode(origin.node, origin.label, origin.entityID)
+
+		if origin.clientish && !resolved[spanKey{span.TraceID(), span.SpanID()}] {
+			if peer := spanPeer(span.Attributes()); peer != "" {
+				if peerNode, peerLabel := serviceNode(model, peer, origin.namespace); peerNode != origin.node {
+					addNode(peerNode, peerLabel, "")
+					addEdge(origin.node, peerNode, origin.protocol, origin.name)
+				}
+			}
+		}
+
+		if span.ParentSpanID().IsEmpty() {
+			return
+		}
+		parent, ok := origins[spanKey{span.TraceID(), span.ParentSpanID()}]
+		if !ok || parent.node == origin.node {
+			return
+		}
+		protocol := parent.protocol
+		if protocol == "" {
+			protocol = origin.protocol
+		}
+		addNode(parent.node, parent.label, parent.entityID)
+		addEdge(parent.node, origin.node, protocol, parent.name)
+	})
+
+	graph := DependencyGraph{
+		Nodes: make([]DependencyNode, 0, len(nodes)),
+		Edges: make([]DependencyEdge, 0, len(edges)),
+	}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	for _, edge := range edges {
+		graph.Edges = append(graph.Edges, *edge)
+	}
+	graph.Sort()
+
+	return graph
+}
+
+// spanPeer returns the remote service or address a span talks to
+func spanPeer(attributes pcommon.Map) string {
+	for _, key := range []string{"peer.service", "server.address", "net.peer.name"} {
+		if value, ok := attributes.Get(key); ok && value.AsString() != "" {
+			return value.AsString()
+		}
+	}
+	return ""
+}
+
+// spanProtocol derives the protocol of a span from its semantic convention attributes
+func spanProtocol(attributes pcommon.Map) string {
+	for _, key := range []string{"rpc.system", "messaging.system", "db.system.name", "db.system"} {
+		if value, ok := attributes.Get(key); ok && value.AsString() != "" {
+			return value.AsString()
+		}
+	}
+	for _, key := range []string{"http.request.method", "http.method"} {
+		if _, ok := attributes.Get(key); ok {
+			return "http"
+		}
+	}
+	return ""
+}
+
+// Sort orders nodes by ID and edges by caller, callee, protocol and schema key
+func (g *DependencyGraph) Sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.CallerID != b.CallerID {
+			return a.CallerID < b.CallerID
+		}
+		if a.CalleeID != b.CalleeID {
+			return a.CalleeID < b.CalleeID
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.SchemaKey < b.SchemaKey
+	})
+}
+
+// edgeLabel describes an edge by its protocol and span schema key
+func edgeLabel(edge DependencyEdge) string {
+	if edge.Protocol == "" {
+		return edge.SchemaKey
+	}
+	return edge.Protocol + ": " + edge.SchemaKey
+}
+
+// DOT renders the graph in the Graphviz DOT language
+func (g DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %q [label=%q];\n", node.ID, node.Name)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.CallerID, edge.CalleeID, edgeLabel(edge))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart
+func (g DependencyGraph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	nodeID := func(id string) string {
+		if mermaidID, ok := ids[id]; ok {
+			return mermaidID
+		}
+		mermaidID := fmt.Sprintf("n%d", len(ids))
+		ids[id] = mermaidID
+		return mermaidID
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodeID(node.ID), mermaidEscape(node.Name))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", nodeID(edge.CallerID), mermaidEscape(edgeLabel(edge)), nodeID(edge.CalleeID))
+	}
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
@@ -0,0 +1,187 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func appendServiceSpan(traces ptrace.Traces, service string) ptrace.Span {
+	resourceSpan := traces.ResourceSpans().AppendEmpty()
+	resourceSpan.Resource().Attributes().PutStr("service.name", service)
+	return resourceSpan.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+}
+
+// serviceNodeID returns the node ID of a service in the default entity model
+func serviceNodeID(name, namespace string) string {
+	id, _ := serviceNode(DefaultEntityModel(), name, namespace)
+	return id
+}
+
+// nodeByName returns the node of the graph with the given name
+func nodeByName(t *testing.T, graph DependencyGraph, name string) DependencyNode {
+	for _, node := range graph.Nodes {
+		if node.Name == name {
+			return node
+		}
+	}
+	require.Failf(t, "node not found", "no node named %s", name)
+	return DependencyNode{}
+}
+
+func TestExtractDependencies(t *testing.T) {
+	traces := ptrace.NewTraces()
+	traceID := pcommon.TraceID([16]byte{1})
+
+	// checkout calls payments over gRPC, payments' server span is in the same batch
+	client := appendServiceSpan(traces, "checkout")
+	client.SetName("payments.Charge")
+	client.SetKind(ptrace.SpanKindClient)
+	client.SetTraceID(traceID)
+	client.SetSpanID(pcommon.SpanID([8]byte{1}))
+	client.Attributes().PutStr("rpc.system", "grpc")
+	client.Attributes().PutStr("peer.service", "payments")
+
+	server := appendServiceSpan(traces, "payments")
+	server.SetName("payments.Charge")
+	server.SetKind(ptrace.SpanKindServer)
+	server.SetTraceID(traceID)
+	server.SetSpanID(pcommon.SpanID([8]byte{2}))
+	server.SetParentSpanID(pcommon.SpanID([8]byte{1}))
+
+	// payments publishes to kafka, known only by its address
+	producer := appendServiceSpan(traces, "payments")
+	producer.SetName("orders publish")
+	producer.SetKind(ptrace.SpanKindProducer)
+	producer.SetTraceID(traceID)
+	producer.SetSpanID(pcommon.SpanID([8]byte{3}))
+	producer.SetParentSpanID(pcommon.SpanID([8]byte{2}))
+	producer.Attributes().PutStr("messaging.system", "kafka")
+	producer.Attributes().PutStr("server.address", "kafka:9092")
+
+	// Internal spans do not create edges
+	internal := appendServiceSpan(traces, "payments")
+	internal.SetName("validate")
+	internal.SetTraceID(traceID)
+	internal.SetSpanID(pcommon.SpanID([8]byte{4}))
+	internal.SetParentSpanID(pcommon.SpanID([8]byte{2}))
+
+	graph := ExtractDependencies(traces, DefaultEntityModel())
+
+	require.Len(t, graph.Nodes, 3)
+	checkout := nodeByName(t, graph, "checkout")
+	assert.Equal(t, serviceNodeID("checkout", ""), checkout.ID)
+	assert.Equal(t, checkout.ID, checkout.EntityID)
+	kafka := nodeByName(t, graph, "kafka:9092")
+	assert.Empty(t, kafka.EntityID)
+	payments := nodeByName(t, graph, "payments")
+	assert.Equal(t, serviceNodeID("payments", ""), payments.ID)
+
+	edges := make(map[string]DependencyEdge)
+	for _, edge := range graph.Edges {
+		edges[edge.CallerID+"->"+edge.CalleeID] = edge
+	}
+	require.Len(t, edges, 2)
+	charge := edges[checkout.ID+"->"+payments.ID]
+	assert.Equal(t, "grpc", charge.Protocol)
+	assert.Equal(t, "payments.Charge", charge.SchemaKey)
+	// The client span and the parent/child link describe the same call
+	assert.Equal(t, int64(1), charge.SeenCount)
+
+	publish := edges[payments.ID+"->"+kafka.ID]
+	assert.Equal(t, "kafka", publish.Protocol)
+}
+
+func TestExtractDependencies_KeysNodesOnServiceIdentity(t *testing.T) {
+	traces := ptrace.NewTraces()
+	appendCall := func(id byte, namespace string) {
+		traceID := pcommon.TraceID([16]byte{id})
+		resourceSpan := traces.ResourceSpans().AppendEmpty()
+		resourceSpan.Resource().Attributes().PutStr("service.name", "checkout")
+		resourceSpan.Resource().Attributes().PutStr("service.namespace", namespace)
+		client := resourceSpan.ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+		client.SetName("POST /charge")
+		client.SetKind(ptrace.SpanKindClient)
+		client.SetTraceID(traceID)
+		client.SetSpanID(pcommon.SpanID([8]byte{id}))
+		client.Attributes().PutStr("http.request.method", "POST")
+		client.Attributes().PutStr("peer.service", "payments")
+	}
+	// checkout runs in two namespaces, each calling the payments of its namespace
+	appendCall(1, "shop")
+	appendCall(2, "staging")
+
+	graph := ExtractDependencies(traces, DefaultEntityModel())
+
+	require.Len(t, graph.Nodes, 4)
+	shop := nodeByName(t, graph, "shop/checkout")
+	staging := nodeByName(t, graph, "staging/checkout")
+	assert.NotEqual(t, shop.ID, staging.ID)
+	assert.NotEqual(t, shop.EntityID, staging.EntityID)
+	assert.Equal(t, shop.ID, shop.EntityID)
+	assert.Equal(t, serviceNodeID("payments", "shop"), nodeByName(t, graph, "shop/payments").ID)
+
+	require.Len(t, graph.Edges, 2)
+	for _, edge := range graph.Edges {
+		assert.Equal(t, int64(1), edge.SeenCount)
+	}
+}
+
+func TestExtractDependencies_CountsCallsOnce(t *testing.T) {
+	traces := ptrace.NewTraces()
+	appendCall := func(id byte, withServer bool) {
+		traceID := pcommon.TraceID([16]byte{id})
+		client := appendServiceSpan(traces, "checkout")
+		client.SetName("POST /charge")
+		client.SetKind(ptrace.SpanKindClient)
+		client.SetTraceID(traceID)
+		client.SetSpanID(pcommon.SpanID([8]byte{id, 1}))
+		client.Attributes().PutStr("http.request.method", "POST")
+		client.Attributes().PutStr("server.address", "payments")
+		if !withServer {
+			return
+		}
+		server := appendServiceSpan(traces, "payments")
+		server.SetName("POST /charge")
+		server.SetKind(ptrace.SpanKindServer)
+		server.SetTraceID(traceID)
+		server.SetSpanID(pcommon.SpanID([8]byte{id, 2}))
+		server.SetParentSpanID(pcommon.SpanID([8]byte{id, 1}))
+	}
+	// Two calls with their client and server spans in the batch, one with its client span only
+	appendCall(1, true)
+	appendCall(2, true)
+	appendCall(3, false)
+
+	graph := ExtractDependencies(traces, DefaultEntityModel())
+
+	require.Len(t, graph.Edges, 1)
+	assert.Equal(t, serviceNodeID("checkout", ""), graph.Edges[0].CallerID)
+	assert.Equal(t, serviceNodeID("payments", ""), graph.Edges[0].CalleeID)
+	assert.Equal(t, "http", graph.Edges[0].Protocol)
+	assert.Equal(t, int64(3), graph.Edges[0].SeenCount)
+}
+
+func TestDependencyGraphRendering(t *testing.T) {
+	graph := DependencyGraph{
+		Nodes: []DependencyNode{{ID: "checkout", Name: "checkout"}, {ID: "payments", Name: "payments"}},
+		Edges: []DependencyEdge{{CallerID: "checkout", CalleeID: "payments", Protocol: "http", SchemaKey: "POST /charge"}},
+	}
+
+	assert.Equal(t, `digraph dependencies {
+  rankdir=LR;
+  "checkout" [label="checkout"];
+  "payments" [label="payments"];
+  "checkout" -> "payments" [label="http: POST /charge"];
+}
+`, graph.DOT())
+
+	assert.Equal(t, `flowchart LR
+  n0["checkout"]
+  n1["payments"]
+  n0 -->|"http: POST /charge"| n1
+`, graph.Mermaid())
+}
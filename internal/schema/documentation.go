@@ -0,0 +1,196 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Stability is the maturity of a telemetry or an attribute, as understood by Weaver
+type Stability string
+
+const (
+	StabilityStable      Stability = "stable"
+	StabilityDevelopment Stability = "development"
+	StabilityDeprecated  Stability = "deprecated"
+)
+
+// ParseStability parses a stability level case-insensitively
+func ParseStability(s string) (Stability, bool) {
+	for _, stability := range []Stability{StabilityStable, StabilityDevelopment, StabilityDeprecated} {
+		if strings.EqualFold(s, string(stability)) {
+			return stability, true
+		}
+	}
+	return "", false
+}
+
+// ParseRequirementLevel parses a requirement level case-insensitively, accepting the Weaver
+// spelling opt_in as well
+func ParseRequirementLevel(s string) (RequirementLevel, bool) {
+	s = strings.ReplaceAll(s, "_", "")
+	for _, level := range []RequirementLevel{RequirementLevelRequired, RequirementLevelRecommended, RequirementLevelOptIn} {
+		if strings.EqualFold(s, string(level)) {
+			return level, true
+		}
+	}
+	return "", false
+}
+
+// AttributeDocumentation is the curated documentation of an attribute of a telemetry
+type AttributeDocumentation struct {
+	Brief            string           `json:"brief,omitempty"`
+	RequirementLevel RequirementLevel `json:"requirementLevel,omitempty"`
+	Stability        Stability        `json:"stability,omitempty"`
+}
+
+// TelemetryDocumentation is the documentation curated by hand for a schema key. It is stored apart
+// from the inferred schemas so that ingesting the telemetry again never overwrites it.
+type TelemetryDocumentation struct {
+	SchemaKey string    `json:"schemaKey"`
+	Brief     string    `json:"brief,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	Stability Stability `json:"stability,omitempty"`
+	Tags      []string  `json:"tags"`
+	// Attributes maps attribute names to their documentation
+	Attributes map[string]AttributeDocumentation `json:"attributes"`
+	UpdatedAt  time.Time                         `json:"updatedAt"`
+}
+
+// AttributeDocumentationPatch updates the documentation of an attribute. Unset fields are left
+// unchanged and empty strings clear the curated value.
+type AttributeDocumentationPatch struct {
+	Brief            *string `json:"brief,omitempty"`
+	RequirementLevel *string `json:"requirementLevel,omitempty"`
+	Stability        *string `json:"stability,omitempty"`
+}
+
+// DocumentationPatch updates the documentation of a schema key. Unset fields are left unchanged,
+// empty strings clear the curated value and Tags replaces all the tags.
+type DocumentationPatch struct {
+	Brief      *string                                `json:"brief,omitempty"`
+	Note       *string                                `json:"note,omitempty"`
+	Stability  *string                                `json:"stability,omitempty"`
+	Tags       *[]string                              `json:"tags,omitempty"`
+	Attributes map[string]AttributeDocumentationPatch `json:"attributes,omitempty"`
+}
+
+// Validate checks the stability and requirement levels of the patch
+func (p DocumentationPatch) Validate() error {
+	if err := validateStability(p.Stability); err != nil {
+		return err
+	}
+	if p.Tags != nil {
+		for _, tag := range *p.Tags {
+			if strings.TrimSpace(tag) == "" {
+				return fmt.Errorf("tags cannot be empty")
+			}
+		}
+	}
+	for name, attr := range p.Attributes {
+		if name == "" {
+			return fmt.Errorf("attribute name cannot be empty")
+		}
+		if err := validateStability(attr.Stability); err != nil {
+			return fmt.Errorf("attribute %s: %w", name, err)
+		}
+		if attr.RequirementLevel != nil && *attr.RequirementLevel != "" {
+			if _, ok := ParseRequirementLevel(*attr.RequirementLevel); !ok {
+				return fmt.Errorf("attribute %s: invalid requirement level %q, expected required, recommended or opt_in", name, *attr.RequirementLevel)
+			}
+		}
+	}
+	return nil
+}
+
+func validateStability(stability *string) error {
+	if stability == nil || *stability == "" {
+		return nil
+	}
+	if _, ok := ParseStability(*stability); !ok {
+		return fmt.Errorf("invalid stability %q, expected stable, development or deprecated", *stability)
+	}
+	return nil
+}
+
+// Apply merges a validated patch into the documentation
+func (d *TelemetryDocumentation) Apply(p DocumentationPatch) {
+	if p.Brief != nil {
+		d.Brief = *p.Brief
+	}
+	if p.Note != nil {
+		d.Note = *p.Note
+	}
+	if p.Stability != nil {
+		d.Stability, _ = ParseStability(*p.Stability)
+	}
+	if p.Tags != nil {
+		d.Tags = make([]string, 0, len(*p.Tags))
+		for _, tag := range *p.Tags {
+			d.Tags = append(d.Tags, strings.TrimSpace(tag))
+		}
+	}
+	for name, patch := range p.Attributes {
+		if d.Attributes == nil {
+			d.Attributes = make(map[string]AttributeDocumentation)
+		}
+		attr := d.Attributes[name]
+		if patch.Brief != nil {
+			attr.Brief = *patch.Brief
+		}
+		if patch.RequirementLevel != nil {
+			attr.RequirementLevel, _ = ParseRequirementLevel(*patch.RequirementLevel)
+		}
+		if patch.Stability != nil {
+			attr.Stability, _ = ParseStability(*patch.Stability)
+		}
+		if attr == (AttributeDocumentation{}) {
+			delete(d.Attributes, name)
+			continue
+		}
+		d.Attributes[name] = attr
+	}
+}
+
+// Document overrides the inferred brief, note, stability, tags and attribute documentation of a
+// telemetry with the curated values
+func (d *TelemetryDocumentation) Document(t *Telemetry) {
+	if d == nil {
+		return
+	}
+	if d.Brief != "" {
+		t.Brief = d.Brief
+	}
+	if d.Note != "" {
+		t.Note = d.Note
+	}
+	if d.Stability != "" {
+		t.Stability = d.Stability
+	}
+	if len(d.Tags) > 0 {
+		t.Tags = d.Tags
+	}
+	d.DocumentAttributes(t.Attributes)
+}
+
+// DocumentAttributes overrides the inferred documentation of attributes with the curated values
+func (d *TelemetryDocumentation) DocumentAttributes(attributes []Attribute) {
+	if d == nil {
+		return
+	}
+	for i := range attributes {
+		doc, ok := d.Attributes[attributes[i].Name]
+		if !ok {
+			continue
+		}
+		if doc.Brief != "" {
+			attributes[i].Brief = doc.Brief
+		}
+		if doc.RequirementLevel != "" {
+			attributes[i].RequirementLevel = doc.RequirementLevel
+		}
+		if doc.Stability != "" {
+			attributes[i].Stability = doc.Stability
+		}
+	}
+}
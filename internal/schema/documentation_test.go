@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentationPatch_Validate(t *testing.T) {
+	valid, invalid := "opt_in", "sometimes"
+
+	require.NoError(t, DocumentationPatch{
+		Attributes: map[string]AttributeDocumentationPatch{"http.method": {RequirementLevel: &valid}},
+	}.Validate())
+
+	require.ErrorContains(t, DocumentationPatch{Stability: &invalid}.Validate(), "invalid stability")
+	require.ErrorContains(t, DocumentationPatch{
+		Attributes: map[string]AttributeDocumentationPatch{"http.method": {RequirementLevel: &invalid}},
+	}.Validate(), "invalid requirement level")
+
+	tags := []string{"checkout", " "}
+	require.ErrorContains(t, DocumentationPatch{Tags: &tags}.Validate(), "tags cannot be empty")
+}
+
+func TestTelemetryDocumentation_Document(t *testing.T) {
+	doc := &TelemetryDocumentation{}
+	brief, note, level := "Requests handled by checkout", "Curated note", "Required"
+	doc.Apply(DocumentationPatch{
+		Brief: &brief,
+		Note:  &note,
+		Attributes: map[string]AttributeDocumentationPatch{
+			"http.method": {RequirementLevel: &level},
+		},
+	})
+
+	telemetry := &Telemetry{
+		Brief: "inferred",
+		Attributes: []Attribute{
+			{Name: "http.method", RequirementLevel: RequirementLevelRecommended},
+			{Name: "http.route", Brief: "inferred route"},
+		},
+	}
+	doc.Document(telemetry)
+
+	require.Equal(t, brief, telemetry.Brief)
+	require.Equal(t, note, telemetry.Note)
+	require.Equal(t, RequirementLevelRequired, telemetry.Attributes[0].RequirementLevel)
+	require.Equal(t, "inferred route", telemetry.Attributes[1].Brief)
+
+	// Telemetries without curated documentation keep the inferred one
+	var none *TelemetryDocumentation
+	none.Document(telemetry)
+	require.Equal(t, brief, telemetry.Brief)
+}
@@ -17,18 +17,20 @@ type Entity struct {
 	Attributes map[string]interface{} `json:"attributes"`
 	FirstSeen  time.Time              `json:"firstSeen"`
 	LastSeen   time.Time              `json:"lastSeen"`
+	// Link describes the entity's activity for the schema it was loaded for, if any
+	Link *SchemaLink `json:"link,omitempty"`
+	// Owners are the teams owning the entity
+	Owners []Owner `json:"owners,omitempty"`
 }
 
-// EntityID generates a unique ID for an entity using deterministic hashing
-func (e *Entity) EntityID() string {
-	if e.ID != "" {
-		return e.ID
-	}
-	e.ID = GenerateEntityID(e.Type, e.Attributes)
-	return e.ID
+// SchemaLink describes when an entity or scope was first and last seen emitting a schema
+type SchemaLink struct {
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	SeenCount int64     `json:"seenCount"`
 }
 
-// GenerateEntityID creates a deterministic entity ID from type and attributes
+// GenerateEntityID creates a deterministic entity ID from type and identifying attributes
 func GenerateEntityID(entityType string, attributes map[string]interface{}) string {
 	// Sort attribute keys for consistent ordering
 	keys := make([]string, 0, len(attributes))
@@ -54,40 +56,6 @@ func GenerateEntityID(entityType string, attributes map[string]interface{}) stri
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
-// DetectEntities extracts multiple entities from resource attributes
-func DetectEntities(resourceAttributes pcommon.Map) []Entity {
-	entityGroups := make(map[string]map[string]interface{})
-	now := time.Now()
-
-	// Group attributes by entity type prefix
-	resourceAttributes.Range(func(key string, value pcommon.Value) bool {
-		parts := strings.Split(key, ".")
-		if len(parts) > 0 {
-			entityType := parts[0]
-			if entityGroups[entityType] == nil {
-				entityGroups[entityType] = make(map[string]interface{})
-			}
-			entityGroups[entityType][key] = convertPCommonValue(value)
-		}
-		return true
-	})
-
-	// Create an entity for each type found
-	var entities []Entity
-	for entityType, entityAttrs := range entityGroups {
-		entity := Entity{
-			Type:       entityType,
-			Attributes: entityAttrs,
-			FirstSeen:  now,
-			LastSeen:   now,
-		}
-		entity.ID = entity.EntityID()
-		entities = append(entities, entity)
-	}
-
-	return entities
-}
-
 // convertPCommonValue converts pcommon.Value to interface{} for storage
 func convertPCommonValue(value pcommon.Value) interface{} {
 	switch value.Type() {
@@ -141,7 +109,7 @@ func MergeEntity(existing, new Entity) Entity {
 	return merged
 }
 
-// GetEntityAttributesByType filters entity attributes by their prefix type
+// GetEntityAttributesByType returns the attributes of the first entity with the given type
 func GetEntityAttributesByType(entities []Entity, entityType string) map[string]interface{} {
 	for _, entity := range entities {
 		if entity.Type == entityType {
@@ -151,21 +119,13 @@ func GetEntityAttributesByType(entities []Entity, entityType string) map[string]
 	return nil
 }
 
-// EntityTypesFromAttributes returns all unique entity types found in attributes
-func EntityTypesFromAttributes(resourceAttributes pcommon.Map) []string {
-	entityTypes := make(map[string]bool)
+// EntityTypesFromAttributes returns all unique entity types the model detects in attributes
+func EntityTypesFromAttributes(model *EntityModel, resourceAttributes pcommon.Map) []string {
+	entities := model.Detect(resourceAttributes)
 
-	resourceAttributes.Range(func(key string, value pcommon.Value) bool {
-		parts := strings.Split(key, ".")
-		if len(parts) > 0 {
-			entityTypes[parts[0]] = true
-		}
-		return true
-	})
-
-	result := make([]string, 0, len(entityTypes))
-	for entityType := range entityTypes {
-		result = append(result, entityType)
+	result := make([]string, 0, len(entities))
+	for _, entity := range entities {
+		result = append(result, entity.Type)
 	}
 	sort.Strings(result) // For consistent ordering
 	return result
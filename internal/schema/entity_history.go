@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"sort"
+	"time"
+)
+
+// EntityAttributeChange records a change of an attribute value on a stable entity
+type EntityAttributeChange struct {
+	ID        int       `json:"id"`
+	EntityID  string    `json:"entityId"`
+	Name      string    `json:"name"`
+	OldValue  *string   `json:"oldValue,omitempty"`
+	NewValue  string    `json:"newValue"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+type SchemaChangeType string
+
+const (
+	SchemaChangeAdded   SchemaChangeType = "added"
+	SchemaChangeRemoved SchemaChangeType = "removed"
+)
+
+// EntitySchemaChange records a schema appearing on or disappearing from an entity.
+// A schema disappears when the entity starts emitting a different schema for the same key.
+type EntitySchemaChange struct {
+	ID               int                    `json:"id"`
+	EntityID         string                 `json:"entityId"`
+	SchemaID         string                 `json:"schemaId"`
+	SchemaKey        string                 `json:"schemaKey"`
+	Change           SchemaChangeType       `json:"change"`
+	EntityAttributes map[string]interface{} `json:"entityAttributes"`
+	ObservedAt       time.Time              `json:"observedAt"`
+}
+
+// EntityRollout groups the attribute changes of an entity that happened at the same
+// time (e.g. a release) with the schema changes observed until the next rollout
+type EntityRollout struct {
+	StartedAt        time.Time               `json:"startedAt"`
+	EndedAt          *time.Time              `json:"endedAt,omitempty"`
+	AttributeChanges []EntityAttributeChange `json:"attributeChanges"`
+	SchemaChanges    []EntitySchemaChange    `json:"schemaChanges"`
+}
+
+// CorrelateRollouts groups attribute changes into rollouts and assigns every schema change
+// to the rollout that was active when it was observed. Schema changes observed before the
+// first attribute change belong to an initial rollout without attribute changes.
+func CorrelateRollouts(attributeChanges []EntityAttributeChange, schemaChanges []EntitySchemaChange) []EntityRollout {
+	attributeChanges = append([]EntityAttributeChange(nil), attributeChanges...)
+	sort.SliceStable(attributeChanges, func(i, j int) bool {
+		return attributeChanges[i].ChangedAt.Before(attributeChanges[j].ChangedAt)
+	})
+	schemaChanges = append([]EntitySchemaChange(nil), schemaChanges...)
+	sort.SliceStable(schemaChanges, func(i, j int) bool {
+		return schemaChanges[i].ObservedAt.Before(schemaChanges[j].ObservedAt)
+	})
+
+	var rollouts []EntityRollout
+	for _, change := range attributeChanges {
+		if n := len(rollouts); n > 0 && rollouts[n-1].StartedAt.Equal(change.ChangedAt) {
+			rollouts[n-1].AttributeChanges = append(rollouts[n-1].AttributeChanges, change)
+			continue
+		}
+		rollouts = append(rollouts, EntityRollout{
+			StartedAt:        change.ChangedAt,
+			AttributeChanges: []EntityAttributeChange{change},
+			SchemaChanges:    []EntitySchemaChange{},
+		})
+	}
+
+	for i := range rollouts {
+		if i+1 < len(rollouts) {
+			endedAt := rollouts[i+1].StartedAt
+			rollouts[i].EndedAt = &endedAt
+		}
+	}
+
+	var initial *EntityRollout
+	for _, change := range schemaChanges {
+		idx := sort.Search(len(rollouts), func(i int) bool {
+			return rollouts[i].StartedAt.After(change.ObservedAt)
+		}) - 1
+		if idx < 0 {
+			if initial == nil {
+				initial = &EntityRollout{
+					StartedAt:        change.ObservedAt,
+					AttributeChanges: []EntityAttributeChange{},
+				}
+			}
+			initial.SchemaChanges = append(initial.SchemaChanges, change)
+			continue
+		}
+		rollouts[idx].SchemaChanges = append(rollouts[idx].SchemaChanges, change)
+	}
+
+	if initial != nil {
+		if len(rollouts) > 0 {
+			endedAt := rollouts[0].StartedAt
+			initial.EndedAt = &endedAt
+		}
+		rollouts = append([]EntityRollout{*initial}, rollouts...)
+	}
+
+	return rollouts
+}
@@ -0,0 +1,52 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrelateRollouts(t *testing.T) {
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	oldVersion := "1.0.0"
+
+	attributeChanges := []EntityAttributeChange{
+		{ID: 2, Name: "service.version", OldValue: &oldVersion, NewValue: "1.1.0", ChangedAt: t0.Add(time.Hour)},
+		{ID: 3, Name: "container.image.tags", NewValue: "1.1.0", ChangedAt: t0.Add(time.Hour)},
+		{ID: 4, Name: "service.version", NewValue: "1.2.0", ChangedAt: t0.Add(2 * time.Hour)},
+	}
+	schemaChanges := []EntitySchemaChange{
+		{ID: 1, SchemaID: "v1", Change: SchemaChangeAdded, ObservedAt: t0},
+		{ID: 3, SchemaID: "v2", Change: SchemaChangeAdded, ObservedAt: t0.Add(time.Hour + time.Minute)},
+		{ID: 2, SchemaID: "v1", Change: SchemaChangeRemoved, ObservedAt: t0.Add(time.Hour)},
+		{ID: 4, SchemaID: "v3", Change: SchemaChangeAdded, ObservedAt: t0.Add(3 * time.Hour)},
+	}
+
+	rollouts := CorrelateRollouts(attributeChanges, schemaChanges)
+	require.Len(t, rollouts, 3)
+
+	// Schemas seen before the first attribute change form the initial rollout
+	assert.True(t, t0.Equal(rollouts[0].StartedAt))
+	require.NotNil(t, rollouts[0].EndedAt)
+	assert.True(t, t0.Add(time.Hour).Equal(*rollouts[0].EndedAt))
+	assert.Empty(t, rollouts[0].AttributeChanges)
+	require.Len(t, rollouts[0].SchemaChanges, 1)
+	assert.Equal(t, "v1", rollouts[0].SchemaChanges[0].SchemaID)
+
+	// Attribute changes at the same time belong to the same rollout
+	assert.Len(t, rollouts[1].AttributeChanges, 2)
+	require.Len(t, rollouts[1].SchemaChanges, 2)
+	assert.Equal(t, SchemaChangeRemoved, rollouts[1].SchemaChanges[0].Change)
+	assert.Equal(t, "v2", rollouts[1].SchemaChanges[1].SchemaID)
+
+	// The latest rollout is still active
+	assert.Nil(t, rollouts[2].EndedAt)
+	require.Len(t, rollouts[2].SchemaChanges, 1)
+	assert.Equal(t, "v3", rollouts[2].SchemaChanges[0].SchemaID)
+}
+
+func TestCorrelateRollouts_Empty(t *testing.T) {
+	assert.Empty(t, CorrelateRollouts(nil, nil))
+}
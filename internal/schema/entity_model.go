@@ -0,0 +1,237 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"gopkg.in/yaml.v3"
+)
+
+// EntityDefinition describes an entity type as defined in the OTel Entity data model.
+// Identifying attributes determine the identity of an entity, while descriptive
+// attributes carry additional information that may change over its lifetime.
+type EntityDefinition struct {
+	Type                  string   `yaml:"type" json:"type"`
+	IdentifyingAttributes []string `yaml:"identifying_attributes" json:"identifyingAttributes"`
+	DescriptiveAttributes []string `yaml:"descriptive_attributes" json:"descriptiveAttributes"`
+}
+
+// EntityModel is the set of entity types TallyCat detects from resource attributes. It is read
+// once on start and passed to everything detecting entities, so it never changes while serving.
+type EntityModel struct {
+	Entities []EntityDefinition `yaml:"entities" json:"entities"`
+}
+
+// DefaultEntityModel returns the built-in entity model covering the most common
+// resource semantic conventions
+func DefaultEntityModel() *EntityModel {
+	return &EntityModel{
+		Entities: []EntityDefinition{
+			{
+				// Replicas send their service.instance.id at the same time, so it is left out:
+				// as an identifying attribute every restart would add an entity, and as a
+				// descriptive one every batch of another replica would record a change
+				Type:                  "service",
+				IdentifyingAttributes: []string{"service.name", "service.namespace"},
+				DescriptiveAttributes: []string{"service.version"},
+			},
+			{
+				Type:                  "host",
+				IdentifyingAttributes: []string{"host.id", "host.name"},
+				DescriptiveAttributes: []string{"host.arch", "host.type", "host.image.id", "host.image.name", "host.image.version"},
+			},
+			{
+				Type:                  "container",
+				IdentifyingAttributes: []string{"container.id"},
+				DescriptiveAttributes: []string{"container.name", "container.image.name", "container.image.tags", "container.runtime"},
+			},
+			{
+				Type:                  "k8s.pod",
+				IdentifyingAttributes: []string{"k8s.pod.uid", "k8s.pod.name"},
+			},
+			{
+				Type:                  "k8s.deployment",
+				IdentifyingAttributes: []string{"k8s.deployment.uid", "k8s.deployment.name"},
+			},
+			{
+				Type:                  "k8s.namespace",
+				IdentifyingAttributes: []string{"k8s.namespace.name"},
+			},
+			{
+				Type:                  "k8s.node",
+				IdentifyingAttributes: []string{"k8s.node.uid", "k8s.node.name"},
+			},
+		},
+	}
+}
+
+// LoadEntityModel reads an entity model from a YAML file
+func LoadEntityModel(path string) (*EntityModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity model %s: %w", path, err)
+	}
+	return ParseEntityModel(data)
+}
+
+// ParseEntityModel parses and validates an entity model from YAML content
+func ParseEntityModel(data []byte) (*EntityModel, error) {
+	var model EntityModel
+	if err := yaml.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse entity model: %w", err)
+	}
+	if err := model.Validate(); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Validate checks that every entity type is unique and has identifying attributes
+func (m *EntityModel) Validate() error {
+	if len(m.Entities) == 0 {
+		return fmt.Errorf("entity model must define at least one entity type")
+	}
+
+	types := make(map[string]bool)
+	for _, def := range m.Entities {
+		if def.Type == "" {
+			return fmt.Errorf("entity type cannot be empty")
+		}
+		if types[def.Type] {
+			return fmt.Errorf("entity type %q is defined more than once", def.Type)
+		}
+		types[def.Type] = true
+
+		if len(def.IdentifyingAttributes) == 0 {
+			return fmt.Errorf("entity type %q must have at least one identifying attribute", def.Type)
+		}
+	}
+	return nil
+}
+
+// Definition returns the definition for the given entity type
+func (m *EntityModel) Definition(entityType string) (EntityDefinition, bool) {
+	for _, def := range m.Entities {
+		if def.Type == entityType {
+			return def, true
+		}
+	}
+	return EntityDefinition{}, false
+}
+
+// Detect extracts the entities described by the model from resource attributes.
+// An entity is detected when at least one of its identifying attributes is present.
+func (m *EntityModel) Detect(resourceAttributes pcommon.Map) []Entity {
+	now := time.Now()
+
+	var entities []Entity
+	for _, def := range m.Entities {
+		identifying := make(map[string]interface{})
+		for _, name := range def.IdentifyingAttributes {
+			if value, ok := resourceAttributes.Get(name); ok {
+				identifying[name] = convertPCommonValue(value)
+			}
+		}
+		if len(identifying) == 0 {
+			continue
+		}
+
+		attributes := make(map[string]interface{}, len(identifying)+len(def.DescriptiveAttributes))
+		for name, value := range identifying {
+			attributes[name] = value
+		}
+		for _, name := range def.DescriptiveAttributes {
+			if value, ok := resourceAttributes.Get(name); ok {
+				attributes[name] = convertPCommonValue(value)
+			}
+		}
+
+		entities = append(entities, Entity{
+			ID:         m.EntityID(def.Type, identifying),
+			Type:       def.Type,
+			Attributes: attributes,
+			FirstSeen:  now,
+			LastSeen:   now,
+		})
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].Type < entities[j].Type
+	})
+
+	return entities
+}
+
+// IdentifyingAttributes returns the subset of attributes that identify an entity of
+// the given type. Unknown entity types are identified by all of their attributes.
+func (m *EntityModel) IdentifyingAttributes(entityType string, attributes map[string]interface{}) map[string]interface{} {
+	def, ok := m.Definition(entityType)
+	if !ok {
+		return attributes
+	}
+
+	identifying := make(map[string]interface{})
+	for _, name := range def.IdentifyingAttributes {
+		if value, ok := attributes[name]; ok {
+			identifying[name] = value
+		}
+	}
+	return identifying
+}
+
+// EntityID returns the ID of an entity of the given type, computed from its identifying attributes
+func (m *EntityModel) EntityID(entityType string, attributes map[string]interface{}) string {
+	return GenerateEntityID(entityType, m.IdentifyingAttributes(entityType, attributes))
+}
+
+// EntityRekey is an entity moved to the ID the entity model gives it
+type EntityRekey struct {
+	// Entity is stored under its new ID, merged from the entities moved to it and the one
+	// already stored under it, the attributes of the last seen winning
+	Entity Entity
+	// PreviousIDs are the IDs the entity was stored under
+	PreviousIDs []string
+}
+
+// RekeyEntities groups the stored entities by the ID id computes for them, keeping the groups
+// with entities stored under another ID, as they are when the entity model changes. Entities
+// without attributes are left alone, their identity being unknown.
+func RekeyEntities(entities []Entity, id func(Entity) string) []EntityRekey {
+	groups := make(map[string][]Entity)
+	moved := make(map[string][]string)
+	for _, entity := range entities {
+		if len(entity.Attributes) == 0 {
+			continue
+		}
+		newID := id(entity)
+		groups[newID] = append(groups[newID], entity)
+		if newID != entity.ID {
+			moved[newID] = append(moved[newID], entity.ID)
+		}
+	}
+
+	rekeys := make([]EntityRekey, 0, len(moved))
+	for newID, previousIDs := range moved {
+		group := groups[newID]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].LastSeen.Before(group[j].LastSeen)
+		})
+
+		merged := group[0]
+		merged.Attributes = make(map[string]interface{}, len(group[0].Attributes))
+		for _, entity := range group {
+			merged = MergeEntity(merged, entity)
+		}
+		merged.ID = newID
+
+		sort.Strings(previousIDs)
+		rekeys = append(rekeys, EntityRekey{Entity: merged, PreviousIDs: previousIDs})
+	}
+	sort.Slice(rekeys, func(i, j int) bool {
+		return rekeys[i].Entity.ID < rekeys[j].Entity.ID
+	})
+	return rekeys
+}
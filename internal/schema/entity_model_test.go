@@ -0,0 +1,188 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestEntityModel_DetectIDIgnoresDescriptiveAttributes(t *testing.T) {
+	model := DefaultEntityModel()
+
+	v1 := pcommon.NewMap()
+	v1.PutStr("service.name", "checkout")
+	v1.PutStr("service.version", "1.0.0")
+	v1.PutStr("service.instance.id", "checkout-7d9f")
+
+	// A release restarts the instances
+	v2 := pcommon.NewMap()
+	v2.PutStr("service.name", "checkout")
+	v2.PutStr("service.version", "1.1.0")
+	v2.PutStr("service.instance.id", "checkout-5c2a")
+
+	e1 := model.Detect(v1)
+	e2 := model.Detect(v2)
+	require.Len(t, e1, 1)
+	require.Len(t, e2, 1)
+
+	assert.Equal(t, e1[0].ID, e2[0].ID)
+	assert.Equal(t, "1.0.0", e1[0].Attributes["service.version"])
+	assert.Equal(t, "1.1.0", e2[0].Attributes["service.version"])
+	assert.NotContains(t, e2[0].Attributes, "service.instance.id")
+
+	other := pcommon.NewMap()
+	other.PutStr("service.name", "payments")
+	other.PutStr("service.version", "1.0.0")
+	e3 := model.Detect(other)
+	require.Len(t, e3, 1)
+	assert.NotEqual(t, e1[0].ID, e3[0].ID)
+
+	namespaced := pcommon.NewMap()
+	namespaced.PutStr("service.name", "checkout")
+	namespaced.PutStr("service.namespace", "staging")
+	e4 := model.Detect(namespaced)
+	require.Len(t, e4, 1)
+	assert.NotEqual(t, e1[0].ID, e4[0].ID)
+}
+
+func TestEntityModel_DetectOnlyKeepsModelAttributes(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("k8s.pod.name", "checkout-7d9f")
+	attrs.PutStr("k8s.namespace.name", "shop")
+	attrs.PutStr("k8s.cluster.name", "prod")
+
+	entities := DefaultEntityModel().Detect(attrs)
+	require.Len(t, entities, 2)
+
+	assert.Equal(t, "k8s.namespace", entities[0].Type)
+	assert.Equal(t, map[string]interface{}{"k8s.namespace.name": "shop"}, entities[0].Attributes)
+	assert.Equal(t, "k8s.pod", entities[1].Type)
+	assert.Equal(t, map[string]interface{}{"k8s.pod.name": "checkout-7d9f"}, entities[1].Attributes)
+}
+
+func TestParseEntityModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid model",
+			yaml: `
+entities:
+  - type: service
+    identifying_attributes: [service.name, service.namespace]
+    descriptive_attributes: [service.version]
+  - type: k8s.cluster
+    identifying_attributes: [k8s.cluster.uid]
+`,
+		},
+		{
+			name:    "no entities",
+			yaml:    `entities: []`,
+			wantErr: "at least one entity type",
+		},
+		{
+			name: "missing identifying attributes",
+			yaml: `
+entities:
+  - type: service
+    descriptive_attributes: [service.version]
+`,
+			wantErr: "at least one identifying attribute",
+		},
+		{
+			name: "duplicate type",
+			yaml: `
+entities:
+  - type: service
+    identifying_attributes: [service.name]
+  - type: service
+    identifying_attributes: [service.instance.id]
+`,
+			wantErr: "defined more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, err := ParseEntityModel([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			def, ok := model.Definition("k8s.cluster")
+			require.True(t, ok)
+			assert.Equal(t, []string{"k8s.cluster.uid"}, def.IdentifyingAttributes)
+		})
+	}
+}
+
+func TestEntityTypesFromAttributes_CustomModel(t *testing.T) {
+	model := &EntityModel{
+		Entities: []EntityDefinition{
+			{Type: "deployment.environment", IdentifyingAttributes: []string{"deployment.environment.name"}},
+		},
+	}
+
+	attrs := pcommon.NewMap()
+	attrs.PutStr("service.name", "checkout")
+	attrs.PutStr("deployment.environment.name", "production")
+
+	assert.Equal(t, []string{"deployment.environment"}, EntityTypesFromAttributes(model, attrs))
+}
+
+func TestRekeyEntities(t *testing.T) {
+	model := DefaultEntityModel()
+	t0 := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	// Instances of checkout stored as entities of their own, as before instances were descriptive
+	instance := func(id, instanceID, version string, lastSeen time.Time) Entity {
+		return Entity{
+			ID:   id,
+			Type: "service",
+			Attributes: map[string]interface{}{
+				"service.name":        "checkout",
+				"service.instance.id": instanceID,
+				"service.version":     version,
+			},
+			FirstSeen: lastSeen.Add(-time.Hour),
+			LastSeen:  lastSeen,
+		}
+	}
+	payments := Entity{
+		Type:       "service",
+		Attributes: map[string]interface{}{"service.name": "payments"},
+		FirstSeen:  t0,
+		LastSeen:   t0,
+	}
+	payments.ID = model.EntityID(payments.Type, payments.Attributes)
+
+	rekeys := RekeyEntities([]Entity{
+		instance("pod-2", "checkout-5c2a", "1.1.0", t0.Add(2*time.Hour)),
+		instance("pod-1", "checkout-7d9f", "1.0.0", t0),
+		payments,
+		{ID: "unknown", Type: "service"},
+	}, func(e Entity) string {
+		return model.EntityID(e.Type, e.Attributes)
+	})
+
+	require.Len(t, rekeys, 1)
+	checkout := rekeys[0]
+	assert.Equal(t, []string{"pod-1", "pod-2"}, checkout.PreviousIDs)
+	assert.Equal(t, model.EntityID("service", map[string]interface{}{"service.name": "checkout"}), checkout.Entity.ID)
+	assert.Equal(t, t0.Add(-time.Hour), checkout.Entity.FirstSeen)
+	assert.Equal(t, t0.Add(2*time.Hour), checkout.Entity.LastSeen)
+	assert.Equal(t, "1.1.0", checkout.Entity.Attributes["service.version"])
+	assert.Equal(t, "checkout-5c2a", checkout.Entity.Attributes["service.instance.id"])
+
+	// Entities already under their ID are left alone
+	assert.Empty(t, RekeyEntities([]Entity{payments}, func(e Entity) string {
+		return model.EntityID(e.Type, e.Attributes)
+	}))
+}
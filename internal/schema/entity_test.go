@@ -81,17 +81,28 @@ func TestDetectEntities(t *testing.T) {
 				"host.arch":          "amd64",
 				"container.id":       "docker-789",
 			},
-			wantTypes: []string{"container", "host", "k8s", "service"},
-			wantCount: 4,
+			wantTypes: []string{"container", "host", "k8s.namespace", "k8s.pod", "service"},
+			wantCount: 5,
 		},
 		{
-			name: "no dotted attributes",
+			name: "attributes outside the entity model",
 			attributes: map[string]interface{}{
-				"simple_attr": "value",
-				"another":     "value2",
+				"service.name":           "payment-api",
+				"telemetry.sdk.name":     "opentelemetry",
+				"telemetry.sdk.language": "go",
+				"simple_attr":            "value",
 			},
-			wantTypes: []string{"another", "simple_attr"},
-			wantCount: 2,
+			wantTypes: []string{"service"},
+			wantCount: 1,
+		},
+		{
+			name: "no identifying attributes",
+			attributes: map[string]interface{}{
+				"service.version": "1.0.0",
+				"host.arch":       "amd64",
+			},
+			wantTypes: []string{},
+			wantCount: 0,
 		},
 	}
 
@@ -112,7 +123,7 @@ func TestDetectEntities(t *testing.T) {
 				}
 			}
 
-			entities := DetectEntities(resourceAttrs)
+			entities := DefaultEntityModel().Detect(resourceAttrs)
 
 			// Check count
 			assert.Len(t, entities, tt.wantCount)
@@ -138,25 +149,19 @@ func TestDetectEntities(t *testing.T) {
 	}
 }
 
-func TestEntityID(t *testing.T) {
-	entity := Entity{
-		Type: "service",
-		Attributes: map[string]interface{}{
-			"service.name":    "test-service",
-			"service.version": "1.0.0",
-		},
-		FirstSeen: time.Now(),
-		LastSeen:  time.Now(),
+func TestEntityModel_EntityID(t *testing.T) {
+	model := DefaultEntityModel()
+	attributes := map[string]interface{}{
+		"service.name":    "test-service",
+		"service.version": "1.0.0",
 	}
 
-	// First call should generate and set ID
-	id1 := entity.EntityID()
-	assert.NotEmpty(t, id1)
-	assert.Equal(t, id1, entity.ID)
+	id := model.EntityID("service", attributes)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, GenerateEntityID("service", map[string]interface{}{"service.name": "test-service"}), id)
 
-	// Second call should return the same ID
-	id2 := entity.EntityID()
-	assert.Equal(t, id1, id2)
+	// Entities of types outside the model are identified by all of their attributes
+	assert.Equal(t, GenerateEntityID("telemetry", attributes), model.EntityID("telemetry", attributes))
 }
 
 func TestMergeEntity(t *testing.T) {
@@ -236,9 +241,9 @@ func TestEntityTypesFromAttributes(t *testing.T) {
 	resourceAttrs.PutStr("host.name", "worker-01")
 	resourceAttrs.PutStr("simple_attr", "value")
 
-	entityTypes := EntityTypesFromAttributes(resourceAttrs)
+	entityTypes := EntityTypesFromAttributes(DefaultEntityModel(), resourceAttrs)
 
-	expected := []string{"host", "k8s", "service", "simple_attr"}
+	expected := []string{"host", "k8s.pod", "service"}
 	assert.ElementsMatch(t, expected, entityTypes)
 }
 
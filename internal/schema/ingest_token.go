@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/tallycat/tallycat/internal/tenant"
+)
+
+var (
+	// ErrIngestTokenNotFound is returned when revoking an unknown or revoked ingest token
+	ErrIngestTokenNotFound = errors.New("ingest token not found")
+	// ErrIngestTokenExists is returned when creating an ingest token under a name already in use
+	ErrIngestTokenExists = errors.New("ingest token already exists")
+	// ErrInvalidIngestToken is returned for an ingest token request with an invalid name or rate limit
+	ErrInvalidIngestToken = errors.New("invalid ingest token")
+)
+
+// ingestTokenPrefix marks the ingest tokens generated by TallyCat, so leaked ones are easy to find
+const ingestTokenPrefix = "tcit_"
+
+var ingestTokenName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// IngestToken authenticates a client pushing OTLP and carries its rate limits. Only the SHA-256
+// hash of the token is stored.
+type IngestToken struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// RequestsPerSecond and SchemasPerSecond limit the exports and the schemas they carry, zero is unlimited
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	SchemasPerSecond  float64 `json:"schemasPerSecond"`
+	// Tenant binds the token to a tenant, empty for tokens sending to the default tenant, or to the
+	// tenant named in their metadata when the server allows it
+	Tenant    string     `json:"tenant,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	CreatedBy *string    `json:"createdBy,omitempty"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	Hash      string     `json:"-"`
+}
+
+// IngestTokenRequest creates an ingest token
+type IngestTokenRequest struct {
+	Name              string  `json:"name"`
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	SchemasPerSecond  float64 `json:"schemasPerSecond"`
+	Tenant            string  `json:"tenant,omitempty"`
+}
+
+// Validate checks the token is named with letters, digits, dots, dashes and underscores, that
+// its rate limits are not negative and that it is bound to a valid tenant, if any
+func (r *IngestTokenRequest) Validate() error {
+	if !ingestTokenName.MatchString(r.Name) {
+		return fmt.Errorf("%w: name must be 1 to 64 letters, digits, dots, dashes or underscores", ErrInvalidIngestToken)
+	}
+	if r.RequestsPerSecond < 0 || r.SchemasPerSecond < 0 {
+		return fmt.Errorf("%w: rate limits cannot be negative", ErrInvalidIngestToken)
+	}
+	if r.Tenant != "" {
+		if err := tenant.Validate(r.Tenant); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidIngestToken, err)
+		}
+	}
+	return nil
+}
+
+// CreatedIngestToken is an ingest token just created, the only time its secret is returned
+type CreatedIngestToken struct {
+	IngestToken
+	Token string `json:"token"`
+}
+
+// GenerateIngestToken returns a new random ingest token and its hash
+func GenerateIngestToken() (token, hash string, err error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", fmt.Errorf("failed to generate ingest token: %w", err)
+	}
+	token = ingestTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, HashIngestToken(token), nil
+}
+
+// HashIngestToken returns the hex encoded SHA-256 hash ingest tokens are stored and looked up by
+func HashIngestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IngestUsage counts the exports of a client authenticated by an ingest token
+type IngestUsage struct {
+	TokenID   int64
+	Timestamp time.Time
+	Requests  int64
+	Schemas   int64
+	// Throttled counts the requests refused over the rate limit
+	Throttled int64
+}
+
+// IngestTokenStats are the exports of an ingest token within a time window
+type IngestTokenStats struct {
+	Name      string     `json:"name"`
+	Requests  int64      `json:"requests"`
+	Schemas   int64      `json:"schemas"`
+	Throttled int64      `json:"throttled"`
+	LastSeen  *time.Time `json:"lastSeen,omitempty"`
+}
@@ -0,0 +1,368 @@
+package schema
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownTeam is returned when assigning ownership to a team the ownership model does not declare
+	ErrUnknownTeam = errors.New("unknown team")
+	// ErrAssetNotFound is returned when resolving the owners of an unknown telemetry, entity or scope
+	ErrAssetNotFound = errors.New("asset not found")
+)
+
+// OwnedKind is the kind of asset a team owns
+type OwnedKind string
+
+const (
+	// OwnedKindTelemetry is a telemetry, identified by its schema key
+	OwnedKindTelemetry OwnedKind = "telemetry"
+	OwnedKindEntity    OwnedKind = "entity"
+	OwnedKindScope     OwnedKind = "scope"
+)
+
+// ParseOwnedKind parses the kind of an owned asset
+func ParseOwnedKind(s string) (OwnedKind, error) {
+	switch kind := OwnedKind(s); kind {
+	case OwnedKindTelemetry, OwnedKindEntity, OwnedKindScope:
+		return kind, nil
+	}
+	return "", fmt.Errorf("invalid kind %q, expected telemetry, entity or scope", s)
+}
+
+// OwnershipSource is how the owner of an asset was resolved, in order of precedence
+type OwnershipSource string
+
+const (
+	// OwnershipSourceExplicit is an owner assigned through the API
+	OwnershipSourceExplicit OwnershipSource = "explicit"
+	// OwnershipSourceCodeOwners is an owner matched by a schema key pattern of the CODEOWNERS file
+	OwnershipSourceCodeOwners OwnershipSource = "codeowners"
+	// OwnershipSourceRule is an owner matched by a resource attribute or scope rule
+	OwnershipSourceRule OwnershipSource = "rule"
+)
+
+// Team owns telemetry and is reached through its contacts, e.g. an email address or a chat channel
+type Team struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Contacts    []string `yaml:"contacts,omitempty" json:"contacts,omitempty"`
+}
+
+// OwnershipRule assigns a team to the assets matching either a resource attribute selector or a
+// scope name pattern
+type OwnershipRule struct {
+	Team string `yaml:"team" json:"team"`
+	// Resource is a selector on resource attributes such as service.namespace=payments. It matches
+	// entities having these attributes and the telemetry they emit.
+	Resource string `yaml:"resource,omitempty" json:"resource,omitempty"`
+	// Scope is a glob pattern on instrumentation scope names, e.g. io.opentelemetry.contrib.*
+	Scope string `yaml:"scope,omitempty" json:"scope,omitempty"`
+
+	selector EntitySelector
+}
+
+// CodeOwnersEntry assigns teams to the schema keys matching a glob pattern
+type CodeOwnersEntry struct {
+	Pattern string   `json:"pattern"`
+	Teams   []string `json:"teams"`
+}
+
+// OwnershipModel declares the teams and the rules resolving who owns telemetry, entities and scopes
+type OwnershipModel struct {
+	Teams []Team          `yaml:"teams" json:"teams"`
+	Rules []OwnershipRule `yaml:"rules,omitempty" json:"rules,omitempty"`
+	// CodeOwners is the path of a CODEOWNERS-style file of schema key patterns, relative to the
+	// ownership file
+	CodeOwners string `yaml:"codeowners,omitempty" json:"codeowners,omitempty"`
+
+	codeOwners []CodeOwnersEntry
+}
+
+// Owner is a team owning an asset and how the ownership was resolved
+type Owner struct {
+	Team   string          `json:"team"`
+	Source OwnershipSource `json:"source"`
+}
+
+// Ownership lists the owners resolved for an asset
+type Ownership struct {
+	Kind   OwnedKind `json:"kind"`
+	ID     string    `json:"id"`
+	Owners []Owner   `json:"owners"`
+}
+
+// OwnedAsset is a telemetry, entity or scope owned by a team
+type OwnedAsset struct {
+	ID string `json:"id"`
+	// Type is the signal type of a telemetry or the type of an entity
+	Type string `json:"type,omitempty"`
+	// Name is the name of a scope
+	Name   string          `json:"name,omitempty"`
+	Source OwnershipSource `json:"source"`
+}
+
+// TeamInventory lists the assets owned by a team
+type TeamInventory struct {
+	Team        Team         `json:"team"`
+	Telemetries []OwnedAsset `json:"telemetries"`
+	Entities    []OwnedAsset `json:"entities"`
+	Scopes      []OwnedAsset `json:"scopes"`
+}
+
+// TeamSummary is a team with the number of assets it owns
+type TeamSummary struct {
+	Team        Team `json:"team"`
+	Telemetries int  `json:"telemetries"`
+	Entities    int  `json:"entities"`
+	Scopes      int  `json:"scopes"`
+}
+
+// LoadOwnershipModel reads an ownership model from a YAML file, along with the CODEOWNERS file it
+// references
+func LoadOwnershipModel(filePath string) (*OwnershipModel, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ownership model %s: %w", filePath, err)
+	}
+	model, err := ParseOwnershipModel(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.CodeOwners != "" {
+		codeOwnersPath := model.CodeOwners
+		if !filepath.IsAbs(codeOwnersPath) {
+			codeOwnersPath = filepath.Join(filepath.Dir(filePath), codeOwnersPath)
+		}
+		data, err := os.ReadFile(codeOwnersPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read codeowners %s: %w", codeOwnersPath, err)
+		}
+		entries, err := ParseCodeOwners(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := model.SetCodeOwners(entries); err != nil {
+			return nil, err
+		}
+	}
+
+	return model, nil
+}
+
+// ParseOwnershipModel parses and validates an ownership model from YAML content
+func ParseOwnershipModel(data []byte) (*OwnershipModel, error) {
+	var model OwnershipModel
+	if err := yaml.Unmarshal(data, &model); err != nil {
+		return nil, fmt.Errorf("failed to parse ownership model: %w", err)
+	}
+	if err := model.Validate(); err != nil {
+		return nil, err
+	}
+	return &model, nil
+}
+
+// Validate checks that teams are uniquely named and that every rule targets a declared team with
+// either a resource selector or a scope pattern
+func (m *OwnershipModel) Validate() error {
+	names := make(map[string]bool)
+	for _, team := range m.Teams {
+		if team.Name == "" {
+			return fmt.Errorf("team name cannot be empty")
+		}
+		if names[team.Name] {
+			return fmt.Errorf("team %q is defined more than once", team.Name)
+		}
+		names[team.Name] = true
+	}
+
+	for i := range m.Rules {
+		rule := &m.Rules[i]
+		if !names[rule.Team] {
+			return fmt.Errorf("ownership rule %d: %w %q", i+1, ErrUnknownTeam, rule.Team)
+		}
+		switch {
+		case rule.Resource != "" && rule.Scope != "":
+			return fmt.Errorf("ownership rule %d cannot match both a resource and a scope", i+1)
+		case rule.Resource != "":
+			selector, err := ParseEntitySelector(rule.Resource)
+			if err != nil {
+				return fmt.Errorf("ownership rule %d: %w", i+1, err)
+			}
+			rule.selector = selector
+		case rule.Scope != "":
+			if _, err := path.Match(rule.Scope, ""); err != nil {
+				return fmt.Errorf("ownership rule %d has invalid pattern %q: %w", i+1, rule.Scope, err)
+			}
+		default:
+			return fmt.Errorf("ownership rule %d must match a resource or a scope", i+1)
+		}
+	}
+
+	return nil
+}
+
+// ParseCodeOwners parses a CODEOWNERS-style file where each line is a schema key glob pattern
+// followed by the teams owning the matching keys. Team names may be prefixed with @.
+func ParseCodeOwners(data []byte) ([]CodeOwnersEntry, error) {
+	var entries []CodeOwnersEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("codeowners line %d: pattern %q has no owner", lineNumber, fields[0])
+		}
+		if _, err := path.Match(fields[0], ""); err != nil {
+			return nil, fmt.Errorf("codeowners line %d has invalid pattern %q: %w", lineNumber, fields[0], err)
+		}
+		entry := CodeOwnersEntry{Pattern: fields[0]}
+		for _, team := range fields[1:] {
+			entry.Teams = append(entry.Teams, strings.TrimPrefix(team, "@"))
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read codeowners: %w", err)
+	}
+	return entries, nil
+}
+
+// SetCodeOwners replaces the CODEOWNERS entries of the model, which must reference declared teams
+func (m *OwnershipModel) SetCodeOwners(entries []CodeOwnersEntry) error {
+	for _, entry := range entries {
+		for _, team := range entry.Teams {
+			if !m.HasTeam(team) {
+				return fmt.Errorf("codeowners pattern %q: %w %q", entry.Pattern, ErrUnknownTeam, team)
+			}
+		}
+	}
+	m.codeOwners = entries
+	return nil
+}
+
+// Team returns the declared team of the given name
+func (m *OwnershipModel) Team(name string) (Team, bool) {
+	if m != nil {
+		for _, team := range m.Teams {
+			if team.Name == name {
+				return team, true
+			}
+		}
+	}
+	return Team{}, false
+}
+
+// HasTeam reports whether the model declares the team. Without a model, any team is accepted.
+func (m *OwnershipModel) HasTeam(name string) bool {
+	if m == nil {
+		return true
+	}
+	_, ok := m.Team(name)
+	return ok
+}
+
+// HasResourceRules reports whether ownership depends on the attributes of the entities
+func (m *OwnershipModel) HasResourceRules() bool {
+	if m == nil {
+		return false
+	}
+	for _, rule := range m.Rules {
+		if rule.Resource != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveTelemetryOwners returns the owners of a schema key: the teams of the last CODEOWNERS
+// pattern matching it or, without a match, the teams whose resource rules match one of the entities
+// emitting it
+func (m *OwnershipModel) ResolveTelemetryOwners(schemaKey string, entities []*Entity) []Owner {
+	if m == nil {
+		return nil
+	}
+	for i := len(m.codeOwners) - 1; i >= 0; i-- {
+		entry := m.codeOwners[i]
+		if ok, _ := path.Match(entry.Pattern, schemaKey); ok {
+			return owners(entry.Teams, OwnershipSourceCodeOwners)
+		}
+	}
+
+	var teams []string
+	for _, entity := range entities {
+		teams = append(teams, m.resourceTeams(entity)...)
+	}
+	return owners(teams, OwnershipSourceRule)
+}
+
+// ResolveEntityOwners returns the teams whose resource rules match the entity
+func (m *OwnershipModel) ResolveEntityOwners(entity *Entity) []Owner {
+	if m == nil {
+		return nil
+	}
+	return owners(m.resourceTeams(entity), OwnershipSourceRule)
+}
+
+// ResolveScopeOwners returns the teams whose scope rules match the scope name
+func (m *OwnershipModel) ResolveScopeOwners(scope *Scope) []Owner {
+	if m == nil {
+		return nil
+	}
+	var teams []string
+	for _, rule := range m.Rules {
+		if rule.Scope == "" {
+			continue
+		}
+		if ok, _ := path.Match(rule.Scope, scope.Name); ok {
+			teams = append(teams, rule.Team)
+		}
+	}
+	return owners(teams, OwnershipSourceRule)
+}
+
+func (m *OwnershipModel) resourceTeams(entity *Entity) []string {
+	var teams []string
+	for _, rule := range m.Rules {
+		if rule.selector != nil && rule.selector.Matches(entity) {
+			teams = append(teams, rule.Team)
+		}
+	}
+	return teams
+}
+
+// ExplicitOwners returns the owners assigned through the API
+func ExplicitOwners(teams []string) []Owner {
+	return owners(teams, OwnershipSourceExplicit)
+}
+
+// owners returns the distinct teams, sorted, as owners resolved from the source
+func owners(teams []string, source OwnershipSource) []Owner {
+	if len(teams) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), teams...)
+	sort.Strings(sorted)
+	var result []Owner
+	for i, team := range sorted {
+		if i > 0 && team == sorted[i-1] {
+			continue
+		}
+		result = append(result, Owner{Team: team, Source: source})
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOwnershipModel = `
+teams:
+  - name: payments
+    contacts: [payments@example.com]
+  - name: platform
+    contacts: ["#platform-oncall"]
+rules:
+  - team: payments
+    resource: service.namespace=payments
+  - team: platform
+    scope: io.opentelemetry.contrib.*
+codeowners: OWNERS
+`
+
+func TestLoadOwnershipModel(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ownership.yaml"), []byte(testOwnershipModel), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "OWNERS"), []byte(`
+# Runtime metrics belong to the platform team
+process.runtime.*   @platform
+http.server.*       @platform @payments
+http.server.active_requests platform # last match wins
+`), 0o644))
+
+	model, err := LoadOwnershipModel(filepath.Join(dir, "ownership.yaml"))
+	require.NoError(t, err)
+	require.Len(t, model.Teams, 2)
+
+	payments := &Entity{ID: "checkout", Attributes: map[string]interface{}{"service.name": "checkout", "service.namespace": "payments"}}
+	other := &Entity{ID: "search", Attributes: map[string]interface{}{"service.name": "search"}}
+
+	assert.Equal(t, []Owner{{Team: "platform", Source: OwnershipSourceCodeOwners}},
+		model.ResolveTelemetryOwners("process.runtime.go.goroutines", []*Entity{payments}))
+	assert.Equal(t, []Owner{{Team: "payments", Source: OwnershipSourceCodeOwners}, {Team: "platform", Source: OwnershipSourceCodeOwners}},
+		model.ResolveTelemetryOwners("http.server.duration", nil))
+	assert.Equal(t, []Owner{{Team: "platform", Source: OwnershipSourceCodeOwners}},
+		model.ResolveTelemetryOwners("http.server.active_requests", nil))
+	assert.Equal(t, []Owner{{Team: "payments", Source: OwnershipSourceRule}},
+		model.ResolveTelemetryOwners("checkout.orders", []*Entity{other, payments}))
+	assert.Empty(t, model.ResolveTelemetryOwners("checkout.orders", []*Entity{other}))
+
+	assert.Equal(t, []Owner{{Team: "payments", Source: OwnershipSourceRule}}, model.ResolveEntityOwners(payments))
+	assert.Empty(t, model.ResolveEntityOwners(other))
+	assert.Equal(t, []Owner{{Team: "platform", Source: OwnershipSourceRule}},
+		model.ResolveScopeOwners(&Scope{Name: "io.opentelemetry.contrib.otelhttp"}))
+	assert.Empty(t, model.ResolveScopeOwners(&Scope{Name: "checkout"}))
+}
+
+func TestParseOwnershipModel_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   string
+		wantErr string
+	}{
+		{"missing team name", "teams:\n  - contacts: [a]\n", "team name cannot be empty"},
+		{"duplicate team", "teams:\n  - name: a\n  - name: a\n", "defined more than once"},
+		{"unknown team", "teams: [{name: a}]\nrules:\n  - {team: b, resource: a=b}\n", "unknown team"},
+		{"no matcher", "teams: [{name: a}]\nrules:\n  - {team: a}\n", "must match a resource or a scope"},
+		{"both matchers", "teams: [{name: a}]\nrules:\n  - {team: a, resource: a=b, scope: c}\n", "cannot match both"},
+		{"bad selector", "teams: [{name: a}]\nrules:\n  - {team: a, resource: payments}\n", "invalid selector"},
+		{"bad pattern", "teams: [{name: a}]\nrules:\n  - {team: a, scope: \"[\"}\n", "invalid pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOwnershipModel([]byte(tt.model))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestParseCodeOwners(t *testing.T) {
+	entries, err := ParseCodeOwners([]byte("# comment\n\nhttp.* @web  @platform\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []CodeOwnersEntry{{Pattern: "http.*", Teams: []string{"web", "platform"}}}, entries)
+
+	_, err = ParseCodeOwners([]byte("http.*\n"))
+	assert.ErrorContains(t, err, "has no owner")
+
+	model, err := ParseOwnershipModel([]byte("teams: [{name: web}]\n"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, model.SetCodeOwners(entries), ErrUnknownTeam)
+}
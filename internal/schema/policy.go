@@ -0,0 +1,243 @@
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyMode decides what happens to telemetry that violates a policy
+type PolicyMode string
+
+const (
+	// PolicyModeAudit only records the violation
+	PolicyModeAudit PolicyMode = "audit"
+	// PolicyModeWarn records the violation and reports it to the sender through OTLP partial success
+	PolicyModeWarn PolicyMode = "warn"
+	// PolicyModeReject records the violation and refuses the export
+	PolicyModeReject PolicyMode = "reject"
+	// PolicyModeDrop records the violation and leaves the violating schemas out of the catalog,
+	// accepting the rest of the export
+	PolicyModeDrop PolicyMode = "drop"
+)
+
+// Policy is a governance rule set evaluated against every schema extracted on ingest.
+// Match and Signals restrict the schemas a policy applies to; every rule that is set is checked.
+type Policy struct {
+	Name        string     `yaml:"name" json:"name"`
+	Description string     `yaml:"description,omitempty" json:"description,omitempty"`
+	Mode        PolicyMode `yaml:"mode" json:"mode"`
+	// Match is a glob pattern on the schema key, e.g. http.server.*
+	Match   string          `yaml:"match,omitempty" json:"match,omitempty"`
+	Signals []TelemetryType `yaml:"signals,omitempty" json:"signals,omitempty"`
+
+	// ForbiddenAttributes are glob patterns of attribute names that must not be sent
+	ForbiddenAttributes []string `yaml:"forbidden_attributes,omitempty" json:"forbiddenAttributes,omitempty"`
+	// RequiredResourceAttributes must be present on the resource of every matching schema
+	RequiredResourceAttributes []string `yaml:"required_resource_attributes,omitempty" json:"requiredResourceAttributes,omitempty"`
+	// AllowedMetricTypes limits matching metrics to these metric types
+	AllowedMetricTypes []MetricType `yaml:"allowed_metric_types,omitempty" json:"allowedMetricTypes,omitempty"`
+	// MaxAttributes caps the attributes defining a schema, resource and scope attributes excluded
+	MaxAttributes int `yaml:"max_attributes,omitempty" json:"maxAttributes,omitempty"`
+	// Deny makes every matching schema a violation, e.g. telemetry rejected in review
+	Deny bool `yaml:"deny,omitempty" json:"deny,omitempty"`
+}
+
+// PolicySet is the set of policies loaded from disk
+type PolicySet struct {
+	Policies []Policy `yaml:"policies" json:"policies"`
+}
+
+// PolicyViolation records a schema breaking a rule of a policy
+type PolicyViolation struct {
+	ID            int64         `json:"id"`
+	Policy        string        `json:"policy"`
+	Mode          PolicyMode    `json:"mode"`
+	Rule          string        `json:"rule"`
+	SchemaKey     string        `json:"schemaKey"`
+	SchemaID      string        `json:"schemaId"`
+	TelemetryType TelemetryType `json:"telemetryType"`
+	Message       string        `json:"message"`
+	ObservedAt    time.Time     `json:"observedAt"`
+}
+
+const (
+	PolicyRuleForbiddenAttribute        = "forbidden_attribute"
+	PolicyRuleRequiredResourceAttribute = "required_resource_attribute"
+	PolicyRuleAllowedMetricType         = "allowed_metric_type"
+	PolicyRuleMaxAttributes             = "max_attributes"
+	PolicyRuleDeny                      = "deny"
+)
+
+// LoadPolicies reads a policy set from a YAML file
+func LoadPolicies(path string) (*PolicySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policies %s: %w", path, err)
+	}
+	return ParsePolicies(data)
+}
+
+// ParsePolicies parses and validates a policy set from YAML content
+func ParsePolicies(data []byte) (*PolicySet, error) {
+	var set PolicySet
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse policies: %w", err)
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Validate checks that policies are uniquely named, use a known mode and define at least one rule.
+// A policy without a mode is audited.
+func (s *PolicySet) Validate() error {
+	names := make(map[string]bool)
+	for i := range s.Policies {
+		p := &s.Policies[i]
+		if p.Name == "" {
+			return fmt.Errorf("policy name cannot be empty")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("policy %q is defined more than once", p.Name)
+		}
+		names[p.Name] = true
+
+		switch p.Mode {
+		case "":
+			p.Mode = PolicyModeAudit
+		case PolicyModeAudit, PolicyModeWarn, PolicyModeReject, PolicyModeDrop:
+		default:
+			return fmt.Errorf("policy %q has invalid mode %q, expected audit, warn, reject or drop", p.Name, p.Mode)
+		}
+
+		for _, pattern := range append([]string{p.Match}, p.ForbiddenAttributes...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy %q has invalid pattern %q: %w", p.Name, pattern, err)
+			}
+		}
+
+		if len(p.ForbiddenAttributes) == 0 && len(p.RequiredResourceAttributes) == 0 &&
+			len(p.AllowedMetricTypes) == 0 && p.MaxAttributes <= 0 && !p.Deny {
+			return fmt.Errorf("policy %q must define at least one rule", p.Name)
+		}
+	}
+	return nil
+}
+
+// applies reports whether the policy covers the telemetry
+func (p *Policy) applies(telemetry *Telemetry) bool {
+	if len(p.Signals) > 0 {
+		found := false
+		for _, signal := range p.Signals {
+			if strings.EqualFold(string(signal), string(telemetry.TelemetryType)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if p.Match != "" {
+		if ok, _ := path.Match(p.Match, telemetry.SchemaKey); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate checks every telemetry against the policies and returns the violations found
+func (s *PolicySet) Evaluate(telemetries []Telemetry) []PolicyViolation {
+	if s == nil {
+		return nil
+	}
+
+	now := time.Now()
+	var violations []PolicyViolation
+	for i := range telemetries {
+		telemetry := &telemetries[i]
+		for j := range s.Policies {
+			policy := &s.Policies[j]
+			if !policy.applies(telemetry) {
+				continue
+			}
+			violate := func(rule, message string) {
+				violations = append(violations, PolicyViolation{
+					Policy:        policy.Name,
+					Mode:          policy.Mode,
+					Rule:          rule,
+					SchemaKey:     telemetry.SchemaKey,
+					SchemaID:      telemetry.SchemaID,
+					TelemetryType: telemetry.TelemetryType,
+					Message:       message,
+					ObservedAt:    now,
+				})
+			}
+
+			if policy.Deny {
+				violate(PolicyRuleDeny, "is denied")
+			}
+
+			resourceAttributes := make(map[string]bool)
+			schemaAttributes := make(map[string]bool)
+			for _, attr := range telemetry.Attributes {
+				for _, pattern := range policy.ForbiddenAttributes {
+					if ok, _ := path.Match(pattern, attr.Name); ok {
+						violate(PolicyRuleForbiddenAttribute, fmt.Sprintf("%s attribute %s is forbidden", strings.ToLower(string(attr.Source)), attr.Name))
+						break
+					}
+				}
+				switch attr.Source {
+				case AttributeSourceResource:
+					resourceAttributes[attr.Name] = true
+				case AttributeSourceScope:
+				default:
+					schemaAttributes[attr.Name] = true
+				}
+			}
+
+			var missing []string
+			for _, name := range policy.RequiredResourceAttributes {
+				if !resourceAttributes[name] {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				violate(PolicyRuleRequiredResourceAttribute, fmt.Sprintf("missing required resource attributes %s", strings.Join(missing, ", ")))
+			}
+
+			if len(policy.AllowedMetricTypes) > 0 && telemetry.TelemetryType == TelemetryTypeMetric {
+				allowed := false
+				for _, metricType := range policy.AllowedMetricTypes {
+					if strings.EqualFold(string(metricType), string(telemetry.MetricType)) {
+						allowed = true
+						break
+					}
+				}
+				if !allowed {
+					violate(PolicyRuleAllowedMetricType, fmt.Sprintf("metric type %s is not allowed", telemetry.MetricType))
+				}
+			}
+
+			if policy.MaxAttributes > 0 && len(schemaAttributes) > policy.MaxAttributes {
+				violate(PolicyRuleMaxAttributes, fmt.Sprintf("%d attributes exceed the maximum of %d", len(schemaAttributes), policy.MaxAttributes))
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].SchemaKey != violations[j].SchemaKey {
+			return violations[i].SchemaKey < violations[j].SchemaKey
+		}
+		return violations[i].Policy < violations[j].Policy
+	})
+
+	return violations
+}
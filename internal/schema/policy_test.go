@@ -0,0 +1,122 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicies = `
+policies:
+  - name: no-pii
+    mode: reject
+    forbidden_attributes: ["user.email", "*.password"]
+  - name: service-identity
+    mode: warn
+    required_resource_attributes: [service.name, deployment.environment]
+  - name: http-durations
+    match: "http.*.duration"
+    signals: [Metric]
+    allowed_metric_types: [Histogram, ExponentialHistogram]
+  - name: cardinality
+    max_attributes: 2
+`
+
+func TestParsePolicies(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	require.NoError(t, err)
+	require.Len(t, set.Policies, 4)
+	assert.Equal(t, PolicyModeReject, set.Policies[0].Mode)
+	// Policies default to audit
+	assert.Equal(t, PolicyModeAudit, set.Policies[2].Mode)
+	assert.Equal(t, []MetricType{MetricTypeHistogram, MetricTypeExponentialHistogram}, set.Policies[2].AllowedMetricTypes)
+}
+
+func TestParsePolicies_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"missing name":   "policies:\n  - max_attributes: 1\n",
+		"duplicate name": "policies:\n  - name: a\n    max_attributes: 1\n  - name: a\n    max_attributes: 2\n",
+		"unknown mode":   "policies:\n  - name: a\n    mode: block\n    max_attributes: 1\n",
+		"no rules":       "policies:\n  - name: a\n    mode: warn\n",
+		"bad pattern":    "policies:\n  - name: a\n    forbidden_attributes: [\"[\"]\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParsePolicies([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPolicySet_Evaluate(t *testing.T) {
+	set, err := ParsePolicies([]byte(testPolicies))
+	require.NoError(t, err)
+
+	telemetries := []Telemetry{
+		{
+			SchemaID:      "s1",
+			SchemaKey:     "http.server.duration",
+			TelemetryType: TelemetryTypeMetric,
+			MetricType:    MetricTypeSum,
+			Attributes: []Attribute{
+				{Name: "service.name", Source: AttributeSourceResource},
+				{Name: "http.method", Source: AttributeSourceDataPoint},
+				{Name: "http.route", Source: AttributeSourceDataPoint},
+				{Name: "user.email", Source: AttributeSourceDataPoint},
+			},
+		},
+		{
+			SchemaID:      "s2",
+			SchemaKey:     "db.query",
+			TelemetryType: TelemetryTypeSpan,
+			Attributes: []Attribute{
+				{Name: "service.name", Source: AttributeSourceResource},
+				{Name: "deployment.environment", Source: AttributeSourceResource},
+				{Name: "db.system", Source: AttributeSourceSpan},
+			},
+		},
+	}
+
+	violations := set.Evaluate(telemetries)
+	require.Len(t, violations, 4)
+
+	byRule := make(map[string]PolicyViolation)
+	for _, v := range violations {
+		assert.Equal(t, "http.server.duration", v.SchemaKey)
+		assert.Equal(t, "s1", v.SchemaID)
+		byRule[v.Rule] = v
+	}
+
+	assert.Equal(t, "no-pii", byRule[PolicyRuleForbiddenAttribute].Policy)
+	assert.Equal(t, PolicyModeReject, byRule[PolicyRuleForbiddenAttribute].Mode)
+	assert.Equal(t, "datapoint attribute user.email is forbidden", byRule[PolicyRuleForbiddenAttribute].Message)
+	assert.Equal(t, "missing required resource attributes deployment.environment", byRule[PolicyRuleRequiredResourceAttribute].Message)
+	assert.Equal(t, "metric type Sum is not allowed", byRule[PolicyRuleAllowedMetricType].Message)
+	assert.Equal(t, "3 attributes exceed the maximum of 2", byRule[PolicyRuleMaxAttributes].Message)
+}
+
+func TestPolicySet_EvaluateDeny(t *testing.T) {
+	set, err := ParsePolicies([]byte(`
+policies:
+  - name: rejected
+    mode: drop
+    match: legacy.*
+    deny: true
+`))
+	require.NoError(t, err)
+
+	violations := set.Evaluate([]Telemetry{
+		{SchemaID: "s1", SchemaKey: "legacy.requests", TelemetryType: TelemetryTypeMetric},
+		{SchemaID: "s2", SchemaKey: "http.server.duration", TelemetryType: TelemetryTypeMetric},
+	})
+	require.Len(t, violations, 1)
+	assert.Equal(t, "legacy.requests", violations[0].SchemaKey)
+	assert.Equal(t, PolicyRuleDeny, violations[0].Rule)
+	assert.Equal(t, PolicyModeDrop, violations[0].Mode)
+}
+
+func TestPolicySet_EvaluateNil(t *testing.T) {
+	var set *PolicySet
+	assert.Empty(t, set.Evaluate([]Telemetry{{SchemaKey: "a"}}))
+}
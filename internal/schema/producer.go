@@ -2,19 +2,59 @@ package schema
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 )
 
+// Producer is the service emitting telemetry, identified by its service name, namespace and
+// version. Its instances come and go with every restart, so they are a detail of the producer.
 type Producer struct {
-	Name       string    `json:"name"`
-	Namespace  string    `json:"namespace,omitempty"`
-	Version    string    `json:"version,omitempty"`
-	InstanceID string    `json:"instanceId,omitempty"`
-	FirstSeen  time.Time `json:"firstSeen"`
-	LastSeen   time.Time `json:"lastSeen"`
+	ID        string             `json:"id"`
+	Name      string             `json:"name"`
+	Namespace string             `json:"namespace,omitempty"`
+	Version   string             `json:"version,omitempty"`
+	Instances []ProducerInstance `json:"instances,omitempty"`
+	FirstSeen time.Time          `json:"firstSeen"`
+	LastSeen  time.Time          `json:"lastSeen"`
+}
+
+// ProducerInstance is an instance of a producer, identified by its service.instance.id
+type ProducerInstance struct {
+	ID        string    `json:"id"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// DetectProducer extracts the producer from resource attributes.
+// It returns nil when the resource has no service.name.
+func DetectProducer(resourceAttributes pcommon.Map) *Producer {
+	name, ok := resourceAttributes.Get("service.name")
+	if !ok || name.AsString() == "" {
+		return nil
+	}
+
+	now := time.Now()
+	producer := &Producer{
+		Name:      name.AsString(),
+		FirstSeen: now,
+		LastSeen:  now,
+	}
+	if namespace, ok := resourceAttributes.Get("service.namespace"); ok {
+		producer.Namespace = namespace.AsString()
+	}
+	if version, ok := resourceAttributes.Get("service.version"); ok {
+		producer.Version = version.AsString()
+	}
+	if instanceID, ok := resourceAttributes.Get("service.instance.id"); ok && instanceID.AsString() != "" {
+		producer.Instances = []ProducerInstance{{ID: instanceID.AsString(), FirstSeen: now, LastSeen: now}}
+	}
+	producer.ID = producer.ProducerID()
+
+	return producer
 }
 
 // ProducerID generates a unique ID for a producer using xxhash
@@ -23,7 +63,6 @@ func (p *Producer) ProducerID() string {
 		p.Name,
 		p.Namespace,
 		p.Version,
-		p.InstanceID,
 	}
 
 	// Filter out empty parts
@@ -38,3 +77,46 @@ func (p *Producer) ProducerID() string {
 	h.Write([]byte(strings.Join(nonEmptyParts, "|")))
 	return fmt.Sprintf("%x", h.Sum64())
 }
+
+// ProducerRekey is a producer stored under other IDs than its own, as the producers stored by
+// releases keying them per instance
+type ProducerRekey struct {
+	Producer    Producer
+	PreviousIDs []string
+}
+
+// RekeyProducers groups the producers by the ID id gives them, and returns the groups stored under
+// other IDs, merged into a single producer. Instances are left to the caller to move.
+func RekeyProducers(producers []Producer, id func(Producer) string) []ProducerRekey {
+	groups := make(map[string][]Producer)
+	for _, producer := range producers {
+		newID := id(producer)
+		groups[newID] = append(groups[newID], producer)
+	}
+
+	var rekeys []ProducerRekey
+	for newID, group := range groups {
+		merged := group[0]
+		merged.ID = newID
+		merged.Instances = nil
+		var previousIDs []string
+		for _, producer := range group {
+			if producer.FirstSeen.Before(merged.FirstSeen) {
+				merged.FirstSeen = producer.FirstSeen
+			}
+			if producer.LastSeen.After(merged.LastSeen) {
+				merged.LastSeen = producer.LastSeen
+			}
+			if producer.ID != newID {
+				previousIDs = append(previousIDs, producer.ID)
+			}
+		}
+		if len(previousIDs) == 0 {
+			continue
+		}
+		sort.Strings(previousIDs)
+		rekeys = append(rekeys, ProducerRekey{Producer: merged, PreviousIDs: previousIDs})
+	}
+	sort.Slice(rekeys, func(i, j int) bool { return rekeys[i].Producer.ID < rekeys[j].Producer.ID })
+	return rekeys
+}
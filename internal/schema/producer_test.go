@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestDetectProducer(t *testing.T) {
+	attrs := pcommon.NewMap()
+	attrs.PutStr("service.name", "checkout")
+	attrs.PutStr("service.namespace", "shop")
+	attrs.PutStr("service.version", "1.2.0")
+	attrs.PutStr("service.instance.id", "pod-1")
+
+	producer := DetectProducer(attrs)
+	require.NotNil(t, producer)
+	assert.Equal(t, "checkout", producer.Name)
+	assert.Equal(t, "shop", producer.Namespace)
+	assert.Equal(t, "1.2.0", producer.Version)
+	require.Len(t, producer.Instances, 1)
+	assert.Equal(t, "pod-1", producer.Instances[0].ID)
+	assert.Equal(t, producer.ProducerID(), producer.ID)
+
+	// Restarting the service yields a new instance of the same producer
+	attrs.PutStr("service.instance.id", "pod-2")
+	restarted := DetectProducer(attrs)
+	require.NotNil(t, restarted)
+	assert.Equal(t, producer.ID, restarted.ID)
+	assert.Equal(t, "pod-2", restarted.Instances[0].ID)
+
+	assert.Nil(t, DetectProducer(pcommon.NewMap()))
+}
+
+func TestRekeyProducers(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	checkout := (&Producer{Name: "checkout", Version: "1.0.0"}).ProducerID()
+	producers := []Producer{
+		{ID: "pod-1", Name: "checkout", Version: "1.0.0", FirstSeen: t0, LastSeen: t0.Add(time.Hour)},
+		{ID: "pod-2", Name: "checkout", Version: "1.0.0", FirstSeen: t0.Add(time.Hour), LastSeen: t0.Add(2 * time.Hour)},
+		{ID: checkout, Name: "checkout", Version: "1.0.0", FirstSeen: t0.Add(-time.Hour), LastSeen: t0},
+		{ID: (&Producer{Name: "payments"}).ProducerID(), Name: "payments", FirstSeen: t0, LastSeen: t0},
+	}
+
+	rekeys := RekeyProducers(producers, func(p Producer) string { return p.ProducerID() })
+	require.Len(t, rekeys, 1)
+	assert.Equal(t, checkout, rekeys[0].Producer.ID)
+	assert.Equal(t, []string{"pod-1", "pod-2"}, rekeys[0].PreviousIDs)
+	assert.Equal(t, t0.Add(-time.Hour), rekeys[0].Producer.FirstSeen)
+	assert.Equal(t, t0.Add(2*time.Hour), rekeys[0].Producer.LastSeen)
+
+	assert.Empty(t, RekeyProducers(producers[2:], func(p Producer) string { return p.ProducerID() }))
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrSchemaNotStale is returned when removing a schema that the retention job has not marked stale
+var ErrSchemaNotStale = errors.New("schema is not stale")
+
+// RetentionAction is what happens to a schema once it expires
+type RetentionAction string
+
+const (
+	// RetentionActionDelete removes the schema
+	RetentionActionDelete RetentionAction = "delete"
+	// RetentionActionArchive stores a snapshot of the schema in the archive before removing it
+	RetentionActionArchive RetentionAction = "archive"
+)
+
+// RetentionTTL controls when a schema not seen anymore becomes stale and when it expires.
+// A zero DeleteAfter keeps stale schemas forever.
+type RetentionTTL struct {
+	StaleAfter  time.Duration `yaml:"stale_after,omitempty" json:"staleAfter"`
+	DeleteAfter time.Duration `yaml:"delete_after,omitempty" json:"deleteAfter,omitempty"`
+}
+
+// RetentionPolicy configures the retention job, with TTLs overridable per signal type
+type RetentionPolicy struct {
+	// Interval is how often the retention job runs
+	Interval time.Duration                  `yaml:"interval,omitempty" json:"interval"`
+	Action   RetentionAction                `yaml:"action,omitempty" json:"action"`
+	Default  RetentionTTL                   `yaml:"default,omitempty" json:"default"`
+	Signals  map[TelemetryType]RetentionTTL `yaml:"signals,omitempty" json:"signals,omitempty"`
+}
+
+// DefaultRetentionPolicy marks schemas not seen for a week as stale, hourly, and never deletes them
+func DefaultRetentionPolicy() *RetentionPolicy {
+	return &RetentionPolicy{
+		Interval: time.Hour,
+		Action:   RetentionActionDelete,
+		Default:  RetentionTTL{StaleAfter: 7 * 24 * time.Hour},
+	}
+}
+
+// StaleTelemetry is a schema that has not been seen for longer than the stale TTL of its signal
+type StaleTelemetry struct {
+	SchemaID      string        `json:"schemaId"`
+	SchemaKey     string        `json:"schemaKey"`
+	TelemetryType TelemetryType `json:"telemetryType"`
+	LastSeen      time.Time     `json:"lastSeen"`
+	StaleSince    time.Time     `json:"staleSince"`
+	// ExpiresAt is when the retention job removes the schema, unset when it is kept forever
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// RetentionResult summarizes a run of the retention job
+type RetentionResult struct {
+	MarkedStale      int `json:"markedStale"`
+	Archived         int `json:"archived"`
+	DeletedSchemas   int `json:"deletedSchemas"`
+	DeletedEntities  int `json:"deletedEntities"`
+	DeletedScopes    int `json:"deletedScopes"`
+	DeletedProducers int `json:"deletedProducers"`
+}
+
+// LoadRetentionPolicy reads a retention policy from a YAML file
+func LoadRetentionPolicy(path string) (*RetentionPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policy %s: %w", path, err)
+	}
+	return ParseRetentionPolicy(data)
+}
+
+// ParseRetentionPolicy parses and validates a retention policy from YAML content.
+// Fields left unset keep the values of DefaultRetentionPolicy.
+func ParseRetentionPolicy(data []byte) (*RetentionPolicy, error) {
+	policy := DefaultRetentionPolicy()
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to parse retention policy: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks the action, the signal types and that schemas become stale before they expire.
+// Signal TTLs left unset inherit the default ones.
+func (p *RetentionPolicy) Validate() error {
+	if p.Interval <= 0 {
+		return fmt.Errorf("retention interval must be positive")
+	}
+	switch p.Action {
+	case "":
+		p.Action = RetentionActionDelete
+	case RetentionActionDelete, RetentionActionArchive:
+	default:
+		return fmt.Errorf("invalid retention action %q, expected delete or archive", p.Action)
+	}
+
+	if err := p.Default.validate(); err != nil {
+		return fmt.Errorf("default retention: %w", err)
+	}
+
+	signals := make(map[TelemetryType]RetentionTTL, len(p.Signals))
+	for signal, ttl := range p.Signals {
+		telemetryType, ok := ParseTelemetryType(string(signal))
+		if !ok {
+			return fmt.Errorf("invalid signal type %q, expected Metric, Log, Span or Profile", signal)
+		}
+		if ttl.StaleAfter == 0 {
+			ttl.StaleAfter = p.Default.StaleAfter
+		}
+		if ttl.DeleteAfter == 0 {
+			ttl.DeleteAfter = p.Default.DeleteAfter
+		}
+		if err := ttl.validate(); err != nil {
+			return fmt.Errorf("%s retention: %w", telemetryType, err)
+		}
+		signals[telemetryType] = ttl
+	}
+	p.Signals = signals
+
+	return nil
+}
+
+func (t RetentionTTL) validate() error {
+	if t.StaleAfter <= 0 {
+		return fmt.Errorf("stale_after must be positive")
+	}
+	if t.DeleteAfter < 0 {
+		return fmt.Errorf("delete_after cannot be negative")
+	}
+	if t.DeleteAfter > 0 && t.DeleteAfter < t.StaleAfter {
+		return fmt.Errorf("delete_after must not be shorter than stale_after")
+	}
+	return nil
+}
+
+// TTL returns the TTLs of a signal type
+func (p *RetentionPolicy) TTL(telemetryType TelemetryType) RetentionTTL {
+	if ttl, ok := p.Signals[telemetryType]; ok {
+		return ttl
+	}
+	return p.Default
+}
+
+// ParseTelemetryType parses a signal type case-insensitively
+func ParseTelemetryType(s string) (TelemetryType, bool) {
+	for _, telemetryType := range []TelemetryType{TelemetryTypeMetric, TelemetryTypeLog, TelemetryTypeSpan, TelemetryTypeProfile} {
+		if strings.EqualFold(s, string(telemetryType)) {
+			return telemetryType, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+package schema
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	policy, err := ParseRetentionPolicy([]byte(`
+action: archive
+default:
+  stale_after: 168h
+  delete_after: 720h
+signals:
+  log:
+    stale_after: 24h
+  Profile:
+    delete_after: 2160h
+`))
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, policy.Interval)
+	assert.Equal(t, RetentionActionArchive, policy.Action)
+	assert.Equal(t, RetentionTTL{StaleAfter: 24 * time.Hour, DeleteAfter: 720 * time.Hour}, policy.TTL(TelemetryTypeLog))
+	assert.Equal(t, RetentionTTL{StaleAfter: 168 * time.Hour, DeleteAfter: 2160 * time.Hour}, policy.TTL(TelemetryTypeProfile))
+	assert.Equal(t, policy.Default, policy.TTL(TelemetryTypeMetric))
+}
+
+func TestParseRetentionPolicy_Defaults(t *testing.T) {
+	policy, err := ParseRetentionPolicy([]byte(`{}`))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultRetentionPolicy().Default, policy.Default)
+	assert.Equal(t, RetentionActionDelete, policy.Action)
+	assert.Zero(t, policy.TTL(TelemetryTypeSpan).DeleteAfter)
+}
+
+func TestParseRetentionPolicy_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr string
+	}{
+		{"bad action", "action: purge\n", "invalid retention action"},
+		{"bad interval", "interval: 0s\n", "interval must be positive"},
+		{"delete before stale", "default: {stale_after: 48h, delete_after: 24h}\n", "delete_after must not be shorter"},
+		{"negative delete", "default: {delete_after: -1h}\n", "cannot be negative"},
+		{"bad signal", "signals: {traces: {stale_after: 1h}}\n", "invalid signal type"},
+		{"bad duration", "default: {stale_after: 7d}\n", "failed to parse retention policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRetentionPolicy([]byte(tt.policy))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrReviewNotFound is returned when deciding on an unknown review
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrReviewDecided is returned when deciding on a review that is no longer pending
+	ErrReviewDecided = errors.New("review already decided")
+	// ErrInvalidReviewDecision is returned for a decision that cannot apply to the review
+	ErrInvalidReviewDecision = errors.New("invalid review decision")
+)
+
+// ReviewState is the state of a telemetry in the review queue
+type ReviewState string
+
+const (
+	ReviewStatePending  ReviewState = "pending"
+	ReviewStateApproved ReviewState = "approved"
+	ReviewStateRejected ReviewState = "rejected"
+	// ReviewStateMerged is a schema key merged into another key, which telemetry sent under it joins
+	ReviewStateMerged ReviewState = "merged"
+)
+
+// ParseReviewState parses a review state
+func ParseReviewState(s string) (ReviewState, error) {
+	switch state := ReviewState(s); state {
+	case ReviewStatePending, ReviewStateApproved, ReviewStateRejected, ReviewStateMerged:
+		return state, nil
+	}
+	return "", fmt.Errorf("invalid review state %q, expected pending, approved, rejected or merged", s)
+}
+
+// ReviewReason is why a telemetry entered the review queue
+type ReviewReason string
+
+const (
+	// ReviewReasonNewKey is a schema key observed for the first time
+	ReviewReasonNewKey ReviewReason = "new_key"
+	// ReviewReasonNewVariant is a new variant of an approved schema key
+	ReviewReasonNewVariant ReviewReason = "new_variant"
+)
+
+// ReviewAction is the decision of a reviewer
+type ReviewAction string
+
+const (
+	ReviewActionApprove ReviewAction = "approve"
+	ReviewActionReject  ReviewAction = "reject"
+	ReviewActionMerge   ReviewAction = "merge"
+)
+
+// RejectAction is the policy created when rejecting a telemetry
+type RejectAction string
+
+const (
+	// RejectActionNone only records the rejection
+	RejectActionNone RejectAction = ""
+	// RejectActionDrop leaves the schema key out of the catalog from then on
+	RejectActionDrop RejectAction = "drop"
+	// RejectActionFlag records a violation and warns the sender every time the schema key is sent
+	RejectActionFlag RejectAction = "flag"
+)
+
+// TelemetryReview is a newly observed schema key, or a new variant of an approved one, waiting for a
+// reviewer, and the decision taken on it
+type TelemetryReview struct {
+	ID            int64         `json:"id"`
+	SchemaKey     string        `json:"schemaKey"`
+	SchemaID      string        `json:"schemaId"`
+	TelemetryType TelemetryType `json:"telemetryType"`
+	Reason        ReviewReason  `json:"reason"`
+	State         ReviewState   `json:"state"`
+	DetectedAt    time.Time     `json:"detectedAt"`
+	// Decision fields, set once the review is no longer pending
+	Reviewer     string       `json:"reviewer,omitempty"`
+	Comment      string       `json:"comment,omitempty"`
+	RejectAction RejectAction `json:"rejectAction,omitempty"`
+	MergedInto   string       `json:"mergedInto,omitempty"`
+	DecidedAt    *time.Time   `json:"decidedAt,omitempty"`
+}
+
+// ReviewDecision is a reviewer approving, rejecting or merging a pending review
+type ReviewDecision struct {
+	Action  ReviewAction `json:"action"`
+	Author  string       `json:"author"`
+	Comment string       `json:"comment,omitempty"`
+	// RejectAction optionally creates a policy when rejecting
+	RejectAction RejectAction `json:"rejectAction,omitempty"`
+	// MergeInto is the existing schema key to merge into
+	MergeInto string `json:"mergeInto,omitempty"`
+}
+
+// ReviewNotification records a telemetry entering the review queue
+type ReviewNotification struct {
+	ID        int64        `json:"id"`
+	ReviewID  int64        `json:"reviewId"`
+	SchemaKey string       `json:"schemaKey"`
+	Reason    ReviewReason `json:"reason"`
+	Message   string       `json:"message"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+// Validate checks the decision is complete and can apply to the review
+func (d *ReviewDecision) Validate(review *TelemetryReview) error {
+	d.Author = strings.TrimSpace(d.Author)
+	if d.Author == "" {
+		return fmt.Errorf("%w: author is required", ErrInvalidReviewDecision)
+	}
+
+	switch d.Action {
+	case ReviewActionApprove:
+	case ReviewActionReject:
+		switch d.RejectAction {
+		case RejectActionNone:
+		case RejectActionDrop, RejectActionFlag:
+			// The policy covers the whole schema key, which must not drop or flag approved variants
+			if review.Reason != ReviewReasonNewKey {
+				return fmt.Errorf("%w: a policy can only be created when rejecting a new schema key", ErrInvalidReviewDecision)
+			}
+		default:
+			return fmt.Errorf("%w: invalid reject action %q, expected drop or flag", ErrInvalidReviewDecision, d.RejectAction)
+		}
+	case ReviewActionMerge:
+		d.MergeInto = strings.TrimSpace(d.MergeInto)
+		switch {
+		case review.Reason != ReviewReasonNewKey:
+			return fmt.Errorf("%w: only new schema keys can be merged", ErrInvalidReviewDecision)
+		case d.MergeInto == "":
+			return fmt.Errorf("%w: mergeInto is required", ErrInvalidReviewDecision)
+		case d.MergeInto == review.SchemaKey:
+			return fmt.Errorf("%w: cannot merge %s into itself", ErrInvalidReviewDecision, review.SchemaKey)
+		}
+	default:
+		return fmt.Errorf("%w: invalid action %q, expected approve, reject or merge", ErrInvalidReviewDecision, d.Action)
+	}
+
+	if d.Action != ReviewActionReject && d.RejectAction != RejectActionNone {
+		return fmt.Errorf("%w: rejectAction only applies when rejecting", ErrInvalidReviewDecision)
+	}
+	if d.Action != ReviewActionMerge && d.MergeInto != "" {
+		return fmt.Errorf("%w: mergeInto only applies when merging", ErrInvalidReviewDecision)
+	}
+	return nil
+}
+
+// State is the review state the decision leads to
+func (d *ReviewDecision) State() ReviewState {
+	switch d.Action {
+	case ReviewActionApprove:
+		return ReviewStateApproved
+	case ReviewActionReject:
+		return ReviewStateRejected
+	default:
+		return ReviewStateMerged
+	}
+}
+
+// Summary describes the decision in the telemetry history
+func (d *ReviewDecision) Summary(review *TelemetryReview) string {
+	subject := "new schema key " + review.SchemaKey
+	if review.Reason == ReviewReasonNewVariant {
+		subject = fmt.Sprintf("schema %s of %s", review.SchemaID, review.SchemaKey)
+	}
+
+	var summary string
+	switch d.Action {
+	case ReviewActionApprove:
+		summary = "Approved " + subject
+	case ReviewActionReject:
+		summary = "Rejected " + subject
+		if d.RejectAction != RejectActionNone {
+			summary += fmt.Sprintf(" (%s)", d.RejectAction)
+		}
+	case ReviewActionMerge:
+		summary = fmt.Sprintf("Merged %s into %s", subject, d.MergeInto)
+	}
+	if d.Comment != "" {
+		summary += ": " + d.Comment
+	}
+	return summary
+}
+
+// Message describes the review for its notification
+func (r *TelemetryReview) Message() string {
+	if r.Reason == ReviewReasonNewVariant {
+		return fmt.Sprintf("New variant %s of %s %s is waiting for review", r.SchemaID, strings.ToLower(string(r.TelemetryType)), r.SchemaKey)
+	}
+	return fmt.Sprintf("New %s %s is waiting for review", strings.ToLower(string(r.TelemetryType)), r.SchemaKey)
+}
+
+// Policy returns the policy created by rejecting the review with a drop or flag action
+func (r *TelemetryReview) Policy() (Policy, bool) {
+	if r.State != ReviewStateRejected || r.RejectAction == RejectActionNone {
+		return Policy{}, false
+	}
+
+	mode := PolicyModeWarn
+	if r.RejectAction == RejectActionDrop {
+		mode = PolicyModeDrop
+	}
+	return Policy{
+		Name:        fmt.Sprintf("review-%d", r.ID),
+		Description: fmt.Sprintf("%s rejected in review by %s", r.SchemaKey, r.Reviewer),
+		Mode:        mode,
+		Match:       escapeGlob(r.SchemaKey),
+		Signals:     []TelemetryType{r.TelemetryType},
+		Deny:        true,
+	}, true
+}
+
+// escapeGlob escapes the meta characters of a schema key so it is matched literally
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
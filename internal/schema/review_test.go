@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewDecision_Validate(t *testing.T) {
+	newKey := &TelemetryReview{SchemaKey: "checkout.requests", Reason: ReviewReasonNewKey}
+	newVariant := &TelemetryReview{SchemaKey: "checkout.requests", Reason: ReviewReasonNewVariant}
+
+	tests := []struct {
+		name     string
+		review   *TelemetryReview
+		decision ReviewDecision
+		wantErr  string
+	}{
+		{name: "approve", review: newVariant, decision: ReviewDecision{Action: ReviewActionApprove, Author: "alice"}},
+		{name: "reject with drop", review: newKey, decision: ReviewDecision{Action: ReviewActionReject, Author: "alice", RejectAction: RejectActionDrop}},
+		{name: "merge", review: newKey, decision: ReviewDecision{Action: ReviewActionMerge, Author: "alice", MergeInto: "checkout.request_count"}},
+		{name: "author required", review: newKey, decision: ReviewDecision{Action: ReviewActionApprove, Author: " "}, wantErr: "author is required"},
+		{name: "unknown action", review: newKey, decision: ReviewDecision{Action: "ignore", Author: "alice"}, wantErr: "invalid action"},
+		{name: "unknown reject action", review: newKey, decision: ReviewDecision{Action: ReviewActionReject, Author: "alice", RejectAction: "delete"}, wantErr: "invalid reject action"},
+		{name: "policy on a variant", review: newVariant, decision: ReviewDecision{Action: ReviewActionReject, Author: "alice", RejectAction: RejectActionFlag}, wantErr: "only be created when rejecting a new schema key"},
+		{name: "merge a variant", review: newVariant, decision: ReviewDecision{Action: ReviewActionMerge, Author: "alice", MergeInto: "other"}, wantErr: "only new schema keys"},
+		{name: "merge into itself", review: newKey, decision: ReviewDecision{Action: ReviewActionMerge, Author: "alice", MergeInto: "checkout.requests"}, wantErr: "into itself"},
+		{name: "reject action when approving", review: newKey, decision: ReviewDecision{Action: ReviewActionApprove, Author: "alice", RejectAction: RejectActionDrop}, wantErr: "only applies when rejecting"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.decision.Validate(tt.review)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidReviewDecision)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTelemetryReview_Policy(t *testing.T) {
+	review := TelemetryReview{
+		ID:            7,
+		SchemaKey:     "debug.requests[0]",
+		TelemetryType: TelemetryTypeMetric,
+		State:         ReviewStateRejected,
+		Reviewer:      "alice",
+		RejectAction:  RejectActionFlag,
+	}
+
+	policy, ok := review.Policy()
+	require.True(t, ok)
+	assert.Equal(t, "review-7", policy.Name)
+	assert.Equal(t, PolicyModeWarn, policy.Mode)
+
+	set := &PolicySet{Policies: []Policy{policy}}
+	require.NoError(t, set.Validate())
+	violations := set.Evaluate([]Telemetry{
+		{SchemaKey: "debug.requests[0]", TelemetryType: TelemetryTypeMetric},
+		{SchemaKey: "debug.requests0", TelemetryType: TelemetryTypeMetric},
+		{SchemaKey: "debug.requests[0]", TelemetryType: TelemetryTypeLog},
+	})
+	require.Len(t, violations, 1)
+	assert.Equal(t, TelemetryTypeMetric, violations[0].TelemetryType)
+
+	review.RejectAction = RejectActionNone
+	_, ok = review.Policy()
+	assert.False(t, ok)
+}
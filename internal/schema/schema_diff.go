@@ -0,0 +1,314 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AttributeTypeChange is an attribute kept between two schema variants whose type changed
+type AttributeTypeChange struct {
+	Name    string          `json:"name"`
+	Source  AttributeSource `json:"source"`
+	OldType AttributeType   `json:"oldType"`
+	NewType AttributeType   `json:"newType"`
+}
+
+// FieldChange is a telemetry field, such as the metric unit, whose value changed between two schema variants
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// SchemaDiff is the structured difference between two schema variants of the same schema key
+type SchemaDiff struct {
+	FromSchemaID      string                `json:"fromSchemaId"`
+	ToSchemaID        string                `json:"toSchemaId"`
+	AddedAttributes   []Attribute           `json:"addedAttributes"`
+	RemovedAttributes []Attribute           `json:"removedAttributes"`
+	ChangedAttributes []AttributeTypeChange `json:"changedAttributes"`
+	FieldChanges      []FieldChange         `json:"fieldChanges"`
+	// Membership changes are only computed when comparing stored schemas, see DiffTelemetrySchemas
+	AddedEntities   []string `json:"addedEntities,omitempty"`
+	RemovedEntities []string `json:"removedEntities,omitempty"`
+	AddedScopes     []string `json:"addedScopes,omitempty"`
+	RemovedScopes   []string `json:"removedScopes,omitempty"`
+}
+
+// IsEmpty reports whether the two variants have no visible difference
+func (d SchemaDiff) IsEmpty() bool {
+	return len(d.AddedAttributes) == 0 &&
+		len(d.RemovedAttributes) == 0 &&
+		len(d.ChangedAttributes) == 0 &&
+		len(d.FieldChanges) == 0 &&
+		len(d.AddedEntities) == 0 &&
+		len(d.RemovedEntities) == 0 &&
+		len(d.AddedScopes) == 0 &&
+		len(d.RemovedScopes) == 0
+}
+
+// Summary describes the diff in a single human readable line
+func (d SchemaDiff) Summary() string {
+	var parts []string
+	if n := len(d.AddedAttributes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s added", n, pluralize("attribute", n)))
+	}
+	if n := len(d.RemovedAttributes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s removed", n, pluralize("attribute", n)))
+	}
+	if n := len(d.ChangedAttributes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s changed type", n, pluralize("attribute", n)))
+	}
+	for _, change := range d.FieldChanges {
+		parts = append(parts, fmt.Sprintf("%s changed from %q to %q", change.Field, change.OldValue, change.NewValue))
+	}
+	if n := len(d.AddedEntities); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s added", n, pluralize("entity", n)))
+	}
+	if n := len(d.RemovedEntities); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s removed", n, pluralize("entity", n)))
+	}
+	if n := len(d.AddedScopes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s added", n, pluralize("scope", n)))
+	}
+	if n := len(d.RemovedScopes); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s removed", n, pluralize("scope", n)))
+	}
+	if len(parts) == 0 {
+		return "New schema variant with no visible changes"
+	}
+	return "Schema changed: " + strings.Join(parts, ", ")
+}
+
+func pluralize(word string, n int) string {
+	if n == 1 {
+		return word
+	}
+	if strings.HasSuffix(word, "y") {
+		return strings.TrimSuffix(word, "y") + "ies"
+	}
+	return word + "s"
+}
+
+// DiffTelemetry compares two variants of the same telemetry. Attributes are matched by name and source.
+func DiffTelemetry(from, to Telemetry) SchemaDiff {
+	diff := SchemaDiff{
+		FromSchemaID:      from.SchemaID,
+		ToSchemaID:        to.SchemaID,
+		AddedAttributes:   []Attribute{},
+		RemovedAttributes: []Attribute{},
+		ChangedAttributes: []AttributeTypeChange{},
+		FieldChanges:      []FieldChange{},
+	}
+
+	type attributeKey struct {
+		name   string
+		source AttributeSource
+	}
+	fromAttributes := make(map[attributeKey]Attribute, len(from.Attributes))
+	for _, attr := range from.Attributes {
+		fromAttributes[attributeKey{attr.Name, attr.Source}] = attr
+	}
+	toAttributes := make(map[attributeKey]Attribute, len(to.Attributes))
+	for _, attr := range to.Attributes {
+		toAttributes[attributeKey{attr.Name, attr.Source}] = attr
+	}
+
+	for key, attr := range toAttributes {
+		previous, ok := fromAttributes[key]
+		if !ok {
+			diff.AddedAttributes = append(diff.AddedAttributes, attr)
+			continue
+		}
+		if previous.Type != attr.Type {
+			diff.ChangedAttributes = append(diff.ChangedAttributes, AttributeTypeChange{
+				Name:    attr.Name,
+				Source:  attr.Source,
+				OldType: previous.Type,
+				NewType: attr.Type,
+			})
+		}
+	}
+	for key, attr := range fromAttributes {
+		if _, ok := toAttributes[key]; !ok {
+			diff.RemovedAttributes = append(diff.RemovedAttributes, attr)
+		}
+	}
+
+	fields := []struct {
+		name     string
+		from, to string
+	}{
+		{"unit", from.MetricUnit, to.MetricUnit},
+		{"metricType", string(from.MetricType), string(to.MetricType)},
+		{"temporality", string(from.MetricTemporality), string(to.MetricTemporality)},
+		{"spanKind", string(from.SpanKind), string(to.SpanKind)},
+		{"logSeverityText", from.LogSeverityText, to.LogSeverityText},
+		{"logEventName", from.LogEventName, to.LogEventName},
+		{"profileSampleUnit", from.ProfileSampleUnit, to.ProfileSampleUnit},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.FieldChanges = append(diff.FieldChanges, FieldChange{
+				Field:    field.name,
+				OldValue: field.from,
+				NewValue: field.to,
+			})
+		}
+	}
+
+	sortAttributes(diff.AddedAttributes)
+	sortAttributes(diff.RemovedAttributes)
+	sort.Slice(diff.ChangedAttributes, func(i, j int) bool {
+		a, b := diff.ChangedAttributes[i], diff.ChangedAttributes[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Source < b.Source
+	})
+
+	return diff
+}
+
+func sortAttributes(attributes []Attribute) {
+	sort.Slice(attributes, func(i, j int) bool {
+		if attributes[i].Name != attributes[j].Name {
+			return attributes[i].Name < attributes[j].Name
+		}
+		return attributes[i].Source < attributes[j].Source
+	})
+}
+
+// DiffTelemetrySchemas compares two stored variants, including the entities and scopes emitting them
+func DiffTelemetrySchemas(from, to *TelemetrySchema) SchemaDiff {
+	diff := DiffTelemetry(from.telemetry(), to.telemetry())
+
+	diff.AddedEntities, diff.RemovedEntities = diffSets(entityIDs(from.Entities), entityIDs(to.Entities))
+	diff.AddedScopes, diff.RemovedScopes = diffSets(scopeLabels(from.Scopes), scopeLabels(to.Scopes))
+
+	return diff
+}
+
+// telemetry returns the fields of the variant that DiffTelemetry compares
+func (s *TelemetrySchema) telemetry() Telemetry {
+	return Telemetry{
+		SchemaID:          s.SchemaId,
+		SchemaKey:         s.SchemaKey,
+		TelemetryType:     s.TelemetryType,
+		MetricUnit:        s.MetricUnit,
+		MetricType:        s.MetricType,
+		MetricTemporality: s.MetricTemporality,
+		SpanKind:          s.SpanKind,
+		LogSeverityText:   s.LogSeverityText,
+		LogEventName:      s.LogEventName,
+		ProfileSampleUnit: s.ProfileSampleUnit,
+		Attributes:        s.Attributes,
+	}
+}
+
+func entityIDs(entities map[string]*Entity) []string {
+	keys := make([]string, 0, len(entities))
+	for id := range entities {
+		keys = append(keys, id)
+	}
+	return keys
+}
+
+// scopeLabels identifies scopes by name and version
+func scopeLabels(scopes []Scope) []string {
+	labels := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		label := scope.Name
+		if scope.Version != "" {
+			label += "@" + scope.Version
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// diffSets returns the sorted values only present in to and only present in from
+func diffSets(from, to []string) (added, removed []string) {
+	fromSet := make(map[string]bool, len(from))
+	for _, v := range from {
+		fromSet[v] = true
+	}
+	toSet := make(map[string]bool, len(to))
+	for _, v := range to {
+		toSet[v] = true
+		if !fromSet[v] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range from {
+		if !toSet[v] {
+			removed = append(removed, v)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+// Markdown renders the diff for pasting into a review
+func (d SchemaDiff) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Schema diff `%s` → `%s`\n\n", d.FromSchemaID, d.ToSchemaID)
+
+	if d.IsEmpty() {
+		b.WriteString("No changes.\n")
+		return b.String()
+	}
+
+	if len(d.FieldChanges) > 0 {
+		b.WriteString("### Metadata\n\n")
+		b.WriteString("| Field | Before | After |\n")
+		b.WriteString("| --- | --- | --- |\n")
+		for _, change := range d.FieldChanges {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", change.Field, markdownValue(change.OldValue), markdownValue(change.NewValue))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.AddedAttributes) > 0 || len(d.RemovedAttributes) > 0 || len(d.ChangedAttributes) > 0 {
+		b.WriteString("### Attributes\n\n")
+		b.WriteString("| Change | Name | Source | Type |\n")
+		b.WriteString("| --- | --- | --- | --- |\n")
+		for _, attr := range d.AddedAttributes {
+			fmt.Fprintf(&b, "| added | `%s` | %s | %s |\n", attr.Name, attr.Source, attr.Type)
+		}
+		for _, attr := range d.RemovedAttributes {
+			fmt.Fprintf(&b, "| removed | `%s` | %s | %s |\n", attr.Name, attr.Source, attr.Type)
+		}
+		for _, change := range d.ChangedAttributes {
+			fmt.Fprintf(&b, "| type changed | `%s` | %s | %s → %s |\n", change.Name, change.Source, change.OldType, change.NewType)
+		}
+		b.WriteString("\n")
+	}
+
+	writeMembership := func(title string, added, removed []string) {
+		if len(added) == 0 && len(removed) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "### %s\n\n", title)
+		for _, v := range added {
+			fmt.Fprintf(&b, "- added `%s`\n", v)
+		}
+		for _, v := range removed {
+			fmt.Fprintf(&b, "- removed `%s`\n", v)
+		}
+		b.WriteString("\n")
+	}
+	writeMembership("Entities", d.AddedEntities, d.RemovedEntities)
+	writeMembership("Scopes", d.AddedScopes, d.RemovedScopes)
+
+	return b.String()
+}
+
+func markdownValue(v string) string {
+	if v == "" {
+		return "_none_"
+	}
+	return "`" + v + "`"
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffTelemetry(t *testing.T) {
+	from := Telemetry{
+		SchemaID:          "v1",
+		SchemaKey:         "http.server.duration",
+		MetricUnit:        "ms",
+		MetricType:        MetricTypeHistogram,
+		MetricTemporality: MetricTemporalityCumulative,
+		Attributes: []Attribute{
+			{Name: "http.method", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+			{Name: "http.status_code", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+			{Name: "net.host.name", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+		},
+	}
+	to := Telemetry{
+		SchemaID:          "v2",
+		SchemaKey:         "http.server.duration",
+		MetricUnit:        "s",
+		MetricType:        MetricTypeHistogram,
+		MetricTemporality: MetricTemporalityDelta,
+		Attributes: []Attribute{
+			{Name: "http.method", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+			{Name: "http.status_code", Type: AttributeTypeInt, Source: AttributeSourceDataPoint},
+			{Name: "server.address", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+		},
+	}
+
+	diff := DiffTelemetry(from, to)
+	assert.Equal(t, "v1", diff.FromSchemaID)
+	assert.Equal(t, "v2", diff.ToSchemaID)
+	assert.False(t, diff.IsEmpty())
+
+	require.Len(t, diff.AddedAttributes, 1)
+	assert.Equal(t, "server.address", diff.AddedAttributes[0].Name)
+	require.Len(t, diff.RemovedAttributes, 1)
+	assert.Equal(t, "net.host.name", diff.RemovedAttributes[0].Name)
+	require.Len(t, diff.ChangedAttributes, 1)
+	assert.Equal(t, AttributeTypeChange{
+		Name:    "http.status_code",
+		Source:  AttributeSourceDataPoint,
+		OldType: AttributeTypeStr,
+		NewType: AttributeTypeInt,
+	}, diff.ChangedAttributes[0])
+
+	assert.Equal(t, []FieldChange{
+		{Field: "unit", OldValue: "ms", NewValue: "s"},
+		{Field: "temporality", OldValue: "Cumulative", NewValue: "Delta"},
+	}, diff.FieldChanges)
+
+	assert.Equal(t,
+		`Schema changed: 1 attribute added, 1 attribute removed, 1 attribute changed type, unit changed from "ms" to "s", temporality changed from "Cumulative" to "Delta"`,
+		diff.Summary())
+}
+
+func TestDiffTelemetry_SameNameDifferentSource(t *testing.T) {
+	from := Telemetry{Attributes: []Attribute{
+		{Name: "service.name", Type: AttributeTypeStr, Source: AttributeSourceResource},
+	}}
+	to := Telemetry{Attributes: []Attribute{
+		{Name: "service.name", Type: AttributeTypeStr, Source: AttributeSourceDataPoint},
+	}}
+
+	diff := DiffTelemetry(from, to)
+	require.Len(t, diff.AddedAttributes, 1)
+	assert.Equal(t, AttributeSourceDataPoint, diff.AddedAttributes[0].Source)
+	require.Len(t, diff.RemovedAttributes, 1)
+	assert.Equal(t, AttributeSourceResource, diff.RemovedAttributes[0].Source)
+}
+
+func TestDiffTelemetry_Empty(t *testing.T) {
+	diff := DiffTelemetry(Telemetry{SchemaID: "a"}, Telemetry{SchemaID: "b"})
+	assert.True(t, diff.IsEmpty())
+	assert.Equal(t, "New schema variant with no visible changes", diff.Summary())
+}
+
+func TestDiffTelemetrySchemas_Membership(t *testing.T) {
+	from := &TelemetrySchema{
+		SchemaId: "v1",
+		Entities: map[string]*Entity{"a": {ID: "a"}, "b": {ID: "b"}},
+		Scopes:   []Scope{{Name: "otelhttp"}},
+	}
+	to := &TelemetrySchema{
+		SchemaId: "v2",
+		Entities: map[string]*Entity{"b": {ID: "b"}, "c": {ID: "c"}},
+		Scopes:   []Scope{{Name: "otelhttp"}},
+	}
+
+	diff := DiffTelemetrySchemas(from, to)
+	assert.Equal(t, []string{"c"}, diff.AddedEntities)
+	assert.Equal(t, []string{"a"}, diff.RemovedEntities)
+	assert.Empty(t, diff.AddedScopes)
+	assert.Empty(t, diff.RemovedScopes)
+	assert.Equal(t, "Schema changed: 1 entity added, 1 entity removed", diff.Summary())
+
+	markdown := diff.Markdown()
+	assert.Contains(t, markdown, "### Entities")
+	assert.Contains(t, markdown, "- removed `a`")
+	assert.NotContains(t, markdown, "### Attributes")
+}
@@ -52,8 +52,95 @@ func generateMetricSchemaID(telemetry Telemetry) string {
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
-func ExtractFromMetrics(metrics pmetric.Metrics) []Telemetry {
+// Rough wire size of the fixed fields of a metric data point, log record, span and profile sample,
+// added to the size of their attributes to estimate the bytes received
+const (
+	dataPointOverheadBytes     = 16
+	logRecordOverheadBytes     = 24
+	spanOverheadBytes          = 56
+	profileSampleOverheadBytes = 16
+)
+
+// attributesSize estimates the bytes of the keys and values of attributes
+func attributesSize(attributes pcommon.Map) int64 {
+	var size int64
+	attributes.Range(func(key string, value pcommon.Value) bool {
+		size += int64(len(key) + len(value.AsString()))
+		return true
+	})
+	return size
+}
+
+// mergeTelemetry adds a telemetry to the ones extracted so far, accumulating the counts and
+// entities of a schema seen several times in the same batch
+func mergeTelemetry(telemetries map[string]Telemetry, telemetry Telemetry) {
+	existing, ok := telemetries[telemetry.SchemaID]
+	if !ok {
+		telemetries[telemetry.SchemaID] = telemetry
+		return
+	}
+
+	existing.SeenCount += telemetry.SeenCount
+	existing.DataPointCount += telemetry.DataPointCount
+	existing.EstimatedBytes += telemetry.EstimatedBytes
+	for id, entity := range telemetry.Entities {
+		if _, ok := existing.Entities[id]; !ok {
+			existing.Entities[id] = entity
+		}
+	}
+	telemetries[telemetry.SchemaID] = existing
+}
+
+// generateSeriesID identifies a metric series by the metric name and its resource and data point attribute values
+func generateSeriesID(metricName string, resourceAttributes, dataPointAttributes pcommon.Map) string {
+	parts := []string{metricName}
+	for _, attributes := range []pcommon.Map{resourceAttributes, dataPointAttributes} {
+		pairs := make([]string, 0, attributes.Len())
+		attributes.Range(func(key string, value pcommon.Value) bool {
+			pairs = append(pairs, key+"="+value.AsString())
+			return true
+		})
+		sort.Strings(pairs)
+		parts = append(parts, strings.Join(pairs, ","))
+	}
+
+	h := xxhash.New()
+	h.Write([]byte(strings.Join(parts, "|")))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// dataPointAttributes returns the attributes of every data point of a metric
+func dataPointAttributes(metric pmetric.Metric) []pcommon.Map {
+	var attributes []pcommon.Map
+	switch metric.Type() {
+	case pmetric.MetricTypeGauge:
+		for i := range metric.Gauge().DataPoints().Len() {
+			attributes = append(attributes, metric.Gauge().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSum:
+		for i := range metric.Sum().DataPoints().Len() {
+			attributes = append(attributes, metric.Sum().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeHistogram:
+		for i := range metric.Histogram().DataPoints().Len() {
+			attributes = append(attributes, metric.Histogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for i := range metric.ExponentialHistogram().DataPoints().Len() {
+			attributes = append(attributes, metric.ExponentialHistogram().DataPoints().At(i).Attributes())
+		}
+	case pmetric.MetricTypeSummary:
+		for i := range metric.Summary().DataPoints().Len() {
+			attributes = append(attributes, metric.Summary().DataPoints().At(i).Attributes())
+		}
+	}
+	return attributes
+}
+
+func ExtractFromMetrics(metrics pmetric.Metrics, model *EntityModel) []Telemetry {
 	telemetries := map[string]Telemetry{}
+	// Distinct series per schema ID, kept apart as repeated schemas are not merged
+	series := map[string]map[string]Series{}
 
 	for i := range metrics.ResourceMetrics().Len() {
 		resourceMetric := metrics.ResourceMetrics().At(i)
@@ -101,11 +188,16 @@ func ExtractFromMetrics(metrics pmetric.Metrics) []Telemetry {
 				}
 
 				// Extract entities from resource attributes
-				entities := DetectEntities(resourceAttributes)
+				entities := model.Detect(resourceAttributes)
 				for _, entity := range entities {
 					telemetry.Entities[entity.ID] = &entity
 				}
 
+				// Extract the producer from resource attributes
+				if producer := DetectProducer(resourceAttributes); producer != nil {
+					telemetry.Producers = map[string]*Producer{producer.ID: producer}
+				}
+
 				scope := DetectScopes(scopeMetric.Scope(), scopeMetric.SchemaUrl())
 				telemetry.Scope = &scope
 
@@ -137,17 +229,35 @@ func ExtractFromMetrics(metrics pmetric.Metrics) []Telemetry {
 				})
 
 				telemetry.SchemaID = generateMetricSchemaID(telemetry)
-				if existing, ok := telemetries[telemetry.SchemaID]; ok {
-					existing.SeenCount++
-				} else {
-					telemetries[telemetry.SchemaID] = telemetry
+
+				entityIDs := make([]string, 0, len(entities))
+				for _, entity := range entities {
+					entityIDs = append(entityIDs, entity.ID)
 				}
+				sort.Strings(entityIDs)
+				if series[telemetry.SchemaID] == nil {
+					series[telemetry.SchemaID] = map[string]Series{}
+				}
+				for _, attributes := range dataPointAttributes(metric) {
+					id := generateSeriesID(metric.Name(), resourceAttributes, attributes)
+					series[telemetry.SchemaID][id] = Series{ID: id, EntityIDs: entityIDs}
+					telemetry.DataPointCount++
+					telemetry.EstimatedBytes += dataPointOverheadBytes + attributesSize(attributes)
+				}
+
+				mergeTelemetry(telemetries, telemetry)
 			}
 		}
 	}
 
 	result := make([]Telemetry, 0, len(telemetries))
 	for _, telemetry := range telemetries {
+		for _, s := range series[telemetry.SchemaID] {
+			telemetry.Series = append(telemetry.Series, s)
+		}
+		sort.Slice(telemetry.Series, func(i, j int) bool {
+			return telemetry.Series[i].ID < telemetry.Series[j].ID
+		})
 		result = append(result, telemetry)
 	}
 
@@ -183,6 +293,21 @@ func generateLogSchemaID(telemetry Telemetry) string {
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
+// SpanSchemaID returns the ID of the schema ExtractFromTraces extracts from a span
+func SpanSchemaID(span ptrace.Span) string {
+	telemetry := Telemetry{
+		SchemaKey:   span.Name(),
+		SpanKind:    SpanKind(span.Kind().String()),
+		SpanName:    span.Name(),
+		SpanTraceID: span.TraceID().String(),
+	}
+	span.Attributes().Range(func(key string, _ pcommon.Value) bool {
+		telemetry.Attributes = append(telemetry.Attributes, Attribute{Name: key, Source: AttributeSourceSpan})
+		return true
+	})
+	return generateTraceSchemaID(telemetry)
+}
+
 func generateTraceSchemaID(telemetry Telemetry) string {
 	attributeNames := make([]string, 0, len(telemetry.Attributes))
 	for _, attr := range telemetry.Attributes {
@@ -205,7 +330,7 @@ func generateTraceSchemaID(telemetry Telemetry) string {
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
-func ExtractFromLogs(logs plog.Logs) []Telemetry {
+func ExtractFromLogs(logs plog.Logs, model *EntityModel) []Telemetry {
 	telemetries := map[string]Telemetry{}
 
 	for i := range logs.ResourceLogs().Len() {
@@ -257,11 +382,16 @@ func ExtractFromLogs(logs plog.Logs) []Telemetry {
 				}
 
 				// Extract entities from resource attributes
-				entities := DetectEntities(resourceAttributes)
+				entities := model.Detect(resourceAttributes)
 				for _, entity := range entities {
 					telemetry.Entities[entity.ID] = &entity
 				}
 
+				// Extract the producer from resource attributes
+				if producer := DetectProducer(resourceAttributes); producer != nil {
+					telemetry.Producers = map[string]*Producer{producer.ID: producer}
+				}
+
 				scope := DetectScopes(scopeLog.Scope(), scopeLog.SchemaUrl())
 				telemetry.Scope = &scope
 
@@ -293,11 +423,9 @@ func ExtractFromLogs(logs plog.Logs) []Telemetry {
 				})
 
 				telemetry.SchemaID = generateLogSchemaID(telemetry)
-				if existing, ok := telemetries[telemetry.SchemaID]; ok {
-					existing.SeenCount++
-				} else {
-					telemetries[telemetry.SchemaID] = telemetry
-				}
+				telemetry.DataPointCount = 1
+				telemetry.EstimatedBytes = logRecordOverheadBytes + int64(len(telemetry.LogBody)) + attributesSize(logAttributes)
+				mergeTelemetry(telemetries, telemetry)
 			}
 		}
 	}
@@ -310,7 +438,7 @@ func ExtractFromLogs(logs plog.Logs) []Telemetry {
 	return result
 }
 
-func ExtractFromTraces(traces ptrace.Traces) []Telemetry {
+func ExtractFromTraces(traces ptrace.Traces, model *EntityModel) []Telemetry {
 	telemetries := map[string]Telemetry{}
 
 	for i := range traces.ResourceSpans().Len() {
@@ -341,11 +469,16 @@ func ExtractFromTraces(traces ptrace.Traces) []Telemetry {
 				}
 
 				// Extract entities from resource attributes
-				entities := DetectEntities(resourceAttributes)
+				entities := model.Detect(resourceAttributes)
 				for _, entity := range entities {
 					telemetry.Entities[entity.ID] = &entity
 				}
 
+				// Extract the producer from resource attributes
+				if producer := DetectProducer(resourceAttributes); producer != nil {
+					telemetry.Producers = map[string]*Producer{producer.ID: producer}
+				}
+
 				scope := DetectScopes(scopeSpan.Scope(), scopeSpan.SchemaUrl())
 				telemetry.Scope = &scope
 
@@ -377,11 +510,9 @@ func ExtractFromTraces(traces ptrace.Traces) []Telemetry {
 				})
 
 				telemetry.SchemaID = generateTraceSchemaID(telemetry)
-				if existing, ok := telemetries[telemetry.SchemaID]; ok {
-					existing.SeenCount++
-				} else {
-					telemetries[telemetry.SchemaID] = telemetry
-				}
+				telemetry.DataPointCount = 1
+				telemetry.EstimatedBytes = spanOverheadBytes + int64(len(span.Name())) + attributesSize(spanAttributes)
+				mergeTelemetry(telemetries, telemetry)
 			}
 		}
 	}
@@ -415,7 +546,7 @@ func generateProfileSchemaID(telemetry Telemetry) string {
 	return fmt.Sprintf("%x", h.Sum64())
 }
 
-func ExtractFromProfiles(profiles pprofile.Profiles, dictionary *profilepb.ProfilesDictionary) []Telemetry {
+func ExtractFromProfiles(profiles pprofile.Profiles, dictionary *profilepb.ProfilesDictionary, model *EntityModel) []Telemetry {
 	telemetries := map[string]Telemetry{}
 
 	for i := range profiles.ResourceProfiles().Len() {
@@ -471,11 +602,16 @@ func ExtractFromProfiles(profiles pprofile.Profiles, dictionary *profilepb.Profi
 					}
 
 					// Extract entities from resource attributes
-					entities := DetectEntities(resourceAttributes)
+					entities := model.Detect(resourceAttributes)
 					for _, entity := range entities {
 						telemetry.Entities[entity.ID] = &entity
 					}
 
+					// Extract the producer from resource attributes
+					if producer := DetectProducer(resourceAttributes); producer != nil {
+						telemetry.Producers = map[string]*Producer{producer.ID: producer}
+					}
+
 					scope := DetectScopes(scopeProfile.Scope(), scopeProfile.SchemaUrl())
 					telemetry.Scope = &scope
 
@@ -507,11 +643,9 @@ func ExtractFromProfiles(profiles pprofile.Profiles, dictionary *profilepb.Profi
 					})
 
 					telemetry.SchemaID = generateProfileSchemaID(telemetry)
-					if existing, ok := telemetries[telemetry.SchemaID]; ok {
-						existing.SeenCount++
-					} else {
-						telemetries[telemetry.SchemaID] = telemetry
-					}
+					telemetry.DataPointCount = int64(profile.Sample().Len())
+					telemetry.EstimatedBytes = attributesSize(profileAttributes) + profileSampleOverheadBytes*telemetry.DataPointCount
+					mergeTelemetry(telemetries, telemetry)
 				}
 			}
 		}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/golden"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
 )
 
 // WriteTelemetriesToFile writes the telemetries to a JSON file
@@ -45,10 +46,61 @@ func TestExtractFromMetrics(t *testing.T) {
 			md, err := golden.ReadMetrics(filepath.Join("testdata", fmt.Sprintf("%s.yaml", tc.name)))
 			require.NoError(t, err)
 
-			telemetries := ExtractFromMetrics(md)
+			telemetries := ExtractFromMetrics(md, DefaultEntityModel())
 
 			require.Equal(t, tc.expected, len(telemetries))
 		})
 	}
 
 }
+
+func TestExtractFromMetrics_Series(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for _, pod := range []string{"checkout-1", "checkout-2"} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", "checkout")
+		rm.Resource().Attributes().PutStr("k8s.pod.name", pod)
+		metric := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		metric.SetName("http.server.requests")
+		dps := metric.SetEmptySum().DataPoints()
+		for _, route := range []string{"/cart", "/pay", "/cart"} {
+			dps.AppendEmpty().Attributes().PutStr("http.route", route)
+		}
+	}
+
+	telemetries := ExtractFromMetrics(md, DefaultEntityModel())
+	require.Len(t, telemetries, 1)
+
+	// Two routes on two pods, the repeated data point is the same series
+	series := telemetries[0].Series
+	require.Len(t, series, 4)
+	for _, s := range series {
+		require.NotEmpty(t, s.ID)
+		require.Len(t, s.EntityIDs, 2, "service and pod entities")
+	}
+}
+
+func TestExtractFromMetrics_Usage(t *testing.T) {
+	md := pmetric.NewMetrics()
+	for _, pod := range []string{"checkout-1", "checkout-2"} {
+		rm := md.ResourceMetrics().AppendEmpty()
+		rm.Resource().Attributes().PutStr("service.name", "checkout")
+		rm.Resource().Attributes().PutStr("k8s.pod.name", pod)
+		metric := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		metric.SetName("http.server.requests")
+		dps := metric.SetEmptyGauge().DataPoints()
+		for range 3 {
+			dps.AppendEmpty().Attributes().PutStr("http.route", "/cart")
+		}
+	}
+
+	telemetries := ExtractFromMetrics(md, DefaultEntityModel())
+	require.Len(t, telemetries, 1)
+
+	// The schema seen on both pods is counted once per pod, with the entities of both
+	telemetry := telemetries[0]
+	require.Equal(t, 2, telemetry.SeenCount)
+	require.Equal(t, int64(6), telemetry.DataPointCount)
+	require.Equal(t, int64(6*(dataPointOverheadBytes+len("http.route")+len("/cart"))), telemetry.EstimatedBytes)
+	require.Len(t, telemetry.Entities, 3, "one service and two pods")
+}
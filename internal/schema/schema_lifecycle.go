@@ -0,0 +1,304 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrSchemaNotFound is returned when a schema ID is unknown
+	ErrSchemaNotFound = errors.New("schema not found")
+	// ErrInvalidVersion is returned when a version is not valid SemVer
+	ErrInvalidVersion = errors.New("invalid version")
+	// ErrInvalidStatus is returned for an unknown version status
+	ErrInvalidStatus = errors.New("invalid version status")
+	// ErrInvalidStatusTransition is returned when a version status cannot move to the requested one
+	ErrInvalidStatusTransition = errors.New("invalid version status transition")
+	// ErrVersionConflict is returned when a version is already assigned to another schema of the same key
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrIncompatibleVersion is returned when a minor or patch bump contains breaking changes
+	ErrIncompatibleVersion = errors.New("incompatible version")
+)
+
+type VersionStatus string
+
+const (
+	VersionStatusDraft      VersionStatus = "draft"
+	VersionStatusActive     VersionStatus = "active"
+	VersionStatusDeprecated VersionStatus = "deprecated"
+	VersionStatusRetired    VersionStatus = "retired"
+)
+
+// IsValid reports whether the status is one of the known lifecycle statuses
+func (s VersionStatus) IsValid() bool {
+	switch s {
+	case VersionStatusDraft, VersionStatusActive, VersionStatusDeprecated, VersionStatusRetired:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a version may move from s to next.
+// Versions move forward through draft, active, deprecated and retired, one step at a time.
+func (s VersionStatus) CanTransitionTo(next VersionStatus) bool {
+	if s == next {
+		return true
+	}
+	switch s {
+	case VersionStatusDraft:
+		return next == VersionStatusActive
+	case VersionStatusActive:
+		return next == VersionStatusDeprecated
+	case VersionStatusDeprecated:
+		return next == VersionStatusRetired
+	}
+	return false
+}
+
+// SemVer is a semantic version as defined by https://semver.org
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+	Build      string
+}
+
+// ParseSemVer parses MAJOR.MINOR.PATCH with optional pre-release and build metadata
+func ParseSemVer(version string) (SemVer, error) {
+	var v SemVer
+	rest := version
+
+	if i := strings.Index(rest, "+"); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+		if v.Build == "" {
+			return SemVer{}, fmt.Errorf("%w %q: empty build metadata", ErrInvalidVersion, version)
+		}
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		v.PreRelease = rest[i+1:]
+		rest = rest[:i]
+		if v.PreRelease == "" {
+			return SemVer{}, fmt.Errorf("%w %q: empty pre-release", ErrInvalidVersion, version)
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("%w %q: expected MAJOR.MINOR.PATCH", ErrInvalidVersion, version)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		if part == "" || (len(part) > 1 && part[0] == '0') {
+			return SemVer{}, fmt.Errorf("%w %q: invalid number %q", ErrInvalidVersion, version, part)
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return SemVer{}, fmt.Errorf("%w %q: invalid number %q", ErrInvalidVersion, version, part)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+
+	return v, nil
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is lower than, equal to or greater than other.
+// Build metadata is ignored and a pre-release sorts before its release.
+func (v SemVer) Compare(other SemVer) int {
+	for _, d := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	switch {
+	case v.PreRelease == other.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case other.PreRelease == "":
+		return -1
+	}
+	return comparePreRelease(v.PreRelease, other.PreRelease)
+}
+
+func comparePreRelease(a, b string) int {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aParts[i], bParts[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	switch {
+	case len(aParts) < len(bParts):
+		return -1
+	case len(aParts) > len(bParts):
+		return 1
+	}
+	return 0
+}
+
+// BreakingChanges lists the changes in the diff that consumers of the previous variant would notice:
+// removed attributes, attribute type changes and metadata changes such as unit or metric type.
+func (d SchemaDiff) BreakingChanges() []string {
+	var changes []string
+	for _, attr := range d.RemovedAttributes {
+		changes = append(changes, fmt.Sprintf("attribute %s removed", attr.Name))
+	}
+	for _, change := range d.ChangedAttributes {
+		changes = append(changes, fmt.Sprintf("attribute %s changed type from %s to %s", change.Name, change.OldType, change.NewType))
+	}
+	for _, change := range d.FieldChanges {
+		changes = append(changes, fmt.Sprintf("%s changed from %q to %q", change.Field, change.OldValue, change.NewValue))
+	}
+	return changes
+}
+
+// CheckCompatibility refuses a minor or patch bump over the previous active version when the
+// diff between the two schemas contains breaking changes. A major bump may break anything.
+func CheckCompatibility(previous, next SemVer, diff SchemaDiff) error {
+	if next.Major != previous.Major {
+		return nil
+	}
+	breaking := diff.BreakingChanges()
+	if len(breaking) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is not a major bump over %s but %s",
+		ErrIncompatibleVersion, next, previous, strings.Join(breaking, ", "))
+}
+
+// AssignedVersion is the version and lifecycle status stored for a schema variant. Versions
+// stored without a status are active.
+type AssignedVersion struct {
+	SchemaID string
+	Version  string
+	Status   VersionStatus
+}
+
+// VersionChange is what a valid assignment stores: the resulting status, and the deprecation
+// time when the assignment sets one or deprecates the schema
+type VersionChange struct {
+	Status       VersionStatus
+	DeprecatedAt *time.Time
+}
+
+// Validate checks the version and status of an assignment without looking at stored versions
+func (a SchemaAssignment) Validate() error {
+	if _, err := ParseSemVer(a.Version); err != nil {
+		return err
+	}
+	if a.Status != "" && !a.Status.IsValid() {
+		return fmt.Errorf("%w %q", ErrInvalidStatus, a.Status)
+	}
+	return nil
+}
+
+// ResolveVersionAssignment validates assigning a version to target, the schema of the
+// assignment. current is the version already assigned to target, nil when there is none, and
+// others are the versions assigned to the other variants of its schema key. The status defaults
+// to the current one, or to active, and must be a valid transition; the version of a schema no
+// longer in draft cannot change, and no two variants of a key share a version. Activating a
+// minor or patch bump is refused when target breaks the previous active version of the key,
+// which previous loads.
+func ResolveVersionAssignment(assignment SchemaAssignment, target *TelemetrySchema, current *AssignedVersion, others []AssignedVersion, previous func(schemaID string) (*TelemetrySchema, error)) (VersionChange, error) {
+	if err := assignment.Validate(); err != nil {
+		return VersionChange{}, err
+	}
+	version, _ := ParseSemVer(assignment.Version)
+
+	status := assignment.Status
+	currentStatus := VersionStatus("")
+	if current != nil {
+		currentStatus = current.status()
+		if status == "" {
+			status = currentStatus
+		}
+		if !currentStatus.CanTransitionTo(status) {
+			return VersionChange{}, fmt.Errorf("%w: %s to %s", ErrInvalidStatusTransition, currentStatus, status)
+		}
+		if current.Version != assignment.Version && currentStatus != VersionStatusDraft {
+			return VersionChange{}, fmt.Errorf("%w: schema %s is %s as %s and its version can no longer change",
+				ErrVersionConflict, assignment.SchemaId, currentStatus, current.Version)
+		}
+	} else if status == "" {
+		status = VersionStatusActive
+	}
+
+	// The previous version is the highest active version of the key below the assigned one
+	previousID := ""
+	var previousVersion SemVer
+	for _, other := range others {
+		if other.Version == assignment.Version {
+			return VersionChange{}, fmt.Errorf("%w: version %s is already assigned to schema %s", ErrVersionConflict, other.Version, other.SchemaID)
+		}
+		otherVersion, err := ParseSemVer(other.Version)
+		if err != nil || other.status() != VersionStatusActive {
+			// Versions assigned before SemVer validation are not compared against
+			continue
+		}
+		if otherVersion.Compare(version) < 0 && (previousID == "" || otherVersion.Compare(previousVersion) > 0) {
+			previousID, previousVersion = other.SchemaID, otherVersion
+		}
+	}
+
+	if status == VersionStatusActive && previousID != "" {
+		previousSchema, err := previous(previousID)
+		if err != nil {
+			return VersionChange{}, err
+		}
+		if previousSchema != nil {
+			if err := CheckCompatibility(previousVersion, version, DiffTelemetrySchemas(previousSchema, target)); err != nil {
+				return VersionChange{}, err
+			}
+		}
+	}
+
+	change := VersionChange{Status: status, DeprecatedAt: assignment.DeprecatedAt}
+	if change.DeprecatedAt == nil && status == VersionStatusDeprecated && currentStatus != VersionStatusDeprecated {
+		now := time.Now()
+		change.DeprecatedAt = &now
+	}
+	return change, nil
+}
+
+func (v AssignedVersion) status() VersionStatus {
+	if v.Status == "" {
+		return VersionStatusActive
+	}
+	return v.Status
+}
@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSemVer(t *testing.T) {
+	v, err := ParseSemVer("1.2.3-rc.1+build.5")
+	require.NoError(t, err)
+	assert.Equal(t, SemVer{Major: 1, Minor: 2, Patch: 3, PreRelease: "rc.1", Build: "build.5"}, v)
+	assert.Equal(t, "1.2.3-rc.1+build.5", v.String())
+
+	for _, invalid := range []string{"", "1", "1.2", "1.2.3.4", "v1.2.3", "01.2.3", "1.-2.3", "1.2.3-", "1.2.3+", "a.b.c"} {
+		_, err := ParseSemVer(invalid)
+		assert.ErrorIs(t, err, ErrInvalidVersion, invalid)
+	}
+}
+
+func TestSemVer_Compare(t *testing.T) {
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, err := ParseSemVer(ordered[i])
+		require.NoError(t, err)
+		b, err := ParseSemVer(ordered[i+1])
+		require.NoError(t, err)
+		assert.Equal(t, -1, a.Compare(b), "%s < %s", a, b)
+		assert.Equal(t, 1, b.Compare(a), "%s > %s", b, a)
+	}
+
+	a, _ := ParseSemVer("1.0.0+one")
+	b, _ := ParseSemVer("1.0.0+two")
+	assert.Equal(t, 0, a.Compare(b))
+}
+
+func TestVersionStatus_CanTransitionTo(t *testing.T) {
+	assert.True(t, VersionStatusDraft.CanTransitionTo(VersionStatusActive))
+	assert.True(t, VersionStatusActive.CanTransitionTo(VersionStatusDeprecated))
+	assert.True(t, VersionStatusDeprecated.CanTransitionTo(VersionStatusRetired))
+	assert.True(t, VersionStatusActive.CanTransitionTo(VersionStatusActive))
+
+	assert.False(t, VersionStatusDraft.CanTransitionTo(VersionStatusDeprecated))
+	assert.False(t, VersionStatusActive.CanTransitionTo(VersionStatusDraft))
+	assert.False(t, VersionStatusDeprecated.CanTransitionTo(VersionStatusActive))
+	assert.False(t, VersionStatusRetired.CanTransitionTo(VersionStatusActive))
+
+	assert.False(t, VersionStatus("archived").IsValid())
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	v1, _ := ParseSemVer("1.0.0")
+	minor, _ := ParseSemVer("1.1.0")
+	major, _ := ParseSemVer("2.0.0")
+
+	breaking := SchemaDiff{
+		RemovedAttributes: []Attribute{{Name: "http.method"}},
+		ChangedAttributes: []AttributeTypeChange{{Name: "http.status_code", OldType: AttributeTypeStr, NewType: AttributeTypeInt}},
+	}
+	err := CheckCompatibility(v1, minor, breaking)
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+	assert.Contains(t, err.Error(), "attribute http.method removed")
+	assert.Contains(t, err.Error(), "attribute http.status_code changed type from Str to Int")
+
+	assert.NoError(t, CheckCompatibility(v1, major, breaking))
+
+	additive := SchemaDiff{AddedAttributes: []Attribute{{Name: "server.address"}}}
+	assert.NoError(t, CheckCompatibility(v1, minor, additive))
+}
+
+func TestResolveVersionAssignment(t *testing.T) {
+	previous := &TelemetrySchema{SchemaId: "v1", Attributes: []Attribute{{Name: "http.method", Type: AttributeTypeStr}}}
+	target := &TelemetrySchema{SchemaId: "v2"}
+	load := func(schemaID string) (*TelemetrySchema, error) {
+		require.Equal(t, "v1", schemaID)
+		return previous, nil
+	}
+	others := []AssignedVersion{{SchemaID: "v1", Version: "1.0.0"}}
+
+	_, err := ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "one"}, target, nil, others, load)
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+
+	_, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "1.0.0"}, target, nil, others, load)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	// Removing http.method breaks 1.0.0, which a draft or a major bump may do
+	_, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "1.1.0"}, target, nil, others, load)
+	assert.ErrorIs(t, err, ErrIncompatibleVersion)
+	change, err := ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "1.1.0", Status: VersionStatusDraft}, target, nil, others, load)
+	require.NoError(t, err)
+	assert.Equal(t, VersionStatusDraft, change.Status)
+	change, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "2.0.0"}, target, nil, others, load)
+	require.NoError(t, err)
+	assert.Equal(t, VersionStatusActive, change.Status)
+	assert.Nil(t, change.DeprecatedAt)
+
+	current := &AssignedVersion{SchemaID: "v2", Version: "2.0.0", Status: VersionStatusActive}
+	_, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "2.0.1"}, target, current, others, load)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	_, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "2.0.0", Status: VersionStatusDraft}, target, current, others, load)
+	assert.ErrorIs(t, err, ErrInvalidStatusTransition)
+
+	change, err = ResolveVersionAssignment(SchemaAssignment{SchemaId: "v2", Version: "2.0.0", Status: VersionStatusDeprecated}, target, current, others, load)
+	require.NoError(t, err)
+	assert.Equal(t, VersionStatusDeprecated, change.Status)
+	assert.NotNil(t, change.DeprecatedAt)
+}
@@ -6,14 +6,34 @@ type SchemaAssignment struct {
 	SchemaId string `json:"schemaId"`
 	Version  string `json:"version,omitempty"`
 	Reason   string `json:"reason,omitempty"`
+	// Status defaults to active for a new assignment and to the current status otherwise
+	Status       VersionStatus `json:"status,omitempty"`
+	DeprecatedAt *time.Time    `json:"deprecatedAt,omitempty"`
+	// ReplacedBy is the schema ID superseding a deprecated or retired version
+	ReplacedBy string `json:"replacedBy,omitempty"`
 }
 
 type TelemetrySchema struct {
-	SchemaId    string             `json:"schemaId"`
-	Version     string             `json:"version"`
-	EntityCount int                `json:"entityCount"`
-	LastSeen    *time.Time         `json:"lastSeen,omitempty"`
-	Entities    map[string]*Entity `json:"entities"`
-	Scope       *Scope             `json:"scope"`
-	Attributes  []Attribute        `json:"attributes"`
+	SchemaId      string        `json:"schemaId"`
+	SchemaKey     string        `json:"schemaKey,omitempty"`
+	TelemetryType TelemetryType `json:"telemetryType,omitempty"`
+	Version       string        `json:"version"`
+	Status        VersionStatus `json:"status,omitempty"`
+	DeprecatedAt  *time.Time    `json:"deprecatedAt,omitempty"`
+	ReplacedBy    string        `json:"replacedBy,omitempty"`
+	EntityCount   int           `json:"entityCount"`
+	LastSeen      *time.Time    `json:"lastSeen,omitempty"`
+	// Metric, span, log and profile metadata of the variant
+	MetricUnit        string            `json:"metricUnit,omitempty"`
+	MetricType        MetricType        `json:"metricType,omitempty"`
+	MetricTemporality MetricTemporality `json:"metricTemporality,omitempty"`
+	SpanKind          SpanKind          `json:"spanKind,omitempty"`
+	LogSeverityText   string            `json:"logSeverityText,omitempty"`
+	LogEventName      string            `json:"logEventName,omitempty"`
+	ProfileSampleUnit string            `json:"profileSampleUnit,omitempty"`
+
+	Entities map[string]*Entity `json:"entities"`
+	// Scopes are the instrumentation scopes emitting the variant, listed without their attributes
+	Scopes     []Scope     `json:"scopes,omitempty"`
+	Attributes []Attribute `json:"attributes"`
 }
@@ -20,6 +20,10 @@ type Scope struct {
 	Attributes map[string]interface{} `json:"attributes"`
 	FirstSeen  time.Time              `json:"firstSeen"`
 	LastSeen   time.Time              `json:"lastSeen"`
+	// Link describes the scope's activity for the schema it was loaded for, if any
+	Link *SchemaLink `json:"link,omitempty"`
+	// Owners are the teams owning the scope
+	Owners []Owner `json:"owners,omitempty"`
 }
 
 // SanitizeScopeName sanitizes a scope name for use in group IDs
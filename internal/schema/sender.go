@@ -0,0 +1,12 @@
+package schema
+
+import "time"
+
+// Sender is a client identified by its verified TLS certificate, such as a collector, that sent a
+// telemetry. The identity is the certificate subject or the tenant it is mapped to.
+type Sender struct {
+	Identity  string    `json:"identity"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	SeenCount int64     `json:"seenCount"`
+}
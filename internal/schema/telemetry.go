@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -50,6 +51,8 @@ const (
 )
 
 type Telemetry struct {
+	// Tenant owns the telemetry, set when reading it
+	Tenant        string        `json:"tenant,omitempty"`
 	SchemaID      string        `json:"schemaId"`
 	SchemaVersion string        `json:"schemaVersion"`
 	SchemaURL     string        `json:"schemaURL,omitempty"`
@@ -78,17 +81,46 @@ type Telemetry struct {
 	ProfileSampleAggregationTemporality string `json:"profileSampleAggregationTemporality"`
 	ProfileSampleUnit                   string `json:"profileSampleUnit"`
 
-	Attributes []Attribute       `json:"attributes"`
-	Note       string            `json:"note,omitempty"`
-	Protocol   TelemetryProtocol `json:"protocol"`
-	SeenCount  int               `json:"seenCount"`
-	CreatedAt  time.Time         `json:"createdAt"`
-	UpdatedAt  time.Time         `json:"updatedAt"`
+	Attributes []Attribute `json:"attributes"`
+	Note       string      `json:"note,omitempty"`
+	// Stability and Tags are only set through the curated documentation
+	Stability Stability         `json:"stability,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	Protocol  TelemetryProtocol `json:"protocol"`
+	SeenCount int               `json:"seenCount"`
+	CreatedAt time.Time         `json:"createdAt"`
+	UpdatedAt time.Time         `json:"updatedAt"`
 	// Entities maps entity IDs to their information
 	Entities map[string]*Entity `json:"entities"`
 	Scope    *Scope             `json:"scope"`
+	// Producers maps producer IDs to the services that emitted the telemetry
+	Producers map[string]*Producer `json:"producers,omitempty"`
+	// Senders are the clients, identified by their verified TLS certificate, that sent the telemetry
+	Senders []Sender `json:"senders,omitempty"`
+	// Sender is the identity of the client sending the telemetry on ingest
+	Sender string `json:"-"`
+	// Owners are the teams owning the schema key
+	Owners []Owner `json:"owners,omitempty"`
+	// ReviewState is pending while the schema key or one of its variants waits for a reviewer
+	ReviewState ReviewState `json:"reviewState,omitempty"`
+	// DataPointCount and EstimatedBytes measure the data points, log records, spans or profile samples
+	// received on ingest, recorded in the usage rollups
+	DataPointCount int64 `json:"-"`
+	EstimatedBytes int64 `json:"-"`
+	// Series lists the distinct time series of a metric seen on ingest, used to estimate active series
+	Series []Series `json:"-"`
 }
 
+// Series is a metric time series, identified by the metric name and its resource and data point attribute values
+type Series struct {
+	ID string
+	// EntityIDs are the entities of the resource emitting the series
+	EntityIDs []string
+}
+
+// TelemetryHistoryStatusDetected marks history entries written on ingest for a new schema variant
+const TelemetryHistoryStatusDetected = "detected"
+
 type TelemetryHistory struct {
 	Id        int       `json:"id"`
 	SchemaKey string    `json:"schemaKey"`
@@ -98,6 +130,25 @@ type TelemetryHistory struct {
 	Summary   string    `json:"summary"`
 	Status    string    `json:"status"`
 	Snapshot  []byte    `json:"snapshot"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	// SchemaID and Diff are set on entries recorded automatically when a new schema variant is detected
+	SchemaID  string      `json:"schemaId,omitempty"`
+	Diff      *SchemaDiff `json:"diff,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+}
+
+// SnapshotSchemaID returns the schema variant a history entry records: its schema ID, or else
+// the ID of the telemetry in its snapshot, as in entries inserted through the API or recorded
+// before schema IDs were. It is empty when the snapshot holds no telemetry.
+func (h TelemetryHistory) SnapshotSchemaID() string {
+	if h.SchemaID != "" {
+		return h.SchemaID
+	}
+	var snapshot struct {
+		SchemaID string `json:"schemaId"`
+	}
+	if err := json.Unmarshal(h.Snapshot, &snapshot); err != nil {
+		return ""
+	}
+	return snapshot.SchemaID
 }
@@ -0,0 +1,14 @@
+package schema
+
+import "time"
+
+// TenantSummary sizes the catalog of a tenant, for the cross-tenant admin view
+type TenantSummary struct {
+	Name string `json:"name"`
+	// Telemetries counts the schema keys, Schemas their variants
+	Telemetries int64     `json:"telemetries"`
+	Schemas     int64     `json:"schemas"`
+	Entities    int64     `json:"entities"`
+	Scopes      int64     `json:"scopes"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"fmt"
+	"time"
+)
+
+// UsageResolution is the size of the time buckets of the usage rollups
+type UsageResolution string
+
+const (
+	UsageResolutionHour UsageResolution = "hour"
+	UsageResolutionDay  UsageResolution = "day"
+)
+
+// Usage rollups are recorded per schema, and per entity and scope emitting it
+const (
+	UsageDimensionSchema = "schema"
+	UsageDimensionEntity = "entity"
+	UsageDimensionScope  = "scope"
+)
+
+// ParseUsageResolution parses hour or day, defaulting to hour
+func ParseUsageResolution(s string) (UsageResolution, error) {
+	switch UsageResolution(s) {
+	case "", UsageResolutionHour:
+		return UsageResolutionHour, nil
+	case UsageResolutionDay:
+		return UsageResolutionDay, nil
+	}
+	return "", fmt.Errorf("invalid resolution %q, expected hour or day", s)
+}
+
+// Bucket returns the start of the bucket holding t, in UTC
+func (r UsageResolution) Bucket(t time.Time) time.Time {
+	if r == UsageResolutionDay {
+		return StartOfDay(t)
+	}
+	return t.UTC().Truncate(time.Hour)
+}
+
+// DefaultWindow is the time range returned when none is requested
+func (r UsageResolution) DefaultWindow() time.Duration {
+	if r == UsageResolutionDay {
+		return 30 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// UsagePoint is the usage recorded in one time bucket
+type UsagePoint struct {
+	Bucket         time.Time `json:"bucket"`
+	SeenCount      int64     `json:"seenCount"`
+	DataPointCount int64     `json:"dataPointCount"`
+	EstimatedBytes int64     `json:"estimatedBytes"`
+}
+
+// UsageSeries is the usage of a telemetry key or entity over time
+type UsageSeries struct {
+	SchemaKey  string          `json:"schemaKey,omitempty"`
+	EntityID   string          `json:"entityId,omitempty"`
+	Resolution UsageResolution `json:"resolution"`
+	Since      time.Time       `json:"since"`
+	Until      time.Time       `json:"until"`
+	Points     []UsagePoint    `json:"points"`
+}
+
+// UsageRetention controls the downsampling of the usage rollups. Hourly buckets older than Hourly
+// are merged into daily buckets, and daily buckets older than Daily are deleted.
+type UsageRetention struct {
+	Hourly time.Duration
+	Daily  time.Duration
+}
+
+// DefaultUsageRetention keeps a week of hourly buckets and a year of daily buckets
+var DefaultUsageRetention = UsageRetention{
+	Hourly: 7 * 24 * time.Hour,
+	Daily:  365 * 24 * time.Hour,
+}
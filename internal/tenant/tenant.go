@@ -0,0 +1,61 @@
+// Package tenant isolates the catalogs of the business units sharing a TallyCat. The tenant of a
+// request travels in its context, from which the repositories scope every read and write.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// Default owns the telemetry sent without a tenant, and all telemetry stored before tenants
+	Default = "default"
+	// All selects every tenant in the cross-tenant admin views. It is never stored.
+	All = "*"
+)
+
+// ErrInvalidTenant is returned for a tenant name with characters other than letters, digits,
+// dots, dashes and underscores
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Validate checks the tenant is named with 1 to 64 letters, digits, dots, dashes and underscores
+func Validate(name string) error {
+	if !tenantName.MatchString(name) {
+		return fmt.Errorf("%w %q: must be 1 to 64 letters, digits, dots, dashes or underscores", ErrInvalidTenant, name)
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoped to the tenant
+func WithTenant(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, name)
+}
+
+// FromContext returns the tenant of the context, the default tenant when unset
+func FromContext(ctx context.Context) string {
+	if name, ok := ctx.Value(tenantKey{}).(string); ok && name != "" {
+		return name
+	}
+	return Default
+}
+
+// ScopeID derives the ID of a schema, entity, scope or producer within a tenant, so equal
+// telemetry sent by two tenants is stored apart. IDs of the default tenant are unchanged.
+func ScopeID(name, id string) string {
+	if name == Default || id == "" {
+		return id
+	}
+	h := xxhash.New()
+	h.WriteString(name)
+	h.WriteString("|")
+	h.WriteString(id)
+	return fmt.Sprintf("%x", h.Sum64())
+}
@@ -0,0 +1,29 @@
+package tenant
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	require.Equal(t, Default, FromContext(context.Background()))
+	require.Equal(t, "payments", FromContext(WithTenant(context.Background(), "payments")))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate("payments"))
+	require.NoError(t, Validate("bu-1.eu_west"))
+	require.ErrorIs(t, Validate(""), ErrInvalidTenant)
+	require.ErrorIs(t, Validate(All), ErrInvalidTenant)
+	require.ErrorIs(t, Validate("a/b"), ErrInvalidTenant)
+}
+
+func TestScopeID(t *testing.T) {
+	require.Equal(t, "abc", ScopeID(Default, "abc"))
+	require.Equal(t, "", ScopeID("payments", ""))
+	require.NotEqual(t, "abc", ScopeID("payments", "abc"))
+	require.NotEqual(t, ScopeID("payments", "abc"), ScopeID("search", "abc"))
+	require.Equal(t, ScopeID("payments", "abc"), ScopeID("payments", "abc"))
+}
@@ -0,0 +1,136 @@
+package tlsconfig
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultReloadInterval is how often the certificate files are checked for rotation
+const DefaultReloadInterval = time.Minute
+
+// ClientAuth is how a listener verifies client certificates
+type ClientAuth string
+
+const (
+	// ClientAuthNone does not ask for client certificates
+	ClientAuthNone ClientAuth = "none"
+	// ClientAuthRequest verifies the client certificates sent, but accepts clients without one
+	ClientAuthRequest ClientAuth = "request"
+	// ClientAuthRequire refuses clients without a certificate signed by the client CA bundle
+	ClientAuthRequire ClientAuth = "require"
+)
+
+// Config enables TLS on the OTLP gRPC listener, the HTTP listener or both
+type Config struct {
+	GRPC *ListenerConfig `yaml:"grpc,omitempty"`
+	HTTP *ListenerConfig `yaml:"http,omitempty"`
+	// ReloadInterval is how often the certificate, key and CA files are checked for changes
+	ReloadInterval time.Duration `yaml:"reload_interval,omitempty"`
+	// Identities map verified client certificates to the identity recorded with ingested schemas,
+	// which is also the tenant they send to. Clients matching no rule are identified by their
+	// certificate subject CN.
+	Identities []IdentityRule `yaml:"identities,omitempty"`
+}
+
+// ListenerConfig is the server certificate of a listener and how it verifies clients (mTLS)
+type ListenerConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile is a PEM bundle of the CAs client certificates are verified against
+	ClientCAFile string     `yaml:"client_ca_file,omitempty"`
+	ClientAuth   ClientAuth `yaml:"client_auth,omitempty"`
+}
+
+// IdentityRule maps the client certificates with a subject CN, DNS, URI or email SAN matching a
+// glob pattern to an identity, the tenant they send to. Map them to the default tenant to share it.
+type IdentityRule struct {
+	Match    string `yaml:"match"`
+	Identity string `yaml:"identity"`
+}
+
+// LoadConfig reads a TLS config from a YAML file. Relative certificate, key and CA files are
+// resolved against the directory of the config.
+func LoadConfig(p string) (*Config, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TLS config %s: %w", p, err)
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range []*ListenerConfig{cfg.GRPC, cfg.HTTP} {
+		if l == nil {
+			continue
+		}
+		for _, file := range []*string{&l.CertFile, &l.KeyFile, &l.ClientCAFile} {
+			if *file != "" && !filepath.IsAbs(*file) {
+				*file = filepath.Join(filepath.Dir(p), *file)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses and validates a TLS config from YAML content
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse TLS config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks that listeners name a certificate and key, that client verification has a CA
+// bundle and that identity rules are valid patterns
+func (c *Config) Validate() error {
+	if c.GRPC == nil && c.HTTP == nil {
+		return fmt.Errorf("TLS config must enable the grpc or http listener")
+	}
+	for name, l := range map[string]*ListenerConfig{"grpc": c.GRPC, "http": c.HTTP} {
+		if l == nil {
+			continue
+		}
+		if l.CertFile == "" || l.KeyFile == "" {
+			return fmt.Errorf("%s: cert_file and key_file are required", name)
+		}
+		switch l.ClientAuth {
+		case "":
+			l.ClientAuth = ClientAuthNone
+			if l.ClientCAFile != "" {
+				l.ClientAuth = ClientAuthRequire
+			}
+		case ClientAuthNone, ClientAuthRequest, ClientAuthRequire:
+		default:
+			return fmt.Errorf("%s: invalid client_auth %q, expected none, request or require", name, l.ClientAuth)
+		}
+		if l.ClientAuth != ClientAuthNone && l.ClientCAFile == "" {
+			return fmt.Errorf("%s: client_ca_file is required to verify client certificates", name)
+		}
+	}
+
+	if c.ReloadInterval < 0 {
+		return fmt.Errorf("reload_interval cannot be negative")
+	}
+	if c.ReloadInterval == 0 {
+		c.ReloadInterval = DefaultReloadInterval
+	}
+
+	for i, rule := range c.Identities {
+		if rule.Match == "" || rule.Identity == "" {
+			return fmt.Errorf("identity rule %d: match and identity are required", i)
+		}
+		if _, err := path.Match(rule.Match, ""); err != nil {
+			return fmt.Errorf("identity rule %d: invalid pattern %q: %w", i, rule.Match, err)
+		}
+	}
+	return nil
+}
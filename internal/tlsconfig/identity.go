@@ -0,0 +1,46 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path"
+)
+
+// ClientIdentity returns the identity of the verified client certificate of a connection, empty
+// when the client did not present one
+func (c *Config) ClientIdentity(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return MapIdentity(c.Identities, state.VerifiedChains[0][0])
+}
+
+// MapIdentity returns the identity of the first rule matching the subject CN or a SAN of the
+// certificate, falling back to the CN or else the first SAN
+func MapIdentity(rules []IdentityRule, cert *x509.Certificate) string {
+	names := certificateNames(cert)
+	for _, rule := range rules {
+		for _, name := range names {
+			if ok, _ := path.Match(rule.Match, name); ok {
+				return rule.Identity
+			}
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+func certificateNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.EmailAddresses...)
+	return names
+}
@@ -0,0 +1,125 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the certificate and client CA bundle of a listener, reloading them when their
+// files change so rotated certificates are picked up without a restart
+type Reloader struct {
+	listener *ListenerConfig
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// NewReloader loads the certificate, key and client CA bundle of a listener
+func NewReloader(listener *ListenerConfig, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{listener: listener, interval: interval, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ServerConfig returns the TLS config of the listener. Each handshake uses the latest certificate
+// and CA bundle.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current(), nil
+		},
+	}
+}
+
+// current returns the loaded config, reloading the files first when the reload interval passed
+// and one of them changed. A failed reload keeps the previous certificate.
+func (r *Reloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.interval {
+		return r.config
+	}
+	r.lastCheck = now
+
+	changed := false
+	for file, modTime := range r.modTimes {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	if changed {
+		if err := r.loadLocked(); err != nil {
+			slog.Error("failed to reload TLS certificate, keeping the previous one", "error", err, "cert", r.listener.CertFile)
+		} else {
+			slog.Info("Reloaded TLS certificate", "cert", r.listener.CertFile)
+		}
+	}
+	return r.config
+}
+
+func (r *Reloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = r.now()
+	return r.loadLocked()
+}
+
+func (r *Reloader) loadLocked() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{r.listener.CertFile, r.listener.KeyFile, r.listener.ClientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.listener.CertFile, r.listener.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate %s: %w", r.listener.CertFile, err)
+	}
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.NoClientCert,
+	}
+
+	if r.listener.ClientAuth != ClientAuthNone {
+		pem, err := os.ReadFile(r.listener.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA bundle %s: %w", r.listener.ClientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA bundle %s has no PEM certificate", r.listener.ClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if r.listener.ClientAuth == ClientAuthRequire {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	r.config = config
+	r.modTimes = modTimes
+	return nil
+}
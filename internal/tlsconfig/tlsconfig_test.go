@@ -0,0 +1,105 @@
+package tlsconfig_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/tallycat/tallycat/internal/integration/testutil"
+	"github.com/tallycat/tallycat/internal/tlsconfig"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tls.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+grpc:
+  cert_file: certs/server.crt
+  key_file: certs/server.key
+  client_ca_file: /etc/tallycat/ca.pem
+  client_auth: request
+http:
+  cert_file: certs/server.crt
+  key_file: certs/server.key
+`), 0o600))
+
+	cfg, err := tlsconfig.LoadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "certs/server.crt"), cfg.GRPC.CertFile)
+	require.Equal(t, "/etc/tallycat/ca.pem", cfg.GRPC.ClientCAFile)
+	require.Equal(t, tlsconfig.ClientAuthRequest, cfg.GRPC.ClientAuth)
+	require.Equal(t, tlsconfig.ClientAuthNone, cfg.HTTP.ClientAuth)
+	require.Equal(t, tlsconfig.DefaultReloadInterval, cfg.ReloadInterval)
+}
+
+func TestParseConfig_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"no listener":        "reload_interval: 1m",
+		"no key":             "grpc: {cert_file: server.crt}",
+		"client auth":        "grpc: {cert_file: server.crt, key_file: server.key, client_auth: optional}",
+		"no client CA":       "http: {cert_file: server.crt, key_file: server.key, client_auth: require}",
+		"negative interval":  "http: {cert_file: server.crt, key_file: server.key}\nreload_interval: -1m",
+		"invalid pattern":    "http: {cert_file: server.crt, key_file: server.key}\nidentities: [{match: '[', identity: prod}]",
+		"identity not named": "http: {cert_file: server.crt, key_file: server.key}\nidentities: [{match: '*'}]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tlsconfig.ParseConfig([]byte(content))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestMapIdentity(t *testing.T) {
+	spiffe, err := url.Parse("spiffe://example.com/collector/eu")
+	require.NoError(t, err)
+	rules := []tlsconfig.IdentityRule{
+		{Match: "*.prod.example.com", Identity: "prod"},
+		{Match: "spiffe://example.com/collector/*", Identity: "collectors"},
+	}
+
+	require.Equal(t, "prod", tlsconfig.MapIdentity(rules, &x509.Certificate{DNSNames: []string{"gw-1.prod.example.com"}}))
+	require.Equal(t, "collectors", tlsconfig.MapIdentity(rules, &x509.Certificate{URIs: []*url.URL{spiffe}}))
+
+	cert := &x509.Certificate{DNSNames: []string{"gw-1.staging.example.com"}}
+	cert.Subject.CommonName = "gateway-1"
+	require.Equal(t, "gateway-1", tlsconfig.MapIdentity(rules, cert))
+	require.Equal(t, "gw-1.staging.example.com", tlsconfig.MapIdentity(nil, &x509.Certificate{DNSNames: cert.DNSNames}))
+	require.Empty(t, tlsconfig.MapIdentity(rules, &x509.Certificate{}))
+}
+
+func TestReloader_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := testutil.NewTestCA(t, "ca")
+	certFile, keyFile := ca.IssueFiles(t, dir, "server", "tallycat-1")
+
+	reloader, err := tlsconfig.NewReloader(&tlsconfig.ListenerConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: tlsconfig.ClientAuthNone}, time.Nanosecond)
+	require.NoError(t, err)
+	serverConfig := reloader.ServerConfig()
+
+	served := func() string {
+		config, err := serverConfig.GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "tallycat-1", served())
+
+	// A rotated certificate is served once its files changed
+	later := time.Now().Add(time.Minute)
+	ca.IssueFiles(t, dir, "server", "tallycat-2")
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	require.Equal(t, "tallycat-2", served())
+
+	// A broken rotation keeps the previous certificate
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0o600))
+	require.NoError(t, os.Chtimes(keyFile, later.Add(time.Minute), later.Add(time.Minute)))
+	require.Equal(t, "tallycat-2", served())
+}
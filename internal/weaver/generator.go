@@ -2,6 +2,7 @@ package weaver
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/tallycat/tallycat/internal/schema"
@@ -61,8 +62,7 @@ func generateMetricYAML(telemetry *schema.Telemetry, telemetrySchema *schema.Tel
 	yamlLines = append(yamlLines, "    type: metric")
 	yamlLines = append(yamlLines, fmt.Sprintf("    metric_name: %s", telemetry.SchemaKey))
 
-	yamlLines = append(yamlLines, fmt.Sprintf("    brief: %s", quoteYAMLString(telemetry.Brief)))
-	yamlLines = append(yamlLines, "    stability: stable")
+	yamlLines = append(yamlLines, formatDocumentation(telemetry)...)
 
 	// Add instrument (metric type)
 	yamlLines = append(yamlLines, fmt.Sprintf("    instrument: %s", convertMetricTypeToInstrument(telemetry.MetricType)))
@@ -103,9 +103,14 @@ func generateMetricYAML(telemetry *schema.Telemetry, telemetrySchema *schema.Tel
 	if len(dataPointAttributes) > 0 {
 		yamlLines = append(yamlLines, "    attributes:")
 
-		// Format each attribute
-		for _, attr := range dataPointAttributes {
-			yamlLines = append(yamlLines, formatAttribute(attr)...)
+		// Format each attribute, sorted by name for a stable output
+		names := make([]string, 0, len(dataPointAttributes))
+		for name := range dataPointAttributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			yamlLines = append(yamlLines, formatAttribute(dataPointAttributes[name])...)
 		}
 	}
 
@@ -128,8 +133,7 @@ func generateLogEventYAML(telemetry *schema.Telemetry, telemetrySchema *schema.T
 	yamlLines = append(yamlLines, fmt.Sprintf("  - id: %s", buildGroupID("event", telemetry)))
 	yamlLines = append(yamlLines, "    type: event")
 	yamlLines = append(yamlLines, fmt.Sprintf("    name: %s", eventName))
-	yamlLines = append(yamlLines, fmt.Sprintf("    brief: %s", quoteYAMLString(telemetry.Brief)))
-	yamlLines = append(yamlLines, "    stability: stable")
+	yamlLines = append(yamlLines, formatDocumentation(telemetry)...)
 
 	// Collect all attributes (LogRecord source + log-specific attributes)
 	var allAttributes []schema.Attribute
@@ -177,8 +181,7 @@ func generateSpanYAML(telemetry *schema.Telemetry, telemetrySchema *schema.Telem
 	yamlLines = append(yamlLines, "groups:")
 	yamlLines = append(yamlLines, fmt.Sprintf("  - id: %s", buildGroupID("span", telemetry)))
 	yamlLines = append(yamlLines, "    type: span")
-	yamlLines = append(yamlLines, fmt.Sprintf("    brief: %s", quoteYAMLString(telemetry.Brief)))
-	yamlLines = append(yamlLines, "    stability: stable")
+	yamlLines = append(yamlLines, formatDocumentation(telemetry)...)
 
 	// Add span_kind (required for SpanSemanticConvention)
 	spanKind := convertSpanKindToWeaver(telemetry.SpanKind)
@@ -244,6 +247,17 @@ func generateLogSpecificAttributes(telemetry *schema.Telemetry) []schema.Attribu
 	return attributes
 }
 
+// formatDocumentation formats the brief, note and stability of a telemetry group into YAML lines.
+// The note is left out when it only repeats the brief, as both are inferred from the metric description.
+func formatDocumentation(telemetry *schema.Telemetry) []string {
+	lines := []string{fmt.Sprintf("    brief: %s", quoteYAMLString(telemetry.Brief))}
+	if telemetry.Note != "" && telemetry.Note != telemetry.Brief {
+		lines = append(lines, fmt.Sprintf("    note: %s", quoteYAMLString(telemetry.Note)))
+	}
+	lines = append(lines, fmt.Sprintf("    stability: %s", convertStability(telemetry.Stability)))
+	return lines
+}
+
 // formatAttribute formats a single attribute into YAML lines
 func formatAttribute(attr schema.Attribute) []string {
 	var lines []string
@@ -256,13 +270,9 @@ func formatAttribute(attr schema.Attribute) []string {
 	lines = append(lines, fmt.Sprintf("        type: %s", weaverType))
 
 	// Add requirement level - default to recommended as per frontend
-	requirementLevel := "recommended"
-	if attr.RequirementLevel != "" {
-		requirementLevel = strings.ToLower(string(attr.RequirementLevel))
-	}
-	lines = append(lines, fmt.Sprintf("        requirement_level: %s", requirementLevel))
+	lines = append(lines, fmt.Sprintf("        requirement_level: %s", convertRequirementLevel(attr.RequirementLevel)))
 
-	lines = append(lines, "        stability: stable")
+	lines = append(lines, fmt.Sprintf("        stability: %s", convertStability(attr.Stability)))
 
 	// Add brief - always include even if empty (required by Weaver schema)
 	lines = append(lines, fmt.Sprintf("        brief: %s", quoteYAMLString(attr.Brief)))
@@ -410,6 +420,26 @@ func convertAttributeType(attrType schema.AttributeType) string {
 	}
 }
 
+// convertRequirementLevel converts requirement levels to Weaver ones, defaulting to recommended
+func convertRequirementLevel(level schema.RequirementLevel) string {
+	switch level {
+	case schema.RequirementLevelRequired:
+		return "required"
+	case schema.RequirementLevelOptIn:
+		return "opt_in"
+	default:
+		return "recommended"
+	}
+}
+
+// convertStability returns the Weaver stability, defaulting to stable when none was curated
+func convertStability(stability schema.Stability) string {
+	if stability == "" {
+		return string(schema.StabilityStable)
+	}
+	return string(stability)
+}
+
 // convertSpanKindToWeaver converts internal span kinds to Weaver-compatible span_kind values
 func convertSpanKindToWeaver(spanKind schema.SpanKind) string {
 	switch spanKind {
@@ -1116,3 +1116,56 @@ func TestGenerateMultiLogYAML_MultipleLogs(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateYAML_CuratedDocumentation(t *testing.T) {
+
+	telemetry := &schema.Telemetry{
+		SchemaKey:     "checkout.requests",
+		Brief:         "Requests handled by checkout",
+		Note:          "Counts retries as separate requests",
+		Stability:     schema.StabilityDevelopment,
+		MetricType:    schema.MetricTypeSum,
+		TelemetryType: schema.TelemetryTypeMetric,
+		Attributes: []schema.Attribute{
+			{
+				Name:             "payment.method",
+				Type:             schema.AttributeTypeStr,
+				Source:           schema.AttributeSourceDataPoint,
+				RequirementLevel: schema.RequirementLevelOptIn,
+				Stability:        schema.StabilityDeprecated,
+				Brief:            "Payment method chosen by the customer",
+			},
+		},
+	}
+
+	yaml, err := GenerateYAML(telemetry, nil)
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedLines := []string{
+		"    brief: \"Requests handled by checkout\"",
+		"    note: \"Counts retries as separate requests\"",
+		"    stability: development",
+		"        requirement_level: opt_in",
+		"        stability: deprecated",
+		"        brief: \"Payment method chosen by the customer\"",
+	}
+
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(yaml, expectedLine) {
+			t.Errorf("Expected YAML to contain '%s', but it didn't.\nActual YAML:\n%s", expectedLine, yaml)
+		}
+	}
+
+	// A note inferred from the same description as the brief is not repeated
+	telemetry.Note = telemetry.Brief
+	yaml, err = GenerateYAML(telemetry, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(yaml, "note:") {
+		t.Errorf("Expected YAML without note, got:\n%s", yaml)
+	}
+}
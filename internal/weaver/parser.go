@@ -0,0 +1,230 @@
+package weaver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tallycat/tallycat/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// registryFile is a Weaver semantic convention file, as written by GenerateYAML
+type registryFile struct {
+	Groups []registryGroup `yaml:"groups"`
+}
+
+type registryGroup struct {
+	ID         string              `yaml:"id"`
+	Type       string              `yaml:"type"`
+	MetricName string              `yaml:"metric_name"`
+	Name       string              `yaml:"name"`
+	Brief      string              `yaml:"brief"`
+	Note       string              `yaml:"note"`
+	Stability  string              `yaml:"stability"`
+	Instrument string              `yaml:"instrument"`
+	Unit       string              `yaml:"unit"`
+	SpanKind   string              `yaml:"span_kind"`
+	Attributes []registryAttribute `yaml:"attributes"`
+}
+
+type registryAttribute struct {
+	ID  string `yaml:"id"`
+	Ref string `yaml:"ref"`
+	// Type is a type name or an enum definition, and RequirementLevel a level name or a
+	// conditionally_required mapping
+	Type             yaml.Node `yaml:"type"`
+	RequirementLevel yaml.Node `yaml:"requirement_level"`
+	Stability        string    `yaml:"stability"`
+	Brief            string    `yaml:"brief"`
+}
+
+// ParseYAML reads the metric, event and span groups of Weaver semantic convention files, the inverse
+// of GenerateYAML. The content may hold several YAML documents. Other group types are ignored.
+//
+// Metrics are keyed by metric_name and events by name. Spans have no name in Weaver, so their key
+// is read from the group ID as written by GenerateYAML: span.{scope}.{key}.
+func ParseYAML(data []byte) ([]schema.DeclaredTelemetry, error) {
+	var declared []schema.DeclaredTelemetry
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var file registryFile
+		if err := decoder.Decode(&file); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("failed to parse registry: %w", err)
+		}
+
+		for _, group := range file.Groups {
+			d, ok, err := parseGroup(group)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", group.ID, err)
+			}
+			if ok {
+				declared = append(declared, d)
+			}
+		}
+	}
+
+	return declared, nil
+}
+
+func parseGroup(group registryGroup) (schema.DeclaredTelemetry, bool, error) {
+	d := schema.DeclaredTelemetry{
+		GroupID: group.ID,
+		Brief:   strings.TrimSpace(group.Brief),
+		Note:    strings.TrimSpace(group.Note),
+	}
+	if group.Stability != "" {
+		stability, ok := schema.ParseStability(group.Stability)
+		if !ok {
+			// Older registries still use experimental
+			stability = schema.StabilityDevelopment
+		}
+		d.Stability = stability
+	}
+
+	switch group.Type {
+	case "metric":
+		if group.MetricName == "" {
+			return d, false, fmt.Errorf("metric_name is required")
+		}
+		d.TelemetryType = schema.TelemetryTypeMetric
+		d.SchemaKey = group.MetricName
+		d.MetricUnit = group.Unit
+		d.MetricType = convertInstrumentToMetricType(group.Instrument)
+	case "event":
+		if group.Name == "" {
+			return d, false, fmt.Errorf("name is required")
+		}
+		d.TelemetryType = schema.TelemetryTypeLog
+		d.SchemaKey = group.Name
+	case "span":
+		d.TelemetryType = schema.TelemetryTypeSpan
+		d.SchemaKey = spanKeyFromGroupID(group.ID)
+		d.SpanKind = convertWeaverSpanKind(group.SpanKind)
+	default:
+		return d, false, nil
+	}
+
+	d.Attributes = make([]schema.Attribute, 0, len(group.Attributes))
+	for _, attr := range group.Attributes {
+		name := attr.ID
+		if name == "" {
+			name = attr.Ref
+		}
+		if name == "" {
+			return d, false, fmt.Errorf("attribute without id or ref")
+		}
+		if d.TelemetryType == schema.TelemetryTypeLog && logRecordFields[name] {
+			continue
+		}
+		parsed := schema.Attribute{
+			Name:             name,
+			Type:             convertWeaverAttributeType(attr.Type),
+			Brief:            strings.TrimSpace(attr.Brief),
+			RequirementLevel: convertWeaverRequirementLevel(attr.RequirementLevel),
+		}
+		if stability, ok := schema.ParseStability(attr.Stability); ok {
+			parsed.Stability = stability
+		}
+		d.Attributes = append(d.Attributes, parsed)
+	}
+
+	return d, true, nil
+}
+
+// logRecordFields are the log record fields GenerateYAML exports as event attributes
+var logRecordFields = map[string]bool{
+	"log.severity.number": true,
+	"log.severity.text":   true,
+}
+
+// spanKeyFromGroupID strips the span prefix and the scope segment of the group ID
+func spanKeyFromGroupID(id string) string {
+	parts := strings.SplitN(id, ".", 3)
+	switch {
+	case len(parts) == 3 && parts[0] == "span":
+		return parts[2]
+	case len(parts) >= 2 && parts[0] == "span":
+		return strings.TrimPrefix(id, "span.")
+	default:
+		return id
+	}
+}
+
+// convertInstrumentToMetricType converts Weaver instruments to metric types
+func convertInstrumentToMetricType(instrument string) schema.MetricType {
+	switch instrument {
+	case "counter", "updowncounter":
+		return schema.MetricTypeSum
+	case "gauge":
+		return schema.MetricTypeGauge
+	case "histogram":
+		return schema.MetricTypeHistogram
+	default:
+		return schema.MetricTypeEmpty
+	}
+}
+
+// convertWeaverSpanKind converts Weaver span kinds to internal ones
+func convertWeaverSpanKind(spanKind string) schema.SpanKind {
+	switch spanKind {
+	case "client":
+		return schema.SpanKindClient
+	case "server":
+		return schema.SpanKindServer
+	case "producer":
+		return schema.SpanKindProducer
+	case "consumer":
+		return schema.SpanKindConsumer
+	default:
+		return schema.SpanKindInternal
+	}
+}
+
+// convertWeaverAttributeType converts Weaver attribute types to internal ones. Enums are strings or
+// integers depending on their members, approximated as strings. Attributes referenced without a
+// type have an empty one, which matches any observed type.
+func convertWeaverAttributeType(node yaml.Node) schema.AttributeType {
+	switch node.Kind {
+	case 0:
+		return ""
+	case yaml.ScalarNode:
+	default:
+		return schema.AttributeTypeStr
+	}
+	switch node.Value {
+	case "string":
+		return schema.AttributeTypeStr
+	case "boolean":
+		return schema.AttributeTypeBool
+	case "int":
+		return schema.AttributeTypeInt
+	case "double":
+		return schema.AttributeTypeDouble
+	case "any":
+		return schema.AttributeTypeMap
+	default:
+		if strings.HasSuffix(node.Value, "[]") {
+			return schema.AttributeTypeSlice
+		}
+		return schema.AttributeTypeStr
+	}
+}
+
+// convertWeaverRequirementLevel converts Weaver requirement levels to internal ones. Conditionally
+// required attributes are treated as recommended since the condition cannot be checked.
+func convertWeaverRequirementLevel(node yaml.Node) schema.RequirementLevel {
+	if node.Kind != yaml.ScalarNode {
+		return schema.RequirementLevelRecommended
+	}
+	if level, ok := schema.ParseRequirementLevel(node.Value); ok {
+		return level
+	}
+	return schema.RequirementLevelRecommended
+}
@@ -0,0 +1,88 @@
+package weaver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tallycat/tallycat/internal/schema"
+)
+
+func TestParseYAML_RoundTrip(t *testing.T) {
+	metric := &schema.Telemetry{
+		SchemaKey:     "http.server.duration",
+		Brief:         "Measures the duration of HTTP server requests",
+		Stability:     schema.StabilityDevelopment,
+		MetricType:    schema.MetricTypeHistogram,
+		MetricUnit:    "ms",
+		TelemetryType: schema.TelemetryTypeMetric,
+		Attributes: []schema.Attribute{
+			{Name: "http.method", Type: schema.AttributeTypeStr, Source: schema.AttributeSourceDataPoint, RequirementLevel: schema.RequirementLevelRequired, Brief: "HTTP request method"},
+			{Name: "http.status_code", Type: schema.AttributeTypeInt, Source: schema.AttributeSourceDataPoint, RequirementLevel: schema.RequirementLevelOptIn},
+		},
+		Scope: &schema.Scope{Name: "go.opentelemetry.io/contrib/otelhttp"},
+	}
+	event := &schema.Telemetry{
+		SchemaKey:     "payments.audit",
+		TelemetryType: schema.TelemetryTypeLog,
+		Attributes: []schema.Attribute{
+			{Name: "payment.id", Type: schema.AttributeTypeStr, Source: schema.AttributeSourceLogRecord},
+		},
+	}
+	span := &schema.Telemetry{
+		SchemaKey:     "GET /checkout",
+		TelemetryType: schema.TelemetryTypeSpan,
+		SpanKind:      schema.SpanKindServer,
+		Scope:         &schema.Scope{Name: "otelhttp"},
+	}
+
+	var documents []byte
+	for _, telemetry := range []*schema.Telemetry{metric, event, span} {
+		yaml, err := GenerateYAML(telemetry, nil)
+		require.NoError(t, err)
+		documents = append(documents, []byte(yaml+"\n---\n")...)
+	}
+
+	declared, err := ParseYAML(documents)
+	require.NoError(t, err)
+	require.Len(t, declared, 3)
+
+	require.Equal(t, schema.DeclaredTelemetry{
+		GroupID:       "metric.go_opentelemetry_io_contrib_otelhttp.http.server.duration",
+		SchemaKey:     "http.server.duration",
+		TelemetryType: schema.TelemetryTypeMetric,
+		Brief:         "Measures the duration of HTTP server requests",
+		Stability:     schema.StabilityDevelopment,
+		MetricUnit:    "ms",
+		MetricType:    schema.MetricTypeHistogram,
+		Attributes: []schema.Attribute{
+			{Name: "http.method", Type: schema.AttributeTypeStr, RequirementLevel: schema.RequirementLevelRequired, Stability: schema.StabilityStable, Brief: "HTTP request method"},
+			{Name: "http.status_code", Type: schema.AttributeTypeInt, RequirementLevel: schema.RequirementLevelOptIn, Stability: schema.StabilityStable},
+		},
+	}, declared[0])
+
+	// Log severity fields exported as event attributes are not declared attributes
+	require.Equal(t, "payments.audit", declared[1].SchemaKey)
+	require.Equal(t, schema.TelemetryTypeLog, declared[1].TelemetryType)
+	require.Len(t, declared[1].Attributes, 1)
+	require.Equal(t, "payment.id", declared[1].Attributes[0].Name)
+
+	require.Equal(t, "GET /checkout", declared[2].SchemaKey)
+	require.Equal(t, schema.SpanKindServer, declared[2].SpanKind)
+}
+
+func TestParseYAML_Invalid(t *testing.T) {
+	_, err := ParseYAML([]byte("groups:\n  - id: metric.missing\n    type: metric\n"))
+	require.ErrorContains(t, err, "metric_name is required")
+
+	_, err = ParseYAML([]byte("groups: [\n"))
+	require.ErrorContains(t, err, "failed to parse registry")
+
+	declared, err := ParseYAML([]byte(`
+groups:
+  - id: registry.http
+    type: attribute_group
+    brief: HTTP attributes
+`))
+	require.NoError(t, err)
+	require.Empty(t, declared)
+}